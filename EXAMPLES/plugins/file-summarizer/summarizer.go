@@ -0,0 +1,288 @@
+// Package main implements the file-summarizer plugin, which summarizes
+// project files through the host's file services
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+const (
+	// defaultMaxFiles is how many files a call summarizes when it sets no
+	// max_files
+	defaultMaxFiles = 200
+
+	// maxFiles caps the max_files of a call
+	maxFiles = 5000
+)
+
+// languages names the language of files by extension
+var languages = map[string]string{
+	".go":    "Go",
+	".py":    "Python",
+	".js":    "JavaScript",
+	".ts":    "TypeScript",
+	".proto": "Protocol Buffers",
+	".md":    "Markdown",
+	".json":  "JSON",
+	".yaml":  "YAML",
+	".yml":   "YAML",
+	".sh":    "Shell",
+	".c":     "C",
+	".h":     "C",
+	".rs":    "Rust",
+	".java":  "Java",
+	".html":  "HTML",
+	".css":   "CSS",
+	".sql":   "SQL",
+	".toml":  "TOML",
+}
+
+// FileSummarizerPlugin summarizes the files matching a glob under the
+// roots the host grants it: their size, language and first comment or
+// heading. It needs the project root granted for reading:
+//
+//	files: {roots: {project: {path: "."}}}
+type FileSummarizerPlugin struct {
+	host pluginsdk.HostServices
+}
+
+// Summary is the result of files.summarize
+type Summary struct {
+	Pattern   string                   `json:"pattern"`
+	Files     []FileSummary            `json:"files"`
+	Languages map[string]LanguageTotal `json:"languages"`
+	Skipped   int                      `json:"skipped,omitempty"`
+	Partial   bool                     `json:"partial,omitempty"`
+}
+
+// FileSummary describes one file
+type FileSummary struct {
+	Path     string `json:"path"`
+	Bytes    int    `json:"bytes"`
+	Lines    int    `json:"lines"`
+	Language string `json:"language,omitempty"`
+	Binary   bool   `json:"binary,omitempty"`
+	Headline string `json:"headline,omitempty"`
+}
+
+// LanguageTotal sums up the files of one language
+type LanguageTotal struct {
+	Files int `json:"files"`
+	Lines int `json:"lines"`
+	Bytes int `json:"bytes"`
+}
+
+// Name returns the plugin's unique identifier
+func (p *FileSummarizerPlugin) Name() string {
+	return "file-summarizer"
+}
+
+// Version returns the plugin's version
+func (p *FileSummarizerPlugin) Version() string {
+	return pluginsdk.VersionOr("1.0.0")
+}
+
+// SetHostServices keeps the host's services for reading files
+func (p *FileSummarizerPlugin) SetHostServices(host pluginsdk.HostServices) {
+	p.host = host
+}
+
+// Execute summarizes the files of a files.summarize call. When the call's
+// latency budget runs out, it answers with the files summarized so far.
+func (p *FileSummarizerPlugin) Execute(args map[string]interface{}) (string, error) {
+	if p.host == nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeUnsupported, "host does not offer file services")
+	}
+	pattern, _ := args["pattern"].(string)
+	if pattern == "" {
+		pattern = "**/*"
+	}
+	limit := defaultMaxFiles
+	if v, ok := args["max_files"].(float64); ok {
+		limit = int(v)
+	}
+	if limit < 1 || limit > maxFiles {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "max_files must be between 1 and %d", maxFiles)
+	}
+
+	paths, err := p.host.GlobFiles(pattern)
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+	s := &Summary{Pattern: pattern, Files: []FileSummary{}, Languages: map[string]LanguageTotal{}}
+	if len(paths) > limit {
+		s.Skipped = len(paths) - limit
+		paths = paths[:limit]
+	}
+
+	root := pluginsdk.ContextOf(args).ProjectRoot
+	if root == "" {
+		root = commonDir(paths)
+	}
+	wrapUp := pluginsdk.WrapUp(args)
+	for i, path := range paths {
+		select {
+		case <-wrapUp:
+			s.Partial = true
+			s.Skipped += len(paths) - i
+			result, err := render(args, s)
+			if err != nil {
+				return "", err
+			}
+			return result, pluginsdk.ErrPartial
+		default:
+		}
+		if ctx := pluginsdk.CallContext(args); ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+
+		data, err := p.host.ReadFile(path)
+		if err != nil {
+			s.Skipped++
+			continue
+		}
+		f := summarize(relative(root, path), data)
+		s.Files = append(s.Files, f)
+		if f.Language != "" {
+			total := s.Languages[f.Language]
+			total.Files++
+			total.Lines += f.Lines
+			total.Bytes += f.Bytes
+			s.Languages[f.Language] = total
+		}
+		if i%20 == 0 {
+			pluginsdk.ReportProgress(p.host, args, float64(i)*100/float64(len(paths)), "reading "+f.Path)
+		}
+	}
+	return render(args, s)
+}
+
+// summarize describes one file from its content
+func summarize(path string, data []byte) FileSummary {
+	f := FileSummary{Path: path, Bytes: len(data), Language: languages[strings.ToLower(filepath.Ext(path))]}
+	if bytes.IndexByte(data, 0) >= 0 {
+		f.Binary = true
+		return f
+	}
+	f.Lines = bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		f.Lines++
+	}
+	f.Headline = headline(data)
+	return f
+}
+
+// headline returns the first comment or Markdown heading of a file, with
+// its marker removed
+func headline(data []byte) string {
+	for _, line := range strings.SplitN(string(data), "\n", 50) {
+		line = strings.TrimSpace(line)
+		for _, marker := range []string{"//", "#", "--", "/*", "*"} {
+			if strings.HasPrefix(line, marker) && !strings.HasPrefix(line, "#!") {
+				text := strings.TrimSpace(strings.TrimSuffix(strings.TrimLeft(line, marker), "*/"))
+				if text != "" {
+					return text
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// commonDir returns the deepest directory all paths lie under
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	dir := filepath.Dir(paths[0])
+	for _, path := range paths[1:] {
+		for dir != filepath.Dir(dir) && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
+
+// relative shortens path to be relative to root when it lies under it
+func relative(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// render answers in the format the call accepts, Markdown by default
+func render(args map[string]interface{}, s *Summary) (string, error) {
+	if pluginsdk.ResultFormat(args, formats) == pluginsdk.FormatJSON {
+		data, err := json.Marshal(s)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Summary of %s\n\n", s.Pattern)
+	if len(s.Languages) > 0 {
+		names := make([]string, 0, len(s.Languages))
+		for name := range s.Languages {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("| Language | Files | Lines | Bytes |\n|---|---|---|---|\n")
+		for _, name := range names {
+			t := s.Languages[name]
+			fmt.Fprintf(&b, "| %s | %d | %d | %d |\n", name, t.Files, t.Lines, t.Bytes)
+		}
+		b.WriteString("\n")
+	}
+	for _, f := range s.Files {
+		switch {
+		case f.Binary:
+			fmt.Fprintf(&b, "- `%s`: binary, %d bytes\n", f.Path, f.Bytes)
+		case f.Headline != "":
+			fmt.Fprintf(&b, "- `%s`: %d lines, %s\n", f.Path, f.Lines, f.Headline)
+		default:
+			fmt.Fprintf(&b, "- `%s`: %d lines\n", f.Path, f.Lines)
+		}
+	}
+	if s.Skipped > 0 {
+		fmt.Fprintf(&b, "\n%d more files not summarized.\n", s.Skipped)
+	}
+	return b.String(), nil
+}
+
+// formats are the result formats of files.summarize, the default first
+var formats = []string{pluginsdk.FormatMarkdown, pluginsdk.FormatJSON}
+
+// GetCapabilities describes the capabilities this plugin provides
+func (p *FileSummarizerPlugin) GetCapabilities() []pluginsdk.Capability {
+	return []pluginsdk.Capability{
+		{
+			Name:        "files.summarize",
+			Description: "Summarize the project files matching a pattern by size, language and headline",
+			ArgsSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"pattern":   map[string]interface{}{"type": "string", "description": "Glob under the project, e.g. src/**/*.go"},
+					"max_files": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": maxFiles},
+				},
+			},
+			Example:    map[string]interface{}{"pattern": "**/*.go", "max_files": 50},
+			Tags:       []string{"files", "summary"},
+			Idempotent: true,
+			Cost:       pluginsdk.CostMedium,
+			Formats:    formats,
+			Partial:    true,
+		},
+	}
+}
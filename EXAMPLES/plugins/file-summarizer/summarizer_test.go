@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// fakeHost serves files from memory. Methods the plugin does not use are
+// left to the nil interface it embeds.
+type fakeHost struct {
+	pluginsdk.HostServices
+	files map[string]string
+	// unreadable are paths GlobFiles lists but ReadFile refuses
+	unreadable map[string]bool
+	pattern    string
+}
+
+func (h *fakeHost) GlobFiles(pattern string) ([]string, error) {
+	h.pattern = pattern
+	var paths []string
+	for path := range h.files {
+		// Matching names against the last element of the pattern is
+		// enough for the patterns these tests use
+		if ok, _ := filepath.Match(filepath.Base(pattern), filepath.Base(path)); ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (h *fakeHost) ReadFile(path string) ([]byte, error) {
+	if h.unreadable[path] {
+		return nil, errors.New("permission denied")
+	}
+	return []byte(h.files[path]), nil
+}
+
+func (h *fakeHost) ReportProgress(requestID string, percent float64, message string) error {
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	files := map[string]string{
+		"/project/main.go":       "// Package main runs it\npackage main\n",
+		"/project/README.md":     "# Project\n\nText",
+		"/project/run.sh":        "#!/bin/sh\necho hi\n",
+		"/project/logo.png":      "\x89PNG\x00\x01",
+		"/project/docs/notes.md": "no heading\n",
+	}
+	tests := []struct {
+		name       string
+		args       map[string]interface{}
+		unreadable map[string]bool
+		want       Summary
+	}{
+		{
+			name: "all files",
+			args: map[string]interface{}{},
+			want: Summary{
+				Pattern: "**/*",
+				Files: []FileSummary{
+					{Path: "README.md", Bytes: 15, Lines: 3, Language: "Markdown", Headline: "Project"},
+					{Path: "docs/notes.md", Bytes: 11, Lines: 1, Language: "Markdown"},
+					{Path: "logo.png", Bytes: 6, Binary: true},
+					{Path: "main.go", Bytes: 37, Lines: 2, Language: "Go", Headline: "Package main runs it"},
+					{Path: "run.sh", Bytes: 18, Lines: 2, Language: "Shell"},
+				},
+				Languages: map[string]LanguageTotal{
+					"Go":       {Files: 1, Lines: 2, Bytes: 37},
+					"Markdown": {Files: 2, Lines: 4, Bytes: 26},
+					"Shell":    {Files: 1, Lines: 2, Bytes: 18},
+				},
+			},
+		},
+		{
+			name: "max_files",
+			args: map[string]interface{}{"pattern": "**/*.md", "max_files": float64(1)},
+			want: Summary{
+				Pattern:   "**/*.md",
+				Files:     []FileSummary{{Path: "README.md", Bytes: 15, Lines: 3, Language: "Markdown", Headline: "Project"}},
+				Languages: map[string]LanguageTotal{"Markdown": {Files: 1, Lines: 3, Bytes: 15}},
+				Skipped:   1,
+			},
+		},
+		{
+			name:       "unreadable files are skipped",
+			args:       map[string]interface{}{"max_files": float64(2)},
+			unreadable: map[string]bool{"/project/README.md": true},
+			want: Summary{
+				Pattern:   "**/*",
+				Files:     []FileSummary{{Path: "docs/notes.md", Bytes: 11, Lines: 1, Language: "Markdown"}},
+				Languages: map[string]LanguageTotal{"Markdown": {Files: 1, Lines: 1, Bytes: 11}},
+				Skipped:   4,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host := &fakeHost{files: files, unreadable: tt.unreadable}
+			p := &FileSummarizerPlugin{}
+			p.SetHostServices(host)
+			tt.args[pluginsdk.ArgOptions] = map[string]interface{}{pluginsdk.OptionAccept: []interface{}{"json"}}
+			out, err := p.Execute(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if host.pattern != tt.want.Pattern {
+				t.Errorf("globbed %q, want %q", host.pattern, tt.want.Pattern)
+			}
+			var got Summary
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("result %q: %v", out, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteMarkdown(t *testing.T) {
+	p := &FileSummarizerPlugin{}
+	p.SetHostServices(&fakeHost{files: map[string]string{
+		"/project/main.go":  "// Package main runs it\npackage main\n",
+		"/project/logo.png": "\x00",
+	}})
+	out, err := p.Execute(map[string]interface{}{"max_files": float64(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Summary of **/*", "- `logo.png`: binary, 1 bytes", "1 more files not summarized."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("result lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		host pluginsdk.HostServices
+		args map[string]interface{}
+		want pluginsdk.ErrorCode
+	}{
+		{"no host", nil, map[string]interface{}{}, pluginsdk.CodeUnsupported},
+		{"max_files too low", &fakeHost{}, map[string]interface{}{"max_files": float64(0)}, pluginsdk.CodeInvalidArgument},
+		{"max_files too high", &fakeHost{}, map[string]interface{}{"max_files": float64(maxFiles + 1)}, pluginsdk.CodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &FileSummarizerPlugin{}
+			if tt.host != nil {
+				p.SetHostServices(tt.host)
+			}
+			_, err := p.Execute(tt.args)
+			if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != tt.want {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+// Package main implements the git-info plugin, which reads the state of git
+// repositories with the git command
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// maxLogEntries caps the limit of git.log calls
+const maxLogEntries = 500
+
+// GitInfoPlugin answers questions about the repository a call works in:
+// its "dir" argument, else the call's working directory, else the project
+// root of its request
+type GitInfoPlugin struct{}
+
+// Status is the result of git.status
+type Status struct {
+	Root     string       `json:"root"`
+	Branch   string       `json:"branch"`
+	Upstream string       `json:"upstream,omitempty"`
+	Ahead    int          `json:"ahead"`
+	Behind   int          `json:"behind"`
+	Clean    bool         `json:"clean"`
+	Files    []FileStatus `json:"files"`
+}
+
+// FileStatus is a changed file as git status --porcelain reports it, with
+// its index and worktree status letters, e.g. "M" or "?"
+type FileStatus struct {
+	Path     string `json:"path"`
+	From     string `json:"from,omitempty"`
+	Index    string `json:"index"`
+	Worktree string `json:"worktree"`
+}
+
+// Commit is an entry of git.log
+type Commit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Date    string `json:"date"`
+	Subject string `json:"subject"`
+}
+
+// Branch is an entry of git.branches
+type Branch struct {
+	Name     string `json:"name"`
+	Current  bool   `json:"current"`
+	Upstream string `json:"upstream,omitempty"`
+	Commit   string `json:"commit"`
+}
+
+// Name returns the plugin's unique identifier
+func (p *GitInfoPlugin) Name() string {
+	return "git-info"
+}
+
+// Version returns the plugin's version
+func (p *GitInfoPlugin) Version() string {
+	return pluginsdk.VersionOr("1.0.0")
+}
+
+// Execute runs the capability a call names
+func (p *GitInfoPlugin) Execute(args map[string]interface{}) (string, error) {
+	var result interface{}
+	var err error
+	switch capability, _ := args[pluginsdk.ArgCapability].(string); capability {
+	case "git.status", "":
+		result, err = gitStatus(args)
+	case "git.log":
+		result, err = gitLog(args)
+	case "git.branches":
+		result, err = gitBranches(args)
+	default:
+		return "", pluginsdk.NewError(pluginsdk.CodeUnsupported, "unknown capability %q", capability)
+	}
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func gitStatus(args map[string]interface{}) (*Status, error) {
+	root, err := git(args, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	out, err := git(args, "status", "--porcelain=v1", "--branch")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Status{Root: strings.TrimSpace(root), Files: []FileStatus{}}
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "## "):
+			parseBranchLine(s, strings.TrimPrefix(line, "## "))
+		case len(line) > 3:
+			f := FileStatus{Index: strings.TrimSpace(line[:1]), Worktree: strings.TrimSpace(line[1:2]), Path: line[3:]}
+			if from, to, renamed := strings.Cut(f.Path, " -> "); renamed {
+				f.From, f.Path = from, to
+			}
+			s.Files = append(s.Files, f)
+		}
+	}
+	s.Clean = len(s.Files) == 0
+	return s, nil
+}
+
+// parseBranchLine reads the header of git status --branch, e.g.
+// "main...origin/main [ahead 1, behind 2]" or "No commits yet on main"
+func parseBranchLine(s *Status, line string) {
+	line = strings.TrimPrefix(line, "No commits yet on ")
+	head, counts, _ := strings.Cut(line, " [")
+	s.Branch, s.Upstream, _ = strings.Cut(head, "...")
+	for _, part := range strings.Split(strings.TrimSuffix(counts, "]"), ", ") {
+		kind, n, _ := strings.Cut(part, " ")
+		count, _ := strconv.Atoi(n)
+		switch kind {
+		case "ahead":
+			s.Ahead = count
+		case "behind":
+			s.Behind = count
+		}
+	}
+}
+
+func gitLog(args map[string]interface{}) ([]Commit, error) {
+	limit := 20
+	if v, ok := args["limit"].(float64); ok {
+		limit = int(v)
+	}
+	if limit < 1 || limit > maxLogEntries {
+		return nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "limit must be between 1 and %d", maxLogEntries)
+	}
+	gitArgs := []string{"log", "-n", strconv.Itoa(limit), "--format=%H%x1f%an%x1f%aI%x1f%s"}
+	if ref, _ := args["ref"].(string); ref != "" {
+		if strings.HasPrefix(ref, "-") {
+			return nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "invalid ref %q", ref)
+		}
+		gitArgs = append(gitArgs, ref)
+	}
+	if path, _ := args["path"].(string); path != "" {
+		gitArgs = append(gitArgs, "--", path)
+	}
+	out, err := git(args, gitArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []Commit{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		commits = append(commits, Commit{Hash: fields[0], Author: fields[1], Date: fields[2], Subject: fields[3]})
+	}
+	return commits, nil
+}
+
+func gitBranches(args map[string]interface{}) ([]Branch, error) {
+	out, err := git(args, "for-each-ref", "--format=%(refname:short)%1f%(HEAD)%1f%(upstream:short)%1f%(objectname:short)", "refs/heads")
+	if err != nil {
+		return nil, err
+	}
+
+	list := []Branch{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 4 {
+			continue
+		}
+		list = append(list, Branch{Name: fields[0], Current: fields[1] == "*", Upstream: fields[2], Commit: fields[3]})
+	}
+	return list, nil
+}
+
+// git runs git in the repository of a call and returns its output. It is
+// killed when the host cancels the call.
+func git(args map[string]interface{}, gitArgs ...string) (string, error) {
+	dir := repoDir(args)
+	if dir == "" {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "dir is required when the call has no working directory")
+	}
+	cmd := pluginsdk.Command(args, "git", gitArgs...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeUnavailable, "cannot run git: %v", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-pluginsdk.CallContext(args).Done():
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx := pluginsdk.CallContext(args); ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && strings.Contains(msg, "not a git repository") {
+			return "", pluginsdk.NewError(pluginsdk.CodeNotFound, "%s is not in a git repository", dir)
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", pluginsdk.NewError(pluginsdk.CodeInternal, "git %s: %s", gitArgs[0], msg)
+	}
+	return stdout.String(), nil
+}
+
+func repoDir(args map[string]interface{}) string {
+	if dir, _ := args["dir"].(string); dir != "" {
+		return pluginsdk.ResolvePath(args, dir)
+	}
+	if dir := pluginsdk.WorkDir(args); dir != "" {
+		return dir
+	}
+	return pluginsdk.ContextOf(args).ProjectRoot
+}
+
+// GetCapabilities describes the capabilities this plugin provides
+func (p *GitInfoPlugin) GetCapabilities() []pluginsdk.Capability {
+	dirArg := map[string]interface{}{"type": "string", "description": "Directory inside the repository"}
+	return []pluginsdk.Capability{
+		{
+			Name:        "git.status",
+			Description: "Current branch, upstream distance and changed files",
+			ArgsSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"dir": dirArg},
+			},
+			Tags:       []string{"git", "vcs"},
+			Idempotent: true,
+			Cost:       pluginsdk.CostCheap,
+			Latency:    "100ms",
+			Formats:    []string{pluginsdk.FormatJSON},
+		},
+		{
+			Name:        "git.log",
+			Description: "Most recent commits, optionally of one ref or path",
+			ArgsSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"dir":   dirArg,
+					"limit": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": maxLogEntries},
+					"ref":   map[string]interface{}{"type": "string", "description": "Branch, tag or commit to start from"},
+					"path":  map[string]interface{}{"type": "string", "description": "Only commits touching this path"},
+				},
+			},
+			Example:    map[string]interface{}{"limit": 5},
+			Tags:       []string{"git", "vcs", "history"},
+			Idempotent: true,
+			Cost:       pluginsdk.CostCheap,
+			Latency:    "100ms",
+			Formats:    []string{pluginsdk.FormatJSON},
+		},
+		{
+			Name:        "git.branches",
+			Description: "Local branches with their upstreams",
+			ArgsSchema: map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"dir": dirArg},
+			},
+			Tags:       []string{"git", "vcs"},
+			Idempotent: true,
+			Cost:       pluginsdk.CostCheap,
+			Latency:    "100ms",
+			Formats:    []string{pluginsdk.FormatJSON},
+		},
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// newRepo creates a repository on branch main with two commits, a feature
+// branch, a modified file and an untracked one
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Ada", "-c", "user.email=ada@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run("init", "-q", "-b", "main")
+	write("README.md", "# Repo\n")
+	run("add", "README.md")
+	run("commit", "-q", "-m", "Add readme")
+	write("main.go", "package main\n")
+	run("add", "main.go")
+	run("commit", "-q", "-m", "Add main")
+	run("branch", "feature")
+	write("README.md", "# Repo\n\nChanged\n")
+	write("notes.txt", "todo\n")
+	return dir
+}
+
+func TestExecute(t *testing.T) {
+	dir := newRepo(t)
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		args  map[string]interface{}
+		check func(t *testing.T, out string)
+	}{
+		{
+			name: "status",
+			args: map[string]interface{}{pluginsdk.ArgCapability: "git.status", "dir": dir},
+			check: func(t *testing.T, out string) {
+				var got Status
+				decode(t, out, &got)
+				if resolved, _ := filepath.EvalSymlinks(got.Root); resolved != root {
+					t.Errorf("root = %q, want %q", got.Root, root)
+				}
+				want := []FileStatus{{Path: "README.md", Worktree: "M"}, {Path: "notes.txt", Index: "?", Worktree: "?"}}
+				if got.Branch != "main" || got.Clean || !reflect.DeepEqual(got.Files, want) {
+					t.Errorf("got %+v, want branch main with files %+v", got, want)
+				}
+			},
+		},
+		{
+			name: "log",
+			args: map[string]interface{}{pluginsdk.ArgCapability: "git.log", "dir": dir, "limit": float64(1)},
+			check: func(t *testing.T, out string) {
+				var got []Commit
+				decode(t, out, &got)
+				if len(got) != 1 || got[0].Subject != "Add main" || got[0].Author != "Ada" {
+					t.Errorf("got %+v, want the last commit", got)
+				}
+			},
+		},
+		{
+			name: "log of a path",
+			args: map[string]interface{}{pluginsdk.ArgCapability: "git.log", "dir": dir, "path": "README.md"},
+			check: func(t *testing.T, out string) {
+				var got []Commit
+				decode(t, out, &got)
+				if len(got) != 1 || got[0].Subject != "Add readme" {
+					t.Errorf("got %+v, want the commit of README.md", got)
+				}
+			},
+		},
+		{
+			name: "branches",
+			args: map[string]interface{}{pluginsdk.ArgCapability: "git.branches", "dir": dir},
+			check: func(t *testing.T, out string) {
+				var got []Branch
+				decode(t, out, &got)
+				if len(got) != 2 || got[0].Name != "feature" || got[0].Current || got[1].Name != "main" || !got[1].Current {
+					t.Errorf("got %+v, want feature and the current main", got)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := (&GitInfoPlugin{}).Execute(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, out)
+		})
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	dir := newRepo(t)
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want pluginsdk.ErrorCode
+	}{
+		{"unknown capability", map[string]interface{}{pluginsdk.ArgCapability: "git.push", "dir": dir}, pluginsdk.CodeUnsupported},
+		{"no directory", map[string]interface{}{pluginsdk.ArgCapability: "git.status"}, pluginsdk.CodeInvalidArgument},
+		{"not a repository", map[string]interface{}{pluginsdk.ArgCapability: "git.status", "dir": t.TempDir()}, pluginsdk.CodeNotFound},
+		{"limit too high", map[string]interface{}{pluginsdk.ArgCapability: "git.log", "dir": dir, "limit": float64(maxLogEntries + 1)}, pluginsdk.CodeInvalidArgument},
+		{"ref looking like a flag", map[string]interface{}{pluginsdk.ArgCapability: "git.log", "dir": dir, "ref": "--output=x"}, pluginsdk.CodeInvalidArgument},
+		{"unknown ref", map[string]interface{}{pluginsdk.ArgCapability: "git.log", "dir": dir, "ref": "nope"}, pluginsdk.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := (&GitInfoPlugin{}).Execute(tt.args)
+			if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != tt.want {
+				t.Errorf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func decode(t *testing.T, out string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		t.Fatalf("result %q: %v", out, err)
+	}
+}
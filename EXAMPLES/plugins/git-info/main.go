@@ -0,0 +1,10 @@
+// Package main is the entry point for the git-info plugin
+package main
+
+import (
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func main() {
+	pluginsdk.Serve(&GitInfoPlugin{})
+}
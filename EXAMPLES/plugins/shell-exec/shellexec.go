@@ -0,0 +1,254 @@
+// Package main implements the shell-exec plugin, which runs commands the
+// host config allows
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// defaultMaxOutput caps what is kept of stdout and stderr each when the
+// config sets no max_output
+const defaultMaxOutput = 1 << 20
+
+// ShellExecPlugin runs commands without a shell, so arguments are never
+// interpreted. Only commands the plugin's config allows may run:
+//
+//	plugin_config: {shell-exec: {allow: [git, go, "npm"], max_output: 65536}}
+//
+// Entries are command names or path patterns such as "/usr/bin/*"; without
+// any, every command is refused.
+type ShellExecPlugin struct {
+	mu        sync.RWMutex
+	allow     []string
+	maxOutput int
+}
+
+// execResult is the result of shell.exec
+type execResult struct {
+	ExitCode  int    `json:"exit_code"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// Name returns the plugin's unique identifier
+func (p *ShellExecPlugin) Name() string {
+	return "shell-exec"
+}
+
+// Version returns the plugin's version
+func (p *ShellExecPlugin) Version() string {
+	return pluginsdk.VersionOr("1.0.0")
+}
+
+// Initialize reads the allowlist from the plugin's entry in plugin_config
+func (p *ShellExecPlugin) Initialize(config map[string]interface{}) error {
+	allow, err := stringList(config["allow"])
+	if err != nil {
+		return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "allow: %v", err)
+	}
+	for _, pattern := range allow {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "allow: invalid pattern %q", pattern)
+		}
+	}
+	maxOutput := defaultMaxOutput
+	if v, ok := config["max_output"].(float64); ok && v > 0 {
+		maxOutput = int(v)
+	}
+
+	p.mu.Lock()
+	p.allow, p.maxOutput = allow, maxOutput
+	p.mu.Unlock()
+	return nil
+}
+
+// HandleEvent picks up allowlist changes without a restart
+func (p *ShellExecPlugin) HandleEvent(e pluginsdk.Event) error {
+	change, ok := pluginsdk.ConfigChangeOf(e)
+	if !ok || change.Config == nil {
+		return nil
+	}
+	return p.Initialize(change.Config)
+}
+
+// Execute runs the command of a shell.exec call and reports its exit code
+// and output; a command failing is not a plugin error
+func (p *ShellExecPlugin) Execute(args map[string]interface{}) (string, error) {
+	name, argv, err := p.command(args)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.RLock()
+	maxOutput := p.maxOutput
+	p.mu.RUnlock()
+	if maxOutput == 0 {
+		maxOutput = defaultMaxOutput
+	}
+
+	cmd := pluginsdk.Command(args, name, argv...)
+	stdout := &limitedBuffer{max: maxOutput}
+	stderr := &limitedBuffer{max: maxOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if stdin, ok := args["stdin"].(string); ok {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeNotFound, "cannot run %s: %v", name, err)
+	}
+
+	// Kill the command when the host cancels the call
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-pluginsdk.CallContext(args).Done():
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	res := execResult{}
+	if err := cmd.Wait(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok || exitErr.ExitCode() < 0 {
+			if ctx := pluginsdk.CallContext(args); ctx.Err() != nil {
+				return "", context.Cause(ctx)
+			}
+			return "", pluginsdk.NewError(pluginsdk.CodeInternal, "%s failed: %v", name, err)
+		}
+		res.ExitCode = exitErr.ExitCode()
+	}
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	res.Truncated = stdout.truncated || stderr.truncated
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Plan says which command Execute would run
+func (p *ShellExecPlugin) Plan(args map[string]interface{}) (string, error) {
+	name, argv, err := p.command(args)
+	if err != nil {
+		return "", err
+	}
+	plan := "Would run " + strings.Join(append([]string{name}, argv...), " ")
+	if dir := pluginsdk.WorkDir(args); dir != "" {
+		plan += " in " + dir
+	}
+	return plan + ".", nil
+}
+
+// command returns the command and arguments of a call, refusing commands
+// the allowlist does not match
+func (p *ShellExecPlugin) command(args map[string]interface{}) (string, []string, error) {
+	name, _ := args["command"].(string)
+	if name == "" {
+		return "", nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "command is required")
+	}
+	argv, err := stringList(args["args"])
+	if err != nil {
+		return "", nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "args: %v", err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, pattern := range p.allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return name, argv, nil
+		}
+	}
+	return "", nil, pluginsdk.NewError(pluginsdk.CodePermissionDenied, "command %q is not in the allowlist of plugin shell-exec", name)
+}
+
+// GetCapabilities describes the capabilities this plugin provides
+func (p *ShellExecPlugin) GetCapabilities() []pluginsdk.Capability {
+	return []pluginsdk.Capability{
+		{
+			Name:        "shell.exec",
+			Description: "Run an allowlisted command without a shell",
+			ArgsSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"command"},
+				"properties": map[string]interface{}{
+					"command": map[string]interface{}{"type": "string", "description": "Command name, e.g. git"},
+					"args":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+					"stdin":   map[string]interface{}{"type": "string", "description": "Text written to the command's input"},
+				},
+			},
+			Example: map[string]interface{}{"command": "git", "args": []interface{}{"status", "--short"}},
+			ResultSchema: map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"exit_code", "stdout", "stderr"},
+				"properties": map[string]interface{}{
+					"exit_code": map[string]interface{}{"type": "integer"},
+					"stdout":    map[string]interface{}{"type": "string"},
+					"stderr":    map[string]interface{}{"type": "string"},
+					"truncated": map[string]interface{}{"type": "boolean"},
+				},
+			},
+			Tags:    []string{"shell", "exec"},
+			Cost:    pluginsdk.CostMedium,
+			Formats: []string{pluginsdk.FormatJSON},
+		},
+	}
+}
+
+// limitedBuffer keeps the first max bytes written to it. The buffer is not
+// embedded, as its ReadFrom would let io.Copy write past max.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(data) > room {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(data[:room])
+		}
+		return len(data), nil
+	}
+	return b.buf.Write(data)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// stringList reads a list of strings from a config or argument value
+func stringList(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected strings, got %T", item)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("expected a list of strings, got %T", v)
+	}
+}
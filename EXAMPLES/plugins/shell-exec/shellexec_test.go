@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   map[string]interface{}
+		args     map[string]interface{}
+		wantCode pluginsdk.ErrorCode
+		want     execResult
+	}{
+		{
+			name:     "no allowlist",
+			config:   map[string]interface{}{},
+			args:     map[string]interface{}{"command": "echo"},
+			wantCode: pluginsdk.CodePermissionDenied,
+		},
+		{
+			name:     "not allowlisted",
+			config:   map[string]interface{}{"allow": []interface{}{"echo"}},
+			args:     map[string]interface{}{"command": "rm", "args": []interface{}{"-rf", "x"}},
+			wantCode: pluginsdk.CodePermissionDenied,
+		},
+		{
+			name:     "missing command",
+			config:   map[string]interface{}{"allow": []interface{}{"*"}},
+			args:     map[string]interface{}{},
+			wantCode: pluginsdk.CodeInvalidArgument,
+		},
+		{
+			name:   "allowlisted name",
+			config: map[string]interface{}{"allow": []interface{}{"echo"}},
+			args:   map[string]interface{}{"command": "echo", "args": []interface{}{"hello", "$HOME"}},
+			want:   execResult{Stdout: "hello $HOME\n"},
+		},
+		{
+			name:   "glob pattern",
+			config: map[string]interface{}{"allow": []interface{}{"/bin/*"}},
+			args:   map[string]interface{}{"command": "/bin/echo", "args": []interface{}{"hi"}},
+			want:   execResult{Stdout: "hi\n"},
+		},
+		{
+			name:     "glob pattern does not match bare names",
+			config:   map[string]interface{}{"allow": []interface{}{"/bin/*"}},
+			args:     map[string]interface{}{"command": "echo"},
+			wantCode: pluginsdk.CodePermissionDenied,
+		},
+		{
+			name:     "glob pattern does not match subdirectories",
+			config:   map[string]interface{}{"allow": []interface{}{"/usr/*"}},
+			args:     map[string]interface{}{"command": "/usr/bin/echo"},
+			wantCode: pluginsdk.CodePermissionDenied,
+		},
+		{
+			name:   "stdin",
+			config: map[string]interface{}{"allow": []interface{}{"cat"}},
+			args:   map[string]interface{}{"command": "cat", "stdin": "from stdin"},
+			want:   execResult{Stdout: "from stdin"},
+		},
+		{
+			name:   "output truncated at max_output",
+			config: map[string]interface{}{"allow": []interface{}{"echo"}, "max_output": float64(5)},
+			args:   map[string]interface{}{"command": "echo", "args": []interface{}{"hello world"}},
+			want:   execResult{Stdout: "hello", Truncated: true},
+		},
+		{
+			name:   "non-zero exit code",
+			config: map[string]interface{}{"allow": []interface{}{"sh"}},
+			args:   map[string]interface{}{"command": "sh", "args": []interface{}{"-c", "echo oops >&2; exit 3"}},
+			want:   execResult{ExitCode: 3, Stderr: "oops\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &ShellExecPlugin{}
+			if err := p.Initialize(tt.config); err != nil {
+				t.Fatal(err)
+			}
+			out, err := p.Execute(tt.args)
+			if tt.wantCode != "" {
+				if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != tt.wantCode {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got execResult
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("result %q: %v", out, err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitializeRejectsInvalidPatterns(t *testing.T) {
+	p := &ShellExecPlugin{}
+	err := p.Initialize(map[string]interface{}{"allow": []interface{}{"[git"}})
+	if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != pluginsdk.CodeInvalidArgument {
+		t.Errorf("error = %v, want %s", err, pluginsdk.CodeInvalidArgument)
+	}
+}
@@ -0,0 +1,199 @@
+// Package main implements the template-renderer plugin, which renders Go
+// text templates for other plugins and callers
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// maxOutput caps the size of a rendered template, so a template looping
+// over large data cannot exhaust the plugin's memory
+const maxOutput = 4 << 20
+
+// TemplateRendererPlugin renders the template a call passes inline under
+// "template", or the host template "name" through the host services,
+// with "data" as the dot. With "strict", missing keys are errors rather
+// than "<no value>".
+type TemplateRendererPlugin struct {
+	host pluginsdk.HostServices
+}
+
+// Name returns the plugin's unique identifier
+func (p *TemplateRendererPlugin) Name() string {
+	return "template-renderer"
+}
+
+// Version returns the plugin's version
+func (p *TemplateRendererPlugin) Version() string {
+	return pluginsdk.VersionOr("1.0.0")
+}
+
+// SetHostServices keeps the host's services for rendering host templates
+func (p *TemplateRendererPlugin) SetHostServices(host pluginsdk.HostServices) {
+	p.host = host
+}
+
+// Execute renders or checks the template of a call
+func (p *TemplateRendererPlugin) Execute(args map[string]interface{}) (string, error) {
+	switch capability, _ := args[pluginsdk.ArgCapability].(string); capability {
+	case "template.render", "":
+		return p.render(args)
+	case "template.check":
+		return check(args)
+	default:
+		return "", pluginsdk.NewError(pluginsdk.CodeUnsupported, "unknown capability %q", capability)
+	}
+}
+
+func (p *TemplateRendererPlugin) render(args map[string]interface{}) (string, error) {
+	data, _ := args["data"].(map[string]interface{})
+	if name, _ := args["name"].(string); name != "" {
+		if p.host == nil {
+			return "", pluginsdk.NewError(pluginsdk.CodeUnsupported, "host does not offer templates")
+		}
+		return p.host.RenderTemplate(name, data)
+	}
+
+	tmpl, err := parse(args)
+	if err != nil {
+		return "", err
+	}
+	out := &limitedWriter{}
+	if err := tmpl.Execute(out, data); err != nil {
+		if out.full {
+			return "", pluginsdk.NewError(pluginsdk.CodeTooLarge, "rendered template exceeds %d bytes", maxOutput)
+		}
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "%v", err)
+	}
+	return out.String(), nil
+}
+
+// check parses an inline template and reports the templates it defines
+func check(args map[string]interface{}) (string, error) {
+	tmpl, err := parse(args)
+	if err != nil {
+		return "", err
+	}
+	var defined []string
+	for _, t := range tmpl.Templates() {
+		if t.Name() != tmpl.Name() {
+			defined = append(defined, t.Name())
+		}
+	}
+	result, err := json.Marshal(map[string]interface{}{"ok": true, "defines": defined})
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// parse parses the inline template of a call
+func parse(args map[string]interface{}) (*template.Template, error) {
+	text, ok := args["template"].(string)
+	if !ok {
+		return nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "template or name is required")
+	}
+	tmpl := template.New("template").Funcs(funcs)
+	if strict, _ := args["strict"].(bool); strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(text)
+	if err != nil {
+		return nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "%v", err)
+	}
+	return tmpl, nil
+}
+
+// funcs are the functions templates can use besides the built-in ones,
+// the same the host offers its own templates where they overlap
+var funcs = template.FuncMap{
+	"default": func(fallback, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"join": func(sep string, items interface{}) string {
+		switch items := items.(type) {
+		case []string:
+			return strings.Join(items, sep)
+		case []interface{}:
+			parts := make([]string, len(items))
+			for i, item := range items {
+				parts[i] = fmt.Sprint(item)
+			}
+			return strings.Join(parts, sep)
+		}
+		return fmt.Sprint(items)
+	},
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+	},
+	"json": func(v interface{}) (string, error) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		return string(data), err
+	},
+}
+
+// limitedWriter fails writes beyond maxOutput
+type limitedWriter struct {
+	bytes.Buffer
+	full bool
+}
+
+func (w *limitedWriter) Write(data []byte) (int, error) {
+	if w.Len()+len(data) > maxOutput {
+		w.full = true
+		return 0, fmt.Errorf("output limit reached")
+	}
+	return w.Buffer.Write(data)
+}
+
+// GetCapabilities describes the capabilities this plugin provides
+func (p *TemplateRendererPlugin) GetCapabilities() []pluginsdk.Capability {
+	templateArg := map[string]interface{}{"type": "string", "description": "Go text/template source"}
+	return []pluginsdk.Capability{
+		{
+			Name:        "template.render",
+			Description: "Render an inline Go template or a host template with data",
+			ArgsSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"template": templateArg,
+					"name":     map[string]interface{}{"type": "string", "description": "Host template, e.g. commands/greet"},
+					"data":     map[string]interface{}{"type": "object"},
+					"strict":   map[string]interface{}{"type": "boolean", "description": "Fail on missing keys"},
+				},
+			},
+			Example:    map[string]interface{}{"template": "Hello {{ .name | upper }}!", "data": map[string]interface{}{"name": "ada"}},
+			Tags:       []string{"template", "text"},
+			Idempotent: true,
+			Cost:       pluginsdk.CostCheap,
+			Latency:    "10ms",
+		},
+		{
+			Name:        "template.check",
+			Description: "Check that an inline Go template parses",
+			ArgsSchema: map[string]interface{}{
+				"type":       "object",
+				"required":   []interface{}{"template"},
+				"properties": map[string]interface{}{"template": templateArg},
+			},
+			Tags:       []string{"template"},
+			Idempotent: true,
+			Cost:       pluginsdk.CostCheap,
+			Latency:    "10ms",
+			Formats:    []string{pluginsdk.FormatJSON},
+		},
+	}
+}
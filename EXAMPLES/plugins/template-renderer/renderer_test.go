@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// fakeHost renders host templates by name. Methods the plugin does not
+// use are left to the nil interface it embeds.
+type fakeHost struct {
+	pluginsdk.HostServices
+}
+
+func (h *fakeHost) RenderTemplate(name string, data map[string]interface{}) (string, error) {
+	if name != "commands/greet" {
+		return "", pluginsdk.NewError(pluginsdk.CodeNotFound, "no template %s", name)
+	}
+	return fmt.Sprintf("host says hello %v", data["name"]), nil
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		host     pluginsdk.HostServices
+		args     map[string]interface{}
+		want     string
+		wantCode pluginsdk.ErrorCode
+	}{
+		{
+			name: "inline template",
+			args: map[string]interface{}{"template": "Hello {{ .name | upper }}!", "data": map[string]interface{}{"name": "ada"}},
+			want: "Hello ADA!",
+		},
+		{
+			name: "inline template with functions",
+			args: map[string]interface{}{"template": `{{ join ", " .items }} {{ default "none" .missing }}`, "data": map[string]interface{}{"items": []interface{}{"a", 1}}},
+			want: "a, 1 none",
+		},
+		{
+			name: "missing key",
+			args: map[string]interface{}{"template": "{{ .missing }}"},
+			want: "<no value>",
+		},
+		{
+			name:     "missing key in strict mode",
+			args:     map[string]interface{}{"template": "{{ .missing }}", "strict": true, "data": map[string]interface{}{}},
+			wantCode: pluginsdk.CodeInvalidArgument,
+		},
+		{
+			name:     "parse error",
+			args:     map[string]interface{}{"template": "Hello {{ .name"},
+			wantCode: pluginsdk.CodeInvalidArgument,
+		},
+		{
+			name:     "neither template nor name",
+			args:     map[string]interface{}{},
+			wantCode: pluginsdk.CodeInvalidArgument,
+		},
+		{
+			name: "host template",
+			host: &fakeHost{},
+			args: map[string]interface{}{"name": "commands/greet", "data": map[string]interface{}{"name": "ada"}},
+			want: "host says hello ada",
+		},
+		{
+			name: "host template wins over inline",
+			host: &fakeHost{},
+			args: map[string]interface{}{"name": "commands/greet", "template": "inline", "data": map[string]interface{}{"name": "ada"}},
+			want: "host says hello ada",
+		},
+		{
+			name:     "unknown host template",
+			host:     &fakeHost{},
+			args:     map[string]interface{}{"name": "commands/missing"},
+			wantCode: pluginsdk.CodeNotFound,
+		},
+		{
+			name:     "host template without host",
+			args:     map[string]interface{}{"name": "commands/greet"},
+			wantCode: pluginsdk.CodeUnsupported,
+		},
+		{
+			name:     "output too large",
+			args:     map[string]interface{}{"template": `{{ range .items }}{{ $.chunk }}{{ end }}`, "data": map[string]interface{}{"items": make([]interface{}, 5), "chunk": strings.Repeat("x", maxOutput/4)}},
+			wantCode: pluginsdk.CodeTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &TemplateRendererPlugin{}
+			if tt.host != nil {
+				p.SetHostServices(tt.host)
+			}
+			out, err := p.Execute(tt.args)
+			if tt.wantCode != "" {
+				if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != tt.wantCode {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Errorf("got %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantCode pluginsdk.ErrorCode
+	}{
+		{"plain", "Hello", `{"defines":null,"ok":true}`, ""},
+		{"defines", `{{ define "row" }}x{{ end }}{{ template "row" }}`, `{"defines":["row"],"ok":true}`, ""},
+		{"parse error", "{{ if }}", "", pluginsdk.CodeInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := (&TemplateRendererPlugin{}).Execute(map[string]interface{}{pluginsdk.ArgCapability: "template.check", "template": tt.template})
+			if tt.wantCode != "" {
+				if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != tt.wantCode {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Errorf("got %s, want %s", out, tt.want)
+			}
+		})
+	}
+}
@@ -4,10 +4,35 @@ package main
 import (
 	"fmt"
 	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
 )
 
+// messages are the greetings in the locales the manifest lists
+var messages = newMessages()
+
+func newMessages() *pluginsdk.Catalog {
+	c := pluginsdk.NewCatalog("en")
+	c.Add("en", map[string]string{
+		"formal":   "Greetings, %s. Welcome to the SuperClaude integration platform.",
+		"casual":   "Hey %s! Ready to enhance OpenCode with AI?",
+		"standard": "Hello %s from SuperClaude integration!",
+	})
+	c.Add("de", map[string]string{
+		"formal":   "Seien Sie gegrüßt, %s. Willkommen auf der SuperClaude-Integrationsplattform.",
+		"casual":   "Hey %s! Bereit, OpenCode mit KI aufzuwerten?",
+		"standard": "Hallo %s von der SuperClaude-Integration!",
+	})
+	return c
+}
+
 // HelloPlugin is a simple plugin that demonstrates the plugin architecture
-type HelloPlugin struct{}
+type HelloPlugin struct {
+	host pluginsdk.HostServices
+}
 
 // Name returns the plugin's unique identifier
 func (p *HelloPlugin) Name() string {
@@ -16,22 +41,32 @@ func (p *HelloPlugin) Name() string {
 
 // Version returns the plugin's version
 func (p *HelloPlugin) Version() string {
-	return "1.0.0"
+	return pluginsdk.VersionOr("1.0.0")
 }
 
 // Execute runs the plugin's main functionality
 func (p *HelloPlugin) Execute(args map[string]interface{}) (string, error) {
 	log.Println("[PLUGIN] Executing hello command")
 	
+	if args[pluginsdk.ArgCapability] == "greet.relay" {
+		return p.relay(args)
+	}
+	
 	// Extract name from args, with default
 	name := "World"
-	if n, ok := args["name"].(string); ok && n != "" {
-		name = n
+	if raw, present := args["name"]; present {
+		n, ok := raw.(string)
+		if !ok {
+			return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "name must be a string, got %T", raw)
+		}
+		if n != "" {
+			name = n
+		}
 	}
 	
-	// Extract greeting type
+	// Extract greeting type; greet@v1 predates greeting types
 	greetingType := "standard"
-	if t, ok := args["type"].(string); ok {
+	if t, ok := args["type"].(string); ok && args[pluginsdk.ArgCapabilityVersion] != "v1" {
 		greetingType = t
 	}
 	
@@ -39,42 +74,221 @@ func (p *HelloPlugin) Execute(args map[string]interface{}) (string, error) {
 	var response string
 	switch greetingType {
 	case "formal":
-		response = fmt.Sprintf("Greetings, %s. Welcome to the SuperClaude integration platform.", name)
+		response = messages.Localize(args, "formal", name)
 	case "casual":
-		response = fmt.Sprintf("Hey %s! Ready to enhance OpenCode with AI?", name)
+		response = messages.Localize(args, "casual", name)
 	case "technical":
 		response = fmt.Sprintf("Plugin 'hello' v%s initialized. Target: %s. Integration: operational.", p.Version(), name)
+		if dir := pluginsdk.WorkDir(args); dir != "" {
+			response += " Workspace: " + dir + "."
+		}
+	case "prompt":
+		// The prompt text lives in the host's templates directory
+		if p.host == nil {
+			return "", pluginsdk.NewError(pluginsdk.CodeUnsupported, "host does not offer templates")
+		}
+		prompt, err := p.host.RenderTemplate("commands/greet", map[string]interface{}{"name": name})
+		if err != nil {
+			return "", err
+		}
+		response = prompt
 	default:
-		response = fmt.Sprintf("Hello %s from SuperClaude integration!", name)
+		response = messages.Localize(args, "standard", name)
+	}
+	
+	// The host passes normalized SuperClaude flags as options; --uc asks
+	// for token-optimized output
+	if options, ok := args[pluginsdk.ArgOptions].(map[string]interface{}); ok && options["compressed"] == true {
+		response = fmt.Sprintf("hi %s", name)
 	}
 	
-	log.Printf("[PLUGIN] Generated response: %s", response)
+	// Debug output only shows once an operator switches the plugin to debug
+	pluginsdk.Logger().Debug("generated greeting", "type", greetingType, "response", response)
 	return response, nil
 }
 
-// GetCapabilities returns a list of capabilities this plugin provides
-func (p *HelloPlugin) GetCapabilities() []string {
-	return []string{
-		"greet",           // Basic greeting
-		"greet.formal",    // Formal greeting
-		"greet.casual",    // Casual greeting
-		"greet.technical", // Technical greeting
-		"plugin.info",     // Plugin information
+// relay asks another plugin for the greeting through the host
+func (p *HelloPlugin) relay(args map[string]interface{}) (string, error) {
+	to, _ := args["to"].(string)
+	if to == "" {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "to must name the plugin to relay to")
 	}
+	if p.host == nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeUnsupported, "host does not offer plugin calls")
+	}
+	// The callee joins the request this call belongs to, if any
+	callArgs := pluginsdk.WithContext(args, map[string]interface{}{"name": args["name"], "type": "casual"})
+	greeting, err := p.host.CallPlugin(to, "greet", callArgs)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s says: %s", to, greeting), nil
 }
 
-// Additional methods that could be added in a real plugin:
+// Plan describes what Execute would do for a dry run (--plan)
+func (p *HelloPlugin) Plan(args map[string]interface{}) (string, error) {
+	name, _ := args["name"].(string)
+	if name == "" {
+		name = "World"
+	}
+	greetingType, _ := args["type"].(string)
+	if greetingType == "" {
+		greetingType = "standard"
+	}
+	
+	plan := fmt.Sprintf("Would greet %s with a %s greeting", name, greetingType)
+	if greetingType == "prompt" {
+		plan += " rendered from the host template commands/greet"
+	}
+	return plan + ".", nil
+}
+
+// HandleEvent reacts to the editor events listed in the manifest
+func (p *HelloPlugin) HandleEvent(e pluginsdk.Event) error {
+	log.Printf("[PLUGIN] Noticed %s: %v", e.Type, e.Data["path"])
+	return nil
+}
 
-// Initialize would set up any resources the plugin needs
+// GetCapabilities describes the capabilities this plugin provides
+func (p *HelloPlugin) GetCapabilities() []pluginsdk.Capability {
+	nameArg := map[string]interface{}{
+		"type":        "string",
+		"description": "Who to greet",
+	}
+	greetSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": nameArg,
+			"type": map[string]interface{}{
+				"type": "string",
+				"enum": []interface{}{"standard", "formal", "casual", "technical", "prompt"},
+			},
+		},
+	}
+	
+	return []pluginsdk.Capability{
+		{
+			Name:        "greet",
+			Version:     "v2",
+			Description: "Greet someone in the requested style",
+			ArgsSchema:  greetSchema,
+			Example:     map[string]interface{}{"name": "Developer", "type": "casual"},
+			ResultSchema: map[string]interface{}{
+				"type":      "string",
+				"minLength": 1,
+			},
+			Tags:       []string{"greeting"},
+			Idempotent: true,
+		},
+		{
+			Name:        "greet",
+			Version:     "v1",
+			Description: "Greet someone",
+			Example:     map[string]interface{}{"name": "Developer"},
+			Tags:        []string{"greeting"},
+			Idempotent:  true,
+			Deprecated:  "use greet@v2, which also takes a greeting type",
+		},
+		{
+			Name:        "greet.formal",
+			Description: "Formal greeting",
+			Example:     map[string]interface{}{"name": "Enterprise User", "type": "formal"},
+			Tags:        []string{"greeting"},
+			Idempotent:  true,
+		},
+		{
+			Name:        "greet.casual",
+			Description: "Casual greeting",
+			Example:     map[string]interface{}{"name": "Coder", "type": "casual"},
+			Tags:        []string{"greeting"},
+			Idempotent:  true,
+		},
+		{
+			Name:        "greet.technical",
+			Description: "Technical greeting",
+			Example:     map[string]interface{}{"name": "System", "type": "technical"},
+			Tags:        []string{"greeting"},
+			Idempotent:  true,
+		},
+		{
+			Name:        "greet.prompt",
+			Description: "Greeting prompt rendered from the host's templates",
+			Example:     map[string]interface{}{"name": "Developer", "type": "prompt"},
+			Tags:        []string{"greeting", "prompt"},
+		},
+		{
+			Name:        "greet.relay",
+			Description: "Greeting fetched from another plugin through the host",
+			Example:     map[string]interface{}{"name": "Developer", "to": "hello-py"},
+			Tags:        []string{"greeting", "composition"},
+		},
+		{
+			Name:        "plugin.info",
+			Description: "Plugin information",
+			Tags:        []string{"meta"},
+		},
+	}
+}
+
+// SetHostServices keeps the host's services for rendering prompts and
+// relaying greetings
+func (p *HelloPlugin) SetHostServices(host pluginsdk.HostServices) {
+	p.host = host
+}
+
+// CacheTTLs lets the host cache greetings, which are deterministic
+func (p *HelloPlugin) CacheTTLs() map[string]time.Duration {
+	return map[string]time.Duration{
+		"greet": time.Minute,
+	}
+}
+
+// OpenSession starts a conversation that remembers how often it greeted
+func (p *HelloPlugin) OpenSession(id string) (pluginsdk.PluginSession, error) {
+	log.Printf("[PLUGIN] Opened session %s", id)
+	return &helloSession{plugin: p}, nil
+}
+
+// helloSession greets like Execute but keeps count across calls
+type helloSession struct {
+	plugin *HelloPlugin
+	calls  int
+}
+
+// Execute greets and mentions how many times this session has greeted before
+func (s *helloSession) Execute(args map[string]interface{}) (string, error) {
+	response, err := s.plugin.Execute(args)
+	if err != nil {
+		return "", err
+	}
+	s.calls++
+	if s.calls > 1 {
+		response = fmt.Sprintf("%s (greeting #%d in this session)", response, s.calls)
+	}
+	return response, nil
+}
+
+// Close ends the session
+func (s *helloSession) Close() error {
+	log.Printf("[PLUGIN] Closed session after %d greeting(s)", s.calls)
+	return nil
+}
+
+// Initialize receives the plugin's entry from the host's plugin_config,
+// with secret references already resolved. Only the keys are logged so
+// secrets stay out of the host's plugin logs.
 func (p *HelloPlugin) Initialize(config map[string]interface{}) error {
-	log.Println("[PLUGIN] Hello plugin initialized")
-	// In a real plugin, this might:
-	// - Connect to databases
-	// - Load configuration
-	// - Set up event listeners
+	keys := make([]string, 0, len(config))
+	for k := range config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	log.Printf("[PLUGIN] Hello plugin initialized with config keys: %s", strings.Join(keys, ", "))
 	return nil
 }
 
+// Additional methods that could be added in a real plugin:
+
 // Cleanup would clean up resources when the plugin shuts down
 func (p *HelloPlugin) Cleanup() error {
 	log.Println("[PLUGIN] Hello plugin shutting down")
@@ -2,22 +2,10 @@
 package main
 
 import (
-	"github.com/hashicorp/go-plugin"
-	"github.com/opencode-superclaude/examples/simple-plugin/shared"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
 )
 
 func main() {
-	// Create an instance of our plugin
-	helloPlugin := &HelloPlugin{}
-
-	// Serve the plugin
-	plugin.Serve(&plugin.ServeConfig{
-		HandshakeConfig: shared.Handshake,
-		Plugins: map[string]plugin.Plugin{
-			"command": &shared.CommandPluginImpl{Impl: helloPlugin},
-		},
-		
-		// A non-nil value here enables gRPC serving for this plugin
-		GRPCServer: plugin.DefaultGRPCServer,
-	})
-}
\ No newline at end of file
+	// Serve our plugin over gRPC or net/rpc, whichever the host asks for
+	pluginsdk.Serve(&HelloPlugin{})
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/hostapi"
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// defaultHost is the management API "superhost serve" listens on
+const defaultHost = "http://localhost:8080"
+
+// requestTimeout bounds requests to the host other than plugin calls and
+// followed logs, which take as long as they take
+const requestTimeout = 10 * time.Second
+
+// hostClient talks to the management API of a running host
+type hostClient struct {
+	base    string
+	token   string
+	timeout time.Duration
+}
+
+func newHostClient(base string) *hostClient {
+	return &hostClient{base: strings.TrimSuffix(base, "/"), token: hostapi.ClientToken(), timeout: requestTimeout}
+}
+
+// do sends a request with a JSON body, if any, and returns the response
+// once it answered with a 2xx status; otherwise it returns the error the
+// host answered with
+func (c *hostClient) do(ctx context.Context, method, path string, body interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := (&http.Client{Timeout: c.timeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the host at %s (is \"superhost serve\" running?): %w", c.base, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		var answer apiError
+		if json.Unmarshal(data, &answer) == nil && answer.Message != "" {
+			return nil, &answer
+		}
+		return nil, fmt.Errorf("host answered %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	return resp, nil
+}
+
+// apiError is an error the host answered with; Code is set for failed
+// plugin calls
+type apiError struct {
+	Message string              `json:"error"`
+	Code    pluginsdk.ErrorCode `json:"code,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	if e.Code != "" {
+		return string(e.Code) + ": " + e.Message
+	}
+	return e.Message
+}
+
+// call sends a request and decodes the JSON answer into v, unless v is nil
+func (c *hostClient) call(method, path string, body, v interface{}) error {
+	resp, err := c.do(context.Background(), method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("unexpected answer from the host: %w", err)
+	}
+	return nil
+}
+
+// argsFlag collects repeated -arg key=value flags. Values that are valid
+// JSON, such as 3, true or ["a","b"], are passed decoded; others as
+// strings.
+type argsFlag map[string]interface{}
+
+func (a argsFlag) String() string { return "" }
+
+func (a argsFlag) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("want key=value, got %q", s)
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+	a[key] = v
+	return nil
+}
+
+// execCommand runs a capability of a plugin, on the running host or with
+// -local in this process, and prints its result
+func execCommand(args []string) error {
+	fs := flag.NewFlagSet("exec", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: superhost exec [flags] <plugin> [capability]
+
+Runs a capability of a plugin and prints its result, e.g.
+"superhost exec hello greet -arg name=Ada -arg type=formal". Without a
+capability the plugin's default one runs.`)
+		fs.PrintDefaults()
+	}
+	callArgs := argsFlag{}
+	fs.Var(callArgs, "arg", "argument as key=value, repeatable; JSON values such as 3 or true are decoded")
+	argsJSON := fs.String("args", "", "arguments as a JSON object, merged under the -arg flags")
+	host := fs.String("host", defaultHost, "management API of the running host")
+	jsonFlag := fs.Bool("json", false, "print the whole answer as JSON")
+	local := fs.Bool("local", false, "run the plugin in this process from the config file instead of on the running host")
+	verbose := fs.Bool("v", false, "print the host log with -local")
+	hf := addHostFlags(fs)
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 || len(positional) > 2 {
+		fs.Usage()
+		return fmt.Errorf("want a plugin and optionally a capability")
+	}
+	name := positional[0]
+
+	callArgs, err = mergeArgs(*argsJSON, callArgs)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 2 {
+		callArgs[pluginsdk.ArgCapability] = positional[1]
+	}
+
+	var answer struct {
+		Result  string `json:"result"`
+		Format  string `json:"format,omitempty"`
+		Partial bool   `json:"partial,omitempty"`
+	}
+	if *local {
+		manager, err := hf.open(*verbose)
+		if err != nil {
+			return err
+		}
+		defer manager.Shutdown()
+		res, err := manager.Execute(name, callArgs, nil)
+		if err != nil {
+			return err
+		}
+		answer.Result = res.Result
+		if path, spilled := manager.ResultFile(res.Result); spilled {
+			data, err := os.ReadFile(path)
+			os.Remove(path)
+			if err != nil {
+				return fmt.Errorf("failed to read spilled result: %w", err)
+			}
+			answer.Result = string(data)
+		}
+		answer.Format, answer.Partial = manager.ResultFormat(name, callArgs), res.Partial
+	} else {
+		// A plugin call takes as long as its timeouts allow
+		c := newHostClient(*host)
+		c.timeout = 0
+		if err := c.call(http.MethodPost, "/plugins/"+url.PathEscape(name)+"/execute", map[string]interface{}{"args": callArgs}, &answer); err != nil {
+			return err
+		}
+	}
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(answer)
+	}
+	fmt.Println(strings.TrimSuffix(answer.Result, "\n"))
+	if answer.Partial {
+		fmt.Fprintln(os.Stderr, "(partial result: the latency budget ran out)")
+	}
+	return nil
+}
+
+// mergeArgs returns the arguments of the -args JSON object overridden by
+// the -arg flags
+func mergeArgs(argsJSON string, flags argsFlag) (argsFlag, error) {
+	merged := argsFlag{}
+	if argsJSON != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &merged); err != nil {
+			return nil, fmt.Errorf("-args must be a JSON object: %w", err)
+		}
+	}
+	for k, v := range flags {
+		merged[k] = v
+	}
+	return merged, nil
+}
+
+// list prints the plugins of the running host, or with -local those the
+// config file loads
+func list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: superhost list [flags]")
+		fs.PrintDefaults()
+	}
+	host := fs.String("host", defaultHost, "management API of the running host")
+	jsonFlag := fs.Bool("json", false, "print the full status of every plugin as JSON")
+	local := fs.Bool("local", false, "load the plugins of the config file in this process instead of asking the running host")
+	verbose := fs.Bool("v", false, "print the host log with -local")
+	hf := addHostFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var plugins []pluginhost.PluginStatus
+	if *local {
+		manager, err := hf.open(*verbose)
+		if err != nil {
+			return err
+		}
+		plugins = manager.ListPlugins()
+		manager.Shutdown()
+	} else if err := newHostClient(*host).call(http.MethodGet, "/plugins", nil, &plugins); err != nil {
+		return err
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plugins)
+	}
+	if len(plugins) == 0 {
+		fmt.Fprintln(os.Stderr, "No plugins loaded")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVERSION\tSTATE\tCALLS\tCAPABILITIES")
+	for _, p := range plugins {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", p.Name, p.Version, p.State, p.Calls, strings.Join(pluginsdk.CapabilityNames(p.Capabilities), ","))
+	}
+	return tw.Flush()
+}
+
+// reload restarts plugins of the running host from their binaries, e.g.
+// after rebuilding one
+func reload(args []string) error {
+	fs := flag.NewFlagSet("reload", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: superhost reload [flags] <plugin>...")
+		fs.PrintDefaults()
+	}
+	host := fs.String("host", defaultHost, "management API of the running host")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing plugin name")
+	}
+
+	c := newHostClient(*host)
+	// Restarting waits for the calls running to finish
+	c.timeout = 0
+	failed := 0
+	for _, name := range fs.Args() {
+		var st pluginhost.PluginStatus
+		if err := c.call(http.MethodPost, "/plugins/"+url.PathEscape(name)+"/reload", nil, &st); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: reloaded v%s [%s]\n", st.Name, st.Version, st.State)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d plugin(s) failed to reload", failed, fs.NArg())
+	}
+	return nil
+}
+
+// logs prints the recent log of a plugin of the running host and, with
+// -f, the entries that follow until interrupted
+func logs(args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: superhost logs [flags] <plugin>")
+		fs.PrintDefaults()
+	}
+	host := fs.String("host", defaultHost, "management API of the running host")
+	follow := fs.Bool("f", false, "keep printing new entries until interrupted")
+	level := fs.String("level", "", "print only entries at this level or above: trace, debug, info, warn or error")
+	jsonFlag := fs.Bool("json", false, "print the entries as JSON lines")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return fmt.Errorf("want one plugin")
+	}
+
+	q := url.Values{}
+	if *level != "" {
+		q.Set("level", *level)
+	}
+	c := newHostClient(*host)
+	if *follow {
+		q.Set("follow", "true")
+		c.timeout = 0
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	resp, err := c.do(ctx, http.MethodGet, "/plugins/"+url.PathEscape(positional[0])+"/logs?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if *jsonFlag {
+			fmt.Println(scanner.Text())
+			continue
+		}
+		var e pluginhost.LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return fmt.Errorf("unexpected answer from the host: %w", err)
+		}
+		fmt.Println(formatLogEntry(e))
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// logLevel switches the level a plugin of the running host logs at, e.g.
+// to debug while looking into it, without restarting anything
+func logLevel(args []string) error {
+	fs := flag.NewFlagSet("log-level", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: superhost log-level [flags] <plugin> <level>
+       superhost log-level [flags] -reset <plugin>
+
+The level is trace, debug, info, warn or error. -reset lets the plugin log
+at its own level again.`)
+		fs.PrintDefaults()
+	}
+	host := fs.String("host", defaultHost, "management API of the running host")
+	reset := fs.Bool("reset", false, "let the plugin log at its own level again")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if (*reset && len(positional) != 1) || (!*reset && len(positional) != 2) {
+		fs.Usage()
+		return fmt.Errorf("want a plugin and a level, or -reset and a plugin")
+	}
+
+	path := "/plugins/" + url.PathEscape(positional[0]) + "/log-level"
+	var st pluginhost.PluginStatus
+	if *reset {
+		err = newHostClient(*host).call(http.MethodDelete, path, nil, &st)
+	} else {
+		err = newHostClient(*host).call(http.MethodPut, path, map[string]string{"level": positional[1]}, &st)
+	}
+	if err != nil {
+		return err
+	}
+	if st.LogLevel == "" {
+		fmt.Printf("%s: logging at its own level\n", st.Name)
+	} else {
+		fmt.Printf("%s: logging at %s\n", st.Name, st.LogLevel)
+	}
+	return nil
+}
+
+// formatLogEntry renders e as one line: time, level, message and its
+// fields sorted by key
+func formatLogEntry(e pluginhost.LogEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", e.Time.Local().Format("15:04:05.000"), strings.ToUpper(e.Level), e.Message)
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Fields[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// defaultConfigPath is the config file the host reads unless -config names
+// another; host.json is still read when there is no host.yaml
+func defaultConfigPath() string {
+	if _, err := os.Stat("host.yaml"); errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat("host.json"); err == nil {
+			return "host.json"
+		}
+	}
+	return "host.yaml"
+}
+
+// configCommand checks config files or prints their schema, so they can be
+// verified before a running host picks them up
+func configCommand(args []string) error {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: superhost config validate [-config file] [file...]")
+		fmt.Fprintln(fs.Output(), "       superhost config schema")
+		fs.PrintDefaults()
+	}
+	configPath := fs.String("config", defaultConfigPath(), "config file to validate")
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing validate or schema")
+	}
+	action := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "validate":
+		paths := fs.Args()
+		if len(paths) == 0 {
+			paths = []string{*configPath}
+		}
+		failed := 0
+		for _, path := range paths {
+			if !validateConfig(path) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d config file(s) invalid", failed, len(paths))
+		}
+		return nil
+	case "schema":
+		data, err := json.MarshalIndent(pluginhost.ConfigSchema(), "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// validateConfig prints every problem of the config file at path, one per
+// line as file:line:column: message, and reports whether there were none
+func validateConfig(path string) bool {
+	_, err := pluginhost.LoadConfig(path)
+	var invalid *pluginhost.ConfigFileError
+	switch {
+	case err == nil:
+		fmt.Printf("%s: ok\n", path)
+		return true
+	case errors.As(err, &invalid):
+		for _, p := range invalid.Problems {
+			fmt.Printf("%s:%s\n", path, p)
+		}
+	default:
+		fmt.Printf("%s: %v\n", path, err)
+	}
+	return false
+}
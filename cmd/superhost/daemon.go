@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/Kirchlive/super/pkg/hostdaemon"
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// defaultServiceName is the name the host is installed under
+const defaultServiceName = "opencode-host"
+
+// shutdownTimeout bounds how long the management API gets to finish its requests when
+// the daemon stops
+const shutdownTimeout = 10 * time.Second
+
+// daemon is what serve needs to run, reload and stop the host
+type daemon struct {
+	manager    *pluginhost.PluginManager
+	configPath string
+	override   func(cfg *pluginhost.HostConfig)
+	server     *http.Server
+	grpcServer *grpc.Server
+	logFile    *hostdaemon.LogFile
+}
+
+// run serves until a stop signal or the service manager stops the host,
+// then shuts it down cleanly
+func (d *daemon) run(pidFile string) error {
+	if pidFile != "" {
+		if err := hostdaemon.WritePIDFile(pidFile); err != nil {
+			return err
+		}
+		defer hostdaemon.RemovePIDFile(pidFile)
+	}
+
+	log.Printf("Host running with %d plugin(s)", len(d.manager.ListPlugins()))
+	err := hostdaemon.Run(defaultServiceName, d.reload)
+
+	log.Println("Shutting down plugin system...")
+	if d.server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := d.server.Shutdown(ctx); err != nil {
+			log.Printf("Failed to stop the management API: %v", err)
+		}
+	}
+	if d.grpcServer != nil {
+		d.grpcServer.GracefulStop()
+	}
+	d.manager.Shutdown()
+	return err
+}
+
+// reload reopens the log file for logrotate and applies the config file
+func (d *daemon) reload() {
+	if d.logFile != nil {
+		if err := d.logFile.Reopen(); err != nil {
+			log.Printf("Failed to reopen log file: %v", err)
+		}
+	}
+	cfg, err := pluginhost.LoadConfig(d.configPath)
+	if err != nil {
+		log.Printf("Failed to reload config: %v", err)
+		return
+	}
+	d.override(cfg)
+	if err := d.manager.ApplyConfig(cfg); err != nil {
+		log.Printf("Failed to apply config: %v", err)
+		return
+	}
+	log.Printf("Reloaded config from %s", d.configPath)
+}
+
+// serviceCommand installs or removes the host as a systemd or Windows
+// service. Flags after the -- separator are passed to serve, e.g.
+// "service install -- -http :8080".
+func serviceCommand(args []string) error {
+	fs := flag.NewFlagSet("service", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: superhost service install|uninstall [flags] [-- serve flags]")
+		fs.PrintDefaults()
+	}
+	name := fs.String("name", defaultServiceName, "name of the service")
+	configPath := fs.String("config", defaultConfigPath(), "config file the service runs with")
+	user := fs.String("user", "", "account the service runs as (default root or LocalSystem)")
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing install or uninstall")
+	}
+	action := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch action {
+	case "install":
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		config, err := filepath.Abs(*configPath)
+		if err != nil {
+			return err
+		}
+		// Plugin and template directories are relative to the config
+		serviceArgs := append([]string{"serve", "-dir", filepath.Dir(config), "-config", config}, fs.Args()...)
+		err = hostdaemon.Install(hostdaemon.Service{
+			Name:        *name,
+			Description: "OpenCode plugin host",
+			Executable:  exe,
+			Args:        serviceArgs,
+			User:        *user,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Installed service %s running %s with %s\n", *name, exe, config)
+	case "uninstall":
+		if err := hostdaemon.Uninstall(*name); err != nil {
+			return err
+		}
+		fmt.Printf("Uninstalled service %s\n", *name)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown service action %q", action)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// hostFlags are the flags of the commands that start a host from the
+// config file
+type hostFlags struct {
+	config    *string
+	profile   *string
+	dir       *string
+	llmRecord *string
+	llmReplay *string
+}
+
+// addHostFlags defines the host flags on fs
+func addHostFlags(fs *flag.FlagSet) *hostFlags {
+	return &hostFlags{
+		config:    fs.String("config", "", "path to the host config file, YAML or JSON (default host.yaml, or host.json when there is none)"),
+		profile:   fs.String("profile", "", "plugin profile to run, overriding the config file"),
+		dir:       fs.String("dir", "", "change to this directory before anything else"),
+		llmRecord: fs.String("llm-record", "", "record the completions of the LLM service to this cassette"),
+		llmReplay: fs.String("llm-replay", "", "answer LLM requests from this cassette instead of the providers"),
+	}
+}
+
+// configPath returns the config file to read. It is resolved after -dir
+// changed the directory, so the default is looked up there.
+func (f *hostFlags) configPath() string {
+	if *f.config != "" {
+		return *f.config
+	}
+	return defaultConfigPath()
+}
+
+// load changes to -dir and returns the config file with the flags
+// applied; without a config file it returns the defaults
+func (f *hostFlags) load() (*pluginhost.HostConfig, error) {
+	if *f.dir != "" {
+		if err := os.Chdir(*f.dir); err != nil {
+			return nil, fmt.Errorf("failed to change directory: %w", err)
+		}
+	}
+	if *f.llmRecord != "" && *f.llmReplay != "" {
+		return nil, fmt.Errorf("-llm-record and -llm-replay cannot be combined")
+	}
+	cfg := pluginhost.DefaultConfig()
+	if _, err := os.Stat(f.configPath()); err == nil {
+		if cfg, err = pluginhost.LoadConfig(f.configPath()); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+	f.override(cfg)
+	return cfg, nil
+}
+
+// override applies the flags that take precedence over the config file,
+// also when it is edited while the host runs
+func (f *hostFlags) override(cfg *pluginhost.HostConfig) {
+	if *f.profile != "" {
+		cfg.Profile = *f.profile
+	}
+	if *f.llmRecord != "" {
+		cfg.LLM.Cassette = pluginhost.LLMCassetteConfig{Mode: pluginhost.CassetteRecord, Path: *f.llmRecord}
+	}
+	if *f.llmReplay != "" {
+		cfg.LLM.Cassette = pluginhost.LLMCassetteConfig{Mode: pluginhost.CassetteReplay, Path: *f.llmReplay}
+	}
+}
+
+// open starts a host for a command that runs once. Its log is dropped
+// unless verbose, so only the command's output is printed.
+func (f *hostFlags) open(verbose bool) (*pluginhost.PluginManager, error) {
+	cfg, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	return f.openConfig(cfg, verbose)
+}
+
+// openConfig is open with a config already loaded
+func (f *hostFlags) openConfig(cfg *pluginhost.HostConfig, verbose bool) (*pluginhost.PluginManager, error) {
+	var opts []pluginhost.Option
+	if !verbose {
+		opts = append(opts, pluginhost.WithLogger(log.New(io.Discard, "", 0)), pluginhost.WithLogOutput(io.Discard))
+	}
+	manager, err := pluginhost.New(append(opts, pluginhost.WithConfig(cfg))...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin manager: %w", err)
+	}
+	return manager, nil
+}
+
+// parseInterspersed parses args with fs, allowing flags after the
+// positional arguments as in "exec hello greet -arg name=Ada", and returns
+// the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// "--" ends the flags for good
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
@@ -0,0 +1,71 @@
+// Command superhost runs the plugin host and operates a running one
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// commands maps each subcommand to the function running it with the
+// remaining arguments
+var commands = map[string]func(args []string) error{
+	"serve":     serve,
+	"exec":      execCommand,
+	"list":      list,
+	"reload":    reload,
+	"logs":      logs,
+	"log-level": logLevel,
+	"verify":    verify,
+	"doctor":    doctor,
+	"replay":    replay,
+	"update":    update,
+	"lock":      lock,
+	"bundle":    bundle,
+	"cache":     cache,
+	"config":    configCommand,
+	"service":   serviceCommand,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: superhost <command> [flags]
+
+Commands:
+  serve     run the host with its management API until it is stopped
+  exec      run a capability of a plugin and print the result
+  list      list the plugins with their state and capabilities
+  reload    restart plugins from their binaries
+  logs      print or follow the log of a plugin
+  log-level switch the level a plugin logs at while it runs
+  verify    check every plugin against its capability examples and schemas
+  doctor    check whether the host can run each plugin and how to fix it
+  replay    re-run the calls of a trace file and report differing results
+  update    update every updatable plugin from the registry index
+  lock      pin the discovered plugin binaries in a lockfile
+  bundle    pack a plugin into an offline bundle or install one
+  cache     list or garbage-collect the cache of downloaded plugins
+  config    validate config files or print their schema
+  service   install or remove the host as a system service
+
+exec, list, reload, logs and log-level talk to a host running "superhost serve";
+exec and list run the plugins themselves with -local. Run
+"superhost <command> -h" for the flags of a command.`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "superhost: unknown command %q\n", os.Args[1])
+		}
+		usage()
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "superhost %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/Kirchlive/super/pkg/hostapi"
+	"github.com/Kirchlive/super/pkg/hostdaemon"
+	"github.com/Kirchlive/super/pkg/hostgrpc"
+	"github.com/Kirchlive/super/pkg/hosttui"
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// serve runs the host with its management API until SIGINT or SIGTERM, or
+// until the service manager stops it; SIGHUP reloads the config
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: superhost serve [flags]
+
+Loads the plugins of the config file and serves them until stopped. Edits
+to the config file are applied while running.`)
+		fs.PrintDefaults()
+	}
+	hf := addHostFlags(fs)
+	httpAddr := fs.String("http", "localhost:8080", "serve the management API on this address; empty disables it")
+	tokenFile := fs.String("token-file", hostapi.DefaultTokenFile(), "write the API key of the management API's operator to this file, which the other commands read")
+	grpcAddr := fs.String("grpc", "", "serve the capability gateway on this address, e.g. :9090")
+	grpcCert := fs.String("grpc-cert", "", "serve -grpc with TLS using this certificate (PEM)")
+	grpcKey := fs.String("grpc-key", "", "private key of -grpc-cert (PEM)")
+	tui := fs.Bool("tui", false, "show the interactive dashboard; logs go to host.log")
+	trace := fs.String("trace", "", "record every call with its full arguments and result to this trace file")
+	halt := fs.String("halt", "", "start with plugin execution halted for this reason, e.g. during an incident")
+	pidFile := fs.String("pid-file", "", "write the process ID to this file while running")
+	logPath := fs.String("log-file", "", "write logs to this file instead of stderr")
+	logMaxSize := fs.Int64("log-max-size", 100, "rotate -log-file when it reaches this many megabytes; 0 never rotates")
+	logMaxBackups := fs.Int("log-max-backups", 5, "rotated log files to keep")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if *tui && *logPath != "" {
+		return fmt.Errorf("-tui and -log-file cannot be combined")
+	}
+
+	log.SetPrefix("[HOST] ")
+	log.SetFlags(log.Ltime | log.Lshortfile)
+	opts := []pluginhost.Option{pluginhost.WithCache()}
+
+	cfg, err := hf.load()
+	if err != nil {
+		return err
+	}
+	if *trace != "" {
+		cfg.Trace.Path = *trace
+	}
+
+	// The dashboard owns the terminal, so logs go to a file while it runs
+	if *tui {
+		logFile, err := os.OpenFile("host.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+		opts = append(opts, pluginhost.WithLogOutput(logFile))
+	}
+	var logFile *hostdaemon.LogFile
+	if *logPath != "" {
+		if logFile, err = hostdaemon.OpenLogFile(*logPath, *logMaxSize<<20, *logMaxBackups); err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		defer logFile.Close()
+		log.SetOutput(logFile)
+		opts = append(opts, pluginhost.WithLogOutput(logFile))
+	}
+
+	log.Println("Starting plugin system...")
+	manager, err := pluginhost.New(append(opts, pluginhost.WithConfig(cfg))...)
+	if err != nil {
+		return fmt.Errorf("failed to start plugin manager: %w", err)
+	}
+	if *halt != "" {
+		if _, err := manager.Halt(*halt, false); err != nil {
+			manager.Shutdown()
+			return fmt.Errorf("failed to halt plugin execution: %w", err)
+		}
+	}
+
+	// Apply config edits while running
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	go pluginhost.NewConfigWatcher(hf.configPath(), func(cfg *pluginhost.HostConfig) {
+		hf.override(cfg)
+		if err := manager.ApplyConfig(cfg); err != nil {
+			log.Printf("Failed to apply config: %v", err)
+		}
+	}).Watch(stopWatching)
+
+	// Serve the management API, which exec, list, reload and logs talk to
+	var server *http.Server
+	if *httpAddr != "" {
+		lis, err := net.Listen("tcp", *httpAddr)
+		if err != nil {
+			manager.Shutdown()
+			return fmt.Errorf("failed to listen for the management API: %w", err)
+		}
+		token, err := writeToken(*tokenFile)
+		if err != nil {
+			lis.Close()
+			manager.Shutdown()
+			return err
+		}
+		server = &http.Server{Handler: hostapi.NewHandler(manager, hostapi.WithToken(token))}
+		go func() {
+			log.Printf("Management API listening on %s; its API key is in %s, open the dashboard with http://%s/?token=<key>", lis.Addr(), *tokenFile, lis.Addr())
+			if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Management API stopped: %v", err)
+			}
+		}()
+	}
+
+	// Serve the capability gateway to other services
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		var opts []grpc.ServerOption
+		if *grpcCert != "" {
+			creds, err := credentials.NewServerTLSFromFile(*grpcCert, *grpcKey)
+			if err != nil {
+				manager.Shutdown()
+				return fmt.Errorf("failed to load gateway certificate: %w", err)
+			}
+			opts = append(opts, grpc.Creds(creds))
+		}
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			manager.Shutdown()
+			return fmt.Errorf("failed to listen for the gateway: %w", err)
+		}
+		grpcServer = hostgrpc.NewServer(manager, opts...)
+		go func() {
+			log.Printf("Capability gateway listening on %s", lis.Addr())
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Printf("Capability gateway stopped: %v", err)
+			}
+		}()
+	}
+
+	if *tui {
+		err := hosttui.Run(manager)
+		if server != nil {
+			server.Close()
+		}
+		if grpcServer != nil {
+			grpcServer.Stop()
+		}
+		manager.Shutdown()
+		if err != nil {
+			return fmt.Errorf("dashboard failed: %w", err)
+		}
+		return nil
+	}
+
+	d := &daemon{manager: manager, configPath: hf.configPath(), override: hf.override, server: server, grpcServer: grpcServer, logFile: logFile}
+	return d.run(*pidFile)
+}
+
+// writeToken generates the API key of the management API's operator and
+// writes it to path, readable only by the user running the host
+func writeToken(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no token file: set -token-file")
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to write token file: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write token file: %w", err)
+	}
+	return token, nil
+}
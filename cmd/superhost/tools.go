@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// toolFlags defines the flags of the commands that load the plugins of the
+// config file, do one thing and exit
+func toolFlags(name, usage string) (*flag.FlagSet, *hostFlags, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	verbose := fs.Bool("v", false, "print the host log")
+	return fs, addHostFlags(fs), verbose
+}
+
+// verify prints the conformance report of every plugin and fails unless
+// all of them passed, for use as a pre-deploy gate
+func verify(args []string) error {
+	fs, hf, verbose := toolFlags("verify", "Usage: superhost verify [flags]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	manager, err := hf.open(*verbose)
+	if err != nil {
+		return err
+	}
+	defer manager.Shutdown()
+
+	failed := 0
+	plugins := manager.ListPlugins()
+	for _, p := range plugins {
+		report, err := manager.Verify(p.Name)
+		if err != nil {
+			fmt.Printf("%s: %v\n", p.Name, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%s v%s:\n", report.Plugin, report.Version)
+		for _, c := range report.Checks {
+			fmt.Printf("  %-20s %s\n", c.Capability, c.Status)
+			for _, problem := range c.Problems {
+				fmt.Printf("    %s\n", problem)
+			}
+		}
+		if !report.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d plugin(s) failed", failed, len(plugins))
+	}
+	return nil
+}
+
+// replay re-runs the calls recorded in a trace file, prints how the
+// results differ and fails when any call did not match
+func replay(args []string) error {
+	fs, hf, verbose := toolFlags("replay", "Usage: superhost replay [flags] <trace file>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("want one trace file")
+	}
+	entries, err := pluginhost.ReadTrace(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	cfg, err := hf.load()
+	if err != nil {
+		return err
+	}
+	// A replay compares against a trace; it does not record one
+	cfg.Trace.Path = ""
+	manager, err := hf.openConfig(cfg, *verbose)
+	if err != nil {
+		return err
+	}
+	defer manager.Shutdown()
+
+	var matched, mismatched, skipped int
+	for i, r := range manager.Replay(entries) {
+		call := r.Entry.Plugin
+		if r.Entry.Capability != "" {
+			call += "." + r.Entry.Capability
+		}
+		switch {
+		case r.Skipped != "":
+			skipped++
+			fmt.Printf("#%d %s: skipped, %s\n", i+1, call, r.Skipped)
+		case r.Match:
+			matched++
+			fmt.Printf("#%d %s: ok\n", i+1, call)
+		default:
+			mismatched++
+			fmt.Printf("#%d %s: differs (recorded v%s, replayed v%s)\n", i+1, call, r.Entry.Version, r.Version)
+			for _, line := range strings.Split(strings.TrimSuffix(r.Diff, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	fmt.Printf("\nReplayed %d call(s): %d matched, %d differed, %d skipped\n", len(entries), matched, mismatched, skipped)
+	if mismatched > 0 {
+		return fmt.Errorf("%d call(s) differed", mismatched)
+	}
+	return nil
+}
+
+// update updates every updatable plugin from the registry index, prints
+// what became of each and fails when any update failed
+func update(args []string) error {
+	fs, hf, verbose := toolFlags("update", "Usage: superhost update [flags]")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	manager, err := hf.open(*verbose)
+	if err != nil {
+		return err
+	}
+	defer manager.Shutdown()
+
+	results, err := manager.UpdateAll()
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			fmt.Printf("%s: update from v%s failed: %s\n", r.Plugin, r.From, r.Error)
+			failed++
+		case r.Updated:
+			fmt.Printf("%s: updated v%s -> v%s\n", r.Plugin, r.From, r.To)
+		default:
+			fmt.Printf("%s: v%s is up to date\n", r.Plugin, r.From)
+		}
+	}
+	if len(results) == 0 {
+		fmt.Println("No plugin is updatable")
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d plugin(s) failed to update", failed)
+	}
+	return nil
+}
+
+// lock pins the discovered plugin binaries in a lockfile
+func lock(args []string) error {
+	fs, hf, verbose := toolFlags("lock", "Usage: superhost lock [flags] <lockfile>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("want one lockfile")
+	}
+	manager, err := hf.open(*verbose)
+	if err != nil {
+		return err
+	}
+	defer manager.Shutdown()
+	if err := manager.WriteLockfile(fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", fs.Arg(0))
+	return nil
+}
+
+// bundle packs a plugin into an offline bundle, or verifies and installs
+// the plugin in one
+func bundle(args []string) error {
+	usage := `Usage: superhost bundle pack [-key file] <binary> [doc...]
+       superhost bundle install [flags] <bundle>`
+	if len(args) == 0 {
+		fmt.Println(usage)
+		return fmt.Errorf("missing pack or install")
+	}
+	switch args[0] {
+	case "pack":
+		fs := flag.NewFlagSet("bundle pack", flag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintln(fs.Output(), usage)
+			fs.PrintDefaults()
+		}
+		key := fs.String("key", "", "sign the bundle with this ed25519 key (PKCS #8 PEM)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			fs.Usage()
+			return fmt.Errorf("missing plugin binary")
+		}
+		return packBundle(fs.Arg(0), fs.Args()[1:], *key)
+	case "install":
+		fs, hf, verbose := toolFlags("bundle install", usage)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("want one bundle")
+		}
+		manager, err := hf.open(*verbose)
+		if err != nil {
+			return err
+		}
+		defer manager.Shutdown()
+		name, err := manager.InstallBundle(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Installed plugin %s from %s\n", name, fs.Arg(0))
+		return nil
+	default:
+		fmt.Println(usage)
+		return fmt.Errorf("unknown bundle action %q", args[0])
+	}
+}
+
+// packBundle writes the bundle of a plugin binary with its docs to
+// name-version.ocpkg in the working directory
+func packBundle(binary string, docs []string, keyPath string) error {
+	m, err := pluginhost.LoadManifest(binary + ".json")
+	if err != nil {
+		return err
+	}
+	spec := pluginhost.BundleSpec{Binary: binary, Docs: docs}
+	if keyPath != "" {
+		if spec.Key, err = pluginhost.LoadBundleKey(keyPath); err != nil {
+			return err
+		}
+	}
+	out := fmt.Sprintf("%s-%s%s", m.Name, m.Version, pluginhost.BundleExt)
+	if err := pluginhost.CreateBundle(out, spec); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n", out)
+	if spec.Key != nil {
+		fmt.Printf("Signed with public key %s\n", pluginhost.BundlePublicKey(spec.Key))
+	}
+	return nil
+}
+
+// cache lists the artifact cache the hosts on the machine share, or
+// removes old artifacts from it
+func cache(args []string) error {
+	fs := flag.NewFlagSet("cache", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: superhost cache list [flags]
+       superhost cache gc [flags]
+
+gc removes the artifacts not used for -max-age, then the least recently
+used ones while the cache holds more than -max-bytes. Both default to
+artifacts.max_age and artifacts.max_bytes of the config.`)
+		fs.PrintDefaults()
+	}
+	hf := addHostFlags(fs)
+	maxAge := fs.Duration("max-age", 0, "remove artifacts not used for this long")
+	maxBytes := fs.Int64("max-bytes", 0, "remove the least recently used artifacts above this size")
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing list or gc")
+	}
+	action := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	cfg, err := hf.load()
+	if err != nil {
+		return err
+	}
+	artifacts, err := pluginhost.OpenArtifactCache(cfg.Artifacts)
+	if err != nil {
+		return err
+	}
+	if artifacts == nil {
+		return fmt.Errorf("the artifact cache is disabled")
+	}
+
+	switch action {
+	case "list":
+		list, err := artifacts.List()
+		if err != nil {
+			return err
+		}
+		var total int64
+		for _, a := range list {
+			fmt.Printf("%s  %10d  %s\n", a.SHA256, a.Size, a.LastUsed.Format(time.RFC3339))
+			total += a.Size
+		}
+		fmt.Printf("%d artifact(s), %d bytes in %s\n", len(list), total, artifacts.Dir())
+		return nil
+	case "gc":
+		if *maxAge == 0 {
+			*maxAge = time.Duration(cfg.Artifacts.MaxAge)
+		}
+		if *maxBytes == 0 {
+			*maxBytes = cfg.Artifacts.MaxBytes
+		}
+		if *maxAge == 0 && *maxBytes == 0 {
+			return fmt.Errorf("set -max-age or -max-bytes, or artifacts.max_age or artifacts.max_bytes in the config")
+		}
+		res, err := artifacts.GC(*maxAge, *maxBytes)
+		for _, a := range res.Removed {
+			fmt.Printf("removed %s (%d bytes)\n", a.SHA256, a.Size)
+		}
+		fmt.Printf("removed %d artifact(s), %d bytes; kept %d, %d bytes\n", len(res.Removed), res.RemovedBytes, res.Kept, res.KeptBytes)
+		return err
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+// doctor checks every plugin binary in the plugin directories, prints a
+// matrix of the checks with hints for those that did not pass and fails
+// when any check failed
+func doctor(args []string) error {
+	fs, hf, verbose := toolFlags("doctor", `Usage: superhost doctor [flags] [dir...]
+
+Checks the plugins in the given directories, or in those the config
+discovers plugins in.`)
+	jsonFlag := fs.Bool("json", false, "print the reports as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	manager, err := hf.open(*verbose)
+	if err != nil {
+		return err
+	}
+	defer manager.Shutdown()
+
+	reports, err := manager.Doctor(fs.Args()...)
+	if err != nil {
+		return err
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(reports); err != nil {
+			return err
+		}
+	} else if err := printDoctor(reports); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range reports {
+		if !r.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d plugin(s) failed", failed, len(reports))
+	}
+	return nil
+}
+
+// doctorMarks are the cells of the doctor matrix for each outcome
+var doctorMarks = map[string]string{
+	pluginhost.CheckPassed:  "ok",
+	pluginhost.CheckFailed:  "FAIL",
+	pluginhost.CheckWarned:  "warn",
+	pluginhost.CheckSkipped: "-",
+}
+
+// printDoctor prints the matrix of reports, then what failed or warned
+// with how to fix it
+func printDoctor(reports []pluginhost.DoctorReport) error {
+	if len(reports) == 0 {
+		fmt.Fprintln(os.Stderr, "No plugin binaries found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "PLUGIN")
+	for _, check := range pluginhost.DoctorChecks {
+		fmt.Fprintf(tw, "\t%s", strings.ToUpper(check))
+	}
+	fmt.Fprintln(tw)
+	for _, r := range reports {
+		fmt.Fprint(tw, doctorName(r))
+		for _, check := range pluginhost.DoctorChecks {
+			fmt.Fprintf(tw, "\t%s", doctorMarks[r.Check(check).Status])
+		}
+		fmt.Fprintln(tw)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, r := range reports {
+		for _, c := range r.Checks {
+			if c.Status != pluginhost.CheckFailed && c.Status != pluginhost.CheckWarned {
+				continue
+			}
+			fmt.Printf("\n%s: %s %s: %s\n", doctorName(r), c.Check, c.Status, c.Detail)
+			if c.Hint != "" {
+				fmt.Printf("  fix: %s\n", c.Hint)
+			}
+		}
+	}
+	return nil
+}
+
+// doctorName names the plugin of a report, by its binary when it is not
+// known
+func doctorName(r pluginhost.DoctorReport) string {
+	if r.Plugin == "" {
+		return filepath.Base(r.Path)
+	}
+	return r.Plugin
+}
@@ -0,0 +1,45 @@
+// Command superplugin is the command line tool for plugin authors
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// commands maps each subcommand to the function running it with the
+// remaining arguments
+var commands = map[string]func(args []string) error{
+	"manifest": manifest,
+	"release":  release,
+	"search":   search,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: superplugin <command> [flags]
+
+Commands:
+  manifest  upgrade a plugin manifest to a newer schema version
+  release   build, checksum and sign a plugin for several platforms
+  search    find the capabilities of a running host's plugins
+
+Run "superplugin <command> -h" for the flags of a command.`)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		if os.Args[1] != "-h" && os.Args[1] != "help" {
+			fmt.Fprintf(os.Stderr, "superplugin: unknown command %q\n", os.Args[1])
+		}
+		usage()
+		os.Exit(2)
+	}
+	if err := run(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "superplugin %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
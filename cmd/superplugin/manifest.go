@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// manifest runs the subcommands working on plugin manifests
+func manifest(args []string) error {
+	if len(args) == 0 || args[0] != "migrate" {
+		fmt.Fprintln(os.Stderr, `Usage: superplugin manifest migrate [flags] [manifest]
+
+Commands:
+  migrate   upgrade a manifest to a newer schema version`)
+		if len(args) == 0 || args[0] == "-h" || args[0] == "help" {
+			return nil
+		}
+		return fmt.Errorf("unknown manifest command %q", args[0])
+	}
+	return migrateManifest(args[1:])
+}
+
+// migrateManifest upgrades a manifest to a newer schema version, explaining
+// each change on stderr
+func migrateManifest(args []string) error {
+	fs := flag.NewFlagSet("manifest migrate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: superplugin manifest migrate [flags] [manifest]
+
+Upgrades a plugin manifest (default "manifest.json") to a newer schema
+version and explains every change. The upgraded manifest is printed unless
+-w writes it back. Hosts read manifests of schema version %d and %d.
+`, pluginhost.ManifestSchemaVersion-1, pluginhost.ManifestSchemaVersion)
+		fs.PrintDefaults()
+	}
+	toFlag := fs.Int("to", pluginhost.ManifestSchemaVersion, "schema version to migrate to")
+	writeFlag := fs.Bool("w", false, "write the upgraded manifest back to the file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected at most one manifest, got %d", fs.NArg())
+	}
+	path := "manifest.json"
+	if fs.NArg() == 1 {
+		path = fs.Arg(0)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, changes, err := pluginhost.MigrateManifest(data, *toFlag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(changes) == 0 {
+		fmt.Fprintf(os.Stderr, "%s is already at schema version %d\n", path, *toFlag)
+	}
+	for _, c := range changes {
+		fmt.Fprintf(os.Stderr, "v%d %s: %s\n", c.To, c.Field, c.Explanation)
+	}
+	if !*writeFlag {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, out, fi.Mode().Perm()); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+	return nil
+}
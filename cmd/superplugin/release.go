@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// defaultTargets are the platforms release builds for when -targets is not
+// given
+const defaultTargets = "linux/amd64,linux/arm64,darwin/amd64,darwin/arm64,windows/amd64"
+
+// sdkPackage is where the build metadata variables live
+const sdkPackage = "github.com/Kirchlive/super/pkg/pluginsdk"
+
+// Files written next to the binaries of a release
+const (
+	sumsFile  = "SHA256SUMS"
+	indexFile = "index.json"
+	sigExt    = ".sig"
+)
+
+// target is a platform to build for
+type target struct {
+	os, arch string
+}
+
+func (t target) String() string {
+	return t.os + "/" + t.arch
+}
+
+// release builds a plugin package for several platforms into a directory
+// holding the binaries, the manifest, their checksums, signatures and a
+// registry index listing them
+func release(args []string) error {
+	fs := flag.NewFlagSet("release", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: superplugin release [flags] [package]\n\nBuilds the plugin's main package (default \".\") for every target.")
+		fs.PrintDefaults()
+	}
+	manifestFlag := fs.String("manifest", "", "plugin manifest (default <package>/manifest.json)")
+	versionFlag := fs.String("version", "", "version to release (default the manifest's)")
+	targetsFlag := fs.String("targets", defaultTargets, "comma-separated GOOS/GOARCH pairs to build for")
+	outFlag := fs.String("out", "dist", "directory receiving the release directory")
+	keyFlag := fs.String("key", "", "sign the artifacts with this ed25519 key (PKCS #8 PEM)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected at most one package, got %d", fs.NArg())
+	}
+	pkg := "."
+	if fs.NArg() == 1 {
+		pkg = fs.Arg(0)
+	}
+	manifestPath := *manifestFlag
+	if manifestPath == "" {
+		manifestPath = filepath.Join(pkg, "manifest.json")
+	}
+
+	m, err := pluginhost.LoadManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+	if len(m.Migrated) > 0 {
+		fmt.Printf("Warning: %s: %s; upgrade it with \"superplugin manifest migrate -w %s\"\n", manifestPath, m.Migrated[0].Explanation, manifestPath)
+	}
+	version := *versionFlag
+	if version == "" {
+		version = m.Version
+	}
+	if version == "" {
+		return fmt.Errorf("manifest %s has no version; pass -version", manifestPath)
+	}
+	targets, err := parseTargets(*targetsFlag)
+	if err != nil {
+		return err
+	}
+	var key ed25519.PrivateKey
+	if *keyFlag != "" {
+		if key, err = pluginhost.LoadBundleKey(*keyFlag); err != nil {
+			return err
+		}
+	}
+
+	dir := filepath.Join(*outFlag, m.Name+"-"+version)
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("%s already exists", dir)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// The manifest ships with the release version
+	manifestName := "plugin-" + m.Name + ".json"
+	manifest, err := releaseManifest(manifestPath, version)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestName), manifest, 0644); err != nil {
+		return err
+	}
+	artifacts := []string{manifestName}
+
+	ldflags := buildFlags(pkg, version)
+	capabilities := make([]string, 0, len(m.Capabilities))
+	for _, c := range m.Capabilities {
+		capabilities = append(capabilities, c.Ref())
+	}
+	index := pluginhost.RegistryIndex{}
+	for _, t := range targets {
+		binary := path.Join(t.os+"_"+t.arch, "plugin-"+m.Name)
+		if t.os == "windows" {
+			binary += ".exe"
+		}
+		fmt.Printf("Building %s for %s\n", m.Name, t)
+		if err := build(pkg, t, ldflags, filepath.Join(dir, filepath.FromSlash(binary))); err != nil {
+			return fmt.Errorf("build for %s failed: %w", t, err)
+		}
+		artifacts = append(artifacts, binary)
+		index.Plugins = append(index.Plugins, pluginhost.IndexEntry{
+			Name:         m.Name,
+			Version:      version,
+			Capabilities: capabilities,
+			URL:          binary,
+			Manifest:     manifestName,
+			OS:           t.os,
+			Arch:         t.arch,
+		})
+	}
+
+	// Checksums, then the index that lists them
+	sums := make(map[string]string, len(artifacts))
+	var list strings.Builder
+	for _, name := range artifacts {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		sums[name] = hex.EncodeToString(sum[:])
+		fmt.Fprintf(&list, "%s  %s\n", sums[name], name)
+	}
+	if err := os.WriteFile(filepath.Join(dir, sumsFile), []byte(list.String()), 0644); err != nil {
+		return err
+	}
+	for i := range index.Plugins {
+		index.Plugins[i].SHA256 = sums[index.Plugins[i].URL]
+	}
+	rawIndex, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, indexFile), append(rawIndex, '\n'), 0644); err != nil {
+		return err
+	}
+
+	if key != nil {
+		signed := append(append([]string(nil), artifacts...), sumsFile, indexFile)
+		for _, name := range signed {
+			if err := sign(key, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Printf("Released %s v%s for %d target(s) in %s\n", m.Name, version, len(targets), dir)
+	if key != nil {
+		fmt.Printf("Signed with public key %s\n", pluginhost.BundlePublicKey(key))
+	}
+	return nil
+}
+
+// parseTargets parses a comma-separated list of GOOS/GOARCH pairs
+func parseTargets(s string) ([]target, error) {
+	var targets []target
+	seen := make(map[target]bool)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(field, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid target %q, want GOOS/GOARCH", field)
+		}
+		t := target{goos, goarch}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets")
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].String() < targets[j].String() })
+	return targets, nil
+}
+
+// releaseManifest returns the manifest with its version set to version.
+// Other fields are kept as they are.
+func releaseManifest(p, version string) ([]byte, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", p, err)
+	}
+	var current string
+	if err := json.Unmarshal(fields["version"], &current); err == nil && current == version {
+		return data, nil
+	}
+	fields["version"], _ = json.Marshal(version)
+	out, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// buildFlags returns the linker flags embedding the version, the commit and
+// the commit's date. The date comes from SOURCE_DATE_EPOCH or the commit,
+// never the clock, so rebuilding a commit gives the same binaries.
+func buildFlags(pkg, version string) string {
+	commit := gitOutput(pkg, "rev-parse", "HEAD")
+	var date string
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		epoch = gitOutput(pkg, "log", "-1", "--format=%ct")
+	}
+	if secs, err := strconv.ParseInt(epoch, 10, 64); err == nil {
+		date = time.Unix(secs, 0).UTC().Format(time.RFC3339)
+	}
+	flags := []string{"-s", "-w", "-buildid="}
+	vars := [][2]string{{"BuildVersion", version}, {"BuildCommit", commit}, {"BuildDate", date}}
+	for _, v := range vars {
+		if v[1] != "" {
+			flags = append(flags, fmt.Sprintf("-X %s.%s=%s", sdkPackage, v[0], v[1]))
+		}
+	}
+	return strings.Join(flags, " ")
+}
+
+// gitOutput runs git in dir and returns its trimmed output, or "" when it
+// fails, e.g. outside a repository
+func gitOutput(dir string, args ...string) string {
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// build compiles pkg for t into out, without cgo and with paths trimmed
+// so the binary does not depend on where it was built
+func build(pkg string, t target, ldflags, out string) error {
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("go", "build", "-trimpath", "-buildvcs=false", "-ldflags", ldflags, "-o", abs, ".")
+	cmd.Dir = pkg
+	cmd.Env = append(os.Environ(), "GOOS="+t.os, "GOARCH="+t.arch, "CGO_ENABLED=0")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// sign writes the base64 ed25519 signature of the file at p next to it
+func sign(key ed25519.PrivateKey, p string) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	sig := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	return os.WriteFile(p+sigExt, []byte(sig+"\n"), 0644)
+}
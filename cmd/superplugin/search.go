@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/hostapi"
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// searchTimeout bounds the request to the host
+const searchTimeout = 10 * time.Second
+
+// search asks a running host's management API which capabilities of its
+// plugins match a query
+func search(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), `Usage: superplugin search [flags] [query]
+
+Searches the capabilities of the plugins a host runs by name, tag and
+description, forgiving typos. "tag:x" and "plugin:x" narrow the results,
+e.g. "superplugin search greet tag:text". No query lists everything.`)
+		fs.PrintDefaults()
+	}
+	hostFlag := fs.String("host", "http://localhost:8080", "management API of the host to search")
+	jsonFlag := fs.Bool("json", false, "print the matches as JSON")
+	mcpFlag := fs.Bool("mcp", false, "print the matches as MCP tool descriptions")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+
+	path := "/capabilities"
+	if *mcpFlag {
+		path = "/mcp/tools"
+	}
+	body, err := getHost(strings.TrimSuffix(*hostFlag, "/") + path + "?q=" + url.QueryEscape(query))
+	if err != nil {
+		return err
+	}
+	if *jsonFlag || *mcpFlag {
+		_, err := os.Stdout.Write(body)
+		return err
+	}
+
+	var matches []pluginhost.CapabilityMatch
+	if err := json.Unmarshal(body, &matches); err != nil {
+		return fmt.Errorf("unexpected answer from the host: %w", err)
+	}
+	if len(matches) == 0 {
+		fmt.Fprintf(os.Stderr, "No capability matches %q\n", query)
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PLUGIN\tCAPABILITY\tTAGS\tDESCRIPTION")
+	for _, m := range matches {
+		description := m.Capability.Description
+		if m.Capability.Deprecated != "" {
+			description += " (deprecated: " + m.Capability.Deprecated + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Plugin, m.Capability.Ref(), strings.Join(m.Capability.Tags, ","), description)
+	}
+	return tw.Flush()
+}
+
+// getHost returns the body of a successful GET to the management API
+func getHost(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := hostapi.ClientToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{Timeout: searchTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the host (is it running with -http?): %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("host answered %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -0,0 +1,1176 @@
+// Package hostapi serves a plugin manager's management API over HTTP
+package hostapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// NewHandler returns the HTTP handler for the management API of pm:
+//
+//	GET /                      the web dashboard
+//	GET /plugins               status of every plugin, including resource use
+//	GET /version               the host's version, commit and Go version, the protocol versions it
+//	                              speaks and the build of every plugin
+//	GET /metrics               plugin and event bus metrics in the Prometheus text format
+//	GET /history               executions matching ?plugin=&capability=&status=&since=&until=&limit=
+//	GET /events/stats          delivery metrics of every event subscription
+//	GET /slos                  each capability with an objective against its p95 latency and error rate
+//	GET /capabilities          capabilities matching ?q=, e.g. "greet tag:text plugin:hello", best first
+//	GET /mcp/tools             the capabilities matching ?q= as MCP tool descriptions
+//	GET /plugins/{name}/logs   a plugin's log as JSON lines, ?level=&follow=true
+//	POST /editor/events        an editor event such as {"type": "file.saved", "data": {"path": "main.go"}}
+//	POST /apply                bring the plugins in line with a desired state, ?dry_run=true only diffs
+//	GET /mode                  the host mode and the calls it holds or is running
+//	PUT /mode                  switch the host mode, e.g. {"mode": "maintenance"}
+//	GET /halt                  whether the kill switch is engaged, why and since when
+//	PUT /halt                  refuse every plugin call, e.g. {"reason": "incident 42", "abort": true}
+//	                              also cancels the calls running
+//	DELETE /halt               let plugins take calls again
+//	GET /concurrency           the concurrency keys calls hold, with the calls waiting for each
+//	GET /environment           the host's fingerprint plugin requirements are checked against: OS, arch, C library and tool versions
+//	GET /feature-flags         the feature flags gating capabilities, where they came from and when
+//	POST /feature-flags/reload read the flag source now rather than at the next refresh
+//	GET /llm/usage             requests, failures and tokens of each plugin with each LLM provider and model
+//	GET /costs                 LLM tokens and estimated cost by ?by=plugin,capability,caller,provider,model
+//	                              within ?since=&until=, and the state of every budget
+//	GET /conflicts             binaries that reported the name of a registered plugin, and how each was resolved
+//	GET /commands              which plugins cover each command of the catalog
+//	GET /project               the project context of ?dir=, e.g. its branch and dirty files; ?refresh=true computes it anew
+//	POST /plugins/{name}/reload   restart a plugin from its binary
+//	POST /plugins/{name}/upgrade  move a plugin to a new binary, e.g. {"target": "1.1.0"}
+//	POST /plugins/{name}/update   move an updatable plugin to its newest compatible version in the registry index
+//	POST /plugins/update       update every updatable plugin, answering the outcome of each
+//	PUT /plugins/{name}/pin       pin a plugin to a version, e.g. {"version": "1.0.0"}
+//	DELETE /plugins/{name}/pin    let a pinned plugin be upgraded again
+//	PUT /plugins/{name}/disabled  refuse a plugin's calls but keep it loaded
+//	DELETE /plugins/{name}/disabled  let a disabled plugin take calls again
+//	PUT /plugins/{name}/muted     suppress the events a plugin emits, e.g. {"for": "10m", "reason": "flooding"};
+//	                              without "for" until unmuted
+//	DELETE /plugins/{name}/muted  let a muted plugin's events through again
+//	PUT /plugins/{name}/log-level switch the level a plugin logs at while it runs, e.g. {"level": "debug"}
+//	DELETE /plugins/{name}/log-level  let a plugin log at its own level again
+//	POST /plugins/{name}/daemon/start  start a daemon plugin's server again, after a stop or once its restarts ran out
+//	POST /plugins/{name}/daemon/stop   stop a daemon plugin's server, keeping it stopped until started
+//	POST /plugins/{name}/execute  run a plugin, e.g. {"args": {"capability": "greet", "name": "Ada"}},
+//	                              optionally starting a request with {"context": {"user": "ada"}}, or
+//	                              {"context": {"user": "billing", "service": true}} for a service
+//	                              while authorization is off; clients with an API key act as its service;
+//	                              the locale defaults to the Accept-Language header; a request the call
+//	                              started is canceled when the client goes away;
+//	                              {"args": {"options": {"accept": ["markdown", "text"]}}} picks the
+//	                              result format, which the response names in "format";
+//	                              {"args": {"latency_budget": "2s"}} answers with what the plugin has
+//	                              by then, marked "partial": true
+//	POST /plugins/{name}/batch    run many calls in one round trip, e.g. {"items": [{"name": "Ada"}, {"name": "Bob"}]},
+//	                              answering {"results": [...]} with a result or error per item
+//	POST /requests/{id}/cancel    cancel the calls of a request, optionally {"reason": "user pressed stop"}
+//	POST /plugins/{name}/tasks    start a task in the background, e.g. {"capability": "analyze", "args": {"path": "."}},
+//	                              optionally for {"context": {"user": "ada"}} as with execute
+//	GET /tasks                 tasks matching ?plugin=&state=, newest first
+//	GET /tasks/{id}            a task with its progress, and its result once it finished
+//	POST /tasks/{id}/cancel    cancel a running task, optionally {"reason": "user pressed stop"}
+//	POST /plugins/{name}/verify   run each capability's example and check it against its schemas
+//	GET /pipelines             the pipelines of the config
+//	POST /pipelines/{name}/run run a pipeline, e.g. {"args": {"project": "shop"}}, rolling it back when a step fails;
+//	                              {"context": {"user": "ada"}} runs it for a user
+//	POST /route                the persona and plugin for a command, e.g. {"command": "build", "files": ["App.tsx"]}
+//	GET /remotes               the attached remote hosts and the plugins they serve
+//	PUT /remotes/{name}        attach a remote host, e.g. {"url": "http://worker-1:8080"}
+//	DELETE /remotes/{name}     detach a remote host
+//	POST /bundles/inspect      describe a bundle on the host's disk, e.g. {"path": "/media/hello.ocpkg"}
+//	POST /bundles/verify       check a bundle's checksums and signature
+//	POST /bundles/install      install and load the plugin in a bundle
+//
+// Clients authenticate with an API key of the config's api section or the
+// token of WithToken. Only the latter may use every route: other clients
+// may only call and list plugins and run their own tasks unless their
+// principal is bound to an Admin role. Requests with bodies other than
+// JSON, and requests browsers send from other sites, are refused.
+func NewHandler(pm *pluginhost.PluginManager, opts ...Option) http.Handler {
+	s := &server{pm: pm, auth: &authenticator{pm: pm}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.dashboard)
+	mux.HandleFunc("GET /plugins", s.plugins)
+	mux.HandleFunc("GET /version", s.version)
+	mux.HandleFunc("GET /metrics", s.metrics)
+	mux.HandleFunc("GET /history", s.history)
+	mux.HandleFunc("GET /events/stats", s.eventStats)
+	mux.HandleFunc("GET /slos", s.slos)
+	mux.HandleFunc("GET /capabilities", s.capabilities)
+	mux.HandleFunc("GET /mcp/tools", s.mcpTools)
+	mux.HandleFunc("GET /plugins/{name}/logs", s.logs)
+	mux.HandleFunc("POST /editor/events", s.editorEvent)
+	mux.HandleFunc("POST /apply", s.apply)
+	mux.HandleFunc("GET /mode", s.mode)
+	mux.HandleFunc("PUT /mode", s.setMode)
+	mux.HandleFunc("GET /halt", s.halted)
+	mux.HandleFunc("PUT /halt", s.halt)
+	mux.HandleFunc("DELETE /halt", s.resume)
+	mux.HandleFunc("GET /concurrency", s.concurrency)
+	mux.HandleFunc("GET /environment", s.environment)
+	mux.HandleFunc("GET /feature-flags", s.featureFlags)
+	mux.HandleFunc("POST /feature-flags/reload", s.reloadFeatureFlags)
+	mux.HandleFunc("GET /llm/usage", s.llmUsage)
+	mux.HandleFunc("GET /costs", s.costs)
+	mux.HandleFunc("GET /conflicts", s.nameConflicts)
+	mux.HandleFunc("GET /commands", s.commands)
+	mux.HandleFunc("GET /project", s.project)
+	mux.HandleFunc("POST /plugins/{name}/reload", s.reload)
+	mux.HandleFunc("POST /plugins/{name}/upgrade", s.upgrade)
+	mux.HandleFunc("POST /plugins/{name}/update", s.update)
+	mux.HandleFunc("POST /plugins/update", s.updateAll)
+	mux.HandleFunc("PUT /plugins/{name}/pin", s.pin)
+	mux.HandleFunc("DELETE /plugins/{name}/pin", s.unpin)
+	mux.HandleFunc("PUT /plugins/{name}/disabled", s.disable)
+	mux.HandleFunc("DELETE /plugins/{name}/disabled", s.enable)
+	mux.HandleFunc("PUT /plugins/{name}/muted", s.mute)
+	mux.HandleFunc("DELETE /plugins/{name}/muted", s.unmute)
+	mux.HandleFunc("PUT /plugins/{name}/log-level", s.setLogLevel)
+	mux.HandleFunc("DELETE /plugins/{name}/log-level", s.resetLogLevel)
+	mux.HandleFunc("POST /plugins/{name}/daemon/start", s.startDaemon)
+	mux.HandleFunc("POST /plugins/{name}/daemon/stop", s.stopDaemon)
+	mux.HandleFunc("POST /plugins/{name}/execute", s.execute)
+	mux.HandleFunc("POST /plugins/{name}/batch", s.batch)
+	mux.HandleFunc("POST /plugins/{name}/verify", s.verify)
+	mux.HandleFunc("POST /requests/{id}/cancel", s.cancelRequest)
+	mux.HandleFunc("POST /plugins/{name}/tasks", s.startTask)
+	mux.HandleFunc("GET /tasks", s.tasks)
+	mux.HandleFunc("GET /tasks/{id}", s.task)
+	mux.HandleFunc("POST /tasks/{id}/cancel", s.cancelTask)
+	mux.HandleFunc("GET /pipelines", s.pipelines)
+	mux.HandleFunc("POST /pipelines/{name}/run", s.runPipeline)
+	mux.HandleFunc("POST /route", s.route)
+	mux.HandleFunc("GET /remotes", s.remotes)
+	mux.HandleFunc("PUT /remotes/{name}", s.attachRemote)
+	mux.HandleFunc("DELETE /remotes/{name}", s.detachRemote)
+	mux.HandleFunc("POST /bundles/inspect", s.inspectBundle)
+	mux.HandleFunc("POST /bundles/verify", s.verifyBundle)
+	mux.HandleFunc("POST /bundles/install", s.installBundle)
+	return s.guard(mux)
+}
+
+type server struct {
+	pm   *pluginhost.PluginManager
+	auth *authenticator
+
+	// token is the API key of the host's operator, if any
+	token string
+}
+
+func (s *server) plugins(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.ListPlugins())
+}
+
+// history answers questions like "what did plugin X do yesterday".
+// since and until take an RFC 3339 time or a duration before now such as
+// "24h".
+func (s *server) history(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseHistoryFilter(r.URL.Query(), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	records, err := s.pm.History(filter)
+	if errors.Is(err, pluginhost.ErrHistoryDisabled) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// eventStats shows which subscribers are falling behind
+func (s *server) eventStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.EventStats())
+}
+
+// slos shows which capabilities miss their service level objectives
+func (s *server) slos(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.SLOs())
+}
+
+// capabilities searches what the plugins can do
+func (s *server) capabilities(w http.ResponseWriter, r *http.Request) {
+	matches := s.pm.FindCapabilities(r.URL.Query().Get("q"))
+	if matches == nil {
+		matches = []pluginhost.CapabilityMatch{}
+	}
+	writeJSON(w, http.StatusOK, matches)
+}
+
+// mcpTools lists capabilities the way an MCP bridge offers them as tools
+func (s *server) mcpTools(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tools": s.pm.MCPTools(r.URL.Query().Get("q"))})
+}
+
+// logs writes a plugin's recent log entries, one JSON object per line. With
+// follow=true it keeps the response open and streams new entries until the
+// client disconnects.
+func (s *server) logs(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	level, err := pluginhost.ParseLogLevel(r.URL.Query().Get("level"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if r.URL.Query().Get("follow") != "true" {
+		entries, err := s.pm.Logs(name, level)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			enc.Encode(e)
+		}
+		return
+	}
+
+	recent, entries, stop, err := s.pm.FollowLogs(name, level)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	defer stop()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	for _, e := range recent {
+		enc.Encode(e)
+	}
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case e := <-entries:
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// editorEvent lets an editor running in another process feed its activity
+// to plugins
+func (s *server) editorEvent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type string                 `json:"type"`
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid event: %w", err))
+		return
+	}
+	if err := s.pm.Editor().Publish(req.Type, req.Data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// apply answers with the changes made, or with the ones that would be made
+// for a dry run. A failed apply was rolled back.
+func (s *server) apply(w http.ResponseWriter, r *http.Request) {
+	var desired pluginhost.DesiredState
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&desired); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid desired state: %w", err))
+		return
+	}
+
+	apply := s.pm.Apply
+	if r.URL.Query().Get("dry_run") == "true" {
+		apply = s.pm.Diff
+	}
+	plan, err := apply(&desired)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+func (s *server) mode(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.Mode())
+}
+
+// setMode switches between normal, read_only and maintenance, e.g. around a
+// plugin upgrade, and answers with the new status
+func (s *server) setMode(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Mode string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid mode: %w", err))
+		return
+	}
+	mode, err := pluginhost.ParseHostMode(req.Mode)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.pm.SetMode(mode); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.pm.Mode())
+}
+
+func (s *server) halted(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.Halted())
+}
+
+// halt engages the kill switch and answers with its status and how many
+// running calls it aborted
+func (s *server) halt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+		Abort  bool   `json:"abort"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid halt: %w", err))
+		return
+	}
+	aborted, err := s.pm.Halt(req.Reason, req.Abort)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  s.pm.Halted(),
+		"aborted": aborted,
+	})
+}
+
+func (s *server) resume(w http.ResponseWriter, r *http.Request) {
+	s.pm.Resume()
+	writeJSON(w, http.StatusOK, s.pm.Halted())
+}
+
+func (s *server) concurrency(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.ConcurrencyKeys())
+}
+
+func (s *server) version(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.HostInfo())
+}
+
+func (s *server) environment(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.Environment())
+}
+
+func (s *server) featureFlags(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.FeatureFlags())
+}
+
+// reloadFeatureFlags answers with the flags in use, or 502 when the source
+// could not be read and the flags read before stay
+func (s *server) reloadFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	if err := s.pm.ReloadFeatureFlags(); err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.pm.FeatureFlags())
+}
+
+func (s *server) llmUsage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.LLMUsage())
+}
+
+// costs answers what the LLM requests of plugins cost, e.g. per caller
+// over the last week with ?by=caller&since=168h
+func (s *server) costs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	now := time.Now()
+	var query pluginhost.CostQuery
+	var err error
+	if by := q.Get("by"); by != "" {
+		query.By = strings.Split(by, ",")
+	}
+	if query.Since, err = parseTime(q.Get("since"), now); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since: %w", err))
+		return
+	}
+	if query.Until, err = parseTime(q.Get("until"), now); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid until: %w", err))
+		return
+	}
+	report, err := s.pm.CostReport(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (s *server) nameConflicts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.Conflicts())
+}
+
+func (s *server) commands(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.CommandCoverage())
+}
+
+func (s *server) project(w http.ResponseWriter, r *http.Request) {
+	dir := r.URL.Query().Get("dir")
+	if dir == "" {
+		writeError(w, http.StatusBadRequest, errors.New("dir is required"))
+		return
+	}
+	var pc pluginsdk.ProjectContext
+	var err error
+	if r.URL.Query().Get("refresh") == "true" {
+		pc, err = s.pm.RefreshProject(dir)
+	} else {
+		pc, err = s.pm.ProjectContext(dir)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, pc)
+}
+
+// reload answers with the plugin's status once it runs again
+func (s *server) reload(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.pm.ReloadPlugin(name); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, pluginhost.ErrPluginNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	st, err := s.pm.GetPlugin(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// upgrade answers with the plugin's status once the new binary serves calls.
+// The target is a binary path or a version found in the plugin directories.
+func (s *server) upgrade(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	var req struct {
+		Target string `json:"target"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Target == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid upgrade: want {\"target\": path or version}"))
+		return
+	}
+	if err := s.pm.Upgrade(name, req.Target); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, pluginhost.ErrPluginNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	st, err := s.pm.GetPlugin(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// update answers with the outcome once the plugin runs its newest
+// compatible version, or is found up to date
+func (s *server) update(w http.ResponseWriter, r *http.Request) {
+	res, err := s.pm.UpdatePlugin(r.PathValue("name"))
+	if err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, pluginhost.ErrPluginNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// updateAll answers with the outcome of updating each updatable plugin;
+// plugins that failed to update carry their error
+func (s *server) updateAll(w http.ResponseWriter, r *http.Request) {
+	results, err := s.pm.UpdateAll()
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (s *server) pin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid pin: %w", err))
+		return
+	}
+	if err := s.pm.Pin(r.PathValue("name"), req.Version); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.pm.Pins())
+}
+
+func (s *server) unpin(w http.ResponseWriter, r *http.Request) {
+	s.pm.Unpin(r.PathValue("name"))
+	writeJSON(w, http.StatusOK, s.pm.Pins())
+}
+
+func (s *server) disable(w http.ResponseWriter, r *http.Request) {
+	if err := s.pm.Disable(r.PathValue("name")); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.pm.Disabled())
+}
+
+func (s *server) enable(w http.ResponseWriter, r *http.Request) {
+	if err := s.pm.Enable(r.PathValue("name")); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.pm.Disabled())
+}
+
+// mute answers with what became of the plugin's events, including the new
+// mute
+func (s *server) mute(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		For    pluginhost.Duration `json:"for"`
+		Reason string              `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid mute: %w", err))
+		return
+	}
+	if req.For < 0 {
+		writeError(w, http.StatusBadRequest, errors.New("invalid mute: for must not be negative"))
+		return
+	}
+	name := r.PathValue("name")
+	if err := s.pm.MuteEvents(name, time.Duration(req.For), req.Reason); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.emission(w, name)
+}
+
+func (s *server) unmute(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if err := s.pm.UnmuteEvents(name); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	s.emission(w, name)
+}
+
+// setLogLevel answers with the plugin's status, which carries the level
+func (s *server) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Level == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid log level: want {\"level\": \"debug\"}"))
+		return
+	}
+	if _, err := pluginhost.ParseLogLevel(req.Level); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.applyLogLevel(w, r.PathValue("name"), req.Level)
+}
+
+func (s *server) resetLogLevel(w http.ResponseWriter, r *http.Request) {
+	s.applyLogLevel(w, r.PathValue("name"), "")
+}
+
+func (s *server) applyLogLevel(w http.ResponseWriter, name, level string) {
+	if err := s.pm.SetLogLevel(name, level); err != nil {
+		// Other than a missing plugin, the plugin failed to apply the level
+		status := http.StatusBadGateway
+		if errors.Is(err, pluginhost.ErrPluginNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	st, err := s.pm.GetPlugin(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// startDaemon answers with the plugin's status, which carries the state of
+// its server
+func (s *server) startDaemon(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	_, err := s.pm.StartDaemon(name)
+	s.daemonStatus(w, name, err)
+}
+
+func (s *server) stopDaemon(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	_, err := s.pm.StopDaemon(name)
+	s.daemonStatus(w, name, err)
+}
+
+func (s *server) daemonStatus(w http.ResponseWriter, name string, err error) {
+	if err != nil {
+		// Other than a missing plugin or one that is no daemon, the
+		// server failed to start or stop
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, pluginhost.ErrPluginNotFound):
+			status = http.StatusNotFound
+		case pluginsdk.AsPluginError(err).Code == pluginsdk.CodeUnsupported:
+			status = http.StatusConflict
+		}
+		writeError(w, status, err)
+		return
+	}
+	st, err := s.pm.GetPlugin(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// emission writes what became of the events of a plugin; nothing is
+// known of plugins that are gone
+func (s *server) emission(w http.ResponseWriter, name string) {
+	events := &pluginhost.EventEmission{}
+	if st, err := s.pm.GetPlugin(name); err == nil && st.Events != nil {
+		events = st.Events
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// execute runs a call for another host that attached this one, or any
+// other client. A result the plugin spilled to a file is sent inline, as the
+// file is not reachable from elsewhere, and a partial one is flagged so.
+// Errors carry the plugin error code.
+func (s *server) execute(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Args    map[string]interface{}    `json:"args"`
+		Context *pluginsdk.RequestContext `json:"context"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid call: %w", err))
+		return
+	}
+	if req.Args == nil {
+		req.Args = map[string]interface{}{}
+	}
+	locale := pluginsdk.Locale(req.Args)
+	if locale == "" {
+		if locale = acceptLanguage(r.Header.Get("Accept-Language")); locale != "" {
+			req.Args[pluginsdk.ArgLocale] = locale
+		}
+	}
+
+	if c, ok := clientOf(r); ok && c.name != "" && req.Context == nil {
+		req.Context = &pluginsdk.RequestContext{}
+	}
+	var res pluginhost.ExecuteResult
+	var err error
+	if req.Context == nil {
+		res, err = s.pm.Execute(r.PathValue("name"), req.Args, nil)
+	} else if err = s.principal(r, req.Context); err == nil {
+		res, err = s.pm.ExecuteContext(r.Context(), r.PathValue("name"), req.Args, *req.Context)
+	}
+	if err != nil {
+		status, body := s.callError(err, locale)
+		writeJSON(w, status, body)
+		return
+	}
+	result, err := s.readResult(res.Result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := map[string]interface{}{"result": result}
+	if format := s.pm.ResultFormat(r.PathValue("name"), req.Args); format != "" {
+		resp["format"] = format
+	}
+	if res.Partial {
+		resp["partial"] = true
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// callError returns the status and body answering a failed call, with its
+// message in locale
+func (s *server) callError(err error, locale string) (int, map[string]interface{}) {
+	status := http.StatusInternalServerError
+	pe := pluginsdk.AsPluginError(err)
+	switch {
+	case errors.Is(err, pluginhost.ErrPluginNotFound):
+		status, pe.Code = http.StatusNotFound, pluginsdk.CodeNotFound
+	case errors.Is(err, pluginhost.ErrCapabilityNotSupported):
+		pe.Code = pluginsdk.CodeUnsupported
+	case errors.Is(err, pluginhost.ErrPluginDisabled):
+		status, pe.Code = http.StatusConflict, pluginsdk.CodeUnavailable
+	case errors.Is(err, pluginhost.ErrHalted):
+		status = http.StatusServiceUnavailable
+	case pe.Code == pluginsdk.CodeTimeout:
+		status = http.StatusGatewayTimeout
+	case pe.Code == pluginsdk.CodePermissionDenied:
+		status = http.StatusForbidden
+	}
+	return status, map[string]interface{}{
+		"error":     s.pm.LocalizeError(err, locale),
+		"code":      pe.Code,
+		"retryable": pe.Retryable,
+		"details":   pe.Details,
+	}
+}
+
+// readResult returns the content of a result the plugin spilled to a
+// file, removing the file, and other results as they are
+func (s *server) readResult(result string) (string, error) {
+	path, spilled := s.pm.ResultFile(result)
+	if !spilled {
+		return result, nil
+	}
+	data, err := os.ReadFile(path)
+	os.Remove(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read spilled result: %w", err)
+	}
+	return string(data), nil
+}
+
+func (s *server) batch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid batch: %w", err))
+		return
+	}
+	locale := acceptLanguage(r.Header.Get("Accept-Language"))
+	for i, args := range req.Items {
+		if args == nil {
+			args = map[string]interface{}{}
+			req.Items[i] = args
+		}
+		if pluginsdk.Locale(args) == "" && locale != "" {
+			args[pluginsdk.ArgLocale] = locale
+		}
+	}
+
+	name := r.PathValue("name")
+	results, err := s.pm.ExecuteBatch(name, req.Items)
+	if err != nil {
+		status, body := s.callError(err, locale)
+		writeJSON(w, status, body)
+		return
+	}
+	out := make([]map[string]interface{}, len(results))
+	for i, res := range results {
+		if res.Err != nil {
+			_, out[i] = s.callError(res.Err, pluginsdk.Locale(req.Items[i]))
+			continue
+		}
+		result, err := s.readResult(res.Result)
+		if err != nil {
+			_, out[i] = s.callError(err, pluginsdk.Locale(req.Items[i]))
+			continue
+		}
+		out[i] = map[string]interface{}{"result": result}
+		if format := s.pm.ResultFormat(name, req.Items[i]); format != "" {
+			out[i]["format"] = format
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"results": out})
+}
+
+func (s *server) pipelines(w http.ResponseWriter, r *http.Request) {
+	pipelines := s.pm.Pipelines()
+	if pipelines == nil {
+		pipelines = map[string]pluginhost.Pipeline{}
+	}
+	writeJSON(w, http.StatusOK, pipelines)
+}
+
+// runPipeline answers with the run, which says for a failed pipeline what
+// was undone
+func (s *server) runPipeline(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Args    map[string]interface{}   `json:"args"`
+		Context pluginsdk.RequestContext `json:"context"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid run: %w", err))
+		return
+	}
+	if err := s.principal(r, &req.Context); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	run, err := s.pm.RunPipelineWithContext(r.PathValue("name"), req.Args, req.Context)
+	var pe *pluginhost.PipelineError
+	switch {
+	case errors.Is(err, pluginhost.ErrPipelineNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.As(err, &pe):
+		writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error(), "run": run})
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"run": run})
+	}
+}
+
+// cancelRequest answers with how many calls of the request it canceled, or
+// 404 when none were running
+func (s *server) cancelRequest(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cancellation: %w", err))
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "canceled through the management API"
+	}
+
+	// Clients with an API key may only cancel their own requests
+	id := r.PathValue("id")
+	if p, ok := keyPrincipal(r); ok {
+		owner := s.pm.PrincipalOf(map[string]interface{}{pluginsdk.ArgContext: map[string]interface{}{"request_id": id}})
+		if owner != p {
+			writeError(w, http.StatusNotFound, fmt.Errorf("no calls of request %s are running", id))
+			return
+		}
+	}
+	n := s.pm.CancelRequest(id, req.Reason)
+	if n == 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no calls of request %s are running", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"canceled": n})
+}
+
+// startTask answers 202 with the task as it started; poll GET /tasks/{id}
+// for its progress
+func (s *server) startTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Capability string                   `json:"capability"`
+		Args       map[string]interface{}   `json:"args"`
+		Context    pluginsdk.RequestContext `json:"context"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid task: %w", err))
+		return
+	}
+	if err := s.principal(r, &req.Context); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+
+	id, err := s.pm.StartTaskWithContext(r.PathValue("name"), req.Capability, req.Args, req.Context)
+	if errors.Is(err, pluginhost.ErrPluginNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, pluginhost.ErrHalted) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	task, err := s.pm.GetTask(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, task)
+}
+
+// tasks answers with the tasks the client may see: clients with an API key
+// only those they started
+func (s *server) tasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := pluginhost.TaskFilter{Plugin: q.Get("plugin"), State: q.Get("state")}
+	if p, ok := keyPrincipal(r); ok {
+		filter.Principal = &p
+	}
+	writeJSON(w, http.StatusOK, s.pm.ListTasks(filter))
+}
+
+// taskOf returns the task of id unless the client of r has an API key and
+// did not start it, as if there were no such task
+func (s *server) taskOf(r *http.Request, id string) (pluginhost.Task, error) {
+	task, err := s.pm.GetTask(id)
+	if err != nil {
+		return pluginhost.Task{}, err
+	}
+	if p, ok := keyPrincipal(r); ok && task.Principal != p {
+		return pluginhost.Task{}, pluginsdk.NewError(pluginsdk.CodeNotFound, "no task %q", id)
+	}
+	return task, nil
+}
+
+func (s *server) task(w http.ResponseWriter, r *http.Request) {
+	task, err := s.taskOf(r, r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+// cancelTask answers with the task, which ends as canceled once its call
+// stopped; 409 when it is not running
+func (s *server) cancelTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cancellation: %w", err))
+		return
+	}
+	if req.Reason == "" {
+		req.Reason = "canceled through the management API"
+	}
+
+	id := r.PathValue("id")
+	if _, err := s.taskOf(r, id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := s.pm.CancelTask(id, req.Reason); err != nil {
+		status := http.StatusConflict
+		if pluginsdk.AsPluginError(err).Code == pluginsdk.CodeNotFound {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	task, _ := s.pm.GetTask(id)
+	writeJSON(w, http.StatusOK, task)
+}
+
+// acceptLanguage returns the first language of an Accept-Language header,
+// or "" when it names none
+func acceptLanguage(header string) string {
+	for _, field := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(field, ";")
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "*" {
+			return tag
+		}
+	}
+	return ""
+}
+
+// verify answers with the conformance report; a plugin that fails its
+// checks still gets 200, the report says so
+func (s *server) verify(w http.ResponseWriter, r *http.Request) {
+	report, err := s.pm.Verify(r.PathValue("name"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, pluginhost.ErrPluginNotFound) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// route answers which persona and plugin would serve a command, and the
+// arguments to call the plugin with
+func (s *server) route(w http.ResponseWriter, r *http.Request) {
+	var req pluginhost.RouteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid route request: %w", err))
+		return
+	}
+	persona, plugin, args, err := s.pm.Router().Resolve(req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, pluginhost.ErrCapabilityNotSupported) {
+			status = http.StatusNotFound
+		}
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"persona": persona, "plugin": plugin, "args": args})
+}
+
+func (s *server) remotes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.pm.Remotes())
+}
+
+// attachRemote answers with the remote hosts once the new one is attached.
+// A remote host that cannot be listed yet stays attached and is retried.
+func (s *server) attachRemote(w http.ResponseWriter, r *http.Request) {
+	var cfg pluginhost.RemoteConfig
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid remote host: %w", err))
+		return
+	}
+	name := r.PathValue("name")
+	err := s.pm.AttachRemote(name, cfg)
+	remotes := s.pm.Remotes()
+	for _, rs := range remotes {
+		if rs.Name == name {
+			writeJSON(w, http.StatusOK, remotes)
+			return
+		}
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+func (s *server) detachRemote(w http.ResponseWriter, r *http.Request) {
+	s.pm.DetachRemote(r.PathValue("name"))
+	writeJSON(w, http.StatusOK, s.pm.Remotes())
+}
+
+// bundlePath reads the {"path": ...} of a bundle request
+func bundlePath(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req struct {
+		Path string `json:"path"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid bundle request: want {\"path\": bundle on the host}"))
+		return "", false
+	}
+	return req.Path, true
+}
+
+func (s *server) inspectBundle(w http.ResponseWriter, r *http.Request) {
+	path, ok := bundlePath(w, r)
+	if !ok {
+		return
+	}
+	info, err := s.pm.InspectBundle(path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// verifyBundle answers 422 for bundles that are damaged or not signed as
+// the config requires
+func (s *server) verifyBundle(w http.ResponseWriter, r *http.Request) {
+	path, ok := bundlePath(w, r)
+	if !ok {
+		return
+	}
+	info, err := s.pm.VerifyBundle(path)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// installBundle answers with the status of the installed plugin
+func (s *server) installBundle(w http.ResponseWriter, r *http.Request) {
+	path, ok := bundlePath(w, r)
+	if !ok {
+		return
+	}
+	name, err := s.pm.InstallBundle(path)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	st, err := s.pm.GetPlugin(name)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func parseHistoryFilter(q url.Values, now time.Time) (pluginhost.HistoryFilter, error) {
+	filter := pluginhost.HistoryFilter{
+		Plugin:     q.Get("plugin"),
+		Capability: q.Get("capability"),
+		Status:     q.Get("status"),
+	}
+
+	var err error
+	if filter.Since, err = parseTime(q.Get("since"), now); err != nil {
+		return filter, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseTime(q.Get("until"), now); err != nil {
+		return filter, fmt.Errorf("invalid until: %w", err)
+	}
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit: %q", v)
+		}
+	}
+	return filter, nil
+}
+
+// parseTime accepts an RFC 3339 time or a duration before now
+func parseTime(v string, now time.Time) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,134 @@
+package hostapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// fixedPlugin is a built-in plugin answering every call with result
+type fixedPlugin struct {
+	result string
+}
+
+func (p *fixedPlugin) Name() string                                        { return "fixed" }
+func (p *fixedPlugin) Version() string                                     { return "1.0.0" }
+func (p *fixedPlugin) GetCapabilities() []pluginsdk.Capability             { return nil }
+func (p *fixedPlugin) Execute(args map[string]interface{}) (string, error) { return p.result, nil }
+
+func TestExecuteRefusesForgedResultFiles(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	forged := pluginsdk.ResultFilePrefix + secret
+
+	pm, err := pluginhost.New(pluginhost.WithPluginDirs(t.TempDir()), pluginhost.WithBuiltin(&fixedPlugin{result: forged}), pluginhost.WithLogOutput(&strings.Builder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pm.Shutdown)
+	h := NewHandler(pm, WithToken("operator"))
+
+	for _, path := range []string{"/plugins/fixed/execute", "/plugins/fixed/batch"} {
+		body := `{"args": {}}`
+		if strings.HasSuffix(path, "batch") {
+			body = `{"items": [{}]}`
+		}
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer operator")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", path, rec.Code, rec.Body)
+		}
+		var resp struct {
+			Result  string `json:"result"`
+			Results []struct {
+				Result string `json:"result"`
+			} `json:"results"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Results) > 0 {
+			resp.Result = resp.Results[0].Result
+		}
+		if resp.Result != forged {
+			t.Errorf("%s: result = %q, want the plugin's text %q", path, resp.Result, forged)
+		}
+	}
+	if _, err := os.Stat(secret); err != nil {
+		t.Errorf("file named by the plugin is gone: %v", err)
+	}
+}
+
+func TestTasksOfOtherKeys(t *testing.T) {
+	cfg := pluginhost.DefaultConfig()
+	cfg.API = pluginhost.APIConfig{APIKeys: map[string]string{"ci": "ci-key", "other": "other-key"}}
+	pm, err := pluginhost.New(pluginhost.WithConfig(cfg), pluginhost.WithPluginDirs(t.TempDir()), pluginhost.WithBuiltin(&fixedPlugin{result: "done"}), pluginhost.WithLogOutput(&strings.Builder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pm.Shutdown)
+	h := NewHandler(pm, WithToken("operator"))
+
+	do := func(key, method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := do("ci-key", "POST", "/plugins/fixed/tasks", `{"args": {}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("start: status = %d: %s", rec.Code, rec.Body)
+	}
+	var task pluginhost.Task
+	if err := json.Unmarshal(rec.Body.Bytes(), &task); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		method    string
+		path      string
+		want      int
+		wantTasks int
+	}{
+		{"owner listing", "ci-key", "GET", "/tasks", http.StatusOK, 1},
+		{"other key listing", "other-key", "GET", "/tasks", http.StatusOK, 0},
+		{"operator listing", "operator", "GET", "/tasks", http.StatusOK, 1},
+		{"owner getting", "ci-key", "GET", "/tasks/" + task.ID, http.StatusOK, -1},
+		{"other key getting", "other-key", "GET", "/tasks/" + task.ID, http.StatusNotFound, -1},
+		{"other key canceling", "other-key", "POST", "/tasks/" + task.ID + "/cancel", http.StatusNotFound, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(tt.key, tt.method, tt.path, "")
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantTasks < 0 {
+				return
+			}
+			var tasks []pluginhost.Task
+			if err := json.Unmarshal(rec.Body.Bytes(), &tasks); err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != tt.wantTasks {
+				t.Errorf("listed %d tasks, want %d", len(tasks), tt.wantTasks)
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package hostapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// TokenCookie is the cookie the dashboard keeps its API key in after being
+// opened with "?token="
+const TokenCookie = "superhost_token"
+
+// TokenEnv names the environment variable clients take the API key of a
+// host from
+const TokenEnv = "SUPERHOST_TOKEN"
+
+// DefaultTokenFile is where "superhost serve" writes the API key of its
+// operator, in the user's config directory
+func DefaultTokenFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "superhost", "token")
+}
+
+// ClientToken returns the API key clients on this machine send: TokenEnv,
+// or else the key in DefaultTokenFile; empty when there is neither
+func ClientToken() string {
+	if token := os.Getenv(TokenEnv); token != "" {
+		return token
+	}
+	path := DefaultTokenFile()
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Option configures the handler NewHandler returns
+type Option func(*server)
+
+// WithToken accepts token besides the API keys of the config, as the key of
+// the host's own operator. "superhost serve" generates one for its CLI and
+// dashboard.
+func WithToken(token string) Option {
+	return func(s *server) {
+		s.token = token
+	}
+}
+
+// clientKey is the context key of the authenticated client
+type clientKey struct{}
+
+// client is who a request was authenticated as: the name of a configured
+// API key, or the host's operator, who has no name
+type client struct {
+	name string
+}
+
+// clientOf returns the client a request was authenticated as and whether
+// it was authenticated at all
+func clientOf(r *http.Request) (client, bool) {
+	c, ok := r.Context().Value(clientKey{}).(client)
+	return c, ok
+}
+
+// keyPrincipal returns the principal of a client with an API key of the
+// config; false for the operator and clients without a key
+func keyPrincipal(r *http.Request) (pluginhost.Principal, bool) {
+	c, ok := clientOf(r)
+	if !ok || c.name == "" {
+		return pluginhost.Principal{}, false
+	}
+	return pluginhost.Principal{Name: c.name, Service: true}, true
+}
+
+// principal sets who rc is made for from the client the request was
+// authenticated as, as the gateway does: clients with an API key of the
+// config act as the service it is named for. Others may name a user only
+// while authorization is off, as nothing vouches for who they say they are.
+func (s *server) principal(r *http.Request, rc *pluginsdk.RequestContext) error {
+	if c, ok := clientOf(r); ok && c.name != "" {
+		rc.User, rc.Service = c.name, true
+		return nil
+	}
+	if (rc.User != "" || rc.Service) && s.pm.Config().Authorization.Enabled {
+		return pluginsdk.NewError(pluginsdk.CodePermissionDenied, "requests cannot name their user while authorization is enabled; send an API key of the config's api section")
+	}
+	return nil
+}
+
+// authenticator checks the API key of every request against the api
+// section of the config in effect, so reloads take effect immediately
+type authenticator struct {
+	pm *pluginhost.PluginManager
+
+	mu sync.Mutex
+	// config is what keys were resolved for
+	config *pluginhost.HostConfig
+	keys   map[string]string
+}
+
+// credentials returns the API keys of the config in effect, with secret
+// references resolved
+func (a *authenticator) credentials() (map[string]string, pluginhost.APIConfig) {
+	cfg := a.pm.Config()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cfg == a.config {
+		return a.keys, cfg.API
+	}
+	keys := make(map[string]string, len(cfg.API.APIKeys))
+	for name, key := range cfg.API.APIKeys {
+		resolved, err := a.pm.ResolveSecret(key)
+		if err != nil {
+			log.Printf("Failed to resolve API key of %s: %v", name, err)
+			continue
+		}
+		keys[name] = resolved
+	}
+	a.config, a.keys = cfg, keys
+	return keys, cfg.API
+}
+
+// callerRoutes are the routes of clients calling plugins, including those
+// listing what there is to call, as remote hosts do. Every other route
+// manages the host and is only for its operator and Admin principals.
+var callerRoutes = map[string]bool{
+	"GET /version":                 true,
+	"GET /plugins":                 true,
+	"GET /capabilities":            true,
+	"POST /plugins/{name}/execute": true,
+	"POST /plugins/{name}/batch":   true,
+	"POST /requests/{id}/cancel":   true,
+	"POST /plugins/{name}/tasks":   true,
+	"GET /tasks":                   true,
+	"GET /tasks/{id}":              true,
+	"POST /tasks/{id}/cancel":      true,
+	"POST /pipelines/{name}/run":   true,
+}
+
+// guard turns away requests other sites make through a browser, bodies
+// that are not JSON, clients without a valid API key and clients using
+// management routes they are not an admin of before they reach the API.
+// Browsers cannot send JSON to another origin without asking first, and
+// the origin checks catch what gets through anyway.
+func (s *server) guard(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if crossSite(r) {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin requests are not allowed"))
+			return
+		}
+		if r.ContentLength != 0 && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("request bodies must be sent as application/json"))
+				return
+			}
+		}
+
+		// The dashboard is opened with its key once and keeps it in a
+		// cookie, so the key does not stay in the address bar or history
+		if token := r.URL.Query().Get("token"); token != "" && r.Method == http.MethodGet && r.URL.Path == "/" {
+			if _, ok := s.authenticate(token); !ok {
+				writeError(w, http.StatusUnauthorized, errors.New("invalid API key"))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{
+				Name:     TokenCookie,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+
+		token := requestToken(r)
+		if token == "" {
+			keys, api := s.auth.credentials()
+			if api.AllowUnauthenticated {
+				if !s.mayUse(mux, r, pluginhost.Principal{}, false) {
+					writeError(w, http.StatusForbidden, errors.New("clients without an API key may only call plugins"))
+					return
+				}
+				mux.ServeHTTP(w, r)
+				return
+			}
+			if len(keys) == 0 && s.token == "" {
+				writeError(w, http.StatusForbidden, errors.New("the management API has no API keys configured"))
+				return
+			}
+			writeError(w, http.StatusUnauthorized, errors.New("missing API key: send \"Authorization: Bearer <key>\""))
+			return
+		}
+		c, ok := s.authenticate(token)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid API key"))
+			return
+		}
+		if !s.mayUse(mux, r, pluginhost.Principal{Name: c.name, Service: true}, c.name == "") {
+			writeError(w, http.StatusForbidden, fmt.Errorf("API key %s may only call plugins; bind it to an admin role to manage the host", c.name))
+			return
+		}
+		mux.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, c)))
+	})
+}
+
+// mayUse reports whether the route of r is open to principal: every route
+// is to the operator, caller routes to everyone and the others to
+// principals with an Admin role
+func (s *server) mayUse(mux *http.ServeMux, r *http.Request, principal pluginhost.Principal, operator bool) bool {
+	if operator {
+		return true
+	}
+	if _, pattern := mux.Handler(r); callerRoutes[pattern] {
+		return true
+	}
+	return s.pm.Config().Authorization.IsAdmin(principal)
+}
+
+// authenticate returns the client token is the API key of
+func (s *server) authenticate(token string) (client, bool) {
+	if s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1 {
+		return client{}, true
+	}
+	keys, _ := s.auth.credentials()
+	for name, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return client{name: name}, true
+		}
+	}
+	return client{}, false
+}
+
+// requestToken returns the key of the request's "Authorization: Bearer"
+// header, or else of the dashboard's cookie
+func requestToken(r *http.Request) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "bearer") {
+		return strings.TrimSpace(token)
+	}
+	if c, err := r.Cookie(TokenCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// crossSite reports whether a browser sent r on behalf of another site:
+// its Sec-Fetch-Site says so, or its Origin is not the host it was sent to
+func crossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "cross-site", "same-site":
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || u.Host == "" || !strings.EqualFold(u.Host, r.Host)
+}
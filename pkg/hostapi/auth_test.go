@@ -0,0 +1,148 @@
+package hostapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+func newTestHandler(t *testing.T, cfg *pluginhost.HostConfig, opts ...Option) http.Handler {
+	t.Helper()
+	pm, err := pluginhost.New(pluginhost.WithConfig(cfg), pluginhost.WithPluginDirs(t.TempDir()), pluginhost.WithLogOutput(&strings.Builder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pm.Shutdown)
+	return NewHandler(pm, opts...)
+}
+
+func TestGuard(t *testing.T) {
+	keys := pluginhost.APIConfig{APIKeys: map[string]string{"ci": "ci-key"}}
+	tests := []struct {
+		name    string
+		api     pluginhost.APIConfig
+		method  string
+		path    string
+		headers map[string]string
+		body    string
+		want    int
+	}{
+		{"no key", keys, "GET", "/version", nil, "", http.StatusUnauthorized},
+		{"wrong key", keys, "GET", "/version", map[string]string{"Authorization": "Bearer nope"}, "", http.StatusUnauthorized},
+		{"config key", keys, "GET", "/version", map[string]string{"Authorization": "Bearer ci-key"}, "", http.StatusOK},
+		{"operator token", keys, "GET", "/version", map[string]string{"Authorization": "Bearer operator"}, "", http.StatusOK},
+		{"cookie", keys, "GET", "/version", map[string]string{"Cookie": TokenCookie + "=operator"}, "", http.StatusOK},
+		{"dashboard without key", keys, "GET", "/", nil, "", http.StatusUnauthorized},
+		{"dashboard with key", keys, "GET", "/?token=operator", nil, "", http.StatusSeeOther},
+		{"dashboard with wrong key", keys, "GET", "/?token=nope", nil, "", http.StatusUnauthorized},
+		{"other origin", keys, "GET", "/version", map[string]string{"Authorization": "Bearer operator", "Origin": "http://evil.example"}, "", http.StatusForbidden},
+		{"same origin", keys, "GET", "/version", map[string]string{"Authorization": "Bearer operator", "Origin": "http://localhost:8080"}, "", http.StatusOK},
+		{"cross-site fetch", keys, "GET", "/version", map[string]string{"Cookie": TokenCookie + "=operator", "Sec-Fetch-Site": "cross-site"}, "", http.StatusForbidden},
+		{"text body", keys, "PUT", "/mode", map[string]string{"Authorization": "Bearer operator", "Content-Type": "text/plain"}, `{"mode":"normal"}`, http.StatusUnsupportedMediaType},
+		{"json body", keys, "PUT", "/mode", map[string]string{"Authorization": "Bearer operator", "Content-Type": "application/json; charset=utf-8"}, `{"mode":"normal"}`, http.StatusOK},
+		{"no keys configured", pluginhost.APIConfig{}, "GET", "/version", nil, "", http.StatusForbidden},
+		{"unauthenticated allowed", pluginhost.APIConfig{APIKeys: map[string]string{"ci": "ci-key"}, AllowUnauthenticated: true}, "GET", "/version", nil, "", http.StatusOK},
+		{"unauthenticated managing", pluginhost.APIConfig{APIKeys: map[string]string{"ci": "ci-key"}, AllowUnauthenticated: true}, "PUT", "/mode", map[string]string{"Content-Type": "application/json"}, `{"mode":"normal"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if len(tt.api.APIKeys) > 0 {
+				opts = append(opts, WithToken("operator"))
+			}
+			cfg := pluginhost.DefaultConfig()
+			cfg.API = tt.api
+			h := newTestHandler(t, cfg, opts...)
+
+			req := httptest.NewRequest(tt.method, "http://localhost:8080"+tt.path, strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestExecuteIdentity(t *testing.T) {
+	cfg := pluginhost.DefaultConfig()
+	cfg.API = pluginhost.APIConfig{APIKeys: map[string]string{"ci": "ci-key"}}
+	cfg.Authorization = pluginhost.AuthorizationConfig{
+		Enabled:  true,
+		Roles:    map[string]pluginhost.Role{"all": {Allow: []string{"*"}}},
+		Bindings: map[string][]string{"service:ci": {"all"}},
+	}
+	h := newTestHandler(t, cfg, WithToken("operator"))
+
+	tests := []struct {
+		name string
+		key  string
+		body string
+		want int
+	}{
+		{"operator naming a user", "operator", `{"context": {"user": "ada"}}`, http.StatusForbidden},
+		{"operator naming a bound service", "operator", `{"context": {"user": "ci", "service": true}}`, http.StatusForbidden},
+		{"operator without identity", "operator", `{"context": {}}`, http.StatusForbidden},
+		{"bound key", "ci-key", `{}`, http.StatusNotFound},
+		{"bound key naming a user", "ci-key", `{"context": {"user": "ada"}}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/plugins/missing/execute", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestManagementRoutesNeedAdmin(t *testing.T) {
+	cfg := pluginhost.DefaultConfig()
+	cfg.API = pluginhost.APIConfig{APIKeys: map[string]string{"ci": "ci-key", "deploy": "deploy-key"}}
+	cfg.Authorization = pluginhost.AuthorizationConfig{
+		Roles:    map[string]pluginhost.Role{"deployer": {Admin: true}},
+		Bindings: map[string][]string{"service:deploy": {"deployer"}},
+	}
+	h := newTestHandler(t, cfg, WithToken("operator"))
+
+	tests := []struct {
+		name   string
+		key    string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"key applying config", "ci-key", "POST", "/apply", `{}`, http.StatusForbidden},
+		{"key halting", "ci-key", "PUT", "/halt", `{"reason": "x"}`, http.StatusForbidden},
+		{"key resuming", "ci-key", "DELETE", "/halt", "", http.StatusForbidden},
+		{"key reloading", "ci-key", "POST", "/plugins/missing/reload", "", http.StatusForbidden},
+		{"key listing plugins", "ci-key", "GET", "/plugins", "", http.StatusOK},
+		{"key executing", "ci-key", "POST", "/plugins/missing/execute", `{}`, http.StatusNotFound},
+		{"key listing tasks", "ci-key", "GET", "/tasks", "", http.StatusOK},
+		{"admin key halting", "deploy-key", "GET", "/halt", "", http.StatusOK},
+		{"operator halting", "operator", "GET", "/halt", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package hostapi
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// dashboardPage is the web dashboard, a single page using the API's own
+// endpoints
+//
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// dashboard serves the web dashboard: plugin status, capabilities, call
+// and resource charts, logs, and buttons to reload, disable, verify and
+// execute plugins
+func (s *server) dashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardPage)
+}
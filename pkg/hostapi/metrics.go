@@ -0,0 +1,89 @@
+package hostapi
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// metrics writes plugin and event bus metrics in the Prometheus text format
+func (s *server) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	plugins := s.pm.ListPlugins()
+	family(w, "plugin_calls_total", "counter", "Calls made to the plugin.")
+	for _, p := range plugins {
+		sample(w, "plugin_calls_total", "plugin", p.Name, float64(p.Calls))
+	}
+	family(w, "plugin_failures_total", "counter", "Calls to the plugin that failed.")
+	for _, p := range plugins {
+		sample(w, "plugin_failures_total", "plugin", p.Name, float64(p.Failures))
+	}
+	family(w, "plugin_timeouts_total", "counter", "Calls to the plugin that ran out of time.")
+	for _, p := range plugins {
+		sample(w, "plugin_timeouts_total", "plugin", p.Name, float64(p.Timeouts))
+	}
+	family(w, "plugin_llm_requests_total", "counter", "LLM requests the plugin made through the host.")
+	for _, p := range plugins {
+		if p.LLM != nil {
+			sample(w, "plugin_llm_requests_total", "plugin", p.Name, float64(p.LLM.Requests))
+		}
+	}
+	family(w, "plugin_llm_tokens_total", "counter", "Input and output tokens of the plugin's LLM requests.")
+	for _, p := range plugins {
+		if p.LLM != nil {
+			sample(w, "plugin_llm_tokens_total", "plugin", p.Name, float64(p.LLM.InputTokens+p.LLM.OutputTokens))
+		}
+	}
+	family(w, "plugin_llm_cost_usd_total", "counter", "Estimated cost of the plugin's LLM requests in US dollars.")
+	for _, p := range plugins {
+		if p.LLM != nil {
+			sample(w, "plugin_llm_cost_usd_total", "plugin", p.Name, p.LLM.CostUSD)
+		}
+	}
+	family(w, "llm_budget_used_ratio", "gauge", "Share of the LLM budget spent in its current period; 1 refuses further requests.")
+	for _, b := range s.pm.LLMBudgets() {
+		sample(w, "llm_budget_used_ratio", "budget", b.Name, b.Used())
+	}
+	family(w, "plugin_cpu_percent", "gauge", "CPU used by the plugin process since the previous sample; 100 is one core.")
+	for _, p := range plugins {
+		if p.Resources != nil {
+			sample(w, "plugin_cpu_percent", "plugin", p.Name, p.Resources.CPUPercent)
+		}
+	}
+	family(w, "plugin_rss_bytes", "gauge", "Resident memory of the plugin process.")
+	for _, p := range plugins {
+		if p.Resources != nil {
+			sample(w, "plugin_rss_bytes", "plugin", p.Name, float64(p.Resources.RSSBytes))
+		}
+	}
+
+	subs := s.pm.EventStats()
+	family(w, "event_subscription_dropped_total", "counter", "Events dropped because the subscriber fell behind.")
+	for _, st := range subs {
+		sample(w, "event_subscription_dropped_total", "subscription", st.Name, float64(st.Dropped))
+	}
+	family(w, "event_subscription_spilled_total", "counter", "Events queued on disk for the subscriber.")
+	for _, st := range subs {
+		sample(w, "event_subscription_spilled_total", "subscription", st.Name, float64(st.Spilled))
+	}
+	family(w, "event_subscription_queued", "gauge", "Events waiting for the subscriber.")
+	for _, st := range subs {
+		sample(w, "event_subscription_queued", "subscription", st.Name, float64(st.Queued))
+	}
+}
+
+func family(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func sample(w io.Writer, name, label, value string, v float64) {
+	fmt.Fprintf(w, "%s{%s=%s} %s\n", name, label, quoteLabel(value), strconv.FormatFloat(v, 'g', -1, 64))
+}
+
+// quoteLabel escapes a label value as the text format requires
+func quoteLabel(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
@@ -0,0 +1,52 @@
+// Package hostdaemon runs a plugin host as a long-lived managed service. It
+// handles the stop and reload signals, writes a PID file, tells systemd when
+// the host is ready, rotates the host's log file and installs the host as a
+// systemd or Windows service.
+package hostdaemon
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Run blocks until the service manager or a signal stops the daemon. It is
+// called once the host is ready to serve: Run reports readiness to systemd
+// and keeps its watchdog fed. SIGHUP, or a Windows parameter change, calls
+// reload; SIGINT and SIGTERM, or a Windows stop or shutdown request, return.
+// Under the Windows service control manager name is the service's name.
+func Run(name string, reload func()) error {
+	if runsAsService() {
+		return runService(name, reload)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	stopWatchdog := make(chan struct{})
+	defer close(stopWatchdog)
+	go Watchdog(stopWatchdog)
+	notify(Ready)
+	defer notify(Stopping)
+
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			log.Printf("Received %v, shutting down", sig)
+			return nil
+		}
+		log.Printf("Received %v, reloading", sig)
+		notify(Reloading)
+		reload()
+		notify(Ready)
+	}
+	return nil
+}
+
+// notify sends a state to systemd, logging failures
+func notify(state string) {
+	if _, err := Notify(state); err != nil {
+		log.Printf("Failed to notify systemd of %q: %v", state, err)
+	}
+}
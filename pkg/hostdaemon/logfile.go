@@ -0,0 +1,116 @@
+package hostdaemon
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// LogFile is an append-only log file that rotates itself by size and can be
+// reopened after an external tool such as logrotate moved it. It is safe
+// for concurrent use, so the host's logger and plugin output may share it.
+type LogFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenLogFile opens the log file at path for appending. Once a write would
+// take it past maxSize bytes it is renamed to path.1, older files move up
+// to path.maxBackups and the oldest is deleted. A maxSize of 0 never
+// rotates.
+func OpenLogFile(path string, maxSize int64, maxBackups int) (*LogFile, error) {
+	l := &LogFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the file at l.path; the caller holds l.mu
+func (l *LogFile) open() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Write appends p, rotating first when p would not fit
+func (l *LogFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return 0, os.ErrClosed
+	}
+	if l.maxSize > 0 && l.size > 0 && l.size+int64(len(p)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate %s: %w", l.path, err)
+		}
+	}
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// rotate moves the backups up one place and starts a new file; the caller
+// holds l.mu
+func (l *LogFile) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	if l.maxBackups > 0 {
+		os.Remove(backupName(l.path, l.maxBackups))
+		for i := l.maxBackups - 1; i >= 1; i-- {
+			os.Rename(backupName(l.path, i), backupName(l.path, i+1))
+		}
+		if err := os.Rename(l.path, backupName(l.path, 1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(l.path); err != nil {
+		return err
+	}
+	return l.open()
+}
+
+func backupName(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Reopen closes the file and opens path again, for use on SIGHUP after
+// logrotate renamed it
+func (l *LogFile) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+	return l.open()
+}
+
+// Close closes the file; later writes fail
+func (l *LogFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
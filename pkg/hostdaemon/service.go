@@ -0,0 +1,23 @@
+package hostdaemon
+
+// Service describes the host for the operating system's service manager
+type Service struct {
+	// Name identifies the service, e.g. "opencode-host"
+	Name string
+
+	// Description is shown by systemctl status or the Windows services
+	// console
+	Description string
+
+	// Executable is the absolute path of the host binary
+	Executable string
+
+	// Args are passed to the host when the service starts; they should
+	// select daemon mode and an absolute config path
+	Args []string
+
+	// User runs the service under this account instead of root or
+	// LocalSystem; on Windows only accounts without a password work, such
+	// as "NT AUTHORITY\\LocalService"
+	User string
+}
@@ -0,0 +1,89 @@
+package hostdaemon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SystemdUnitDir is where Install writes unit files
+var SystemdUnitDir = "/etc/systemd/system"
+
+// Install writes a systemd unit for the service and enables it, so it
+// starts at boot; start it with "systemctl start <name>". The unit waits
+// for the host's readiness notification, reloads it with SIGHUP and
+// restarts it when it fails.
+func Install(s Service) error {
+	path := unitPath(s.Name)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("service %s is already installed at %s", s.Name, path)
+	}
+	if err := os.WriteFile(path, []byte(unit(s)), 0644); err != nil {
+		return err
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	return systemctl("enable", s.Name)
+}
+
+// Uninstall stops and disables the service and removes its unit
+func Uninstall(name string) error {
+	path := unitPath(name)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	if err := systemctl("disable", "--now", name); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return systemctl("daemon-reload")
+}
+
+func unitPath(name string) string {
+	return filepath.Join(SystemdUnitDir, name+".service")
+}
+
+// unit returns the systemd unit running the service
+func unit(s Service) string {
+	command := []string{unitQuote(s.Executable)}
+	for _, a := range s.Args {
+		command = append(command, unitQuote(a))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "[Unit]\nDescription=%s\nWants=network-online.target\nAfter=network-online.target\n\n", s.Description)
+	fmt.Fprintf(&b, "[Service]\nType=notify\nExecStart=%s\nExecReload=/bin/kill -HUP $MAINPID\n", strings.Join(command, " "))
+	b.WriteString("Restart=on-failure\nRestartSec=5s\nTimeoutStopSec=30s\n")
+	if s.User != "" {
+		fmt.Fprintf(&b, "User=%s\n", s.User)
+	}
+	b.WriteString("\n[Install]\nWantedBy=multi-user.target\n")
+	return b.String()
+}
+
+// unitQuote quotes an argument of ExecStart, escaping the characters
+// systemd would expand
+func unitQuote(arg string) string {
+	arg = strings.NewReplacer("%", "%%", "$", "$$").Replace(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// systemctl runs systemctl, returning its output as the error when it fails
+func systemctl(args ...string) error {
+	out, err := exec.Command("systemctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("systemctl %s: %s", strings.Join(args, " "), msg)
+		}
+		return fmt.Errorf("systemctl %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
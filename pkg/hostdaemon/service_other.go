@@ -0,0 +1,19 @@
+//go:build !linux && !windows
+
+package hostdaemon
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Install fails because services are only installed with systemd or the
+// Windows service control manager
+func Install(s Service) error {
+	return fmt.Errorf("installing a service is not supported on %s", runtime.GOOS)
+}
+
+// Uninstall fails like Install
+func Uninstall(name string) error {
+	return fmt.Errorf("uninstalling a service is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+
+package hostdaemon
+
+import (
+	"errors"
+	"syscall"
+)
+
+// runsAsService reports whether a service control manager the daemon must
+// answer started the process; only Windows has one
+func runsAsService() bool {
+	return false
+}
+
+func runService(name string, reload func()) error {
+	return errors.New("service control is only supported on Windows")
+}
+
+// processRunning reports whether a process with the ID exists; a process
+// of another user counts, even though it may not be signaled
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
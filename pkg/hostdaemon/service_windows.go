@@ -0,0 +1,119 @@
+package hostdaemon
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// stillActive is the exit code of a process that has not exited
+const stillActive = 259
+
+// runsAsService reports whether the service control manager started the
+// process, which must then answer it
+func runsAsService() bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		log.Printf("Failed to detect the service control manager: %v", err)
+	}
+	return isService
+}
+
+// runService answers the service control manager until it stops the
+// service
+func runService(name string, reload func()) error {
+	return svc.Run(name, &handler{reload: reload})
+}
+
+// handler turns service control requests into reloads and the stop
+type handler struct {
+	reload func()
+}
+
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptParamChange
+	status <- svc.Status{State: svc.Running, Accepts: accepts}
+	for r := range requests {
+		switch r.Cmd {
+		case svc.Interrogate:
+			status <- r.CurrentStatus
+		case svc.ParamChange:
+			log.Printf("Received parameter change, reloading")
+			h.reload()
+		case svc.Stop, svc.Shutdown:
+			log.Printf("Received service stop, shutting down")
+			status <- svc.Status{State: svc.StopPending}
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// Install registers the service to start automatically at boot and to be
+// restarted when it fails; start it with "sc start <name>"
+func Install(s Service) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if existing, err := m.OpenService(s.Name); err == nil {
+		existing.Close()
+		return fmt.Errorf("service %s is already installed", s.Name)
+	}
+	service, err := m.CreateService(s.Name, s.Executable, mgr.Config{
+		DisplayName:      s.Name,
+		Description:      s.Description,
+		StartType:        mgr.StartAutomatic,
+		ServiceStartName: s.User,
+	}, s.Args...)
+	if err != nil {
+		return err
+	}
+	defer service.Close()
+
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+	if err := service.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("failed to set restart on failure: %w", err)
+	}
+	return nil
+}
+
+// Uninstall stops the service and removes it
+func Uninstall(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	service, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", name)
+	}
+	defer service.Close()
+
+	// Stopping fails when it is not running, which is fine
+	service.Control(svc.Stop)
+	return service.Delete()
+}
+
+// processRunning reports whether a process with the ID exists and has not
+// exited
+func processRunning(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
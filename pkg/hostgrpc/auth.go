@@ -0,0 +1,140 @@
+package hostgrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// clientKey is the context key of the authenticated client
+type clientKey struct{}
+
+// client is who a call was authenticated as: a user of the OIDC provider,
+// or the service an API key belongs to
+type client struct {
+	name    string
+	service bool
+}
+
+// clientOf returns the client a call was authenticated as
+func clientOf(ctx context.Context) client {
+	c, _ := ctx.Value(clientKey{}).(client)
+	return c
+}
+
+// authenticator checks the bearer token of every call against the gateway
+// section of the config in effect, so reloads take effect immediately
+type authenticator struct {
+	pm *pluginhost.PluginManager
+
+	mu sync.Mutex
+	// config is what keys and verifier were set up for
+	config   *pluginhost.HostConfig
+	keys     map[string]string
+	verifier *oidcVerifier
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticatedStream carries the authenticated client in its context
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate returns ctx with the client the call's token belongs to
+func (a *authenticator) authenticate(ctx context.Context) (context.Context, error) {
+	keys, verifier, gw := a.credentials()
+	if len(keys) == 0 && verifier == nil {
+		if gw.AllowUnauthenticated {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.PermissionDenied, "the gateway has no API keys or OIDC provider configured")
+	}
+
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	for name, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return context.WithValue(ctx, clientKey{}, client{name: name, service: true}), nil
+		}
+	}
+	if verifier != nil {
+		user, err := verifier.verify(ctx, token)
+		if err == nil {
+			return context.WithValue(ctx, clientKey{}, client{name: user}), nil
+		}
+		log.Printf("Rejected gateway token: %v", err)
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+}
+
+// credentials returns the API keys, with secret references resolved, and
+// the OIDC verifier of the config in effect
+func (a *authenticator) credentials() (map[string]string, *oidcVerifier, pluginhost.GatewayConfig) {
+	cfg := a.pm.Config()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if cfg == a.config {
+		return a.keys, a.verifier, cfg.Gateway
+	}
+	keys := make(map[string]string, len(cfg.Gateway.APIKeys))
+	for name, key := range cfg.Gateway.APIKeys {
+		resolved, err := a.pm.ResolveSecret(key)
+		if err != nil {
+			log.Printf("Failed to resolve gateway API key of %s: %v", name, err)
+			continue
+		}
+		keys[name] = resolved
+	}
+	verifier := a.verifier
+	if o := cfg.Gateway.OIDC; o == nil {
+		verifier = nil
+	} else if verifier == nil || verifier.config != *o {
+		verifier = newOIDCVerifier(*o)
+	}
+	a.config, a.keys, a.verifier = cfg, keys, verifier
+	return keys, verifier, cfg.Gateway
+}
+
+// bearerToken returns the token of the call's "authorization: Bearer"
+// metadata
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if scheme, token, ok := strings.Cut(v, " "); ok && strings.EqualFold(scheme, "bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return ""
+}
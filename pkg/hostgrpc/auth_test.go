@@ -0,0 +1,79 @@
+package hostgrpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+func newTestAuthenticator(t *testing.T, gw pluginhost.GatewayConfig) *authenticator {
+	t.Helper()
+	cfg := pluginhost.DefaultConfig()
+	cfg.Gateway = gw
+	pm, err := pluginhost.New(pluginhost.WithConfig(cfg), pluginhost.WithPluginDirs(t.TempDir()), pluginhost.WithLogOutput(&strings.Builder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pm.Shutdown)
+	return &authenticator{pm: pm}
+}
+
+func TestAuthenticate(t *testing.T) {
+	p := newProvider(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.add("k1", key)
+	token := sign(t, "ES256", "k1", key, map[string]interface{}{"iss": p.URL, "aud": "superhost", "sub": "ada", "exp": time.Now().Add(time.Hour).Unix()})
+
+	keys := pluginhost.GatewayConfig{APIKeys: map[string]string{"billing": "billing-key"}}
+	withOIDC := pluginhost.GatewayConfig{APIKeys: keys.APIKeys, OIDC: &pluginhost.OIDCConfig{Issuer: p.URL, Audience: "superhost"}}
+	tests := []struct {
+		name   string
+		gw     pluginhost.GatewayConfig
+		header string
+		want   client
+		code   codes.Code
+	}{
+		{"API key", keys, "Bearer billing-key", client{name: "billing", service: true}, codes.OK},
+		{"lower-case scheme", keys, "bearer billing-key", client{name: "billing", service: true}, codes.OK},
+		{"wrong key", keys, "Bearer nope", client{}, codes.Unauthenticated},
+		{"no token", keys, "", client{}, codes.Unauthenticated},
+		{"other scheme", keys, "Basic billing-key", client{}, codes.Unauthenticated},
+		{"OIDC token", withOIDC, "Bearer " + token, client{name: "ada"}, codes.OK},
+		{"API key besides OIDC", withOIDC, "Bearer billing-key", client{name: "billing", service: true}, codes.OK},
+		{"OIDC token without a provider", keys, "Bearer " + token, client{}, codes.Unauthenticated},
+		{"nothing configured", pluginhost.GatewayConfig{}, "Bearer billing-key", client{}, codes.PermissionDenied},
+		{"unauthenticated allowed", pluginhost.GatewayConfig{AllowUnauthenticated: true}, "", client{}, codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestAuthenticator(t, tt.gw)
+			ctx := context.Background()
+			if tt.header != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.header))
+			}
+			ctx, err := a.authenticate(ctx)
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %s, want %s: %v", code, tt.code, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := clientOf(ctx); got != tt.want {
+				t.Errorf("client = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
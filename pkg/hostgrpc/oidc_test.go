@@ -0,0 +1,232 @@
+package hostgrpc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+)
+
+// provider is an OpenID Connect provider publishing the public halves of
+// its keys by ID
+type provider struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]crypto.Signer
+	fetches int
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	p := &provider{keys: make(map[string]crypto.Signer)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": p.URL + "/jwks"})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.fetches++
+		var set []jwk
+		for kid, key := range p.keys {
+			set = append(set, toJWK(t, kid, key.Public()))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": set})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// add publishes key under kid
+func (p *provider) add(kid string, key crypto.Signer) {
+	p.mu.Lock()
+	p.keys[kid] = key
+	p.mu.Unlock()
+}
+
+func (p *provider) fetchCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fetches
+}
+
+func toJWK(t *testing.T, kid string, pub crypto.PublicKey) jwk {
+	enc := base64.RawURLEncoding.EncodeToString
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		return jwk{Kty: "RSA", Kid: kid, N: enc(pub.N.Bytes()), E: enc([]byte{1, 0, 1})}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return jwk{Kty: "EC", Kid: kid, Crv: pub.Curve.Params().Name, X: enc(pub.X.FillBytes(make([]byte, size))), Y: enc(pub.Y.FillBytes(make([]byte, size)))}
+	}
+	t.Fatalf("unsupported key %T", pub)
+	return jwk{}
+}
+
+// sign returns a JWT of claims signed by key with alg
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+
+	hash := map[string]crypto.Hash{"RS256": crypto.SHA256, "ES256": crypto.SHA256, "ES384": crypto.SHA384}[alg]
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, key, hash, digest); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestOIDCVerify(t *testing.T) {
+	p := newProvider(t)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.add("rsa", rsaKey)
+	p.add("p256", p256)
+	p.add("p384", p384)
+
+	now := time.Now()
+	claims := func(change func(c map[string]interface{})) map[string]interface{} {
+		c := map[string]interface{}{"iss": p.URL, "aud": "superhost", "sub": "ada", "exp": now.Add(time.Hour).Unix()}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+	v := newOIDCVerifier(pluginhost.OIDCConfig{Issuer: p.URL, Audience: "superhost"})
+
+	tampered := sign(t, "RS256", "rsa", rsaKey, claims(nil))
+	parts := strings.Split(tampered, ".")
+	other, _ := json.Marshal(claims(func(c map[string]interface{}) { c["sub"] = "mallory" }))
+	tampered = parts[0] + "." + base64.RawURLEncoding.EncodeToString(other) + "." + parts[2]
+
+	none, _ := json.Marshal(map[string]string{"alg": "none", "kid": "rsa"})
+	unsigned := base64.RawURLEncoding.EncodeToString(none) + "." + parts[1] + "."
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"RS256", sign(t, "RS256", "rsa", rsaKey, claims(nil)), false},
+		{"ES256", sign(t, "ES256", "p256", p256, claims(nil)), false},
+		{"ES384", sign(t, "ES384", "p384", p384, claims(nil)), false},
+		{"audience list", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { c["aud"] = []string{"other", "superhost"} })), false},
+		{"within clock skew", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { c["exp"] = now.Add(-clockSkew / 2).Unix() })), false},
+		{"other issuer", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { c["iss"] = "https://evil.example" })), true},
+		{"other audience", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { c["aud"] = "other" })), true},
+		{"no audience", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { delete(c, "aud") })), true},
+		{"expired", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { c["exp"] = now.Add(-2 * clockSkew).Unix() })), true},
+		{"no expiry", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { delete(c, "exp") })), true},
+		{"not valid yet", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { c["nbf"] = now.Add(2 * clockSkew).Unix() })), true},
+		{"no user", sign(t, "RS256", "rsa", rsaKey, claims(func(c map[string]interface{}) { delete(c, "sub") })), true},
+		{"tampered claims", tampered, true},
+		{"RSA algorithm with an EC key", sign(t, "RS256", "p256", rsaKey, claims(nil)), true},
+		{"EC algorithm with an RSA key", sign(t, "ES256", "rsa", p256, claims(nil)), true},
+		{"ES256 with a P-384 key", sign(t, "ES256", "p384", p384, claims(nil)), true},
+		{"ES384 with a P-256 key", sign(t, "ES384", "p256", p256, claims(nil)), true},
+		{"unsigned", unsigned, true},
+		{"not a JWT", "abc.def", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := v.verify(context.Background(), tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verify error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && user != "ada" {
+				t.Errorf("user = %q, want ada", user)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRefetch(t *testing.T) {
+	p := newProvider(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.add("old", key)
+	v := newOIDCVerifier(pluginhost.OIDCConfig{Issuer: p.URL, Audience: "superhost"})
+	claims := map[string]interface{}{"iss": p.URL, "aud": "superhost", "sub": "ada", "exp": time.Now().Add(time.Hour).Unix()}
+
+	if _, err := v.verify(context.Background(), sign(t, "ES256", "old", key, claims)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens of unknown keys fetch the keys at most once per interval
+	for i := 0; i < 3; i++ {
+		if _, err := v.verify(context.Background(), sign(t, "ES256", "unknown", key, claims)); err == nil {
+			t.Fatal("token of an unknown key was accepted")
+		}
+	}
+	if n := p.fetchCount(); n != 1 {
+		t.Errorf("keys fetched %d times, want once", n)
+	}
+
+	// A rotated key is picked up once the interval passed
+	next, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.add("next", next)
+	token := sign(t, "ES256", "next", next, claims)
+	if _, err := v.verify(context.Background(), token); err == nil {
+		t.Fatal("keys were fetched again within the interval")
+	}
+	v.mu.Lock()
+	v.fetched = v.fetched.Add(-refetchInterval)
+	v.mu.Unlock()
+	if _, err := v.verify(context.Background(), token); err != nil {
+		t.Fatalf("token of the rotated key: %v", err)
+	}
+	if n := p.fetchCount(); n != 2 {
+		t.Errorf("keys fetched %d times, want twice", n)
+	}
+}
@@ -0,0 +1,216 @@
+// Package hostgrpc serves a plugin manager's capabilities to other services
+// over gRPC, as the CapabilityGateway service of pkg/pluginsdk/proto. It is
+// the public counterpart of the management API in package hostapi.
+package hostgrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// chunkSize is how much of a result one CapabilityChunk carries
+const chunkSize = 64 << 10
+
+// Trailer keys carrying the plugin's error details of a failed call
+const (
+	TrailerErrorCode = "x-error-code"
+	TrailerRetryable = "x-retryable"
+)
+
+// NewServer returns a gRPC server offering the capabilities of pm to other
+// services. Calls are authenticated as the gateway section of pm's config
+// says; pass grpc.Creds to serve TLS, as bearer tokens should not travel in
+// the clear.
+func NewServer(pm *pluginhost.PluginManager, opts ...grpc.ServerOption) *grpc.Server {
+	auth := &authenticator{pm: pm}
+	opts = append(opts, grpc.ChainUnaryInterceptor(auth.unary), grpc.ChainStreamInterceptor(auth.stream))
+	s := grpc.NewServer(opts...)
+	proto.RegisterCapabilityGatewayServer(s, &gateway{pm: pm})
+	return s
+}
+
+type gateway struct {
+	proto.UnimplementedCapabilityGatewayServer
+	pm *pluginhost.PluginManager
+}
+
+func (g *gateway) ExecuteCapability(ctx context.Context, req *proto.ExecuteCapabilityRequest) (*proto.ExecuteCapabilityResponse, error) {
+	plugin, requestID, res, err := g.execute(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	result := res.Result
+	if path, spilled := g.pm.ResultFile(result); spilled {
+		data, err := os.ReadFile(path)
+		os.Remove(path)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read spilled result: %v", err)
+		}
+		result = string(data)
+	}
+	return &proto.ExecuteCapabilityResponse{Result: result, Plugin: plugin, RequestId: requestID, Partial: res.Partial}, nil
+}
+
+// StreamCapability sends the result in chunks; spilled results are read
+// from their file as they are sent, so they never have to fit in memory
+func (g *gateway) StreamCapability(req *proto.ExecuteCapabilityRequest, stream proto.CapabilityGateway_StreamCapabilityServer) error {
+	plugin, requestID, res, err := g.execute(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	var r io.Reader
+	if path, spilled := g.pm.ResultFile(res.Result); spilled {
+		f, err := os.Open(path)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read spilled result: %v", err)
+		}
+		defer os.Remove(path)
+		defer f.Close()
+		r = f
+	} else {
+		r = strings.NewReader(res.Result)
+	}
+
+	first := &proto.CapabilityChunk{Plugin: plugin, RequestId: requestID, Partial: res.Partial}
+	buf := make([]byte, chunkSize)
+	for sent := false; ; sent = true {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 || !sent {
+			chunk := &proto.CapabilityChunk{Data: append([]byte(nil), buf[:n]...)}
+			if !sent {
+				first.Data = chunk.Data
+				chunk = first
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to read spilled result: %v", err)
+		}
+	}
+}
+
+// ListCapabilities lists what FindCapabilities finds, so tags added in the
+// search config count here too
+func (g *gateway) ListCapabilities(ctx context.Context, req *proto.ListCapabilitiesRequest) (*proto.ListCapabilitiesResponse, error) {
+	var query []string
+	if req.GetPlugin() != "" {
+		query = append(query, "plugin:"+req.GetPlugin())
+	}
+	if req.GetTag() != "" {
+		query = append(query, "tag:"+req.GetTag())
+	}
+
+	resp := &proto.ListCapabilitiesResponse{}
+	for _, m := range g.pm.FindCapabilities(strings.Join(query, " ")) {
+		pc, err := pluginsdk.CapabilityToProto(m.Capability)
+		if err != nil {
+			log.Printf("Failed to list capability %s of %s: %v", m.Capability.Ref(), m.Plugin, err)
+			continue
+		}
+		resp.Capabilities = append(resp.Capabilities, &proto.ProvidedCapability{Plugin: m.Plugin, PluginVersion: m.PluginVersion, Capability: pc})
+	}
+	return resp, nil
+}
+
+// execute runs the capability of a request as a request of the calling
+// client, bounded by the client's deadline and canceled when the client
+// cancels the RPC
+func (g *gateway) execute(ctx context.Context, req *proto.ExecuteCapabilityRequest) (plugin, requestID string, res pluginhost.ExecuteResult, err error) {
+	if req.GetCapability() == "" {
+		return "", "", res, status.Error(codes.InvalidArgument, "capability is required")
+	}
+	plugin = req.GetPlugin()
+	if plugin == "" {
+		if plugin, err = g.pm.Provider(req.GetCapability()); err != nil {
+			return "", "", res, g.status(ctx, err, req.GetLocale())
+		}
+	}
+
+	args := req.GetArgs().AsMap()
+	args[pluginsdk.ArgCapability] = req.GetCapability()
+	if req.GetLocale() != "" {
+		args[pluginsdk.ArgLocale] = req.GetLocale()
+	}
+	if req.GetLatencyBudget() != "" {
+		args[pluginsdk.ArgLatencyBudget] = req.GetLatencyBudget()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return "", "", res, status.Error(codes.DeadlineExceeded, "deadline exceeded before the call started")
+		}
+		args[pluginsdk.ArgTimeout] = remaining.String()
+	}
+
+	c := clientOf(ctx)
+	rc := pluginsdk.RequestContext{RequestID: req.GetRequestId(), User: c.name, Service: c.service, Namespace: req.GetNamespace()}
+	if rc.RequestID == "" {
+		rc.RequestID = newRequestID()
+	}
+	res, err = g.pm.ExecuteContext(ctx, plugin, args, rc)
+	if err != nil {
+		return "", "", res, g.status(ctx, err, req.GetLocale())
+	}
+	return plugin, rc.RequestID, res, nil
+}
+
+// status turns a failed call into a gRPC status error. Errors of the plugin
+// get a code following theirs, which the trailer carries as it is.
+func (g *gateway) status(ctx context.Context, err error, locale string) error {
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, pluginhost.ErrPluginNotFound), errors.Is(err, pluginhost.ErrCapabilityNotSupported):
+		code = codes.NotFound
+	case errors.Is(err, pluginhost.ErrPluginDisabled), errors.Is(err, pluginhost.ErrPluginUnhealthy):
+		code = codes.Unavailable
+	default:
+		pe := pluginsdk.AsPluginError(err)
+		if c, ok := grpcCodes[pe.Code]; ok {
+			code = c
+		}
+		grpc.SetTrailer(ctx, metadata.Pairs(TrailerErrorCode, string(pe.Code), TrailerRetryable, strconv.FormatBool(pe.Retryable)))
+	}
+	return status.Error(code, g.pm.LocalizeError(err, locale))
+}
+
+// grpcCodes maps plugin error codes to the gRPC codes clients expect
+var grpcCodes = map[pluginsdk.ErrorCode]codes.Code{
+	pluginsdk.CodeInvalidArgument:  codes.InvalidArgument,
+	pluginsdk.CodeNotFound:         codes.NotFound,
+	pluginsdk.CodePermissionDenied: codes.PermissionDenied,
+	pluginsdk.CodeUnsupported:      codes.Unimplemented,
+	pluginsdk.CodeUnavailable:      codes.Unavailable,
+	pluginsdk.CodeTimeout:          codes.DeadlineExceeded,
+	pluginsdk.CodeInternal:         codes.Internal,
+	pluginsdk.CodeTooLarge:         codes.ResourceExhausted,
+	pluginsdk.CodeCanceled:         codes.Canceled,
+}
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
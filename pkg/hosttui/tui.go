@@ -0,0 +1,418 @@
+// Package hosttui is a terminal dashboard for a running plugin manager. It
+// shows loaded plugins with their live status, recent executions and stderr
+// tail, and lets the user reload, unload, disable and execute plugins.
+package hosttui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// refreshInterval is how often the dashboard polls the manager
+const refreshInterval = time.Second
+
+const (
+	// recentCallsShown is how many executions are listed for a plugin
+	recentCallsShown = 5
+
+	// minLogLines is the log tail height on small terminals
+	minLogLines = 5
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	headerStyle   = lipgloss.NewStyle().Bold(true).Underline(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+	stateStyles = map[pluginhost.PluginState]lipgloss.Style{
+		pluginhost.StateReady:     lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+		pluginhost.StateLoading:   lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+		pluginhost.StateUnhealthy: lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+		pluginhost.StateCrashed:   lipgloss.NewStyle().Foreground(lipgloss.Color("1")),
+		pluginhost.StateStopped:   dimStyle,
+		pluginhost.StateWaiting:   lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	}
+)
+
+// Run shows the dashboard for pm until the user quits. The host should send
+// its log output elsewhere first, since it would otherwise tear the screen.
+func Run(pm *pluginhost.PluginManager) error {
+	_, err := tea.NewProgram(newModel(pm), tea.WithAltScreen()).Run()
+	return err
+}
+
+// tickMsg triggers a periodic refresh
+type tickMsg time.Time
+
+// actionMsg reports the outcome of a reload, unload or execute
+type actionMsg struct {
+	text string
+	err  error
+}
+
+// model is the bubbletea model for the dashboard
+type model struct {
+	pm *pluginhost.PluginManager
+
+	plugins  []pluginhost.PluginStatus
+	selected string
+	calls    []pluginhost.CallRecord
+	logTail  string
+
+	// inputting is true while the execute prompt is open
+	inputting bool
+	input     string
+
+	status string
+	width  int
+	height int
+}
+
+func newModel(pm *pluginhost.PluginManager) *model {
+	m := &model{pm: pm}
+	m.refresh()
+	return m
+}
+
+func (m *model) Init() tea.Cmd {
+	return tick()
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	})
+}
+
+// refresh reloads the plugin list and the selected plugin's details
+func (m *model) refresh() {
+	m.plugins = m.pm.ListPlugins()
+	if m.index() < 0 {
+		m.selected = ""
+		if len(m.plugins) > 0 {
+			m.selected = m.plugins[0].Name
+		}
+	}
+
+	m.calls, m.logTail = nil, ""
+	if m.selected == "" {
+		return
+	}
+	m.calls, _ = m.pm.RecentCalls(m.selected)
+	m.logTail, _ = m.pm.LogTail(m.selected)
+}
+
+// index returns the position of the selected plugin, or -1
+func (m *model) index() int {
+	for i, p := range m.plugins {
+		if p.Name == m.selected {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *model) move(delta int) {
+	if len(m.plugins) == 0 {
+		return
+	}
+	i := m.index() + delta
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(m.plugins) {
+		i = len(m.plugins) - 1
+	}
+	m.selected = m.plugins[i].Name
+	m.refresh()
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case tickMsg:
+		m.refresh()
+		return m, tick()
+	case actionMsg:
+		if msg.err != nil {
+			m.status = errorStyle.Render(msg.err.Error())
+		} else {
+			m.status = msg.text
+		}
+		m.refresh()
+	case tea.KeyMsg:
+		if m.inputting {
+			return m, m.updateInput(msg)
+		}
+		return m, m.updateKeys(msg)
+	}
+	return m, nil
+}
+
+// updateKeys handles keys while browsing the plugin list
+func (m *model) updateKeys(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "r":
+		if name := m.selected; name != "" {
+			m.status = "Reloading " + name + "..."
+			return m.action("Reloaded "+name, func() error {
+				return m.pm.ReloadPlugin(name)
+			})
+		}
+	case "u":
+		if name := m.selected; name != "" {
+			m.status = "Unloading " + name + "..."
+			return m.action("Unloaded "+name, func() error {
+				return m.pm.UnloadPlugin(name)
+			})
+		}
+	case "d":
+		if i := m.index(); i >= 0 {
+			name := m.selected
+			if m.plugins[i].Disabled {
+				m.status = "Enabling " + name + "..."
+				return m.action("Enabled "+name, func() error {
+					return m.pm.Enable(name)
+				})
+			}
+			m.status = "Disabling " + name + "..."
+			return m.action("Disabled "+name, func() error {
+				return m.pm.Disable(name)
+			})
+		}
+	case "x", "enter":
+		if m.selected != "" {
+			m.inputting = true
+			m.input = ""
+		}
+	}
+	return nil
+}
+
+// updateInput handles keys while the execute prompt is open
+func (m *model) updateInput(msg tea.KeyMsg) tea.Cmd {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return tea.Quit
+	case tea.KeyEsc:
+		m.inputting = false
+	case tea.KeyBackspace:
+		if r := []rune(m.input); len(r) > 0 {
+			m.input = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.input = ""
+	case tea.KeySpace:
+		m.input += " "
+	case tea.KeyRunes:
+		m.input += string(msg.Runes)
+	case tea.KeyEnter:
+		m.inputting = false
+		return m.execute(m.selected, m.input)
+	}
+	return nil
+}
+
+// execute runs the selected plugin with the JSON arguments from the prompt
+func (m *model) execute(name, input string) tea.Cmd {
+	args := make(map[string]interface{})
+	if strings.TrimSpace(input) != "" {
+		if err := json.Unmarshal([]byte(input), &args); err != nil {
+			m.status = errorStyle.Render("Invalid arguments: " + err.Error())
+			return nil
+		}
+	}
+
+	m.status = "Executing " + name + "..."
+	pm := m.pm
+	return func() tea.Msg {
+		start := time.Now()
+		result, err := pm.ExecutePlugin(name, args)
+		if err != nil {
+			return actionMsg{err: err}
+		}
+		return actionMsg{text: fmt.Sprintf("%s (%s): %s", name, time.Since(start).Round(time.Millisecond), result)}
+	}
+}
+
+// action runs fn off the UI goroutine and reports text when it succeeds
+func (m *model) action(text string, fn func() error) tea.Cmd {
+	return func() tea.Msg {
+		if err := fn(); err != nil {
+			return actionMsg{err: err}
+		}
+		return actionMsg{text: text}
+	}
+}
+
+func (m *model) View() string {
+	var b strings.Builder
+
+	b.WriteString(titleStyle.Render(fmt.Sprintf("Plugin Host — %d plugin(s)", len(m.plugins))))
+	b.WriteString("\n\n")
+
+	b.WriteString(headerStyle.Render(fmt.Sprintf("%-20s %-10s %-10s %7s %8s %10s %6s %8s", "PLUGIN", "VERSION", "STATE", "CALLS", "FAILURES", "UPTIME", "CPU", "RSS")))
+	b.WriteString("\n")
+	if len(m.plugins) == 0 {
+		b.WriteString(dimStyle.Render("No plugins loaded"))
+		b.WriteString("\n")
+	}
+	for _, p := range m.plugins {
+		cpu, rss := "-", "-"
+		if p.Resources != nil {
+			cpu = fmt.Sprintf("%.0f%%", p.Resources.CPUPercent)
+			rss = fmt.Sprintf("%dM", p.Resources.RSSBytes>>20)
+		}
+		state := string(p.State)
+		if p.Disabled {
+			state = "disabled"
+		}
+		row := fmt.Sprintf("%-20s %-10s %-10s %7d %8d %10s %6s %8s",
+			truncate(p.Name, 20), truncate(p.Version, 10), state,
+			p.Calls, p.Failures, formatUptime(p.Uptime), cpu, rss)
+		if p.Name == m.selected {
+			row = selectedStyle.Render(row)
+		} else if p.Disabled {
+			row = dimStyle.Render(row)
+		} else if style, ok := stateStyles[p.State]; ok {
+			row = style.Render(row)
+		}
+		b.WriteString(row)
+		b.WriteString("\n")
+	}
+
+	if i := m.index(); i >= 0 {
+		m.viewDetails(&b, m.plugins[i])
+	}
+
+	b.WriteString("\n")
+	if m.inputting {
+		fmt.Fprintf(&b, "Execute %s with JSON args: %s█\n", m.selected, m.input)
+		b.WriteString(dimStyle.Render(fmt.Sprintf("enter run • esc cancel • set %q to pick a capability", pluginsdk.ArgCapability)))
+	} else {
+		if m.status != "" {
+			b.WriteString(m.status)
+			b.WriteString("\n")
+		}
+		b.WriteString(dimStyle.Render("↑/↓ select • x execute • r reload • u unload • d disable/enable • q quit"))
+	}
+	return b.String()
+}
+
+// viewDetails renders the capabilities, recent executions and log tail of
+// the selected plugin
+func (m *model) viewDetails(b *strings.Builder, p pluginhost.PluginStatus) {
+	b.WriteString("\n")
+	b.WriteString(titleStyle.Render(p.Name))
+	b.WriteString("\n")
+	b.WriteString("Capabilities:\n")
+	for _, c := range p.Capabilities {
+		fmt.Fprintf(b, "  %-20s %s\n", truncate(c.Name, 20), dimStyle.Render(c.Description))
+	}
+	if p.LastError != "" {
+		fmt.Fprintf(b, "Last error:   %s\n", errorStyle.Render(p.LastError))
+	}
+	if p.LastCrash != nil {
+		fmt.Fprintf(b, "Last crash:   %s (exit %d) %s\n",
+			p.LastCrash.Time.Format("15:04:05"), p.LastCrash.ExitCode, p.LastCrash.BundlePath)
+	}
+
+	b.WriteString("\n")
+	b.WriteString(headerStyle.Render("Recent executions"))
+	b.WriteString("\n")
+	if len(m.calls) == 0 {
+		b.WriteString(dimStyle.Render("None yet"))
+		b.WriteString("\n")
+	}
+	calls := m.calls
+	if len(calls) > recentCallsShown {
+		calls = calls[len(calls)-recentCallsShown:]
+	}
+	for i := len(calls) - 1; i >= 0; i-- {
+		c := calls[i]
+		capability, _ := c.Args[pluginsdk.ArgCapability].(string)
+		if capability == "" {
+			capability = "-"
+		}
+		outcome := "ok"
+		if c.Error != "" {
+			outcome = errorStyle.Render(truncate(c.Error, 60))
+		}
+		fmt.Fprintf(b, "%s  %-16s %8s  %s\n",
+			c.Time.Format("15:04:05"), truncate(capability, 16), c.Duration.Round(time.Millisecond), outcome)
+	}
+
+	b.WriteString("\n")
+	b.WriteString(headerStyle.Render("Log"))
+	b.WriteString("\n")
+	lines := tailLines(m.logTail, m.logLines())
+	if len(lines) == 0 {
+		b.WriteString(dimStyle.Render("No output"))
+		b.WriteString("\n")
+	}
+	for _, line := range lines {
+		if m.width > 0 {
+			line = truncate(line, m.width)
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+}
+
+// logLines returns how many log lines fit below the rest of the dashboard
+func (m *model) logLines() int {
+	// Title, table, details, executions and footer take roughly this much
+	used := 20 + len(m.plugins) + recentCallsShown
+	if n := m.height - used; n > minLogLines {
+		return n
+	}
+	return minLogLines
+}
+
+// tailLines returns the last n non-empty lines of s
+func tailLines(s string, n int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func formatUptime(d time.Duration) string {
+	if d == 0 {
+		return "-"
+	}
+	return d.Round(time.Second).String()
+}
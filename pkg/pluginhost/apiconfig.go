@@ -0,0 +1,28 @@
+package pluginhost
+
+import "fmt"
+
+// APIConfig sets who may use the HTTP management API (see package hostapi).
+// Clients send an API key as "Authorization: Bearer <key>"; the dashboard
+// is opened once with "?token=<key>" and keeps the key in a cookie. Requests
+// without a key are refused unless AllowUnauthenticated is set. Clients
+// other than the host's operator may only call plugins unless their
+// principal is bound to an Admin role of the authorization section.
+type APIConfig struct {
+	// APIKeys maps client names to their keys; the name is the service
+	// principal of the calls they make. Keys may be secret:// references.
+	APIKeys map[string]string `json:"api_keys"`
+
+	// AllowUnauthenticated serves clients without a key too; only for
+	// hosts where every local user and process is trusted
+	AllowUnauthenticated bool `json:"allow_unauthenticated"`
+}
+
+func (c *APIConfig) validate() error {
+	for name, key := range c.APIKeys {
+		if name == "" || key == "" {
+			return fmt.Errorf("api.api_keys: client names and keys must not be empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DesiredState is the complete set of plugins the host should run, as kept
+// in version control for GitOps-style management. Plugins not listed are
+// unloaded by Apply.
+type DesiredState struct {
+	Plugins []DesiredPlugin `json:"plugins"`
+}
+
+// DesiredPlugin is one plugin binary. Its manifest must sit next to it, as
+// the manifest's name is what Apply compares with the running plugins.
+type DesiredPlugin struct {
+	Path string `json:"path"`
+
+	// Version, when set, must match the manifest, guarding against
+	// deploying a different build than the one reviewed
+	Version string `json:"version,omitempty"`
+}
+
+// ApplyPlan lists the plugins Apply loads, reloads and unloads, by name
+type ApplyPlan struct {
+	Load   []string `json:"load"`
+	Reload []string `json:"reload"`
+	Unload []string `json:"unload"`
+}
+
+// Empty reports whether the host already matches the desired state
+func (p *ApplyPlan) Empty() bool {
+	return len(p.Load) == 0 && len(p.Reload) == 0 && len(p.Unload) == 0
+}
+
+// LoadDesiredState reads a desired state file. Relative plugin paths are
+// taken relative to the file.
+func LoadDesiredState(path string) (*DesiredState, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open desired state: %w", err)
+	}
+	defer f.Close()
+
+	state := &DesiredState{}
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(state); err != nil {
+		return nil, fmt.Errorf("failed to parse desired state %s: %w", path, err)
+	}
+	for i := range state.Plugins {
+		if p := state.Plugins[i].Path; p != "" && !filepath.IsAbs(p) {
+			state.Plugins[i].Path = filepath.Join(filepath.Dir(path), p)
+		}
+	}
+	return state, nil
+}
+
+// desiredEntry is a desired plugin with its manifest read
+type desiredEntry struct {
+	path     string
+	manifest *Manifest
+}
+
+// resolve reads the manifest of every desired plugin, keyed by name
+func (s *DesiredState) resolve() (map[string]desiredEntry, error) {
+	entries := make(map[string]desiredEntry, len(s.Plugins))
+	for _, p := range s.Plugins {
+		if p.Path == "" {
+			return nil, fmt.Errorf("desired plugin without a path")
+		}
+		path := filepath.Clean(p.Path)
+		m, err := LoadManifest(manifestPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("desired plugin %s needs a manifest: %w", path, err)
+		}
+		if p.Version != "" && p.Version != m.Version {
+			return nil, fmt.Errorf("desired plugin %s is version %s, want %s", m.Name, m.Version, p.Version)
+		}
+		if prev, dup := entries[m.Name]; dup {
+			return nil, fmt.Errorf("plugin %s is desired twice: %s and %s", m.Name, prev.path, path)
+		}
+		entries[m.Name] = desiredEntry{path: path, manifest: m}
+	}
+	return entries, nil
+}
+
+// resolve reads the desired plugins and refuses versions other than the
+// pinned ones
+func (pm *PluginManager) resolve(desired *DesiredState) (map[string]desiredEntry, error) {
+	entries, err := desired.resolve()
+	if err != nil {
+		return nil, err
+	}
+	for name, e := range entries {
+		if err := pm.checkPin(name, e.manifest.Version); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Diff returns what Apply would change to reach desired, without changing
+// anything. A running plugin is reloaded when its binary path or version
+// differs from the desired one.
+func (pm *PluginManager) Diff(desired *DesiredState) (*ApplyPlan, error) {
+	entries, err := pm.resolve(desired)
+	if err != nil {
+		return nil, err
+	}
+	return pm.diff(entries), nil
+}
+
+func (pm *PluginManager) diff(entries map[string]desiredEntry) *ApplyPlan {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	plan := &ApplyPlan{}
+	for name, e := range entries {
+		info, exists := pm.plugins[name]
+		switch {
+		case exists && info.builtin:
+		case !exists:
+			plan.Load = append(plan.Load, name)
+		case filepath.Clean(info.Path) != e.path || info.Version != e.manifest.Version:
+			plan.Reload = append(plan.Reload, name)
+		}
+	}
+	for name, info := range pm.plugins {
+		if _, ok := entries[name]; !ok && !info.builtin {
+			plan.Unload = append(plan.Unload, name)
+		}
+	}
+	sort.Strings(plan.Load)
+	sort.Strings(plan.Reload)
+	sort.Strings(plan.Unload)
+	return plan
+}
+
+// Apply brings the running plugins in line with desired: missing plugins
+// are loaded, changed ones reloaded and unlisted ones unloaded. Loads and
+// reloads come first; if one fails, every change made so far is undone
+// and the host keeps running the plugins it had. Unloads come last and
+// cannot fail. Plugins pinned with Pin must be desired at their pinned
+// version. Concurrent calls to Apply and Upgrade are serialized.
+func (pm *PluginManager) Apply(desired *DesiredState) (*ApplyPlan, error) {
+	pm.applyMu.Lock()
+	defer pm.applyMu.Unlock()
+
+	entries, err := pm.resolve(desired)
+	if err != nil {
+		return nil, err
+	}
+	plan := pm.diff(entries)
+	if plan.Empty() {
+		return plan, nil
+	}
+	pm.hostLog.Printf("Applying desired state: load %v, reload %v, unload %v", plan.Load, plan.Reload, plan.Unload)
+
+	var undo []func()
+	rollback := func(cause error) error {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+		return fmt.Errorf("apply rolled back: %w", cause)
+	}
+
+	for _, name := range plan.Load {
+		if err := pm.applyLoad(name, entries[name]); err != nil {
+			return nil, rollback(err)
+		}
+		undo = append(undo, func() {
+			if err := pm.UnloadPlugin(name); err != nil {
+				pm.hostLog.Printf("Failed to roll back load of %s: %v", name, err)
+			}
+		})
+	}
+
+	for _, name := range plan.Reload {
+		pm.mu.RLock()
+		prevPath := pm.plugins[name].Path
+		pm.mu.RUnlock()
+
+		if err := pm.UnloadPlugin(name); err != nil {
+			return nil, rollback(err)
+		}
+		restore := func() {
+			m := pm.pluginManifest(prevPath)
+			if err := pm.applyLoad(name, desiredEntry{path: prevPath, manifest: m}); err != nil {
+				pm.hostLog.Printf("Failed to restore plugin %s from %s: %v", name, prevPath, err)
+			}
+		}
+		if err := pm.applyLoad(name, entries[name]); err != nil {
+			restore()
+			return nil, rollback(err)
+		}
+		undo = append(undo, func() {
+			if err := pm.UnloadPlugin(name); err != nil {
+				pm.hostLog.Printf("Failed to roll back reload of %s: %v", name, err)
+			}
+			restore()
+		})
+	}
+
+	for _, name := range plan.Unload {
+		if err := pm.UnloadPlugin(name); err != nil {
+			// Gone already, e.g. removed with its plugin directory
+			pm.hostLog.Printf("Failed to unload plugin %s: %v", name, err)
+		}
+	}
+
+	pm.hostLog.Printf("Applied desired state: %d loaded, %d reloaded, %d unloaded", len(plan.Load), len(plan.Reload), len(plan.Unload))
+	return plan, nil
+}
+
+// applyLoad starts one desired plugin the way discovery would, registering
+// it lazily when the config says so. A binary reporting a different name
+// than its manifest is stopped and refused.
+func (pm *PluginManager) applyLoad(name string, e desiredEntry) error {
+	if pm.startsLazily(pm.Config(), e.manifest) {
+		pm.registerLazy(e.path, e.manifest)
+		return nil
+	}
+
+	got, err := pm.loadPlugin(e.path)
+	if err != nil {
+		return fmt.Errorf("failed to load plugin %s: %w", name, err)
+	}
+	if pm.reportedBy(got) != name {
+		if err := pm.UnloadPlugin(got); err != nil {
+			pm.hostLog.Printf("Failed to unload plugin %s: %v", got, err)
+		}
+		return fmt.Errorf("plugin at %s reports name %q but its manifest says %q", e.path, got, name)
+	}
+	return nil
+}
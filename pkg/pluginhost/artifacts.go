@@ -0,0 +1,249 @@
+package pluginhost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArtifactCacheConfig keeps the plugin binaries the host downloads in a
+// cache addressed by their SHA-256. The cache is shared by every host of
+// the user, so a plugin installed, updated or synced from a source by
+// several projects or namespaces is downloaded once.
+type ArtifactCacheConfig struct {
+	// Disabled downloads every binary, as before the cache existed
+	Disabled bool `json:"disabled"`
+
+	// Dir holds the cache; the default is $XDG_CACHE_HOME/opencode/artifacts,
+	// or ~/.cache/opencode/artifacts
+	Dir string `json:"dir"`
+
+	// MaxAge removes artifacts no host used for this long; zero keeps them
+	MaxAge Duration `json:"max_age"`
+
+	// MaxBytes removes the least recently used artifacts while the cache
+	// holds more; zero means no limit
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+func (c *ArtifactCacheConfig) validate() error {
+	if c.MaxAge < 0 {
+		return fmt.Errorf("artifacts.max_age must not be negative")
+	}
+	if c.MaxBytes < 0 {
+		return fmt.Errorf("artifacts.max_bytes must not be negative")
+	}
+	return nil
+}
+
+// dir returns the directory of the cache with its default applied
+func (c *ArtifactCacheConfig) dir() (string, error) {
+	if c.Dir != "" {
+		return c.Dir, nil
+	}
+	plugins, err := xdgRoot("XDG_CACHE_HOME", ".cache")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(plugins), "artifacts"), nil
+}
+
+// ArtifactCache is a directory of files named by their SHA-256, e.g.
+// sha256/ab/ab12…, which any number of hosts may use at once: files are
+// only ever put in place complete and verified. A file's modification time
+// is when a host last used it.
+type ArtifactCache struct {
+	dir      string
+	maxAge   time.Duration
+	maxBytes int64
+}
+
+// Artifact is a file in the cache
+type Artifact struct {
+	SHA256   string    `json:"sha256"`
+	Size     int64     `json:"size"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// ArtifactGC is what a garbage collection of the cache removed and kept
+type ArtifactGC struct {
+	Removed      []Artifact `json:"removed"`
+	RemovedBytes int64      `json:"removed_bytes"`
+	Kept         int        `json:"kept"`
+	KeptBytes    int64      `json:"kept_bytes"`
+}
+
+// OpenArtifactCache returns the cache cfg configures, or nil when it is
+// disabled
+func OpenArtifactCache(cfg ArtifactCacheConfig) (*ArtifactCache, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	dir, err := cfg.dir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate artifact cache: %w", err)
+	}
+	return &ArtifactCache{dir: dir, maxAge: time.Duration(cfg.MaxAge), maxBytes: cfg.MaxBytes}, nil
+}
+
+// artifacts returns the artifact cache of the host's config, or nil when
+// it is disabled or cannot be located, in which case binaries are
+// downloaded every time
+func (pm *PluginManager) artifacts() *ArtifactCache {
+	c, err := OpenArtifactCache(pm.Config().Artifacts)
+	if err != nil {
+		pm.hostLog.Printf("Downloading plugins without a cache: %v", err)
+		return nil
+	}
+	return c
+}
+
+// Dir returns the directory of the cache
+func (c *ArtifactCache) Dir() string {
+	return c.dir
+}
+
+// path returns where the artifact with the hex sum is kept
+func (c *ArtifactCache) path(sum string) string {
+	sum = strings.ToLower(sum)
+	return filepath.Join(c.dir, "sha256", sum[:2], sum)
+}
+
+// Fetch returns the file at location, which must have the hex SHA-256 sum.
+// A cached copy is used when there is one; otherwise the file is downloaded,
+// verified and cached. A nil cache downloads every time.
+func (c *ArtifactCache) Fetch(client *http.Client, location, sum string) ([]byte, error) {
+	if !validSHA256(sum) {
+		return nil, fmt.Errorf("invalid sha256 %q", sum)
+	}
+	if c != nil {
+		if data, ok := c.get(sum); ok {
+			return data, nil
+		}
+	}
+
+	data, err := fetch(client, location)
+	if err != nil {
+		return nil, err
+	}
+	if got := sha256Hex(data); !strings.EqualFold(got, sum) {
+		return nil, fmt.Errorf("checksum mismatch: got %s, want %s", got, sum)
+	}
+	if c != nil {
+		// A download that cannot be cached is still good to install
+		if err := c.put(sum, data); err == nil {
+			c.autoGC()
+		}
+	}
+	return data, nil
+}
+
+// get returns the cached artifact with sum and marks it used. Copies that
+// no longer match their sum are removed.
+func (c *ArtifactCache) get(sum string) ([]byte, bool) {
+	path := c.path(sum)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	if !strings.EqualFold(sha256Hex(data), sum) {
+		os.Remove(path)
+		return nil, false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, true
+}
+
+// put adds data, whose sum was verified, to the cache
+func (c *ArtifactCache) put(sum string, data []byte) error {
+	path := c.path(sum)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return installFile(path, data, 0644)
+}
+
+// List returns the artifacts in the cache, least recently used first
+func (c *ArtifactCache) List() ([]Artifact, error) {
+	var artifacts []Artifact
+	err := filepath.WalkDir(filepath.Join(c.dir, "sha256"), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// Skips directories and the temporary files of puts in progress
+		if d.IsDir() || !validSHA256(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		artifacts = append(artifacts, Artifact{SHA256: d.Name(), Size: info.Size(), LastUsed: info.ModTime()})
+		return nil
+	})
+	sort.Slice(artifacts, func(i, j int) bool { return artifacts[i].LastUsed.Before(artifacts[j].LastUsed) })
+	return artifacts, err
+}
+
+// GC removes the artifacts not used for maxAge, then the least recently
+// used ones while the cache holds more than maxBytes. Zero disables
+// either limit.
+func (c *ArtifactCache) GC(maxAge time.Duration, maxBytes int64) (ArtifactGC, error) {
+	var res ArtifactGC
+	artifacts, err := c.List()
+	if err != nil {
+		return res, err
+	}
+	for _, a := range artifacts {
+		res.KeptBytes += a.Size
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, a := range artifacts {
+		stale := maxAge > 0 && now.Sub(a.LastUsed) > maxAge
+		over := maxBytes > 0 && res.KeptBytes > maxBytes
+		if !stale && !over {
+			break
+		}
+		if err := os.Remove(c.path(a.SHA256)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		res.Removed = append(res.Removed, a)
+		res.RemovedBytes += a.Size
+		res.KeptBytes -= a.Size
+	}
+	res.Kept = len(artifacts) - len(res.Removed)
+	return res, errors.Join(errs...)
+}
+
+// autoGC applies the configured limits after an artifact was added
+func (c *ArtifactCache) autoGC() {
+	if c.maxAge > 0 || c.maxBytes > 0 {
+		c.GC(c.maxAge, c.maxBytes)
+	}
+}
+
+// validSHA256 reports whether s is a hex SHA-256 sum
+func validSHA256(s string) bool {
+	b, err := hex.DecodeString(s)
+	return err == nil && len(b) == sha256.Size
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
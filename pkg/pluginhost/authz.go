@@ -0,0 +1,354 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// EventCallDenied is published when the authorization policy refuses a call
+const EventCallDenied = "call.denied"
+
+// PrincipalAnonymous is the principal of calls made for no one, e.g. calls
+// without a request context
+const PrincipalAnonymous = "anonymous"
+
+// AuthorizationConfig decides who may execute which capabilities. While it
+// is disabled every caller may execute everything.
+type AuthorizationConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Roles are sets of rules by name
+	Roles map[string]Role `json:"roles"`
+
+	// Bindings grant roles to principals: "user:ada", "service:billing",
+	// "user:*" for every user, "service:*" for every service and
+	// "anonymous" for calls made for no one
+	Bindings map[string][]string `json:"bindings"`
+
+	// AuditLog is a file every decision is appended to as JSON lines
+	AuditLog string `json:"audit_log"`
+}
+
+// Role is what its principals may execute, as "plugin/capability" patterns
+// such as "hello/greet", "hello/greet.*", "hello" for every capability of
+// hello or "*" for everything
+type Role struct {
+	Allow []string `json:"allow"`
+
+	// Deny takes precedence over what any role allows
+	Deny []string `json:"deny"`
+
+	// Admin lets the role's principals use the management routes of the
+	// HTTP API, which change the config and load, halt or install
+	// plugins; otherwise only the host's operator may. It holds whether
+	// or not authorization is enabled.
+	Admin bool `json:"admin"`
+}
+
+func (c *AuthorizationConfig) validate() error {
+	for name, role := range c.Roles {
+		for _, rule := range append(append([]string(nil), role.Allow...), role.Deny...) {
+			if err := validateRule(rule); err != nil {
+				return fmt.Errorf("authorization.roles.%s: %w", name, err)
+			}
+		}
+	}
+	for principal, roles := range c.Bindings {
+		kind, name, _ := strings.Cut(principal, ":")
+		switch {
+		case principal == PrincipalAnonymous:
+		case (kind == "user" || kind == "service") && name != "":
+		default:
+			return fmt.Errorf("authorization.bindings: %q is not a principal; use user:<name>, service:<name> or anonymous", principal)
+		}
+		for _, role := range roles {
+			if _, ok := c.Roles[role]; !ok {
+				return fmt.Errorf("authorization.bindings.%s: unknown role %q", principal, role)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRule checks that a rule is a valid pattern
+func validateRule(rule string) error {
+	if rule == "" {
+		return fmt.Errorf("empty rule")
+	}
+	if _, err := path.Match(rule, ""); err != nil {
+		return fmt.Errorf("rule %q: %w", rule, err)
+	}
+	return nil
+}
+
+// Principal is who a call is made for
+type Principal struct {
+	// Name is the user or service; empty for anonymous calls
+	Name string `json:"name,omitempty"`
+
+	// Service marks principals that are other systems rather than people
+	Service bool `json:"service,omitempty"`
+}
+
+// String returns the principal the way bindings name it, e.g. "user:ada"
+func (p Principal) String() string {
+	switch {
+	case p.Name == "":
+		return PrincipalAnonymous
+	case p.Service:
+		return "service:" + p.Name
+	}
+	return "user:" + p.Name
+}
+
+// AuditRecord is one authorization decision
+type AuditRecord struct {
+	Time       time.Time `json:"time"`
+	Principal  string    `json:"principal"`
+	Plugin     string    `json:"plugin"`
+	Capability string    `json:"capability,omitempty"`
+	Allowed    bool      `json:"allowed"`
+
+	// Role and Rule are what allowed or denied the call; both are empty
+	// when no role of the principal allows it
+	Role string `json:"role,omitempty"`
+	Rule string `json:"rule,omitempty"`
+
+	RequestID string `json:"request_id,omitempty"`
+
+	// Caller is the plugin making the call, for calls between plugins
+	Caller string `json:"caller,omitempty"`
+}
+
+// auditWriter appends records to an audit log
+type auditWriter struct {
+	path string
+	f    *os.File
+	mu   sync.Mutex
+}
+
+func openAudit(path string) (*auditWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	return &auditWriter{path: path, f: f}, nil
+}
+
+func (a *auditWriter) write(r interface{}) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.f.Write(append(data, '\n'))
+	return err
+}
+
+func (a *auditWriter) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.f.Close()
+}
+
+// applyAudit opens, switches or closes the audit log to match cfg
+func (pm *PluginManager) applyAudit(cfg AuthorizationConfig) error {
+	path := cfg.AuditLog
+	if !cfg.Enabled {
+		path = ""
+	}
+	pm.mu.RLock()
+	current := pm.audit
+	pm.mu.RUnlock()
+
+	if current != nil && current.path == path {
+		return nil
+	}
+
+	var next *auditWriter
+	if path != "" {
+		var err error
+		if next, err = openAudit(path); err != nil {
+			return err
+		}
+		pm.hostLog.Printf("Recording authorization decisions to %s", path)
+	}
+
+	pm.mu.Lock()
+	pm.audit = next
+	pm.mu.Unlock()
+
+	if current != nil {
+		if err := current.close(); err != nil {
+			pm.hostLog.Printf("Failed to close audit log %s: %v", current.path, err)
+		}
+	}
+	return nil
+}
+
+// PrincipalOf returns who a call is made for. It is taken from the running
+// request the call's context names, so arguments cannot claim to be made
+// for someone else; calls outside a request are anonymous.
+func (pm *PluginManager) PrincipalOf(args map[string]interface{}) Principal {
+	id := pluginsdk.ContextOf(args).RequestID
+	if id == "" {
+		return Principal{}
+	}
+	rc, ok := pm.requests.context(id)
+	if !ok {
+		return Principal{}
+	}
+	return Principal{Name: rc.User, Service: rc.Service}
+}
+
+// authorize checks the call against the authorization policy and records
+// the decision. Calls plugins make outside a request are left to the
+// permissions.calls of their manifest, as they are made for no principal.
+func (pm *PluginManager) authorize(name string, args map[string]interface{}) error {
+	cfg := pm.Config().Authorization
+	if !cfg.Enabled {
+		return nil
+	}
+	caller, _ := args[pluginsdk.ArgCaller].(string)
+	rc := pluginsdk.ContextOf(args)
+	if caller != "" && rc.RequestID == "" {
+		return nil
+	}
+
+	principal := pm.PrincipalOf(args)
+	ref, _ := args[pluginsdk.ArgCapability].(string)
+	capability, _ := pluginsdk.ParseCapabilityRef(ref)
+	allowed, role, rule := cfg.decide(principal, name, capability)
+
+	pm.recordAudit(AuditRecord{
+		Time:       time.Now(),
+		Principal:  principal.String(),
+		Plugin:     name,
+		Capability: capability,
+		Allowed:    allowed,
+		Role:       role,
+		Rule:       rule,
+		RequestID:  rc.RequestID,
+		Caller:     caller,
+	})
+	if allowed {
+		return nil
+	}
+
+	pm.hostLog.Printf("Denied %s executing %s", principal, capabilityLabel(name, capability))
+	pm.events.Publish(Event{Type: EventCallDenied, Plugin: name, Data: map[string]interface{}{
+		"principal":  principal.String(),
+		"capability": capability,
+		"request_id": rc.RequestID,
+	}})
+	return pluginsdk.NewError(pluginsdk.CodePermissionDenied, "%s may not execute %s", principal, capabilityLabel(name, capability))
+}
+
+// recordAudit appends a decision to the audit log, if one is kept
+func (pm *PluginManager) recordAudit(r AuditRecord) {
+	pm.mu.RLock()
+	audit := pm.audit
+	pm.mu.RUnlock()
+
+	if audit == nil {
+		return
+	}
+	if err := audit.write(r); err != nil {
+		pm.hostLog.Printf("Failed to record authorization decision: %v", err)
+	}
+}
+
+// decide returns whether principal may execute capability of plugin, and
+// the role and rule that decided it. A rule denying the call wins over
+// every rule allowing it.
+func (c AuthorizationConfig) decide(principal Principal, plugin, capability string) (allowed bool, role, rule string) {
+	roles := c.rolesOf(principal)
+	for _, name := range roles {
+		if r := matchRules(c.Roles[name].Deny, plugin, capability); r != "" {
+			return false, name, r
+		}
+	}
+	for _, name := range roles {
+		if r := matchRules(c.Roles[name].Allow, plugin, capability); r != "" {
+			return true, name, r
+		}
+	}
+	return false, "", ""
+}
+
+// IsAdmin reports whether a role bound to principal is an Admin role
+func (c AuthorizationConfig) IsAdmin(principal Principal) bool {
+	for _, role := range c.rolesOf(principal) {
+		if c.Roles[role].Admin {
+			return true
+		}
+	}
+	return false
+}
+
+// rolesOf returns the roles bound to principal, directly or through a
+// wildcard binding, in a stable order
+func (c AuthorizationConfig) rolesOf(principal Principal) []string {
+	keys := []string{principal.String()}
+	if principal.Name != "" {
+		kind, _, _ := strings.Cut(principal.String(), ":")
+		keys = append(keys, kind+":*")
+	}
+	var roles []string
+	for _, key := range keys {
+		for _, role := range c.Bindings[key] {
+			if !containsString(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// matchRules returns the first of rules matching capability of plugin
+func matchRules(rules []string, plugin, capability string) string {
+	for _, rule := range rules {
+		if matchRule(rule, plugin, capability) {
+			return rule
+		}
+	}
+	return ""
+}
+
+// matchRule reports whether a rule covers capability of plugin; each part
+// of the rule is a path.Match pattern, and a rule without a capability
+// part covers every capability
+func matchRule(rule, plugin, capability string) bool {
+	if rule == "*" {
+		return true
+	}
+	pluginPattern, capabilityPattern, ok := strings.Cut(rule, "/")
+	if !ok {
+		capabilityPattern = "*"
+	}
+	if matched, _ := path.Match(pluginPattern, plugin); !matched {
+		return false
+	}
+	matched, _ := path.Match(capabilityPattern, capability)
+	return matched
+}
+
+// capabilityLabel names a capability of a plugin in messages
+func capabilityLabel(plugin, capability string) string {
+	if capability == "" {
+		return plugin
+	}
+	return plugin + "/" + capability
+}
@@ -0,0 +1,68 @@
+package pluginhost
+
+import (
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestAuthorizeIgnoresReservedArgs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Authorization = AuthorizationConfig{
+		Enabled:  true,
+		Roles:    map[string]Role{"all": {Allow: []string{"*"}}},
+		Bindings: map[string][]string{"service:billing": {"all"}},
+	}
+	pm, err := New(WithConfig(cfg), WithBuiltin(&sessionPlugin{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	billing := &pluginsdk.RequestContext{User: "billing", Service: true}
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		rc      *pluginsdk.RequestContext
+		allowed bool
+	}{
+		{"anonymous", map[string]interface{}{}, nil, false},
+		{"posing as a plugin", map[string]interface{}{pluginsdk.ArgCaller: "hello"}, nil, false},
+		{"naming a request", map[string]interface{}{pluginsdk.ArgCaller: "hello", pluginsdk.ArgContext: map[string]interface{}{"request_id": "r1", "user": "billing", "service": true}}, nil, false},
+		{"bound service", map[string]interface{}{}, billing, true},
+		{"bound service posing as a plugin", map[string]interface{}{pluginsdk.ArgCaller: "hello"}, billing, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rc *pluginsdk.RequestContext
+			if tt.rc != nil {
+				copied := *tt.rc
+				rc = &copied
+			}
+			_, err := pm.Execute("sessions", tt.args, rc)
+			if tt.allowed && err != nil {
+				t.Errorf("call refused: %v", err)
+			}
+			if !tt.allowed && pluginsdk.AsPluginError(err).Code != pluginsdk.CodePermissionDenied {
+				t.Errorf("error = %v, want %s", err, pluginsdk.CodePermissionDenied)
+			}
+		})
+	}
+}
+
+func TestWithoutReserved(t *testing.T) {
+	args := map[string]interface{}{
+		"name":               "Ada",
+		pluginsdk.ArgCaller:  "hello",
+		pluginsdk.ArgContext: map[string]interface{}{"user": "ada"},
+		pluginsdk.ArgEnv:     map[string]interface{}{"HOME": "/h"},
+		pluginsdk.ArgWorkDir: "/",
+	}
+	got := withoutReserved(args)
+	if len(got) != 1 || got["name"] != "Ada" {
+		t.Errorf("withoutReserved = %v, want only name", got)
+	}
+	if len(args) != 5 {
+		t.Errorf("withoutReserved changed its argument: %v", args)
+	}
+}
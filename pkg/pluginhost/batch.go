@@ -0,0 +1,301 @@
+package pluginhost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// ExecuteBatch runs many calls of a plugin, one per args of items, and
+// returns the result or error of each in order. Plugins receive the calls
+// in one round trip and can share setup across them; see
+// pluginsdk.BatchPlugin. Each item passes the same checks as a call to
+// ExecutePlugin and is cached, recorded and converted like one, but is not
+// retried. The batch runs within the sum of its items' timeouts. Items
+// with a latency budget or dry_run, and batches for built-in or remote
+// plugins or plugins predating batches, run one call at a time. The error
+// is for the batch as a whole, e.g. while the host is read-only.
+func (pm *PluginManager) ExecuteBatch(name string, items []map[string]interface{}) ([]pluginsdk.BatchResult, error) {
+	if err := pm.admit(name, nil); err != nil {
+		return nil, err
+	}
+	items = append([]map[string]interface{}(nil), items...)
+	for i, args := range items {
+		items[i] = withoutReserved(args)
+	}
+	results := make([]pluginsdk.BatchResult, len(items))
+	if !pm.batches(name) {
+		for i, args := range items {
+			results[i].Result, results[i].Err = pm.executeAdmitted(name, args)
+		}
+		return results, nil
+	}
+	if err := pm.ensureRunning(name); err != nil {
+		return nil, err
+	}
+
+	// Prepare every item as its own call; those served from the cache or
+	// that must run alone drop out of the batch
+	var calls, single []*preparedCall
+	var batched, alone []int
+	for i, args := range items {
+		if err := pm.checkFormat(name, args); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := pm.authorize(name, args); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := pm.checkFlags(name, args); err != nil {
+			results[i].Err = err
+			continue
+		}
+		call, err := pm.prepareCall(name, pm.withProject(name, args))
+		switch {
+		case err != nil:
+			results[i].Err = err
+		case call.cached:
+			results[i].Result, results[i].Err = pm.finishResult(name, args, call.result)
+		case !call.wrapUpAt.IsZero() || pluginsdk.DryRun(call.args):
+			single, alone = append(single, call), append(alone, i)
+		default:
+			calls, batched = append(calls, call), append(batched, i)
+		}
+	}
+
+	out, err := pm.runBatch(name, calls)
+	if errors.Is(err, pluginsdk.ErrBatchUnsupported) {
+		single, alone = append(single, calls...), append(alone, batched...)
+	} else {
+		for j, i := range batched {
+			if results[i].Err = out[j].Err; results[i].Err == nil {
+				results[i].Result, results[i].Err = pm.finishResult(name, items[i], out[j].Result)
+			}
+		}
+	}
+	for j, call := range single {
+		i := alone[j]
+		res, err := pm.runCall(name, call)
+		if results[i].Err = err; err == nil {
+			results[i].Result, results[i].Err = pm.finishResult(name, items[i], res.Result)
+		}
+	}
+	return results, nil
+}
+
+// executeAdmitted runs a call the host mode admitted the way ExecutePlugin
+// does
+func (pm *PluginManager) executeAdmitted(name string, args map[string]interface{}) (string, error) {
+	if err := pm.checkFormat(name, args); err != nil {
+		return "", err
+	}
+	res, err := pm.executeResult(name, args)
+	if err != nil {
+		return "", err
+	}
+	return pm.finishResult(name, args, res.Result)
+}
+
+// finishResult converts and transforms the result of a call as
+// ExecutePlugin does
+func (pm *PluginManager) finishResult(name string, args map[string]interface{}, result string) (string, error) {
+	result, err := pm.convertResult(name, args, result)
+	if err != nil {
+		return "", err
+	}
+	return pm.transform(name, args, result)
+}
+
+// batches reports whether a plugin's calls can be sent to it in batches:
+// it runs in its own process on this host, which can be asked to
+func (pm *PluginManager) batches(name string) bool {
+	if pm.remoteFor(name) != nil {
+		return false
+	}
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	info, ok := pm.plugins[name]
+	return ok && !info.builtin
+}
+
+// runBatch sends prepared calls to their plugin in one round trip and
+// ends them, recording each; the batch failing as a whole fails every
+// item. It returns pluginsdk.ErrBatchUnsupported without ending the calls
+// when the plugin cannot take batches.
+func (pm *PluginManager) runBatch(name string, calls []*preparedCall) ([]pluginsdk.BatchResult, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+	lead := calls[0]
+	executor, ok := lead.instance.(pluginsdk.BatchExecutor)
+	if !ok {
+		return nil, pluginsdk.ErrBatchUnsupported
+	}
+
+	var timeout time.Duration
+	for _, call := range calls {
+		timeout += call.timeout
+	}
+	ctx, cancel := context.WithTimeoutCause(context.Background(), timeout, errTimeout(name, timeout))
+	defer cancel()
+	ctx, cancelCalls := context.WithCancelCause(ctx)
+	defer cancelCalls(nil)
+
+	// Canceling the request of any item cancels the batch
+	items := make([]map[string]interface{}, len(calls))
+	recorded := make([]map[string]interface{}, len(calls))
+	keys := map[string]string{}
+	pm.mu.RLock()
+	for i, call := range calls {
+		requestID := pluginsdk.ContextOf(call.args).RequestID
+		untrack := pm.running.add(requestID, cancelCalls)
+		defer untrack()
+		items[i] = withCallID(call.args)
+		defer pm.trackLLMCall(name, items[i])()
+		recorded[i] = pm.recordArgs(call.info, call.args)
+		if call.concurrencyKey != "" {
+			keys[call.concurrencyKey] = requestID
+		}
+	}
+	pm.mu.RUnlock()
+
+	start := time.Now()
+	results, err := pm.holdingKeys(ctx, name, keys, func() ([]pluginsdk.BatchResult, error) {
+		return pm.invokeBatch(ctx, name, lead, executor, items)
+	})
+	if errors.Is(err, pluginsdk.ErrBatchUnsupported) {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	if err != nil && ctx.Err() != nil {
+		err = context.Cause(ctx)
+		pm.hostLog.Printf("Batch of %d call(s) to %s ended early: %v", len(calls), name, err)
+	}
+
+	out := make([]pluginsdk.BatchResult, len(calls))
+	for i, call := range calls {
+		result, itemErr := "", err
+		if err == nil {
+			result, itemErr = results[i].Result, results[i].Err
+		}
+		pm.endBatchItem(name, call, recorded[i], start, elapsed, len(calls), result, itemErr)
+		if itemErr != nil {
+			out[i].Err = fmt.Errorf("plugin execution failed: %w", itemErr)
+			continue
+		}
+		out[i].Result = result
+		if _, spilled := pluginsdk.ResultFile(result); call.cacheKey != "" && !spilled {
+			call.cache.Put(name, call.cacheKey, result, call.ttl)
+		}
+	}
+	return out, nil
+}
+
+// endBatchItem records an item of a batch as a call that took as long as
+// the batch, and ends it
+func (pm *PluginManager) endBatchItem(name string, call *preparedCall, recorded map[string]interface{}, start time.Time, elapsed time.Duration, size int, result string, err error) {
+	defer call.info.active.Add(-1)
+	if call.worker != nil {
+		defer call.pool.release(call.worker)
+	}
+
+	rec := CallRecord{Time: start, Args: recorded, Duration: elapsed, Attempt: 1, Batch: size}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	pm.observeAttempt(name, call.args, elapsed, err)
+	call.info.calls.add(rec)
+	call.info.stats.record(err)
+	pm.mu.RLock()
+	pm.recordHistory(name, recorded, start, result, err)
+	pm.recordTrace(call.info, recorded, start, elapsed, result, err)
+	pm.mu.RUnlock()
+	pm.publishExecuted(name, call.args, err)
+	capability, _ := call.args[pluginsdk.ArgCapability].(string)
+	execution := Execution{Plugin: name, Capability: capability, Duration: elapsed, Attempts: 1, Err: err}
+	pm.executed(execution)
+	pm.observeSLO(execution)
+}
+
+// holdingKeys runs fn holding the concurrency keys of a batch, taken in
+// order so batches sharing keys cannot deadlock. The wait counts against
+// the batch's timeout.
+func (pm *PluginManager) holdingKeys(ctx context.Context, name string, keys map[string]string, fn func() ([]pluginsdk.BatchResult, error)) ([]pluginsdk.BatchResult, error) {
+	names := make([]string, 0, len(keys))
+	for key := range keys {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	for _, key := range names {
+		release, err := pm.keys.acquire(ctx, key, name, keys[key])
+		if err != nil {
+			pm.hostLog.Printf("Batch to %s gave up waiting for concurrency key %s: %v", name, key, err)
+			return nil, err
+		}
+		defer release()
+	}
+	return fn()
+}
+
+// invokeBatch sends a batch until it returns or ctx ends. When ctx ends
+// first, the plugin is asked to abort every item and given the grace
+// period to return, after which the process serving it is killed, as
+// invokeCancelable does for single calls.
+func (pm *PluginManager) invokeBatch(ctx context.Context, name string, lead *preparedCall, executor pluginsdk.BatchExecutor, items []map[string]interface{}) ([]pluginsdk.BatchResult, error) {
+	grace := pm.Config().Cancellation.grace()
+	rpcCtx, stopRPC := context.WithCancel(context.Background())
+	if deadline, ok := ctx.Deadline(); ok {
+		rpcCtx, stopRPC = context.WithDeadline(context.Background(), deadline.Add(grace))
+	}
+	defer stopRPC()
+
+	type outcome struct {
+		results []pluginsdk.BatchResult
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		defer func() { done <- o }()
+		defer recoverPanic(&o.err)
+		o.results, o.err = executor.ExecuteBatch(rpcCtx, items)
+	}()
+	select {
+	case o := <-done:
+		return o.results, o.err
+	case <-ctx.Done():
+	}
+
+	cause := context.Cause(ctx)
+	killed := false
+	if canceler, ok := lead.instance.(pluginsdk.Canceler); !ok {
+		stopRPC()
+	} else {
+		go func() {
+			for _, args := range items {
+				callID, _ := args[pluginsdk.ArgCallID].(string)
+				if _, err := canceler.Cancel(callID, cause.Error()); err != nil {
+					pm.hostLog.Printf("Failed to ask plugin %s to abort batch: %v", name, err)
+					stopRPC()
+					return
+				}
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(grace):
+			pm.hostLog.Printf("Plugin %s did not abort canceled batch within %v", name, grace)
+			killed = pm.terminate(name, lead)
+		}
+	}
+	for _, args := range items {
+		pm.publishCanceled(name, args, cause, killed)
+	}
+	return nil, cause
+}
@@ -0,0 +1,88 @@
+package pluginhost
+
+import (
+	"context"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// ExecuteResult is the outcome of a successful call
+type ExecuteResult struct {
+	Result string `json:"result"`
+
+	// Partial is set when the call had a pluginsdk.ArgLatencyBudget that
+	// ran out, and the result is what the plugin had by then
+	Partial bool `json:"partial,omitempty"`
+}
+
+// ResultFile returns the file holding a result a plugin spilled because it
+// was too large to send, and whether it did. The caller owns the file and
+// removes it when done. Only files plugins reported spilling to the
+// host's spill directory count; other results starting with
+// pluginsdk.ResultFilePrefix are just text.
+func (pm *PluginManager) ResultFile(result string) (string, bool) {
+	return pm.spills.take(result)
+}
+
+// Execute runs a call like ExecutePlugin, or like ExecuteWithContext when
+// rc is not nil, and says whether the result is partial. Calls of
+// capabilities declaring pluginsdk.Capability.Partial may set a latency
+// budget with pluginsdk.ArgLatencyBudget; when it runs out the plugin is
+// asked to return what it has so far, and the call succeeds with that
+// instead of running into its timeout. Partial results are not cached.
+func (pm *PluginManager) Execute(name string, args map[string]interface{}, rc *pluginsdk.RequestContext) (ExecuteResult, error) {
+	args = withoutReserved(args)
+	if rc != nil {
+		return pm.executeWithContext(context.Background(), name, args, *rc)
+	}
+	return pm.executeConverted(name, args)
+}
+
+// latencyBudget returns the latency budget of a call, zero when it has
+// none, and the arguments without pluginsdk.ArgLatencyBudget. Only
+// capabilities declaring partial results of plugins the host can reach
+// mid-call may be given one. The caller holds pm.mu.
+func latencyBudget(info *pluginInfo, args map[string]interface{}) (time.Duration, map[string]interface{}, error) {
+	v, ok := args[pluginsdk.ArgLatencyBudget]
+	if !ok {
+		return 0, args, nil
+	}
+	budget, err := pluginsdk.ParseLatencyBudget(v)
+	if err != nil {
+		return 0, nil, err
+	}
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	version, _ := args[pluginsdk.ArgCapabilityVersion].(string)
+	if c := findCapability(info.Capabilities, capability, version); c == nil || !c.Partial {
+		return 0, nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "capability %q of plugin %s does not return partial results, so calls cannot have a %s", capability, info.Name, pluginsdk.ArgLatencyBudget)
+	}
+	if _, ok := info.Instance.(pluginsdk.BudgetExpirer); !ok {
+		return 0, nil, pluginsdk.NewError(pluginsdk.CodeUnsupported, "plugin %s cannot be given a %s", info.Name, pluginsdk.ArgLatencyBudget)
+	}
+
+	stripped := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k != pluginsdk.ArgLatencyBudget {
+			stripped[k] = v
+		}
+	}
+	return budget, stripped, nil
+}
+
+// wrapUpAt tells the plugin serving a call to return what it has once the
+// call's latency budget runs out, and returns the function that stops the
+// timer when the call returns first
+func (pm *PluginManager) wrapUpAt(name string, call *preparedCall, args map[string]interface{}) func() {
+	expirer, ok := call.instance.(pluginsdk.BudgetExpirer)
+	if call.wrapUpAt.IsZero() || !ok {
+		return func() {}
+	}
+	callID, _ := args[pluginsdk.ArgCallID].(string)
+	t := time.AfterFunc(time.Until(call.wrapUpAt), func() {
+		if _, err := expirer.ExpireBudget(callID); err != nil {
+			pm.hostLog.Printf("Failed to tell plugin %s that a call's latency budget ran out: %v", name, err)
+		}
+	})
+	return func() { t.Stop() }
+}
@@ -0,0 +1,91 @@
+package pluginhost
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// builtinPrefix marks the Path of built-in plugins, which have no binary
+const builtinPrefix = "builtin:"
+
+// RegisterBuiltin runs p inside the host process instead of as a plugin
+// subprocess. It is meant for trusted first-party plugins, which save the
+// process and serialization overhead; third-party plugins should stay
+// isolated in their own processes. p receives its plugin_config entry and,
+// when it implements HostAware, the host services, just as an external
+// plugin would, and may call any other plugin. Built-in plugins cannot be reloaded or upgraded, and
+// declarative state, profiles and lockfiles leave them alone.
+func (pm *PluginManager) RegisterBuiltin(p pluginsdk.CommandPlugin) error {
+	name := p.Name()
+	if err := checkPluginName(name); err != nil {
+		return err
+	}
+	path := builtinPrefix + name
+
+	pm.mu.Lock()
+	_, exists := pm.plugins[name]
+	denied := pm.config.isDenied(name)
+	pm.mu.Unlock()
+	if exists {
+		return fmt.Errorf("plugin %s is already loaded", name)
+	}
+	if denied {
+		return fmt.Errorf("plugin denied by policy: %s", name)
+	}
+
+	begin := time.Now()
+	if err := pm.initialize(name, p); err != nil {
+		return &StartupError{Path: path, Phase: PhaseInitialize, Elapsed: time.Since(begin), Err: err}
+	}
+	if aware, ok := p.(pluginsdk.HostAware); ok {
+		aware.SetHostServices(pm.hostServices(path))
+	}
+	md := fetchMetadata(p)
+
+	info := &pluginInfo{
+		Name:      name,
+		Path:      path,
+		Instance:  p,
+		StartedAt: time.Now(),
+		Startup:   []PhaseTiming{{Phase: PhaseInitialize, Duration: time.Since(begin)}},
+		Calls:     []string{"*"},
+		builtin:   true,
+		calls:     &callHistory{},
+		stats:     &pluginStats{},
+	}
+	pm.mu.Lock()
+	if _, exists := pm.plugins[name]; exists {
+		pm.mu.Unlock()
+		return fmt.Errorf("plugin %s is already loaded", name)
+	}
+	info.LastCrash = pm.crashes[name]
+	pm.plugins[name] = info
+	pm.setMetadata(info, md)
+	pm.mu.Unlock()
+
+	pm.hostLog.Printf("Registered built-in plugin: %s v%s", name, info.Version)
+	pm.events.Publish(Event{Type: EventPluginLoaded, Plugin: name, Data: map[string]interface{}{"version": md.version}})
+	pm.loaded(info)
+	pm.superviseDaemon(info)
+	return nil
+}
+
+// errBuiltin refuses operations that need a plugin binary
+func errBuiltin(name, op string) error {
+	return fmt.Errorf("plugin %s is built in and cannot be %s", name, op)
+}
+
+// recoverPanic turns a panic of a call into its error, so a failing
+// built-in plugin does not take the host down with it
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = &pluginsdk.PluginError{
+			Code:    pluginsdk.CodeInternal,
+			Message: fmt.Sprintf("plugin panicked: %v", r),
+			Details: map[string]string{"stack": string(debug.Stack())},
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package pluginhost
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BundleExt is the extension of offline plugin bundles
+const BundleExt = ".ocpkg"
+
+// Files at the root of every bundle
+const (
+	// bundleIndex is the BundleManifest
+	bundleIndex = "bundle.json"
+
+	// bundleSignature is the base64 ed25519 signature of bundleIndex
+	bundleSignature = "bundle.sig"
+)
+
+// BundleConfig sets which bundles InstallBundle accepts
+type BundleConfig struct {
+	// TrustedKeys are the base64 ed25519 public keys bundles may be
+	// signed with
+	TrustedKeys []string `json:"trusted_keys"`
+
+	// RequireSignature refuses bundles not signed by a trusted key
+	RequireSignature bool `json:"require_signature"`
+}
+
+func (c *BundleConfig) validate() error {
+	for i, k := range c.TrustedKeys {
+		if _, err := parsePublicKey(k); err != nil {
+			return fmt.Errorf("bundles.trusted_keys[%d]: %w", i, err)
+		}
+	}
+	if c.RequireSignature && len(c.TrustedKeys) == 0 {
+		return fmt.Errorf("bundles.require_signature needs bundles.trusted_keys")
+	}
+	return nil
+}
+
+// BundleManifest is the index of a bundle: what it holds and the checksum
+// of every other file in it. The signature covers the index, and through
+// the checksums the whole bundle.
+type BundleManifest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Binary and Manifest are the paths of the plugin binary and its
+	// manifest in the bundle
+	Binary   string `json:"binary"`
+	Manifest string `json:"manifest"`
+
+	// Docs are the paths of the documentation in the bundle
+	Docs []string `json:"docs,omitempty"`
+
+	// SBOM and Provenance are the paths of the binary's software bill of
+	// materials and SLSA provenance in the bundle, if it has them
+	SBOM       string `json:"sbom,omitempty"`
+	Provenance string `json:"provenance,omitempty"`
+
+	// Files maps each path in the bundle to its hex sha256
+	Files map[string]string `json:"files"`
+
+	Created time.Time `json:"created"`
+}
+
+// BundleInfo describes a bundle as InspectBundle and VerifyBundle read it
+type BundleInfo struct {
+	Path   string         `json:"path"`
+	Bundle BundleManifest `json:"bundle"`
+	Plugin *Manifest      `json:"plugin"`
+
+	// Size is the size of the plugin binary in bytes
+	Size int64 `json:"size"`
+
+	// Signed is set when the bundle carries a signature
+	Signed bool `json:"signed"`
+
+	// SignedBy is the trusted key the signature verifies with; empty when
+	// the bundle is unsigned or signed by a key that is not trusted
+	SignedBy string `json:"signed_by,omitempty"`
+
+	// SupplyChain is what the bundle's SBOM and provenance say; nil when
+	// they cannot be read
+	SupplyChain *SupplyChain `json:"supply_chain,omitempty"`
+}
+
+// BundleSpec is what CreateBundle packs
+type BundleSpec struct {
+	// Binary is the plugin binary
+	Binary string
+
+	// Manifest is the plugin manifest; defaults to the one next to Binary.
+	// It names the bundle.
+	Manifest string
+
+	// Docs are documentation files, stored by base name under docs/
+	Docs []string
+
+	// SBOM and Provenance are the binary's SPDX or CycloneDX SBOM and its
+	// SLSA provenance; default to the ones next to Binary, if any
+	SBOM       string
+	Provenance string
+
+	// Key signs the bundle; unsigned when nil
+	Key ed25519.PrivateKey
+}
+
+// bundle is a read bundle whose checksums matched
+type bundle struct {
+	index     BundleManifest
+	rawIndex  []byte
+	signature []byte
+	plugin    *Manifest
+	files     map[string][]byte
+}
+
+// CreateBundle packs a plugin binary, its manifest and docs into a bundle at
+// out, signed when spec has a key
+func CreateBundle(out string, spec BundleSpec) error {
+	if spec.Manifest == "" {
+		spec.Manifest = manifestPath(spec.Binary)
+	}
+	if _, err := os.Stat(spec.Binary + sbomExt); spec.SBOM == "" && err == nil {
+		spec.SBOM = spec.Binary + sbomExt
+	}
+	if _, err := os.Stat(spec.Binary + provenanceExt); spec.Provenance == "" && err == nil {
+		spec.Provenance = spec.Binary + provenanceExt
+	}
+	m, err := LoadManifest(spec.Manifest)
+	if err != nil {
+		return fmt.Errorf("bundles need the plugin manifest: %w", err)
+	}
+	if err := checkPluginName(m.Name); err != nil {
+		return err
+	}
+
+	files := make(map[string][]byte)
+	add := func(name, src string) error {
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if _, dup := files[name]; dup {
+			return fmt.Errorf("%s is in the bundle twice", name)
+		}
+		files[name] = data
+		return nil
+	}
+	index := BundleManifest{
+		Name:     m.Name,
+		Version:  m.Version,
+		Binary:   "plugin-" + m.Name,
+		Manifest: "plugin-" + m.Name + manifestExt,
+		Files:    make(map[string]string),
+		Created:  time.Now().UTC(),
+	}
+	if err := add(index.Binary, spec.Binary); err != nil {
+		return err
+	}
+	if err := add(index.Manifest, spec.Manifest); err != nil {
+		return err
+	}
+	if spec.SBOM != "" {
+		index.SBOM = index.Binary + sbomExt
+		if err := add(index.SBOM, spec.SBOM); err != nil {
+			return err
+		}
+	}
+	if spec.Provenance != "" {
+		index.Provenance = index.Binary + provenanceExt
+		if err := add(index.Provenance, spec.Provenance); err != nil {
+			return err
+		}
+	}
+	for _, doc := range spec.Docs {
+		name := path.Join("docs", filepath.Base(doc))
+		if err := add(name, doc); err != nil {
+			return err
+		}
+		index.Docs = append(index.Docs, name)
+	}
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		index.Files[name] = hex.EncodeToString(sum[:])
+	}
+
+	rawIndex, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	files[bundleIndex] = rawIndex
+	if spec.Key != nil {
+		sig := ed25519.Sign(spec.Key, rawIndex)
+		files[bundleSignature] = []byte(base64.StdEncoding.EncodeToString(sig))
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		mode := int64(0644)
+		if name == index.Binary {
+			mode = 0755
+		}
+		hdr := &tar.Header{Name: name, Mode: mode, Size: int64(len(files[name])), ModTime: index.Created, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return installFile(out, buf.Bytes(), 0644)
+}
+
+// LoadBundleKey reads an ed25519 private key in PKCS #8 PEM, as written by
+// "openssl genpkey -algorithm ed25519"
+func LoadBundleKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an ed25519 key", path)
+	}
+	return ed, nil
+}
+
+// BundlePublicKey returns the public key of key as bundles.trusted_keys
+// lists it
+func BundlePublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("want a %d byte ed25519 public key, got %d bytes", ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// checkPluginName refuses names that cannot be used in a binary's file name
+// and those of host event namespaces
+func checkPluginName(name string) error {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid plugin name %q", name)
+	}
+	if hostEventNamespaces[name] {
+		return fmt.Errorf("plugin name %q is reserved for host events", name)
+	}
+	return nil
+}
+
+// readBundle reads the bundle at p and checks every file against the
+// index. The signature is read but not checked.
+func readBundle(p string) (*bundle, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a bundle: %w", p, err)
+	}
+	tr := tar.NewReader(zr)
+	b := &bundle{files: make(map[string][]byte)}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a bundle: %w", p, err)
+		}
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("bundle entry %s is not a regular file", hdr.Name)
+		}
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("bundle entry %s is outside the bundle", hdr.Name)
+		}
+		if _, dup := b.files[name]; dup {
+			return nil, fmt.Errorf("bundle entry %s appears twice", name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		b.files[name] = data
+	}
+
+	b.rawIndex = b.files[bundleIndex]
+	if b.rawIndex == nil {
+		return nil, fmt.Errorf("%s has no %s", p, bundleIndex)
+	}
+	if err := json.Unmarshal(b.rawIndex, &b.index); err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", bundleIndex, p, err)
+	}
+	if sig, ok := b.files[bundleSignature]; ok {
+		if b.signature, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err != nil {
+			return nil, fmt.Errorf("invalid %s in %s: %w", bundleSignature, p, err)
+		}
+	}
+	delete(b.files, bundleIndex)
+	delete(b.files, bundleSignature)
+
+	for name, data := range b.files {
+		want, ok := b.index.Files[name]
+		if !ok {
+			return nil, fmt.Errorf("bundle entry %s is not in the index", name)
+		}
+		sum := sha256.Sum256(data)
+		if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+			return nil, fmt.Errorf("checksum of bundle entry %s does not match: got %s, want %s", name, got, want)
+		}
+	}
+	for name := range b.index.Files {
+		if _, ok := b.files[name]; !ok {
+			return nil, fmt.Errorf("bundle entry %s is missing", name)
+		}
+	}
+
+	if err := checkPluginName(b.index.Name); err != nil {
+		return nil, err
+	}
+	if _, ok := b.files[b.index.Binary]; !ok {
+		return nil, fmt.Errorf("bundle has no binary")
+	}
+	for _, name := range append([]string{b.index.SBOM, b.index.Provenance}, b.index.Docs...) {
+		if _, ok := b.files[name]; name != "" && !ok {
+			return nil, fmt.Errorf("bundle entry %s is missing", name)
+		}
+	}
+	raw, ok := b.files[b.index.Manifest]
+	if !ok {
+		return nil, fmt.Errorf("bundle has no manifest")
+	}
+	if b.plugin, err = parseManifest(raw); err != nil {
+		return nil, fmt.Errorf("invalid plugin manifest in bundle: %w", err)
+	}
+	if b.plugin.Name != b.index.Name || b.plugin.Version != b.index.Version {
+		return nil, fmt.Errorf("bundle is %s v%s, but its manifest says %s v%s", b.index.Name, b.index.Version, b.plugin.Name, b.plugin.Version)
+	}
+	return b, nil
+}
+
+// signer returns the trusted key the bundle's signature verifies with
+func (b *bundle) signer(trusted []string) string {
+	if b.signature == nil {
+		return ""
+	}
+	for _, k := range trusted {
+		pub, err := parsePublicKey(k)
+		if err == nil && ed25519.Verify(pub, b.rawIndex, b.signature) {
+			return k
+		}
+	}
+	return ""
+}
+
+func (b *bundle) info(p string, trusted []string) *BundleInfo {
+	info := &BundleInfo{
+		Path:     p,
+		Bundle:   b.index,
+		Plugin:   b.plugin,
+		Size:     int64(len(b.files[b.index.Binary])),
+		Signed:   b.signature != nil,
+		SignedBy: b.signer(trusted),
+	}
+	if sc, err := readSupplyChain(b.files[b.index.SBOM], b.files[b.index.Provenance]); err == nil {
+		info.SupplyChain = sc.orNil()
+	}
+	return info
+}
+
+// InspectBundle reads a bundle and describes it. Its checksums must match;
+// its signature is reported but need not be trusted.
+func (pm *PluginManager) InspectBundle(path string) (*BundleInfo, error) {
+	b, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	return b.info(path, pm.Config().Bundles.TrustedKeys), nil
+}
+
+// VerifyBundle checks that a bundle is intact, signed by a trusted key when
+// bundles.require_signature is set, and that its SBOM and provenance pass
+// the supply_chain policy
+func (pm *PluginManager) VerifyBundle(path string) (*BundleInfo, error) {
+	b, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	return pm.verifyBundle(b, path)
+}
+
+func (pm *PluginManager) verifyBundle(b *bundle, path string) (*BundleInfo, error) {
+	cfg := pm.Config().Bundles
+	info := b.info(path, cfg.TrustedKeys)
+	if cfg.RequireSignature && info.SignedBy == "" {
+		if info.Signed {
+			return nil, fmt.Errorf("bundle %s is not signed by a trusted key", path)
+		}
+		return nil, fmt.Errorf("bundle %s is not signed", path)
+	}
+	if _, err := pm.bundleSupplyChain(b); err != nil {
+		err = fmt.Errorf("supply chain check of bundle %s failed: %w", path, err)
+		if pm.Config().SupplyChain.Mode != LockWarn {
+			return nil, err
+		}
+		pm.hostLog.Printf("Accepting bundle despite failed check: %v", err)
+	}
+	return info, nil
+}
+
+// InstallBundle verifies a bundle, puts its binary, manifest and docs in
+// provision.dir or the first plugin directory, and loads the plugin. The
+// docs go to a directory next to the binary, e.g. plugin-hello.docs.
+func (pm *PluginManager) InstallBundle(path string) (string, error) {
+	pm.installMu.Lock()
+	defer pm.installMu.Unlock()
+
+	b, err := readBundle(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := pm.verifyBundle(b, path)
+	if err != nil {
+		return "", err
+	}
+	name, version := b.index.Name, b.index.Version
+
+	cfg := pm.Config()
+	if cfg.isDenied(name) {
+		return "", fmt.Errorf("plugin denied by policy: %s", name)
+	}
+	if err := pm.checkPin(name, version); err != nil {
+		return "", err
+	}
+	pm.mu.RLock()
+	_, loaded := pm.plugins[name]
+	pm.mu.RUnlock()
+	if loaded {
+		return "", fmt.Errorf("plugin %s is already loaded; upgrade it instead", name)
+	}
+
+	binary, err := pm.installPath(cfg.Provision, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to install bundle %s: %w", path, err)
+	}
+	if err := installFile(manifestPath(binary), b.files[b.index.Manifest], 0644); err != nil {
+		return "", err
+	}
+	attachments := map[string]string{b.index.SBOM: binary + sbomExt, b.index.Provenance: binary + provenanceExt}
+	for name, dest := range attachments {
+		if name == "" {
+			os.Remove(dest)
+			continue
+		}
+		if err := installFile(dest, b.files[name], 0644); err != nil {
+			return "", err
+		}
+	}
+	for _, doc := range b.index.Docs {
+		dest := filepath.Join(binary+".docs", filepath.FromSlash(strings.TrimPrefix(doc, "docs/")))
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return "", err
+		}
+		if err := installFile(dest, b.files[doc], 0644); err != nil {
+			return "", err
+		}
+	}
+	if err := installFile(binary, b.files[b.index.Binary], 0755); err != nil {
+		return "", err
+	}
+	signed := "unsigned"
+	if info.SignedBy != "" {
+		signed = "signed by " + info.SignedBy
+	}
+	pm.hostLog.Printf("Installed plugin %s v%s from bundle %s (%s): %s", name, version, path, signed, binary)
+
+	got, err := pm.loadPlugin(binary)
+	if err != nil {
+		return "", fmt.Errorf("failed to load installed plugin %s: %w", name, err)
+	}
+	if pm.reportedBy(got) != name {
+		pm.UnloadPlugin(got)
+		return "", fmt.Errorf("plugin at %s reports name %q but its bundle says %q", binary, got, name)
+	}
+	pm.events.Publish(Event{Type: EventPluginInstalled, Plugin: name, Data: map[string]interface{}{"version": version, "bundle": path, "path": binary}})
+	return name, nil
+}
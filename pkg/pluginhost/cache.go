@@ -0,0 +1,176 @@
+package pluginhost
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultResultCacheSize is how many results a cache holds when no size is
+// given; the least recently used are evicted beyond it
+const DefaultResultCacheSize = 10000
+
+// cacheSweepInterval is how often Put drops the expired entries of every
+// plugin, so results that are never read again do not pile up
+const cacheSweepInterval = time.Minute
+
+// cacheEntry is a single memoized plugin result
+type cacheEntry struct {
+	plugin  string
+	key     string
+	result  string
+	expires time.Time
+}
+
+// ResultCache memoizes plugin results for capabilities the plugin declared
+// cacheable. Entries are grouped by plugin so a reload can drop them all.
+// It holds a bounded number of results, evicting the least recently used.
+type ResultCache struct {
+	entries map[string]map[string]*list.Element
+	lru     *list.List
+	max     int
+	swept   time.Time
+	mu      sync.Mutex
+}
+
+// NewResultCache creates an empty result cache holding up to
+// DefaultResultCacheSize results
+func NewResultCache() *ResultCache {
+	return NewResultCacheSize(DefaultResultCacheSize)
+}
+
+// NewResultCacheSize creates an empty result cache holding up to max
+// results; max <= 0 means DefaultResultCacheSize
+func NewResultCacheSize(max int) *ResultCache {
+	if max <= 0 {
+		max = DefaultResultCacheSize
+	}
+	return &ResultCache{
+		entries: make(map[string]map[string]*list.Element),
+		lru:     list.New(),
+		max:     max,
+		swept:   time.Now(),
+	}
+}
+
+// cacheKey derives a key from the capability and arguments. encoding/json
+// sorts map keys, so logically equal argument maps normalize to the same key.
+// The request ID differs for every request, so it is left out of the key.
+func cacheKey(capability string, args map[string]interface{}) (string, bool) {
+	if rc, ok := args[pluginsdk.ArgContext].(map[string]interface{}); ok {
+		if _, ok := rc["request_id"]; ok {
+			args = withoutRequestID(args, rc)
+		}
+	}
+	normalized, err := json.Marshal(args)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(append([]byte(capability+"\x00"), normalized...))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// withoutRequestID returns args with the request context rc minus its ID
+func withoutRequestID(args, rc map[string]interface{}) map[string]interface{} {
+	shared := make(map[string]interface{}, len(rc))
+	for k, v := range rc {
+		if k != "request_id" {
+			shared[k] = v
+		}
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	out[pluginsdk.ArgContext] = shared
+	return out
+}
+
+// Get returns a cached result if one exists and has not expired
+func (c *ResultCache) Get(plugin, key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[plugin][key]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return "", false
+	}
+	c.lru.MoveToFront(el)
+	return entry.result, true
+}
+
+// Put stores a result for the given lifetime, evicting the least recently
+// used results beyond the cache's size
+func (c *ResultCache) Put(plugin, key, result string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.swept) >= cacheSweepInterval {
+		c.sweep(now)
+	}
+	if el, ok := c.entries[plugin][key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.result, entry.expires = result, now.Add(ttl)
+		c.lru.MoveToFront(el)
+		return
+	}
+	if c.entries[plugin] == nil {
+		c.entries[plugin] = make(map[string]*list.Element)
+	}
+	c.entries[plugin][key] = c.lru.PushFront(&cacheEntry{plugin: plugin, key: key, result: result, expires: now.Add(ttl)})
+	for c.lru.Len() > c.max {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Len returns how many results the cache holds, expired ones not yet
+// swept included
+func (c *ResultCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+// InvalidatePlugin drops every cached result produced by a plugin
+func (c *ResultCache) InvalidatePlugin(plugin string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, el := range c.entries[plugin] {
+		c.lru.Remove(el)
+	}
+	delete(c.entries, plugin)
+}
+
+// sweep drops the expired entries. The caller holds c.mu.
+func (c *ResultCache) sweep(now time.Time) {
+	c.swept = now
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if now.After(el.Value.(*cacheEntry).expires) {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+// remove drops the entry of el. The caller holds c.mu.
+func (c *ResultCache) remove(el *list.Element) {
+	entry := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries[entry.plugin], entry.key)
+	if len(c.entries[entry.plugin]) == 0 {
+		delete(c.entries, entry.plugin)
+	}
+}
@@ -0,0 +1,45 @@
+package pluginhost
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestResultCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewResultCacheSize(2)
+	c.Put("p", "a", "1", time.Hour)
+	c.Put("p", "b", "2", time.Hour)
+	c.Get("p", "a")
+	c.Put("q", "c", "3", time.Hour)
+
+	if _, ok := c.Get("p", "b"); ok {
+		t.Error("b was used least recently and should be evicted")
+	}
+	for _, key := range []struct{ plugin, key string }{{"p", "a"}, {"q", "c"}} {
+		if _, ok := c.Get(key.plugin, key.key); !ok {
+			t.Errorf("%s/%s should be cached", key.plugin, key.key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len = %d, want 2", c.Len())
+	}
+}
+
+func TestResultCacheSweepsExpiredEntries(t *testing.T) {
+	c := NewResultCacheSize(100)
+	for i := 0; i < 10; i++ {
+		c.Put("p", fmt.Sprint(i), "x", time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+	c.swept = time.Now().Add(-cacheSweepInterval)
+	c.Put("p", "fresh", "y", time.Hour)
+
+	if c.Len() != 1 {
+		t.Errorf("Len = %d after sweeping, want 1", c.Len())
+	}
+	c.InvalidatePlugin("p")
+	if c.Len() != 0 || len(c.entries) != 0 {
+		t.Errorf("InvalidatePlugin left %d entries", c.Len())
+	}
+}
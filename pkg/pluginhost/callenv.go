@@ -0,0 +1,152 @@
+package pluginhost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// CallEnv scopes one call to a project: the directory it works in and the
+// environment variables it sees
+type CallEnv struct {
+	// WorkDir is made absolute and must be an existing directory
+	WorkDir string
+
+	// Env is filtered by the host's call_env.allow list
+	Env map[string]string
+}
+
+// CallEnvConfig limits the environment callers can pass to plugins
+type CallEnvConfig struct {
+	// Allow lists the variable names passed to plugins; a trailing "*"
+	// matches by prefix, e.g. "GIT_*". Empty passes every variable.
+	// Variables that change how programs are loaded, LD_* and DYLD_*, are
+	// never passed.
+	Allow []string `json:"allow"`
+}
+
+// loaderVars are the prefixes of the variables that make programs load
+// other code, e.g. LD_PRELOAD
+var loaderVars = []string{"LD_", "DYLD_"}
+
+// allowed reports whether a variable may be passed to plugins
+func (c *CallEnvConfig) allowed(name string) bool {
+	for _, prefix := range loaderVars {
+		if strings.HasPrefix(strings.ToUpper(name), prefix) {
+			return false
+		}
+	}
+	if len(c.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range c.Allow {
+		if pattern == name || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeCall checks the working directory of a call and filters its
+// environment variables by the allow list, whichever way the call came in.
+// The directory is made absolute; variables that are not allowed, or not
+// strings, are dropped.
+func (c *CallEnvConfig) scopeCall(args map[string]interface{}) (map[string]interface{}, error) {
+	rawDir, hasDir := args[pluginsdk.ArgWorkDir]
+	rawEnv, hasEnv := args[pluginsdk.ArgEnv]
+	if !hasDir && !hasEnv {
+		return args, nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		if k != pluginsdk.ArgWorkDir && k != pluginsdk.ArgEnv {
+			out[k] = v
+		}
+	}
+
+	if hasDir {
+		dir, ok := rawDir.(string)
+		if !ok {
+			return nil, &pluginsdk.PluginError{Code: pluginsdk.CodeInvalidArgument, Message: "working directory must be a string"}
+		}
+		if dir != "" {
+			dir, err := filepath.Abs(dir)
+			if err != nil {
+				return nil, &pluginsdk.PluginError{Code: pluginsdk.CodeInvalidArgument, Message: fmt.Sprintf("invalid working directory: %v", err)}
+			}
+			if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+				return nil, &pluginsdk.PluginError{Code: pluginsdk.CodeInvalidArgument, Message: "working directory does not exist: " + dir}
+			}
+			out[pluginsdk.ArgWorkDir] = dir
+		}
+	}
+
+	if hasEnv {
+		vars := make(map[string]interface{})
+		switch env := rawEnv.(type) {
+		case map[string]interface{}:
+			for k, v := range env {
+				if s, ok := v.(string); ok && c.allowed(k) {
+					vars[k] = s
+				}
+			}
+		case map[string]string:
+			for k, v := range env {
+				if c.allowed(k) {
+					vars[k] = v
+				}
+			}
+		}
+		if len(vars) > 0 {
+			out[pluginsdk.ArgEnv] = vars
+		}
+	}
+	return out, nil
+}
+
+// ExecuteIn runs a call scoped to env. The plugin receives the working
+// directory and the allowed variables in the reserved workdir and env
+// arguments, replacing any args carries; nothing about the plugin process
+// itself changes, so calls for different projects can run side by side.
+func (pm *PluginManager) ExecuteIn(name string, args map[string]interface{}, env CallEnv) (string, error) {
+	args = withoutReserved(args)
+	callArgs := make(map[string]interface{}, len(args)+2)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	if env.WorkDir != "" {
+		callArgs[pluginsdk.ArgWorkDir] = env.WorkDir
+	}
+	if len(env.Env) > 0 {
+		vars := make(map[string]interface{}, len(env.Env))
+		for k, v := range env.Env {
+			vars[k] = v
+		}
+		callArgs[pluginsdk.ArgEnv] = vars
+	}
+	res, err := pm.executeConverted(name, callArgs)
+	return res.Result, err
+}
+
+// recordedArgs returns args as they may be stored in the history and crash
+// bundles: environment values often hold credentials, so only the variable
+// names are kept
+func recordedArgs(args map[string]interface{}) map[string]interface{} {
+	vars, ok := args[pluginsdk.ArgEnv].(map[string]interface{})
+	if !ok {
+		return args
+	}
+	names := make([]interface{}, 0, len(vars))
+	for k := range vars {
+		names = append(names, k)
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		out[k] = v
+	}
+	out[pluginsdk.ArgEnv] = names
+	return out
+}
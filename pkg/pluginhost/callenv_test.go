@@ -0,0 +1,74 @@
+package pluginhost
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestScopeCall(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		allow   []string
+		args    map[string]interface{}
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "no scope",
+			args: map[string]interface{}{"a": 1},
+			want: map[string]interface{}{"a": 1},
+		},
+		{
+			name:  "allow list",
+			allow: []string{"GIT_AUTHOR_*", "HOME"},
+			args:  map[string]interface{}{pluginsdk.ArgEnv: map[string]interface{}{"GIT_AUTHOR_NAME": "ada", "GIT_SSH_COMMAND": "sh", "HOME": "/h"}},
+			want:  map[string]interface{}{pluginsdk.ArgEnv: map[string]interface{}{"GIT_AUTHOR_NAME": "ada", "HOME": "/h"}},
+		},
+		{
+			name: "loader variables without allow list",
+			args: map[string]interface{}{pluginsdk.ArgEnv: map[string]interface{}{"LD_PRELOAD": "/x.so", "DYLD_INSERT_LIBRARIES": "/x", "TERM": "xterm"}},
+			want: map[string]interface{}{pluginsdk.ArgEnv: map[string]interface{}{"TERM": "xterm"}},
+		},
+		{
+			name:  "nothing allowed",
+			allow: []string{"HOME"},
+			args:  map[string]interface{}{pluginsdk.ArgEnv: map[string]interface{}{"PATH": "/tmp"}, "a": 1},
+			want:  map[string]interface{}{"a": 1},
+		},
+		{
+			name: "non-string values",
+			args: map[string]interface{}{pluginsdk.ArgEnv: map[string]interface{}{"N": 1}},
+			want: map[string]interface{}{},
+		},
+		{
+			name: "existing workdir",
+			args: map[string]interface{}{pluginsdk.ArgWorkDir: dir},
+			want: map[string]interface{}{pluginsdk.ArgWorkDir: dir},
+		},
+		{
+			name:    "missing workdir",
+			args:    map[string]interface{}{pluginsdk.ArgWorkDir: dir + "/missing"},
+			wantErr: true,
+		},
+		{
+			name:    "workdir of another type",
+			args:    map[string]interface{}{pluginsdk.ArgWorkDir: 42},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := CallEnvConfig{Allow: tt.allow}
+			got, err := cfg.scopeCall(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package pluginhost
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// pluginHost is the HostServices one plugin process receives. It knows the
+// plugin's binary, so calls back into the host can be attributed to it.
+type pluginHost struct {
+	pm   *PluginManager
+	path string
+}
+
+// hostServices returns the HostServices for the plugin binary at path
+func (pm *PluginManager) hostServices(path string) pluginsdk.HostServices {
+	return &pluginHost{pm: pm, path: path}
+}
+
+func (h *pluginHost) RenderTemplate(name string, data map[string]interface{}) (string, error) {
+	return h.pm.RenderTemplate(name, data)
+}
+
+// CallPlugin runs capability of another plugin on behalf of the calling
+// plugin. The callee receives the caller's name in the reserved caller
+// argument, and the current context of the caller's request when the
+// arguments carry it.
+func (h *pluginHost) CallPlugin(plugin, capability string, args map[string]interface{}) (string, error) {
+	pm := h.pm
+	caller, allowed := pm.callerOf(h.path)
+	if caller == "" {
+		return "", pluginsdk.NewError(pluginsdk.CodeUnavailable, "calling plugin is not registered")
+	}
+	if !allowed(plugin) {
+		return "", pluginsdk.NewError(pluginsdk.CodePermissionDenied, "plugin %s may not call %s; list it under permissions.calls in its manifest", caller, plugin)
+	}
+
+	if cycle := pm.calls.enter(caller, plugin); cycle != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "call would form a cycle: %s", strings.Join(cycle, " -> "))
+	}
+	defer pm.calls.leave(caller, plugin)
+
+	callArgs := make(map[string]interface{}, len(args)+2)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	if capability != "" {
+		callArgs[pluginsdk.ArgCapability] = capability
+	}
+	callArgs[pluginsdk.ArgCaller] = caller
+
+	// A callee joining the caller's request sees the values set so far
+	if id := pluginsdk.ContextOf(args).RequestID; id != "" {
+		if rc, ok := pm.requests.join(id, plugin); ok {
+			callArgs[pluginsdk.ArgContext] = rc
+		}
+	}
+	return pm.execute(plugin, callArgs)
+}
+
+// reservedArgs are the argument keys only the host sets: the plugin making
+// a call, the request it belongs to, the environment it runs in and the
+// project it works on
+var reservedArgs = []string{pluginsdk.ArgCaller, pluginsdk.ArgContext, pluginsdk.ArgEnv, pluginsdk.ArgWorkDir, pluginsdk.ArgProject}
+
+// withoutReserved returns args without the keys only the host sets, so
+// callers cannot pose as a plugin, join a request or change where a call
+// runs; ExecuteWithContext and ExecuteIn set them instead
+func withoutReserved(args map[string]interface{}) map[string]interface{} {
+	var out map[string]interface{}
+	for _, k := range reservedArgs {
+		if _, ok := args[k]; !ok {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(args))
+			for k, v := range args {
+				out[k] = v
+			}
+		}
+		delete(out, k)
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// callerOf returns the name of the plugin running the binary at path and a
+// check of which plugins it may call
+func (pm *PluginManager) callerOf(path string) (string, func(string) bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, info := range pm.plugins {
+		if info.Path != path {
+			continue
+		}
+		patterns := info.Calls
+		return info.Name, func(name string) bool {
+			for _, p := range patterns {
+				if p == name || (strings.HasSuffix(p, "*") && strings.HasPrefix(name, strings.TrimSuffix(p, "*"))) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return "", nil
+}
+
+// callGraph records which plugins are waiting on calls to which others. A
+// new call forms a cycle when its callee is already waiting, directly or
+// through others, on its caller. Such a call could only recurse or wait
+// forever, so it is refused. Unrelated calls between the same plugins in
+// opposite directions are refused too, which errs on the safe side.
+type callGraph struct {
+	waiting map[string]map[string]int
+	mu      sync.Mutex
+}
+
+// enter records a call from caller to callee, or returns the cycle it
+// would form without recording it
+func (g *callGraph) enter(caller, callee string) []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if path := g.path(callee, caller, map[string]bool{}); path != nil {
+		return append([]string{caller}, path...)
+	}
+	if g.waiting == nil {
+		g.waiting = make(map[string]map[string]int)
+	}
+	if g.waiting[caller] == nil {
+		g.waiting[caller] = make(map[string]int)
+	}
+	g.waiting[caller][callee]++
+	return nil
+}
+
+// leave removes a call recorded by enter
+func (g *callGraph) leave(caller, callee string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.waiting[caller][callee]--
+	if g.waiting[caller][callee] == 0 {
+		delete(g.waiting[caller], callee)
+	}
+	if len(g.waiting[caller]) == 0 {
+		delete(g.waiting, caller)
+	}
+}
+
+// path returns the plugins from from to to along waiting calls, or nil if
+// to cannot be reached. The caller holds g.mu.
+func (g *callGraph) path(from, to string, seen map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+	seen[from] = true
+	for next := range g.waiting[from] {
+		if seen[next] {
+			continue
+		}
+		if rest := g.path(next, to, seen); rest != nil {
+			return append([]string{from}, rest...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// EventCallCanceled is published when a call is canceled or times out
+// before its plugin answered
+const EventCallCanceled = "call.canceled"
+
+// DefaultCancelGrace is how long a canceled call gets to stop when the
+// config sets no grace period
+const DefaultCancelGrace = 5 * time.Second
+
+// CancellationConfig sets what happens to calls that are canceled or run
+// out of time. Their plugin is asked to abort the call; if the call has not
+// returned after Grace, the process serving it is killed and restarted.
+type CancellationConfig struct {
+	// Grace is how long the plugin gets to abort a call; defaults to
+	// DefaultCancelGrace
+	Grace Duration `json:"grace"`
+}
+
+func (c *CancellationConfig) validate() error {
+	if c.Grace < 0 {
+		return fmt.Errorf("cancellation.grace must not be negative")
+	}
+	return nil
+}
+
+func (c CancellationConfig) grace() time.Duration {
+	if c.Grace == 0 {
+		return DefaultCancelGrace
+	}
+	return time.Duration(c.Grace)
+}
+
+// runningCalls holds the cancel functions of the calls in flight by the ID
+// of the request they belong to
+type runningCalls struct {
+	calls map[string]map[int]context.CancelCauseFunc
+	next  int
+	mu    sync.Mutex
+}
+
+// add registers a call of request, which is empty for calls outside one,
+// and returns the function removing it
+func (r *runningCalls) add(request string, cancel context.CancelCauseFunc) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.calls == nil {
+		r.calls = make(map[string]map[int]context.CancelCauseFunc)
+	}
+	if r.calls[request] == nil {
+		r.calls[request] = make(map[int]context.CancelCauseFunc)
+	}
+	r.next++
+	id := r.next
+	r.calls[request][id] = cancel
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		delete(r.calls[request], id)
+		if len(r.calls[request]) == 0 {
+			delete(r.calls, request)
+		}
+	}
+}
+
+// cancel cancels every call of request with cause and returns how many
+// there were
+func (r *runningCalls) cancel(request string, cause error) int {
+	r.mu.Lock()
+	cancels := make([]context.CancelCauseFunc, 0, len(r.calls[request]))
+	for _, cancel := range r.calls[request] {
+		cancels = append(cancels, cancel)
+	}
+	r.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel(cause)
+	}
+	return len(cancels)
+}
+
+// cancelAll cancels every call with cause and returns how many there were
+func (r *runningCalls) cancelAll(cause error) int {
+	r.mu.Lock()
+	var cancels []context.CancelCauseFunc
+	for _, calls := range r.calls {
+		for _, cancel := range calls {
+			cancels = append(cancels, cancel)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel(cause)
+	}
+	return len(cancels)
+}
+
+// CancelRequest cancels the calls running for a request, including those
+// plugins made to other plugins, and returns how many it canceled. The
+// calls fail with pluginsdk.CodeCanceled.
+func (pm *PluginManager) CancelRequest(requestID, reason string) int {
+	if requestID == "" {
+		return 0
+	}
+	n := pm.running.cancel(requestID, pluginsdk.NewError(pluginsdk.CodeCanceled, "call canceled: %s", reason))
+	if n > 0 {
+		pm.hostLog.Printf("Canceled %d call(s) of request %s: %s", n, requestID, reason)
+	}
+	return n
+}
+
+// withCallID returns args with a new pluginsdk.ArgCallID, by which the
+// plugin is told to abort the call
+func withCallID(args map[string]interface{}) map[string]interface{} {
+	id, err := newSessionID()
+	if err != nil {
+		return args
+	}
+	out := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		out[k] = v
+	}
+	out[pluginsdk.ArgCallID] = id
+	return out
+}
+
+// invokeCancelable runs a call until it returns or ctx ends. When ctx ends
+// first, the plugin is asked to abort the call and given the grace period
+// to return, after which the process serving it is killed. Plugins that
+// cannot be asked have the call abandoned right away, as before.
+func (pm *PluginManager) invokeCancelable(ctx context.Context, name string, call *preparedCall, args map[string]interface{}) (string, error) {
+	grace := pm.Config().Cancellation.grace()
+
+	// The plugin sees the call's deadline, extended by the grace period
+	rpcCtx, stopRPC := context.WithCancel(context.Background())
+	if deadline, ok := ctx.Deadline(); ok {
+		rpcCtx, stopRPC = context.WithDeadline(context.Background(), deadline.Add(grace))
+	}
+	defer stopRPC()
+	rpcCtx = withPluginCall(rpcCtx, name, args)
+	defer pm.wrapUpAt(name, call, args)()
+	defer pm.trackLLMCall(name, args)()
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		o.result, o.err = invokeContext(rpcCtx, call.instance, args)
+		done <- o
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+	}
+
+	cause := context.Cause(ctx)
+	canceler, ok := call.instance.(pluginsdk.Canceler)
+	if !ok {
+		stopRPC()
+		pm.publishCanceled(name, args, cause, false)
+		return "", cause
+	}
+	go func() {
+		callID, _ := args[pluginsdk.ArgCallID].(string)
+		if _, err := canceler.Cancel(callID, cause.Error()); err != nil {
+			// Plugins predating cancellation only notice the RPC ending
+			pm.hostLog.Printf("Failed to ask plugin %s to abort call: %v", name, err)
+			stopRPC()
+		}
+	}()
+
+	select {
+	case <-done:
+		pm.publishCanceled(name, args, cause, false)
+	case <-time.After(grace):
+		pm.hostLog.Printf("Plugin %s did not abort canceled call within %v", name, grace)
+		pm.publishCanceled(name, args, cause, pm.terminate(name, call))
+	}
+	return "", cause
+}
+
+// terminate kills the process serving a call that ignored its cancellation
+// and reports whether it did. The main process of a plugin is restarted;
+// pools replace their workers themselves.
+func (pm *PluginManager) terminate(name string, call *preparedCall) bool {
+	if call.worker != nil && call.worker.client != nil {
+		pm.hostLog.Printf("Killing pool process of plugin %s", name)
+		killProcess(call.worker.client)
+		return true
+	}
+
+	pm.mu.RLock()
+	client, builtin := call.info.Client, call.info.builtin
+	replaced := call.info.Instance != call.instance
+	pm.mu.RUnlock()
+	if builtin || client == nil || replaced {
+		return false
+	}
+
+	pm.hostLog.Printf("Killing and restarting plugin %s", name)
+	// A busy process would also hang the graceful shutdown in
+	// ReloadPlugin, so stop it the hard way first
+	killProcess(client)
+	go func() {
+		if err := pm.ReloadPlugin(name); err != nil {
+			pm.hostLog.Printf("Failed to restart plugin %s: %v", name, err)
+		}
+	}()
+	return true
+}
+
+func (pm *PluginManager) publishCanceled(name string, args map[string]interface{}, cause error, killed bool) {
+	pm.events.Publish(Event{Type: EventCallCanceled, Plugin: name, Data: map[string]interface{}{
+		"reason":     cause.Error(),
+		"killed":     killed,
+		"request_id": pluginsdk.ContextOf(args).RequestID,
+	}})
+}
@@ -0,0 +1,236 @@
+package pluginhost
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// gitTimeout bounds each git command run for argument templates and
+// project contexts
+const gitTimeout = 2 * time.Second
+
+// ArgsData is what the argument templates of a command see, e.g.
+// {{ .Flags.target }} or {{ .Git.Branch }}
+type ArgsData struct {
+	Command string
+
+	// Input is the positional input of the command, e.g. the paths after
+	// "/analyze"
+	Input []string
+
+	// Flags maps each flag of the request to its value: "--target=prod"
+	// gives Flags.target "prod" and "--uc" gives Flags.uc "true". Aliases
+	// are also set under their canonical name.
+	Flags map[string]string
+
+	// Env is the host's environment, limited by call_env.allow
+	Env map[string]string
+
+	// Persona is the persona the request runs as
+	Persona string
+
+	Files   []string
+	WorkDir string
+
+	// Git describes the repository WorkDir is in; it is empty outside one
+	Git GitInfo
+}
+
+// GitInfo is the state of a git repository
+type GitInfo struct {
+	Branch string
+	Commit string
+	Root   string
+
+	// Dirty is true when there are uncommitted changes
+	Dirty bool
+}
+
+// parseArgTemplates checks the templates in a command's args; the
+// functions are those of prompt templates
+func parseArgTemplates(cmd CommandDef) error {
+	funcs := (&PluginManager{}).templateFuncs()
+	var check func(key string, v interface{}) error
+	check = func(key string, v interface{}) error {
+		switch v := v.(type) {
+		case string:
+			if _, err := template.New(key).Funcs(funcs).Parse(v); err != nil {
+				return fmt.Errorf("command %s: invalid template in args.%s: %w", cmd.Name, key, err)
+			}
+		case map[string]interface{}:
+			for k, item := range v {
+				if err := check(key+"."+k, item); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for i, item := range v {
+				if err := check(fmt.Sprintf("%s[%d]", key, i), item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for k, v := range cmd.Args {
+		if err := check(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// argsData gathers what the argument templates of cmd may use for req.
+// git only runs when a template mentions .Git.
+func (pm *PluginManager) argsData(cmd CommandDef, req RouteRequest, persona string) ArgsData {
+	data := ArgsData{
+		Command: req.Command,
+		Input:   req.Input,
+		Flags:   make(map[string]string, len(req.Flags)),
+		Env:     make(map[string]string),
+		Persona: persona,
+		Files:   req.Files,
+		WorkDir: req.WorkDir,
+	}
+	for _, flag := range req.Flags {
+		name, value, found := strings.Cut(strings.TrimLeft(flag, "-"), "=")
+		if !found {
+			value = "true"
+		}
+		data.Flags[name] = value
+		if canonical := pm.flags.canonical(name); canonical != name {
+			data.Flags[canonical] = value
+		}
+	}
+	allow := pm.Config().CallEnv
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && allow.allowed(k) {
+			data.Env[k] = v
+		}
+	}
+	if mentionsGit(cmd.Args) {
+		data.Git = gitInfo(req.WorkDir)
+	}
+	return data
+}
+
+// renderArgs renders the argument templates of cmd with data. Every string
+// is a template and renders to a string; entries rendering to "" are left
+// out, so optional flags need no conditionals.
+func (pm *PluginManager) renderArgs(cmd CommandDef, data ArgsData) (map[string]interface{}, error) {
+	funcs := pm.templateFuncs()
+	var render func(key string, v interface{}) (interface{}, error)
+	render = func(key string, v interface{}) (interface{}, error) {
+		switch v := v.(type) {
+		case string:
+			if !strings.Contains(v, "{{") {
+				return v, nil
+			}
+			t, err := template.New(key).Funcs(funcs).Option("missingkey=zero").Parse(v)
+			if err != nil {
+				return nil, err
+			}
+			var b bytes.Buffer
+			if err := t.Execute(&b, data); err != nil {
+				return nil, fmt.Errorf("command %s: args.%s: %w", cmd.Name, key, err)
+			}
+			return b.String(), nil
+		case map[string]interface{}:
+			out := make(map[string]interface{}, len(v))
+			for k, item := range v {
+				rendered, err := render(key+"."+k, item)
+				if err != nil {
+					return nil, err
+				}
+				if rendered != "" {
+					out[k] = rendered
+				}
+			}
+			return out, nil
+		case []interface{}:
+			out := make([]interface{}, 0, len(v))
+			for i, item := range v {
+				rendered, err := render(fmt.Sprintf("%s[%d]", key, i), item)
+				if err != nil {
+					return nil, err
+				}
+				if rendered != "" {
+					out = append(out, rendered)
+				}
+			}
+			return out, nil
+		}
+		return v, nil
+	}
+
+	args := make(map[string]interface{}, len(cmd.Args))
+	for k, v := range cmd.Args {
+		rendered, err := render(k, v)
+		if err != nil {
+			return nil, err
+		}
+		if rendered != "" {
+			args[k] = rendered
+		}
+	}
+	return args, nil
+}
+
+// mentionsGit reports whether a template in args uses .Git
+func mentionsGit(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.Contains(v, ".Git")
+	case map[string]interface{}:
+		for _, item := range v {
+			if mentionsGit(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if mentionsGit(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gitInfo describes the repository dir is in; fields git cannot answer are
+// left empty
+func gitInfo(dir string) GitInfo {
+	info := GitInfo{Root: gitOutput(dir, "rev-parse", "--show-toplevel")}
+	if info.Root == "" {
+		return GitInfo{}
+	}
+	info.Branch = gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	info.Commit = gitOutput(dir, "rev-parse", "HEAD")
+	info.Dirty = gitOutput(dir, "status", "--porcelain") != ""
+	return info
+}
+
+// gitOutput runs git in dir and returns its trimmed output, or "" when it
+// fails
+func gitOutput(dir string, args ...string) string {
+	out, err := gitRaw(dir, args...)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// gitRaw runs git in dir and returns its output as it is
+func gitRaw(dir string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
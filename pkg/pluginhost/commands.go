@@ -0,0 +1,206 @@
+package pluginhost
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// defaultCatalog defines the 19 SuperClaude commands
+//
+//go:embed commands.yaml
+var defaultCatalog []byte
+
+// CommandsConfig chooses the command catalog the host checks plugins against
+type CommandsConfig struct {
+	// Catalog is a YAML file of command definitions replacing the built-in
+	// SuperClaude commands
+	Catalog string `json:"catalog"`
+
+	// Strict fails startup when a command lacks a required capability;
+	// otherwise missing coverage is only logged
+	Strict bool `json:"strict"`
+}
+
+// CommandDef is a command and the plugin capabilities it needs
+type CommandDef struct {
+	Name        string `yaml:"name" json:"name"`
+	Category    string `yaml:"category" json:"category,omitempty"`
+	Description string `yaml:"description" json:"description,omitempty"`
+
+	// Requires lists capability references, such as "analyze" or
+	// "analyze@v2", that loaded plugins must offer for the command to run
+	Requires []string `yaml:"requires" json:"requires"`
+
+	// Optional lists capabilities the command uses when they are offered
+	Optional []string `yaml:"optional" json:"optional,omitempty"`
+
+	// Args maps the command's input to plugin arguments. Strings are
+	// templates over ArgsData, e.g. "{{ .Flags.target }}", rendered when
+	// the router resolves the command.
+	Args map[string]interface{} `yaml:"args" json:"args,omitempty"`
+}
+
+// CommandCoverage reports which plugins serve a command's capabilities
+type CommandCoverage struct {
+	Command  string `json:"command"`
+	Category string `json:"category,omitempty"`
+
+	// Covered is true when every required capability has a provider
+	Covered bool `json:"covered"`
+
+	// Providers maps each offered capability to the plugins offering it
+	Providers map[string][]string `json:"providers,omitempty"`
+
+	// Missing lists the required capabilities no plugin offers
+	Missing []string `json:"missing,omitempty"`
+
+	// MissingOptional lists the optional capabilities no plugin offers
+	MissingOptional []string `json:"missing_optional,omitempty"`
+}
+
+// LoadCommandCatalog reads command definitions from a YAML file with a
+// top-level "commands" list
+func LoadCommandCatalog(path string) ([]CommandDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read command catalog: %w", err)
+	}
+	commands, err := parseCommandCatalog(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command catalog %s: %w", path, err)
+	}
+	return commands, nil
+}
+
+// DefaultCommands returns the built-in SuperClaude command catalog
+func DefaultCommands() []CommandDef {
+	commands, err := parseCommandCatalog(defaultCatalog)
+	if err != nil {
+		panic(fmt.Sprintf("built-in command catalog: %v", err))
+	}
+	return commands
+}
+
+func parseCommandCatalog(data []byte) ([]CommandDef, error) {
+	var catalog struct {
+		Commands []CommandDef `yaml:"commands"`
+	}
+	if err := yaml.Unmarshal(data, &catalog); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(catalog.Commands))
+	for _, c := range catalog.Commands {
+		if c.Name == "" {
+			return nil, fmt.Errorf("command without a name")
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("command %s is defined twice", c.Name)
+		}
+		seen[c.Name] = true
+		if len(c.Requires) == 0 {
+			return nil, fmt.Errorf("command %s requires no capabilities", c.Name)
+		}
+		if err := parseArgTemplates(c); err != nil {
+			return nil, err
+		}
+	}
+	return catalog.Commands, nil
+}
+
+// loadCommands returns the catalog the config selects
+func (c *CommandsConfig) loadCommands() ([]CommandDef, error) {
+	if c.Catalog == "" {
+		return DefaultCommands(), nil
+	}
+	return LoadCommandCatalog(c.Catalog)
+}
+
+// Commands returns the command catalog in use
+func (pm *PluginManager) Commands() []CommandDef {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	return append([]CommandDef(nil), pm.commands...)
+}
+
+// CommandCoverage checks every command of the catalog against the
+// capabilities of the registered plugins, including lazy ones that have not
+// started yet. Plugins denied by policy do not count, nor do removed
+// capability versions.
+func (pm *PluginManager) CommandCoverage() []CommandCoverage {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	report := make([]CommandCoverage, 0, len(pm.commands))
+	for _, cmd := range pm.commands {
+		cov := CommandCoverage{Command: cmd.Name, Category: cmd.Category, Providers: make(map[string][]string)}
+		for _, ref := range cmd.Requires {
+			if providers := pm.capabilityProviders(ref); len(providers) > 0 {
+				cov.Providers[ref] = providers
+			} else {
+				cov.Missing = append(cov.Missing, ref)
+			}
+		}
+		for _, ref := range cmd.Optional {
+			if providers := pm.capabilityProviders(ref); len(providers) > 0 {
+				cov.Providers[ref] = providers
+			} else {
+				cov.MissingOptional = append(cov.MissingOptional, ref)
+			}
+		}
+		cov.Covered = len(cov.Missing) == 0
+		report = append(report, cov)
+	}
+	return report
+}
+
+// capabilityProviders returns the plugins offering a capability reference,
+// the one its hints make the best choice first. The caller holds pm.mu.
+func (pm *PluginManager) capabilityProviders(ref string) []string {
+	name, version := pluginsdk.ParseCapabilityRef(ref)
+
+	var providers []provider
+	for _, info := range pm.plugins {
+		if pm.config.isDenied(info.Name) || pm.disabled[info.Name] {
+			continue
+		}
+		if c := findCapability(info.Capabilities, name, version); c != nil && !c.Removed {
+			providers = append(providers, provider{plugin: info.Name, capability: *c})
+		}
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].before(providers[j]) })
+
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.plugin
+	}
+	return names
+}
+
+// checkCommands logs the commands the registered plugins cannot serve and
+// returns an error naming them
+func (pm *PluginManager) checkCommands() error {
+	var missing []string
+	covered := 0
+	for _, cov := range pm.CommandCoverage() {
+		if cov.Covered {
+			covered++
+			continue
+		}
+		missing = append(missing, fmt.Sprintf("%s (%s)", cov.Command, strings.Join(cov.Missing, ", ")))
+	}
+	if len(missing) == 0 {
+		pm.hostLog.Printf("All %d command(s) covered by plugins", covered)
+		return nil
+	}
+	pm.hostLog.Printf("%d of %d command(s) covered by plugins; missing capabilities for %s", covered, covered+len(missing), strings.Join(missing, "; "))
+	return fmt.Errorf("commands without plugin coverage: %s", strings.Join(missing, "; "))
+}
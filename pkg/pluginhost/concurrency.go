@@ -0,0 +1,133 @@
+package pluginhost
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// KeyStatus is a concurrency key a call holds
+type KeyStatus struct {
+	Key string `json:"key"`
+
+	// Plugin and RequestID are the call holding the key, since Since
+	Plugin    string    `json:"plugin"`
+	RequestID string    `json:"request_id,omitempty"`
+	Since     time.Time `json:"since"`
+
+	// Waiting counts the calls waiting for the key
+	Waiting int `json:"waiting"`
+}
+
+// keyLocks serializes calls by the concurrency key of their capability
+type keyLocks struct {
+	keys map[string]*keyLock
+	mu   sync.Mutex
+}
+
+// keyLock is a concurrency key in use
+type keyLock struct {
+	// slot holds a token while a call has the key
+	slot chan struct{}
+
+	// plugin and request are the call holding the key; depth counts it
+	// and the calls of its request nested in it
+	plugin  string
+	request string
+	since   time.Time
+	depth   int
+
+	waiting int
+}
+
+// concurrencyKey returns the concurrency key of the capability a call
+// names; empty when it has none. The caller holds pm.mu.
+func concurrencyKey(info *pluginInfo, args map[string]interface{}) string {
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	if capability == "" {
+		return ""
+	}
+	version, _ := args[pluginsdk.ArgCapabilityVersion].(string)
+	if c := findCapability(info.Capabilities, capability, version); c != nil {
+		return c.ConcurrencyKey
+	}
+	return ""
+}
+
+// acquire waits until the call of plugin in request has key, or ctx ends,
+// and returns the function giving the key back. Calls the holding call
+// makes through the host, which share its request, take the key right
+// away, as they would otherwise wait for their own caller.
+func (l *keyLocks) acquire(ctx context.Context, key, plugin, request string) (func(), error) {
+	l.mu.Lock()
+	if l.keys == nil {
+		l.keys = make(map[string]*keyLock)
+	}
+	k := l.keys[key]
+	if k == nil {
+		k = &keyLock{slot: make(chan struct{}, 1)}
+		l.keys[key] = k
+	}
+	if request != "" && k.depth > 0 && k.request == request {
+		k.depth++
+		l.mu.Unlock()
+		return func() { l.release(key, k) }, nil
+	}
+	k.waiting++
+	l.mu.Unlock()
+
+	select {
+	case k.slot <- struct{}{}:
+	case <-ctx.Done():
+		l.mu.Lock()
+		k.waiting--
+		l.forget(key, k)
+		l.mu.Unlock()
+		return nil, context.Cause(ctx)
+	}
+
+	l.mu.Lock()
+	k.waiting--
+	k.plugin, k.request, k.since, k.depth = plugin, request, time.Now(), 1
+	l.mu.Unlock()
+	return func() { l.release(key, k) }, nil
+}
+
+func (l *keyLocks) release(key string, k *keyLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if k.depth--; k.depth > 0 {
+		return
+	}
+	k.plugin, k.request = "", ""
+	<-k.slot
+	l.forget(key, k)
+}
+
+// forget drops a key no call holds or waits for. The caller holds l.mu.
+func (l *keyLocks) forget(key string, k *keyLock) {
+	if k.depth == 0 && k.waiting == 0 && len(k.slot) == 0 {
+		delete(l.keys, key)
+	}
+}
+
+// ConcurrencyKeys returns the concurrency keys calls hold, by key, with
+// the calls waiting for each
+func (pm *PluginManager) ConcurrencyKeys() []KeyStatus {
+	pm.keys.mu.Lock()
+	defer pm.keys.mu.Unlock()
+
+	out := make([]KeyStatus, 0, len(pm.keys.keys))
+	for key, k := range pm.keys.keys {
+		if k.depth == 0 {
+			continue
+		}
+		out = append(out, KeyStatus{Key: key, Plugin: k.plugin, RequestID: k.request, Since: k.since, Waiting: k.waiting})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
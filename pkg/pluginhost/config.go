@@ -0,0 +1,548 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HostConfig is the runtime configuration of the host. Every field can be
+// changed while the host is running; see ConfigWatcher.
+type HostConfig struct {
+	// PluginDirs are scanned for plugin binaries
+	PluginDirs []string `json:"plugin_dirs"`
+
+	// Limits throttle plugin execution
+	Limits LimitsConfig `json:"limits"`
+
+	// Policies restrict which plugins may run
+	Policies PolicyConfig `json:"policies"`
+
+	// Personas are the SuperClaude personas known to the host
+	Personas map[string]PersonaConfig `json:"personas"`
+
+	// Routing selects the rules that activate personas automatically
+	Routing RoutingConfig `json:"routing"`
+
+	// CrashDir receives a diagnostics bundle whenever a plugin crashes
+	CrashDir string `json:"crash_dir"`
+
+	// Loading controls when plugin processes are started
+	Loading LoadingConfig `json:"loading"`
+
+	// History persists execution records; disabled when Path is empty
+	History HistoryConfig `json:"history"`
+
+	// Trace records full calls for replay; disabled when Path is empty
+	Trace TraceConfig `json:"trace"`
+
+	// Remotes are other hosts whose plugins this host calls, by name
+	Remotes map[string]RemoteConfig `json:"remotes"`
+
+	// State keeps the plugin registry across restarts; disabled when Path
+	// is empty
+	State StateConfig `json:"state"`
+
+	// Retry controls automatic retries of failed calls
+	Retry RetryConfig `json:"retry"`
+
+	// Profile names the entry in Profiles that selects which plugins to
+	// load; empty loads every discovered plugin
+	Profile string `json:"profile"`
+
+	// Profiles are named plugin selections
+	Profiles map[string]ProfileConfig `json:"profiles"`
+
+	// Groups are named sets of plugin names that profiles can refer to
+	Groups map[string][]string `json:"groups"`
+
+	// TemplateDir holds the prompt templates plugins render through the host
+	TemplateDir string `json:"template_dir"`
+
+	// Sandbox confines plugin processes to their declared permissions
+	Sandbox SandboxConfig `json:"sandbox"`
+
+	// Health checks that running plugins still answer
+	Health HealthConfig `json:"health"`
+
+	// Resources samples the CPU and memory use of plugin processes
+	Resources ResourceConfig `json:"resources"`
+
+	// Events sets the delivery defaults of event subscriptions
+	Events EventsConfig `json:"events"`
+
+	// Lock pins plugin binaries to their checksums
+	Lock LockConfig `json:"lock"`
+
+	// Deprecations controls calls to deprecated capability versions
+	Deprecations DeprecationConfig `json:"deprecations"`
+
+	// CallEnv limits the environment variables calls pass to plugins
+	CallEnv CallEnvConfig `json:"call_env"`
+
+	// Secrets are the providers that resolve "secret://" references in
+	// PluginConfig
+	Secrets SecretsConfig `json:"secrets"`
+
+	// PluginConfig is passed to each plugin by name when it starts
+	PluginConfig map[string]map[string]interface{} `json:"plugin_config"`
+
+	// Commands is the catalog of commands plugins are checked against
+	Commands CommandsConfig `json:"commands"`
+
+	// Transport limits message sizes and spills large results to files
+	Transport TransportConfig `json:"transport"`
+
+	// Upgrade sets the smoke tests new plugin binaries must pass
+	Upgrade UpgradeConfig `json:"upgrade"`
+
+	// Workspace gives each plugin its own data, cache and temp directory
+	Workspace WorkspaceConfig `json:"workspace"`
+
+	// Transforms post-process call results
+	Transforms TransformConfig `json:"transforms"`
+
+	// Timeouts bound plugin calls
+	Timeouts TimeoutConfig `json:"timeouts"`
+
+	// Provision installs plugins from a registry index for capabilities
+	// no plugin offers; disabled when Index is empty
+	Provision ProvisionConfig `json:"provision"`
+
+	// Bundles sets the keys offline bundles may be signed with
+	Bundles BundleConfig `json:"bundles"`
+
+	// Pools run several processes of the named plugins and spread their
+	// calls over them
+	Pools map[string]PoolConfig `json:"pools"`
+
+	// Dependencies sets how long plugins wait for the external services
+	// their manifests list
+	Dependencies DependencyConfig `json:"dependencies"`
+
+	// Redaction keeps sensitive arguments out of the call log, history and
+	// traces
+	Redaction RedactionConfig `json:"redaction"`
+
+	// Locale sets the locale of calls that name none and translations of
+	// host errors
+	Locale LocaleConfig `json:"locale"`
+
+	// SLO sets latency and error rate objectives per capability and where
+	// violations are reported
+	SLO SLOConfig `json:"slo"`
+
+	// Gateway sets how clients of the gRPC capability gateway authenticate
+	Gateway GatewayConfig `json:"gateway"`
+
+	// API sets how clients of the HTTP management API authenticate
+	API APIConfig `json:"api"`
+
+	// Search adds tags to capabilities for FindCapabilities
+	Search SearchConfig `json:"search"`
+
+	// Pipelines are multi-step operations by name, rolled back as far as
+	// their steps can be undone when one fails
+	Pipelines map[string]Pipeline `json:"pipelines"`
+
+	// Authorization decides which users and services may execute which
+	// capabilities
+	Authorization AuthorizationConfig `json:"authorization"`
+
+	// FeatureFlags switch capabilities on and off, roll them out or
+	// launch them dark, without unloading their plugins
+	FeatureFlags FeatureFlagsConfig `json:"feature_flags"`
+
+	// LLM sets the model providers plugins reach through the host, with
+	// the keys, limits and budgets that apply
+	LLM LLMConfig `json:"llm"`
+
+	// Cancellation sets how long canceled calls get to stop before their
+	// plugin process is killed
+	Cancellation CancellationConfig `json:"cancellation"`
+
+	// Shutdown sets how long plugins get to drain before their processes
+	// are stopped
+	Shutdown ShutdownConfig `json:"shutdown"`
+
+	// Daemons sets how the servers of daemon plugins are restarted
+	Daemons DaemonConfig `json:"daemons"`
+
+	// SupplyChain sets the SBOM and provenance plugins need to be installed
+	// and started
+	SupplyChain SupplyChainConfig `json:"supply_chain"`
+
+	// Discovery adds sources of plugins besides PluginDirs, each with its
+	// own trust level
+	Discovery DiscoveryConfig `json:"discovery"`
+
+	// Artifacts sets the cache of downloaded plugin binaries shared by the
+	// hosts on the machine
+	Artifacts ArtifactCacheConfig `json:"artifacts"`
+
+	// Tasks sets how long background tasks may run and where they are
+	// kept
+	Tasks TaskConfig `json:"tasks"`
+
+	// Project sets how the project context of calls is computed and which
+	// plugins receive it
+	Project ProjectConfig `json:"project"`
+
+	// Conflicts sets what happens when two binaries report the same
+	// plugin name
+	Conflicts ConflictConfig `json:"conflicts"`
+
+	// Files sets the directories plugins may read and write through the
+	// host services
+	Files FilesConfig `json:"files"`
+}
+
+// RetryConfig chooses a retry policy per capability. Only capabilities their
+// plugin marks idempotent are ever retried, and only for errors the plugin
+// reports as retryable.
+type RetryConfig struct {
+	// Default applies to capabilities without their own policy
+	Default RetryPolicy `json:"default"`
+
+	// Capabilities maps capability names to their policy
+	Capabilities map[string]RetryPolicy `json:"capabilities"`
+}
+
+// RetryPolicy is exponential backoff with jitter
+type RetryPolicy struct {
+	// MaxAttempts counts the first call; zero or one disables retries
+	MaxAttempts int `json:"max_attempts"`
+
+	// Backoff is the delay before the first retry, doubled for each one after
+	Backoff Duration `json:"backoff"`
+
+	// MaxBackoff caps the delay; zero means no cap
+	MaxBackoff Duration `json:"max_backoff"`
+
+	// Jitter randomizes each delay by up to this fraction, from 0 to 1
+	Jitter float64 `json:"jitter"`
+}
+
+// HistoryConfig controls the persistent execution history
+type HistoryConfig struct {
+	// Path is the sqlite database file
+	Path string `json:"path"`
+
+	// MaxAge deletes records older than this; zero keeps them forever
+	MaxAge Duration `json:"max_age"`
+
+	// MaxRecords keeps at most this many of the newest records; zero means
+	// no limit
+	MaxRecords int `json:"max_records"`
+}
+
+// Loading modes
+const (
+	// LoadEager starts every discovered plugin immediately
+	LoadEager = "eager"
+	// LoadLazy registers plugins from their manifest and starts each one on
+	// its first call
+	LoadLazy = "lazy"
+)
+
+// LoadingConfig controls when plugin processes are started and stopped
+type LoadingConfig struct {
+	// Mode is LoadEager (the default) or LoadLazy
+	Mode string `json:"mode"`
+
+	// IdleShutdown stops lazily started plugins that have not been called
+	// for this long; zero keeps them running
+	IdleShutdown Duration `json:"idle_shutdown"`
+
+	// WarmUp names plugins started at discovery even in lazy mode; they are
+	// never stopped for idleness
+	WarmUp []string `json:"warm_up"`
+
+	// StartTimeout bounds each plugin start, from spawning the process to
+	// initializing it; defaults to DefaultStartTimeout
+	StartTimeout Duration `json:"start_timeout"`
+}
+
+// Duration is a time.Duration written as a string such as "5m" in config
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// LimitsConfig holds execution rate limits. A zero rate means unlimited.
+type LimitsConfig struct {
+	RateLimit        RateLimit            `json:"rate_limit"`
+	PluginRateLimits map[string]RateLimit `json:"plugin_rate_limits"`
+}
+
+// RateLimit is a token bucket: PerSecond calls refill the bucket, which
+// holds at most Burst calls
+type RateLimit struct {
+	PerSecond float64 `json:"per_second"`
+	Burst     int     `json:"burst"`
+}
+
+// PolicyConfig restricts plugin execution
+type PolicyConfig struct {
+	DeniedPlugins []string `json:"denied_plugins"`
+}
+
+// PersonaConfig describes a persona and the plugins it draws on
+type PersonaConfig struct {
+	Description string   `json:"description"`
+	Plugins     []string `json:"plugins"`
+
+	// Template names the persona's prompt template; defaults to
+	// personas/<name>
+	Template string `json:"template"`
+
+	// MCP lists the MCP servers the persona prefers, e.g. "sequential",
+	// replacing the built-in preference; an empty list turns it off
+	MCP []string `json:"mcp"`
+}
+
+// DefaultConfig returns the configuration used when no config file exists
+func DefaultConfig() *HostConfig {
+	return &HostConfig{
+		PluginDirs:  []string{"./plugins"},
+		CrashDir:    "./crashes",
+		TemplateDir: "./templates",
+	}
+}
+
+// LoadConfig reads and validates a config file written in YAML or JSON.
+// Problems with its content are reported as a *ConfigFileError listing
+// each with its line and column.
+func LoadConfig(path string) (*HostConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config: %w", err)
+	}
+	return parseConfig(path, data)
+}
+
+// Validate checks the config for values the host cannot apply
+func (c *HostConfig) Validate() error {
+	// An orchestrator may run no plugins of its own, only remote ones
+	if len(c.PluginDirs) == 0 && len(c.Remotes) == 0 && len(c.Discovery.Sources) == 0 {
+		return fmt.Errorf("plugin_dirs must not be empty without remotes or discovery sources")
+	}
+	switch c.Loading.Mode {
+	case "", LoadEager, LoadLazy:
+	default:
+		return fmt.Errorf("loading.mode must be %q or %q, got %q", LoadEager, LoadLazy, c.Loading.Mode)
+	}
+	if c.Loading.IdleShutdown < 0 {
+		return fmt.Errorf("loading.idle_shutdown must not be negative")
+	}
+	if c.Loading.StartTimeout < 0 {
+		return fmt.Errorf("loading.start_timeout must not be negative")
+	}
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
+	if err := c.Sandbox.validate(); err != nil {
+		return err
+	}
+	if err := c.Health.validate(); err != nil {
+		return err
+	}
+	if err := c.Resources.validate(); err != nil {
+		return err
+	}
+	if err := c.Events.validate(); err != nil {
+		return err
+	}
+	if err := c.Lock.validate(); err != nil {
+		return err
+	}
+	if err := c.Secrets.validate(); err != nil {
+		return err
+	}
+	if err := c.Transport.validate(); err != nil {
+		return err
+	}
+	if err := c.Upgrade.validate(); err != nil {
+		return err
+	}
+	if err := c.Workspace.validate(); err != nil {
+		return err
+	}
+	if err := c.Provision.validate(); err != nil {
+		return err
+	}
+	if err := c.Bundles.validate(); err != nil {
+		return err
+	}
+	if err := c.Dependencies.validate(); err != nil {
+		return err
+	}
+	if err := c.Redaction.validate(); err != nil {
+		return err
+	}
+	if err := c.Locale.validate(); err != nil {
+		return err
+	}
+	if err := c.SLO.validate(); err != nil {
+		return err
+	}
+	if err := c.Gateway.validate(); err != nil {
+		return err
+	}
+	if err := c.API.validate(); err != nil {
+		return err
+	}
+	if err := c.Search.validate(); err != nil {
+		return err
+	}
+	for name, p := range c.Pipelines {
+		if err := p.validate("pipelines." + name); err != nil {
+			return err
+		}
+	}
+	if err := c.Authorization.validate(); err != nil {
+		return err
+	}
+	if err := c.FeatureFlags.validate(); err != nil {
+		return err
+	}
+	if err := c.LLM.validate(); err != nil {
+		return err
+	}
+	if err := c.Cancellation.validate(); err != nil {
+		return err
+	}
+	if err := c.Shutdown.validate(); err != nil {
+		return err
+	}
+	if err := c.Daemons.validate(); err != nil {
+		return err
+	}
+	if err := c.SupplyChain.validate(); err != nil {
+		return err
+	}
+	if err := c.Discovery.validate(); err != nil {
+		return err
+	}
+	if err := c.Artifacts.validate(); err != nil {
+		return err
+	}
+	if err := c.Tasks.validate(); err != nil {
+		return err
+	}
+	if err := c.Project.validate(); err != nil {
+		return err
+	}
+	if err := c.Conflicts.validate(); err != nil {
+		return err
+	}
+	if err := c.Files.validate(); err != nil {
+		return err
+	}
+	if err := c.Timeouts.validate(); err != nil {
+		return err
+	}
+	if err := c.Transforms.validate(); err != nil {
+		return err
+	}
+	for name, r := range c.Remotes {
+		if err := r.validate(name); err != nil {
+			return err
+		}
+	}
+	for name, p := range c.Pools {
+		if err := p.validate(name); err != nil {
+			return err
+		}
+	}
+	for name, p := range c.Personas {
+		for _, server := range p.MCP {
+			if server == "" {
+				return fmt.Errorf("personas.%s.mcp lists an empty server name", name)
+			}
+		}
+	}
+	if err := c.Retry.Default.validate("retry.default"); err != nil {
+		return err
+	}
+	for name, p := range c.Retry.Capabilities {
+		if err := p.validate("retry.capabilities." + name); err != nil {
+			return err
+		}
+	}
+	if c.History.MaxAge < 0 {
+		return fmt.Errorf("history.max_age must not be negative")
+	}
+	if c.History.MaxRecords < 0 {
+		return fmt.Errorf("history.max_records must not be negative")
+	}
+	if err := c.Limits.RateLimit.validate("limits.rate_limit"); err != nil {
+		return err
+	}
+	for name, rl := range c.Limits.PluginRateLimits {
+		if err := rl.validate("limits.plugin_rate_limits." + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rl RateLimit) validate(field string) error {
+	if rl.PerSecond < 0 {
+		return fmt.Errorf("%s.per_second must not be negative", field)
+	}
+	if rl.PerSecond > 0 && rl.Burst < 1 {
+		return fmt.Errorf("%s.burst must be at least 1 when per_second is set", field)
+	}
+	return nil
+}
+
+func (p RetryPolicy) validate(field string) error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("%s.max_attempts must not be negative", field)
+	}
+	if p.Backoff < 0 || p.MaxBackoff < 0 {
+		return fmt.Errorf("%s backoff must not be negative", field)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("%s.jitter must be between 0 and 1", field)
+	}
+	return nil
+}
+
+// isDenied reports whether policy forbids executing the plugin
+func (c *HostConfig) isDenied(plugin string) bool {
+	for _, denied := range c.Policies.DeniedPlugins {
+		if denied == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// isWarm reports whether a plugin is listed for warm-up
+func (c *HostConfig) isWarm(plugin string) bool {
+	for _, name := range c.Loading.WarmUp {
+		if name == plugin {
+			return true
+		}
+	}
+	return false
+}
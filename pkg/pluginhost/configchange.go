@@ -0,0 +1,163 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// EventConfigChanged is published when a config applied at runtime differs
+// from the one before. Its data lists the changed top-level "sections",
+// the "personas_added", "personas_removed" and "personas_changed", and the
+// "plugins" whose plugin_config entry changed. Plugins subscribed to it
+// receive their own current entry as "config"; other subscribers never see
+// plugin_config values.
+const EventConfigChanged = "config.changed"
+
+// configChange describes how next differs from prev; nil when it does not.
+// Persona routing rules are compared as loaded, so edits to the rules file
+// count even when routing is unchanged.
+func configChange(prev, next *HostConfig, prevRules, nextRules []PersonaRule) map[string]interface{} {
+	sections := changedSections(prev, next)
+	if !reflect.DeepEqual(prevRules, nextRules) && !contains(sections, "routing") {
+		sections = append(sections, "routing")
+		sort.Strings(sections)
+	}
+	if len(sections) == 0 {
+		return nil
+	}
+
+	added, removed, changed := diffEntries(prev.Personas, next.Personas)
+	configured, unconfigured, plugins := diffEntries(prev.PluginConfig, next.PluginConfig)
+	plugins = append(append(plugins, configured...), unconfigured...)
+	sort.Strings(plugins)
+
+	return map[string]interface{}{
+		"sections":         toInterfaces(sections),
+		"personas_added":   toInterfaces(added),
+		"personas_removed": toInterfaces(removed),
+		"personas_changed": toInterfaces(changed),
+		"plugins":          toInterfaces(plugins),
+	}
+}
+
+// changedSections returns the top-level config fields, by their names in
+// the file, whose values differ
+func changedSections(prev, next *HostConfig) []string {
+	a, errA := sectionsOf(prev)
+	b, errB := sectionsOf(next)
+	if errA != nil || errB != nil {
+		return nil
+	}
+	var changed []string
+	for name, value := range b {
+		if string(a[name]) != string(value) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func sectionsOf(cfg *HostConfig) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var sections map[string]json.RawMessage
+	err = json.Unmarshal(data, &sections)
+	return sections, err
+}
+
+// diffEntries returns the keys only next has, those only prev has and
+// those whose values differ, each sorted
+func diffEntries[V any](prev, next map[string]V) (added, removed, changed []string) {
+	for name, value := range next {
+		old, ok := prev[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case !reflect.DeepEqual(old, value):
+			changed = append(changed, name)
+		}
+	}
+	for name := range prev {
+		if _, ok := next[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// toInterfaces converts a string list for event data, which must survive
+// the conversion to a protobuf struct; nil becomes an empty list
+func toInterfaces(list []string) []interface{} {
+	out := make([]interface{}, len(list))
+	for i, s := range list {
+		out[i] = s
+	}
+	return out
+}
+
+// forPlugin returns the EventConfigChanged event e as the plugin name
+// receives it: with its own current plugin_config entry, secrets resolved,
+// under "config" when the entry changed. Only the host reports config
+// changes, so events of that type a plugin sent lose any "config" it put
+// in them. Other events are returned as they are.
+func (pm *PluginManager) forPlugin(name string, e Event) Event {
+	if e.Type != EventConfigChanged {
+		return e
+	}
+	if e.Plugin != "" {
+		if _, ok := e.Data["config"]; ok {
+			data := make(map[string]interface{}, len(e.Data))
+			for k, v := range e.Data {
+				if k != "config" {
+					data[k] = v
+				}
+			}
+			e.Data = data
+		}
+		return e
+	}
+	plugins, _ := e.Data["plugins"].([]interface{})
+	mine := false
+	for _, p := range plugins {
+		if p == name {
+			mine = true
+			break
+		}
+	}
+	if !mine {
+		return e
+	}
+
+	data := make(map[string]interface{}, len(e.Data)+1)
+	for k, v := range e.Data {
+		data[k] = v
+	}
+	config := map[string]interface{}{}
+	if entry, ok := pm.Config().PluginConfig[name]; ok {
+		resolved, err := pm.resolveSecrets(entry)
+		if err != nil {
+			pm.hostLog.Printf("Failed to resolve the new config of plugin %s: %v", name, err)
+			return e
+		}
+		config = resolved.(map[string]interface{})
+	}
+	data["config"] = config
+	e.Data = data
+	return e
+}
@@ -0,0 +1,48 @@
+package pluginhost
+
+import (
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestForPluginDropsConfigOfPluginEvents(t *testing.T) {
+	pm, err := New(WithPluginDirs(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	spoofed := map[string]interface{}{"command": "rm -rf /"}
+	tests := []struct {
+		name       string
+		e          Event
+		wantConfig bool
+		wantChange bool
+	}{
+		{
+			name:       "from the host",
+			e:          Event{Type: EventConfigChanged, Data: map[string]interface{}{"plugins": []interface{}{"shell"}}},
+			wantConfig: true,
+			wantChange: true,
+		},
+		{
+			name: "from a plugin",
+			e:    Event{Type: EventConfigChanged, Plugin: "evil", Data: map[string]interface{}{"plugins": []interface{}{"shell"}, "config": spoofed}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := pm.forPlugin("shell", tt.e)
+			if _, ok := e.Data["config"]; ok != tt.wantConfig {
+				t.Errorf("config = %v, want config %v", e.Data["config"], tt.wantConfig)
+			}
+			if _, ok := pluginsdk.ConfigChangeOf(pluginsdk.Event(e)); ok != tt.wantChange {
+				t.Errorf("ConfigChangeOf ok = %v, want %v", ok, tt.wantChange)
+			}
+		})
+	}
+	if _, ok := tests[1].e.Data["config"]; !ok {
+		t.Error("forPlugin changed the data of the event it was given")
+	}
+}
@@ -0,0 +1,462 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigProblem is one thing wrong with a config file and where it is
+type ConfigProblem struct {
+	// Line and Column are 1-based; zero when the problem has no single
+	// place in the file
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+
+	// Field is the dotted path of the value, e.g. "loading.mode"
+	Field string `json:"field,omitempty"`
+
+	Message string `json:"message"`
+}
+
+func (p ConfigProblem) String() string {
+	switch {
+	case p.Line > 0 && p.Column > 0:
+		return fmt.Sprintf("%d:%d: %s", p.Line, p.Column, p.Message)
+	case p.Line > 0:
+		return fmt.Sprintf("%d: %s", p.Line, p.Message)
+	}
+	return p.Message
+}
+
+// ConfigFileError lists the problems found in a config file, in the order
+// they appear
+type ConfigFileError struct {
+	Path     string
+	Problems []ConfigProblem
+}
+
+func (e *ConfigFileError) Error() string {
+	if len(e.Problems) == 0 {
+		return "invalid config " + e.Path
+	}
+	msg := e.Path + ":" + e.Problems[0].String()
+	if len(e.Problems) > 1 {
+		msg += fmt.Sprintf(" (and %d more problem(s))", len(e.Problems)-1)
+	}
+	return msg
+}
+
+// parseConfig reads a config file written in YAML or JSON. The file is
+// checked against the fields of HostConfig first, so every unknown field
+// and wrongly typed value is reported with its line and column, then
+// decoded and validated.
+func parseConfig(path string, data []byte) (*HostConfig, error) {
+	fail := func(problems ...ConfigProblem) error {
+		return &ConfigFileError{Path: path, Problems: problems}
+	}
+
+	// JSON files get the JSON decoder's syntax errors, which are clearer
+	// for JSON than the YAML parser's
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var v interface{}
+		var syntax *json.SyntaxError
+		if err := json.Unmarshal(data, &v); errors.As(err, &syntax) {
+			line, col := lineColumn(data, syntax.Offset)
+			return nil, fail(ConfigProblem{Line: line, Column: col, Message: syntax.Error()})
+		}
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fail(yamlProblem(err))
+	}
+
+	cfg := DefaultConfig()
+	c := &configChecker{positions: make(map[string]ConfigProblem)}
+	if len(doc.Content) > 0 {
+		root := doc.Content[0]
+		c.check(root, reflect.TypeOf(*cfg), "")
+		if len(c.problems) > 0 {
+			sort.SliceStable(c.problems, func(i, j int) bool {
+				a, b := c.problems[i], c.problems[j]
+				return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+			})
+			return nil, fail(c.problems...)
+		}
+
+		// The checked tree is decoded through JSON, so the config types
+		// need no YAML support of their own
+		var v interface{}
+		if err := root.Decode(&v); err != nil {
+			return nil, fail(yamlProblem(err))
+		}
+		js, err := json.Marshal(v)
+		if err != nil {
+			return nil, fail(ConfigProblem{Message: err.Error()})
+		}
+		dec := json.NewDecoder(bytes.NewReader(js))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fail(ConfigProblem{Message: err.Error()})
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fail(c.locate(err))
+	}
+	return cfg, nil
+}
+
+// lineColumn turns a byte offset into a 1-based line and column
+func lineColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	return line, int(offset) - bytes.LastIndexByte(before, '\n') - 1
+}
+
+var yamlLine = regexp.MustCompile(`^yaml: line (\d+): `)
+
+// yamlProblem places a YAML syntax error on its line
+func yamlProblem(err error) ConfigProblem {
+	msg := err.Error()
+	if m := yamlLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return ConfigProblem{Line: line, Message: strings.TrimPrefix(msg, m[0])}
+	}
+	return ConfigProblem{Message: strings.TrimPrefix(msg, "yaml: ")}
+}
+
+var (
+	durationType    = reflect.TypeOf(Duration(0))
+	unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// configChecker checks a config tree against the config types
+type configChecker struct {
+	problems []ConfigProblem
+
+	// positions holds where each field checked is, by dotted path
+	positions map[string]ConfigProblem
+}
+
+func (c *configChecker) report(n *yaml.Node, field, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if field != "" {
+		msg = field + ": " + msg
+	}
+	c.problems = append(c.problems, ConfigProblem{Line: n.Line, Column: n.Column, Field: field, Message: msg})
+}
+
+// check reports where the value n, at the dotted path field, does not fit
+// the type t
+func (c *configChecker) check(n *yaml.Node, t reflect.Type, field string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if field != "" {
+		c.positions[field] = ConfigProblem{Line: n.Line, Column: n.Column}
+	}
+	// null leaves the default, as in JSON
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == durationType {
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!str" {
+			c.report(n, field, "must be a duration such as \"5m\", got %s", describeNode(n))
+		} else if _, err := time.ParseDuration(n.Value); err != nil {
+			c.report(n, field, "%v", err)
+		}
+		return
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		// Types decoding themselves accept what they like
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if !c.expect(n, yaml.MappingNode, field, "a mapping") {
+			return
+		}
+		fields := configFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				c.checkMerge(value, t, field)
+				continue
+			}
+			f, ok := findConfigField(fields, key.Value)
+			if !ok {
+				c.reportUnknown(key, field, fields)
+				continue
+			}
+			c.check(value, f.typ, joinField(field, f.name))
+		}
+
+	case reflect.Map:
+		if !c.expect(n, yaml.MappingNode, field, "a mapping") {
+			return
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Value == "<<" {
+				c.checkMerge(value, t, field)
+				continue
+			}
+			if key.Kind != yaml.ScalarNode || key.Tag != "!!str" {
+				c.report(key, field, "keys must be strings, got %s", describeNode(key))
+				continue
+			}
+			c.check(value, t.Elem(), joinField(field, key.Value))
+		}
+
+	case reflect.Slice, reflect.Array:
+		if !c.expect(n, yaml.SequenceNode, field, "a list") {
+			return
+		}
+		for i, item := range n.Content {
+			c.check(item, t.Elem(), fmt.Sprintf("%s[%d]", field, i))
+		}
+
+	case reflect.Interface:
+
+	case reflect.String:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!str" {
+			c.report(n, field, "must be a string, got %s; quote it", describeNode(n))
+		}
+
+	case reflect.Bool:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!bool" {
+			c.report(n, field, "must be true or false, got %s", describeNode(n))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!int" {
+			c.report(n, field, "must be an integer, got %s", describeNode(n))
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n.Kind != yaml.ScalarNode || n.Tag != "!!int" || strings.HasPrefix(n.Value, "-") {
+			c.report(n, field, "must be a non-negative integer, got %s", describeNode(n))
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if n.Kind != yaml.ScalarNode || (n.Tag != "!!int" && n.Tag != "!!float") {
+			c.report(n, field, "must be a number, got %s", describeNode(n))
+		}
+	}
+}
+
+// checkMerge checks the mappings a YAML merge key brings into a mapping
+func (c *configChecker) checkMerge(n *yaml.Node, t reflect.Type, field string) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Kind == yaml.SequenceNode {
+		for _, item := range n.Content {
+			c.check(item, t, field)
+		}
+		return
+	}
+	c.check(n, t, field)
+}
+
+// expect reports n unless it is of kind
+func (c *configChecker) expect(n *yaml.Node, kind yaml.Kind, field, want string) bool {
+	if n.Kind == kind {
+		return true
+	}
+	c.report(n, field, "must be %s, got %s", want, describeNode(n))
+	return false
+}
+
+// reportUnknown reports a field the config type does not have, suggesting
+// the closest one it does
+func (c *configChecker) reportUnknown(key *yaml.Node, parent string, fields []configField) {
+	msg := fmt.Sprintf("unknown field %q", key.Value)
+	if parent != "" {
+		msg += " in " + parent
+	}
+	best, bestDist := "", 3
+	for _, f := range fields {
+		if d := editDistance(key.Value, f.name); d < bestDist {
+			best, bestDist = f.name, d
+		}
+	}
+	if best != "" {
+		msg += fmt.Sprintf("; did you mean %q?", best)
+	}
+	c.problems = append(c.problems, ConfigProblem{Line: key.Line, Column: key.Column, Field: joinField(parent, key.Value), Message: msg})
+}
+
+// locate places a Validate error on the field its message starts with,
+// the longest dotted prefix of it that the file sets
+func (c *configChecker) locate(err error) ConfigProblem {
+	msg := err.Error()
+	field, _, _ := strings.Cut(msg, " ")
+	field = strings.TrimRight(field, ":,")
+	for field != "" {
+		if at, ok := c.positions[field]; ok {
+			at.Field, at.Message = field, msg
+			return at
+		}
+		i := strings.LastIndexAny(field, ".[")
+		if i < 0 {
+			break
+		}
+		field = field[:i]
+	}
+	return ConfigProblem{Message: msg}
+}
+
+// describeNode says what a value is, for error messages
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch n.Tag {
+	case "!!str":
+		return fmt.Sprintf("the string %q", n.Value)
+	case "!!int", "!!float":
+		return "the number " + n.Value
+	case "!!bool":
+		return n.Value
+	case "!!null":
+		return "null"
+	}
+	return n.Value
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// configField is a field of a config type as it is written in the file
+type configField struct {
+	name string
+	typ  reflect.Type
+}
+
+// configFields returns the fields of the struct type t by their JSON names,
+// including those of embedded structs
+func configFields(t reflect.Type) []configField {
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, configFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, configField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+// findConfigField finds a field by name the way encoding/json does,
+// preferring an exact match over one ignoring case
+func findConfigField(fields []configField, name string) (configField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, name) {
+			return f, true
+		}
+	}
+	return configField{}, false
+}
+
+// ConfigSchema returns a JSON Schema of the host config file, derived from
+// HostConfig, for editors and CI checks. YAML files follow the same schema.
+func ConfigSchema() map[string]interface{} {
+	s := schemaOf(reflect.TypeOf(HostConfig{}), map[reflect.Type]bool{})
+	s["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	s["title"] = "Plugin host config"
+	return s
+}
+
+// schemaOf returns the schema of values of type t; seen holds the structs
+// being described, so recursive types end in an open schema
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return map[string]interface{}{"type": "string", "description": "a duration such as \"5m\""}
+	}
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		props := make(map[string]interface{})
+		for _, f := range configFields(t) {
+			props[f.name] = schemaOf(f.typ, seen)
+		}
+		return map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaOf(t.Elem(), seen)}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaOf(t.Elem(), seen)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,212 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Name conflict policies, for binaries reporting the name of a plugin that
+// is registered already
+const (
+	// ConflictError refuses the later binary; the plugin registered first
+	// keeps the name
+	ConflictError = "error"
+
+	// ConflictSuffixVersion registers the later binary under its name
+	// suffixed with its version, e.g. "hello@1.1.0"
+	ConflictSuffixVersion = "suffix_version"
+
+	// ConflictKeepHighest keeps whichever binary has the higher version,
+	// unloading the registered plugin when the later one is newer
+	ConflictKeepHighest = "keep_highest"
+)
+
+// Conflict resolutions
+const (
+	ConflictRejected = "rejected"
+	ConflictSuffixed = "suffixed"
+	ConflictReplaced = "replaced"
+)
+
+// EventPluginConflict is published when a binary reports the name of a
+// registered plugin
+const EventPluginConflict = "plugin.conflict"
+
+// ErrNameConflict means a binary reports the name of a registered plugin
+// and the conflict policy refused it
+var ErrNameConflict = errors.New("plugin name conflict")
+
+// ConflictConfig sets how name conflicts between plugin binaries are
+// resolved
+type ConflictConfig struct {
+	// Policy is ConflictError, ConflictSuffixVersion or
+	// ConflictKeepHighest; defaults to ConflictError
+	Policy string `json:"policy"`
+}
+
+func (c *ConflictConfig) validate() error {
+	switch c.Policy {
+	case "", ConflictError, ConflictSuffixVersion, ConflictKeepHighest:
+		return nil
+	}
+	return fmt.Errorf("conflicts.policy must be %q, %q or %q, got %q", ConflictError, ConflictSuffixVersion, ConflictKeepHighest, c.Policy)
+}
+
+func (c ConflictConfig) policy() string {
+	if c.Policy == "" {
+		return ConflictError
+	}
+	return c.Policy
+}
+
+// NameConflict is a plugin binary that reported the name of a plugin
+// registered from another binary, and what the policy made of it
+type NameConflict struct {
+	// Name is the name both binaries report
+	Name string `json:"name"`
+
+	// Path and Version are the binary that came later
+	Path    string `json:"path"`
+	Version string `json:"version"`
+
+	// Existing and ExistingVersion are the binary registered before
+	Existing        string `json:"existing"`
+	ExistingVersion string `json:"existing_version"`
+
+	// Policy is the policy applied and Resolution ConflictRejected,
+	// ConflictSuffixed or ConflictReplaced
+	Policy     string `json:"policy"`
+	Resolution string `json:"resolution"`
+
+	// RegisteredAs is the name the later binary was registered under,
+	// unless it was rejected
+	RegisteredAs string `json:"registered_as,omitempty"`
+
+	// At is when the conflict was last seen
+	At time.Time `json:"at"`
+}
+
+// conflictLog holds the conflicts seen, by the path of the later binary
+type conflictLog struct {
+	entries map[string]NameConflict
+	mu      sync.Mutex
+}
+
+// Conflicts returns the name conflicts seen while discovering and loading
+// plugins whose binaries still exist, by name and path. A binary that
+// loads without a conflict later is dropped from the list.
+func (pm *PluginManager) Conflicts() []NameConflict {
+	pm.conflicts.mu.Lock()
+	defer pm.conflicts.mu.Unlock()
+
+	out := make([]NameConflict, 0, len(pm.conflicts.entries))
+	for path, c := range pm.conflicts.entries {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			delete(pm.conflicts.entries, path)
+			continue
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// resolveName applies the conflict policy to the binary at path reporting
+// name and version, and returns the name to register it under. Under
+// ConflictKeepHighest a newer binary has the registered plugin unloaded
+// first; refused binaries get an error matching ErrNameConflict. Invalid
+// and reserved names are refused whatever the policy.
+func (pm *PluginManager) resolveName(path, name, version string) (string, error) {
+	if err := checkPluginName(name); err != nil {
+		return "", err
+	}
+	pm.mu.RLock()
+	existing, exists := pm.plugins[name]
+	var c NameConflict
+	if exists {
+		c = NameConflict{Existing: existing.Path, ExistingVersion: existing.Version}
+	}
+	builtin := exists && existing.builtin
+	policy := pm.config.Conflicts.policy()
+	pm.mu.RUnlock()
+	if !exists || filepath.Clean(c.Existing) == filepath.Clean(path) {
+		pm.conflicts.mu.Lock()
+		delete(pm.conflicts.entries, path)
+		pm.conflicts.mu.Unlock()
+		return name, nil
+	}
+
+	c.Name, c.Path, c.Version = name, path, version
+	c.Policy, c.Resolution, c.At = policy, ConflictRejected, time.Now()
+	switch policy {
+	case ConflictSuffixVersion:
+		suffixed := name + "@" + version
+		pm.mu.RLock()
+		other, taken := pm.plugins[suffixed]
+		taken = taken && filepath.Clean(other.Path) != filepath.Clean(path)
+		pm.mu.RUnlock()
+		if version != "" && !taken {
+			c.Resolution, c.RegisteredAs = ConflictSuffixed, suffixed
+		}
+	case ConflictKeepHighest:
+		if !builtin && compareVersions(version, c.ExistingVersion) > 0 {
+			if err := pm.UnloadPlugin(name); err != nil && !errors.Is(err, ErrPluginNotFound) {
+				return "", fmt.Errorf("failed to unload plugin %s v%s for v%s: %w", name, c.ExistingVersion, version, err)
+			}
+			c.Resolution, c.RegisteredAs = ConflictReplaced, name
+		}
+	}
+
+	pm.conflicts.mu.Lock()
+	if pm.conflicts.entries == nil {
+		pm.conflicts.entries = make(map[string]NameConflict)
+	}
+	pm.conflicts.entries[path] = c
+	pm.conflicts.mu.Unlock()
+
+	pm.hostLog.Printf("Plugin name conflict: %s v%s at %s and v%s at %s (%s: %s)", name, c.ExistingVersion, c.Existing, version, path, policy, c.Resolution)
+	pm.events.Publish(Event{Type: EventPluginConflict, Plugin: name, Data: map[string]interface{}{
+		"path":          path,
+		"version":       version,
+		"existing":      c.Existing,
+		"policy":        policy,
+		"resolution":    c.Resolution,
+		"registered_as": c.RegisteredAs,
+	}})
+	if c.Resolution == ConflictRejected {
+		return "", fmt.Errorf("%w: %s v%s at %s is registered from %s (v%s) already; conflicts.policy is %s",
+			ErrNameConflict, name, version, path, c.Existing, c.ExistingVersion, policy)
+	}
+	return c.RegisteredAs, nil
+}
+
+// reports returns the name the plugin's binary reports, which differs from
+// its name when a conflict had it registered under a suffixed one
+func (info *pluginInfo) reports() string {
+	if info.reported != "" {
+		return info.reported
+	}
+	return info.Name
+}
+
+// reportedBy returns the name the binary of the plugin registered as name
+// reports
+func (pm *PluginManager) reportedBy(name string) string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if info, ok := pm.plugins[name]; ok {
+		return info.reports()
+	}
+	return name
+}
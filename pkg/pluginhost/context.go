@@ -0,0 +1,220 @@
+package pluginhost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// sharedRequest is the live context of one request and the plugins taking
+// part in it
+type sharedRequest struct {
+	ctx     pluginsdk.RequestContext
+	plugins map[string]bool
+	refs    int
+}
+
+// requestRegistry holds the contexts of the requests being served. A
+// context lives while its top-level call runs.
+type requestRegistry struct {
+	active map[string]*sharedRequest
+	mu     sync.Mutex
+}
+
+// begin registers the context of a new request, or joins the running one
+// with the same ID, and reports whether it started the request. Only calls
+// for the principal of a running request may join it, so a request ID
+// cannot be used to act as someone else.
+func (r *requestRegistry) begin(rc pluginsdk.RequestContext, plugin string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.active == nil {
+		r.active = make(map[string]*sharedRequest)
+	}
+	req, ok := r.active[rc.RequestID]
+	if ok && (req.ctx.User != rc.User || req.ctx.Service != rc.Service) {
+		return false, pluginsdk.NewError(pluginsdk.CodePermissionDenied, "request %s is running for someone else", rc.RequestID)
+	}
+	if !ok {
+		rc.Values = copyValues(rc.Values)
+		req = &sharedRequest{ctx: rc, plugins: make(map[string]bool)}
+		r.active[rc.RequestID] = req
+	}
+	req.plugins[plugin] = true
+	req.refs++
+	return !ok, nil
+}
+
+// end releases a context registered by begin
+func (r *requestRegistry) end(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if req, ok := r.active[id]; ok {
+		if req.refs--; req.refs == 0 {
+			delete(r.active, id)
+		}
+	}
+}
+
+// join adds plugin to a running request and returns the context to pass
+// it; ok is false when no request with the ID is running
+func (r *requestRegistry) join(id, plugin string) (map[string]interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, ok := r.active[id]
+	if !ok {
+		return nil, false
+	}
+	req.plugins[plugin] = true
+	return req.ctx.Map(), true
+}
+
+// context returns a copy of the context of a running request, whoever
+// takes part in it
+func (r *requestRegistry) context(id string) (pluginsdk.RequestContext, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, ok := r.active[id]
+	if !ok {
+		return pluginsdk.RequestContext{}, false
+	}
+	rc := req.ctx
+	rc.Values = copyValues(rc.Values)
+	return rc, true
+}
+
+// lookup returns the request with the ID for a plugin taking part in it
+func (r *requestRegistry) lookup(id, plugin string) (*sharedRequest, error) {
+	req, ok := r.active[id]
+	if !ok {
+		return nil, pluginsdk.NewError(pluginsdk.CodeNotFound, "no request %q is running", id)
+	}
+	if !req.plugins[plugin] {
+		return nil, pluginsdk.NewError(pluginsdk.CodePermissionDenied, "plugin %s is not serving request %s", plugin, id)
+	}
+	return req, nil
+}
+
+// get returns a copy of the context of a request plugin takes part in
+func (r *requestRegistry) get(id, plugin string) (pluginsdk.RequestContext, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, err := r.lookup(id, plugin)
+	if err != nil {
+		return pluginsdk.RequestContext{}, err
+	}
+	rc := req.ctx
+	rc.Values = copyValues(rc.Values)
+	return rc, nil
+}
+
+// set stores a value in the context of a request plugin takes part in
+func (r *requestRegistry) set(id, plugin, key string, value interface{}) error {
+	if key == "" {
+		return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "context value needs a key")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	req, err := r.lookup(id, plugin)
+	if err != nil {
+		return err
+	}
+	if req.ctx.Values == nil {
+		req.ctx.Values = make(map[string]interface{})
+	}
+	req.ctx.Values[key] = value
+	return nil
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		out[k] = v
+	}
+	return out
+}
+
+// ExecuteWithContext runs a call as the start of a request sharing rc with
+// every plugin in the chain. The plugin receives rc in the reserved context
+// argument; the plugins it calls through HostServices.CallPlugin with
+// pluginsdk.WithContext receive it too, with the values set so far, and
+// each of them can read and add values with HostServices.GetContext and
+// SetContext until this call returns. An empty RequestID gets a random
+// one; a RequestID already running joins that request, which is refused
+// unless rc names the same user or service.
+func (pm *PluginManager) ExecuteWithContext(name string, args map[string]interface{}, rc pluginsdk.RequestContext) (string, error) {
+	res, err := pm.executeWithContext(context.Background(), name, withoutReserved(args), rc)
+	return res.Result, err
+}
+
+// ExecuteContext runs a call like Execute with rc, for clients that can go
+// away mid-call. When ctx is canceled before the call returns, the request
+// is canceled as by CancelRequest, but only if this call started it; calls
+// joining a running request leave that to the call that started it.
+func (pm *PluginManager) ExecuteContext(ctx context.Context, name string, args map[string]interface{}, rc pluginsdk.RequestContext) (ExecuteResult, error) {
+	return pm.executeWithContext(ctx, name, withoutReserved(args), rc)
+}
+
+// executeWithContext runs a call the way ExecuteContext does, saying
+// whether the result is partial
+func (pm *PluginManager) executeWithContext(ctx context.Context, name string, args map[string]interface{}, rc pluginsdk.RequestContext) (ExecuteResult, error) {
+	if rc.RequestID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return ExecuteResult{}, fmt.Errorf("failed to create request ID: %w", err)
+		}
+		rc.RequestID = id
+	}
+	if rc.ProjectRoot != "" {
+		root, err := filepath.Abs(rc.ProjectRoot)
+		if err != nil {
+			return ExecuteResult{}, &pluginsdk.PluginError{Code: pluginsdk.CodeInvalidArgument, Message: fmt.Sprintf("invalid project root: %v", err)}
+		}
+		rc.ProjectRoot = root
+	}
+
+	id := rc.RequestID
+	started, err := pm.requests.begin(rc, name)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	defer pm.requests.end(id)
+	if started {
+		stop := context.AfterFunc(ctx, func() {
+			if errors.Is(ctx.Err(), context.Canceled) {
+				pm.CancelRequest(id, "client went away")
+			}
+		})
+		defer stop()
+	}
+
+	callArgs := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	callArgs[pluginsdk.ArgContext], _ = pm.requests.join(id, name)
+	return pm.executeConverted(name, callArgs)
+}
+
+func (h *pluginHost) GetContext(requestID string) (pluginsdk.RequestContext, error) {
+	caller, _ := h.pm.callerOf(h.path)
+	return h.pm.requests.get(requestID, caller)
+}
+
+func (h *pluginHost) SetContext(requestID, key string, value interface{}) error {
+	caller, _ := h.pm.callerOf(h.path)
+	return h.pm.requests.set(requestID, caller, key, value)
+}
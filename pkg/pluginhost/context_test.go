@@ -0,0 +1,105 @@
+package pluginhost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestRequestRegistryBegin(t *testing.T) {
+	var r requestRegistry
+	ada := pluginsdk.RequestContext{RequestID: "r1", User: "ada"}
+	if started, err := r.begin(ada, "hello"); err != nil || !started {
+		t.Fatalf("begin = %v, %v; want a new request", started, err)
+	}
+
+	tests := []struct {
+		name    string
+		rc      pluginsdk.RequestContext
+		wantErr bool
+	}{
+		{"same user", ada, false},
+		{"other user", pluginsdk.RequestContext{RequestID: "r1", User: "mallory"}, true},
+		{"service of the same name", pluginsdk.RequestContext{RequestID: "r1", User: "ada", Service: true}, true},
+		{"anonymous", pluginsdk.RequestContext{RequestID: "r1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, err := r.begin(tt.rc, "hello")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("begin error = %v, want error %v", err, tt.wantErr)
+			}
+			if started {
+				t.Error("joining a running request started it")
+			}
+			if err == nil {
+				r.end(tt.rc.RequestID)
+			}
+		})
+	}
+	if rc, _ := r.context("r1"); rc.User != "ada" {
+		t.Errorf("request is now for %q, want ada", rc.User)
+	}
+}
+
+// blockingPlugin is a built-in plugin whose calls wait for release
+type blockingPlugin struct {
+	running chan struct{}
+	release chan struct{}
+}
+
+func (p *blockingPlugin) Name() string                            { return "blocking" }
+func (p *blockingPlugin) Version() string                         { return "1.0.0" }
+func (p *blockingPlugin) GetCapabilities() []pluginsdk.Capability { return nil }
+func (p *blockingPlugin) Execute(args map[string]interface{}) (string, error) {
+	p.running <- struct{}{}
+	<-p.release
+	return "done", nil
+}
+
+func TestExecuteContextCancelsOnlyStartedRequests(t *testing.T) {
+	p := &blockingPlugin{running: make(chan struct{}, 2), release: make(chan struct{})}
+	pm, err := New(WithBuiltin(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	ada := pluginsdk.RequestContext{RequestID: "r1", User: "ada"}
+	done := make(chan error, 1)
+	go func() {
+		_, err := pm.ExecuteContext(context.Background(), "blocking", map[string]interface{}{}, ada)
+		done <- err
+	}()
+	<-p.running
+
+	// Another principal is refused outright
+	_, err = pm.ExecuteContext(context.Background(), "blocking", map[string]interface{}{}, pluginsdk.RequestContext{RequestID: "r1", User: "mallory"})
+	if pluginsdk.AsPluginError(err).Code != pluginsdk.CodePermissionDenied {
+		t.Fatalf("joining another user's request: error = %v, want %s", err, pluginsdk.CodePermissionDenied)
+	}
+
+	// A call joining the request and going away leaves it running
+	ctx, cancel := context.WithCancel(context.Background())
+	joined := make(chan error, 1)
+	go func() {
+		_, err := pm.ExecuteContext(ctx, "blocking", map[string]interface{}{}, ada)
+		joined <- err
+	}()
+	<-p.running
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("request ended when a joining call went away: %v", err)
+	default:
+	}
+
+	close(p.release)
+	if err := <-done; err != nil {
+		t.Errorf("request failed: %v", err)
+	}
+	<-joined
+}
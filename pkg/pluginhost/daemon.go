@@ -0,0 +1,507 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Events published about the servers of daemon plugins
+const (
+	EventDaemonFailed    = "daemon.failed"
+	EventDaemonRestarted = "daemon.restarted"
+)
+
+// Restart policies of DaemonConfig
+const (
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+	RestartNever     = "never"
+)
+
+// Defaults of DaemonConfig
+const (
+	DefaultDaemonCheckInterval = 10 * time.Second
+	DefaultDaemonStartTimeout  = 30 * time.Second
+	DefaultDaemonBackoff       = time.Second
+	DefaultDaemonMaxRestarts   = 5
+)
+
+const (
+	// maxDaemonBackoff caps the doubling delay between restarts
+	maxDaemonBackoff = 5 * time.Minute
+
+	// daemonStableAfter is how long a server must run for its restarts to
+	// count from zero again
+	daemonStableAfter = time.Minute
+)
+
+// DaemonConfig sets how the host manages the servers of daemon plugins,
+// those implementing pluginsdk.DaemonPlugin. The host starts a daemon's
+// server once its process is up, asks for its status every
+// check_interval, restarts it by its restart policy and stops it before
+// stopping the process.
+type DaemonConfig struct {
+	// Restart is the restart policy of daemons Plugins does not list:
+	// "on-failure", the default, restarts servers that failed and
+	// processes that crashed, "always" also servers that stopped on their
+	// own, "never" leaves them
+	Restart string `json:"restart"`
+
+	// MaxRestarts gives up on a daemon after this many restarts in a row,
+	// each without a minute of running in between; defaults to
+	// DefaultDaemonMaxRestarts, -1 never gives up
+	MaxRestarts int `json:"max_restarts"`
+
+	// Backoff is the delay before the first restart, doubling for each
+	// further one up to five minutes; defaults to DefaultDaemonBackoff
+	Backoff Duration `json:"backoff"`
+
+	// CheckInterval is how often the host asks each daemon for its
+	// status; defaults to DefaultDaemonCheckInterval
+	CheckInterval Duration `json:"check_interval"`
+
+	// StartTimeout bounds starting and stopping a server; defaults to
+	// DefaultDaemonStartTimeout
+	StartTimeout Duration `json:"start_timeout"`
+
+	// Plugins overrides the restart policy of single daemons by name
+	Plugins map[string]DaemonPolicy `json:"plugins"`
+}
+
+// DaemonPolicy is the restart policy of one daemon plugin; unset fields
+// take the values of DaemonConfig
+type DaemonPolicy struct {
+	Restart     string `json:"restart"`
+	MaxRestarts int    `json:"max_restarts"`
+}
+
+func (c *DaemonConfig) validate() error {
+	if err := validRestart("daemons.restart", c.Restart); err != nil {
+		return err
+	}
+	if c.MaxRestarts < -1 {
+		return fmt.Errorf("daemons.max_restarts must be -1 or more")
+	}
+	if c.Backoff < 0 || c.CheckInterval < 0 || c.StartTimeout < 0 {
+		return fmt.Errorf("daemons.backoff, daemons.check_interval and daemons.start_timeout must not be negative")
+	}
+	for name, p := range c.Plugins {
+		if err := validRestart(fmt.Sprintf("daemons.plugins.%s.restart", name), p.Restart); err != nil {
+			return err
+		}
+		if p.MaxRestarts < -1 {
+			return fmt.Errorf("daemons.plugins.%s.max_restarts must be -1 or more", name)
+		}
+	}
+	return nil
+}
+
+func validRestart(field, policy string) error {
+	switch policy {
+	case "", RestartOnFailure, RestartAlways, RestartNever:
+		return nil
+	}
+	return fmt.Errorf("%s must be %q, %q or %q, got %q", field, RestartOnFailure, RestartAlways, RestartNever, policy)
+}
+
+// policy returns the restart policy and restart limit of the daemon name
+// with the defaults applied; a negative limit means none
+func (c *DaemonConfig) policy(name string) (string, int) {
+	restart, max := c.Restart, c.MaxRestarts
+	if p, ok := c.Plugins[name]; ok {
+		if p.Restart != "" {
+			restart = p.Restart
+		}
+		if p.MaxRestarts != 0 {
+			max = p.MaxRestarts
+		}
+	}
+	if restart == "" {
+		restart = RestartOnFailure
+	}
+	if max == 0 {
+		max = DefaultDaemonMaxRestarts
+	}
+	return restart, max
+}
+
+func (c *DaemonConfig) checkInterval() time.Duration {
+	if c.CheckInterval > 0 {
+		return time.Duration(c.CheckInterval)
+	}
+	return DefaultDaemonCheckInterval
+}
+
+func (c *DaemonConfig) startTimeout() time.Duration {
+	if c.StartTimeout > 0 {
+		return time.Duration(c.StartTimeout)
+	}
+	return DefaultDaemonStartTimeout
+}
+
+// backoff returns the delay before the restart following restarts others
+func (c *DaemonConfig) backoff(restarts int) time.Duration {
+	delay := DefaultDaemonBackoff
+	if c.Backoff > 0 {
+		delay = time.Duration(c.Backoff)
+	}
+	for i := 0; i < restarts && delay < maxDaemonBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDaemonBackoff)
+}
+
+// DaemonInfo is the state of a daemon plugin's server as the host last
+// saw it
+type DaemonInfo struct {
+	pluginsdk.DaemonStatus
+
+	// Restart is the restart policy that applies
+	Restart string `json:"restart"`
+
+	// Restarts counts the restarts since the server last ran for a minute
+	Restarts int `json:"restarts"`
+
+	// Held is set while an operator has the server stopped; it is not
+	// restarted until started again
+	Held bool `json:"held,omitempty"`
+
+	// GaveUp is set once the restarts ran out; the server is not
+	// restarted until started again
+	GaveUp bool `json:"gave_up,omitempty"`
+
+	// CheckedAt is when the host last learned the status
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// daemonState is the host's side of the server of one daemon plugin
+// process. Its supervisor ends with the process.
+type daemonState struct {
+	plugin     string
+	controller pluginsdk.DaemonController
+	generation int
+
+	// done is closed when the process is being stopped
+	done     chan struct{}
+	doneOnce sync.Once
+
+	mu      sync.Mutex
+	info    DaemonInfo
+	max     int
+	started time.Time
+}
+
+func (d *daemonState) snapshot() DaemonInfo {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.info
+}
+
+// record stores the status the server reported
+func (d *daemonState) record(st pluginsdk.DaemonStatus) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.info.DaemonStatus = st
+	d.info.CheckedAt = time.Now()
+	if st.State == pluginsdk.DaemonRunning && d.info.Restarts > 0 && time.Since(d.started) >= daemonStableAfter {
+		d.info.Restarts = 0
+	}
+}
+
+// end stops the supervisor of the daemon
+func (d *daemonState) end() {
+	d.doneOnce.Do(func() { close(d.done) })
+}
+
+// localDaemon manages the server of a plugin running in the host process
+type localDaemon struct {
+	impl pluginsdk.DaemonPlugin
+}
+
+func (l localDaemon) run(timeout time.Duration, op func(context.Context) error) (pluginsdk.DaemonStatus, error) {
+	var err error
+	if op != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		err = op(ctx)
+	}
+	st := l.impl.Status()
+	if st.Endpoint == "" {
+		st.Endpoint = l.impl.Endpoint()
+	}
+	return st, err
+}
+
+func (l localDaemon) StartDaemon(timeout time.Duration) (pluginsdk.DaemonStatus, error) {
+	return l.run(timeout, l.impl.Start)
+}
+
+func (l localDaemon) StopDaemon(timeout time.Duration) (pluginsdk.DaemonStatus, error) {
+	return l.run(timeout, l.impl.Stop)
+}
+
+func (l localDaemon) DaemonStatus(timeout time.Duration) (pluginsdk.DaemonStatus, error) {
+	return l.run(timeout, nil)
+}
+
+// daemonController returns what manages the server of instance, through
+// its client or directly for builtin plugins; nil when it has none
+func daemonController(instance pluginsdk.CommandPlugin) pluginsdk.DaemonController {
+	switch d := instance.(type) {
+	case pluginsdk.DaemonController:
+		return d
+	case pluginsdk.DaemonPlugin:
+		return localDaemon{d}
+	}
+	return nil
+}
+
+// superviseDaemon finds out whether a plugin that finished loading is a
+// daemon and, if it is, starts its server and supervises it in the
+// background
+func (pm *PluginManager) superviseDaemon(info *pluginInfo) {
+	pm.mu.RLock()
+	name, instance, generation := info.Name, info.Instance, info.generation
+	pm.mu.RUnlock()
+
+	controller := daemonController(instance)
+	if controller == nil {
+		return
+	}
+	cfg := pm.Config().Daemons
+	if _, err := controller.DaemonStatus(cfg.startTimeout()); err != nil && pluginsdk.AsPluginError(err).Code == pluginsdk.CodeUnsupported {
+		return
+	}
+
+	restart, max := cfg.policy(name)
+	d := &daemonState{plugin: name, controller: controller, generation: generation, done: make(chan struct{}), max: max}
+	d.info = DaemonInfo{DaemonStatus: pluginsdk.DaemonStatus{State: pluginsdk.DaemonStarting}, Restart: restart}
+
+	pm.mu.Lock()
+	if info.generation != generation || info.stopping {
+		pm.mu.Unlock()
+		return
+	}
+	// Restarts of the crashed process before this one still count
+	d.info.Restarts = pm.daemonRestarts[name]
+	delete(pm.daemonRestarts, name)
+	info.daemon = d
+	pm.mu.Unlock()
+
+	go pm.supervise(info, d)
+}
+
+// supervise starts the server of d, then checks it every check_interval
+// and restarts it or its process by the restart policy, until the process
+// is stopped
+func (pm *PluginManager) supervise(info *pluginInfo, d *daemonState) {
+	pm.startServer(d)
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-time.After(pm.Config().Daemons.checkInterval()):
+		}
+
+		switch pm.daemonProcess(info, d) {
+		case processStopped:
+			return
+		case processCrashed:
+			pm.restartProcess(d)
+			return
+		}
+
+		cfg := pm.Config().Daemons
+		st, err := d.controller.DaemonStatus(cfg.startTimeout())
+		if err != nil {
+			// The process may be going down; if it crashed, the next
+			// check restarts it
+			d.record(pluginsdk.DaemonStatus{State: pluginsdk.DaemonFailed, Message: err.Error(), Endpoint: d.snapshot().Endpoint})
+			continue
+		}
+		d.record(st)
+		if !pm.shouldRestart(d, st.State) {
+			continue
+		}
+
+		delay := cfg.backoff(d.snapshot().Restarts)
+		pm.hostLog.Printf("Restarting daemon of plugin %s in %v: %s", d.plugin, delay, daemonReason(st))
+		select {
+		case <-d.done:
+			return
+		case <-time.After(delay):
+		}
+		d.mu.Lock()
+		d.info.Restarts++
+		restarts := d.info.Restarts
+		d.mu.Unlock()
+		pm.startServer(d)
+		pm.events.Publish(Event{Type: EventDaemonRestarted, Plugin: d.plugin, Data: map[string]interface{}{"restarts": restarts, "reason": daemonReason(st)}})
+	}
+}
+
+// startServer starts the server of d and records how it went
+func (pm *PluginManager) startServer(d *daemonState) {
+	d.mu.Lock()
+	d.started = time.Now()
+	d.mu.Unlock()
+
+	st, err := d.controller.StartDaemon(pm.Config().Daemons.startTimeout())
+	if err != nil {
+		st.State, st.Message = pluginsdk.DaemonFailed, err.Error()
+		pm.hostLog.Printf("Daemon of plugin %s failed to start: %v", d.plugin, err)
+		pm.events.Publish(Event{Type: EventDaemonFailed, Plugin: d.plugin, Data: map[string]interface{}{"error": err.Error()}})
+	} else {
+		pm.hostLog.Printf("Started daemon of plugin %s: %s", d.plugin, st.Endpoint)
+	}
+	d.record(st)
+}
+
+// shouldRestart reports whether the policy of d restarts a server in
+// state, and gives up once the restarts ran out
+func (pm *PluginManager) shouldRestart(d *daemonState, state string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.info.Held || d.info.GaveUp {
+		return false
+	}
+	switch {
+	case state == pluginsdk.DaemonFailed && d.info.Restart != RestartNever:
+	case state == pluginsdk.DaemonStopped && d.info.Restart == RestartAlways:
+	default:
+		return false
+	}
+	if d.max >= 0 && d.info.Restarts >= d.max {
+		d.info.GaveUp = true
+		pm.hostLog.Printf("Giving up on daemon of plugin %s after %d restarts: %s", d.plugin, d.info.Restarts, daemonReason(d.info.DaemonStatus))
+		pm.events.Publish(Event{Type: EventDaemonFailed, Plugin: d.plugin, Data: map[string]interface{}{"error": daemonReason(d.info.DaemonStatus), "gave_up": true}})
+		return false
+	}
+	return true
+}
+
+// States of the process of a supervised daemon
+const (
+	processRunning = iota
+	processStopped
+	processCrashed
+)
+
+// daemonProcess tells whether the process d supervises still runs
+func (pm *PluginManager) daemonProcess(info *pluginInfo, d *daemonState) int {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	switch {
+	case info.generation != d.generation || info.stopping || pm.plugins[d.plugin] != info:
+		return processStopped
+	case info.crashed:
+		return processCrashed
+	}
+	return processRunning
+}
+
+// restartProcess reloads the plugin of d after its process crashed, if the
+// restart policy says so. The restart counts for the new process.
+func (pm *PluginManager) restartProcess(d *daemonState) {
+	if !pm.shouldRestart(d, pluginsdk.DaemonFailed) {
+		return
+	}
+	restarts := d.snapshot().Restarts
+	delay := pm.Config().Daemons.backoff(restarts)
+	pm.hostLog.Printf("Restarting crashed daemon plugin %s in %v", d.plugin, delay)
+	select {
+	case <-d.done:
+		return
+	case <-time.After(delay):
+	}
+
+	pm.mu.Lock()
+	pm.daemonRestarts[d.plugin] = restarts + 1
+	pm.mu.Unlock()
+	if err := pm.ReloadPlugin(d.plugin); err != nil {
+		pm.hostLog.Printf("Failed to restart daemon plugin %s: %v", d.plugin, err)
+		pm.events.Publish(Event{Type: EventDaemonFailed, Plugin: d.plugin, Data: map[string]interface{}{"error": err.Error()}})
+		return
+	}
+	pm.events.Publish(Event{Type: EventDaemonRestarted, Plugin: d.plugin, Data: map[string]interface{}{"restarts": restarts + 1, "reason": "process crashed"}})
+}
+
+// stopDaemon ends the supervision of d and stops its server, before its
+// process is stopped
+func stopDaemon(d *daemonState, timeout time.Duration) error {
+	d.end()
+	st, err := d.controller.StopDaemon(timeout)
+	if err == nil {
+		d.record(st)
+	}
+	return err
+}
+
+func daemonReason(st pluginsdk.DaemonStatus) string {
+	if st.Message != "" {
+		return fmt.Sprintf("%s: %s", st.State, st.Message)
+	}
+	return st.State
+}
+
+// daemonOf returns the daemon state of the plugin name
+func (pm *PluginManager) daemonOf(name string) (*daemonState, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	info, exists := pm.plugins[name]
+	if !exists {
+		return nil, pm.errPluginNotFound(name)
+	}
+	if info.daemon == nil {
+		return nil, pluginsdk.NewError(pluginsdk.CodeUnsupported, "plugin %s is not a running daemon", name)
+	}
+	return info.daemon, nil
+}
+
+// StopDaemon stops the server of a daemon plugin and keeps it stopped,
+// whatever its restart policy, until StartDaemon starts it again. The
+// process keeps running.
+func (pm *PluginManager) StopDaemon(name string) (*DaemonInfo, error) {
+	d, err := pm.daemonOf(name)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.info.Held = true
+	d.mu.Unlock()
+
+	st, err := d.controller.StopDaemon(pm.Config().Daemons.startTimeout())
+	if err != nil {
+		return nil, fmt.Errorf("failed to stop daemon of plugin %s: %w", name, err)
+	}
+	d.record(st)
+	pm.hostLog.Printf("Stopped daemon of plugin %s", name)
+	info := d.snapshot()
+	return &info, nil
+}
+
+// StartDaemon starts the server of a daemon plugin stopped with StopDaemon,
+// or one whose restarts ran out, and applies its restart policy again
+func (pm *PluginManager) StartDaemon(name string) (*DaemonInfo, error) {
+	d, err := pm.daemonOf(name)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	d.info.Held, d.info.GaveUp, d.info.Restarts = false, false, 0
+	d.mu.Unlock()
+
+	pm.startServer(d)
+	info := d.snapshot()
+	if info.State == pluginsdk.DaemonFailed {
+		return &info, fmt.Errorf("daemon of plugin %s failed to start: %s", name, info.Message)
+	}
+	return &info, nil
+}
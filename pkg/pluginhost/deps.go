@@ -0,0 +1,233 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Defaults of DependencyConfig and Dependency
+const (
+	DefaultDependencyWait     = 2 * time.Minute
+	DefaultDependencyInterval = 2 * time.Second
+	DefaultDependencyTimeout  = 5 * time.Second
+)
+
+// Dependency is an external service a plugin needs, checked before its
+// process is started. Exactly one of TCP, HTTP and Command is set.
+type Dependency struct {
+	// Name identifies the dependency in statuses and logs, e.g. "postgres"
+	Name string `json:"name"`
+
+	// TCP is a host:port that must accept connections
+	TCP string `json:"tcp,omitempty"`
+
+	// HTTP is a URL that must answer with a 2xx or 3xx status
+	HTTP string `json:"http,omitempty"`
+
+	// Command must exit with status 0, e.g. ["pg_isready", "-h", "db"]
+	Command []string `json:"command,omitempty"`
+
+	// Timeout bounds one check; defaults to DefaultDependencyTimeout
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+func (d Dependency) validate() error {
+	set := 0
+	for _, ok := range []bool{d.TCP != "", d.HTTP != "", len(d.Command) > 0} {
+		if ok {
+			set++
+		}
+	}
+	if d.Name == "" {
+		return fmt.Errorf("dependency has no name")
+	}
+	if set != 1 {
+		return fmt.Errorf("dependency %s must set exactly one of tcp, http and command", d.Name)
+	}
+	if d.Timeout < 0 {
+		return fmt.Errorf("dependency %s: timeout must not be negative", d.Name)
+	}
+	return nil
+}
+
+// check reports why the dependency is not ready, or nil when it is
+func (d Dependency) check() error {
+	timeout := time.Duration(d.Timeout)
+	if timeout == 0 {
+		timeout = DefaultDependencyTimeout
+	}
+	switch {
+	case d.TCP != "":
+		conn, err := net.DialTimeout("tcp", d.TCP, timeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case d.HTTP != "":
+		client := &http.Client{Timeout: timeout}
+		resp, err := client.Get(d.HTTP)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("GET %s: %s", d.HTTP, resp.Status)
+		}
+		return nil
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, d.Command[0], d.Command[1:]...).CombinedOutput()
+		if err != nil {
+			if msg := strings.TrimSpace(string(out)); msg != "" {
+				return fmt.Errorf("%v: %s", err, lastLine(msg))
+			}
+			return err
+		}
+		return nil
+	}
+}
+
+// lastLine returns the last line of s
+func lastLine(s string) string {
+	return s[strings.LastIndex(s, "\n")+1:]
+}
+
+// DependencyConfig sets how long plugins wait for their dependencies
+type DependencyConfig struct {
+	// Wait is how long a plugin start waits for its dependencies before
+	// it fails; defaults to DefaultDependencyWait
+	Wait Duration `json:"wait"`
+
+	// Interval is the pause between checks; defaults to
+	// DefaultDependencyInterval
+	Interval Duration `json:"interval"`
+}
+
+func (c *DependencyConfig) validate() error {
+	if c.Wait < 0 || c.Interval < 0 {
+		return fmt.Errorf("dependencies.wait and dependencies.interval must not be negative")
+	}
+	return nil
+}
+
+func (c *DependencyConfig) wait() time.Duration {
+	if c.Wait > 0 {
+		return time.Duration(c.Wait)
+	}
+	return DefaultDependencyWait
+}
+
+func (c *DependencyConfig) interval() time.Duration {
+	if c.Interval > 0 {
+		return time.Duration(c.Interval)
+	}
+	return DefaultDependencyInterval
+}
+
+// awaitDependencies checks the dependencies in the manifest of the binary
+// at path until all are ready, and returns how long that took. While it
+// waits, the plugin's status says what it is waiting on.
+func (pm *PluginManager) awaitDependencies(path string) (time.Duration, error) {
+	m := pm.pluginManifest(path)
+	if m == nil || len(m.Dependencies) == 0 {
+		return 0, nil
+	}
+	cfg := pm.Config().Dependencies
+	// Plugins unloaded while they wait stop waiting; binaries started for
+	// an upgrade are not registered yet
+	registered := pm.hasPath(path)
+	begin := time.Now()
+	deadline := begin.Add(cfg.wait())
+	defer func() {
+		pm.mu.Lock()
+		delete(pm.waiting, path)
+		pm.mu.Unlock()
+	}()
+
+	logged := ""
+	for {
+		var pending string
+		var err error
+		for _, d := range m.Dependencies {
+			if err = d.check(); err != nil {
+				pending = d.Name
+				break
+			}
+		}
+		if err == nil {
+			if logged != "" {
+				pm.hostLog.Printf("Dependencies of plugin %s ready after %v", m.Name, time.Since(begin).Round(time.Millisecond))
+			}
+			return time.Since(begin), nil
+		}
+
+		reason := fmt.Sprintf("%s: %v", pending, err)
+		if registered && !pm.hasPath(path) {
+			return 0, &StartupError{Path: path, Phase: PhaseDependencies, Elapsed: time.Since(begin), Err: fmt.Errorf("unloaded while waiting on dependency %s", reason)}
+		}
+		if time.Now().Add(cfg.interval()).After(deadline) {
+			return 0, &StartupError{Path: path, Phase: PhaseDependencies, Elapsed: time.Since(begin), Err: fmt.Errorf("dependency %s", reason)}
+		}
+		if pending != logged {
+			pm.hostLog.Printf("Plugin %s waiting on dependency %s", m.Name, reason)
+			logged = pending
+		}
+		pm.mu.Lock()
+		if pm.waiting == nil {
+			pm.waiting = make(map[string]string)
+		}
+		pm.waiting[path] = reason
+		pm.mu.Unlock()
+		time.Sleep(cfg.interval())
+	}
+}
+
+// waitState reports a plugin that is not running while it waits on a
+// dependency. The caller holds pm.mu.
+func (pm *PluginManager) waitState(info *pluginInfo, st *PluginStatus) {
+	if reason, ok := pm.waiting[info.Path]; ok && info.Instance == nil {
+		st.State = StateWaiting
+		st.WaitingOn = reason
+		st.Uptime = 0
+	}
+}
+
+// registerWaiting lists a plugin whose manifest declares dependencies
+// before its process starts, so its status shows what it waits on. It
+// returns nil when nothing was registered.
+func (pm *PluginManager) registerWaiting(path string) *pluginInfo {
+	m := pm.pluginManifest(path)
+	if m == nil || len(m.Dependencies) == 0 {
+		return nil
+	}
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if _, exists := pm.plugins[m.Name]; exists {
+		return nil
+	}
+	info := &pluginInfo{
+		Name:    m.Name,
+		Version: m.Version,
+		Path:    path,
+		loading: true,
+		calls:   &callHistory{},
+		stats:   &pluginStats{},
+	}
+	pm.plugins[m.Name] = info
+	return info
+}
+
+// dropWaiting removes what registerWaiting registered, unless it was
+// replaced meanwhile. The caller holds pm.mu.
+func (pm *PluginManager) dropWaiting(info *pluginInfo) {
+	if info != nil && pm.plugins[info.Name] == info {
+		delete(pm.plugins, info.Name)
+	}
+}
@@ -0,0 +1,188 @@
+package pluginhost
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// stderrTailSize is how much of a plugin's stderr is kept for crash reports
+	stderrTailSize = 64 << 10
+
+	// callHistorySize is how many recent calls are kept per plugin
+	callHistorySize = 50
+)
+
+// CrashReport describes the most recent unexpected exit of a plugin
+type CrashReport struct {
+	Time       time.Time `json:"time"`
+	ExitCode   int       `json:"exit_code"`
+	StderrTail string    `json:"-"`
+	BundlePath string    `json:"bundle_path,omitempty"`
+}
+
+// stderrTail is an io.Writer that keeps only the last stderrTailSize bytes
+// written to it, so a crash report has the panic message without the host
+// buffering a chatty plugin's whole output.
+type stderrTail struct {
+	buf []byte
+	mu  sync.Mutex
+}
+
+func (t *stderrTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - stderrTailSize; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (t *stderrTail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return string(t.buf)
+}
+
+// CallRecord is one entry in a plugin's recent call history
+type CallRecord struct {
+	Time     time.Time              `json:"time"`
+	Args     map[string]interface{} `json:"args"`
+	Duration time.Duration          `json:"duration_ns"`
+	Error    string                 `json:"error,omitempty"`
+
+	// Attempt is 1 for the first try and counts up across retries
+	Attempt int `json:"attempt"`
+
+	// Partial is set when the call returned what it had when its latency
+	// budget ran out
+	Partial bool `json:"partial,omitempty"`
+
+	// Batch is the number of calls of the batch the call ran in, whose
+	// duration Duration is; zero for calls that ran alone
+	Batch int `json:"batch,omitempty"`
+}
+
+// callHistory keeps the most recent calls made to a plugin
+type callHistory struct {
+	calls []CallRecord
+	mu    sync.Mutex
+}
+
+func (h *callHistory) add(rec CallRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.calls = append(h.calls, rec)
+	if len(h.calls) > callHistorySize {
+		h.calls = h.calls[len(h.calls)-callHistorySize:]
+	}
+}
+
+func (h *callHistory) snapshot() []CallRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]CallRecord(nil), h.calls...)
+}
+
+// RecentCalls returns up to the last 50 calls made to a plugin, oldest first
+func (pm *PluginManager) RecentCalls(name string) ([]CallRecord, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	info, exists := pm.plugins[name]
+	if !exists {
+		return nil, pm.errPluginNotFound(name)
+	}
+	return info.calls.snapshot(), nil
+}
+
+// LogTail returns the retained tail of a plugin's stderr. It is empty for a
+// plugin whose process has not been started.
+func (pm *PluginManager) LogTail(name string) (string, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	info, exists := pm.plugins[name]
+	if !exists {
+		return "", pm.errPluginNotFound(name)
+	}
+	if info.stderr == nil {
+		return "", nil
+	}
+	return info.stderr.String(), nil
+}
+
+// writeCrashBundle writes crash.json, stderr.log and calls.json for a crashed
+// plugin into a tar.gz in dir and returns the bundle's path
+func writeCrashBundle(dir string, info *pluginInfo, report *CrashReport, calls []CallRecord) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create crash directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.tar.gz", info.Name, report.Time.Format("20060102-150405")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create crash bundle: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	meta, err := json.MarshalIndent(map[string]interface{}{
+		"plugin":    info.Name,
+		"version":   info.Version,
+		"path":      info.Path,
+		"time":      report.Time,
+		"exit_code": report.ExitCode,
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	history, err := json.MarshalIndent(calls, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"crash.json", meta},
+		{"stderr.log", []byte(report.StderrTail)},
+		{"calls.json", history},
+	}
+	for _, file := range files {
+		hdr := &tar.Header{
+			Name:    file.name,
+			Mode:    0644,
+			Size:    int64(len(file.data)),
+			ModTime: report.Time,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return "", fmt.Errorf("failed to write crash bundle: %w", err)
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return "", fmt.Errorf("failed to write crash bundle: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to write crash bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write crash bundle: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,335 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// CheckWarned is the outcome of a doctor check that found a problem the
+// host's config only warns about, so the plugin still starts
+const CheckWarned = "warned"
+
+// The checks of a DoctorReport, in the order they run
+const (
+	DoctorManifest     = "manifest"
+	DoctorEnvironment  = "environment"
+	DoctorDependencies = "dependencies"
+	DoctorSignature    = "signature"
+	DoctorHandshake    = "handshake"
+	DoctorProtocol     = "protocol"
+	DoctorStart        = "start"
+)
+
+// DoctorChecks lists the checks of every DoctorReport in order
+var DoctorChecks = []string{DoctorManifest, DoctorEnvironment, DoctorDependencies, DoctorSignature, DoctorHandshake, DoctorProtocol, DoctorStart}
+
+// DoctorCheck is the outcome of one check of a plugin binary
+type DoctorCheck struct {
+	// Check is one of DoctorChecks
+	Check string `json:"check"`
+
+	// Status is CheckPassed, CheckFailed, CheckWarned or CheckSkipped
+	Status string `json:"status"`
+
+	// Detail says what was found, Hint what to change when the check did
+	// not pass
+	Detail string `json:"detail,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+// DoctorReport tells whether the host can run a plugin binary and, when
+// not, what to change
+type DoctorReport struct {
+	Path string `json:"path"`
+
+	// Plugin and Version are what the plugin reported, or its manifest
+	// says when it could not be started
+	Plugin  string `json:"plugin,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Protocol is the protocol the plugin served, "grpc" or "netrpc"
+	Protocol string `json:"protocol,omitempty"`
+
+	// Passed is true when no check failed; skipped and warned checks do
+	// not count
+	Passed bool `json:"passed"`
+
+	Checks []DoctorCheck `json:"checks"`
+}
+
+// Check returns the outcome of check
+func (r *DoctorReport) Check(check string) DoctorCheck {
+	for _, c := range r.Checks {
+		if c.Check == check {
+			return c
+		}
+	}
+	return DoctorCheck{Check: check, Status: CheckSkipped}
+}
+
+func (r *DoctorReport) add(c DoctorCheck) {
+	r.Checks = append(r.Checks, c)
+	if c.Status == CheckFailed {
+		r.Passed = false
+	}
+}
+
+// Doctor checks every plugin binary in dirs, or in the plugin directories
+// of the config and its discovery sources when none are given, the way
+// loading it would: its manifest, the requirements the manifest states of
+// the machine, the services it depends on, its lockfile pin and provenance,
+// and then the handshake, the protocol it serves and its initialization.
+// Running plugins pass the last three as they are; the others are started
+// for the check and stopped again, unless the host would refuse to start
+// them. Directories that do not exist are skipped.
+func (pm *PluginManager) Doctor(dirs ...string) ([]DoctorReport, error) {
+	if len(dirs) == 0 {
+		dirs = append(append([]string(nil), pm.Config().PluginDirs...), pm.sourceDirs()...)
+	}
+	var reports []DoctorReport
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return reports, fmt.Errorf("failed to read plugin directory: %w", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if ext := filepath.Ext(entry.Name()); ext == manifestExt || ext == BundleExt {
+				continue
+			}
+			reports = append(reports, pm.diagnose(filepath.Join(dir, entry.Name())))
+		}
+	}
+	return reports, nil
+}
+
+// diagnose runs the doctor checks on the binary at path
+func (pm *PluginManager) diagnose(path string) DoctorReport {
+	r := DoctorReport{Path: path, Passed: true}
+
+	m, err := LoadManifest(manifestPath(path))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		m = nil
+		r.add(DoctorCheck{Check: DoctorManifest, Status: CheckSkipped, Detail: "no manifest", Hint: fmt.Sprintf("add %s so the host knows the plugin without starting it", filepath.Base(manifestPath(path)))})
+	case err != nil:
+		m = nil
+		r.add(DoctorCheck{Check: DoctorManifest, Status: CheckFailed, Detail: err.Error(), Hint: "fix the manifest; the host ignores manifests it cannot read"})
+	case len(m.Migrated) > 0:
+		r.Plugin, r.Version = m.Name, m.Version
+		r.add(DoctorCheck{Check: DoctorManifest, Status: CheckWarned, Detail: m.Migrated[0].Explanation, Hint: fmt.Sprintf("upgrade it with \"superplugin manifest migrate -w %s\"", manifestPath(path))})
+	default:
+		r.Plugin, r.Version = m.Name, m.Version
+		r.add(DoctorCheck{Check: DoctorManifest, Status: CheckPassed, Detail: fmt.Sprintf("schema version %d", m.SchemaVersion)})
+	}
+
+	// A manifest the host cannot read is ignored, so only these keep the
+	// plugin from starting
+	blocked := false
+	for _, c := range []DoctorCheck{pm.doctorEnvironment(path, m), doctorDependencies(m), pm.doctorSignature(path)} {
+		r.add(c)
+		blocked = blocked || c.Status == CheckFailed
+	}
+	if blocked {
+		for _, check := range []string{DoctorHandshake, DoctorProtocol, DoctorStart} {
+			r.add(DoctorCheck{Check: check, Status: CheckSkipped, Detail: "not started, as a check above failed"})
+		}
+		return r
+	}
+
+	started := "running"
+	name, version, protocol, running := pm.runningAt(path)
+	if !running {
+		proc, err := pm.startProcess(path)
+		if err != nil {
+			for _, c := range doctorStartFailure(err) {
+				r.add(c)
+			}
+			return r
+		}
+		proc.client.Kill()
+		started = "started"
+		name, version, protocol = proc.name, proc.instance.Version(), proc.protocol
+	}
+
+	r.Plugin, r.Version, r.Protocol = name, version, protocol
+	r.add(DoctorCheck{Check: DoctorHandshake, Status: CheckPassed, Detail: fmt.Sprintf("plugin protocol version %d", pluginsdk.Handshake.ProtocolVersion)})
+	r.add(DoctorCheck{Check: DoctorProtocol, Status: CheckPassed, Detail: protocol})
+	if m != nil && m.Name != name {
+		r.add(DoctorCheck{Check: DoctorStart, Status: CheckFailed, Detail: fmt.Sprintf("plugin reports name %q, but its manifest says %q", name, m.Name), Hint: "make the manifest's name match the name the plugin reports"})
+		return r
+	}
+	r.add(DoctorCheck{Check: DoctorStart, Status: CheckPassed, Detail: fmt.Sprintf("%s as %s", started, name)})
+	return r
+}
+
+// runningAt returns the plugin running the binary at path, if one does
+func (pm *PluginManager) runningAt(path string) (name, version, protocol string, ok bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	path = filepath.Clean(path)
+	for _, info := range pm.plugins {
+		if !info.builtin && info.Instance != nil && filepath.Clean(info.Path) == path {
+			return info.reports(), info.Version, info.Protocol, true
+		}
+	}
+	return "", "", "", false
+}
+
+// doctorEnvironment checks the requirements the manifest m states of the
+// machine
+func (pm *PluginManager) doctorEnvironment(path string, m *Manifest) DoctorCheck {
+	c := DoctorCheck{Check: DoctorEnvironment}
+	if m == nil || m.Requires == nil {
+		c.Status, c.Detail = CheckSkipped, "no requirements stated"
+		return c
+	}
+	err := pm.checkRequirements(path)
+	var re *RequirementsError
+	switch {
+	case errors.As(err, &re):
+		c.Status, c.Detail = CheckFailed, strings.Join(re.Unmet, "; ")
+		c.Hint = "install what is missing, or run the plugin on a host that has it"
+	case err != nil:
+		c.Status, c.Detail = CheckFailed, err.Error()
+	default:
+		c.Status, c.Detail = CheckPassed, fmt.Sprintf("%s/%s meets the manifest's requirements", runtime.GOOS, runtime.GOARCH)
+	}
+	return c
+}
+
+// doctorDependencies checks once that the services the manifest m lists
+// are ready, without waiting for them
+func doctorDependencies(m *Manifest) DoctorCheck {
+	c := DoctorCheck{Check: DoctorDependencies}
+	if m == nil || len(m.Dependencies) == 0 {
+		c.Status, c.Detail = CheckSkipped, "no dependencies"
+		return c
+	}
+	var ready, pending []string
+	for _, d := range m.Dependencies {
+		if err := d.check(); err != nil {
+			pending = append(pending, fmt.Sprintf("%s: %v", d.Name, err))
+		} else {
+			ready = append(ready, d.Name)
+		}
+	}
+	if len(pending) > 0 {
+		c.Status, c.Detail = CheckFailed, strings.Join(pending, "; ")
+		c.Hint = "start the services; the host waits dependencies.wait for them before it gives up on the plugin"
+		return c
+	}
+	c.Status, c.Detail = CheckPassed, strings.Join(ready, ", ")+" ready"
+	return c
+}
+
+// doctorSignature checks the binary against its lockfile pin and its SBOM
+// and provenance, as verifyBinary and verifySupplyChain do before a start
+func (pm *PluginManager) doctorSignature(path string) DoctorCheck {
+	c := DoctorCheck{Check: DoctorSignature}
+	cfg := pm.Config()
+	var found, problems []string
+	warnOnly := true
+
+	if cfg.Lock.Path != "" {
+		if err := pm.checkLock(cfg.Lock.Path, path); err != nil {
+			problems = append(problems, err.Error())
+			warnOnly = warnOnly && cfg.Lock.Mode == LockWarn
+		} else {
+			found = append(found, "pinned in "+cfg.Lock.Path)
+		}
+	}
+
+	sccfg := cfg.SupplyChain
+	trust := pm.trustOf(path)
+	if trust != TrustFull {
+		sccfg.RequireProvenance, sccfg.Mode = true, LockEnforce
+	}
+	sc, err := pm.checkSupplyChain(sccfg, path)
+	if err != nil {
+		if trust != TrustFull {
+			err = fmt.Errorf("%w; its source is only trusted %s", err, trust)
+		}
+		problems = append(problems, err.Error())
+		warnOnly = warnOnly && sccfg.Mode == LockWarn
+	} else if sc = sc.orNil(); sc != nil && sc.Provenance != nil {
+		found = append(found, fmt.Sprintf("provenance by %s", sc.Provenance.Builder))
+	} else if sc != nil {
+		found = append(found, "SBOM attached")
+	}
+
+	switch {
+	case len(problems) > 0:
+		c.Status, c.Detail = CheckFailed, strings.Join(problems, "; ")
+		if warnOnly {
+			c.Status = CheckWarned
+		}
+		c.Hint = "re-pin it with \"superhost lock\" if the binary changed on purpose, or attach the SBOM and provenance its build produced"
+	case len(found) == 0:
+		c.Status, c.Detail = CheckSkipped, "not pinned in a lockfile and no provenance attached"
+		c.Hint = "pin the plugins with \"superhost lock\" and set lock.path"
+	default:
+		c.Status, c.Detail = CheckPassed, strings.Join(found, "; ")
+	}
+	return c
+}
+
+// doctorStartFailure turns the error of a failed start into the outcomes
+// of the handshake, protocol and start checks
+func doctorStartFailure(err error) []DoctorCheck {
+	handshake := DoctorCheck{Check: DoctorHandshake, Status: CheckPassed, Detail: fmt.Sprintf("plugin protocol version %d", pluginsdk.Handshake.ProtocolVersion)}
+	protocol := DoctorCheck{Check: DoctorProtocol, Status: CheckPassed}
+	start := DoctorCheck{Check: DoctorStart, Status: CheckFailed, Detail: err.Error()}
+	skip := func(c *DoctorCheck) {
+		c.Status, c.Detail = CheckSkipped, "not reached"
+	}
+
+	var se *StartupError
+	if !errors.As(err, &se) {
+		// Failed before the process was spawned
+		skip(&handshake)
+		skip(&protocol)
+		return []DoctorCheck{handshake, protocol, start}
+	}
+	switch se.Phase {
+	case PhaseSpawn:
+		handshake.Status, handshake.Detail = CheckFailed, se.Err.Error()
+		handshake.Hint = fmt.Sprintf("make sure the file is an executable built for %s/%s; other files do not belong in a plugin directory", runtime.GOOS, runtime.GOARCH)
+		skip(&protocol)
+		skip(&start)
+	case PhaseHandshake:
+		var pe *ProtocolError
+		switch {
+		case errors.As(se.Err, &pe) && incompatibleVersion.MatchString(pe.Err.Error()):
+			handshake.Status, handshake.Detail, handshake.Hint = CheckFailed, pe.Problem, pe.Fix
+			skip(&protocol)
+		case errors.As(se.Err, &pe):
+			protocol.Status, protocol.Detail, protocol.Hint = CheckFailed, pe.Problem, pe.Fix
+		default:
+			handshake.Status, handshake.Detail = CheckFailed, se.Err.Error()
+			handshake.Hint = "serve the plugin with pluginsdk.Serve or one of the SDKs, and check its log for why it exited; slow starters need a longer loading.start_timeout"
+			skip(&protocol)
+		}
+		skip(&start)
+	case PhaseDispense:
+		start.Hint = "the plugin must serve the CommandPlugin interface and answer Name"
+	case PhaseInitialize:
+		start.Hint = "check the plugin's plugin_config entry; the plugin rejected it"
+	}
+	if protocol.Status == CheckPassed {
+		protocol.Detail = "connected"
+	}
+	return []DoctorCheck{handshake, protocol, start}
+}
@@ -0,0 +1,118 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultShutdownDrainTimeout is how long plugins get to report they are
+// safe to terminate when shutdown.drain_timeout is not set
+const DefaultShutdownDrainTimeout = 10 * time.Second
+
+// ShutdownConfig controls how plugin processes are stopped. Before the host
+// stops a plugin, on Shutdown or when unloading it, it calls the plugin's
+// Drain so the plugin can stop taking on work of its own and checkpoint its
+// state, and waits until the plugin reports it is safe to terminate.
+type ShutdownConfig struct {
+	// DrainTimeout bounds how long the host waits for plugins to drain
+	// before it stops them anyway; defaults to DefaultShutdownDrainTimeout
+	DrainTimeout Duration `json:"drain_timeout"`
+}
+
+func (c *ShutdownConfig) validate() error {
+	if c.DrainTimeout < 0 {
+		return fmt.Errorf("shutdown.drain_timeout must not be negative")
+	}
+	return nil
+}
+
+func (c ShutdownConfig) drainTimeout() time.Duration {
+	if c.DrainTimeout == 0 {
+		return DefaultShutdownDrainTimeout
+	}
+	return time.Duration(c.DrainTimeout)
+}
+
+// drainTarget is a running process of a plugin to drain
+type drainTarget struct {
+	plugin   string
+	instance pluginsdk.CommandPlugin
+
+	// daemon is the server to stop once the process drained; nil unless
+	// the process is a daemon's
+	daemon *daemonState
+}
+
+// drainTargets returns the running processes of info, its pooled ones
+// included. The caller holds pm.mu.
+func drainTargets(info *pluginInfo) []drainTarget {
+	if info.Instance == nil {
+		return nil
+	}
+	targets := []drainTarget{{plugin: info.Name, instance: info.Instance}}
+	if !info.crashed {
+		targets[0].daemon = info.daemon
+	}
+	if info.pool != nil {
+		for _, instance := range info.pool.workers() {
+			targets = append(targets, drainTarget{plugin: info.Name, instance: instance})
+		}
+	}
+	return targets
+}
+
+// drain asks every target to get ready to be stopped and waits until all
+// of them reported they are safe to terminate or the drain timeout ran out.
+// The targets drain concurrently; plugins predating Drain are stopped as
+// before. Daemons then have their server stopped. pm.mu must not be held,
+// since draining plugins may still call back into the host, e.g. to write
+// their state.
+func (pm *PluginManager) drain(targets []drainTarget) {
+	if len(targets) == 0 {
+		return
+	}
+	timeout := pm.Config().Shutdown.drainTimeout()
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t drainTarget) {
+			defer wg.Done()
+
+			done := make(chan error, 1)
+			go func() { done <- drainInstance(t.instance, timeout) }()
+			select {
+			case err := <-done:
+				if err != nil && pluginsdk.AsPluginError(err).Code != pluginsdk.CodeUnsupported {
+					pm.hostLog.Printf("Plugin %s failed to drain: %v", t.plugin, err)
+				}
+			case <-time.After(timeout):
+				pm.hostLog.Printf("Plugin %s did not drain within %v", t.plugin, timeout)
+			}
+			if t.daemon != nil {
+				if err := stopDaemon(t.daemon, pm.Config().Daemons.startTimeout()); err != nil {
+					pm.hostLog.Printf("Daemon of plugin %s failed to stop: %v", t.plugin, err)
+				}
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+// drainInstance drains a plugin process through its client, or a builtin
+// plugin directly
+func drainInstance(instance pluginsdk.CommandPlugin, timeout time.Duration) error {
+	switch d := instance.(type) {
+	case pluginsdk.Drainer:
+		return d.Drain(timeout)
+	case pluginsdk.DrainablePlugin:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return d.Drain(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package pluginhost
+
+import (
+	"fmt"
+	"time"
+)
+
+// Editor lifecycle events, fed in by the editor through EditorAdapter
+const (
+	EventBufferOpened         = "buffer.opened"
+	EventFileSaved            = "file.saved"
+	EventDiagnosticsPublished = "diagnostics.published"
+	EventSelectionChanged     = "selection.changed"
+)
+
+// Position is a zero-based line and character offset in a file, as in the
+// Language Server Protocol
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is the span between two positions; End is exclusive
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Diagnostic is one problem an editor or language server reports
+type Diagnostic struct {
+	Range Range `json:"range"`
+
+	// Severity is "error", "warning", "info" or "hint"
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+
+	// Source names the reporter, e.g. "gopls"
+	Source string `json:"source,omitempty"`
+}
+
+// EditorAdapter turns editor activity into events on the manager's bus.
+// OpenCode calls it from its own hooks; plugins listing the event types in
+// their manifest receive them. Event data holds only JSON values, so it
+// reaches plugins in any language unchanged.
+type EditorAdapter struct {
+	bus *EventBus
+}
+
+// Editor returns the adapter that feeds editor activity into the event bus
+func (pm *PluginManager) Editor() *EditorAdapter {
+	return &EditorAdapter{bus: pm.events}
+}
+
+// BufferOpened reports that the developer opened a file
+func (a *EditorAdapter) BufferOpened(path, language string) {
+	a.publish(EventBufferOpened, map[string]interface{}{
+		"path":     path,
+		"language": language,
+	})
+}
+
+// FileSaved reports that a file was written to disk
+func (a *EditorAdapter) FileSaved(path string) {
+	a.publish(EventFileSaved, map[string]interface{}{
+		"path": path,
+	})
+}
+
+// DiagnosticsPublished reports the complete current diagnostics of a file;
+// an empty list means the file has none left
+func (a *EditorAdapter) DiagnosticsPublished(path string, diagnostics []Diagnostic) {
+	list := make([]interface{}, 0, len(diagnostics))
+	for _, d := range diagnostics {
+		entry := map[string]interface{}{
+			"range":    rangeData(d.Range),
+			"severity": d.Severity,
+			"message":  d.Message,
+		}
+		if d.Source != "" {
+			entry["source"] = d.Source
+		}
+		list = append(list, entry)
+	}
+	a.publish(EventDiagnosticsPublished, map[string]interface{}{
+		"path":        path,
+		"diagnostics": list,
+	})
+}
+
+// SelectionChanged reports the developer's selection in a file; an empty
+// range is the cursor position. text is the selected text and may be empty.
+func (a *EditorAdapter) SelectionChanged(path string, selection Range, text string) {
+	a.publish(EventSelectionChanged, map[string]interface{}{
+		"path":      path,
+		"selection": rangeData(selection),
+		"text":      text,
+	})
+}
+
+// Publish feeds an editor event whose data was built elsewhere, such as one
+// received over the management API. Only the editor event types are
+// accepted, so callers cannot forge host events.
+func (a *EditorAdapter) Publish(eventType string, data map[string]interface{}) error {
+	switch eventType {
+	case EventBufferOpened, EventFileSaved, EventDiagnosticsPublished, EventSelectionChanged:
+	default:
+		return fmt.Errorf("not an editor event: %q", eventType)
+	}
+	a.publish(eventType, data)
+	return nil
+}
+
+func (a *EditorAdapter) publish(eventType string, data map[string]interface{}) {
+	a.bus.Publish(Event{Type: eventType, Time: time.Now(), Data: data})
+}
+
+func rangeData(r Range) map[string]interface{} {
+	return map[string]interface{}{
+		"start": map[string]interface{}{"line": r.Start.Line, "character": r.Start.Character},
+		"end":   map[string]interface{}{"line": r.End.Line, "character": r.End.Character},
+	}
+}
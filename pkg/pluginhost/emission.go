@@ -0,0 +1,219 @@
+package pluginhost
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Events published when an operator mutes or unmutes the events of a
+// plugin
+const (
+	EventPluginMuted   = "plugin.muted"
+	EventPluginUnmuted = "plugin.unmuted"
+)
+
+// EventEmission is what became of the events a plugin emitted: those it
+// published through HostServices.PublishEvent and those it caused by
+// reporting task progress or registering capabilities. Events the host
+// publishes about the plugin, such as plugin.crashed, are not counted and
+// never suppressed.
+type EventEmission struct {
+	// Published reached the event bus
+	Published uint64 `json:"published"`
+
+	// Throttled were suppressed for exceeding the plugin's quota, Muted
+	// for arriving while it was muted
+	Throttled uint64 `json:"throttled"`
+	Muted     uint64 `json:"muted"`
+
+	// Mute is set while the plugin's events are muted
+	Mute *MuteStatus `json:"mute,omitempty"`
+}
+
+// MuteStatus is why and how long a plugin's events are muted
+type MuteStatus struct {
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+
+	// Until is when the mute lifts; nil mutes until UnmuteEvents
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// active reports whether the mute still holds at now
+func (m *MuteStatus) active(now time.Time) bool {
+	return m != nil && (m.Until == nil || now.Before(*m.Until))
+}
+
+// emissionState holds the quotas, mutes and counts of the events plugins
+// emit
+type emissionState struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	counts  map[string]*EventEmission
+	mutes   map[string]*MuteStatus
+
+	// throttling marks the plugins over their quota, so each stretch of
+	// throttling is logged once
+	throttling map[string]bool
+}
+
+// quota returns the event quota of a plugin
+func (c *EventsConfig) quota(name string) RateLimit {
+	if rl, ok := c.PluginQuotas[name]; ok {
+		return rl
+	}
+	return c.PluginQuota
+}
+
+// publishFrom publishes an event a plugin emitted unless the plugin is
+// muted or over its quota
+func (pm *PluginManager) publishFrom(name string, e Event) {
+	cfg := pm.Config().Events
+	s := &pm.emission
+	now := time.Now()
+
+	s.mu.Lock()
+	if s.counts == nil {
+		s.buckets = make(map[string]*tokenBucket)
+		s.counts = make(map[string]*EventEmission)
+		s.throttling = make(map[string]bool)
+	}
+	c := s.counts[name]
+	if c == nil {
+		c = &EventEmission{}
+		s.counts[name] = c
+	}
+	if m := s.mutes[name]; m != nil {
+		if m.active(now) {
+			c.Muted++
+			s.mu.Unlock()
+			return
+		}
+		delete(s.mutes, name)
+	}
+	b := updateBucket(s.buckets[name], cfg.quota(name))
+	if b == nil {
+		delete(s.buckets, name)
+	} else {
+		s.buckets[name] = b
+	}
+	if b != nil && !b.allow(now) {
+		c.Throttled++
+		first := !s.throttling[name]
+		s.throttling[name] = true
+		s.mu.Unlock()
+		if first {
+			pm.hostLog.Printf("Throttling events of plugin %s, over its quota of %v per second", name, cfg.quota(name).PerSecond)
+		}
+		return
+	}
+	delete(s.throttling, name)
+	c.Published++
+	s.mu.Unlock()
+
+	pm.events.Publish(e)
+}
+
+func (h *pluginHost) PublishEvent(eventType string, data map[string]interface{}) error {
+	name, _ := h.pm.callerOf(h.path)
+	if name == "" {
+		return pluginsdk.NewError(pluginsdk.CodeUnavailable, "plugin is not registered yet; publish events once it serves calls")
+	}
+	if rest, ok := strings.CutPrefix(eventType, name+"."); !ok || rest == "" {
+		return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "event type %q of plugin %s must start with %q", eventType, name, name+".")
+	}
+	if hostEventType(eventType) {
+		return pluginsdk.NewError(pluginsdk.CodePermissionDenied, "event type %q is reserved for the host", eventType)
+	}
+	h.pm.publishFrom(name, Event{Type: eventType, Plugin: name, Data: data})
+	return nil
+}
+
+// MuteEvents suppresses the events a plugin emits for d, or until
+// UnmuteEvents when d is 0, without unloading or disabling it: its calls
+// run as before and the host still publishes events about it. Suppressed
+// events are counted in the plugin's status.
+func (pm *PluginManager) MuteEvents(name string, d time.Duration, reason string) error {
+	pm.mu.RLock()
+	_, exists := pm.plugins[name]
+	pm.mu.RUnlock()
+	if !exists {
+		return pm.pluginNotFound(name)
+	}
+	if reason == "" {
+		reason = "muted by an operator"
+	}
+	m := &MuteStatus{Reason: reason, Since: time.Now()}
+	if d > 0 {
+		until := m.Since.Add(d)
+		m.Until = &until
+	}
+
+	s := &pm.emission
+	s.mu.Lock()
+	if s.mutes == nil {
+		s.mutes = make(map[string]*MuteStatus)
+	}
+	s.mutes[name] = m
+	s.mu.Unlock()
+
+	pm.hostLog.Printf("Muted events of plugin %s: %s", name, reason)
+	data := map[string]interface{}{"reason": reason}
+	if m.Until != nil {
+		data["until"] = m.Until.Format(time.RFC3339)
+	}
+	pm.events.Publish(Event{Type: EventPluginMuted, Plugin: name, Data: data})
+	return nil
+}
+
+// UnmuteEvents lets a muted plugin's events through again. The plugin
+// need not be loaded, so a mute of a plugin that is gone can be cleared.
+func (pm *PluginManager) UnmuteEvents(name string) error {
+	s := &pm.emission
+	s.mu.Lock()
+	m := s.mutes[name]
+	delete(s.mutes, name)
+	s.mu.Unlock()
+
+	if !m.active(time.Now()) {
+		pm.mu.RLock()
+		_, exists := pm.plugins[name]
+		pm.mu.RUnlock()
+		if !exists {
+			return pm.pluginNotFound(name)
+		}
+		return nil
+	}
+	pm.hostLog.Printf("Unmuted events of plugin %s", name)
+	pm.events.Publish(Event{Type: EventPluginUnmuted, Plugin: name})
+	return nil
+}
+
+// emissionOf returns what became of a plugin's events, or nil when it
+// emitted none and is not muted
+func (pm *PluginManager) emissionOf(name string) *EventEmission {
+	s := &pm.emission
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counts[name]
+	m := s.mutes[name]
+	if !m.active(time.Now()) {
+		m = nil
+	}
+	if c == nil && m == nil {
+		return nil
+	}
+	e := &EventEmission{}
+	if c != nil {
+		*e = *c
+	}
+	if m != nil {
+		mute := *m
+		e.Mute = &mute
+	}
+	return e
+}
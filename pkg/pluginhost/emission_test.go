@@ -0,0 +1,85 @@
+package pluginhost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// publishingPlugin is a built-in plugin publishing an event of the type
+// its calls name
+type publishingPlugin struct {
+	name string
+	host pluginsdk.HostServices
+}
+
+func (p *publishingPlugin) Name() string                            { return p.name }
+func (p *publishingPlugin) Version() string                         { return "1.0.0" }
+func (p *publishingPlugin) GetCapabilities() []pluginsdk.Capability { return nil }
+func (p *publishingPlugin) SetHostServices(h pluginsdk.HostServices) {
+	p.host = h
+}
+func (p *publishingPlugin) Execute(args map[string]interface{}) (string, error) {
+	eventType, _ := args["type"].(string)
+	return "", p.host.PublishEvent(eventType, nil)
+}
+
+func TestPluginsCannotPublishHostEvents(t *testing.T) {
+	for name := range hostEventNamespaces {
+		if _, err := New(WithBuiltin(&publishingPlugin{name: name})); err == nil {
+			t.Errorf("plugin named %s was registered", name)
+		}
+	}
+
+	pm, err := New(WithBuiltin(&publishingPlugin{name: "linter"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+	sub, err := pm.Events().Subscribe("test", SubscribeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	tests := []struct {
+		eventType string
+		want      pluginsdk.ErrorCode
+	}{
+		{"config.changed", pluginsdk.CodeInvalidArgument},
+		{"plugin.loaded", pluginsdk.CodeInvalidArgument},
+		{"linter", pluginsdk.CodeInvalidArgument},
+		{"linter.finished", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eventType, func(t *testing.T) {
+			_, err := pm.Execute("linter", map[string]interface{}{"type": tt.eventType}, nil)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != tt.want {
+				t.Fatalf("error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+
+	// The refused events were published before the plugin's own, if at all
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-sub.Events():
+			switch e.Type {
+			case "config.changed", "plugin.loaded":
+				t.Fatalf("published %s for the plugin", e.Type)
+			case "linter.finished":
+				return
+			}
+		case <-timeout:
+			t.Fatal("linter.finished was not published")
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// C libraries of Environment.Libc
+const (
+	LibcGlibc = "glibc"
+	LibcMusl  = "musl"
+)
+
+// fingerprintTools are the tools every Environment reports; tools that
+// manifests require are probed as well
+var fingerprintTools = []string{"git", "node", "npm", "python3", "go", "docker"}
+
+// toolProbeTimeout bounds asking a tool for its version
+const toolProbeTimeout = 2 * time.Second
+
+// versionPattern finds a version such as 2.39.2 in a tool's output
+var versionPattern = regexp.MustCompile(`\d+(\.\d+)+`)
+
+// constraintVersion is a version a constraint compares with, e.g. 18 or
+// v2.30
+var constraintVersion = regexp.MustCompile(`^v?\d+(\.\d+)*$`)
+
+// Environment is a fingerprint of the machine the host runs on, which the
+// requirements of manifests are checked against
+type Environment struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+
+	// Libc is LibcGlibc or LibcMusl on Linux and LibcVersion its version;
+	// empty where there is none or it cannot be told
+	Libc        string `json:"libc,omitempty"`
+	LibcVersion string `json:"libc_version,omitempty"`
+
+	// Tools maps the tools found on the PATH to their version, empty when
+	// the tool does not say
+	Tools map[string]string `json:"tools"`
+}
+
+// Requirements are what a plugin needs of the machine, declared under
+// "requires" in its manifest
+type Requirements struct {
+	// OS and Arch list the platforms the plugin runs on, as GOOS and
+	// GOARCH name them; empty runs anywhere
+	OS   []string `json:"os,omitempty"`
+	Arch []string `json:"arch,omitempty"`
+
+	// Libc is the C library the plugin is linked against, LibcGlibc or
+	// LibcMusl, and LibcVersion a constraint on its version, e.g. ">=2.31"
+	Libc        string `json:"libc,omitempty"`
+	LibcVersion string `json:"libc_version,omitempty"`
+
+	// Tools maps commands the plugin runs to a constraint on their
+	// version, e.g. {"git": ">=2.30", "node": ">=18, <23", "python3": ""};
+	// an empty constraint accepts any version
+	Tools map[string]string `json:"tools,omitempty"`
+}
+
+func (r *Requirements) validate() error {
+	if r == nil {
+		return nil
+	}
+	switch r.Libc {
+	case "", LibcGlibc, LibcMusl:
+	default:
+		return fmt.Errorf("requires.libc must be %q or %q, got %q", LibcGlibc, LibcMusl, r.Libc)
+	}
+	if r.LibcVersion != "" && r.Libc == "" {
+		return fmt.Errorf("requires.libc_version needs requires.libc")
+	}
+	if _, err := parseConstraint(r.LibcVersion); err != nil {
+		return fmt.Errorf("requires.libc_version: %w", err)
+	}
+	for tool, constraint := range r.Tools {
+		if tool == "" || strings.ContainsAny(tool, `/\`) {
+			return fmt.Errorf("requires.tools: invalid tool %q", tool)
+		}
+		if _, err := parseConstraint(constraint); err != nil {
+			return fmt.Errorf("requires.tools.%s: %w", tool, err)
+		}
+	}
+	return nil
+}
+
+// RequirementsError reports the requirements of a plugin the machine
+// does not meet, each as a line of Unmet
+type RequirementsError struct {
+	Path        string
+	Unmet       []string
+	Environment Environment
+}
+
+func (e *RequirementsError) Error() string {
+	return fmt.Sprintf("plugin %s cannot run on this host: %s", e.Path, strings.Join(e.Unmet, "; "))
+}
+
+// environmentProbe fingerprints the machine once and the tools as they
+// are first asked about
+type environmentProbe struct {
+	mu    sync.Mutex
+	base  *Environment
+	tools map[string]*string
+}
+
+// Environment returns the fingerprint of the machine the host runs on:
+// platform, C library and the versions of common tools and of those the
+// manifests of loaded plugins require. Each is probed once.
+func (pm *PluginManager) Environment() Environment {
+	pm.mu.RLock()
+	paths := make([]string, 0, len(pm.plugins))
+	for _, info := range pm.plugins {
+		if !info.builtin {
+			paths = append(paths, info.Path)
+		}
+	}
+	pm.mu.RUnlock()
+
+	tools := append([]string(nil), fingerprintTools...)
+	for _, path := range paths {
+		if m := pm.pluginManifest(path); m != nil && m.Requires != nil {
+			for tool := range m.Requires.Tools {
+				tools = append(tools, tool)
+			}
+		}
+	}
+	return pm.environment.fingerprint(tools)
+}
+
+// fingerprint returns the machine's environment with the given tools
+// probed
+func (p *environmentProbe) fingerprint(tools []string) Environment {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.base == nil {
+		env := Environment{OS: runtime.GOOS, Arch: runtime.GOARCH}
+		if runtime.GOOS == "linux" {
+			env.Libc, env.LibcVersion = detectLibc()
+		}
+		p.base = &env
+		p.tools = make(map[string]*string)
+	}
+	env := *p.base
+	env.Tools = make(map[string]string)
+	for _, tool := range tools {
+		version, probed := p.tools[tool]
+		if !probed {
+			version = probeTool(tool)
+			p.tools[tool] = version
+		}
+		if version != nil {
+			env.Tools[tool] = *version
+		}
+	}
+	return env
+}
+
+// checkRequirements reports the requirements in the manifest of the
+// binary at path that the machine does not meet, all at once
+func (pm *PluginManager) checkRequirements(path string) error {
+	m := pm.pluginManifest(path)
+	if m == nil || m.Requires == nil {
+		return nil
+	}
+	r := m.Requires
+	tools := make([]string, 0, len(r.Tools))
+	for tool := range r.Tools {
+		tools = append(tools, tool)
+	}
+	sort.Strings(tools)
+	env := pm.environment.fingerprint(tools)
+
+	var unmet []string
+	if len(r.OS) > 0 && !containsString(r.OS, env.OS) {
+		unmet = append(unmet, fmt.Sprintf("needs os %s, host is %s", strings.Join(r.OS, " or "), env.OS))
+	}
+	if len(r.Arch) > 0 && !containsString(r.Arch, env.Arch) {
+		unmet = append(unmet, fmt.Sprintf("needs arch %s, host is %s", strings.Join(r.Arch, " or "), env.Arch))
+	}
+	if r.Libc != "" {
+		switch {
+		case env.Libc == "":
+			unmet = append(unmet, fmt.Sprintf("needs %s, host has none that could be detected", r.Libc))
+		case env.Libc != r.Libc:
+			unmet = append(unmet, fmt.Sprintf("needs %s, host has %s", r.Libc, env.Libc))
+		case !satisfies(env.LibcVersion, r.LibcVersion):
+			unmet = append(unmet, fmt.Sprintf("needs %s %s, host has %s", r.Libc, r.LibcVersion, env.LibcVersion))
+		}
+	}
+	for _, tool := range tools {
+		constraint := r.Tools[tool]
+		version, found := env.Tools[tool]
+		switch {
+		case !found:
+			unmet = append(unmet, fmt.Sprintf("needs %s on the PATH", strings.TrimSpace(tool+" "+constraint)))
+		case constraint != "" && version == "":
+			unmet = append(unmet, fmt.Sprintf("needs %s %s, but %s does not report its version", tool, constraint, tool))
+		case !satisfies(version, constraint):
+			unmet = append(unmet, fmt.Sprintf("needs %s %s, found %s", tool, constraint, version))
+		}
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+	return &RequirementsError{Path: path, Unmet: unmet, Environment: env}
+}
+
+// detectLibc tells glibc from musl on Linux and returns its version
+func detectLibc() (string, string) {
+	if out, err := runProbe("getconf", "GNU_LIBC_VERSION"); err == nil {
+		if v := versionPattern.FindString(out); v != "" {
+			return LibcGlibc, v
+		}
+	}
+	loaders, _ := filepath.Glob("/lib/ld-musl-*.so.1")
+	if len(loaders) == 0 {
+		return "", ""
+	}
+	// The musl loader prints its version when run without arguments and
+	// exits with status 1
+	out, _ := runProbe(loaders[0])
+	if _, rest, ok := strings.Cut(out, "Version "); ok {
+		return LibcMusl, versionPattern.FindString(rest)
+	}
+	return LibcMusl, ""
+}
+
+// probeTool returns the version of a tool on the PATH, empty when it does
+// not report one, or nil when it is not installed
+func probeTool(tool string) *string {
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil
+	}
+	arg := "--version"
+	if tool == "go" {
+		arg = "version"
+	}
+	out, _ := runProbe(tool, arg)
+	version := versionPattern.FindString(out)
+	return &version
+}
+
+// runProbe runs a command for what it says about the machine
+func runProbe(name string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), toolProbeTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	return string(out), err
+}
+
+// constraint is one comparison of a version constraint, e.g. >=2.30
+type constraint struct {
+	op      string
+	version string
+}
+
+// parseConstraint parses a comma-separated list of comparisons such as
+// ">=18, <23". A version without an operator matches that version and
+// those it prefixes: "3.11" matches 3.11.4.
+func parseConstraint(s string) ([]constraint, error) {
+	var cs []constraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		c := constraint{version: part}
+		for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+			if rest, ok := strings.CutPrefix(part, op); ok {
+				c = constraint{op: op, version: strings.TrimSpace(rest)}
+				break
+			}
+		}
+		if !constraintVersion.MatchString(c.version) {
+			return nil, fmt.Errorf("invalid version %q in constraint %q", c.version, s)
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// satisfies reports whether version meets the constraint; see
+// parseConstraint
+func satisfies(version, s string) bool {
+	cs, err := parseConstraint(s)
+	if err != nil {
+		return false
+	}
+	for _, c := range cs {
+		cmp := compareVersions(version, c.version)
+		var ok bool
+		switch c.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		default:
+			v, want := strings.TrimPrefix(version, "v"), strings.TrimPrefix(c.version, "v")
+			ok = v == want || strings.HasPrefix(v, want+".")
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Errors for calls the host cannot route, matched with errors.Is. The
+// errors returned are *LookupError values carrying the details.
+var (
+	// ErrPluginNotFound means no plugin with the name is registered
+	ErrPluginNotFound = errors.New("plugin not found")
+
+	// ErrCapabilityNotSupported means the plugin, or for fan-out calls
+	// every plugin, lacks the capability
+	ErrCapabilityNotSupported = errors.New("capability not supported")
+
+	// ErrPluginUnhealthy means the plugin is registered but cannot take
+	// calls, because it is still loading or has crashed
+	ErrPluginUnhealthy = errors.New("plugin unhealthy")
+
+	// ErrPluginDisabled means an operator disabled the plugin with Disable
+	ErrPluginDisabled = errors.New("plugin disabled")
+)
+
+// LookupError reports a plugin or capability a call could not use, with
+// the similar names that exist, so applications can offer "did you mean"
+// hints without parsing error strings
+type LookupError struct {
+	// Kind is ErrPluginNotFound, ErrCapabilityNotSupported,
+	// ErrPluginUnhealthy or ErrPluginDisabled
+	Kind error
+
+	// Plugin is the plugin asked for; empty for a capability no plugin
+	// provides
+	Plugin string
+
+	// Capability is the capability asked for, if any
+	Capability string
+
+	// Reason says why an unhealthy plugin cannot take calls
+	Reason string
+
+	// Suggestions are existing names close to the one asked for, closest
+	// first
+	Suggestions []string
+}
+
+func (e *LookupError) Error() string {
+	var msg string
+	switch {
+	case e.Kind == ErrPluginNotFound:
+		msg = "plugin not found: " + e.Plugin
+	case e.Kind == ErrCapabilityNotSupported && e.Plugin == "":
+		msg = "no plugin provides capability: " + e.Capability
+	case e.Kind == ErrCapabilityNotSupported:
+		msg = fmt.Sprintf("plugin %s does not support capability %s", e.Plugin, e.Capability)
+	case e.Kind == ErrPluginDisabled:
+		msg = fmt.Sprintf("plugin %s is disabled by operator", e.Plugin)
+	default:
+		msg = fmt.Sprintf("plugin %s is unhealthy: %s", e.Plugin, e.Reason)
+	}
+	if len(e.Suggestions) > 0 {
+		quoted := make([]string, len(e.Suggestions))
+		for i, s := range e.Suggestions {
+			quoted[i] = fmt.Sprintf("%q", s)
+		}
+		msg += fmt.Sprintf(" (did you mean %s?)", strings.Join(quoted, " or "))
+	}
+	return msg
+}
+
+func (e *LookupError) Unwrap() error {
+	return e.Kind
+}
+
+// errPluginNotFound reports an unknown plugin, suggesting registered ones
+// with similar names. The caller holds pm.mu.
+func (pm *PluginManager) errPluginNotFound(name string) error {
+	names := make([]string, 0, len(pm.plugins))
+	for n := range pm.plugins {
+		names = append(names, n)
+	}
+	for _, st := range pm.remotePlugins() {
+		names = append(names, st.Name)
+	}
+	return &LookupError{Kind: ErrPluginNotFound, Plugin: name, Suggestions: suggest(name, names)}
+}
+
+// pluginNotFound is errPluginNotFound for callers not holding pm.mu
+func (pm *PluginManager) pluginNotFound(name string) error {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.errPluginNotFound(name)
+}
+
+// maxSuggestions caps the "did you mean" hints of a LookupError
+const maxSuggestions = 3
+
+// suggest returns the candidates close to name: within a few edits, or
+// extending it, as "greet" does "greet.formal"
+func suggest(name string, candidates []string) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+	limit := len(name)/4 + 1
+	var matches []match
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		d := editDistance(strings.ToLower(name), strings.ToLower(c))
+		if d <= limit || strings.HasPrefix(c, name) {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	var names []string
+	for i := 0; i < len(matches) && i < maxSuggestions; i++ {
+		names = append(names, matches[i].name)
+	}
+	return names
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
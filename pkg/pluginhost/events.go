@@ -0,0 +1,621 @@
+package pluginhost
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Event types published by the host
+const (
+	EventPluginLoaded    = "plugin.loaded"
+	EventPluginUnloaded  = "plugin.unloaded"
+	EventPluginCrashed   = "plugin.crashed"
+	EventPluginUnhealthy = "plugin.unhealthy"
+	EventPluginExecuted  = "plugin.executed"
+)
+
+// hostEventNamespaces are the first segments of the event types the host
+// publishes. Plugins publish events under their own name, so no plugin
+// may be named after one of them.
+var hostEventNamespaces = map[string]bool{
+	"buffer": true, "call": true, "config": true, "daemon": true,
+	"diagnostics": true, "file": true, "files": true, "flags": true,
+	"host": true, "pipeline": true, "plugin": true, "selection": true,
+	"slo": true, "task": true,
+}
+
+// hostEventType reports whether eventType lies in a namespace of host
+// events
+func hostEventType(eventType string) bool {
+	ns, _, _ := strings.Cut(eventType, ".")
+	return hostEventNamespaces[ns]
+}
+
+// Event is something that happened in the host
+type Event struct {
+	Type   string                 `json:"type"`
+	Plugin string                 `json:"plugin,omitempty"`
+	Time   time.Time              `json:"time"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// DeliveryPolicy decides what happens to an event when a subscriber's
+// buffer is full
+type DeliveryPolicy string
+
+const (
+	// DeliverDropOldest discards the oldest buffered event to make room
+	DeliverDropOldest DeliveryPolicy = "drop_oldest"
+	// DeliverBlock makes the publisher wait up to the block timeout for
+	// room and then drops the new event
+	DeliverBlock DeliveryPolicy = "block"
+	// DeliverSpill queues events in a file until the subscriber catches up,
+	// so nothing is lost
+	DeliverSpill DeliveryPolicy = "spill"
+)
+
+// EventsConfig holds the defaults for subscriptions that do not choose
+// their own delivery
+type EventsConfig struct {
+	// Buffer is the number of events held per subscriber; defaults to 64
+	Buffer int `json:"buffer"`
+
+	// Policy defaults to DeliverDropOldest
+	Policy DeliveryPolicy `json:"policy"`
+
+	// BlockTimeout bounds how long DeliverBlock stalls the publisher;
+	// defaults to one second
+	BlockTimeout Duration `json:"block_timeout"`
+
+	// SpillDir holds the queues of DeliverSpill subscriptions; defaults to
+	// the system temp directory
+	SpillDir string `json:"spill_dir"`
+
+	// PluginQuota limits the events each plugin emits; those over it are
+	// suppressed and counted in the plugin's status. Zero per_second
+	// leaves plugins unlimited. See EventEmission.
+	PluginQuota RateLimit `json:"plugin_quota"`
+
+	// PluginQuotas override PluginQuota for single plugins
+	PluginQuotas map[string]RateLimit `json:"plugin_quotas"`
+}
+
+func (c *EventsConfig) validate() error {
+	switch c.Policy {
+	case "", DeliverDropOldest, DeliverBlock, DeliverSpill:
+	default:
+		return fmt.Errorf("events.policy must be %q, %q or %q, got %q", DeliverDropOldest, DeliverBlock, DeliverSpill, c.Policy)
+	}
+	if c.Buffer < 0 || c.BlockTimeout < 0 {
+		return fmt.Errorf("events.buffer and events.block_timeout must not be negative")
+	}
+	if err := c.PluginQuota.validate("events.plugin_quota"); err != nil {
+		return err
+	}
+	for name, rl := range c.PluginQuotas {
+		if err := rl.validate("events.plugin_quotas." + name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SubscribeOptions configure one subscription. Zero fields take the
+// defaults from EventsConfig.
+type SubscribeOptions struct {
+	// Types selects the events to receive; "plugin.*" matches by prefix.
+	// Empty receives everything.
+	Types []string
+
+	Buffer       int
+	Policy       DeliveryPolicy
+	BlockTimeout time.Duration
+	SpillDir     string
+}
+
+// SubscriptionStats are the delivery metrics of one subscription
+type SubscriptionStats struct {
+	Name      string         `json:"name"`
+	Policy    DeliveryPolicy `json:"policy"`
+	Queued    int            `json:"queued"`
+	Published uint64         `json:"published"`
+	Dropped   uint64         `json:"dropped"`
+	Spilled   uint64         `json:"spilled"`
+}
+
+// EventBus fans host events out to subscribers. Each subscriber has its own
+// buffer and delivery policy, so a slow one neither stalls the host beyond
+// its policy nor delays the others.
+type EventBus struct {
+	subs   map[string]*Subscription
+	cfg    EventsConfig
+	logger *log.Logger
+	mu     sync.RWMutex
+}
+
+func newEventBus(logger *log.Logger) *EventBus {
+	return &EventBus{subs: make(map[string]*Subscription), logger: logger}
+}
+
+// configure sets the defaults for subscriptions created from now on
+func (b *EventBus) configure(cfg EventsConfig) {
+	b.mu.Lock()
+	b.cfg = cfg
+	b.mu.Unlock()
+}
+
+// Subscribe registers a subscriber under a unique name. Read events from
+// the returned subscription's Events channel and Close it when done.
+func (b *EventBus) Subscribe(name string, opts SubscribeOptions) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.subs[name]; exists {
+		return nil, fmt.Errorf("subscription %s already exists", name)
+	}
+
+	if opts.Buffer == 0 {
+		opts.Buffer = b.cfg.Buffer
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = 64
+	}
+	if opts.Policy == "" {
+		opts.Policy = b.cfg.Policy
+	}
+	if opts.Policy == "" {
+		opts.Policy = DeliverDropOldest
+	}
+	if opts.BlockTimeout == 0 {
+		opts.BlockTimeout = time.Duration(b.cfg.BlockTimeout)
+	}
+	if opts.BlockTimeout == 0 {
+		opts.BlockTimeout = time.Second
+	}
+	if opts.SpillDir == "" {
+		opts.SpillDir = b.cfg.SpillDir
+	}
+
+	s := &Subscription{
+		name: name,
+		opts: opts,
+		ch:   make(chan Event, opts.Buffer),
+		bus:  b,
+	}
+	switch opts.Policy {
+	case DeliverDropOldest, DeliverBlock:
+	case DeliverSpill:
+		q, err := newSpillQueue(opts.SpillDir, name)
+		if err != nil {
+			return nil, err
+		}
+		s.spill = q
+		s.stop = make(chan struct{})
+		s.pumpDone = make(chan struct{})
+		go s.pump()
+	default:
+		return nil, fmt.Errorf("unknown delivery policy %q", opts.Policy)
+	}
+
+	b.subs[name] = s
+	return s, nil
+}
+
+// Publish delivers an event to every matching subscriber
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, s := range b.subs {
+		if s.matches(e.Type) {
+			s.deliver(e)
+		}
+	}
+}
+
+// Stats returns the delivery metrics of every subscription, sorted by name
+func (b *EventBus) Stats() []SubscriptionStats {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	stats := make([]SubscriptionStats, 0, len(b.subs))
+	for _, s := range b.subs {
+		stats = append(stats, s.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// closeAll ends every subscription
+func (b *EventBus) closeAll() {
+	b.mu.RLock()
+	subs := make([]*Subscription, 0, len(b.subs))
+	for _, s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+// Subscription is one subscriber's view of the bus
+type Subscription struct {
+	name string
+	opts SubscribeOptions
+	ch   chan Event
+	bus  *EventBus
+
+	spill    *spillQueue
+	stop     chan struct{}
+	pumpDone chan struct{}
+
+	published uint64
+	dropped   uint64
+	spilled   uint64
+	closed    bool
+	mu        sync.Mutex
+}
+
+// Events returns the channel events are delivered on. It is closed by
+// Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Stats returns the subscription's delivery metrics
+func (s *Subscription) Stats() SubscriptionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queued := len(s.ch)
+	if s.spill != nil {
+		queued += s.spill.pending()
+	}
+	return SubscriptionStats{
+		Name:      s.name,
+		Policy:    s.opts.Policy,
+		Queued:    queued,
+		Published: s.published,
+		Dropped:   s.dropped,
+		Spilled:   s.spilled,
+	}
+}
+
+// Close removes the subscription from the bus, closes its channel and
+// deletes its spill queue
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	if s.bus.subs[s.name] == s {
+		delete(s.bus.subs, s.name)
+	}
+	s.bus.mu.Unlock()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	if s.spill != nil {
+		close(s.stop)
+		<-s.pumpDone
+		if err := s.spill.remove(); err != nil {
+			s.bus.logger.Printf("Failed to remove spill queue of subscription %s: %v", s.name, err)
+		}
+	}
+	close(s.ch)
+}
+
+func (s *Subscription) matches(eventType string) bool {
+	if len(s.opts.Types) == 0 {
+		return true
+	}
+	for _, t := range s.opts.Types {
+		if t == eventType || (strings.HasSuffix(t, "*") && strings.HasPrefix(eventType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver hands e to the subscriber according to its policy
+func (s *Subscription) deliver(e Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+	s.published++
+
+	switch s.opts.Policy {
+	case DeliverDropOldest:
+		for {
+			select {
+			case s.ch <- e:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.drop()
+			default:
+			}
+		}
+
+	case DeliverBlock:
+		select {
+		case s.ch <- e:
+			return
+		default:
+		}
+		timer := time.NewTimer(s.opts.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case s.ch <- e:
+		case <-timer.C:
+			s.drop()
+		}
+
+	case DeliverSpill:
+		// Once events are spilled, later ones queue behind them to keep
+		// the order
+		if s.spill.pending() == 0 {
+			select {
+			case s.ch <- e:
+				return
+			default:
+			}
+		}
+		if err := s.spill.push(e); err != nil {
+			s.bus.logger.Printf("Failed to spill event for subscription %s: %v", s.name, err)
+			s.drop()
+			return
+		}
+		s.spilled++
+	}
+}
+
+// drop counts a lost event. The first drop and every thousandth after it
+// are logged so a slow subscriber is noticed without flooding the log.
+// The caller holds s.mu.
+func (s *Subscription) drop() {
+	s.dropped++
+	if s.dropped%1000 == 1 {
+		s.bus.logger.Printf("Subscription %s is not keeping up: %d event(s) dropped", s.name, s.dropped)
+	}
+}
+
+// pump moves spilled events into the channel as the subscriber frees room
+func (s *Subscription) pump() {
+	defer close(s.pumpDone)
+	for {
+		e, ok, err := s.spill.peek(s.stop)
+		if err != nil {
+			s.bus.logger.Printf("Failed to read spilled event for subscription %s: %v", s.name, err)
+		}
+		if !ok {
+			return
+		}
+		select {
+		case s.ch <- e:
+			s.spill.pop()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// spillQueue is a file of JSON lines written at the end and read from the
+// front. It is truncated whenever it runs empty.
+type spillQueue struct {
+	path  string
+	w     *os.File
+	r     *os.File
+	br    *bufio.Reader
+	next  *Event
+	count int
+	ready chan struct{}
+	mu    sync.Mutex
+}
+
+func newSpillQueue(dir, name string) (*spillQueue, error) {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	w, err := os.CreateTemp(dir, "events-"+filepath.Base(name)+"-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill queue: %w", err)
+	}
+	r, err := os.Open(w.Name())
+	if err != nil {
+		w.Close()
+		os.Remove(w.Name())
+		return nil, fmt.Errorf("failed to open spill queue: %w", err)
+	}
+	return &spillQueue{
+		path:  w.Name(),
+		w:     w,
+		r:     r,
+		br:    bufio.NewReader(r),
+		ready: make(chan struct{}, 1),
+	}, nil
+}
+
+func (q *spillQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+func (q *spillQueue) push(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, err := q.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	q.count++
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// peek waits for the oldest spilled event without removing it. It returns
+// false once stop is closed.
+func (q *spillQueue) peek(stop <-chan struct{}) (Event, bool, error) {
+	for {
+		q.mu.Lock()
+		if q.next != nil {
+			e := *q.next
+			q.mu.Unlock()
+			return e, true, nil
+		}
+		if q.count > 0 {
+			line, err := q.br.ReadBytes('\n')
+			if err == nil {
+				var e Event
+				err = json.Unmarshal(line, &e)
+				if err == nil {
+					q.next = &e
+					q.mu.Unlock()
+					continue
+				}
+			}
+			// Skip what cannot be read rather than stopping delivery
+			q.count--
+			q.mu.Unlock()
+			return Event{}, true, err
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-q.ready:
+		case <-stop:
+			return Event{}, false, nil
+		}
+	}
+}
+
+// pop removes the event returned by the last peek
+func (q *spillQueue) pop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.next = nil
+	q.count--
+	if q.count > 0 {
+		return
+	}
+	// Empty: start over so the file does not grow forever
+	if err := q.w.Truncate(0); err == nil {
+		q.w.Seek(0, 0)
+		q.r.Seek(0, 0)
+		q.br.Reset(q.r)
+	}
+}
+
+func (q *spillQueue) remove() error {
+	q.w.Close()
+	q.r.Close()
+	return os.Remove(q.path)
+}
+
+// Events returns the bus the manager publishes plugin lifecycle and
+// execution events on
+func (pm *PluginManager) Events() *EventBus {
+	return pm.events
+}
+
+// EventStats returns the delivery metrics of every event subscription
+func (pm *PluginManager) EventStats() []SubscriptionStats {
+	return pm.events.Stats()
+}
+
+// publishExecuted reports a finished call. Arguments are left out since they
+// may be large or sensitive.
+func (pm *PluginManager) publishExecuted(name string, args map[string]interface{}, err error) {
+	data := map[string]interface{}{}
+	if capability, ok := args[pluginsdk.ArgCapability].(string); ok {
+		data["capability"] = capability
+	}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	pm.events.Publish(Event{Type: EventPluginExecuted, Plugin: name, Data: data})
+}
+
+// subscribePlugin delivers the events a plugin's manifest lists to the
+// plugin, starting it first if it is lazily loaded. Each plugin has its own
+// subscription, so one that handles events slowly only backs up its own
+// queue. The caller holds pm.mu.
+func (pm *PluginManager) subscribePlugin(info *pluginInfo) {
+	if len(info.Events) == 0 || info.subscription != nil {
+		return
+	}
+	sub, err := pm.events.Subscribe("plugin/"+info.Name, SubscribeOptions{Types: info.Events})
+	if err != nil {
+		pm.hostLog.Printf("Failed to subscribe plugin %s to events: %v", info.Name, err)
+		return
+	}
+	info.subscription = sub
+	go pm.forwardEvents(info.Name, sub)
+}
+
+// forwardEvents hands a plugin the events of its subscription until the
+// subscription is closed
+func (pm *PluginManager) forwardEvents(name string, sub *Subscription) {
+	for e := range sub.Events() {
+		if pm.Halted().Halted {
+			continue
+		}
+		if err := pm.ensureRunning(name); err != nil {
+			pm.hostLog.Printf("Failed to deliver %s event to plugin %s: %v", e.Type, name, err)
+			continue
+		}
+
+		pm.mu.RLock()
+		var handler pluginsdk.EventHandler
+		if info, ok := pm.plugins[name]; ok && info.Instance != nil && !info.loading && !info.crashed && !pm.disabled[name] {
+			handler, _ = info.Instance.(pluginsdk.EventHandler)
+		}
+		pm.mu.RUnlock()
+		if handler == nil {
+			continue
+		}
+
+		if err := handler.HandleEvent(pluginsdk.Event(pm.forPlugin(name, e))); err != nil {
+			pm.hostLog.Printf("Failed to deliver %s event to plugin %s: %v", e.Type, name, err)
+		}
+	}
+}
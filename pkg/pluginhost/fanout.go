@@ -0,0 +1,99 @@
+package pluginhost
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// FanOutResult is one plugin's outcome from ExecuteAll
+type FanOutResult struct {
+	Plugin string
+	Result string
+	Err    error
+}
+
+// providers returns the names of all plugins advertising a capability
+func (pm *PluginManager) providers(capability string) []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var names []string
+	for name, info := range pm.plugins {
+		for _, c := range info.Capabilities {
+			if c.Name == capability {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	for _, st := range pm.remotePlugins() {
+		for _, c := range st.Capabilities {
+			if c.Name == capability {
+				names = append(names, st.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// noProvider reports a capability no plugin offers, suggesting the ones
+// with similar names
+func (pm *PluginManager) noProvider(capability string) error {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var names []string
+	caps := make([][]pluginsdk.Capability, 0, len(pm.plugins))
+	for _, info := range pm.plugins {
+		caps = append(caps, info.Capabilities)
+	}
+	for _, st := range pm.remotePlugins() {
+		caps = append(caps, st.Capabilities)
+	}
+	for _, cs := range caps {
+		for _, c := range cs {
+			if !seen[c.Name] {
+				seen[c.Name] = true
+				names = append(names, c.Name)
+			}
+		}
+	}
+	return &LookupError{Kind: ErrCapabilityNotSupported, Capability: capability, Suggestions: suggest(capability, names)}
+}
+
+// ExecuteAll runs a capability on every plugin that advertises it, in
+// parallel, for "all linters" style operations. Each plugin's result or error
+// is reported separately, ordered by plugin name; an error is returned only
+// when no plugin offers the capability.
+func (pm *PluginManager) ExecuteAll(capability string, args map[string]interface{}) ([]FanOutResult, error) {
+	names := pm.providers(capability)
+	if len(names) == 0 {
+		return nil, pm.noProvider(capability)
+	}
+
+	results := make([]FanOutResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		// Each plugin gets its own copy so none can observe another's arguments
+		callArgs := make(map[string]interface{}, len(args)+1)
+		for k, v := range args {
+			callArgs[k] = v
+		}
+		callArgs[pluginsdk.ArgCapability] = capability
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			result, err := pm.ExecutePlugin(name, callArgs)
+			results[i] = FanOutResult{Plugin: name, Result: result, Err: err}
+		}(i, name)
+	}
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,418 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// States of a feature flag
+const (
+	FlagOn   = "on"
+	FlagOff  = "off"
+	FlagDark = "dark"
+)
+
+// EventFlagsChanged is published when the feature flags read from their
+// source change
+const EventFlagsChanged = "flags.changed"
+
+// defaultFlagRefresh is how often the flag source is read again when the
+// config does not say
+const defaultFlagRefresh = 30 * time.Second
+
+// FeatureFlagsConfig locates the feature flags that switch capabilities on
+// and off without unloading their plugins
+type FeatureFlagsConfig struct {
+	// Source is a YAML or JSON flag document, as a file or an http(s) URL
+	// of a flag service; empty uses no flags
+	Source string `json:"source"`
+
+	// Refresh is how often Source is read again; the default is 30s
+	Refresh Duration `json:"refresh"`
+}
+
+func (c *FeatureFlagsConfig) validate() error {
+	if c.Refresh < 0 {
+		return fmt.Errorf("feature_flags.refresh must not be negative")
+	}
+	return nil
+}
+
+func (c *FeatureFlagsConfig) refresh() time.Duration {
+	if c.Refresh > 0 {
+		return time.Duration(c.Refresh)
+	}
+	return defaultFlagRefresh
+}
+
+// FeatureFlag gates the capabilities its pattern matches. A call must pass
+// every flag matching its capability.
+type FeatureFlag struct {
+	// Capability is a "plugin/capability" pattern as in authorization
+	// roles, e.g. "summarizer/summarize" or "summarizer" for all of it
+	Capability string `yaml:"capability" json:"capability"`
+
+	// State is FlagOn, the default; FlagOff, refusing every call; or
+	// FlagDark, refusing calls from anyone not in Allow as if the
+	// capability did not exist
+	State string `yaml:"state" json:"state,omitempty"`
+
+	// Rollout is the percentage of callers a capability that is on is
+	// rolled out to; unset is everyone. Callers are users and services,
+	// or requests for calls made for no one, and stay in or out of a
+	// rollout as long as its percentage does not drop.
+	Rollout *float64 `yaml:"rollout" json:"rollout,omitempty"`
+
+	// Allow lists callers that get the capability whatever its state and
+	// rollout: "user:ada", "service:ci", "namespace:staging" or
+	// "plugin:orchestrator" for calls that plugin makes
+	Allow []string `yaml:"allow" json:"allow,omitempty"`
+
+	// Namespaces override State and Rollout for calls made in a
+	// namespace, see pluginsdk.RequestContext
+	Namespaces map[string]FlagOverride `yaml:"namespaces" json:"namespaces,omitempty"`
+}
+
+// FlagOverride is the state and rollout of a flag in one namespace; what
+// is unset is the flag's own
+type FlagOverride struct {
+	State   string   `yaml:"state" json:"state,omitempty"`
+	Rollout *float64 `yaml:"rollout" json:"rollout,omitempty"`
+}
+
+func (f *FeatureFlag) validate() error {
+	if f.Capability == "" {
+		return fmt.Errorf("capability is required")
+	}
+	if err := validateRule(f.Capability); err != nil {
+		return err
+	}
+	if err := validateFlagState(f.State, f.Rollout); err != nil {
+		return err
+	}
+	for _, caller := range f.Allow {
+		kind, name, _ := strings.Cut(caller, ":")
+		switch {
+		case name == "":
+			return fmt.Errorf("allow: %q is not a caller; use user:<name>, service:<name>, namespace:<name> or plugin:<name>", caller)
+		case kind == "user", kind == "service", kind == "namespace", kind == "plugin":
+		default:
+			return fmt.Errorf("allow: %q is not a caller; use user:<name>, service:<name>, namespace:<name> or plugin:<name>", caller)
+		}
+	}
+	for ns, o := range f.Namespaces {
+		if err := validateFlagState(o.State, o.Rollout); err != nil {
+			return fmt.Errorf("namespaces.%s: %w", ns, err)
+		}
+	}
+	return nil
+}
+
+func validateFlagState(state string, rollout *float64) error {
+	switch state {
+	case "", FlagOn, FlagOff, FlagDark:
+	default:
+		return fmt.Errorf("state must be %q, %q or %q, got %q", FlagOn, FlagOff, FlagDark, state)
+	}
+	if rollout != nil && (*rollout < 0 || *rollout > 100) {
+		return fmt.Errorf("rollout must be a percentage from 0 to 100, got %v", *rollout)
+	}
+	return nil
+}
+
+// in returns the state and rollout of the flag in a namespace
+func (f *FeatureFlag) in(namespace string) (string, *float64) {
+	state, rollout := f.State, f.Rollout
+	if o, ok := f.Namespaces[namespace]; ok && namespace != "" {
+		if o.State != "" {
+			state = o.State
+		}
+		if o.Rollout != nil {
+			rollout = o.Rollout
+		}
+	}
+	if state == "" {
+		state = FlagOn
+	}
+	return state, rollout
+}
+
+// ParseFeatureFlags reads a flag document: flags by name under "flags"
+func ParseFeatureFlags(data []byte) (map[string]FeatureFlag, error) {
+	var doc struct {
+		Flags map[string]FeatureFlag `yaml:"flags"`
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for name, f := range doc.Flags {
+		if err := f.validate(); err != nil {
+			return nil, fmt.Errorf("flag %s: %w", name, err)
+		}
+	}
+	if doc.Flags == nil {
+		doc.Flags = map[string]FeatureFlag{}
+	}
+	return doc.Flags, nil
+}
+
+// FeatureFlagsStatus is what the host knows of its feature flags
+type FeatureFlagsStatus struct {
+	// Source is where Flags were read from
+	Source string                 `json:"source,omitempty"`
+	Flags  map[string]FeatureFlag `json:"flags"`
+
+	// LoadedAt is when the flags were last read; Error why the latest
+	// read failed, in which case the flags read before stay in use
+	LoadedAt time.Time `json:"loaded_at,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// flagStore holds the feature flags read from their source
+type flagStore struct {
+	mu     sync.RWMutex
+	status FeatureFlagsStatus
+
+	// reload serializes reads of the source
+	reload sync.Mutex
+
+	// stop ends the refresh loop
+	stop chan struct{}
+}
+
+// current returns the flags in use
+func (s *flagStore) current() map[string]FeatureFlag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.status.Flags
+}
+
+// FeatureFlags returns the feature flags in use and where they came from
+func (pm *PluginManager) FeatureFlags() FeatureFlagsStatus {
+	pm.featureFlags.mu.RLock()
+	defer pm.featureFlags.mu.RUnlock()
+
+	st := pm.featureFlags.status
+	st.Flags = make(map[string]FeatureFlag, len(pm.featureFlags.status.Flags))
+	for name, f := range pm.featureFlags.status.Flags {
+		st.Flags[name] = f
+	}
+	return st
+}
+
+// ReloadFeatureFlags reads the flag source now rather than at the next
+// refresh. A source that cannot be read or parsed leaves the flags as
+// they were.
+func (pm *PluginManager) ReloadFeatureFlags() error {
+	return pm.loadFlags(pm.Config().FeatureFlags.Source)
+}
+
+// applyFeatureFlags reads the flags of a new source and keeps them
+// refreshed
+func (pm *PluginManager) applyFeatureFlags(cfg FeatureFlagsConfig) {
+	pm.featureFlags.mu.RLock()
+	source := pm.featureFlags.status.Source
+	pm.featureFlags.mu.RUnlock()
+
+	if cfg.Source != source {
+		if err := pm.loadFlags(cfg.Source); err != nil {
+			pm.hostLog.Printf("Failed to load feature flags: %v", err)
+		}
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	switch {
+	case cfg.Source != "" && pm.featureFlags.stop == nil:
+		pm.featureFlags.stop = make(chan struct{})
+		go pm.flagLoop(pm.featureFlags.stop)
+	case cfg.Source == "" && pm.featureFlags.stop != nil:
+		close(pm.featureFlags.stop)
+		pm.featureFlags.stop = nil
+	}
+}
+
+// flagLoop reads the flag source again every refresh until stop is closed
+func (pm *PluginManager) flagLoop(stop chan struct{}) {
+	for {
+		cfg := pm.Config().FeatureFlags
+		if cfg.Source == "" {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(cfg.refresh()):
+		}
+		if err := pm.loadFlags(pm.Config().FeatureFlags.Source); err != nil {
+			pm.hostLog.Printf("Failed to refresh feature flags: %v", err)
+		}
+	}
+}
+
+// loadFlags reads the flags of source and puts them in use, publishing
+// EventFlagsChanged when they differ from those before. The flags in use
+// stay when source fails, even if it is a new one; "" clears them.
+func (pm *PluginManager) loadFlags(source string) error {
+	s := &pm.featureFlags
+	s.reload.Lock()
+	defer s.reload.Unlock()
+
+	var flags map[string]FeatureFlag
+	var err error
+	if source != "" {
+		var data []byte
+		client := &http.Client{Timeout: 10 * time.Second}
+		if data, err = fetch(client, source); err == nil {
+			flags, err = ParseFeatureFlags(data)
+		}
+		if err != nil {
+			err = fmt.Errorf("feature flags %s: %w", source, err)
+		}
+	}
+
+	s.mu.Lock()
+	prev := s.status
+	if err != nil {
+		s.status.Error = err.Error()
+		s.mu.Unlock()
+		return err
+	}
+	s.status = FeatureFlagsStatus{Source: source, Flags: flags, LoadedAt: time.Now()}
+	s.mu.Unlock()
+
+	if reflect.DeepEqual(prev.Flags, flags) || len(prev.Flags) == 0 && len(flags) == 0 {
+		return nil
+	}
+	pm.hostLog.Printf("Feature flags changed: %d flag(s) from %s", len(flags), source)
+	pm.events.Publish(Event{Type: EventFlagsChanged, Data: map[string]interface{}{
+		"source": source,
+		"flags":  len(flags),
+	}})
+	return nil
+}
+
+// flagCaller is who a call is made by, as feature flags see it
+type flagCaller struct {
+	// ids are how Allow may name the caller
+	ids []string
+
+	// namespace is the namespace of the call's request
+	namespace string
+
+	// key places the caller in rollouts
+	key string
+}
+
+// flagCallerOf returns who a call is made by, taken from its running
+// request so arguments cannot claim a namespace or user
+func (pm *PluginManager) flagCallerOf(args map[string]interface{}) flagCaller {
+	var c flagCaller
+	principal := pm.PrincipalOf(args)
+	if principal.Name != "" {
+		c.ids = append(c.ids, principal.String())
+		c.key = principal.String()
+	}
+	if id := pluginsdk.ContextOf(args).RequestID; id != "" {
+		if rc, ok := pm.requests.context(id); ok && rc.Namespace != "" {
+			c.namespace = rc.Namespace
+			c.ids = append(c.ids, "namespace:"+rc.Namespace)
+		}
+		if c.key == "" {
+			c.key = "request:" + id
+		}
+	}
+	if caller, _ := args[pluginsdk.ArgCaller].(string); caller != "" {
+		c.ids = append(c.ids, "plugin:"+caller)
+		if c.key == "" {
+			c.key = "plugin:" + caller
+		}
+	}
+	if c.key == "" {
+		c.key = PrincipalAnonymous
+	}
+	return c
+}
+
+// checkFlags refuses a call the feature flags matching its capability do
+// not let through
+func (pm *PluginManager) checkFlags(name string, args map[string]interface{}) error {
+	flags := pm.featureFlags.current()
+	if len(flags) == 0 {
+		return nil
+	}
+	ref, _ := args[pluginsdk.ArgCapability].(string)
+	capability, _ := pluginsdk.ParseCapabilityRef(ref)
+
+	names := make([]string, 0, len(flags))
+	for flag, f := range flags {
+		if matchRule(f.Capability, name, capability) {
+			names = append(names, flag)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	caller := pm.flagCallerOf(args)
+	label := capabilityLabel(name, capability)
+	for _, flag := range names {
+		f := flags[flag]
+		if containsAny(f.Allow, caller.ids) {
+			continue
+		}
+		state, rollout := f.in(caller.namespace)
+		switch {
+		case state == FlagOff:
+			return &pluginsdk.PluginError{
+				Code:    pluginsdk.CodeUnavailable,
+				Message: fmt.Sprintf("%s is switched off by feature flag %s", label, flag),
+				Details: map[string]string{"feature_flag": flag},
+			}
+		case state == FlagDark:
+			return pluginsdk.NewError(pluginsdk.CodeNotFound, "%s is not available", label)
+		case rollout != nil && !inRollout(flag, caller.key, *rollout):
+			return &pluginsdk.PluginError{
+				Code:    pluginsdk.CodeUnavailable,
+				Message: fmt.Sprintf("%s is not rolled out to this caller yet (feature flag %s)", label, flag),
+				Details: map[string]string{"feature_flag": flag},
+			}
+		}
+	}
+	return nil
+}
+
+// inRollout reports whether a caller falls within the first percent of a
+// flag's callers. Each flag orders callers differently, so the same
+// callers are not always the first to get new capabilities.
+func inRollout(flag, key string, percent float64) bool {
+	h := fnv.New64a()
+	h.Write([]byte(flag + "\x00" + key))
+	return float64(h.Sum64()%10000) < percent*100
+}
+
+// containsAny reports whether list holds any of values
+func containsAny(list, values []string) bool {
+	for _, v := range values {
+		if containsString(list, v) {
+			return true
+		}
+	}
+	return false
+}
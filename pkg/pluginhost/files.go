@@ -0,0 +1,627 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+const (
+	// DefaultFileMaxSize bounds the files plugins read and write through
+	// the host when the config sets no limit
+	DefaultFileMaxSize = 1 << 20
+
+	// DefaultWatchInterval is how often watched files are checked when the
+	// config sets no interval
+	DefaultWatchInterval = 2 * time.Second
+
+	// maxGlobFiles bounds the files a glob or watch may match
+	maxGlobFiles = 10000
+
+	// maxWatches bounds the watches one plugin may keep
+	maxWatches = 32
+)
+
+// Access levels of the file roots granted in manifests
+const (
+	FileRead  = "read"
+	FileWrite = "write"
+)
+
+// FilesConfig sets the directories plugins may work with through the host
+// services, the sandboxed ones in particular. A plugin's manifest asks for
+// roots by name in permissions.files, e.g. ["project:write"].
+type FilesConfig struct {
+	// Roots are the directories plugins may be granted, by name
+	Roots map[string]FileRoot `json:"roots"`
+
+	// MaxSize bounds a file read or written, in bytes; defaults to
+	// DefaultFileMaxSize
+	MaxSize int64 `json:"max_size"`
+
+	// WatchInterval is how often watched files are checked; defaults to
+	// DefaultWatchInterval
+	WatchInterval Duration `json:"watch_interval"`
+
+	// AuditLog is a file every operation is appended to as JSON lines
+	AuditLog string `json:"audit_log"`
+}
+
+// FileRoot is a directory plugins may be granted
+type FileRoot struct {
+	Path string `json:"path"`
+
+	// ReadOnly refuses writes whatever the manifest asks for
+	ReadOnly bool `json:"read_only"`
+}
+
+func (c *FilesConfig) validate() error {
+	for name, root := range c.Roots {
+		if name == "" || strings.Contains(name, ":") || root.Path == "" {
+			return fmt.Errorf("files.roots.%s needs a name without \":\" and a path", name)
+		}
+	}
+	if c.MaxSize < 0 || c.WatchInterval < 0 {
+		return fmt.Errorf("files.max_size and files.watch_interval must not be negative")
+	}
+	return nil
+}
+
+func (c FilesConfig) maxSize() int64 {
+	if c.MaxSize == 0 {
+		return DefaultFileMaxSize
+	}
+	return c.MaxSize
+}
+
+func (c FilesConfig) watchInterval() time.Duration {
+	if c.WatchInterval == 0 {
+		return DefaultWatchInterval
+	}
+	return time.Duration(c.WatchInterval)
+}
+
+// parseFileGrant splits a manifest grant such as "project:write" into the
+// root and its access, which defaults to FileRead
+func parseFileGrant(grant string) (string, string, error) {
+	root, access, _ := strings.Cut(grant, ":")
+	if access == "" {
+		access = FileRead
+	}
+	if root == "" || (access != FileRead && access != FileWrite) {
+		return "", "", fmt.Errorf("permissions.files: %q is not a grant; use <root>, <root>:read or <root>:write", grant)
+	}
+	return root, access, nil
+}
+
+// FileAuditRecord is one file operation a plugin asked the host for
+type FileAuditRecord struct {
+	Time   time.Time `json:"time"`
+	Plugin string    `json:"plugin"`
+
+	// Op is "read", "write", "glob", "watch" or "unwatch", and Path the
+	// path, pattern or watch ID it was given
+	Op   string `json:"op"`
+	Path string `json:"path"`
+
+	Allowed bool   `json:"allowed"`
+	Bytes   int    `json:"bytes,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// grantedRoot is a root a plugin may use, resolved to its real path
+type grantedRoot struct {
+	name  string
+	dir   string
+	write bool
+}
+
+// fileScope is what a plugin may do with files
+type fileScope struct {
+	plugin string
+	roots  []grantedRoot
+}
+
+// fileScopeOf returns the scope of the plugin running the binary at path.
+// Grants naming roots the config lacks are left out.
+func (pm *PluginManager) fileScopeOf(binary string) (*fileScope, error) {
+	pm.mu.RLock()
+	var plugin string
+	var grants []string
+	for _, info := range pm.plugins {
+		if info.Path == binary {
+			plugin, grants = info.Name, info.Files
+			break
+		}
+	}
+	roots := pm.config.Files.Roots
+	pm.mu.RUnlock()
+	if plugin == "" {
+		return nil, pluginsdk.NewError(pluginsdk.CodeUnavailable, "calling plugin is not registered")
+	}
+
+	scope := &fileScope{plugin: plugin}
+	for _, grant := range grants {
+		name, access, err := parseFileGrant(grant)
+		if err != nil {
+			continue
+		}
+		root, ok := roots[name]
+		if !ok {
+			continue
+		}
+		dir, err := realPath(root.Path)
+		if err != nil {
+			continue
+		}
+		scope.roots = append(scope.roots, grantedRoot{name: name, dir: dir, write: access == FileWrite && !root.ReadOnly})
+	}
+	if len(scope.roots) == 0 {
+		return scope, pluginsdk.NewError(pluginsdk.CodePermissionDenied, "plugin %s has no file roots; grant them with permissions.files in its manifest and files.roots in the host config", plugin)
+	}
+	return scope, nil
+}
+
+// resolve returns the real path of p, relative paths taken from the first
+// root, and checks that it lies under a root granting the access
+func (s *fileScope) resolve(p string, write bool) (string, error) {
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.roots[0].dir, p)
+	}
+	real, err := realPath(p)
+	if err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "invalid path %s: %v", p, err)
+	}
+	outside := true
+	for _, root := range s.roots {
+		if !within(root.dir, real) {
+			continue
+		}
+		outside = false
+		if !write || root.write {
+			return real, nil
+		}
+	}
+	if outside {
+		return "", pluginsdk.NewError(pluginsdk.CodePermissionDenied, "%s is outside the file roots of plugin %s", p, s.plugin)
+	}
+	return "", pluginsdk.NewError(pluginsdk.CodePermissionDenied, "plugin %s may not write %s; its root is granted for reading", s.plugin, p)
+}
+
+// realPath makes p absolute and resolves its symbolic links, including
+// those of the directories above a file that does not exist yet, so links
+// cannot lead out of a root
+func realPath(p string) (string, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		real, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || dir == filepath.Dir(dir) {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// within reports whether p is dir or below it
+func within(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// recordFileAccess appends an operation to the file audit log, if one is
+// kept, and logs refusals
+func (pm *PluginManager) recordFileAccess(r FileAuditRecord, err error) {
+	r.Time, r.Allowed = time.Now(), err == nil
+	if err != nil {
+		r.Error = err.Error()
+		if pluginsdk.AsPluginError(err).Code == pluginsdk.CodePermissionDenied {
+			pm.hostLog.Printf("Refused %s of %s by plugin %s: %v", r.Op, r.Path, r.Plugin, err)
+		}
+	}
+	pm.mu.RLock()
+	audit := pm.fileAudit
+	pm.mu.RUnlock()
+	if audit == nil {
+		return
+	}
+	if err := audit.write(r); err != nil {
+		pm.hostLog.Printf("Failed to record file access: %v", err)
+	}
+}
+
+// applyFileAudit opens, switches or closes the file audit log to match cfg
+func (pm *PluginManager) applyFileAudit(cfg FilesConfig) error {
+	pm.mu.RLock()
+	current := pm.fileAudit
+	pm.mu.RUnlock()
+	if current != nil && current.path == cfg.AuditLog {
+		return nil
+	}
+
+	var next *auditWriter
+	if cfg.AuditLog != "" {
+		var err error
+		if next, err = openAudit(cfg.AuditLog); err != nil {
+			return err
+		}
+		pm.hostLog.Printf("Recording plugin file access to %s", cfg.AuditLog)
+	}
+
+	pm.mu.Lock()
+	pm.fileAudit = next
+	pm.mu.Unlock()
+
+	if current != nil {
+		if err := current.close(); err != nil {
+			pm.hostLog.Printf("Failed to close file audit log %s: %v", current.path, err)
+		}
+	}
+	return nil
+}
+
+func (h *pluginHost) ReadFile(p string) ([]byte, error) {
+	rec := FileAuditRecord{Op: "read", Path: p}
+	data, err := h.readFile(&rec, p)
+	rec.Bytes = len(data)
+	h.pm.recordFileAccess(rec, err)
+	return data, err
+}
+
+func (h *pluginHost) readFile(rec *FileAuditRecord, p string) ([]byte, error) {
+	scope, err := h.pm.fileScopeOf(h.path)
+	if scope != nil {
+		rec.Plugin = scope.plugin
+	}
+	if err != nil {
+		return nil, err
+	}
+	real, err := scope.resolve(p, false)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(real)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, pluginsdk.NewError(pluginsdk.CodeNotFound, "file not found: %s", p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "%s is a directory", p)
+	}
+	if max := h.pm.Config().Files.maxSize(); fi.Size() > max {
+		return nil, pluginsdk.NewError(pluginsdk.CodeTooLarge, "%s has %d bytes, more than files.max_size (%d)", p, fi.Size(), max)
+	}
+	return os.ReadFile(real)
+}
+
+func (h *pluginHost) WriteFile(p string, data []byte) error {
+	rec := FileAuditRecord{Op: "write", Path: p, Bytes: len(data)}
+	err := h.writeFile(&rec, p, data)
+	h.pm.recordFileAccess(rec, err)
+	return err
+}
+
+func (h *pluginHost) writeFile(rec *FileAuditRecord, p string, data []byte) error {
+	scope, err := h.pm.fileScopeOf(h.path)
+	if scope != nil {
+		rec.Plugin = scope.plugin
+	}
+	if err != nil {
+		return err
+	}
+	real, err := scope.resolve(p, true)
+	if err != nil {
+		return err
+	}
+	if max := h.pm.Config().Files.maxSize(); int64(len(data)) > max {
+		return pluginsdk.NewError(pluginsdk.CodeTooLarge, "%d bytes are more than files.max_size (%d)", len(data), max)
+	}
+
+	// Replace the file in one step, keeping its mode
+	mode := fs.FileMode(0o644)
+	if fi, err := os.Stat(real); err == nil {
+		if fi.IsDir() {
+			return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "%s is a directory", p)
+		}
+		mode = fi.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(real), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(real), filepath.Base(real)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), real)
+}
+
+func (h *pluginHost) GlobFiles(pattern string) ([]string, error) {
+	rec := FileAuditRecord{Op: "glob", Path: pattern}
+	var paths []string
+	scope, err := h.pm.fileScopeOf(h.path)
+	if scope != nil {
+		rec.Plugin = scope.plugin
+	}
+	if err == nil {
+		paths, err = scope.glob(pattern)
+	}
+	h.pm.recordFileAccess(rec, err)
+	return paths, err
+}
+
+// glob returns the real paths of the files matching pattern, which is
+// relative to the first root unless absolute. The directories before the
+// first segment with wildcards must lie under a root.
+func (s *fileScope) glob(pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(s.roots[0].dir, pattern)
+	}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	fixed := 0
+	for fixed < len(parts)-1 && !strings.ContainsAny(parts[fixed], "*?[") {
+		fixed++
+	}
+	base, err := s.resolve(filepath.FromSlash(strings.Join(parts[:fixed], "/")), false)
+	if err != nil {
+		return nil, err
+	}
+	rest := strings.Join(parts[fixed:], "/")
+	if _, err := path.Match(strings.ReplaceAll(rest, "**", "*"), ""); err != nil {
+		return nil, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "invalid pattern %s: %v", pattern, err)
+	}
+
+	var matches []string
+	err = filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(base, p)
+		if err != nil || !matchGlob(rest, filepath.ToSlash(rel)) {
+			return nil
+		}
+		if len(matches) == maxGlobFiles {
+			return pluginsdk.NewError(pluginsdk.CodeTooLarge, "%s matches more than %d files", pattern, maxGlobFiles)
+		}
+		matches = append(matches, p)
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// matchGlob reports whether the slash-separated name matches pattern,
+// where a "**" segment matches any number of directories
+func matchGlob(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// fileWatch is a pattern a plugin watches
+type fileWatch struct {
+	id      string
+	plugin  string
+	pattern string
+	stop    chan struct{}
+}
+
+// watchRegistry holds the file watches of all plugins by ID
+type watchRegistry struct {
+	watches map[string]*fileWatch
+	mu      sync.Mutex
+}
+
+// fileStamp is what a watch compares to notice a change
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func (h *pluginHost) WatchFiles(pattern string) (string, error) {
+	rec := FileAuditRecord{Op: "watch", Path: pattern}
+	id, err := h.watchFiles(&rec, pattern)
+	h.pm.recordFileAccess(rec, err)
+	return id, err
+}
+
+func (h *pluginHost) watchFiles(rec *FileAuditRecord, pattern string) (string, error) {
+	pm := h.pm
+	scope, err := pm.fileScopeOf(h.path)
+	if scope != nil {
+		rec.Plugin = scope.plugin
+	}
+	if err != nil {
+		return "", err
+	}
+	stamps, err := scope.stamps(pattern)
+	if err != nil {
+		return "", err
+	}
+	id, err := newSessionID()
+	if err != nil {
+		return "", err
+	}
+	w := &fileWatch{id: id, plugin: scope.plugin, pattern: pattern, stop: make(chan struct{})}
+
+	pm.watches.mu.Lock()
+	n := 0
+	for _, other := range pm.watches.watches {
+		if other.plugin == w.plugin {
+			n++
+		}
+	}
+	if n >= maxWatches {
+		pm.watches.mu.Unlock()
+		return "", pluginsdk.NewError(pluginsdk.CodeTooLarge, "plugin %s keeps %d watches already", w.plugin, n)
+	}
+	if pm.watches.watches == nil {
+		pm.watches.watches = make(map[string]*fileWatch)
+	}
+	pm.watches.watches[id] = w
+	pm.watches.mu.Unlock()
+
+	go pm.watch(w, scope, stamps)
+	return id, nil
+}
+
+func (h *pluginHost) UnwatchFiles(id string) error {
+	pm := h.pm
+	rec := FileAuditRecord{Op: "unwatch", Path: id}
+	scope, err := pm.fileScopeOf(h.path)
+	if scope != nil {
+		rec.Plugin = scope.plugin
+	}
+	if err == nil {
+		pm.watches.mu.Lock()
+		w, ok := pm.watches.watches[id]
+		if ok && w.plugin == scope.plugin {
+			delete(pm.watches.watches, id)
+			close(w.stop)
+		} else {
+			err = pluginsdk.NewError(pluginsdk.CodeNotFound, "no watch %s", id)
+		}
+		pm.watches.mu.Unlock()
+	}
+	pm.recordFileAccess(rec, err)
+	return err
+}
+
+// stopWatches ends the watches of plugin, e.g. when it is unloaded; an
+// empty plugin ends all of them
+func (pm *PluginManager) stopWatches(plugin string) {
+	pm.watches.mu.Lock()
+	defer pm.watches.mu.Unlock()
+
+	for id, w := range pm.watches.watches {
+		if plugin == "" || w.plugin == plugin {
+			delete(pm.watches.watches, id)
+			close(w.stop)
+		}
+	}
+}
+
+// stamps returns the stamps of the files matching pattern
+func (s *fileScope) stamps(pattern string) (map[string]fileStamp, error) {
+	paths, err := s.glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, p := range paths {
+		if fi, err := os.Stat(p); err == nil {
+			stamps[p] = fileStamp{mod: fi.ModTime(), size: fi.Size()}
+		}
+	}
+	return stamps, nil
+}
+
+// watch polls the files of a watch until it is stopped and sends the
+// plugin an EventFilesChanged event for each round that found changes
+func (pm *PluginManager) watch(w *fileWatch, scope *fileScope, prev map[string]fileStamp) {
+	ticker := time.NewTicker(pm.Config().Files.watchInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		next, err := scope.stamps(w.pattern)
+		if err != nil {
+			pm.hostLog.Printf("Failed to check files %s watched by plugin %s: %v", w.pattern, w.plugin, err)
+			continue
+		}
+		var changes []interface{}
+		for p, stamp := range next {
+			old, ok := prev[p]
+			switch {
+			case !ok:
+				changes = append(changes, map[string]interface{}{"path": p, "change": pluginsdk.FileCreated})
+			case old != stamp:
+				changes = append(changes, map[string]interface{}{"path": p, "change": pluginsdk.FileModified})
+			}
+		}
+		for p := range prev {
+			if _, ok := next[p]; !ok {
+				changes = append(changes, map[string]interface{}{"path": p, "change": pluginsdk.FileRemoved})
+			}
+		}
+		prev = next
+		if len(changes) == 0 || pm.Halted().Halted {
+			continue
+		}
+
+		pm.mu.RLock()
+		var handler pluginsdk.EventHandler
+		if info, ok := pm.plugins[w.plugin]; ok && info.Instance != nil && !info.loading && !info.crashed && !pm.disabled[w.plugin] {
+			handler, _ = info.Instance.(pluginsdk.EventHandler)
+		}
+		pm.mu.RUnlock()
+		if handler == nil {
+			continue
+		}
+		e := pluginsdk.Event{Type: pluginsdk.EventFilesChanged, Plugin: w.plugin, Time: time.Now(), Data: map[string]interface{}{
+			"watch":   w.id,
+			"pattern": w.pattern,
+			"changes": changes,
+		}}
+		if err := handler.HandleEvent(e); err != nil {
+			pm.hostLog.Printf("Failed to deliver %s event to plugin %s: %v", e.Type, w.plugin, err)
+		}
+	}
+}
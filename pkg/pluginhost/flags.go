@@ -0,0 +1,269 @@
+package pluginhost
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// FlagSpec maps one SuperClaude flag onto an execution option
+type FlagSpec struct {
+	// Name is the canonical flag without dashes, e.g. "think-hard"
+	Name string
+
+	// Aliases are alternative spellings, e.g. "uc" for "ultracompressed"
+	Aliases []string
+
+	// Option is the key set in the options passed to plugins
+	Option string
+
+	// Value is stored under Option; nil stores true
+	Value interface{}
+
+	// List appends Value to a list under Option instead of replacing it,
+	// so several flags can contribute, e.g. one per MCP server
+	List bool
+
+	// Prefix makes Name match any flag starting with it and stores the rest
+	// as the value, e.g. "persona-" turns --persona-architect into
+	// persona=architect
+	Prefix bool
+
+	Description string
+}
+
+// FlagRegistry normalizes SuperClaude's universal flags (--think, --uc,
+// --seq, ...) into structured options for plugins
+type FlagRegistry struct {
+	specs    map[string]*FlagSpec
+	prefixes []*FlagSpec
+	logger   *log.Logger
+	mu       sync.RWMutex
+}
+
+// defaultFlags are the SuperClaude universal flags
+var defaultFlags = []FlagSpec{
+	{Name: "think", Option: "think", Value: "standard", Description: "Multi-file analysis"},
+	{Name: "think-hard", Option: "think", Value: "hard", Description: "Deep architectural analysis"},
+	{Name: "ultrathink", Option: "think", Value: "ultra", Description: "Critical system redesign analysis"},
+	{Name: "ultracompressed", Aliases: []string{"uc"}, Option: "compressed", Description: "Token-optimized output"},
+	{Name: "sequential", Aliases: []string{"seq"}, Option: "mcp", Value: "sequential", List: true, Description: "Use the Sequential MCP server"},
+	{Name: "context7", Aliases: []string{"c7"}, Option: "mcp", Value: "context7", List: true, Description: "Use the Context7 MCP server"},
+	{Name: "magic", Option: "mcp", Value: "magic", List: true, Description: "Use the Magic MCP server"},
+	{Name: "playwright", Aliases: []string{"play"}, Option: "mcp", Value: "playwright", List: true, Description: "Use the Playwright MCP server"},
+	{Name: "all-mcp", Option: "mcp", Value: []interface{}{"sequential", "context7", "magic", "playwright"}, List: true, Description: "Use every MCP server"},
+	{Name: "no-mcp", Option: "no_mcp", Description: "Use no MCP servers"},
+	{Name: "persona-", Option: "persona", Prefix: true, Description: "Activate a persona, e.g. --persona-architect"},
+	{Name: "plan", Option: pluginsdk.ArgDryRun, Description: "Describe what would be done without doing it"},
+	{Name: "validate", Option: "validate", Description: "Validate before executing"},
+	{Name: "safe-mode", Option: "safe_mode", Description: "Maximum validation, conservative execution"},
+	{Name: "verbose", Option: "verbose", Description: "Detailed output"},
+	{Name: "introspect", Aliases: []string{"introspection"}, Option: "introspect", Description: "Explain the reasoning process"},
+}
+
+// NewFlagRegistry returns a registry holding the SuperClaude universal flags
+func NewFlagRegistry() *FlagRegistry {
+	r := &FlagRegistry{specs: make(map[string]*FlagSpec), logger: log.Default()}
+	for _, spec := range defaultFlags {
+		if err := r.Register(spec); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a flag. Names and aliases must not already be registered.
+func (r *FlagRegistry) Register(spec FlagSpec) error {
+	if spec.Name == "" || spec.Option == "" {
+		return fmt.Errorf("flag needs a name and an option")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := &spec
+	if spec.Prefix {
+		for _, p := range r.prefixes {
+			if p.Name == spec.Name {
+				return fmt.Errorf("flag --%s is already registered", spec.Name)
+			}
+		}
+		r.prefixes = append(r.prefixes, s)
+		return nil
+	}
+	for _, name := range append([]string{spec.Name}, spec.Aliases...) {
+		if _, exists := r.specs[name]; exists {
+			return fmt.Errorf("flag --%s is already registered", name)
+		}
+	}
+	for _, name := range append([]string{spec.Name}, spec.Aliases...) {
+		r.specs[name] = s
+	}
+	return nil
+}
+
+// Flags returns every registered flag sorted by name
+func (r *FlagRegistry) Flags() []FlagSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	seen := make(map[*FlagSpec]bool)
+	var flags []FlagSpec
+	for _, s := range r.specs {
+		if !seen[s] {
+			seen[s] = true
+			flags = append(flags, *s)
+		}
+	}
+	for _, s := range r.prefixes {
+		flags = append(flags, *s)
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
+// resolve finds the spec for one flag and the value it sets
+func (r *FlagRegistry) resolve(flag string) (*FlagSpec, interface{}, error) {
+	name := strings.TrimLeft(flag, "-")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s, ok := r.specs[name]; ok {
+		if s.Value == nil {
+			return s, true, nil
+		}
+		return s, s.Value, nil
+	}
+	for _, s := range r.prefixes {
+		if value := strings.TrimPrefix(name, s.Name); value != name && value != "" {
+			return s, value, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown flag: %s", flag)
+}
+
+// canonical returns the canonical name for a flag or alias, or the name
+// unchanged if it is not registered
+func (r *FlagRegistry) canonical(name string) string {
+	name = strings.TrimLeft(name, "-")
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if s, ok := r.specs[name]; ok {
+		return s.Name
+	}
+	return name
+}
+
+// Normalize turns flags such as "--think-hard" or "--uc" into options.
+// When honored is not nil, flags outside it are dropped with a log line;
+// honored may use canonical names or aliases. Unknown flags are an error.
+func (r *FlagRegistry) Normalize(flags []string, honored []string) (map[string]interface{}, error) {
+	allowed := make(map[string]bool)
+	for _, name := range honored {
+		allowed[r.canonical(name)] = true
+	}
+
+	options := make(map[string]interface{})
+	for _, flag := range flags {
+		spec, value, err := r.resolve(flag)
+		if err != nil {
+			return nil, err
+		}
+		if honored != nil && !allowed[spec.Name] {
+			r.logger.Printf("Ignoring flag %s: not honored by the plugin", flag)
+			continue
+		}
+
+		if !spec.List {
+			options[spec.Option] = value
+			continue
+		}
+		list, _ := options[spec.Option].([]interface{})
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			if !containsValue(list, v) {
+				list = append(list, v)
+			}
+		}
+		options[spec.Option] = list
+	}
+
+	// --no-mcp wins over any server flags
+	if options["no_mcp"] == true {
+		delete(options, "mcp")
+	}
+	return options, nil
+}
+
+func containsValue(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Flags returns the flag registry, which embedders may extend with their
+// own flags
+func (pm *PluginManager) Flags() *FlagRegistry {
+	return pm.flags
+}
+
+// ExecuteWithFlags runs a plugin with SuperClaude flags normalized into
+// the reserved options argument. Only the flags the plugin declares in its
+// manifest are passed; a plugin without a declaration receives none. --plan
+// is the exception: it always makes the call a dry run, see PlanPlugin.
+func (pm *PluginManager) ExecuteWithFlags(name string, args map[string]interface{}, flags []string) (string, error) {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	var honored []string
+	if exists {
+		honored = append([]string{}, info.Flags...)
+	}
+	pm.mu.RUnlock()
+	if !exists {
+		return "", pm.pluginNotFound(name)
+	}
+
+	// --plan makes the call a dry run whether or not the plugin declares
+	// it, so a plan is never answered by executing
+	dryRun := false
+	var rest []string
+	for _, flag := range flags {
+		if pm.flags.canonical(flag) == planFlag {
+			dryRun = true
+			continue
+		}
+		rest = append(rest, flag)
+	}
+
+	options, err := pm.flags.Normalize(rest, honored)
+	if err != nil {
+		return "", &pluginsdk.PluginError{Code: pluginsdk.CodeInvalidArgument, Message: err.Error()}
+	}
+
+	callArgs := make(map[string]interface{}, len(args)+2)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	if dryRun {
+		callArgs[pluginsdk.ArgDryRun] = true
+	}
+	if len(options) > 0 {
+		callArgs[pluginsdk.ArgOptions] = options
+	}
+	return pm.ExecutePlugin(name, callArgs)
+}
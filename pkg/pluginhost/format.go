@@ -0,0 +1,130 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// converters turn results from one format into another, for callers that
+// do not accept the format a capability answers in
+var converters = map[[2]string]func(string) (string, error){
+	{pluginsdk.FormatJSON, pluginsdk.FormatText}: prettyJSON,
+	{pluginsdk.FormatJSON, pluginsdk.FormatMarkdown}: func(result string) (string, error) {
+		pretty, err := prettyJSON(result)
+		if err != nil {
+			return "", err
+		}
+		return fence("json", pretty), nil
+	},
+	{pluginsdk.FormatMarkdown, pluginsdk.FormatText}: func(result string) (string, error) {
+		return renderMarkdown(result, TransformCall{})
+	},
+	{pluginsdk.FormatDiff, pluginsdk.FormatText}: func(result string) (string, error) {
+		return result, nil
+	},
+	{pluginsdk.FormatDiff, pluginsdk.FormatMarkdown}: func(result string) (string, error) {
+		return fence("diff", result), nil
+	},
+	{pluginsdk.FormatText, pluginsdk.FormatMarkdown}: func(result string) (string, error) {
+		return fence("", result), nil
+	},
+}
+
+// ResultFormat returns the format ExecutePlugin answers a call in: the one
+// the plugin answers in when the call accepts it, else the first accepted
+// format the host can convert it to. It is empty for plugins of remote
+// hosts, which negotiate the format themselves.
+func (pm *PluginManager) ResultFormat(name string, args map[string]interface{}) string {
+	_, to := pm.resultFormats(name, args)
+	return to
+}
+
+// resultFormats returns the format the plugin answers a call in and the
+// one the caller gets; to is empty when no accepted format can be made
+// from it, and both are empty for plugins the host does not run
+func (pm *PluginManager) resultFormats(name string, args map[string]interface{}) (from, to string) {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	var formats []string
+	if exists {
+		ref, _ := args[pluginsdk.ArgCapability].(string)
+		capability, version := pluginsdk.ParseCapabilityRef(ref)
+		if v, ok := args[pluginsdk.ArgCapabilityVersion].(string); ok && version == "" {
+			version = v
+		}
+		if c := findCapability(info.Capabilities, capability, version); c != nil {
+			formats = c.Formats
+		}
+	}
+	pm.mu.RUnlock()
+	if !exists {
+		return "", ""
+	}
+
+	from = pluginsdk.ResultFormat(args, formats)
+	accepted := pluginsdk.Accepted(args)
+	if len(accepted) == 0 || containsString(accepted, from) {
+		return from, from
+	}
+	for _, f := range accepted {
+		if converters[[2]string{from, f}] != nil {
+			return from, f
+		}
+	}
+	return from, ""
+}
+
+// checkFormat refuses a call before it runs when the caller accepts no
+// format the host can give it
+func (pm *PluginManager) checkFormat(name string, args map[string]interface{}) error {
+	if from, to := pm.resultFormats(name, args); from != "" && to == "" {
+		return pluginsdk.NewError(pluginsdk.CodeUnsupported, "plugin %s answers in %s, which cannot be converted to %s", name, from, strings.Join(pluginsdk.Accepted(args), " or "))
+	}
+	return nil
+}
+
+// convertResult converts the result of a call into the format the caller
+// accepts, when the plugin answered in another
+func (pm *PluginManager) convertResult(name string, args map[string]interface{}, result string) (string, error) {
+	if _, spilled := pluginsdk.ResultFile(result); spilled {
+		return result, nil
+	}
+	from, to := pm.resultFormats(name, args)
+	if from == to || to == "" {
+		return result, nil
+	}
+	converted, err := converters[[2]string{from, to}](result)
+	if err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeInternal, "failed to convert the result of %s from %s to %s: %v", name, from, to, err)
+	}
+	return converted, nil
+}
+
+// prettyJSON indents a JSON document for reading
+func prettyJSON(result string) (string, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(result), "", "  "); err != nil {
+		return "", fmt.Errorf("result is not JSON: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// fence puts s in a markdown code block, with a fence longer than any run
+// of backticks in s
+func fence(lang, s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	marker := strings.Repeat("`", max(3, longest+1))
+	return marker + lang + "\n" + strings.TrimSuffix(s, "\n") + "\n" + marker
+}
@@ -0,0 +1,57 @@
+package pluginhost
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// GatewayConfig sets who may use the gRPC capability gateway (see package
+// hostgrpc). Clients send an API key or an OpenID Connect token as
+// "authorization: Bearer <token>". Without keys or OIDC the gateway refuses
+// every call unless AllowUnauthenticated is set.
+type GatewayConfig struct {
+	// APIKeys maps client names to their keys; the name is the service
+	// principal of the calls in history, request contexts and
+	// authorization. Keys may be secret:// references.
+	APIKeys map[string]string `json:"api_keys"`
+
+	// OIDC accepts tokens issued by an OpenID Connect provider
+	OIDC *OIDCConfig `json:"oidc"`
+
+	// AllowUnauthenticated serves clients without credentials; only for
+	// networks where every client is trusted
+	AllowUnauthenticated bool `json:"allow_unauthenticated"`
+}
+
+// OIDCConfig names the provider gateway tokens come from. Tokens must be
+// signed with one of the provider's published keys, issued by Issuer for
+// Audience and not expired.
+type OIDCConfig struct {
+	// Issuer is the provider's URL, e.g. "https://accounts.example.com";
+	// its keys are found through the discovery document below it
+	Issuer string `json:"issuer"`
+
+	// Audience is the client ID tokens must be issued for
+	Audience string `json:"audience"`
+
+	// UserClaim is the claim naming the user of a call; defaults to "sub"
+	UserClaim string `json:"user_claim"`
+}
+
+func (c *GatewayConfig) validate() error {
+	for name, key := range c.APIKeys {
+		if name == "" || key == "" {
+			return fmt.Errorf("gateway.api_keys: client names and keys must not be empty")
+		}
+	}
+	if c.OIDC != nil {
+		u, err := url.Parse(c.OIDC.Issuer)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("gateway.oidc.issuer: %q is not a URL", c.OIDC.Issuer)
+		}
+		if c.OIDC.Audience == "" {
+			return fmt.Errorf("gateway.oidc.audience is required")
+		}
+	}
+	return nil
+}
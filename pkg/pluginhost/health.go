@@ -0,0 +1,196 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthConfig controls liveness checks of running plugins. gRPC plugins
+// are probed through the standard gRPC health service, net/rpc plugins
+// through go-plugin's ping.
+type HealthConfig struct {
+	// Interval between checks; zero disables them
+	Interval Duration `json:"interval"`
+
+	// Timeout for one check; defaults to Interval
+	Timeout Duration `json:"timeout"`
+
+	// FailureThreshold is the number of consecutive failed checks after
+	// which a plugin is reported unhealthy; defaults to 3
+	FailureThreshold int `json:"failure_threshold"`
+
+	// Restart reloads a plugin once it is unhealthy
+	Restart bool `json:"restart"`
+}
+
+func (c *HealthConfig) validate() error {
+	if c.Interval < 0 || c.Timeout < 0 {
+		return fmt.Errorf("health.interval and health.timeout must not be negative")
+	}
+	if c.FailureThreshold < 0 {
+		return fmt.Errorf("health.failure_threshold must not be negative")
+	}
+	return nil
+}
+
+// applyHealth starts or stops the liveness checks to match cfg. The caller
+// holds pm.mu.
+func (pm *PluginManager) applyHealth(cfg *HostConfig) {
+	switch {
+	case cfg.Health.Interval > 0 && pm.healthStop == nil:
+		pm.healthStop = make(chan struct{})
+		go pm.healthLoop(pm.healthStop)
+	case cfg.Health.Interval == 0 && pm.healthStop != nil:
+		close(pm.healthStop)
+		pm.healthStop = nil
+	}
+}
+
+// healthLoop checks every running plugin until stop is closed. The interval
+// is read again each round so config changes apply without a restart.
+func (pm *PluginManager) healthLoop(stop chan struct{}) {
+	for {
+		interval := time.Duration(pm.Config().Health.Interval)
+		if interval <= 0 {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		pm.checkHealth()
+	}
+}
+
+// checkHealth probes all running plugins concurrently, so one hung plugin
+// does not delay the checks of the others
+func (pm *PluginManager) checkHealth() {
+	cfg := pm.Config().Health
+	timeout := time.Duration(cfg.Timeout)
+	if timeout == 0 {
+		timeout = time.Duration(cfg.Interval)
+	}
+	threshold := cfg.FailureThreshold
+	if threshold == 0 {
+		threshold = 3
+	}
+
+	type target struct {
+		info       *pluginInfo
+		client     *plugin.Client
+		generation int
+	}
+	var targets []target
+	pm.mu.RLock()
+	for _, info := range pm.plugins {
+		if info.Client != nil && !info.loading && !info.crashed {
+			targets = append(targets, target{info, info.Client, info.generation})
+		}
+	}
+	pm.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t target) {
+			defer wg.Done()
+			err := probe(t.client, timeout)
+
+			pm.mu.Lock()
+			if t.info.generation != t.generation || t.info.stopping {
+				// Restarted or stopped while being probed
+				pm.mu.Unlock()
+				return
+			}
+			failed := pm.recordProbe(t.info, err, threshold)
+			pm.mu.Unlock()
+
+			if failed {
+				pm.events.Publish(Event{Type: EventPluginUnhealthy, Plugin: t.info.Name, Data: map[string]interface{}{"error": err.Error()}})
+			}
+			if failed && cfg.Restart {
+				pm.hostLog.Printf("Restarting unhealthy plugin %s", t.info.Name)
+				// A hung process would also hang the graceful shutdown in
+				// ReloadPlugin, so stop it the hard way first
+				killProcess(t.client)
+				if err := pm.ReloadPlugin(t.info.Name); err != nil {
+					pm.hostLog.Printf("Failed to restart plugin %s: %v", t.info.Name, err)
+				}
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+// recordProbe updates a plugin's health and reports whether this probe made
+// it unhealthy. The caller holds pm.mu.
+func (pm *PluginManager) recordProbe(info *pluginInfo, err error, threshold int) bool {
+	if err == nil {
+		if info.unresponsive {
+			pm.hostLog.Printf("Plugin %s is healthy again", info.Name)
+		}
+		info.healthFailures = 0
+		info.unresponsive = false
+		return false
+	}
+
+	info.healthFailures++
+	pm.hostLog.Printf("Health check of plugin %s failed (%d/%d): %v", info.Name, info.healthFailures, threshold, err)
+	if info.healthFailures < threshold || info.unresponsive {
+		return false
+	}
+	info.unresponsive = true
+	return true
+}
+
+// killProcess kills a plugin process without asking it to exit
+func killProcess(client *plugin.Client) {
+	rc := client.ReattachConfig()
+	if rc == nil || rc.Pid <= 0 {
+		return
+	}
+	if p, err := os.FindProcess(rc.Pid); err == nil {
+		p.Kill()
+	}
+}
+
+// probe checks that a plugin process answers within timeout
+func probe(client *plugin.Client, timeout time.Duration) error {
+	rpcClient, err := client.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if c, ok := rpcClient.(*plugin.GRPCClient); ok {
+		resp, err := grpc_health_v1.NewHealthClient(c.Conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{
+			Service: plugin.GRPCServiceName,
+		})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING {
+			return fmt.Errorf("status %s", resp.GetStatus())
+		}
+		return nil
+	}
+
+	// net/rpc has no deadlines, so wait for the ping in the background
+	done := make(chan error, 1)
+	go func() { done <- rpcClient.Ping() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("no answer within %s", timeout)
+	}
+}
@@ -0,0 +1,71 @@
+package pluginhost
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultLatencyMultiple is how many times its typical latency a call gets
+// when timeouts.latency_multiple is unset
+const DefaultLatencyMultiple = 10
+
+// minHintedTimeout keeps timeouts derived from short latency hints from
+// failing calls that are only a little slow
+const minHintedTimeout = time.Second
+
+// provider is a plugin offering a capability, with the capability as that
+// plugin declares it
+type provider struct {
+	plugin     string
+	capability pluginsdk.Capability
+}
+
+// before reports whether p is a better choice than q: cheaper, then
+// faster, with capabilities declaring a latency before those declaring
+// none, then by name
+func (p provider) before(q provider) bool {
+	if a, b := pluginsdk.CostRank(p.capability.Cost), pluginsdk.CostRank(q.capability.Cost); a != b {
+		return a < b
+	}
+	if a, b := p.capability.TypicalLatency(), q.capability.TypicalLatency(); a != b {
+		return b == 0 || (a != 0 && a < b)
+	}
+	return p.plugin < q.plugin
+}
+
+// validateHints checks the hint settings of the timeouts section
+func (c *TimeoutConfig) validateHints() error {
+	if c.LatencyMultiple < 0 {
+		return fmt.Errorf("timeouts.latency_multiple must not be negative")
+	}
+	for cost, d := range c.ByCost {
+		if err := (pluginsdk.Capability{Cost: cost}).ValidateHints(); err != nil || cost == "" {
+			return fmt.Errorf("timeouts.by_cost: %q is not a cost class", cost)
+		}
+		if d <= 0 {
+			return fmt.Errorf("timeouts.by_cost.%s must be positive", cost)
+		}
+	}
+	return nil
+}
+
+// hinted returns the timeout the hints of a capability of info imply: its
+// typical latency times LatencyMultiple, but at least a second, else ByCost
+// of its cost class; 0 when neither applies
+func (c TimeoutConfig) hinted(info *pluginInfo, ref string) time.Duration {
+	name, version := pluginsdk.ParseCapabilityRef(ref)
+	capability := findCapability(info.Capabilities, name, version)
+	if capability == nil {
+		return 0
+	}
+	if latency := capability.TypicalLatency(); latency > 0 {
+		multiple := c.LatencyMultiple
+		if multiple == 0 {
+			multiple = DefaultLatencyMultiple
+		}
+		return max(time.Duration(float64(latency)*multiple), minHintedTimeout)
+	}
+	return time.Duration(c.ByCost[capability.Cost])
+}
@@ -0,0 +1,313 @@
+package pluginhost
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+
+	// Pure Go sqlite driver, so the host still cross-compiles without cgo
+	_ "modernc.org/sqlite"
+)
+
+// ErrHistoryDisabled is returned by History when no history path is
+// configured
+var ErrHistoryDisabled = errors.New("execution history is disabled")
+
+// History record statuses
+const (
+	HistoryOK      = "ok"
+	HistoryError   = "error"
+	HistoryTimeout = "timeout"
+)
+
+const (
+	// defaultHistoryLimit is how many records a query returns when the
+	// filter sets no limit
+	defaultHistoryLimit = 100
+
+	// historyPruneInterval is how often retention is enforced
+	historyPruneInterval = time.Hour
+)
+
+// HistoryRecord is one persisted plugin execution
+type HistoryRecord struct {
+	ID         int64                  `json:"id"`
+	Time       time.Time              `json:"time"`
+	Plugin     string                 `json:"plugin"`
+	Capability string                 `json:"capability,omitempty"`
+	Args       map[string]interface{} `json:"args"`
+	ResultSize int                    `json:"result_size"`
+	Duration   time.Duration          `json:"duration_ns"`
+	Status     string                 `json:"status"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+// HistoryFilter selects records from the execution history. Zero fields
+// match everything.
+type HistoryFilter struct {
+	Plugin     string
+	Capability string
+	Status     string
+	Since      time.Time
+	Until      time.Time
+
+	// Limit caps the number of records returned, newest first; zero means
+	// 100
+	Limit int
+}
+
+const historySchema = `
+CREATE TABLE IF NOT EXISTS executions (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	time        INTEGER NOT NULL,
+	plugin      TEXT    NOT NULL,
+	capability  TEXT    NOT NULL,
+	args        TEXT    NOT NULL,
+	result_size INTEGER NOT NULL,
+	duration_ns INTEGER NOT NULL,
+	status      TEXT    NOT NULL,
+	error       TEXT    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS executions_time ON executions (time);
+CREATE INDEX IF NOT EXISTS executions_plugin_time ON executions (plugin, time);
+`
+
+// historyStore persists execution records in sqlite and enforces the
+// configured retention
+type historyStore struct {
+	db        *sql.DB
+	path      string
+	retention HistoryConfig
+	stop      chan struct{}
+	logger    *log.Logger
+	mu        sync.Mutex
+}
+
+// openHistory opens or creates the history database for cfg
+func openHistory(cfg HistoryConfig, logger *log.Logger) (*historyStore, error) {
+	db, err := sql.Open("sqlite", cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history %s: %w", cfg.Path, err)
+	}
+	// sqlite allows a single writer; one connection avoids SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(historySchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history %s: %w", cfg.Path, err)
+	}
+
+	h := &historyStore{
+		db:        db,
+		path:      cfg.Path,
+		retention: cfg,
+		stop:      make(chan struct{}),
+		logger:    logger,
+	}
+	h.prune()
+	go h.pruneLoop()
+	return h, nil
+}
+
+// setRetention changes the retention policy and applies it right away
+func (h *historyStore) setRetention(cfg HistoryConfig) {
+	h.mu.Lock()
+	h.retention = cfg
+	h.mu.Unlock()
+
+	h.prune()
+}
+
+func (h *historyStore) pruneLoop() {
+	ticker := time.NewTicker(historyPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			h.prune()
+		}
+	}
+}
+
+// prune deletes records that fall outside the retention policy
+func (h *historyStore) prune() {
+	h.mu.Lock()
+	retention := h.retention
+	h.mu.Unlock()
+
+	if retention.MaxAge > 0 {
+		cutoff := time.Now().Add(-time.Duration(retention.MaxAge)).UnixNano()
+		if _, err := h.db.Exec(`DELETE FROM executions WHERE time < ?`, cutoff); err != nil {
+			h.logger.Printf("Failed to prune history by age: %v", err)
+		}
+	}
+	if retention.MaxRecords > 0 {
+		_, err := h.db.Exec(`DELETE FROM executions WHERE id <= (
+			SELECT id FROM executions ORDER BY id DESC LIMIT 1 OFFSET ?)`, retention.MaxRecords)
+		if err != nil {
+			h.logger.Printf("Failed to prune history by count: %v", err)
+		}
+	}
+}
+
+// record stores one execution
+func (h *historyStore) record(rec HistoryRecord) error {
+	args, err := json.Marshal(rec.Args)
+	if err != nil {
+		return fmt.Errorf("failed to encode args: %w", err)
+	}
+	_, err = h.db.Exec(`INSERT INTO executions
+		(time, plugin, capability, args, result_size, duration_ns, status, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.Time.UnixNano(), rec.Plugin, rec.Capability, string(args),
+		rec.ResultSize, int64(rec.Duration), rec.Status, rec.Error)
+	return err
+}
+
+// query returns the records matching f, newest first
+func (h *historyStore) query(f HistoryFilter) ([]HistoryRecord, error) {
+	var where []string
+	var params []interface{}
+	if f.Plugin != "" {
+		where = append(where, "plugin = ?")
+		params = append(params, f.Plugin)
+	}
+	if f.Capability != "" {
+		where = append(where, "capability = ?")
+		params = append(params, f.Capability)
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		params = append(params, f.Status)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "time >= ?")
+		params = append(params, f.Since.UnixNano())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "time < ?")
+		params = append(params, f.Until.UnixNano())
+	}
+
+	query := `SELECT id, time, plugin, capability, args, result_size, duration_ns, status, error FROM executions`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	query += " ORDER BY time DESC, id DESC LIMIT ?"
+	params = append(params, limit)
+
+	rows, err := h.db.Query(query, params...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	records := []HistoryRecord{}
+	for rows.Next() {
+		var rec HistoryRecord
+		var t, duration int64
+		var args string
+		if err := rows.Scan(&rec.ID, &t, &rec.Plugin, &rec.Capability, &args,
+			&rec.ResultSize, &duration, &rec.Status, &rec.Error); err != nil {
+			return nil, fmt.Errorf("failed to read history: %w", err)
+		}
+		rec.Time = time.Unix(0, t)
+		rec.Duration = time.Duration(duration)
+		if err := json.Unmarshal([]byte(args), &rec.Args); err != nil {
+			return nil, fmt.Errorf("failed to decode args of record %d: %w", rec.ID, err)
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+func (h *historyStore) close() error {
+	close(h.stop)
+	return h.db.Close()
+}
+
+// applyHistory opens, reopens or closes the history store to match cfg
+func (pm *PluginManager) applyHistory(cfg HistoryConfig) error {
+	pm.mu.RLock()
+	current := pm.history
+	pm.mu.RUnlock()
+
+	if current != nil && current.path == cfg.Path {
+		current.setRetention(cfg)
+		return nil
+	}
+
+	var next *historyStore
+	if cfg.Path != "" {
+		var err error
+		if next, err = openHistory(cfg, pm.hostLog); err != nil {
+			return err
+		}
+	}
+
+	pm.mu.Lock()
+	pm.history = next
+	pm.mu.Unlock()
+
+	if current != nil {
+		if err := current.close(); err != nil {
+			pm.hostLog.Printf("Failed to close history %s: %v", current.path, err)
+		}
+	}
+	return nil
+}
+
+// recordHistory persists a finished execution with args as recordArgs
+// returned them. Failures are logged rather than returned so history never
+// breaks a call; the caller holds pm.mu.
+func (pm *PluginManager) recordHistory(name string, args map[string]interface{}, start time.Time, result string, err error) {
+	if pm.history == nil {
+		return
+	}
+
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	rec := HistoryRecord{
+		Time:       start,
+		Plugin:     name,
+		Capability: capability,
+		Args:       args,
+		ResultSize: len(result),
+		Duration:   time.Since(start),
+		Status:     HistoryOK,
+	}
+	if err != nil {
+		rec.Status = HistoryError
+		if isTimeout(err) {
+			rec.Status = HistoryTimeout
+		}
+		rec.Error = err.Error()
+	}
+	if err := pm.history.record(rec); err != nil {
+		pm.hostLog.Printf("Failed to record history for %s: %v", name, err)
+	}
+}
+
+// History returns persisted executions matching filter, newest first
+func (pm *PluginManager) History(filter HistoryFilter) ([]HistoryRecord, error) {
+	pm.mu.RLock()
+	history := pm.history
+	pm.mu.RUnlock()
+
+	if history == nil {
+		return nil, ErrHistoryDisabled
+	}
+	return history.query(filter)
+}
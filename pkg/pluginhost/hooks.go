@@ -0,0 +1,100 @@
+package pluginhost
+
+import (
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Hooks are callbacks an embedding application runs on plugin lifecycle
+// events. They run on the goroutine that caused the event, without the
+// manager locked, so they may call the manager but should return quickly.
+type Hooks struct {
+	// OnLoad runs when a plugin has started and reported its metadata,
+	// including a lazy plugin started by its first call
+	OnLoad func(PluginStatus)
+
+	// OnUnload runs when a plugin has been removed from the manager
+	OnUnload func(name string)
+
+	// OnExecute runs when a call to a plugin has finished, after any
+	// retries. Calls served from the cache do not run it.
+	OnExecute func(Execution)
+
+	// OnSLOAlert runs when a capability starts or stops violating its
+	// service level objective (see SLOConfig)
+	OnSLOAlert func(SLOAlert)
+
+	// ApproveInstall decides whether ExecuteCapability may install a
+	// plugin from the registry index for a capability no plugin offers.
+	// Without it, installs need provision.policy "auto".
+	ApproveInstall func(entry IndexEntry, capability string) bool
+}
+
+// Execution describes a finished call for Hooks.OnExecute
+type Execution struct {
+	Plugin     string
+	Capability string
+
+	// Duration is the time spent in the plugin over all attempts
+	Duration time.Duration
+
+	// Attempts is how often the call was tried
+	Attempts int
+
+	// Err is the error of the last attempt, or nil
+	Err error
+}
+
+// Metrics receives measurements of plugin activity, so applications can
+// record them in their own metrics registry
+type Metrics interface {
+	// ObserveCall records one attempt of a call
+	ObserveCall(plugin, capability string, elapsed time.Duration, err error)
+
+	// ObserveStartup records how long a plugin process took to start
+	ObserveStartup(plugin string, elapsed time.Duration)
+}
+
+// loaded runs the hooks and metrics for a plugin that finished loading
+func (pm *PluginManager) loaded(info *pluginInfo) {
+	if pm.hooks.OnLoad == nil && pm.metrics == nil {
+		return
+	}
+	pm.mu.RLock()
+	status := info.status()
+	pm.mu.RUnlock()
+
+	if pm.metrics != nil {
+		var elapsed time.Duration
+		for _, t := range status.Startup {
+			elapsed += t.Duration
+		}
+		pm.metrics.ObserveStartup(status.Name, elapsed)
+	}
+	if pm.hooks.OnLoad != nil {
+		pm.hooks.OnLoad(status)
+	}
+}
+
+// unloaded runs the hooks for a plugin removed from the manager
+func (pm *PluginManager) unloaded(name string) {
+	if pm.hooks.OnUnload != nil {
+		pm.hooks.OnUnload(name)
+	}
+}
+
+// observeAttempt records one attempt of a call in the metrics
+func (pm *PluginManager) observeAttempt(name string, args map[string]interface{}, elapsed time.Duration, err error) {
+	if pm.metrics != nil {
+		capability, _ := args[pluginsdk.ArgCapability].(string)
+		pm.metrics.ObserveCall(name, capability, elapsed, err)
+	}
+}
+
+// executed runs the hooks for a finished call
+func (pm *PluginManager) executed(e Execution) {
+	if pm.hooks.OnExecute != nil {
+		pm.hooks.OnExecute(e)
+	}
+}
@@ -0,0 +1,209 @@
+package pluginhost
+
+import (
+	"debug/buildinfo"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Build metadata of the host binary, set when it is linked:
+//
+//	go build -ldflags "-X github.com/Kirchlive/super/pkg/pluginhost.BuildVersion=1.4.0"
+//
+// Without them HostInfo falls back to what the Go toolchain recorded.
+var (
+	BuildVersion string
+	BuildCommit  string
+	BuildDate    string
+)
+
+// Packages build metadata is stamped through, and the module of both
+const (
+	moduleSuper = "github.com/Kirchlive/super"
+	hostPackage = moduleSuper + "/pkg/pluginhost"
+	sdkPackage  = moduleSuper + "/pkg/pluginsdk"
+)
+
+// BuildInfo is what a binary says about how it was built
+type BuildInfo struct {
+	// Version, Commit and Date are the linker-stamped build metadata,
+	// else the module version and VCS stamp the Go toolchain recorded
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	Date    string `json:"date,omitempty"`
+
+	// GoVersion is the Go release the binary was built with
+	GoVersion string `json:"go_version,omitempty"`
+
+	// Module is the main module of the binary and SDKVersion the version
+	// of the plugin SDK module it was built against
+	Module     string `json:"module,omitempty"`
+	SDKVersion string `json:"sdk_version,omitempty"`
+}
+
+// ProtocolInfo lists the protocol versions the host speaks
+type ProtocolInfo struct {
+	// Plugin is the version of the plugin handshake
+	Plugin uint `json:"plugin"`
+
+	// Transports are how the host talks to plugin processes
+	Transports []string `json:"transports"`
+
+	// Codecs are the argument codecs the host can agree on with plugins
+	Codecs []string `json:"codecs"`
+
+	// APIs are the versioned gRPC packages the host serves and calls
+	APIs []string `json:"apis"`
+}
+
+// PluginBuild is what is deployed of one plugin
+type PluginBuild struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Path    string `json:"path,omitempty"`
+
+	// Host is the remote host serving the plugin; empty for local ones
+	Host string `json:"host,omitempty"`
+
+	// InProcess is set for built-in plugins, which are part of the host's
+	// build and have no build of their own
+	InProcess bool `json:"in_process,omitempty"`
+
+	// Build is nil when the binary records none, e.g. for plugins not
+	// written in Go
+	Build *BuildInfo `json:"build,omitempty"`
+}
+
+// HostInfo describes the deployed host and its plugins
+type HostInfo struct {
+	BuildInfo
+	Protocols ProtocolInfo  `json:"protocols"`
+	Plugins   []PluginBuild `json:"plugins"`
+}
+
+// HostInfo returns the host's build, the protocols it speaks and the
+// build of every plugin, as their binaries recorded them when started
+func (pm *PluginManager) HostInfo() HostInfo {
+	info := HostInfo{
+		BuildInfo: hostBuild(),
+		Protocols: ProtocolInfo{
+			Plugin:     pluginsdk.Handshake.ProtocolVersion,
+			Transports: []string{ProtocolGRPC, ProtocolNetRPC},
+			Codecs:     pluginsdk.Codecs(),
+			APIs:       []string{"opencode.plugin.v1", "opencode.gateway.v1"},
+		},
+		Plugins: []PluginBuild{},
+	}
+
+	pm.mu.RLock()
+	for _, p := range pm.plugins {
+		info.Plugins = append(info.Plugins, PluginBuild{
+			Name:      p.Name,
+			Version:   p.Version,
+			Path:      p.Path,
+			InProcess: p.builtin,
+			Build:     p.Build,
+		})
+	}
+	for _, st := range pm.remotePlugins() {
+		info.Plugins = append(info.Plugins, PluginBuild{Name: st.Name, Version: st.Version, Host: st.Host, Build: st.Build})
+	}
+	pm.mu.RUnlock()
+
+	sort.Slice(info.Plugins, func(i, j int) bool { return info.Plugins[i].Name < info.Plugins[j].Name })
+	return info
+}
+
+// hostBuild returns the build of the running host
+func hostBuild() BuildInfo {
+	b := BuildInfo{Version: BuildVersion, Commit: BuildCommit, Date: BuildDate, GoVersion: runtime.Version()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		fillBuild(&b, bi, hostPackage)
+	}
+	return b
+}
+
+// readBuildInfo returns what the binary at path recorded of its build, or
+// nil when it is not a Go binary
+func readBuildInfo(path string) *BuildInfo {
+	bi, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	b := &BuildInfo{GoVersion: bi.GoVersion}
+	fillBuild(b, bi, sdkPackage)
+	return b
+}
+
+// fillBuild completes b from the build info the Go toolchain recorded:
+// the -X flags stamping pkg's Build variables first, then the main
+// module's version and the VCS stamp
+func fillBuild(b *BuildInfo, bi *debug.BuildInfo, pkg string) {
+	b.Module = bi.Main.Path
+	if bi.Main.Path == moduleSuper && bi.Main.Version != "(devel)" {
+		b.SDKVersion = bi.Main.Version
+	}
+	for _, dep := range bi.Deps {
+		if dep.Path == moduleSuper {
+			b.SDKVersion = dep.Version
+		}
+	}
+
+	stamped := map[string]string{}
+	var revision, vcsTime string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "-ldflags":
+			for name, value := range stampedVars(s.Value, pkg) {
+				stamped[name] = value
+			}
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.time":
+			vcsTime = s.Value
+		}
+	}
+	b.Version = firstOf(b.Version, stamped["BuildVersion"])
+	b.Commit = firstOf(b.Commit, stamped["BuildCommit"], revision)
+	b.Date = firstOf(b.Date, stamped["BuildDate"], vcsTime)
+	if v := bi.Main.Version; v != "" && v != "(devel)" {
+		b.Version = firstOf(b.Version, v)
+	}
+}
+
+// stampedVars returns the variables of pkg the linker flags set with -X,
+// e.g. {"BuildVersion": "1.2.0"} for "-X <pkg>.BuildVersion=1.2.0"
+func stampedVars(ldflags, pkg string) map[string]string {
+	vars := map[string]string{}
+	fields := strings.Fields(ldflags)
+	for i, f := range fields {
+		var def string
+		switch {
+		case f == "-X" && i+1 < len(fields):
+			def = fields[i+1]
+		case strings.HasPrefix(f, "-X="):
+			def = strings.TrimPrefix(f, "-X=")
+		default:
+			continue
+		}
+		name, value, ok := strings.Cut(def, "=")
+		if rest, found := strings.CutPrefix(name, pkg+"."); ok && found {
+			vars[rest] = value
+		}
+	}
+	return vars
+}
+
+// firstOf returns the first of values that is not empty
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
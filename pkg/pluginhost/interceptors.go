@@ -0,0 +1,70 @@
+package pluginhost
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// PluginCall describes the plugin call a gRPC client interceptor added with
+// WithUnaryInterceptor or WithStreamInterceptor is running for
+type PluginCall struct {
+	// Path is the binary of the plugin process the call goes to; it is
+	// set for every call
+	Path string
+
+	// Plugin, Capability, CallID and RequestID are set for the calls
+	// running a capability, not for the host's own calls such as health
+	// checks
+	Plugin     string
+	Capability string
+	CallID     string
+	RequestID  string
+}
+
+type pluginCallKey struct{}
+
+// PluginCallFromContext returns the call an interceptor sees ctx of
+func PluginCallFromContext(ctx context.Context) (PluginCall, bool) {
+	c, ok := ctx.Value(pluginCallKey{}).(PluginCall)
+	return c, ok
+}
+
+// withPluginCall returns ctx carrying the call args runs a capability of
+// plugin name with
+func withPluginCall(ctx context.Context, name string, args map[string]interface{}) context.Context {
+	c := PluginCall{Plugin: name, RequestID: pluginsdk.ContextOf(args).RequestID}
+	c.Capability, _ = args[pluginsdk.ArgCapability].(string)
+	c.CallID, _ = args[pluginsdk.ArgCallID].(string)
+	return context.WithValue(ctx, pluginCallKey{}, c)
+}
+
+// withPluginPath adds the binary the connection goes to to the call of
+// ctx, which has none for the host's own calls
+func withPluginPath(ctx context.Context, path string) context.Context {
+	c, _ := PluginCallFromContext(ctx)
+	c.Path = path
+	return context.WithValue(ctx, pluginCallKey{}, c)
+}
+
+// interceptorOptions returns the dial options installing the interceptors
+// embedders added on a connection to the plugin at path, preceded by one
+// telling them the path
+func (pm *PluginManager) interceptorOptions(path string) []grpc.DialOption {
+	var opts []grpc.DialOption
+	if len(pm.unaryInterceptors) > 0 {
+		chain := []grpc.UnaryClientInterceptor{func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(withPluginPath(ctx, path), method, req, reply, cc, opts...)
+		}}
+		opts = append(opts, grpc.WithChainUnaryInterceptor(append(chain, pm.unaryInterceptors...)...))
+	}
+	if len(pm.streamInterceptors) > 0 {
+		chain := []grpc.StreamClientInterceptor{func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(withPluginPath(ctx, path), desc, cc, method, opts...)
+		}}
+		opts = append(opts, grpc.WithChainStreamInterceptor(append(chain, pm.streamInterceptors...)...))
+	}
+	return opts
+}
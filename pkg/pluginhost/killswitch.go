@@ -0,0 +1,115 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// ErrHalted means an operator engaged the kill switch; the errors returned
+// are *HaltError values carrying the reason
+var ErrHalted = errors.New("plugin execution halted")
+
+// Kill switch events
+const (
+	EventHostHalted  = "host.halted"
+	EventHostResumed = "host.resumed"
+)
+
+// HaltStatus is the state of the kill switch
+type HaltStatus struct {
+	Halted bool      `json:"halted"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since,omitempty"`
+}
+
+// HaltError refuses a call while the kill switch is engaged. It matches
+// ErrHalted with errors.Is and is a pluginsdk.CodeUnavailable
+// PluginError for errors.As, so plugins calling other plugins see it too.
+type HaltError struct {
+	Reason string
+	Since  time.Time
+}
+
+func (e *HaltError) Error() string {
+	return fmt.Sprintf("plugin execution is halted by operator since %s: %s", e.Since.Format(time.RFC3339), e.Reason)
+}
+
+func (e *HaltError) Unwrap() []error {
+	return []error{ErrHalted, &pluginsdk.PluginError{
+		Code:    pluginsdk.CodeUnavailable,
+		Message: e.Error(),
+		Details: map[string]string{"halted": "true", "reason": e.Reason},
+	}}
+}
+
+// haltState is the kill switch
+type haltState struct {
+	status HaltStatus
+	mu     sync.Mutex
+}
+
+// Halt engages the kill switch: every plugin call, including those plugins
+// make to each other, session calls and tasks, fails with a *HaltError
+// naming reason, and no events are delivered to plugins, until Resume. With
+// abort the calls running are canceled as well, as by CancelRequest; it
+// returns how many. The switch is kept in the state file, so a restarted
+// host stays halted.
+func (pm *PluginManager) Halt(reason string, abort bool) (int, error) {
+	if reason == "" {
+		return 0, errors.New("halting plugin execution needs a reason")
+	}
+	pm.halt.mu.Lock()
+	pm.halt.status = HaltStatus{Halted: true, Reason: reason, Since: time.Now()}
+	herr := &HaltError{Reason: reason, Since: pm.halt.status.Since}
+	pm.halt.mu.Unlock()
+
+	aborted := 0
+	if abort {
+		aborted = pm.running.cancelAll(herr)
+	}
+	pm.hostLog.Printf("Plugin execution halted (%d call(s) aborted): %s", aborted, reason)
+	pm.events.Publish(Event{Type: EventHostHalted, Data: map[string]interface{}{
+		"reason":  reason,
+		"aborted": aborted,
+	}})
+	pm.saveState()
+	return aborted, nil
+}
+
+// Resume releases the kill switch, so plugins take calls again
+func (pm *PluginManager) Resume() {
+	pm.halt.mu.Lock()
+	was := pm.halt.status
+	pm.halt.status = HaltStatus{}
+	pm.halt.mu.Unlock()
+	if !was.Halted {
+		return
+	}
+
+	pm.hostLog.Printf("Plugin execution resumed, halted since %s: %s", was.Since.Format(time.RFC3339), was.Reason)
+	pm.events.Publish(Event{Type: EventHostResumed, Data: map[string]interface{}{
+		"reason": was.Reason,
+		"since":  was.Since,
+	}})
+	pm.saveState()
+}
+
+// Halted returns the state of the kill switch
+func (pm *PluginManager) Halted() HaltStatus {
+	pm.halt.mu.Lock()
+	defer pm.halt.mu.Unlock()
+
+	return pm.halt.status
+}
+
+// checkHalt refuses calls while the kill switch is engaged
+func (pm *PluginManager) checkHalt() error {
+	if st := pm.Halted(); st.Halted {
+		return &HaltError{Reason: st.Reason, Since: st.Since}
+	}
+	return nil
+}
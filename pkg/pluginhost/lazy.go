@@ -0,0 +1,179 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pluginManifest returns the manifest next to a plugin binary, or nil if
+// there is none or it cannot be used. A manifest of the previous schema
+// version is logged as deprecated the first time it is read.
+func (pm *PluginManager) pluginManifest(binary string) *Manifest {
+	m, err := LoadManifest(manifestPath(binary))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			pm.hostLog.Printf("Ignoring manifest for %s: %v", binary, err)
+		}
+		return nil
+	}
+	if len(m.Migrated) > 0 {
+		if _, warned := pm.deprecationWarned.LoadOrStore("manifest:"+binary, true); !warned {
+			pm.hostLog.Printf("Manifest of plugin %s: %s; upgrade it with \"superplugin manifest migrate -w %s\"", m.Name, m.Migrated[0].Explanation, manifestPath(binary))
+		}
+	}
+	return m
+}
+
+// startsLazily reports whether cfg has a plugin described by m registered
+// without starting it
+func (pm *PluginManager) startsLazily(cfg *HostConfig, m *Manifest) bool {
+	if m == nil || cfg.Loading.Mode != LoadLazy {
+		return false
+	}
+	if cfg.isWarm(m.Name) {
+		pm.hostLog.Printf("Warming up plugin: %s", m.Name)
+		return false
+	}
+	return true
+}
+
+// registerLazy adds a plugin to the registry from its manifest; the process
+// is started by the first call
+func (pm *PluginManager) registerLazy(path string, m *Manifest) {
+	name, err := pm.resolveName(path, m.Name, m.Version)
+	if err != nil {
+		pm.hostLog.Printf("Failed to register plugin %s: %v", path, err)
+		return
+	}
+	info := &pluginInfo{
+		Name:         name,
+		Version:      m.Version,
+		Path:         path,
+		Capabilities: m.Capabilities,
+		Flags:        m.Flags,
+		Events:       m.Events,
+		Locales:      m.Locales,
+		Calls:        m.Permissions.calls(),
+		Files:        m.Permissions.files(),
+		LLM:          m.Permissions.llm(),
+		Inject:       m.Inject,
+		Updatable:    m.Updatable,
+		calls:        &callHistory{},
+		stats:        &pluginStats{},
+		lazy:         true,
+	}
+	info.Timeout, info.CapabilityTimeouts = m.Timeouts.Default, m.Timeouts.Capabilities
+	if name != m.Name {
+		info.reported = m.Name
+	}
+
+	pm.logFor(path).setName(name)
+
+	pm.mu.Lock()
+	info.LastCrash = pm.crashes[name]
+	pm.plugins[name] = info
+	pm.subscribePlugin(info)
+	if pm.idleStop == nil {
+		pm.idleStop = make(chan struct{})
+		go pm.reapIdle(pm.idleStop)
+	}
+	pm.mu.Unlock()
+
+	pm.hostLog.Printf("Registered plugin: %s v%s (lazy)", name, m.Version)
+	pm.saveState()
+}
+
+// ensureRunning starts a lazily registered plugin that is not running.
+// Unknown and disabled plugins are left for the caller to report.
+func (pm *PluginManager) ensureRunning(name string) error {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	running := exists && info.Instance != nil
+	disabled := pm.disabled[name]
+	// Plugins waiting on a dependency are started by their loader
+	waiting := exists && info.loading
+	pm.mu.RUnlock()
+
+	if !exists || running || disabled || waiting {
+		return nil
+	}
+
+	// Only one caller starts the process; the others wait for it
+	info.startMu.Lock()
+	defer info.startMu.Unlock()
+
+	pm.mu.RLock()
+	running = info.Instance != nil
+	pm.mu.RUnlock()
+	if running {
+		return nil
+	}
+
+	pm.hostLog.Printf("Starting plugin on first use: %s", name)
+	proc, err := pm.startProcess(info.Path)
+	if err != nil {
+		return fmt.Errorf("failed to start plugin %s: %w", name, err)
+	}
+	if got := proc.name; got != info.reports() {
+		proc.client.Kill()
+		return fmt.Errorf("plugin at %s reports name %q but its manifest says %q", info.Path, got, info.reports())
+	}
+
+	pm.mu.Lock()
+	pm.attach(info, proc)
+	pm.mu.Unlock()
+
+	pm.readMetadata(info)
+	return nil
+}
+
+// reapIdle stops lazily started plugins that have not been called recently
+func (pm *PluginManager) reapIdle(stop <-chan struct{}) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			pm.stopIdle(now)
+		}
+	}
+}
+
+func (pm *PluginManager) stopIdle(now time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	idle := time.Duration(pm.config.Loading.IdleShutdown)
+	if idle == 0 {
+		return
+	}
+
+	for name, info := range pm.plugins {
+		if !info.lazy || info.Instance == nil || info.loading || info.active.Load() > 0 {
+			continue
+		}
+		// Daemons serve clients of their own, which the host does not see
+		if info.daemon != nil {
+			continue
+		}
+		lastUsed := info.stats.lastUsedAt()
+		if lastUsed.Before(info.StartedAt) {
+			lastUsed = info.StartedAt
+		}
+		if now.Sub(lastUsed) < idle || pm.sessions.hasSessions(name) {
+			continue
+		}
+
+		pm.hostLog.Printf("Stopping idle plugin: %s", name)
+		info.stopping = true
+		info.Client.Kill()
+		stopPool(info)
+		info.Client = nil
+		info.Instance = nil
+	}
+}
@@ -0,0 +1,608 @@
+package pluginhost
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Types of LLM providers
+const (
+	LLMOpenAI    = "openai"
+	LLMAnthropic = "anthropic"
+)
+
+// Defaults of LLM providers that set none
+const (
+	DefaultLLMTimeout   = 2 * time.Minute
+	DefaultLLMMaxTokens = 1024
+)
+
+// llmBaseURLs are the APIs providers talk to unless base_url is set
+var llmBaseURLs = map[string]string{
+	LLMOpenAI:    "https://api.openai.com/v1",
+	LLMAnthropic: "https://api.anthropic.com/v1",
+}
+
+// anthropicVersion is the version of the Anthropic API requests are made to
+const anthropicVersion = "2023-06-01"
+
+// llmResponseLimit bounds the body of a provider's answer
+const llmResponseLimit = 16 << 20
+
+// LLMConfig sets the LLM providers plugins use through
+// HostServices.Complete and what each plugin may spend
+type LLMConfig struct {
+	// Default names the provider of requests naming none; may be left
+	// out when there is only one
+	Default string `json:"default"`
+
+	Providers map[string]LLMProviderConfig `json:"providers"`
+
+	// Plugins limits single plugins by name; "*" applies to the plugins
+	// not listed
+	Plugins map[string]LLMPluginLimits `json:"plugins"`
+
+	// Budgets are hard limits on the tokens and cost of plugins,
+	// capabilities and callers; a request must fit all that match it
+	Budgets []LLMBudget `json:"budgets"`
+
+	// Ledger is a file every request is appended to as a JSON line, from
+	// which a restarted host reads back what was spent; without it the
+	// spending, and with it the budgets, start from nothing on restart
+	Ledger string `json:"ledger"`
+
+	// Cassette records completions or replays recorded ones, for tests
+	Cassette LLMCassetteConfig `json:"cassette"`
+}
+
+// LLMProviderConfig is one account with an LLM provider
+type LLMProviderConfig struct {
+	// Type is LLMOpenAI or LLMAnthropic; OpenAI also covers servers
+	// speaking its API, e.g. a local model behind base_url
+	Type string `json:"type"`
+
+	// BaseURL overrides the provider's API, e.g. "http://localhost:11434/v1"
+	BaseURL string `json:"base_url"`
+
+	// APIKey is the key, usually a "secret://name" reference
+	APIKey string `json:"api_key"`
+
+	// Model is used for requests naming none
+	Model string `json:"model"`
+
+	// Models lists the models plugins may ask for; empty allows any
+	Models []string `json:"models"`
+
+	// MaxTokens is the default and the cap of the tokens a request may
+	// generate; DefaultLLMMaxTokens when unset
+	MaxTokens int `json:"max_tokens"`
+
+	// Timeout bounds each request; DefaultLLMTimeout when unset
+	Timeout Duration `json:"timeout"`
+
+	// RateLimit bounds the requests of all plugins to the provider
+	RateLimit RateLimit `json:"rate_limit"`
+
+	// Prices are what the models cost by name, for estimating the cost of
+	// requests; "*" prices the models not listed
+	Prices map[string]LLMPrice `json:"prices"`
+}
+
+// LLMPluginLimits bounds what one plugin spends on LLM requests
+type LLMPluginLimits struct {
+	// RateLimit bounds the plugin's requests to all providers
+	RateLimit RateLimit `json:"rate_limit"`
+
+	// DailyTokens caps the input and output tokens of the plugin's
+	// requests per UTC day; zero is unlimited
+	DailyTokens int64 `json:"daily_tokens"`
+}
+
+func (c *LLMConfig) validate() error {
+	if c.Default != "" {
+		if _, ok := c.Providers[c.Default]; !ok {
+			return fmt.Errorf("llm.default names unknown provider %q", c.Default)
+		}
+	}
+	for name, p := range c.Providers {
+		field := "llm.providers." + name
+		if _, ok := llmBaseURLs[p.Type]; !ok {
+			return fmt.Errorf("%s.type must be %q or %q", field, LLMOpenAI, LLMAnthropic)
+		}
+		if p.Model == "" {
+			return fmt.Errorf("%s.model must be set", field)
+		}
+		if p.MaxTokens < 0 {
+			return fmt.Errorf("%s.max_tokens must not be negative", field)
+		}
+		if p.Timeout < 0 {
+			return fmt.Errorf("%s.timeout must not be negative", field)
+		}
+		if err := p.RateLimit.validate(field + ".rate_limit"); err != nil {
+			return err
+		}
+		for model, price := range p.Prices {
+			if price.Input < 0 || price.Output < 0 {
+				return fmt.Errorf("%s.prices.%s must not be negative", field, model)
+			}
+		}
+	}
+	for name, l := range c.Plugins {
+		field := "llm.plugins." + name
+		if err := l.RateLimit.validate(field + ".rate_limit"); err != nil {
+			return err
+		}
+		if l.DailyTokens < 0 {
+			return fmt.Errorf("%s.daily_tokens must not be negative", field)
+		}
+	}
+	names := make(map[string]bool)
+	for i, b := range c.Budgets {
+		if err := b.validate(fmt.Sprintf("llm.budgets[%d]", i)); err != nil {
+			return err
+		}
+		if names[b.Name] {
+			return fmt.Errorf("llm.budgets has two budgets named %q", b.Name)
+		}
+		names[b.Name] = true
+	}
+	return c.Cassette.validate()
+}
+
+// provider returns the provider a request names, or the default
+func (c *LLMConfig) provider(name string) (string, LLMProviderConfig, bool) {
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" && len(c.Providers) == 1 {
+		for only := range c.Providers {
+			name = only
+		}
+	}
+	p, ok := c.Providers[name]
+	return name, p, ok
+}
+
+// limits returns the limits of a plugin
+func (c *LLMConfig) limits(plugin string) LLMPluginLimits {
+	if l, ok := c.Plugins[plugin]; ok {
+		return l
+	}
+	return c.Plugins["*"]
+}
+
+// LLMUsageStats is what a plugin spent on LLM requests, in total or with
+// one provider and model
+type LLMUsageStats struct {
+	Plugin   string `json:"plugin"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+
+	Requests     uint64 `json:"requests"`
+	Failures     uint64 `json:"failures"`
+	InputTokens  uint64 `json:"input_tokens"`
+	OutputTokens uint64 `json:"output_tokens"`
+
+	// CostUSD is the estimated cost, by the prices of the providers
+	CostUSD float64 `json:"cost_usd"`
+
+	// TokensToday counts against the plugin's daily_tokens; only set in
+	// the totals of a plugin
+	TokensToday int64 `json:"tokens_today,omitempty"`
+}
+
+// llmState holds the rate limits and the accounting of LLM requests
+type llmState struct {
+	mu        sync.Mutex
+	providers map[string]*tokenBucket
+	plugins   map[string]*tokenBucket
+
+	// spend is what requests spent by day, attribution, provider and
+	// model, read back from ledger; totals sums it by plugin
+	spend  map[llmSpendKey]*llmSpend
+	totals map[string]*llmSpend
+	ledger string
+
+	// calls are the running calls of plugins by call ID, to which their
+	// requests are charged
+	calls map[string]llmAttribution
+
+	// cassette holds the recorded completions while llm.cassette is set
+	cassette llmCassette
+}
+
+// admit applies the budgets and the rate limits to a request attributed
+// to a for provider. The state must be locked.
+func (s *llmState) admit(cfg *LLMConfig, a llmAttribution, provider string, now time.Time) error {
+	if s.providers == nil {
+		s.providers = make(map[string]*tokenBucket)
+		s.plugins = make(map[string]*tokenBucket)
+	}
+	limits := cfg.limits(a.plugin)
+	if limits.DailyTokens > 0 && s.tokensToday(a.plugin, now) >= limits.DailyTokens {
+		return &pluginsdk.PluginError{
+			Code:    pluginsdk.CodeUnavailable,
+			Message: fmt.Sprintf("plugin %s used its %d LLM tokens for today", a.plugin, limits.DailyTokens),
+			Details: map[string]string{"budget": "daily_tokens"},
+		}
+	}
+	if err := s.checkBudgets(cfg.Budgets, a, now); err != nil {
+		return err
+	}
+	if !allowBucket(s.plugins, a.plugin, limits.RateLimit, now) {
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: fmt.Sprintf("LLM rate limit exceeded for plugin %s", a.plugin), Retryable: true}
+	}
+	if !allowBucket(s.providers, provider, cfg.Providers[provider].RateLimit, now) {
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: fmt.Sprintf("rate limit of LLM provider %s exceeded", provider), Retryable: true}
+	}
+	return nil
+}
+
+// allowBucket takes a token from the bucket of name, brought up to date
+// with rl first, so limits follow config changes
+func allowBucket(buckets map[string]*tokenBucket, name string, rl RateLimit, now time.Time) bool {
+	b := updateBucket(buckets[name], rl)
+	if b == nil {
+		delete(buckets, name)
+		return true
+	}
+	buckets[name] = b
+	return b.allow(now)
+}
+
+// tokensToday returns the tokens a plugin used on the UTC day of now
+func (s *llmState) tokensToday(plugin string, now time.Time) int64 {
+	sp := s.spent(now, func(k llmSpendKey) bool { return k.plugin == plugin })
+	return int64(sp.inputTokens + sp.outputTokens)
+}
+
+// LLMUsage returns what each plugin spent with each provider and model,
+// sorted by plugin, provider and model
+func (pm *PluginManager) LLMUsage() []LLMUsageStats {
+	s := pm.lockLLM(pm.Config().LLM.Ledger)
+	defer s.mu.Unlock()
+
+	type usageKey struct{ plugin, provider, model string }
+	byKey := make(map[usageKey]*llmSpend)
+	for key, sp := range s.spend {
+		k := usageKey{key.plugin, key.provider, key.model}
+		if byKey[k] == nil {
+			byKey[k] = &llmSpend{}
+		}
+		byKey[k].add(*sp)
+	}
+	usage := make([]LLMUsageStats, 0, len(byKey))
+	for k, sp := range byKey {
+		usage = append(usage, sp.stats(k.plugin, k.provider, k.model))
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		a, b := usage[i], usage[j]
+		if a.Plugin != b.Plugin {
+			return a.Plugin < b.Plugin
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Model < b.Model
+	})
+	return usage
+}
+
+// stats returns the spending as usage statistics
+func (sp llmSpend) stats(plugin, provider, model string) LLMUsageStats {
+	return LLMUsageStats{
+		Plugin:       plugin,
+		Provider:     provider,
+		Model:        model,
+		Requests:     sp.requests,
+		Failures:     sp.failures,
+		InputTokens:  sp.inputTokens,
+		OutputTokens: sp.outputTokens,
+		CostUSD:      sp.cost,
+	}
+}
+
+// llmUsageOf returns the totals of a plugin, or nil when it made no LLM
+// requests. pm.mu must be held.
+func (pm *PluginManager) llmUsageOf(plugin string) *LLMUsageStats {
+	s := pm.lockLLM(pm.config.LLM.Ledger)
+	defer s.mu.Unlock()
+
+	total, ok := s.totals[plugin]
+	if !ok {
+		return nil
+	}
+	st := total.stats(plugin, "", "")
+	st.TokensToday = s.tokensToday(plugin, time.Now())
+	return &st
+}
+
+// llmGrantsOf returns the name of the plugin running the binary at path
+// and the LLM providers its manifest grants
+func (pm *PluginManager) llmGrantsOf(path string) (string, []string) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, info := range pm.plugins {
+		if info.Path == path {
+			return info.Name, info.LLM
+		}
+	}
+	return "", nil
+}
+
+// Complete runs an LLM request on behalf of the calling plugin
+func (h *pluginHost) Complete(req pluginsdk.LLMRequest) (pluginsdk.LLMResponse, error) {
+	name, grants := h.pm.llmGrantsOf(h.path)
+	if name == "" {
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeUnavailable, "calling plugin is not registered")
+	}
+	return h.pm.complete(name, grants, req)
+}
+
+// complete checks a plugin's LLM request against the config and its
+// grants, sends it to the provider and accounts the tokens
+func (pm *PluginManager) complete(plugin string, grants []string, req pluginsdk.LLMRequest) (pluginsdk.LLMResponse, error) {
+	cfg := pm.Config().LLM
+	if len(cfg.Providers) == 0 {
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeUnavailable, "host has no LLM providers; configure llm.providers")
+	}
+	provider, pc, ok := cfg.provider(req.Provider)
+	if !ok {
+		if req.Provider == "" {
+			return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "request names no LLM provider and the host has no llm.default")
+		}
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeNotFound, "unknown LLM provider %q", req.Provider)
+	}
+	if !containsString(grants, provider) && !containsString(grants, "*") {
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodePermissionDenied, "plugin %s may not use LLM provider %s; list it under permissions.llm in its manifest", plugin, provider)
+	}
+	if len(req.Messages) == 0 {
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "LLM request has no messages")
+	}
+	for _, m := range req.Messages {
+		if m.Role != pluginsdk.RoleUser && m.Role != pluginsdk.RoleAssistant {
+			return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "message role must be %q or %q, not %q", pluginsdk.RoleUser, pluginsdk.RoleAssistant, m.Role)
+		}
+	}
+	req.Provider = provider
+	if req.Model == "" {
+		req.Model = pc.Model
+	}
+	if len(pc.Models) > 0 && !containsString(pc.Models, req.Model) {
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodePermissionDenied, "model %s of LLM provider %s is not allowed; allowed are %s", req.Model, provider, strings.Join(pc.Models, ", "))
+	}
+	maxTokens := pc.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = DefaultLLMMaxTokens
+	}
+	if req.MaxTokens <= 0 || req.MaxTokens > maxTokens {
+		req.MaxTokens = maxTokens
+	}
+
+	a := pm.llm.attribution(plugin, req.CallID)
+	s := pm.lockLLM(cfg.Ledger)
+	err := s.admit(&cfg, a, provider, time.Now())
+	s.mu.Unlock()
+	if err != nil {
+		return pluginsdk.LLMResponse{}, err
+	}
+	var resp pluginsdk.LLMResponse
+	if cfg.Cassette.Mode == CassetteReplay {
+		resp, err = pm.llm.cassette.replay(cfg.Cassette.Path, req)
+	} else {
+		var key string
+		if key, err = pm.ResolveSecret(pc.APIKey); err != nil {
+			pm.hostLog.Printf("No API key for LLM provider %s: %v", provider, err)
+			return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeUnavailable, "LLM provider %s has no API key", provider)
+		}
+		resp, err = sendLLM(pc, key, req)
+	}
+	resp.Provider = provider
+	if resp.Model == "" {
+		resp.Model = req.Model
+	}
+	if err == nil && cfg.Cassette.Mode == CassetteRecord {
+		if err := pm.llm.cassette.record(cfg.Cassette.Path, req, resp); err != nil {
+			pm.hostLog.Printf("Failed to record completion to cassette %s: %v", cfg.Cassette.Path, err)
+		}
+	}
+	entry := llmLedgerEntry{
+		Time:         time.Now(),
+		Plugin:       plugin,
+		Capability:   a.capability,
+		Caller:       a.caller,
+		Provider:     provider,
+		Model:        req.Model,
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+		CostUSD:      pc.price(req.Model).cost(resp.Usage),
+		Failed:       err != nil,
+	}
+	s = pm.lockLLM(cfg.Ledger)
+	if werr := s.record(entry); werr != nil {
+		pm.hostLog.Printf("Failed to append to LLM ledger %s: %v", cfg.Ledger, werr)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return pluginsdk.LLMResponse{}, err
+	}
+	return resp, nil
+}
+
+// sendLLM sends a request to the provider within its timeout
+func sendLLM(pc LLMProviderConfig, key string, req pluginsdk.LLMRequest) (pluginsdk.LLMResponse, error) {
+	timeout := time.Duration(pc.Timeout)
+	if timeout == 0 {
+		timeout = DefaultLLMTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if pc.Type == LLMAnthropic {
+		return anthropicComplete(ctx, pc, key, req)
+	}
+	return openAIComplete(ctx, pc, key, req)
+}
+
+// baseURL returns the API the provider is reached at
+func (p LLMProviderConfig) baseURL() string {
+	if p.BaseURL != "" {
+		return strings.TrimSuffix(p.BaseURL, "/")
+	}
+	return llmBaseURLs[p.Type]
+}
+
+// openAIComplete sends a request to the chat completions API of OpenAI
+func openAIComplete(ctx context.Context, pc LLMProviderConfig, key string, req pluginsdk.LLMRequest) (pluginsdk.LLMResponse, error) {
+	messages := make([]pluginsdk.LLMMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, pluginsdk.LLMMessage{Role: "system", Content: req.System})
+	}
+	messages = append(messages, req.Messages...)
+	body := map[string]interface{}{"model": req.Model, "messages": messages, "max_tokens": req.MaxTokens}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+
+	var out struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      pluginsdk.LLMMessage `json:"message"`
+			FinishReason string               `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	header := http.Header{}
+	if key != "" {
+		header.Set("Authorization", "Bearer "+key)
+	}
+	if err := llmPost(ctx, pc.baseURL()+"/chat/completions", header, body, &out); err != nil {
+		return pluginsdk.LLMResponse{}, err
+	}
+	resp := pluginsdk.LLMResponse{
+		Model: out.Model,
+		Usage: pluginsdk.LLMUsage{InputTokens: out.Usage.PromptTokens, OutputTokens: out.Usage.CompletionTokens},
+	}
+	if len(out.Choices) > 0 {
+		resp.Content = out.Choices[0].Message.Content
+		resp.StopReason = out.Choices[0].FinishReason
+	}
+	return resp, nil
+}
+
+// anthropicComplete sends a request to the messages API of Anthropic
+func anthropicComplete(ctx context.Context, pc LLMProviderConfig, key string, req pluginsdk.LLMRequest) (pluginsdk.LLMResponse, error) {
+	body := map[string]interface{}{"model": req.Model, "messages": req.Messages, "max_tokens": req.MaxTokens}
+	if req.System != "" {
+		body["system"] = req.System
+	}
+	if req.Temperature != nil {
+		body["temperature"] = *req.Temperature
+	}
+
+	var out struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	header := http.Header{}
+	header.Set("x-api-key", key)
+	header.Set("anthropic-version", anthropicVersion)
+	if err := llmPost(ctx, pc.baseURL()+"/messages", header, body, &out); err != nil {
+		return pluginsdk.LLMResponse{}, err
+	}
+	resp := pluginsdk.LLMResponse{
+		Model:      out.Model,
+		StopReason: out.StopReason,
+		Usage:      pluginsdk.LLMUsage{InputTokens: out.Usage.InputTokens, OutputTokens: out.Usage.OutputTokens},
+	}
+	var text strings.Builder
+	for _, c := range out.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+	resp.Content = text.String()
+	return resp, nil
+}
+
+// llmPost posts body as JSON and decodes the answer into out. Failures
+// become plugin errors classified by status; their messages are the
+// provider's, which never include the key.
+func llmPost(ctx context.Context, url string, header http.Header, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "%v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return pluginsdk.NewError(pluginsdk.CodeInternal, "%v", err)
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return &pluginsdk.PluginError{Code: pluginsdk.CodeTimeout, Message: "LLM provider did not answer in time", Retryable: true}
+		}
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: fmt.Sprintf("LLM provider unreachable: %v", err), Retryable: true}
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, llmResponseLimit))
+	if err != nil {
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: fmt.Sprintf("failed to read the LLM provider's answer: %v", err), Retryable: true}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return llmStatusError(resp.StatusCode, raw)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return pluginsdk.NewError(pluginsdk.CodeInternal, "unexpected answer from the LLM provider: %v", err)
+	}
+	return nil
+}
+
+// llmStatusError classifies a provider's failure by its status
+func llmStatusError(status int, body []byte) error {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	msg := http.StatusText(status)
+	if json.Unmarshal(body, &e) == nil && e.Error.Message != "" {
+		msg = e.Error.Message
+	}
+	msg = fmt.Sprintf("LLM provider answered %d: %s", status, msg)
+	switch {
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeInvalidArgument, Message: msg}
+	case status == http.StatusNotFound:
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeNotFound, Message: msg}
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: msg}
+	case status == http.StatusTooManyRequests || status >= 500:
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: msg, Retryable: true}
+	}
+	return &pluginsdk.PluginError{Code: pluginsdk.CodeUnknown, Message: msg}
+}
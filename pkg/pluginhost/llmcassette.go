@@ -0,0 +1,184 @@
+package pluginhost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Cassette modes of the LLM service
+const (
+	// CassetteRecord sends requests to the providers and records their
+	// completions
+	CassetteRecord = "record"
+
+	// CassetteReplay answers requests from the recorded completions and
+	// never reaches a provider
+	CassetteReplay = "replay"
+)
+
+// LLMCassetteConfig records the completions of the LLM service to a file,
+// a cassette, or answers from one, so plugin tests get the same completions
+// every run without API keys or network
+type LLMCassetteConfig struct {
+	// Mode is CassetteRecord or CassetteReplay; empty sends requests to
+	// the providers as usual
+	Mode string `json:"mode"`
+
+	// Path is the cassette file
+	Path string `json:"path"`
+}
+
+func (c *LLMCassetteConfig) validate() error {
+	switch c.Mode {
+	case "":
+		return nil
+	case CassetteRecord, CassetteReplay:
+	default:
+		return fmt.Errorf("llm.cassette.mode must be %q or %q", CassetteRecord, CassetteReplay)
+	}
+	if c.Path == "" {
+		return fmt.Errorf("llm.cassette.path must be set")
+	}
+	return nil
+}
+
+// CassetteEntry is one recorded completion
+type CassetteEntry struct {
+	// Hash is the prompt hash requests are matched by
+	Hash string `json:"hash"`
+
+	// Provider, Model, System and Messages are the request recorded, for
+	// reviewing cassettes; only the hash is matched
+	Provider string                 `json:"provider"`
+	Model    string                 `json:"model"`
+	System   string                 `json:"system,omitempty"`
+	Messages []pluginsdk.LLMMessage `json:"messages"`
+
+	Response   pluginsdk.LLMResponse `json:"response"`
+	RecordedAt time.Time             `json:"recorded_at"`
+}
+
+// Cassette is the file of recorded completions, sorted by hash so
+// re-recording gives small diffs
+type Cassette struct {
+	Entries []CassetteEntry `json:"entries"`
+}
+
+// PromptHash returns the hash requests are matched by in cassettes. It
+// covers the provider, the model, the system prompt and the messages with
+// their whitespace collapsed, so reformatting a prompt does not miss its
+// recording; max_tokens and temperature are not part of it.
+func PromptHash(req pluginsdk.LLMRequest) string {
+	normalized := struct {
+		Provider string                 `json:"provider"`
+		Model    string                 `json:"model"`
+		System   string                 `json:"system"`
+		Messages []pluginsdk.LLMMessage `json:"messages"`
+	}{req.Provider, req.Model, collapseSpace(req.System), make([]pluginsdk.LLMMessage, len(req.Messages))}
+	for i, m := range req.Messages {
+		normalized.Messages[i] = pluginsdk.LLMMessage{Role: m.Role, Content: collapseSpace(m.Content)}
+	}
+	data, _ := json.Marshal(normalized)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// collapseSpace trims s and turns every run of whitespace into one space
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// llmCassette holds the cassette in use by prompt hash
+type llmCassette struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]CassetteEntry
+}
+
+// load reads the cassette at path unless it is the one loaded. A missing
+// file is an empty cassette.
+func (c *llmCassette) load(path string) error {
+	if c.entries != nil && c.path == path {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		c.path, c.entries = path, make(map[string]CassetteEntry)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	c.path, c.entries = path, make(map[string]CassetteEntry, len(cassette.Entries))
+	for _, e := range cassette.Entries {
+		c.entries[e.Hash] = e
+	}
+	return nil
+}
+
+// replay answers req from the cassette at path
+func (c *llmCassette) replay(path string, req pluginsdk.LLMRequest) (pluginsdk.LLMResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(path); err != nil {
+		return pluginsdk.LLMResponse{}, pluginsdk.NewError(pluginsdk.CodeUnavailable, "%v", err)
+	}
+	hash := PromptHash(req)
+	e, ok := c.entries[hash]
+	if !ok {
+		return pluginsdk.LLMResponse{}, &pluginsdk.PluginError{
+			Code:    pluginsdk.CodeNotFound,
+			Message: fmt.Sprintf("cassette %s has no completion for prompt %s to %s/%s; record it with llm.cassette.mode %q", path, hash[:12], req.Provider, req.Model, CassetteRecord),
+			Details: map[string]string{"hash": hash},
+		}
+	}
+	return e.Response, nil
+}
+
+// record adds the completion of req to the cassette at path, replacing
+// one recorded for the same prompt, and writes the cassette
+func (c *llmCassette) record(path string, req pluginsdk.LLMRequest, resp pluginsdk.LLMResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(path); err != nil {
+		return err
+	}
+	hash := PromptHash(req)
+	c.entries[hash] = CassetteEntry{
+		Hash:       hash,
+		Provider:   req.Provider,
+		Model:      req.Model,
+		System:     req.System,
+		Messages:   req.Messages,
+		Response:   resp,
+		RecordedAt: time.Now().UTC(),
+	}
+
+	cassette := Cassette{Entries: make([]CassetteEntry, 0, len(c.entries))}
+	for _, e := range c.entries {
+		cassette.Entries = append(cassette.Entries, e)
+	}
+	sort.Slice(cassette.Entries, func(i, j int) bool { return cassette.Entries[i].Hash < cassette.Entries[j].Hash })
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
@@ -0,0 +1,536 @@
+package pluginhost
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Periods of LLM budgets, in UTC
+const (
+	BudgetDay   = "day"
+	BudgetMonth = "month"
+)
+
+// Dimensions a cost report can be broken down by
+var costDimensions = []string{"plugin", "capability", "caller", "provider", "model"}
+
+// LLMPrice is what a model costs, in US dollars per million tokens
+type LLMPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// cost returns the estimated cost of usage
+func (p LLMPrice) cost(usage pluginsdk.LLMUsage) float64 {
+	return (float64(usage.InputTokens)*p.Input + float64(usage.OutputTokens)*p.Output) / 1e6
+}
+
+// price returns the price of a model of the provider; "*" prices the
+// models not listed, and models without a price cost nothing
+func (p LLMProviderConfig) price(model string) LLMPrice {
+	if price, ok := p.Prices[model]; ok {
+		return price
+	}
+	return p.Prices["*"]
+}
+
+// LLMBudget is a hard limit on LLM spending. Once the requests it matches
+// spent it in the current period, further ones are refused; a request
+// already running when that happens still completes.
+type LLMBudget struct {
+	// Name identifies the budget in refusals and reports
+	Name string `json:"name"`
+
+	// Plugin, Capability and Caller select the requests counted; empty
+	// matches any, so a budget naming only a plugin is shared by all its
+	// capabilities and callers. Callers are named like principals, e.g.
+	// "user:ada", or "plugin:hello" for calls from another plugin.
+	Plugin     string `json:"plugin,omitempty"`
+	Capability string `json:"capability,omitempty"`
+	Caller     string `json:"caller,omitempty"`
+
+	// Period is BudgetDay or BudgetMonth; defaults to BudgetMonth
+	Period string `json:"period,omitempty"`
+
+	// Tokens caps the input and output tokens; zero is unlimited
+	Tokens int64 `json:"tokens,omitempty"`
+
+	// CostUSD caps the estimated cost in US dollars; zero is unlimited
+	CostUSD float64 `json:"cost_usd,omitempty"`
+}
+
+func (b LLMBudget) validate(field string) error {
+	if b.Name == "" {
+		return fmt.Errorf("%s.name must be set", field)
+	}
+	if b.Period != "" && b.Period != BudgetDay && b.Period != BudgetMonth {
+		return fmt.Errorf("%s.period must be %q or %q", field, BudgetDay, BudgetMonth)
+	}
+	if b.Tokens < 0 || b.CostUSD < 0 {
+		return fmt.Errorf("%s limits must not be negative", field)
+	}
+	if b.Tokens == 0 && b.CostUSD == 0 {
+		return fmt.Errorf("%s must set tokens or cost_usd", field)
+	}
+	return nil
+}
+
+// matches reports whether the budget counts requests attributed to a
+func (b LLMBudget) matches(a llmAttribution) bool {
+	return (b.Plugin == "" || b.Plugin == a.plugin) &&
+		(b.Capability == "" || b.Capability == a.capability) &&
+		(b.Caller == "" || b.Caller == a.caller)
+}
+
+// start returns the first day of the budget's period that contains now
+func (b LLMBudget) start(now time.Time) time.Time {
+	now = now.UTC()
+	if b.Period == BudgetDay {
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// resets returns when the budget's period that contains now ends
+func (b LLMBudget) resets(now time.Time) time.Time {
+	if b.Period == BudgetDay {
+		return b.start(now).AddDate(0, 0, 1)
+	}
+	return b.start(now).AddDate(0, 1, 0)
+}
+
+// LLMBudgetStatus is what a budget's requests spent in its current period
+type LLMBudgetStatus struct {
+	LLMBudget
+
+	SpentTokens  int64     `json:"spent_tokens"`
+	SpentCostUSD float64   `json:"spent_cost_usd"`
+	Exhausted    bool      `json:"exhausted"`
+	Resets       time.Time `json:"resets"`
+}
+
+// llmAttribution is who an LLM request is charged to
+type llmAttribution struct {
+	plugin, capability, caller string
+}
+
+// llmSpendKey identifies what requests of the same attribution spent with
+// a provider's model on one UTC day
+type llmSpendKey struct {
+	day string
+	llmAttribution
+	provider, model string
+}
+
+// llmSpend is what a set of LLM requests spent
+type llmSpend struct {
+	requests, failures        uint64
+	inputTokens, outputTokens uint64
+	cost                      float64
+}
+
+func (s *llmSpend) add(o llmSpend) {
+	s.requests += o.requests
+	s.failures += o.failures
+	s.inputTokens += o.inputTokens
+	s.outputTokens += o.outputTokens
+	s.cost += o.cost
+}
+
+// llmLedgerEntry is one LLM request as the ledger records it
+type llmLedgerEntry struct {
+	Time         time.Time `json:"time"`
+	Plugin       string    `json:"plugin"`
+	Capability   string    `json:"capability,omitempty"`
+	Caller       string    `json:"caller,omitempty"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	CostUSD      float64   `json:"cost_usd"`
+	Failed       bool      `json:"failed,omitempty"`
+}
+
+func (e llmLedgerEntry) key() llmSpendKey {
+	return llmSpendKey{
+		day:            e.Time.UTC().Format(time.DateOnly),
+		llmAttribution: llmAttribution{plugin: e.Plugin, capability: e.Capability, caller: e.Caller},
+		provider:       e.Provider,
+		model:          e.Model,
+	}
+}
+
+func (e llmLedgerEntry) spend() llmSpend {
+	sp := llmSpend{requests: 1, inputTokens: uint64(e.InputTokens), outputTokens: uint64(e.OutputTokens), cost: e.CostUSD}
+	if e.Failed {
+		sp.failures = 1
+	}
+	return sp
+}
+
+// lockLLM locks the LLM state, first reading the spending back from
+// ledger when it is not the ledger read last
+func (pm *PluginManager) lockLLM(ledger string) *llmState {
+	s := &pm.llm
+	s.mu.Lock()
+	if s.spend == nil || ledger != s.ledger {
+		if err := s.load(ledger); err != nil {
+			pm.hostLog.Printf("Failed to read LLM ledger %s: %v", ledger, err)
+		}
+	}
+	return s
+}
+
+// load replaces the spending with what ledger records; an empty ledger
+// path starts from nothing
+func (s *llmState) load(ledger string) error {
+	s.ledger = ledger
+	s.spend = make(map[llmSpendKey]*llmSpend)
+	s.totals = make(map[string]*llmSpend)
+	if ledger == "" {
+		return nil
+	}
+	f, err := os.Open(ledger)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var bad int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e llmLedgerEntry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			bad++
+			continue
+		}
+		s.add(e.key(), e.spend())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if bad > 0 {
+		return fmt.Errorf("skipped %d malformed line(s)", bad)
+	}
+	return nil
+}
+
+// add counts what requests spent
+func (s *llmState) add(key llmSpendKey, sp llmSpend) {
+	if s.spend[key] == nil {
+		s.spend[key] = &llmSpend{}
+	}
+	s.spend[key].add(sp)
+	if s.totals[key.plugin] == nil {
+		s.totals[key.plugin] = &llmSpend{}
+	}
+	s.totals[key.plugin].add(sp)
+}
+
+// record counts a request and appends it to the ledger
+func (s *llmState) record(e llmLedgerEntry) error {
+	s.add(e.key(), e.spend())
+	if s.ledger == "" {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.ledger, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// spent sums the spending since the day from matching match
+func (s *llmState) spent(from time.Time, match func(llmSpendKey) bool) llmSpend {
+	day := from.UTC().Format(time.DateOnly)
+	var total llmSpend
+	for key, sp := range s.spend {
+		if key.day >= day && match(key) {
+			total.add(*sp)
+		}
+	}
+	return total
+}
+
+// checkBudgets refuses a request attributed to a when a budget matching it
+// is exhausted
+func (s *llmState) checkBudgets(budgets []LLMBudget, a llmAttribution, now time.Time) error {
+	for _, b := range budgets {
+		if !b.matches(a) {
+			continue
+		}
+		st := s.budgetStatus(b, now)
+		if !st.Exhausted {
+			continue
+		}
+		limit := fmt.Sprintf("%d tokens", b.Tokens)
+		if b.CostUSD > 0 && (b.Tokens == 0 || st.SpentCostUSD >= b.CostUSD) {
+			limit = fmt.Sprintf("$%.2f", b.CostUSD)
+		}
+		return &pluginsdk.PluginError{
+			Code:    pluginsdk.CodeUnavailable,
+			Message: fmt.Sprintf("LLM budget %s of %s per %s is used up until %s", b.Name, limit, b.period(), st.Resets.Format(time.RFC3339)),
+			Details: map[string]string{"budget": b.Name, "resets": st.Resets.Format(time.RFC3339)},
+		}
+	}
+	return nil
+}
+
+// period returns the budget's period with the default applied
+func (b LLMBudget) period() string {
+	if b.Period == "" {
+		return BudgetMonth
+	}
+	return b.Period
+}
+
+// budgetStatus returns what the requests of a budget spent in its period
+func (s *llmState) budgetStatus(b LLMBudget, now time.Time) LLMBudgetStatus {
+	sp := s.spent(b.start(now), func(k llmSpendKey) bool { return b.matches(k.llmAttribution) })
+	st := LLMBudgetStatus{
+		LLMBudget:    b,
+		SpentTokens:  int64(sp.inputTokens + sp.outputTokens),
+		SpentCostUSD: sp.cost,
+		Resets:       b.resets(now),
+	}
+	st.Period = b.period()
+	st.Exhausted = (b.Tokens > 0 && st.SpentTokens >= b.Tokens) || (b.CostUSD > 0 && st.SpentCostUSD >= b.CostUSD)
+	return st
+}
+
+// trackLLMCall registers the call args belong to, so LLM requests its
+// plugin makes for it are charged to its capability and caller, and
+// returns the function removing it
+func (pm *PluginManager) trackLLMCall(plugin string, args map[string]interface{}) func() {
+	id, _ := args[pluginsdk.ArgCallID].(string)
+	if id == "" {
+		return func() {}
+	}
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	a := llmAttribution{plugin: plugin, capability: capability, caller: pm.costCallerOf(args)}
+
+	s := &pm.llm
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.calls == nil {
+		s.calls = make(map[string]llmAttribution)
+	}
+	s.calls[id] = a
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.calls, id)
+	}
+}
+
+// costCallerOf returns who a call is made for: the principal of its
+// request, else the plugin making it
+func (pm *PluginManager) costCallerOf(args map[string]interface{}) string {
+	if principal := pm.PrincipalOf(args); principal.Name != "" {
+		return principal.String()
+	}
+	if caller, _ := args[pluginsdk.ArgCaller].(string); caller != "" {
+		return "plugin:" + caller
+	}
+	return PrincipalAnonymous
+}
+
+// attribution returns who a request of plugin for the call callID is
+// charged to. Requests naming no call of the plugin are charged to its
+// only running call, or to the anonymous caller when it runs several, so
+// the budgets of anonymous calls bound them rather than no caller's.
+func (s *llmState) attribution(plugin, callID string) llmAttribution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a, ok := s.calls[callID]; ok && a.plugin == plugin {
+		return a
+	}
+	var found []llmAttribution
+	for _, a := range s.calls {
+		if a.plugin == plugin {
+			found = append(found, a)
+		}
+	}
+	if len(found) == 1 {
+		return found[0]
+	}
+	return llmAttribution{plugin: plugin, caller: PrincipalAnonymous}
+}
+
+// CostQuery selects and groups the LLM spending of a cost report
+type CostQuery struct {
+	// By lists the dimensions entries are broken down by: "plugin",
+	// "capability", "caller", "provider" and "model"; defaults to plugin
+	By []string
+
+	// Since and Until bound the report to the UTC days they fall on;
+	// zero leaves the report open on that side
+	Since, Until time.Time
+}
+
+// CostEntry is what the requests of one group spent. Only the dimensions
+// the report is broken down by are set.
+type CostEntry struct {
+	Plugin     string `json:"plugin,omitempty"`
+	Capability string `json:"capability,omitempty"`
+	Caller     string `json:"caller,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	Model      string `json:"model,omitempty"`
+
+	Requests     uint64  `json:"requests"`
+	Failures     uint64  `json:"failures"`
+	InputTokens  uint64  `json:"input_tokens"`
+	OutputTokens uint64  `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+func (e *CostEntry) add(sp llmSpend) {
+	e.Requests += sp.requests
+	e.Failures += sp.failures
+	e.InputTokens += sp.inputTokens
+	e.OutputTokens += sp.outputTokens
+	e.CostUSD += sp.cost
+}
+
+// group returns the dimensions of the entry as one string, for sorting
+func (e CostEntry) group() string {
+	return strings.Join([]string{e.Plugin, e.Capability, e.Caller, e.Provider, e.Model}, "\x00")
+}
+
+// CostReport is the LLM spending of plugins, most expensive first
+type CostReport struct {
+	By      []string          `json:"by"`
+	Entries []CostEntry       `json:"entries"`
+	Total   CostEntry         `json:"total"`
+	Budgets []LLMBudgetStatus `json:"budgets"`
+}
+
+// CostReport breaks the LLM spending down as q asks, along with the
+// state of every budget
+func (pm *PluginManager) CostReport(q CostQuery) (*CostReport, error) {
+	by := q.By
+	if len(by) == 0 {
+		by = []string{"plugin"}
+	}
+	for _, d := range by {
+		if !containsString(costDimensions, d) {
+			return nil, fmt.Errorf("unknown dimension %q; costs break down by %s", d, strings.Join(costDimensions, ", "))
+		}
+	}
+	var since, until string
+	if !q.Since.IsZero() {
+		since = q.Since.UTC().Format(time.DateOnly)
+	}
+	if !q.Until.IsZero() {
+		until = q.Until.UTC().Format(time.DateOnly)
+	}
+
+	cfg := pm.Config().LLM
+	s := pm.lockLLM(cfg.Ledger)
+	defer s.mu.Unlock()
+
+	report := &CostReport{By: by, Entries: []CostEntry{}}
+	groups := make(map[CostEntry]*CostEntry)
+	for key, sp := range s.spend {
+		if (since != "" && key.day < since) || (until != "" && key.day > until) {
+			continue
+		}
+		var group CostEntry
+		for _, d := range by {
+			switch d {
+			case "plugin":
+				group.Plugin = key.plugin
+			case "capability":
+				group.Capability = key.capability
+			case "caller":
+				group.Caller = key.caller
+			case "provider":
+				group.Provider = key.provider
+			case "model":
+				group.Model = key.model
+			}
+		}
+		e := groups[group]
+		if e == nil {
+			e = &CostEntry{}
+			*e = group
+			groups[group] = e
+		}
+		e.add(*sp)
+		report.Total.add(*sp)
+	}
+	for _, e := range groups {
+		report.Entries = append(report.Entries, *e)
+	}
+	sort.Slice(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.CostUSD != b.CostUSD {
+			return a.CostUSD > b.CostUSD
+		}
+		if ta, tb := a.InputTokens+a.OutputTokens, b.InputTokens+b.OutputTokens; ta != tb {
+			return ta > tb
+		}
+		return a.group() < b.group()
+	})
+
+	report.Budgets = s.budgetStatuses(cfg.Budgets, time.Now())
+	return report, nil
+}
+
+// LLMBudgets returns what the requests of each budget spent in its
+// current period
+func (pm *PluginManager) LLMBudgets() []LLMBudgetStatus {
+	cfg := pm.Config().LLM
+	s := pm.lockLLM(cfg.Ledger)
+	defer s.mu.Unlock()
+
+	return s.budgetStatuses(cfg.Budgets, time.Now())
+}
+
+// budgetStatuses returns the status of each budget
+func (s *llmState) budgetStatuses(budgets []LLMBudget, now time.Time) []LLMBudgetStatus {
+	statuses := make([]LLMBudgetStatus, 0, len(budgets))
+	for _, b := range budgets {
+		statuses = append(statuses, s.budgetStatus(b, now))
+	}
+	return statuses
+}
+
+// Used returns the share of the budget spent, by whichever of its limits
+// is closer to being reached
+func (st LLMBudgetStatus) Used() float64 {
+	var used float64
+	if st.Tokens > 0 {
+		used = float64(st.SpentTokens) / float64(st.Tokens)
+	}
+	if st.CostUSD > 0 {
+		used = max(used, st.SpentCostUSD/st.CostUSD)
+	}
+	return used
+}
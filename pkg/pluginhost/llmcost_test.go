@@ -0,0 +1,55 @@
+package pluginhost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestUnmappedLLMRequestsAreChargedToAnonymous(t *testing.T) {
+	var s llmState
+	if err := s.load(""); err != nil {
+		t.Fatal(err)
+	}
+	s.calls = map[string]llmAttribution{
+		"c1": {plugin: "review", capability: "diff", caller: "user:ada"},
+		"c2": {plugin: "review", capability: "diff", caller: "user:bob"},
+		"c3": {plugin: "lint", caller: "user:ada"},
+	}
+	budgets := []LLMBudget{{Name: "strangers", Caller: PrincipalAnonymous, Tokens: 100}}
+
+	tests := []struct {
+		name, plugin, callID, want string
+	}{
+		{"known call", "review", "c1", "user:ada"},
+		{"only running call", "lint", "", "user:ada"},
+		{"no call among several", "review", "", PrincipalAnonymous},
+		{"unknown call among several", "review", "c9", PrincipalAnonymous},
+		{"call of another plugin", "review", "c3", PrincipalAnonymous},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if a := s.attribution(tt.plugin, tt.callID); a.caller != tt.want {
+				t.Errorf("charged to %q, want %q", a.caller, tt.want)
+			}
+		})
+	}
+
+	// The budget of anonymous calls bounds the requests no caller is known for
+	now := time.Now()
+	a := s.attribution("review", "")
+	if err := s.checkBudgets(budgets, a, now); err != nil {
+		t.Fatalf("fresh budget refused a request: %v", err)
+	}
+	if err := s.record(llmLedgerEntry{Time: now, Plugin: a.plugin, Caller: a.caller, Provider: "claude", Model: "m", InputTokens: 60, OutputTokens: 40}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.checkBudgets(budgets, a, now)
+	if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != pluginsdk.CodeUnavailable {
+		t.Fatalf("error = %v, want unavailable", err)
+	}
+	if err := s.checkBudgets(budgets, s.attribution("review", "c1"), now); err != nil {
+		t.Errorf("request of a known caller was refused: %v", err)
+	}
+}
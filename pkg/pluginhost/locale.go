@@ -0,0 +1,125 @@
+package pluginhost
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// messages are the host's own translations of its errors
+//
+//go:embed messages/*.json
+var messages embed.FS
+
+// LocaleConfig sets the locale of calls that name none and the messages
+// host errors are localized with
+type LocaleConfig struct {
+	// Default is the locale passed to plugins when a call names none, as a
+	// BCP 47 tag such as "en-US"; empty passes none
+	Default string `json:"default"`
+
+	// Catalog is a directory of "<locale>.json" files adding or replacing
+	// translations of host errors; the host ships English and German
+	Catalog string `json:"catalog"`
+}
+
+func (c *LocaleConfig) validate() error {
+	if c.Default != "" && strings.ContainsAny(c.Default, " /") {
+		return fmt.Errorf("locale.default: invalid locale %q", c.Default)
+	}
+	if c.Catalog != "" {
+		if info, err := os.Stat(c.Catalog); err != nil || !info.IsDir() {
+			return fmt.Errorf("locale.catalog: %s is not a directory", c.Catalog)
+		}
+	}
+	return nil
+}
+
+// loadCatalog returns the host's messages with those of the configured
+// catalog added
+func (c *LocaleConfig) loadCatalog() (*pluginsdk.Catalog, error) {
+	catalog := pluginsdk.NewCatalog("en")
+	if err := catalog.LoadFS(messages, "messages"); err != nil {
+		return nil, err
+	}
+	if c.Catalog != "" {
+		if err := catalog.LoadFS(os.DirFS(c.Catalog), "."); err != nil {
+			return nil, fmt.Errorf("locale.catalog: %w", err)
+		}
+	}
+	return catalog, nil
+}
+
+// localize sets the locale of a call to the closest one the plugin
+// supports. Calls naming no locale get locale.default. Plugins whose
+// manifest lists no locales get the locale as asked; the others get their
+// first locale when none matches. The caller holds pm.mu.
+func (pm *PluginManager) localize(info *pluginInfo, args map[string]interface{}) map[string]interface{} {
+	locale := pluginsdk.Locale(args)
+	if locale == "" {
+		locale = pm.config.Locale.Default
+	}
+	if len(info.Locales) > 0 {
+		if match := pluginsdk.MatchLocale(locale, info.Locales); match != "" {
+			locale = match
+		} else {
+			locale = info.Locales[0]
+		}
+	}
+	if locale == "" || locale == pluginsdk.Locale(args) {
+		return args
+	}
+	localized := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		localized[k] = v
+	}
+	localized[pluginsdk.ArgLocale] = locale
+	return localized
+}
+
+// LocalizeError returns the message of err in the given locale, falling
+// back to locale.default and then English. Lookup errors are translated
+// from the host catalog; errors of plugins are returned as they are, since
+// plugins localize their own messages from the call's locale.
+func (pm *PluginManager) LocalizeError(err error, locale string) string {
+	var lookup *LookupError
+	if !errors.As(err, &lookup) {
+		return err.Error()
+	}
+	pm.mu.RLock()
+	catalog := pm.catalog
+	if locale == "" {
+		locale = pm.config.Locale.Default
+	}
+	pm.mu.RUnlock()
+	if catalog == nil {
+		return err.Error()
+	}
+
+	var key string
+	switch {
+	case lookup.Kind == ErrPluginNotFound:
+		key = "plugin_not_found"
+	case lookup.Kind == ErrCapabilityNotSupported && lookup.Plugin == "":
+		key = "no_provider"
+	case lookup.Kind == ErrCapabilityNotSupported:
+		key = "capability_not_supported"
+	case lookup.Kind == ErrPluginDisabled:
+		key = "plugin_disabled"
+	default:
+		key = "plugin_unhealthy"
+	}
+	msg := catalog.Message(locale, key, lookup.Plugin, lookup.Capability, lookup.Reason)
+	if len(lookup.Suggestions) > 0 {
+		quoted := make([]string, len(lookup.Suggestions))
+		for i, s := range lookup.Suggestions {
+			quoted[i] = fmt.Sprintf("%q", s)
+		}
+		msg += catalog.Message(locale, "did_you_mean", strings.Join(quoted, catalog.Message(locale, "or")))
+	}
+	return msg
+}
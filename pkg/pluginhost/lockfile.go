@@ -0,0 +1,173 @@
+package pluginhost
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Lock modes
+const (
+	// LockEnforce refuses to start binaries that do not match the lockfile
+	LockEnforce = "enforce"
+	// LockWarn starts them anyway and logs the mismatch
+	LockWarn = "warn"
+)
+
+// LockConfig pins plugin binaries to the checksums in a lockfile
+type LockConfig struct {
+	// Path is the lockfile, e.g. "./plugins.lock"; empty disables pinning
+	Path string `json:"path"`
+
+	// Mode is LockEnforce (the default) or LockWarn
+	Mode string `json:"mode"`
+}
+
+func (c *LockConfig) validate() error {
+	switch c.Mode {
+	case "", LockEnforce, LockWarn:
+		return nil
+	}
+	return fmt.Errorf("lock.mode must be %q or %q, got %q", LockEnforce, LockWarn, c.Mode)
+}
+
+// Lockfile maps plugin names to the binaries they were pinned with
+type Lockfile struct {
+	Plugins map[string]LockEntry `json:"plugins"`
+}
+
+// LockEntry is one pinned plugin binary
+type LockEntry struct {
+	Version string `json:"version"`
+
+	// Path identifies binaries without a manifest, whose name is only
+	// known once they run
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// ReadLockfile reads a lockfile
+func ReadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	lf := &Lockfile{}
+	if err := json.Unmarshal(data, lf); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	return lf, nil
+}
+
+// entry finds the pinned entry for the binary at path, by manifest name
+// when there is one and by path otherwise
+func (lf *Lockfile) entry(path string, m *Manifest) (string, LockEntry, bool) {
+	if m != nil {
+		e, ok := lf.Plugins[m.Name]
+		return m.Name, e, ok
+	}
+	for name, e := range lf.Plugins {
+		if filepath.Clean(e.Path) == filepath.Clean(path) {
+			return name, e, true
+		}
+	}
+	return "", LockEntry{}, false
+}
+
+// WriteLockfile pins every registered plugin's binary as it is on disk now
+func (pm *PluginManager) WriteLockfile(path string) error {
+	pm.mu.RLock()
+	lf := &Lockfile{Plugins: make(map[string]LockEntry, len(pm.plugins))}
+	paths := make(map[string]string, len(pm.plugins))
+	for name, info := range pm.plugins {
+		if info.builtin {
+			continue
+		}
+		lf.Plugins[name] = LockEntry{Version: info.Version, Path: info.Path}
+		paths[name] = info.Path
+	}
+	pm.mu.RUnlock()
+
+	for name, p := range paths {
+		sum, err := fileSHA256(p)
+		if err != nil {
+			return fmt.Errorf("failed to hash plugin %s: %w", name, err)
+		}
+		e := lf.Plugins[name]
+		e.SHA256 = sum
+		lf.Plugins[name] = e
+	}
+
+	data, err := json.MarshalIndent(lf, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile: %w", err)
+	}
+	pm.hostLog.Printf("Pinned %d plugin(s) in %s", len(lf.Plugins), path)
+	return nil
+}
+
+// verifyBinary checks the plugin binary at path against the lockfile before
+// it is started. In warn mode mismatches are only logged.
+func (pm *PluginManager) verifyBinary(path string) error {
+	cfg := pm.Config().Lock
+	if cfg.Path == "" {
+		return nil
+	}
+
+	err := pm.checkLock(cfg.Path, path)
+	if err == nil {
+		return nil
+	}
+	if cfg.Mode == LockWarn {
+		pm.hostLog.Printf("Starting plugin %s despite lockfile mismatch: %v", path, err)
+		return nil
+	}
+	return err
+}
+
+func (pm *PluginManager) checkLock(lockPath, path string) error {
+	lf, err := ReadLockfile(lockPath)
+	if err != nil {
+		return fmt.Errorf("failed to read lockfile: %w", err)
+	}
+
+	m := pm.pluginManifest(path)
+	name, e, ok := lf.entry(path, m)
+	if !ok {
+		return fmt.Errorf("plugin %s is not pinned in %s", path, lockPath)
+	}
+	if m != nil && m.Version != e.Version {
+		return fmt.Errorf("plugin %s is version %s, but %s pins %s", name, m.Version, lockPath, e.Version)
+	}
+
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return fmt.Errorf("failed to hash plugin %s: %w", name, err)
+	}
+	if sum != e.SHA256 {
+		return fmt.Errorf("checksum of plugin %s does not match %s: got %s, want %s", name, lockPath, sum, e.SHA256)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,303 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+const (
+	// logHistorySize is how many log entries are kept per plugin
+	logHistorySize = 1000
+
+	// logFollowBuffer is how many entries a follower may fall behind before
+	// entries are dropped for it
+	logFollowBuffer = 256
+)
+
+// LogEntry is one line a plugin wrote to stderr. Lines in hclog's JSON
+// format keep their level and fields; other lines get the level of a
+// leading "[WARN]"-style prefix, or debug, the same as go-plugin assigns.
+type LogEntry struct {
+	Time    time.Time              `json:"time"`
+	Plugin  string                 `json:"plugin"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// pluginLog keeps the recent log entries of one plugin binary and hands new
+// ones to followers. It is keyed by path, so it outlives restarts.
+type pluginLog struct {
+	name      string
+	entries   []LogEntry
+	followers map[chan LogEntry]hclog.Level
+	partial   []byte
+	mu        sync.Mutex
+
+	// level is the level an operator set for the plugin; entries below it
+	// are dropped. NoLevel keeps everything the plugin writes.
+	level hclog.Level
+}
+
+// Write collects stderr output and records each complete line
+func (l *pluginLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		line := l.partial[:i]
+		if len(bytes.TrimSpace(line)) > 0 {
+			if e := parseLogLine(l.name, line); hclog.LevelFromString(e.Level) >= l.level {
+				l.add(e)
+			}
+		}
+		l.partial = l.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// add records e and passes it to followers that want its level. A follower
+// that is not reading loses the entry rather than stalling the plugin's
+// stderr. The caller holds l.mu.
+func (l *pluginLog) add(e LogEntry) {
+	l.entries = append(l.entries, e)
+	if over := len(l.entries) - logHistorySize; over > 0 {
+		l.entries = append(l.entries[:0], l.entries[over:]...)
+	}
+
+	level := hclog.LevelFromString(e.Level)
+	for ch, min := range l.followers {
+		if level < min {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// setName labels entries written from now on with the plugin's name, which
+// is only known once the plugin has started or its manifest was read
+func (l *pluginLog) setName(name string) {
+	l.mu.Lock()
+	l.name = name
+	l.mu.Unlock()
+}
+
+// levelEnv passes the level an operator set to a starting plugin process
+func (l *pluginLog) levelEnv() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.level == hclog.NoLevel {
+		return nil
+	}
+	return []string{pluginsdk.EnvLogLevel + "=" + l.level.String()}
+}
+
+// recent returns the kept entries at or above min
+func (l *pluginLog) recent(min hclog.Level) []LogEntry {
+	var out []LogEntry
+	for _, e := range l.entries {
+		if hclog.LevelFromString(e.Level) >= min {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func parseLogLine(name string, line []byte) LogEntry {
+	e := LogEntry{Time: time.Now(), Plugin: name}
+
+	var raw map[string]interface{}
+	if json.Unmarshal(line, &raw) == nil {
+		if level, ok := raw["@level"].(string); ok {
+			e.Level = level
+			e.Message, _ = raw["@message"].(string)
+			if ts, ok := raw["@timestamp"].(string); ok {
+				if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					e.Time = t
+				}
+			}
+			for k, v := range raw {
+				if strings.HasPrefix(k, "@") {
+					continue
+				}
+				if e.Fields == nil {
+					e.Fields = make(map[string]interface{})
+				}
+				e.Fields[k] = v
+			}
+			return e
+		}
+	}
+
+	e.Message = string(line)
+	e.Level = "debug"
+	for _, level := range []string{"trace", "debug", "info", "warn", "error"} {
+		if strings.HasPrefix(e.Message, "["+strings.ToUpper(level)+"]") {
+			e.Level = level
+			break
+		}
+	}
+	return e
+}
+
+// ParseLogLevel parses a level name such as "warn"; empty means every level
+func ParseLogLevel(s string) (hclog.Level, error) {
+	if s == "" {
+		return hclog.Trace, nil
+	}
+	level := hclog.LevelFromString(s)
+	if level == hclog.NoLevel {
+		return level, fmt.Errorf("unknown log level %q", s)
+	}
+	return level, nil
+}
+
+// logFor returns the log of the plugin binary at path, creating it on
+// first use
+func (pm *PluginManager) logFor(path string) *pluginLog {
+	pm.logsMu.Lock()
+	defer pm.logsMu.Unlock()
+
+	l, ok := pm.logs[path]
+	if !ok {
+		l = &pluginLog{name: path, followers: make(map[chan LogEntry]hclog.Level)}
+		pm.logs[path] = l
+	}
+	return l
+}
+
+// pluginLog returns the log of a registered plugin
+func (pm *PluginManager) pluginLog(name string) (*pluginLog, error) {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	pm.mu.RUnlock()
+	if !exists {
+		return nil, pm.pluginNotFound(name)
+	}
+
+	return pm.logFor(info.Path), nil
+}
+
+// Logs returns a plugin's recent log entries at or above min, oldest first
+func (pm *PluginManager) Logs(name string, min hclog.Level) ([]LogEntry, error) {
+	l, err := pm.pluginLog(name)
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.recent(min), nil
+}
+
+// FollowLogs returns a plugin's recent log entries at or above min and a
+// channel receiving the ones written from now on, across restarts of the
+// plugin. Call stop to end following; it closes the channel.
+func (pm *PluginManager) FollowLogs(name string, min hclog.Level) (recent []LogEntry, entries <-chan LogEntry, stop func(), err error) {
+	l, err := pm.pluginLog(name)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ch := make(chan LogEntry, logFollowBuffer)
+	l.mu.Lock()
+	recent = l.recent(min)
+	l.followers[ch] = min
+	l.mu.Unlock()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			l.mu.Lock()
+			delete(l.followers, ch)
+			l.mu.Unlock()
+			close(ch)
+		})
+	}
+	return recent, ch, stop, nil
+}
+
+// SetLogLevel switches the level a plugin logs at while it runs, e.g. to
+// "debug" to look into one plugin without restarting it or touching the
+// others. The level reaches every process of the plugin over a control RPC
+// and processes it starts later, and the host drops entries below it, so
+// plugins predating the RPC are filtered too. An empty level restores the
+// plugin's own level.
+func (pm *PluginManager) SetLogLevel(name, level string) error {
+	min := hclog.NoLevel
+	if level != "" {
+		var err error
+		if min, err = ParseLogLevel(level); err != nil {
+			return err
+		}
+		level = min.String()
+	}
+
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	if !exists {
+		pm.mu.RUnlock()
+		return pm.pluginNotFound(name)
+	}
+	path := info.Path
+	instances := []pluginsdk.CommandPlugin{info.Instance}
+	if info.pool != nil {
+		instances = append(instances, info.pool.workers()...)
+	}
+	pm.mu.RUnlock()
+
+	l := pm.logFor(path)
+	l.mu.Lock()
+	l.level = min
+	l.mu.Unlock()
+
+	for _, instance := range instances {
+		setter, ok := instance.(pluginsdk.LogLevelSetter)
+		if !ok {
+			continue
+		}
+		if err := setter.SetLogLevel(level); err != nil {
+			if pluginsdk.AsPluginError(err).Code != pluginsdk.CodeUnsupported {
+				return fmt.Errorf("failed to set log level of plugin %s: %w", name, err)
+			}
+			pm.hostLog.Printf("Plugin %s predates runtime log levels; filtering its log in the host only", name)
+		}
+	}
+
+	if level == "" {
+		pm.hostLog.Printf("Reset log level of plugin %s", name)
+	} else {
+		pm.hostLog.Printf("Set log level of plugin %s to %s", name, level)
+	}
+	return nil
+}
+
+// logLevelOf returns the level an operator set for the plugin at path, or
+// empty
+func (pm *PluginManager) logLevelOf(path string) string {
+	l := pm.logFor(path)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.level == hclog.NoLevel {
+		return ""
+	}
+	return l.level.String()
+}
@@ -0,0 +1,1370 @@
+// Package pluginhost embeds plugin discovery, loading and execution into a host application
+package pluginhost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+)
+
+// pluginInfo is the manager's internal record of a loaded plugin. Callers
+// outside the manager get a PluginStatus instead.
+type pluginInfo struct {
+	Name         string
+	Version      string
+	Path         string
+	Capabilities []pluginsdk.Capability
+	Flags        []string
+	Events       []string
+	Locales      []string
+	Calls        []string
+	Files        []string
+	LLM          []string
+	Inject       []string
+	CacheTTLs    map[string]time.Duration
+	StartedAt    time.Time
+	Updatable    bool
+
+	// Timeout and CapabilityTimeouts come from the manifest
+	Timeout            Duration
+	CapabilityTimeouts map[string]Duration
+
+	LastCrash   *CrashReport
+	Startup     []PhaseTiming
+	Codec       string
+	Handoff     bool
+	Protocol    string
+	SupplyChain *SupplyChain
+	Build       *BuildInfo
+	Client      *plugin.Client
+	Instance    pluginsdk.CommandPlugin
+
+	stderr     *stderrTail
+	calls      *callHistory
+	stats      *pluginStats
+	loading    bool
+	crashed    bool
+	stopping   bool
+	lazy       bool
+	builtin    bool
+	generation int
+	startMu    sync.Mutex
+
+	// reported is the name the binary reports when a name conflict had it
+	// registered under another; see reports
+	reported string
+
+	// active counts the calls the plugin is serving
+	active atomic.Int32
+
+	// pool holds the processes started besides Client when the plugin
+	// is pooled; replaced with each new main process
+	pool *processPool
+
+	// healthFailures counts consecutive failed liveness checks
+	healthFailures int
+	unresponsive   bool
+
+	// subscription delivers the events listed in the manifest
+	subscription *Subscription
+
+	// declared are the capabilities the process reported when it started;
+	// Capabilities adds those it registered since and drops those it
+	// unregistered
+	declared     []pluginsdk.Capability
+	registered   []pluginsdk.Capability
+	unregistered []string
+
+	// workspace holds the plugin's own directories; nil when disabled
+	workspace *pluginsdk.Workspace
+
+	// Resources is the latest resource sample; lastSample is the raw
+	// reading CPU use is computed from
+	Resources     *ResourceUsage
+	lastSample    *processSample
+	lastSamplePid int
+
+	// daemon supervises the server of daemon plugins; nil for the others
+	daemon *daemonState
+}
+
+// PluginManager manages the lifecycle of plugins
+type PluginManager struct {
+	plugins  map[string]*pluginInfo
+	cache    *ResultCache
+	sessions *sessionRegistry
+	config   *HostConfig
+	dirs     map[string]bool
+	limiter  *rateLimiter
+	crashes  map[string]*CrashReport
+	idleStop chan struct{}
+	history  *historyStore
+	trace    *traceWriter
+	audit    *auditWriter
+	logger   hclog.Logger
+	flags    *FlagRegistry
+	prompts  *templateStore
+	events   *EventBus
+	mu       sync.RWMutex
+
+	// logs holds the captured stderr of each plugin binary by path
+	logs   map[string]*pluginLog
+	logsMu sync.Mutex
+
+	// deprecationWarned records the deprecated capabilities and manifests
+	// already logged
+	deprecationWarned sync.Map
+
+	// healthStop ends the liveness checks; nil while they are disabled
+	healthStop chan struct{}
+
+	// resourceStop ends resource sampling; nil while it is disabled
+	resourceStop chan struct{}
+
+	// poolStop ends the scaling of process pools; nil while no plugin
+	// is pooled
+	poolStop chan struct{}
+
+	// waiting maps the binaries waiting on a dependency to what they are
+	// waiting on
+	waiting map[string]string
+
+	// secretProviders are asked for secrets after the configured ones
+	secretProviders []SecretProvider
+
+	// applyMu serializes Apply
+	applyMu sync.Mutex
+
+	// installMu serializes installs from the registry index
+	installMu sync.Mutex
+
+	// transformers are added with WithTransformer; fixed once created
+	transformers map[string]Transformer
+
+	// revealed holds the secret values handed to plugins, so
+	// redact_secrets can find them in results
+	revealed sync.Map
+
+	// calls tracks plugins calling other plugins, to refuse cycles
+	calls callGraph
+
+	// requests holds the shared contexts of the requests being served
+	requests requestRegistry
+
+	// running holds the cancel functions of the calls in flight
+	running runningCalls
+
+	// mode is the host mode, which gates new calls
+	mode modeState
+
+	// commands is the command catalog plugins are checked against
+	commands []CommandDef
+
+	// hostLog receives the manager's own log messages
+	hostLog *log.Logger
+
+	// hooks are the application's lifecycle callbacks
+	hooks Hooks
+
+	// metrics receives measurements of plugin activity; nil when unused
+	metrics Metrics
+
+	// pins maps plugin names to the only version they may be upgraded to
+	pins map[string]string
+
+	// disabled holds the plugins whose calls Disable refuses
+	disabled map[string]bool
+
+	// draining holds the processes upgrades replaced that are finishing
+	// their calls
+	draining map[*plugin.Client]bool
+
+	// remotes are the attached remote hosts by name
+	remotes map[string]*remoteHost
+
+	// rules activate personas for the router
+	rules []PersonaRule
+
+	// configured is set once the first config was applied; later ones
+	// publish EventConfigChanged
+	configured bool
+
+	// catalog translates host errors
+	catalog *pluginsdk.Catalog
+
+	// slos holds the windows of calls service level objectives are
+	// evaluated over
+	slos *sloTracker
+
+	// state guards saving the registry to the state file
+	state registryState
+
+	// sources are the discovery sources in use by name
+	sources map[string]*sourceState
+
+	// sourceTypes are added with WithSourceType; fixed once created
+	sourceTypes map[string]SourceFactory
+
+	// unaryInterceptors and streamInterceptors are added to the gRPC
+	// connections to plugins with WithUnaryInterceptor and
+	// WithStreamInterceptor; fixed once created
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+
+	// tasks holds the background tasks by ID
+	tasks taskRegistry
+
+	// projects caches the project contexts injected into calls
+	projects projectCache
+
+	// halt is the kill switch
+	halt haltState
+
+	// conflicts are the name conflicts between plugin binaries seen
+	conflicts conflictLog
+
+	// keys serializes calls sharing a concurrency key
+	keys keyLocks
+
+	// watches are the file watches of plugins; fileAudit records their
+	// file access
+	watches   watchRegistry
+	fileAudit *auditWriter
+
+	// environment fingerprints the machine for manifest requirements
+	environment environmentProbe
+
+	// emission applies the quotas and mutes of the events plugins emit
+	emission emissionState
+
+	// handoffs hold the large arguments handed to plugins through files
+	handoffs handoffStores
+
+	// spills hold the results plugins spilled to files
+	spills spillStores
+
+	// featureFlags are the flags gating capabilities
+	featureFlags flagStore
+
+	// llm limits and accounts the LLM requests of plugins
+	llm llmState
+
+	// daemonRestarts carries the restarts of daemon plugins whose process
+	// crashed over to the process started in its place
+	daemonRestarts map[string]int
+}
+
+// newPluginManager creates a manager with default settings, logging to
+// logger
+func newPluginManager(logger *log.Logger) *PluginManager {
+	flags := NewFlagRegistry()
+	flags.logger = logger
+	return &PluginManager{
+		plugins:  make(map[string]*pluginInfo),
+		sessions: newSessionRegistry(logger),
+		config:   DefaultConfig(),
+		dirs:     make(map[string]bool),
+		limiter:  newRateLimiter(),
+		crashes:  make(map[string]*CrashReport),
+		flags:    flags,
+		prompts:  &templateStore{},
+		events:   newEventBus(logger),
+		logs:     make(map[string]*pluginLog),
+		commands: DefaultCommands(),
+		slos:     newSLOTracker(),
+		hostLog:  logger,
+
+		daemonRestarts: make(map[string]int),
+	}
+}
+
+// ApplyConfig switches the manager to a new configuration at runtime. Newly
+// listed plugin directories and discovery sources are discovered, plugins
+// from those that were removed are unloaded, a changed profile loads and
+// unloads plugins to match, and limits, policies and personas take effect
+// for the next call. The plugins are then checked against the command
+// catalog; with commands.strict, missing coverage is returned as an error,
+// though the config stays applied.
+func (pm *PluginManager) ApplyConfig(cfg *HostConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	commands, err := cfg.Commands.loadCommands()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	rules, err := cfg.Routing.loadRules()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := pm.checkTransforms(cfg.Transforms); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := pm.checkSources(cfg.Discovery); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	catalog, err := cfg.Locale.loadCatalog()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	pm.mu.Lock()
+	prev, prevRules, first := pm.config, pm.rules, !pm.configured
+	pm.config = cfg
+	pm.configured = true
+	pm.commands = commands
+	pm.rules = rules
+	pm.catalog = catalog
+	pm.applyHealth(cfg)
+	pm.applyResources(cfg)
+	pm.applyPools(cfg)
+	var discovered []string
+	for dir := range pm.dirs {
+		discovered = append(discovered, dir)
+	}
+	pm.mu.Unlock()
+
+	pm.limiter.Update(cfg.Limits)
+	pm.events.configure(cfg.Events)
+	if err := pm.applyHistory(cfg.History); err != nil {
+		pm.hostLog.Printf("Failed to apply history config: %v", err)
+	}
+	if err := pm.applyTrace(cfg.Trace); err != nil {
+		pm.hostLog.Printf("Failed to apply trace config: %v", err)
+	}
+	if err := pm.applyAudit(cfg.Authorization); err != nil {
+		pm.hostLog.Printf("Failed to apply authorization config: %v", err)
+	}
+	pm.applyRemotes(cfg.Remotes)
+	pm.applyFeatureFlags(cfg.FeatureFlags)
+	pm.applySources(cfg)
+	if err := pm.applyTasks(cfg.Tasks); err != nil {
+		pm.hostLog.Printf("Failed to apply tasks config: %v", err)
+	}
+	if err := pm.applyFileAudit(cfg.Files); err != nil {
+		pm.hostLog.Printf("Failed to apply files config: %v", err)
+	}
+
+	added, removed := diffDirs(discovered, append(append([]string(nil), cfg.PluginDirs...), pm.sourceDirs()...))
+	for _, dir := range removed {
+		pm.unloadDir(dir)
+	}
+	if profileChanged(prev, cfg) {
+		pm.applyProfile(cfg)
+	}
+
+	// On the first config, a saved registry replaces the directory scan
+	if pm.restoreState(cfg.State.Path) {
+		pm.mu.Lock()
+		for _, dir := range added {
+			pm.dirs[dir] = true
+		}
+		pm.mu.Unlock()
+		added = nil
+	}
+	for _, dir := range added {
+		if err := pm.DiscoverPlugins(dir); err != nil {
+			pm.hostLog.Printf("Failed to discover plugins in %s: %v", dir, err)
+		}
+	}
+
+	pm.hostLog.Printf("Applied config: %d plugin dir(s), %d persona(s)", len(cfg.PluginDirs), len(cfg.Personas))
+	if change := configChange(prev, cfg, prevRules, rules); change != nil && !first {
+		pm.events.Publish(Event{Type: EventConfigChanged, Data: change})
+	}
+	if err := pm.checkCommands(); err != nil && cfg.Commands.Strict {
+		return err
+	}
+	return nil
+}
+
+// Config returns the configuration currently in effect
+func (pm *PluginManager) Config() *HostConfig {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	return pm.config
+}
+
+// diffDirs returns the directories only in next and only in prev
+func diffDirs(prev, next []string) (added, removed []string) {
+	inPrev := make(map[string]bool)
+	for _, d := range prev {
+		inPrev[filepath.Clean(d)] = true
+	}
+	inNext := make(map[string]bool)
+	for _, d := range next {
+		d = filepath.Clean(d)
+		inNext[d] = true
+		if !inPrev[d] {
+			added = append(added, d)
+		}
+	}
+	for d := range inPrev {
+		if !inNext[d] {
+			removed = append(removed, d)
+		}
+	}
+	return added, removed
+}
+
+// unloadDir unloads every plugin whose binary lives in dir
+func (pm *PluginManager) unloadDir(dir string) {
+	pm.mu.Lock()
+	delete(pm.dirs, dir)
+	var names []string
+	for name, info := range pm.plugins {
+		if !info.builtin && filepath.Dir(filepath.Clean(info.Path)) == dir {
+			names = append(names, name)
+		}
+	}
+	pm.mu.Unlock()
+
+	for _, name := range names {
+		if err := pm.UnloadPlugin(name); err != nil {
+			pm.hostLog.Printf("Failed to unload plugin %s: %v", name, err)
+		}
+	}
+}
+
+// DiscoverPlugins searches for and loads plugins from the specified directory
+func (pm *PluginManager) DiscoverPlugins(dir string) error {
+	pm.hostLog.Printf("Discovering plugins in: %s", dir)
+
+	pm.mu.Lock()
+	pm.dirs[filepath.Clean(dir)] = true
+	pm.mu.Unlock()
+
+	// Ensure plugin directory exists
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create plugin directory: %w", err)
+	}
+
+	// Find all plugin binaries
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read plugin directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		if ext := filepath.Ext(entry.Name()); ext == manifestExt || ext == BundleExt {
+			continue
+		}
+
+		pluginPath := filepath.Join(dir, entry.Name())
+		if pm.hasPath(pluginPath) {
+			continue
+		}
+		pm.hostLog.Printf("Found potential plugin: %s", pluginPath)
+
+		// Skip plugins outside the active profile when the manifest names
+		// them; otherwise the name is only known once the plugin runs
+		cfg := pm.Config()
+		manifest := pm.pluginManifest(pluginPath)
+		if manifest != nil && !cfg.inProfile(manifest.Name) {
+			pm.hostLog.Printf("Skipping plugin %s: not in profile %q", manifest.Name, cfg.Profile)
+			continue
+		}
+
+		// In lazy mode, plugins with a manifest are registered without
+		// starting them, unless they are listed for warm-up
+		if pm.startsLazily(cfg, manifest) {
+			pm.registerLazy(pluginPath, manifest)
+			continue
+		}
+
+		// Plugins waiting on their dependencies load in the background, so
+		// they do not hold up the others
+		if waiting := pm.registerWaiting(pluginPath); waiting != nil {
+			go pm.discovered(cfg, pluginPath, waiting)
+			continue
+		}
+		pm.discovered(cfg, pluginPath, nil)
+	}
+
+	return nil
+}
+
+// discovered loads a plugin found by DiscoverPlugins and unloads it again
+// when the name it reports is outside the active profile
+func (pm *PluginManager) discovered(cfg *HostConfig, path string, waiting *pluginInfo) {
+	name, err := pm.loadWaiting(path, waiting)
+	if err != nil {
+		pm.hostLog.Printf("Failed to load plugin %s: %v", path, err)
+		return
+	}
+	if !cfg.inProfile(name) {
+		pm.hostLog.Printf("Unloading plugin %s: not in profile %q", name, cfg.Profile)
+		if err := pm.UnloadPlugin(name); err != nil {
+			pm.hostLog.Printf("Failed to unload plugin %s: %v", name, err)
+		}
+	}
+}
+
+// pluginProcess is a started plugin subprocess with its dispensed instance
+type pluginProcess struct {
+	client   *plugin.Client
+	cmd      *exec.Cmd
+	stderr   *stderrTail
+	instance pluginsdk.CommandPlugin
+	name     string
+
+	// startup is how long each startup phase took
+	startup []PhaseTiming
+
+	// codec is the argument codec agreed with the plugin
+	codec string
+
+	// handoff is set when the plugin accepted large arguments through files
+	handoff bool
+
+	// protocol is the protocol the plugin announced and answered
+	protocol string
+
+	// supplyChain is what the binary's SBOM and provenance say
+	supplyChain *SupplyChain
+
+	// build is what the binary recorded of its build
+	build *BuildInfo
+
+	// workspace holds the process's own directories
+	workspace *pluginsdk.Workspace
+}
+
+// startProcess spawns the plugin binary at path, once the machine meets
+// its manifest's requirements, completes the handshake and initializes
+// the plugin. Each phase must finish within the start timeout; a failure
+// is a *StartupError naming the phase.
+func (pm *PluginManager) startProcess(path string) (*pluginProcess, error) {
+	if err := pm.checkRequirements(path); err != nil {
+		return nil, err
+	}
+	if err := pm.verifyBinary(path); err != nil {
+		return nil, err
+	}
+	supplyChain, err := pm.verifySupplyChain(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wait for the services the plugin depends on, outside the start timeout
+	waited, err := pm.awaitDependencies(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create plugin client. The process starts in its workspace, so the
+	// binary is named by its absolute path.
+	binary, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	cmd, err := pm.pluginCommand(binary)
+	if err != nil {
+		return nil, err
+	}
+	workspace, err := pm.prepareWorkspace(path, cmd)
+	if err != nil {
+		return nil, err
+	}
+	cfg := pm.Config()
+	timeout := cfg.Loading.startTimeout()
+	spills, err := pm.spills.store(&cfg.Transport)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Env = append(cmd.Env, cfg.Transport.env(spills)...)
+	cmd.Env = append(cmd.Env, pm.logFor(path).levelEnv()...)
+	stderr := &stderrTail{}
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig: pluginsdk.Handshake,
+		Plugins: map[string]plugin.Plugin{
+			"command": &pluginsdk.CommandPluginImpl{Host: pm.hostServices(path)},
+		},
+		Cmd:             cmd,
+		Stderr:          io.MultiWriter(stderr, pm.logFor(path)),
+		Logger:          pm.logger,
+		StartTimeout:    timeout,
+		GRPCDialOptions: append(cfg.Transport.dialOptions(), pm.interceptorOptions(path)...),
+		AllowedProtocols: []plugin.Protocol{
+			plugin.ProtocolNetRPC,
+			plugin.ProtocolGRPC,
+		},
+	})
+	st := newStartup(path, timeout)
+	if waited > 0 {
+		st.timings = append(st.timings, PhaseTiming{Phase: PhaseDependencies, Duration: waited})
+	}
+
+	// Spawn the process and wait for its handshake, then connect and check
+	// the plugin serves the protocol it announced
+	if _, err := client.Start(); err != nil {
+		client.Kill()
+		return nil, st.fail(startPhase(err), diagnoseHandshake(err))
+	}
+	var rpcClient plugin.ClientProtocol
+	err = st.run(PhaseHandshake, func() error {
+		var err error
+		if rpcClient, err = client.Client(); err == nil {
+			err = rpcClient.Ping()
+		}
+		if err != nil {
+			return diagnoseProtocol(client, err)
+		}
+		return nil
+	})
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	// Get the plugin instance and its name, and agree on an argument codec
+	var pluginInstance pluginsdk.CommandPlugin
+	var name, codec string
+	var handoff bool
+	err = st.run(PhaseDispense, func() error {
+		raw, err := rpcClient.Dispense("command")
+		if err != nil {
+			return err
+		}
+		instance, ok := raw.(pluginsdk.CommandPlugin)
+		if !ok {
+			return fmt.Errorf("plugin does not implement CommandPlugin interface")
+		}
+		pluginInstance, name = instance, instance.Name()
+		if r, ok := instance.(pluginsdk.SpillReceiver); ok {
+			r.ReceiveSpills(spills)
+		}
+		if n, ok := instance.(pluginsdk.CodecNegotiator); ok {
+			if codec, err = n.NegotiateCodec(cfg.Transport.codecs()); err != nil {
+				return err
+			}
+		}
+		if n, ok := instance.(pluginsdk.HandoffNegotiator); ok && cfg.Transport.HandoffBytes > 0 {
+			handoff, err = n.NegotiateHandoff(pm.handoffs.store(&cfg.Transport))
+		}
+		return err
+	})
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	// Pass the plugin its configuration before it takes calls
+	if err := st.run(PhaseInitialize, func() error {
+		return pm.initialize(name, pluginInstance)
+	}); err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	return &pluginProcess{client: client, cmd: cmd, stderr: stderr, instance: pluginInstance, name: name, startup: st.timings, codec: codec, handoff: handoff, protocol: string(client.Protocol()), supplyChain: supplyChain, build: readBuildInfo(binary), workspace: workspace}, nil
+}
+
+// hasPath reports whether the plugin binary at path is already registered
+func (pm *PluginManager) hasPath(path string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, info := range pm.plugins {
+		if filepath.Clean(info.Path) == filepath.Clean(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPlugin loads a single plugin from the specified path
+func (pm *PluginManager) LoadPlugin(path string) error {
+	_, err := pm.loadPlugin(path)
+	return err
+}
+
+// loadPlugin loads a plugin and returns the name it reports
+func (pm *PluginManager) loadPlugin(path string) (string, error) {
+	return pm.loadWaiting(path, pm.registerWaiting(path))
+}
+
+// loadWaiting loads a plugin that registerWaiting may have listed while it
+// waits on its dependencies, and returns the name it reports
+func (pm *PluginManager) loadWaiting(path string, waiting *pluginInfo) (string, error) {
+	// Spawning and the handshake happen without the lock so a slow plugin
+	// does not hold up calls to the others
+	proc, err := pm.startProcess(path)
+	if err != nil {
+		pm.mu.Lock()
+		pm.dropWaiting(waiting)
+		pm.mu.Unlock()
+		return "", err
+	}
+
+	// Another binary may have the name already
+	name, err := pm.resolveName(path, proc.name, proc.instance.Version())
+	if err != nil {
+		proc.client.Kill()
+		pm.mu.Lock()
+		pm.dropWaiting(waiting)
+		pm.mu.Unlock()
+		return "", err
+	}
+
+	// Register the plugin as loading while its metadata is read
+	info := &pluginInfo{
+		Name:  name,
+		Path:  path,
+		calls: &callHistory{},
+		stats: &pluginStats{},
+	}
+	if name != proc.name {
+		info.reported = proc.name
+	}
+	if m := pm.pluginManifest(path); m != nil {
+		info.Flags = m.Flags
+		info.Events = m.Events
+		info.Locales = m.Locales
+		info.Inject = m.Inject
+		info.Updatable = m.Updatable
+		info.Timeout, info.CapabilityTimeouts = m.Timeouts.Default, m.Timeouts.Capabilities
+		info.Calls = m.Permissions.calls()
+		info.Files = m.Permissions.files()
+		info.LLM = m.Permissions.llm()
+	}
+	pm.logFor(path).setName(name)
+
+	pm.mu.Lock()
+	if waiting != nil && pm.plugins[waiting.Name] != waiting {
+		// Unloaded, or the host shut down, while it waited
+		pm.mu.Unlock()
+		proc.client.Kill()
+		return "", fmt.Errorf("plugin %s was unloaded while waiting on its dependencies", waiting.Name)
+	}
+	if cur, taken := pm.plugins[name]; taken && cur != waiting && filepath.Clean(cur.Path) != filepath.Clean(path) {
+		// Another binary took the name while this one started
+		pm.dropWaiting(waiting)
+		pm.mu.Unlock()
+		proc.client.Kill()
+		return "", fmt.Errorf("%w: %s was registered from %s while %s started", ErrNameConflict, name, cur.Path, path)
+	}
+	pm.dropWaiting(waiting)
+	info.LastCrash = pm.crashes[name]
+	pm.plugins[name] = info
+	pm.attach(info, proc)
+	pm.subscribePlugin(info)
+	pm.mu.Unlock()
+
+	pm.readMetadata(info)
+	pm.hostLog.Printf("Loaded plugin: %s v%s", name, info.Version)
+	pm.saveState()
+
+	return name, nil
+}
+
+// attach binds a freshly started process to a plugin record and starts
+// watching it for crashes. The caller holds pm.mu.
+func (pm *PluginManager) attach(info *pluginInfo, proc *pluginProcess) {
+	info.generation++
+	info.Client = proc.client
+	info.Instance = proc.instance
+	info.Startup = proc.startup
+	info.Codec = proc.codec
+	info.Handoff = proc.handoff
+	info.Protocol = proc.protocol
+	info.SupplyChain = proc.supplyChain
+	info.Build = proc.build
+	info.workspace = proc.workspace
+	info.stderr = proc.stderr
+	info.StartedAt = time.Now()
+	info.loading = true
+	info.crashed = false
+	info.stopping = false
+	info.healthFailures = 0
+	info.unresponsive = false
+	info.pool = &processPool{}
+	info.registered, info.unregistered = nil, nil
+	go pm.monitor(info, proc.client, proc.cmd, info.generation)
+}
+
+// readMetadata asks a just-attached plugin for its metadata and marks it ready
+func (pm *PluginManager) readMetadata(info *pluginInfo) {
+	pm.mu.RLock()
+	instance := info.Instance
+	pm.mu.RUnlock()
+
+	md := fetchMetadata(instance)
+
+	pm.mu.Lock()
+	pm.setMetadata(info, md)
+	pm.fillPool(info)
+	pm.mu.Unlock()
+
+	pm.events.Publish(Event{Type: EventPluginLoaded, Plugin: info.Name, Data: map[string]interface{}{"version": md.version}})
+	pm.loaded(info)
+	pm.superviseDaemon(info)
+}
+
+// metadata is what a plugin reports about itself once started
+type metadata struct {
+	version      string
+	capabilities []pluginsdk.Capability
+	cacheTTLs    map[string]time.Duration
+}
+
+// fetchMetadata asks a plugin instance for its metadata
+func fetchMetadata(instance pluginsdk.CommandPlugin) metadata {
+	md := metadata{
+		version:      instance.Version(),
+		capabilities: instance.GetCapabilities(),
+	}
+	if c, ok := instance.(pluginsdk.CacheablePlugin); ok {
+		md.cacheTTLs = c.CacheTTLs()
+	}
+	return md
+}
+
+// setMetadata records a plugin's metadata and marks it ready, dropping the
+// results cached from its previous process. The caller holds pm.mu.
+func (pm *PluginManager) setMetadata(info *pluginInfo, md metadata) {
+	info.Version = md.version
+	info.declared = md.capabilities
+	info.Capabilities = info.capabilities()
+	info.CacheTTLs = md.cacheTTLs
+	info.loading = false
+	if pm.cache != nil {
+		pm.cache.InvalidatePlugin(info.Name)
+	}
+}
+
+// ExecutePlugin executes a command on the specified plugin. The manager is
+// not locked while the plugin runs, so plugins can call other plugins
+// through the host; unloading a plugin ends the calls it is serving. The
+// result is converted into a format the accept option of the call lists,
+// see ResultFormat, then passes through the configured transformers.
+func (pm *PluginManager) ExecutePlugin(name string, args map[string]interface{}) (string, error) {
+	res, err := pm.executeConverted(name, withoutReserved(args))
+	return res.Result, err
+}
+
+// executeConverted runs a call the way ExecutePlugin does, saying whether
+// the result is partial
+func (pm *PluginManager) executeConverted(name string, args map[string]interface{}) (ExecuteResult, error) {
+	if err := pm.checkFormat(name, args); err != nil {
+		return ExecuteResult{}, err
+	}
+	if err := pm.admit(name, args); err != nil {
+		return ExecuteResult{}, err
+	}
+	res, err := pm.executeResult(name, args)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	if res.Result, err = pm.finishResult(name, args, res.Result); err != nil {
+		return ExecuteResult{}, err
+	}
+	return res, nil
+}
+
+// executeRaw runs a call the way ExecutePlugin does, but returns the result
+// as the plugin produced it
+func (pm *PluginManager) executeRaw(name string, args map[string]interface{}) (string, error) {
+	if err := pm.admit(name, args); err != nil {
+		return "", err
+	}
+	return pm.execute(name, args)
+}
+
+// execute runs a call the host mode admitted. Calls plugins make to other
+// plugins come here directly, as they are part of a call already running.
+func (pm *PluginManager) execute(name string, args map[string]interface{}) (string, error) {
+	res, err := pm.executeResult(name, args)
+	return res.Result, err
+}
+
+// executeResult runs a call the way execute does, saying whether the
+// result is partial
+func (pm *PluginManager) executeResult(name string, args map[string]interface{}) (ExecuteResult, error) {
+	if err := pm.checkHalt(); err != nil {
+		return ExecuteResult{}, err
+	}
+	if err := pm.authorize(name, args); err != nil {
+		return ExecuteResult{}, err
+	}
+	if err := pm.checkFlags(name, args); err != nil {
+		return ExecuteResult{}, err
+	}
+	if r := pm.remoteFor(name); r != nil {
+		return pm.executeRemote(r, name, args)
+	}
+	if err := pm.ensureRunning(name); err != nil {
+		return ExecuteResult{}, err
+	}
+	args = pm.withProject(name, args)
+
+	call, err := pm.prepareCall(name, args)
+	if err != nil {
+		return ExecuteResult{}, err
+	}
+	if call.cached {
+		return ExecuteResult{Result: call.result}, nil
+	}
+	return pm.runCall(name, call)
+}
+
+// runCall runs a prepared call that was not served from the cache and
+// ends it, recording each attempt
+func (pm *PluginManager) runCall(name string, call *preparedCall) (ExecuteResult, error) {
+	defer call.info.active.Add(-1)
+	if call.worker != nil {
+		defer call.pool.release(call.worker)
+	}
+	args := call.args
+	var err error
+
+	// Execute the plugin, retrying idempotent capabilities per policy
+	// within the call's timeout
+	policy := call.policy
+	ctx, cancel := context.WithTimeoutCause(context.Background(), call.timeout, errTimeout(name, call.timeout))
+	defer cancel()
+	ctx, cancelCall := context.WithCancelCause(ctx)
+	defer cancelCall(nil)
+	untrack := pm.running.add(pluginsdk.ContextOf(args).RequestID, cancelCall)
+	defer untrack()
+
+	// Calls sharing a concurrency key run one at a time; the wait counts
+	// against the call's timeout
+	if call.concurrencyKey != "" {
+		release, err := pm.keys.acquire(ctx, call.concurrencyKey, name, pluginsdk.ContextOf(args).RequestID)
+		if err != nil {
+			pm.hostLog.Printf("Call to %s gave up waiting for concurrency key %s: %v", name, call.concurrencyKey, err)
+			return ExecuteResult{}, fmt.Errorf("plugin execution failed: %w", err)
+		}
+		defer release()
+	}
+	var result string
+	var partial bool
+	var elapsed time.Duration
+	pm.mu.RLock()
+	recorded := pm.recordArgs(call.info, args)
+	pm.mu.RUnlock()
+	begun := time.Now()
+	attempt := 1
+	for ; ; attempt++ {
+		start := time.Now()
+		result, err = pm.invokeCancelable(ctx, name, call, withCallID(args))
+		if partial = errors.Is(err, pluginsdk.ErrPartial); partial {
+			err = nil
+		}
+		if err != nil && ctx.Err() != nil {
+			err = context.Cause(ctx)
+			if isTimeout(err) {
+				pm.hostLog.Printf("Call to %s timed out after %v", name, call.timeout)
+			} else {
+				pm.hostLog.Printf("Call to %s was canceled", name)
+			}
+		}
+		rec := CallRecord{Time: start, Args: recorded, Duration: time.Since(start), Attempt: attempt, Partial: partial}
+		elapsed += rec.Duration
+		pm.observeAttempt(name, args, rec.Duration, err)
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		call.info.calls.add(rec)
+		call.info.stats.record(err)
+		pm.mu.RLock()
+		pm.recordHistory(name, recorded, start, result, err)
+		pm.mu.RUnlock()
+
+		if err == nil || attempt >= policy.MaxAttempts || !isRetryable(err) {
+			break
+		}
+		delay := policy.delay(attempt)
+		if deadline, _ := ctx.Deadline(); time.Until(deadline) <= delay {
+			break
+		}
+		pm.hostLog.Printf("Retrying %s after %v (attempt %d of %d): %v", name, delay, attempt+1, policy.MaxAttempts, err)
+		time.Sleep(delay)
+	}
+	pm.mu.RLock()
+	pm.recordTrace(call.info, recorded, begun, elapsed, result, err)
+	pm.mu.RUnlock()
+	pm.publishExecuted(name, args, err)
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	execution := Execution{Plugin: name, Capability: capability, Duration: elapsed, Attempts: attempt, Err: err}
+	pm.executed(execution)
+	pm.observeSLO(execution)
+	if err != nil {
+		return ExecuteResult{}, fmt.Errorf("plugin execution failed: %w", err)
+	}
+
+	// A spilled result's file belongs to the caller, and a partial one is
+	// incomplete, so neither is cached
+	if _, spilled := pluginsdk.ResultFile(result); call.cacheKey != "" && !spilled && !partial {
+		call.cache.Put(name, call.cacheKey, result, call.ttl)
+	}
+
+	return ExecuteResult{Result: result, Partial: partial}, nil
+}
+
+// preparedCall is a call that passed the host's checks, with what running
+// it needs taken from the registry
+type preparedCall struct {
+	info     *pluginInfo
+	instance pluginsdk.CommandPlugin
+	args     map[string]interface{}
+	policy   RetryPolicy
+	timeout  time.Duration
+
+	// worker is the process of the plugin's pool serving the call, to be
+	// released when it ends; nil for plugins that are not pooled
+	pool   *processPool
+	worker *poolWorker
+
+	// cached is set when result came from the cache; the call is not run
+	cached bool
+	result string
+
+	// cacheKey is where the result is stored, when it may be cached
+	cache    *ResultCache
+	cacheKey string
+	ttl      time.Duration
+
+	// concurrencyKey is the key of the capability, which calls hold one
+	// at a time
+	concurrencyKey string
+
+	// wrapUpAt is when the call's latency budget runs out; zero when it
+	// has none
+	wrapUpAt time.Time
+}
+
+// prepareCall checks that a call may run and resolves its capability. Calls
+// that will run are counted as active until the caller decrements
+// info.active, which keeps the idle reaper from stopping the plugin.
+func (pm *PluginManager) prepareCall(name string, args map[string]interface{}) (*preparedCall, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	info, exists := pm.plugins[name]
+	if !exists {
+		return nil, pm.errPluginNotFound(name)
+	}
+	if pm.disabled[name] {
+		return nil, &LookupError{Kind: ErrPluginDisabled, Plugin: name}
+	}
+	if reason, waiting := pm.waiting[info.Path]; waiting && info.Instance == nil {
+		return nil, &LookupError{Kind: ErrPluginUnhealthy, Plugin: name, Reason: "waiting on dependency " + reason}
+	}
+	if info.loading {
+		return nil, &LookupError{Kind: ErrPluginUnhealthy, Plugin: name, Reason: "still loading"}
+	}
+	if info.crashed && info.Instance != nil {
+		return nil, &LookupError{Kind: ErrPluginUnhealthy, Plugin: name, Reason: "crashed; reload it to start a new process"}
+	}
+	if info.Instance == nil {
+		// Stopped for idleness between ensureRunning and taking the lock
+		return nil, &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: "plugin is not running: " + name, Retryable: true}
+	}
+
+	if pm.config.isDenied(name) {
+		return nil, fmt.Errorf("plugin denied by policy: %s", name)
+	}
+	args, err := pm.config.CallEnv.scopeCall(args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve versioned capability names such as "greet@v2"; calls that
+	// cannot be routed do not count against the rate limit
+	args, err = pm.resolveCapability(info, args)
+	if err != nil {
+		return nil, err
+	}
+	timeout, args, err := pm.callTimeout(info, args)
+	if err != nil {
+		return nil, err
+	}
+	budget, args, err := latencyBudget(info, args)
+	if err != nil {
+		return nil, err
+	}
+	args = pm.localize(info, args)
+	if !pm.limiter.Allow(name) {
+		return nil, fmt.Errorf("rate limit exceeded for plugin: %s", name)
+	}
+	call := &preparedCall{info: info, instance: info.Instance, args: args, timeout: timeout}
+	if budget > 0 {
+		call.wrapUpAt = time.Now().Add(budget)
+	}
+
+	// Serve from cache when the plugin declared this capability cacheable
+	if pm.cache != nil {
+		capability, _ := args[pluginsdk.ArgCapability].(string)
+		if ttl := info.CacheTTLs[capability]; ttl > 0 {
+			if key, ok := cacheKey(capability, args); ok {
+				if result, hit := pm.cache.Get(name, key); hit {
+					call.cached, call.result = true, result
+					return call, nil
+				}
+				call.cache, call.cacheKey, call.ttl = pm.cache, key, ttl
+			}
+		}
+	}
+
+	call.policy = pm.retryPolicy(info, args)
+	call.concurrencyKey = concurrencyKey(info, args)
+	info.active.Add(1)
+	if cfg, pooled := pm.config.Pools[name]; pooled && info.pool != nil {
+		w, grow := info.pool.acquire(cfg, info.Instance)
+		call.pool, call.worker, call.instance = info.pool, w, w.instance
+		if grow {
+			go pm.startWorker(info, info.pool, info.generation)
+		}
+	}
+	return call, nil
+}
+
+// UnloadPlugin unloads a specific plugin once it drained
+func (pm *PluginManager) UnloadPlugin(name string) error {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	if !exists {
+		err := pm.errPluginNotFound(name)
+		pm.mu.RUnlock()
+		return err
+	}
+	targets := drainTargets(info)
+	pm.mu.RUnlock()
+	pm.drain(targets)
+
+	pm.mu.Lock()
+
+	info, exists = pm.plugins[name]
+	if !exists {
+		err := pm.errPluginNotFound(name)
+		pm.mu.Unlock()
+		return err
+	}
+
+	// Sessions are bound to the process, so they end with it
+	pm.sessions.closePluginSessions(name)
+
+	// Kill the plugin process
+	info.stopping = true
+	if info.Client != nil {
+		info.Client.Kill()
+	}
+	stopPool(info)
+
+	// Remove from registry
+	delete(pm.plugins, name)
+	if pm.cache != nil {
+		pm.cache.InvalidatePlugin(name)
+	}
+	pm.mu.Unlock()
+
+	// Event delivery may wait for subscribers, so it happens unlocked
+	if info.subscription != nil {
+		info.subscription.Close()
+	}
+	pm.stopWatches(name)
+	pm.cleanWorkspace(name, info.workspace)
+	pm.hostLog.Printf("Unloaded plugin: %s", name)
+	pm.events.Publish(Event{Type: EventPluginUnloaded, Plugin: name})
+	pm.slos.forget(name)
+	pm.unloaded(name)
+	pm.saveState()
+
+	return nil
+}
+
+// ReloadPlugin reloads a plugin (useful for hot-reload)
+func (pm *PluginManager) ReloadPlugin(name string) error {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	pm.mu.RUnlock()
+
+	if !exists {
+		return pm.pluginNotFound(name)
+	}
+	if info.builtin {
+		return errBuiltin(name, "reloaded")
+	}
+
+	path := info.Path
+
+	// Unload the current version
+	if err := pm.UnloadPlugin(name); err != nil {
+		return fmt.Errorf("failed to unload plugin: %w", err)
+	}
+
+	// Load the new version
+	if err := pm.LoadPlugin(path); err != nil {
+		return fmt.Errorf("failed to reload plugin: %w", err)
+	}
+
+	pm.hostLog.Printf("Reloaded plugin: %s", name)
+	return nil
+}
+
+// Shutdown gracefully shuts down all plugins
+func (pm *PluginManager) Shutdown() {
+	// Calls held by maintenance mode are refused rather than left waiting
+	if pm.Mode().Mode == ModeMaintenance {
+		pm.SetMode(ModeReadOnly)
+	}
+	pm.sessions.closeAll()
+	pm.interruptTasks()
+
+	// Plugins drain before the lock is taken, as they may call the host
+	pm.mu.RLock()
+	var targets []drainTarget
+	for _, info := range pm.plugins {
+		targets = append(targets, drainTargets(info)...)
+	}
+	pm.mu.RUnlock()
+	pm.drain(targets)
+
+	// Workspaces are cleaned once the lock is released
+	var workspaces []*pluginInfo
+	defer func() {
+		for _, info := range workspaces {
+			pm.cleanWorkspace(info.Name, info.workspace)
+		}
+	}()
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.idleStop != nil {
+		close(pm.idleStop)
+		pm.idleStop = nil
+	}
+	if pm.healthStop != nil {
+		close(pm.healthStop)
+		pm.healthStop = nil
+	}
+	if pm.resourceStop != nil {
+		close(pm.resourceStop)
+		pm.resourceStop = nil
+	}
+	if pm.poolStop != nil {
+		close(pm.poolStop)
+		pm.poolStop = nil
+	}
+	if pm.featureFlags.stop != nil {
+		close(pm.featureFlags.stop)
+		pm.featureFlags.stop = nil
+	}
+
+	for name, info := range pm.plugins {
+		pm.hostLog.Printf("Shutting down plugin: %s", name)
+		info.stopping = true
+		if info.Client != nil {
+			info.Client.Kill()
+		}
+		stopPool(info)
+		workspaces = append(workspaces, info)
+	}
+	for client := range pm.draining {
+		client.Kill()
+	}
+	pm.draining = nil
+
+	pm.plugins = make(map[string]*pluginInfo)
+	if pm.cache != nil {
+		pm.cache = NewResultCacheSize(pm.cache.max)
+	}
+	if pm.history != nil {
+		if err := pm.history.close(); err != nil {
+			pm.hostLog.Printf("Failed to close history: %v", err)
+		}
+		pm.history = nil
+	}
+	pm.closeRemotes()
+	if pm.trace != nil {
+		if err := pm.trace.close(); err != nil {
+			pm.hostLog.Printf("Failed to close trace: %v", err)
+		}
+		pm.trace = nil
+	}
+	if pm.audit != nil {
+		if err := pm.audit.close(); err != nil {
+			pm.hostLog.Printf("Failed to close audit log: %v", err)
+		}
+		pm.audit = nil
+	}
+	pm.stopWatches("")
+	pm.handoffs.close()
+	pm.spills.close()
+	if pm.fileAudit != nil {
+		if err := pm.fileAudit.close(); err != nil {
+			pm.hostLog.Printf("Failed to close file audit log: %v", err)
+		}
+		pm.fileAudit = nil
+	}
+	pm.events.closeAll()
+}
+
+// monitor waits for a plugin process to exit and, unless the host stopped
+// it, records a crash report and writes a diagnostics bundle
+func (pm *PluginManager) monitor(info *pluginInfo, client *plugin.Client, cmd *exec.Cmd, generation int) {
+	for !client.Exited() {
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	// A newer process may already have replaced this one
+	pm.mu.RLock()
+	stale := info.stopping || info.generation != generation
+	crashDir := pm.config.CrashDir
+	pm.mu.RUnlock()
+	if stale {
+		return
+	}
+
+	report := &CrashReport{
+		Time:       time.Now(),
+		ExitCode:   -1,
+		StderrTail: info.stderr.String(),
+	}
+	if cmd.ProcessState != nil {
+		report.ExitCode = cmd.ProcessState.ExitCode()
+	}
+
+	bundle, err := writeCrashBundle(crashDir, info, report, info.calls.snapshot())
+	if err != nil {
+		pm.hostLog.Printf("Failed to write diagnostics for crashed plugin %s: %v", info.Name, err)
+	} else {
+		report.BundlePath = bundle
+	}
+
+	pm.mu.Lock()
+	info.LastCrash = report
+	info.crashed = true
+	pm.crashes[info.Name] = report
+	stopPool(info)
+	if info.lazy && info.generation == generation {
+		// Lazy plugins are started again by the next call
+		info.Client = nil
+		info.Instance = nil
+	}
+	pm.mu.Unlock()
+
+	pm.hostLog.Printf("Plugin %s crashed (exit code %d), diagnostics: %s", info.Name, report.ExitCode, report.BundlePath)
+	pm.events.Publish(Event{Type: EventPluginCrashed, Plugin: info.Name, Data: map[string]interface{}{"exit_code": report.ExitCode, "bundle": report.BundlePath}})
+}
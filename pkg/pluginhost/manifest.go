@@ -0,0 +1,122 @@
+package pluginhost
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// manifestExt is the extension of the manifest stored next to a plugin
+// binary: plugins/plugin-hello is described by plugins/plugin-hello.json
+const manifestExt = ".json"
+
+// Manifest describes a plugin without having to start it
+type Manifest struct {
+	// SchemaVersion is the version of the manifest format; manifests of
+	// the previous version are read as if migrated to ManifestSchemaVersion
+	SchemaVersion int `json:"schema_version"`
+
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+	Author      string `json:"author"`
+
+	// Capabilities may be listed as bare names or as full descriptions
+	Capabilities []pluginsdk.Capability `json:"capabilities"`
+
+	// Flags lists the SuperClaude flags the plugin honors, e.g. "think" or
+	// "uc"; other flags are not passed to it
+	Flags []string `json:"flags"`
+
+	// Events lists the event types delivered to the plugin's HandleEvent,
+	// e.g. "file.saved" or "buffer.*"
+	Events []string `json:"events"`
+
+	// Locales lists the locales the plugin can answer in, e.g. "en" and
+	// "de-DE", first the one it prefers; calls get the closest of them
+	Locales []string `json:"locales"`
+
+	// Permissions is what the plugin needs when the host sandboxes it
+	Permissions *Permissions `json:"permissions"`
+
+	// Timeouts bound the plugin's calls instead of timeouts.default of the
+	// host
+	Timeouts ManifestTimeouts `json:"timeouts"`
+
+	// Dependencies are external services that must be ready before the
+	// plugin process starts, e.g. its database
+	Dependencies []Dependency `json:"dependencies"`
+
+	// Inject lists what the host computes and adds to every call, e.g.
+	// "project" for the project context in pluginsdk.ArgProject
+	Inject []string `json:"inject"`
+
+	// Updatable lets UpdatePlugin replace the plugin with newer compatible
+	// versions from the registry index
+	Updatable bool `json:"updatable"`
+
+	// Requires is what the plugin needs of the machine; plugins whose
+	// requirements are not met are not started
+	Requires *Requirements `json:"requires"`
+
+	// Migrated explains what changed reading a manifest of the previous
+	// schema version; empty for current manifests
+	Migrated []ManifestChange `json:"-"`
+}
+
+// ManifestTimeouts bound the calls of a plugin
+type ManifestTimeouts struct {
+	// Default bounds all calls of the plugin
+	Default Duration `json:"default"`
+
+	// Capabilities bound the calls of single capabilities instead of
+	// Default
+	Capabilities map[string]Duration `json:"capabilities"`
+}
+
+// manifestPath returns where the manifest for a plugin binary lives
+func manifestPath(binary string) string {
+	return binary + manifestExt
+}
+
+// LoadManifest reads and validates a plugin manifest
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := parseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if m.Name == "" {
+		return nil, fmt.Errorf("manifest %s has no name", path)
+	}
+	for _, c := range m.Capabilities {
+		if err := c.ValidateHints(); err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", path, err)
+		}
+	}
+	for _, d := range m.Dependencies {
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", path, err)
+		}
+	}
+	for _, inject := range m.Inject {
+		if inject != InjectProject {
+			return nil, fmt.Errorf("manifest %s: unknown inject %q", path, inject)
+		}
+	}
+	if err := m.Requires.validate(); err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+	for _, grant := range m.Permissions.files() {
+		if _, _, err := parseFileGrant(grant); err != nil {
+			return nil, fmt.Errorf("manifest %s: %w", path, err)
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,265 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ManifestSchemaVersion is the manifest format this host writes. Manifests
+// of the previous version are still read, with a deprecation warning;
+// "superplugin manifest migrate" upgrades them.
+const ManifestSchemaVersion = 2
+
+// ManifestChange is one change a migration made to a manifest and why
+type ManifestChange struct {
+	// To is the schema version the change belongs to
+	To int `json:"to"`
+
+	// Field is the top-level field changed, e.g. "capabilities"
+	Field string `json:"field"`
+
+	Explanation string `json:"explanation"`
+}
+
+// manifestFields is a manifest as its top-level fields, so migrations keep
+// what they do not know about
+type manifestFields map[string]json.RawMessage
+
+// manifestMigrations upgrade a manifest of schema version i+1 to i+2
+var manifestMigrations = []func(manifestFields) ([]ManifestChange, error){
+	migrateManifestV1,
+}
+
+// migrateManifestV1 upgrades a manifest to schema version 2, which lists
+// capabilities only as objects and groups the timeouts of a plugin like
+// the host configuration does
+func migrateManifestV1(f manifestFields) ([]ManifestChange, error) {
+	var changes []ManifestChange
+
+	if raw, ok := f["capabilities"]; ok {
+		var entries []json.RawMessage
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return nil, fmt.Errorf("capabilities: %w", err)
+		}
+		var bare []string
+		for i, e := range entries {
+			var name string
+			if json.Unmarshal(e, &name) != nil {
+				continue
+			}
+			entries[i], _ = json.Marshal(map[string]string{"name": name})
+			bare = append(bare, name)
+		}
+		if len(bare) > 0 {
+			f["capabilities"], _ = json.Marshal(entries)
+			changes = append(changes, ManifestChange{To: 2, Field: "capabilities", Explanation: fmt.Sprintf(
+				"turned the bare names %s into {\"name\": ...} objects; capabilities are only listed as objects now, which can carry a description, tags and hints", joinQuoted(bare))})
+		}
+	}
+
+	timeouts := make(map[string]json.RawMessage)
+	if raw, ok := f["timeout"]; ok {
+		timeouts["default"] = raw
+		delete(f, "timeout")
+		changes = append(changes, ManifestChange{To: 2, Field: "timeouts", Explanation: "moved timeout to timeouts.default, matching timeouts.default of the host configuration"})
+	}
+	if raw, ok := f["capability_timeouts"]; ok {
+		timeouts["capabilities"] = raw
+		delete(f, "capability_timeouts")
+		changes = append(changes, ManifestChange{To: 2, Field: "timeouts", Explanation: "moved capability_timeouts to timeouts.capabilities"})
+	}
+	if len(timeouts) > 0 {
+		if _, ok := f["timeouts"]; ok {
+			return nil, fmt.Errorf("manifest sets both timeouts and timeout or capability_timeouts")
+		}
+		f["timeouts"], _ = json.Marshal(timeouts)
+	}
+	return changes, nil
+}
+
+// checkManifestV2 refuses what schema version 2 no longer accepts, so a
+// manifest half migrated by hand is not read with fields silently ignored
+func checkManifestV2(f manifestFields) error {
+	for old, now := range map[string]string{"timeout": "timeouts.default", "capability_timeouts": "timeouts.capabilities"} {
+		if _, ok := f[old]; ok {
+			return fmt.Errorf("%s was replaced by %s in schema version 2", old, now)
+		}
+	}
+	var entries []json.RawMessage
+	if raw, ok := f["capabilities"]; ok && json.Unmarshal(raw, &entries) == nil {
+		for _, e := range entries {
+			var name string
+			if json.Unmarshal(e, &name) == nil {
+				return fmt.Errorf("capability %q is a bare name; schema version 2 lists capabilities as objects such as {\"name\": %q}", name, name)
+			}
+		}
+	}
+	return nil
+}
+
+// schemaVersion returns the schema version a manifest declares; manifests
+// predating the field are version 1
+func (f manifestFields) schemaVersion() (int, error) {
+	raw, ok := f["schema_version"]
+	if !ok || bytes.Equal(raw, []byte("null")) {
+		return 1, nil
+	}
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil || v < 1 {
+		return 0, fmt.Errorf("invalid schema_version %s", raw)
+	}
+	return v, nil
+}
+
+// migrate upgrades the fields from schema version from to to
+func (f manifestFields) migrate(from, to int) ([]ManifestChange, error) {
+	var changes []ManifestChange
+	for v := from; v < to; v++ {
+		c, err := manifestMigrations[v-1](f)
+		if err != nil {
+			return nil, fmt.Errorf("migrating to schema version %d: %w", v+1, err)
+		}
+		changes = append(changes, c...)
+	}
+	f["schema_version"], _ = json.Marshal(to)
+	return changes, nil
+}
+
+// parseManifest reads a manifest of the current schema version or the one
+// before, which is migrated first; older and newer manifests are refused
+func parseManifest(data []byte) (*Manifest, error) {
+	var f manifestFields
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	version, err := f.schemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []ManifestChange
+	switch {
+	case version > ManifestSchemaVersion:
+		return nil, fmt.Errorf("schema version %d is newer than this host reads (%d); update the host", version, ManifestSchemaVersion)
+	case version < ManifestSchemaVersion-1:
+		return nil, fmt.Errorf("schema version %d is no longer read; upgrade it with \"superplugin manifest migrate\"", version)
+	case version < ManifestSchemaVersion:
+		if changes, err = f.migrate(version, ManifestSchemaVersion); err != nil {
+			return nil, err
+		}
+		if data, err = json.Marshal(f); err != nil {
+			return nil, err
+		}
+	default:
+		if err := checkManifestV2(f); err != nil {
+			return nil, err
+		}
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if version < ManifestSchemaVersion {
+		m.Migrated = append([]ManifestChange{{To: ManifestSchemaVersion, Field: "schema_version", Explanation: fmt.Sprintf(
+			"schema version %d is deprecated and will not be read by the next host release", version)}}, changes...)
+	}
+	return m, nil
+}
+
+// MigrateManifest upgrades the manifest in data to schema version to,
+// returning it indented and what changed. A manifest already at that
+// version is returned unchanged, with no changes.
+func MigrateManifest(data []byte, to int) ([]byte, []ManifestChange, error) {
+	if to < 1 || to > ManifestSchemaVersion {
+		return nil, nil, fmt.Errorf("unknown schema version %d; the newest is %d", to, ManifestSchemaVersion)
+	}
+	var f manifestFields
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	from, err := f.schemaVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+	if from > to {
+		return nil, nil, fmt.Errorf("manifest is schema version %d; migrating down to %d is not supported", from, to)
+	}
+	if from == to {
+		return data, nil, nil
+	}
+
+	changes, err := f.migrate(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes = append(changes, ManifestChange{To: to, Field: "schema_version", Explanation: fmt.Sprintf("set schema_version to %d", to)})
+	out, err := f.indent()
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := parseManifest(out); err != nil && to == ManifestSchemaVersion {
+		return nil, nil, fmt.Errorf("migrated manifest is invalid: %w", err)
+	}
+	return out, changes, nil
+}
+
+// indent encodes the fields in the order Manifest declares them, followed
+// by those it does not know in alphabetical order
+func (f manifestFields) indent() ([]byte, error) {
+	order := make(map[string]int)
+	t := reflect.TypeOf(Manifest{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		order[name] = i
+	}
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		oi, known := order[keys[i]]
+		oj, knownJ := order[keys[j]]
+		if known != knownJ {
+			return known
+		}
+		if known {
+			return oi < oj
+		}
+		return keys[i] < keys[j]
+	})
+
+	var b bytes.Buffer
+	b.WriteString("{\n")
+	for i, k := range keys {
+		name, _ := json.Marshal(k)
+		fmt.Fprintf(&b, "  %s: ", name)
+		if err := json.Indent(&b, f[k], "  ", "  "); err != nil {
+			return nil, err
+		}
+		if i < len(keys)-1 {
+			b.WriteByte(',')
+		}
+		b.WriteByte('\n')
+	}
+	b.WriteString("}\n")
+	return b.Bytes(), nil
+}
+
+// joinQuoted lists names quoted and sorted, for explanations
+func joinQuoted(names []string) string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	var b bytes.Buffer
+	for i, n := range sorted {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%q", n)
+	}
+	return b.String()
+}
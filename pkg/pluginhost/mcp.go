@@ -0,0 +1,133 @@
+package pluginhost
+
+import "strings"
+
+// mcpMaxToolName is the longest tool name MCP clients accept
+const mcpMaxToolName = 64
+
+// defaultPersonaMCP are the MCP servers each SuperClaude persona prefers,
+// primary first, named as the server flags name them
+var defaultPersonaMCP = map[string][]string{
+	"architect":   {"sequential", "context7"},
+	"frontend":    {"magic", "playwright"},
+	"backend":     {"context7", "sequential"},
+	"analyzer":    {"sequential", "context7"},
+	"security":    {"sequential", "context7"},
+	"mentor":      {"context7", "sequential"},
+	"refactorer":  {"sequential", "context7"},
+	"performance": {"playwright", "sequential"},
+	"qa":          {"playwright", "sequential"},
+	"devops":      {"sequential", "context7"},
+	"scribe":      {"context7", "sequential"},
+}
+
+// MCPServers returns the MCP servers persona prefers: its mcp list in the
+// config, else the built-in preference of the SuperClaude persona
+func (r *PersonaRouter) MCPServers(persona string) []string {
+	r.pm.mu.RLock()
+	defer r.pm.mu.RUnlock()
+
+	if p, ok := r.pm.config.Personas[persona]; ok && p.MCP != nil {
+		return append([]string(nil), p.MCP...)
+	}
+	return append([]string(nil), defaultPersonaMCP[persona]...)
+}
+
+// addMCPServers appends servers to the mcp list of options, after those
+// already chosen. Options with no_mcp set are left without servers.
+func addMCPServers(options map[string]interface{}, servers []string) {
+	if options["no_mcp"] == true || len(servers) == 0 {
+		return
+	}
+	var list []interface{}
+	switch given := options["mcp"].(type) {
+	case []interface{}:
+		list = append(list, given...)
+	case []string:
+		for _, s := range given {
+			list = append(list, s)
+		}
+	}
+	for _, s := range servers {
+		if !containsValue(list, s) {
+			list = append(list, s)
+		}
+	}
+	options["mcp"] = list
+}
+
+// noMCP reports whether flags include --no-mcp
+func (pm *PluginManager) noMCP(flags []string) bool {
+	for _, flag := range flags {
+		spec, _, err := pm.flags.resolve(flag)
+		if err == nil && spec.Option == "no_mcp" {
+			return true
+		}
+	}
+	return false
+}
+
+// MCPTool describes a capability as a tool of the Model Context Protocol,
+// as a bridge lists it to MCP clients in its tools/list response
+type MCPTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// MCPTools returns the capabilities FindCapabilities finds for query as MCP
+// tools, so clients are offered what the loaded plugins can do, best
+// matches first. Tool names are "<plugin>__<capability>" with characters
+// MCP does not allow replaced by "_"; the plugin and capability to call are
+// recovered with MCPToolTarget.
+func (pm *PluginManager) MCPTools(query string) []MCPTool {
+	matches := pm.FindCapabilities(query)
+	tools := make([]MCPTool, 0, len(matches))
+	for _, m := range matches {
+		c := m.Capability
+		description := c.Description
+		if description == "" {
+			description = c.Ref() + " of the " + m.Plugin + " plugin"
+		}
+		if len(c.Tags) > 0 {
+			description += " [" + strings.Join(c.Tags, ", ") + "]"
+		}
+		if c.Deprecated != "" {
+			description += " Deprecated: " + c.Deprecated
+		}
+		schema := c.ArgsSchema
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		tools = append(tools, MCPTool{Name: mcpToolName(m.Plugin, c.Ref()), Description: description, InputSchema: schema})
+	}
+	return tools
+}
+
+// MCPToolTarget returns the plugin and capability an MCP tool of MCPTools
+// stands for
+func (pm *PluginManager) MCPToolTarget(tool string) (plugin, capability string, err error) {
+	for _, p := range pm.ListPlugins() {
+		for _, c := range p.Capabilities {
+			if mcpToolName(p.Name, c.Ref()) == tool {
+				return p.Name, c.Ref(), nil
+			}
+		}
+	}
+	return "", "", &LookupError{Kind: ErrCapabilityNotSupported, Capability: tool}
+}
+
+// mcpToolName names a capability's tool within the characters and length
+// MCP allows for tool names
+func mcpToolName(plugin, capability string) string {
+	name := []byte(plugin + "__" + capability)
+	for i, b := range name {
+		if !(b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '-') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > mcpMaxToolName {
+		name = name[:mcpMaxToolName]
+	}
+	return string(name)
+}
@@ -0,0 +1,196 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// HostMode controls whether the host runs plugin calls
+type HostMode string
+
+const (
+	// ModeNormal runs every call
+	ModeNormal HostMode = "normal"
+
+	// ModeReadOnly refuses calls; listing and inspecting plugins still work
+	ModeReadOnly HostMode = "read_only"
+
+	// ModeMaintenance holds new calls until the host leaves maintenance,
+	// at most for their timeout, while calls already running finish. Use it
+	// to upgrade plugins without failing callers.
+	ModeMaintenance HostMode = "maintenance"
+)
+
+// EventHostModeChanged is published when the host mode changes
+const EventHostModeChanged = "host.mode_changed"
+
+// ModeStatus is the host mode and the calls it affects
+type ModeStatus struct {
+	Mode  HostMode  `json:"mode"`
+	Since time.Time `json:"since"`
+
+	// Queued counts the calls held by maintenance mode
+	Queued int `json:"queued"`
+
+	// Active counts the calls plugins are running; an upgrade can start
+	// once it reaches zero in maintenance mode
+	Active int `json:"active"`
+}
+
+// modeState is the host mode and the calls waiting for it to change
+type modeState struct {
+	mode   HostMode
+	since  time.Time
+	queued int
+
+	// resume is closed when the host leaves maintenance
+	resume chan struct{}
+	mu     sync.Mutex
+}
+
+// ParseHostMode checks a mode name such as "read_only"
+func ParseHostMode(s string) (HostMode, error) {
+	switch m := HostMode(s); m {
+	case ModeNormal, ModeReadOnly, ModeMaintenance:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown host mode %q", s)
+}
+
+// SetMode switches the host mode. Leaving maintenance releases the held
+// calls, which run in normal mode and are refused in read-only mode.
+func (pm *PluginManager) SetMode(mode HostMode) error {
+	if _, err := ParseHostMode(string(mode)); err != nil {
+		return err
+	}
+
+	m := &pm.mode
+	m.mu.Lock()
+	prev := m.current()
+	if mode == prev {
+		m.mu.Unlock()
+		return nil
+	}
+	if mode == ModeMaintenance {
+		m.resume = make(chan struct{})
+	} else if prev == ModeMaintenance {
+		close(m.resume)
+	}
+	m.mode = mode
+	m.since = time.Now()
+	queued := m.queued
+	m.mu.Unlock()
+
+	pm.hostLog.Printf("Host mode changed from %s to %s (%d queued call(s))", prev, mode, queued)
+	pm.events.Publish(Event{Type: EventHostModeChanged, Data: map[string]interface{}{
+		"mode":     string(mode),
+		"previous": string(prev),
+	}})
+	return nil
+}
+
+// Mode returns the host mode and how many calls it holds or is running
+func (pm *PluginManager) Mode() ModeStatus {
+	m := &pm.mode
+	m.mu.Lock()
+	status := ModeStatus{Mode: m.current(), Since: m.since, Queued: m.queued}
+	m.mu.Unlock()
+
+	pm.mu.RLock()
+	for _, info := range pm.plugins {
+		status.Active += int(info.active.Load())
+	}
+	pm.mu.RUnlock()
+	return status
+}
+
+// current returns the mode; the zero state is normal. The caller holds m.mu.
+func (m *modeState) current() HostMode {
+	if m.mode == "" {
+		return ModeNormal
+	}
+	return m.mode
+}
+
+// admit lets a new call of plugin name with args through, holding it while
+// the host is in maintenance and refusing it in read-only mode or while
+// halted. A held call waits at most its timeout and gives up when its
+// request is canceled, failing with pluginsdk.CodeUnavailable.
+func (pm *PluginManager) admit(name string, args map[string]interface{}) error {
+	m := &pm.mode
+	m.mu.Lock()
+	var held context.Context
+	for m.current() == ModeMaintenance {
+		if held == nil {
+			m.mu.Unlock()
+			var release func()
+			held, release = pm.holdContext(name, args)
+			defer release()
+			m.mu.Lock()
+			continue
+		}
+		resume := m.resume
+		m.queued++
+		m.mu.Unlock()
+		select {
+		case <-resume:
+		case <-held.Done():
+			m.mu.Lock()
+			m.queued--
+			m.mu.Unlock()
+			return &pluginsdk.PluginError{
+				Code:      pluginsdk.CodeUnavailable,
+				Message:   fmt.Sprintf("host is in maintenance; stopped waiting: %v", context.Cause(held)),
+				Retryable: true,
+			}
+		}
+		m.mu.Lock()
+		m.queued--
+	}
+	mode := m.current()
+	m.mu.Unlock()
+
+	if mode == ModeReadOnly {
+		return &pluginsdk.PluginError{Code: pluginsdk.CodeUnavailable, Message: "host is read-only; plugin calls are disabled"}
+	}
+	return pm.checkHalt()
+}
+
+// holdContext returns the context a call held by maintenance waits in: it
+// ends when the call's timeout runs out or its request is canceled
+func (pm *PluginManager) holdContext(name string, args map[string]interface{}) (context.Context, func()) {
+	timeout := pm.admitTimeout(name, args)
+	ctx, cancel := context.WithTimeoutCause(context.Background(), timeout, errTimeout(name, timeout))
+	ctx, cancelCall := context.WithCancelCause(ctx)
+	untrack := pm.running.add(pluginsdk.ContextOf(args).RequestID, cancelCall)
+	return ctx, func() {
+		untrack()
+		cancelCall(nil)
+		cancel()
+	}
+}
+
+// admitTimeout returns the timeout of a call that has not been admitted
+// yet: that of the plugin, or of the host for plugins it does not run
+func (pm *PluginManager) admitTimeout(name string, args map[string]interface{}) time.Duration {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if info, ok := pm.plugins[name]; ok {
+		if timeout, _, err := pm.callTimeout(info, args); err == nil {
+			return timeout
+		}
+	}
+	timeout := time.Duration(pm.config.Timeouts.Default)
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+	if d, err := pluginsdk.ParseTimeout(args[pluginsdk.ArgTimeout]); err == nil && d > 0 {
+		timeout = d
+	}
+	return timeout
+}
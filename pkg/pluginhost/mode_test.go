@@ -0,0 +1,74 @@
+package pluginhost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestMaintenanceHoldsCallsWithinBounds(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		rc   *pluginsdk.RequestContext
+		stop func(pm *PluginManager)
+	}{
+		{
+			name: "timeout",
+			args: map[string]interface{}{pluginsdk.ArgTimeout: "50ms"},
+			stop: func(pm *PluginManager) {},
+		},
+		{
+			name: "canceled request",
+			args: map[string]interface{}{},
+			rc:   &pluginsdk.RequestContext{RequestID: "r1"},
+			stop: func(pm *PluginManager) {
+				time.Sleep(50 * time.Millisecond)
+				pm.CancelRequest("r1", "client went away")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, err := New(WithBuiltin(&sessionPlugin{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pm.Shutdown()
+			if err := pm.SetMode(ModeMaintenance); err != nil {
+				t.Fatal(err)
+			}
+
+			go tt.stop(pm)
+			start := time.Now()
+			_, err = pm.Execute("sessions", tt.args, tt.rc)
+			if pe := pluginsdk.AsPluginError(err); err == nil || pe.Code != pluginsdk.CodeUnavailable {
+				t.Fatalf("error = %v, want unavailable", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("held for %v", elapsed)
+			}
+			if q := pm.Mode().Queued; q != 0 {
+				t.Errorf("Queued = %d after giving up, want 0", q)
+			}
+		})
+	}
+}
+
+func TestLeavingMaintenanceReleasesCalls(t *testing.T) {
+	pm, err := New(WithBuiltin(&sessionPlugin{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+	pm.SetMode(ModeMaintenance)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		pm.SetMode(ModeNormal)
+	}()
+	if got, err := pm.ExecutePlugin("sessions", map[string]interface{}{}); err != nil || got != "call" {
+		t.Errorf("got %q, %v; want the call to run", got, err)
+	}
+}
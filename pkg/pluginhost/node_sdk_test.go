@@ -0,0 +1,84 @@
+package pluginhost_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestNodePluginOverGRPC(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		skipSDK(t, "node is not installed")
+	}
+	sdk, err := filepath.Abs(filepath.Join("..", "..", "sdk", "node"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(sdk, "dist", "examples", "hello.js")
+	if _, err := os.Stat(script); err != nil {
+		skipSDK(t, "the Node SDK is not built: cd sdk/node && npm install && npm run build")
+	}
+
+	// The SDK loads the proto it copied when it was built
+	want, err := os.ReadFile(filepath.Join("..", "pluginsdk", "proto", "command.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(sdk, "proto", "command.proto")); err != nil || !bytes.Equal(got, want) {
+		t.Fatal("the Node SDK was built from another proto: cd sdk/node && npm run build")
+	}
+
+	// The host starts binaries, so the script is run through a wrapper
+	dir := t.TempDir()
+	wrapper := fmt.Sprintf("#!/bin/sh\nexec %q %q\n", node, script)
+	if err := os.WriteFile(filepath.Join(dir, "hello-node"), []byte(wrapper), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	pm, err := pluginhost.New(pluginhost.WithPluginDirs(dir), pluginhost.WithLogOutput(&strings.Builder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	st, err := pm.GetPlugin("hello-node")
+	if err != nil {
+		t.Fatalf("plugin not loaded: %v", err)
+	}
+	if st.Protocol != "grpc" {
+		t.Errorf("protocol = %q, want grpc", st.Protocol)
+	}
+	if len(st.Capabilities) == 0 || st.Capabilities[0].Name != "greet" {
+		t.Errorf("capabilities = %v, want greet first", st.Capabilities)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"default", map[string]interface{}{}, "Hello World from SuperClaude integration!"},
+		{"casual", map[string]interface{}{"name": "Ada", "type": "casual"}, "Hey Ada! Ready to enhance OpenCode with AI?"},
+		{"technical", map[string]interface{}{"name": "Ada", "type": "technical"}, "Plugin 'hello-node' v1.0.0 initialized. Target: Ada. Integration: operational."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args[pluginsdk.ArgCapability] = "greet"
+			got, err := pm.ExecutePlugin("hello-node", tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package pluginhost
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Option configures a PluginManager created with New
+type Option func(*options)
+
+// options collects the settings passed to New before the manager starts
+type options struct {
+	config       *HostConfig
+	cache        bool
+	cacheSize    int
+	sessionIdle  time.Duration
+	logOutput    io.Writer
+	secrets      []SecretProvider
+	logger       *log.Logger
+	dirs         []string
+	limits       *LimitsConfig
+	metrics      Metrics
+	hooks        Hooks
+	transformers map[string]Transformer
+	sourceTypes  map[string]SourceFactory
+	builtins     []pluginsdk.CommandPlugin
+	unary        []grpc.UnaryClientInterceptor
+	stream       []grpc.StreamClientInterceptor
+}
+
+// WithConfig applies cfg when the manager is created, discovering the
+// plugins in its plugin directories. Without it, WithPluginDirs or
+// WithLimits the manager starts empty with DefaultConfig and plugins are
+// loaded explicitly.
+func WithConfig(cfg *HostConfig) Option {
+	return func(o *options) {
+		o.config = cfg
+	}
+}
+
+// WithPluginDirs sets the directories scanned for plugins, replacing those
+// of the config given to WithConfig
+func WithPluginDirs(dirs ...string) Option {
+	return func(o *options) {
+		o.dirs = append(o.dirs, dirs...)
+	}
+}
+
+// WithLimits sets the rate limits, replacing those of the config given to
+// WithConfig
+func WithLimits(limits LimitsConfig) Option {
+	return func(o *options) {
+		o.limits = &limits
+	}
+}
+
+// WithLogger sends the manager's log messages to l instead of the standard
+// logger. Plugin processes log through WithLogOutput.
+func WithLogger(l *log.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithMetrics reports call and startup measurements to m, for applications
+// that keep their own metrics registry
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithHooks runs the callbacks set in h on plugin lifecycle events
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = h
+	}
+}
+
+// WithCache turns on result caching for capabilities that plugins declare
+// cacheable
+func WithCache() Option {
+	return func(o *options) {
+		o.cache = true
+	}
+}
+
+// WithCacheSize turns on result caching like WithCache, holding at most n
+// results instead of DefaultResultCacheSize
+func WithCacheSize(n int) Option {
+	return func(o *options) {
+		o.cache = true
+		o.cacheSize = n
+	}
+}
+
+// WithSessionIdleTimeout sets how long idle sessions are kept open. The
+// default is DefaultSessionIdleTimeout.
+func WithSessionIdleTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.sessionIdle = d
+	}
+}
+
+// WithLogOutput sends go-plugin's log of plugin processes, including the
+// plugins' own log lines, to w instead of stderr
+func WithLogOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.logOutput = w
+	}
+}
+
+// WithSecretProvider adds a provider for "secret://" references in
+// plugin_config, asked after the providers the config lists. Use it for
+// secret stores the host has no built-in provider for.
+func WithSecretProvider(p SecretProvider) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, p)
+	}
+}
+
+// WithTransformer registers t under name, for the transforms section of
+// the config to apply to results. It replaces a built-in transformer of the
+// same name.
+func WithTransformer(name string, t Transformer) Option {
+	return func(o *options) {
+		if o.transformers == nil {
+			o.transformers = make(map[string]Transformer)
+		}
+		o.transformers[name] = t
+	}
+}
+
+// WithSourceType registers factory for discovery sources of type kind,
+// for origins of plugins the host has no built-in source for. It replaces
+// a built-in type of the same name.
+func WithSourceType(kind string, factory SourceFactory) Option {
+	return func(o *options) {
+		if o.sourceTypes == nil {
+			o.sourceTypes = make(map[string]SourceFactory)
+		}
+		o.sourceTypes[kind] = factory
+	}
+}
+
+// WithBuiltin registers p as a built-in plugin running inside the host
+// process once the config is applied; see RegisterBuiltin
+func WithBuiltin(p pluginsdk.CommandPlugin) Option {
+	return func(o *options) {
+		o.builtins = append(o.builtins, p)
+	}
+}
+
+// WithUnaryInterceptor adds client interceptors to the unary calls the host
+// makes to plugins served over gRPC, e.g. to attach auth headers or request
+// IDs or to record telemetry. They run in the order added, for plugin
+// processes started after the manager was created; PluginCallFromContext
+// tells them which plugin and call they intercept. Plugins served over
+// net/rpc are not intercepted.
+func WithUnaryInterceptor(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(o *options) {
+		o.unary = append(o.unary, interceptors...)
+	}
+}
+
+// WithStreamInterceptor adds client interceptors to the streaming calls
+// the host makes to plugins served over gRPC, such as sessions; see
+// WithUnaryInterceptor
+func WithStreamInterceptor(interceptors ...grpc.StreamClientInterceptor) Option {
+	return func(o *options) {
+		o.stream = append(o.stream, interceptors...)
+	}
+}
+
+// New creates a plugin manager configured by opts. Call Shutdown when done
+// to stop every plugin process.
+func New(opts ...Option) (*PluginManager, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	logger := o.logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	pm := newPluginManager(logger)
+	pm.metrics = o.metrics
+	pm.hooks = o.hooks
+	if o.cache {
+		pm.cache = NewResultCacheSize(o.cacheSize)
+	}
+	if o.sessionIdle > 0 {
+		pm.sessions.idleTimeout = o.sessionIdle
+	}
+	pm.secretProviders = o.secrets
+	pm.transformers = o.transformers
+	pm.sourceTypes = o.sourceTypes
+	pm.unaryInterceptors = o.unary
+	pm.streamInterceptors = o.stream
+	if o.logOutput != nil {
+		pm.logger = hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin",
+			Output: o.logOutput,
+			Level:  hclog.Trace,
+		})
+	}
+	if cfg := o.hostConfig(); cfg != nil {
+		if err := pm.ApplyConfig(cfg); err != nil {
+			pm.Shutdown()
+			return nil, err
+		}
+	}
+	for _, p := range o.builtins {
+		if err := pm.RegisterBuiltin(p); err != nil {
+			pm.Shutdown()
+			return nil, err
+		}
+	}
+	return pm, nil
+}
+
+// hostConfig returns the config to apply, with the directories and limits
+// passed as options, or nil when there is none
+func (o *options) hostConfig() *HostConfig {
+	if o.config == nil && o.dirs == nil && o.limits == nil {
+		return nil
+	}
+	cfg := DefaultConfig()
+	cfg.PluginDirs = nil
+	if o.config != nil {
+		copied := *o.config
+		cfg = &copied
+	}
+	if o.dirs != nil {
+		cfg.PluginDirs = o.dirs
+	}
+	if o.limits != nil {
+		cfg.Limits = *o.limits
+	}
+	return cfg
+}
@@ -0,0 +1,334 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// EventPipelineFailed is published when a pipeline step fails, after the
+// completed steps were compensated
+const EventPipelineFailed = "pipeline.failed"
+
+// ErrPipelineNotFound is returned by RunPipeline for pipelines the config
+// does not define
+var ErrPipelineNotFound = errors.New("pipeline not found")
+
+// States of the steps of a PipelineRun
+const (
+	// StepSucceeded steps ran and were kept
+	StepSucceeded = "succeeded"
+	// StepFailed is the step that stopped the pipeline
+	StepFailed = "failed"
+	// StepSkipped steps did not run because an earlier one failed
+	StepSkipped = "skipped"
+	// StepCompensated steps ran and were undone after a later step failed
+	StepCompensated = "compensated"
+	// StepCompensationFailed steps ran but could not be undone
+	StepCompensationFailed = "compensation_failed"
+	// StepNotCompensable steps ran but declare no compensation, so their
+	// effects remain
+	StepNotCompensable = "not_compensable"
+)
+
+// Pipeline is a sequence of capability calls that succeeds or is rolled
+// back as a whole, as far as its steps can be undone: when a step fails,
+// the compensating capabilities of the steps before it run in reverse
+// order, e.g. deploy failing undoes configure, then scaffold.
+type Pipeline struct {
+	Description string `json:"description"`
+
+	Steps []PipelineStep `json:"steps"`
+}
+
+// PipelineStep is one call of a pipeline
+type PipelineStep struct {
+	// Name identifies the step's result for later steps; defaults to the
+	// capability
+	Name string `json:"name"`
+
+	// Plugin runs the step; empty picks the first plugin offering the
+	// capability
+	Plugin string `json:"plugin"`
+
+	// Capability to call, e.g. "scaffold" or "deploy@v2"
+	Capability string `json:"capability"`
+
+	// Args are added to the pipeline's arguments for this step
+	Args map[string]interface{} `json:"args"`
+
+	// Compensate overrides the capability its plugin declares to undo the
+	// step
+	Compensate string `json:"compensate"`
+}
+
+// stepName returns the name the step's result goes by
+func (s PipelineStep) stepName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Capability
+}
+
+// validate checks the pipeline the config calls field, e.g.
+// "pipelines.release"
+func (p Pipeline) validate(field string) error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("%s: no steps", field)
+	}
+	seen := make(map[string]bool, len(p.Steps))
+	for i, s := range p.Steps {
+		if s.Capability == "" {
+			return fmt.Errorf("%s.steps[%d]: capability is required", field, i)
+		}
+		if seen[s.stepName()] {
+			return fmt.Errorf("%s.steps[%d]: step %q is named twice", field, i, s.stepName())
+		}
+		seen[s.stepName()] = true
+	}
+	return nil
+}
+
+// PipelineRun is what happened to each step of a pipeline
+type PipelineRun struct {
+	Pipeline  string    `json:"pipeline,omitempty"`
+	RequestID string    `json:"request_id"`
+	Steps     []StepRun `json:"steps"`
+
+	// Failed names the step that failed, if any
+	Failed string `json:"failed,omitempty"`
+
+	// RolledBack is set when a step failed and every step before it was
+	// undone
+	RolledBack bool `json:"rolled_back"`
+}
+
+// StepRun is the outcome of one step and of undoing it
+type StepRun struct {
+	Name       string        `json:"name"`
+	Plugin     string        `json:"plugin,omitempty"`
+	Capability string        `json:"capability"`
+	State      string        `json:"state"`
+	Result     string        `json:"result,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+
+	// Compensation is the capability that undid the step, and its result
+	// or error
+	Compensation       string `json:"compensation,omitempty"`
+	CompensationResult string `json:"compensation_result,omitempty"`
+	CompensationError  string `json:"compensation_error,omitempty"`
+}
+
+// PipelineError reports the step that stopped a pipeline
+type PipelineError struct {
+	Pipeline string
+	Step     string
+	Err      error
+
+	// RolledBack is set when every step before it was undone
+	RolledBack bool
+}
+
+func (e *PipelineError) Error() string {
+	outcome := "rollback incomplete"
+	if e.RolledBack {
+		outcome = "rolled back"
+	}
+	if e.Pipeline == "" {
+		return fmt.Sprintf("pipeline step %s failed (%s): %v", e.Step, outcome, e.Err)
+	}
+	return fmt.Sprintf("pipeline %s: step %s failed (%s): %v", e.Pipeline, e.Step, outcome, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipelines returns the pipelines the config defines
+func (pm *PluginManager) Pipelines() map[string]Pipeline {
+	return pm.Config().Pipelines
+}
+
+// RunPipeline runs a pipeline of the config with args, see ExecutePipeline
+func (pm *PluginManager) RunPipeline(name string, args map[string]interface{}) (*PipelineRun, error) {
+	return pm.RunPipelineWithContext(name, args, pluginsdk.RequestContext{})
+}
+
+// RunPipelineWithContext runs a pipeline of the config as a request with
+// rc, e.g. for the user it names; an empty RequestID gets a random one
+func (pm *PluginManager) RunPipelineWithContext(name string, args map[string]interface{}, rc pluginsdk.RequestContext) (*PipelineRun, error) {
+	p, ok := pm.Config().Pipelines[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPipelineNotFound, name)
+	}
+	return pm.executePipeline(name, p, args, rc)
+}
+
+// ExecutePipeline runs the steps of p in order as one request. Each step
+// gets args with its own args added and the results of the steps before
+// it in ArgSteps. When a step fails, the steps before it are compensated in
+// reverse order with the capabilities their plugins declare in
+// Capability.Compensate, or the step's Compensate; compensation is best
+// effort and carries on past failures. The error is then a *PipelineError
+// and the run says what was undone.
+func (pm *PluginManager) ExecutePipeline(p Pipeline, args map[string]interface{}) (*PipelineRun, error) {
+	if err := p.validate("pipeline"); err != nil {
+		return nil, err
+	}
+	return pm.executePipeline("", p, args, pluginsdk.RequestContext{})
+}
+
+func (pm *PluginManager) executePipeline(name string, p Pipeline, args map[string]interface{}, rc pluginsdk.RequestContext) (*PipelineRun, error) {
+	if rc.RequestID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request ID: %w", err)
+		}
+		rc.RequestID = id
+	}
+	requestID := rc.RequestID
+	run := &PipelineRun{Pipeline: name, RequestID: requestID, Steps: make([]StepRun, len(p.Steps))}
+	stepArgs := make([]map[string]interface{}, len(p.Steps))
+	results := make(map[string]interface{}, len(p.Steps))
+
+	failed := -1
+	var stepErr error
+	for i, step := range p.Steps {
+		sr := &run.Steps[i]
+		sr.Name, sr.Plugin, sr.Capability = step.stepName(), step.Plugin, step.Capability
+		if failed >= 0 {
+			sr.State = StepSkipped
+			continue
+		}
+
+		stepArgs[i] = make(map[string]interface{}, len(args)+len(step.Args))
+		for k, v := range args {
+			stepArgs[i][k] = v
+		}
+		for k, v := range step.Args {
+			stepArgs[i][k] = v
+		}
+
+		start := time.Now()
+		result, err := pm.runStep(sr, stepArgs[i], results, rc)
+		sr.Duration = time.Since(start)
+		if err != nil {
+			sr.State, sr.Error = StepFailed, err.Error()
+			failed, stepErr = i, err
+			continue
+		}
+		sr.State, sr.Result = StepSucceeded, result
+		results[sr.Name] = result
+	}
+	if failed < 0 {
+		return run, nil
+	}
+
+	run.Failed = run.Steps[failed].Name
+	run.RolledBack = true
+	for i := failed - 1; i >= 0; i-- {
+		if !pm.compensate(&run.Steps[i], p.Steps[i], stepArgs[i], results, rc) {
+			run.RolledBack = false
+		}
+	}
+
+	failedStep := run.Steps[failed]
+	pm.hostLog.Printf("Pipeline %s failed at step %s, rolled back: %v", pipelineLabel(name), failedStep.Name, run.RolledBack)
+	pm.events.Publish(Event{Type: EventPipelineFailed, Plugin: failedStep.Plugin, Data: map[string]interface{}{
+		"pipeline":    name,
+		"step":        failedStep.Name,
+		"error":       failedStep.Error,
+		"rolled_back": run.RolledBack,
+		"request_id":  requestID,
+	}})
+	return run, &PipelineError{Pipeline: name, Step: failedStep.Name, Err: stepErr, RolledBack: run.RolledBack}
+}
+
+// runStep calls the step's capability, picking its plugin when the step
+// names none
+func (pm *PluginManager) runStep(sr *StepRun, args, results map[string]interface{}, rc pluginsdk.RequestContext) (string, error) {
+	if sr.Plugin == "" {
+		plugin, err := pm.Provider(sr.Capability)
+		if err != nil {
+			return "", err
+		}
+		sr.Plugin = plugin
+	}
+	callArgs := make(map[string]interface{}, len(args)+2)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	callArgs[pluginsdk.ArgCapability] = sr.Capability
+	callArgs[pluginsdk.ArgSteps] = copyResults(results)
+	return pm.ExecuteWithContext(sr.Plugin, callArgs, rc)
+}
+
+// compensate undoes a step that succeeded, reporting whether its effects
+// are gone
+func (pm *PluginManager) compensate(sr *StepRun, step PipelineStep, args, results map[string]interface{}, rc pluginsdk.RequestContext) bool {
+	capability := step.Compensate
+	if capability == "" {
+		capability = pm.declaredCompensation(sr.Plugin, sr.Capability)
+	}
+	if capability == "" {
+		sr.State = StepNotCompensable
+		pm.hostLog.Printf("Step %s (%s of %s) declares no compensation; its effects remain", sr.Name, sr.Capability, sr.Plugin)
+		return false
+	}
+
+	sr.Compensation = capability
+	callArgs := make(map[string]interface{}, len(args)+3)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	callArgs[pluginsdk.ArgCapability] = capability
+	callArgs[pluginsdk.ArgSteps] = copyResults(results)
+	callArgs[pluginsdk.ArgCompensates] = map[string]interface{}{
+		"capability": sr.Capability,
+		"args":       args,
+		"result":     sr.Result,
+	}
+	result, err := pm.ExecuteWithContext(sr.Plugin, callArgs, rc)
+	if err != nil {
+		sr.State, sr.CompensationError = StepCompensationFailed, err.Error()
+		pm.hostLog.Printf("Failed to compensate step %s with %s of %s: %v", sr.Name, capability, sr.Plugin, err)
+		return false
+	}
+	sr.State, sr.CompensationResult = StepCompensated, result
+	return true
+}
+
+// declaredCompensation returns the capability the plugin declares to undo
+// a call to capability
+func (pm *PluginManager) declaredCompensation(plugin, capability string) string {
+	st, err := pm.GetPlugin(plugin)
+	if err != nil {
+		return ""
+	}
+	name, version := pluginsdk.ParseCapabilityRef(capability)
+	if c := findCapability(st.Capabilities, name, version); c != nil {
+		return c.Compensate
+	}
+	return ""
+}
+
+// copyResults returns a copy of the step results so far, so a plugin
+// cannot see results of steps after it
+func copyResults(results map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(results))
+	for k, v := range results {
+		out[k] = v
+	}
+	return out
+}
+
+// pipelineLabel names a pipeline in the log
+func pipelineLabel(name string) string {
+	if name == "" {
+		return "(ad hoc)"
+	}
+	return name
+}
@@ -0,0 +1,37 @@
+package pluginhost
+
+import (
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// planFlag is the SuperClaude flag that turns a call into a dry run
+const planFlag = "plan"
+
+// PlanPlugin asks a plugin what it would do with args without doing it.
+// Plugins that do not support dry runs fail with CodeUnsupported.
+func (pm *PluginManager) PlanPlugin(name string, args map[string]interface{}) (string, error) {
+	return pm.ExecutePlugin(name, dryRunArgs(args))
+}
+
+// dryRunArgs returns a copy of args marked as a dry run
+func dryRunArgs(args map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		out[k] = v
+	}
+	out[pluginsdk.ArgDryRun] = true
+	return out
+}
+
+// invoke runs one call on a plugin instance, asking for a plan instead when
+// the call is a dry run
+func invoke(instance pluginsdk.CommandPlugin, args map[string]interface{}) (string, error) {
+	if !pluginsdk.DryRun(args) {
+		return instance.Execute(args)
+	}
+	p, ok := instance.(pluginsdk.PlanningPlugin)
+	if !ok {
+		return "", &pluginsdk.PluginError{Code: pluginsdk.CodeUnsupported, Message: "plugin does not support dry runs"}
+	}
+	return p.Plan(args)
+}
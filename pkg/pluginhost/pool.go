@@ -0,0 +1,358 @@
+package pluginhost
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Strategies for spreading calls over the processes of a pool
+const (
+	PoolLeastBusy  = "least_busy"
+	PoolRoundRobin = "round_robin"
+)
+
+// DefaultPoolScaleDownAfter is how long an extra process may sit idle when
+// scale_down_after is not set
+const DefaultPoolScaleDownAfter = time.Minute
+
+// poolInterval is how often pools are scaled down and refilled
+const poolInterval = 10 * time.Second
+
+// PoolConfig runs several processes of a plugin binary and spreads calls
+// over them, for capabilities one process cannot serve fast enough
+type PoolConfig struct {
+	// Min is how many processes run while the plugin is running;
+	// defaults to 1
+	Min int `json:"min"`
+
+	// Max caps the processes; defaults to Min
+	Max int `json:"max"`
+
+	// Strategy is "least_busy", the default, or "round_robin"
+	Strategy string `json:"strategy"`
+
+	// ScaleUpAt starts another process, up to Max, once every process
+	// serves this many calls; defaults to 1
+	ScaleUpAt int `json:"scale_up_at"`
+
+	// ScaleDownAfter stops processes above Min that have been idle this
+	// long; defaults to DefaultPoolScaleDownAfter
+	ScaleDownAfter Duration `json:"scale_down_after"`
+}
+
+func (c PoolConfig) validate(name string) error {
+	switch c.Strategy {
+	case "", PoolLeastBusy, PoolRoundRobin:
+	default:
+		return fmt.Errorf("pools.%s.strategy must be %q or %q, got %q", name, PoolLeastBusy, PoolRoundRobin, c.Strategy)
+	}
+	if c.Min < 0 || c.Max < 0 || c.ScaleUpAt < 0 || c.ScaleDownAfter < 0 {
+		return fmt.Errorf("pools.%s: values must not be negative", name)
+	}
+	if c.Max > 0 && c.Max < c.Min {
+		return fmt.Errorf("pools.%s.max must not be below min", name)
+	}
+	return nil
+}
+
+// bounds returns the smallest and largest number of processes
+func (c PoolConfig) bounds() (int, int) {
+	lo, hi := c.Min, c.Max
+	if lo < 1 {
+		lo = 1
+	}
+	if hi < lo {
+		hi = lo
+	}
+	return lo, hi
+}
+
+func (c PoolConfig) scaleUpAt() int {
+	if c.ScaleUpAt > 0 {
+		return c.ScaleUpAt
+	}
+	return 1
+}
+
+func (c PoolConfig) scaleDownAfter() time.Duration {
+	if c.ScaleDownAfter > 0 {
+		return time.Duration(c.ScaleDownAfter)
+	}
+	return DefaultPoolScaleDownAfter
+}
+
+// poolWorker is one process of a pool and the calls it is serving
+type poolWorker struct {
+	client   *plugin.Client // nil for the plugin's main process
+	instance pluginsdk.CommandPlugin
+	active   int
+	lastUsed time.Time
+}
+
+// processPool holds the processes serving a plugin besides its main one
+type processPool struct {
+	main     poolWorker
+	extra    []*poolWorker
+	next     int
+	starting int
+	mu       sync.Mutex
+}
+
+// size counts the processes, the main one included
+func (p *processPool) size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return 1 + len(p.extra)
+}
+
+// acquire picks the process for a call and counts the call on it until
+// release. grow is set when every process is busy and another may start;
+// the caller then starts one and reports back with started.
+func (p *processPool) acquire(cfg PoolConfig, main pluginsdk.CommandPlugin) (w *poolWorker, grow bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.main.instance = main
+	live := p.extra[:0]
+	for _, e := range p.extra {
+		if !e.client.Exited() {
+			live = append(live, e)
+		}
+	}
+	p.extra = live
+	workers := append([]*poolWorker{&p.main}, p.extra...)
+
+	if cfg.Strategy == PoolRoundRobin {
+		w = workers[p.next%len(workers)]
+		p.next++
+	} else {
+		w = workers[0]
+		for _, c := range workers[1:] {
+			if c.active < w.active {
+				w = c
+			}
+		}
+	}
+	w.active++
+	w.lastUsed = time.Now()
+
+	_, max := cfg.bounds()
+	if len(workers)+p.starting < max {
+		grow = true
+		for _, c := range workers {
+			if c.active < cfg.scaleUpAt() {
+				grow = false
+				break
+			}
+		}
+	}
+	if grow {
+		p.starting++
+	}
+	return w, grow
+}
+
+// release ends a call counted by acquire
+func (p *processPool) release(w *poolWorker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w.active--
+	w.lastUsed = time.Now()
+}
+
+// started adds a process begun after acquire or fill asked for one; a nil
+// client means it failed to start
+func (p *processPool) started(client *plugin.Client, instance pluginsdk.CommandPlugin) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.starting--
+	if client != nil {
+		p.extra = append(p.extra, &poolWorker{client: client, instance: instance, lastUsed: time.Now()})
+	}
+}
+
+// workers returns the instances of the extra processes
+func (p *processPool) workers() []pluginsdk.CommandPlugin {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	instances := make([]pluginsdk.CommandPlugin, 0, len(p.extra))
+	for _, e := range p.extra {
+		instances = append(instances, e.instance)
+	}
+	return instances
+}
+
+// shrink drops exited processes, removes the processes above the minimum
+// that have been idle for long enough, or above the maximum, and returns
+// them to be stopped
+func (p *processPool) shrink(cfg PoolConfig, now time.Time) []*plugin.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	min, max := cfg.bounds()
+	var stop []*plugin.Client
+	keep := p.extra[:0]
+	gone := 0
+	for _, e := range p.extra {
+		if e.client.Exited() {
+			gone++
+			continue
+		}
+		total := 1 + len(p.extra) - gone - len(stop)
+		idle := e.active == 0 && now.Sub(e.lastUsed) >= cfg.scaleDownAfter()
+		if e.active == 0 && (total > max || (total > min && idle)) {
+			stop = append(stop, e.client)
+			continue
+		}
+		keep = append(keep, e)
+	}
+	p.extra = keep
+	return stop
+}
+
+// missing returns how many processes to start to reach the minimum and
+// counts them as starting
+func (p *processPool) missing(cfg PoolConfig) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	min, _ := cfg.bounds()
+	n := min - 1 - len(p.extra) - p.starting
+	if n < 0 {
+		n = 0
+	}
+	p.starting += n
+	return n
+}
+
+// retire removes every extra process and returns them to be stopped
+func (p *processPool) retire() []*plugin.Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clients := make([]*plugin.Client, 0, len(p.extra))
+	for _, e := range p.extra {
+		clients = append(clients, e.client)
+	}
+	p.extra = nil
+	return clients
+}
+
+// startWorker starts another process for a pooled plugin
+func (pm *PluginManager) startWorker(info *pluginInfo, pool *processPool, generation int) {
+	proc, err := pm.startProcess(info.Path)
+	if err == nil && proc.name != info.reports() {
+		proc.client.Kill()
+		err = fmt.Errorf("binary reports name %q", proc.name)
+	}
+	if err != nil {
+		pm.hostLog.Printf("Failed to start pool process for plugin %s: %v", info.Name, err)
+		pool.started(nil, nil)
+		return
+	}
+
+	// The plugin may have been stopped or restarted meanwhile; the pool
+	// is only retired under the write lock, so the check holds until the
+	// process is added
+	pm.mu.RLock()
+	current := !info.stopping && !info.crashed && info.generation == generation && info.pool == pool
+	if current {
+		pool.started(proc.client, proc.instance)
+	}
+	pm.mu.RUnlock()
+	if !current {
+		proc.client.Kill()
+		pool.started(nil, nil)
+		return
+	}
+	pm.hostLog.Printf("Plugin %s runs %d process(es)", info.Name, pool.size())
+}
+
+// fillPool starts the processes a pooled plugin needs to reach its
+// minimum. The caller holds pm.mu.
+func (pm *PluginManager) fillPool(info *pluginInfo) {
+	cfg, ok := pm.config.Pools[info.Name]
+	if !ok || info.pool == nil || info.Instance == nil || info.loading || info.crashed || info.stopping {
+		return
+	}
+	for i := info.pool.missing(cfg); i > 0; i-- {
+		go pm.startWorker(info, info.pool, info.generation)
+	}
+}
+
+// retirePool takes the extra processes from a plugin's pool and returns
+// them to be stopped. The caller holds pm.mu.
+func retirePool(info *pluginInfo) []*plugin.Client {
+	if info.pool == nil {
+		return nil
+	}
+	return info.pool.retire()
+}
+
+// stopPool stops the extra processes of a plugin. The caller holds pm.mu.
+func stopPool(info *pluginInfo) {
+	for _, c := range retirePool(info) {
+		c.Kill()
+	}
+}
+
+// applyPools starts or stops the loop scaling pools. The caller holds
+// pm.mu.
+func (pm *PluginManager) applyPools(cfg *HostConfig) {
+	switch {
+	case len(cfg.Pools) > 0 && pm.poolStop == nil:
+		pm.poolStop = make(chan struct{})
+		go pm.poolLoop(pm.poolStop)
+	case len(cfg.Pools) == 0 && pm.poolStop != nil:
+		close(pm.poolStop)
+		pm.poolStop = nil
+		for _, info := range pm.plugins {
+			stopPool(info)
+		}
+	}
+}
+
+// poolLoop scales pools down when idle and back up to their minimum until
+// stop is closed
+func (pm *PluginManager) poolLoop(stop chan struct{}) {
+	ticker := time.NewTicker(poolInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			pm.scalePools(now)
+		}
+	}
+}
+
+func (pm *PluginManager) scalePools(now time.Time) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for name, info := range pm.plugins {
+		if info.pool == nil {
+			continue
+		}
+		// Plugins no longer pooled shrink to their main process
+		cfg := pm.config.Pools[name]
+		stop := info.pool.shrink(cfg, now)
+		for _, c := range stop {
+			c.Kill()
+		}
+		if len(stop) > 0 {
+			pm.hostLog.Printf("Plugin %s runs %d process(es)", name, info.pool.size())
+		}
+		pm.fillPool(info)
+	}
+}
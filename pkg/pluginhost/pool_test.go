@@ -0,0 +1,15 @@
+package pluginhost
+
+import "testing"
+
+func TestPoolValidationNamesField(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Pools = map[string]PoolConfig{"hello": {Strategy: "random"}}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("invalid strategy accepted")
+	}
+	if want := `pools.hello.strategy must be "least_busy" or "round_robin", got "random"`; err.Error() != want {
+		t.Errorf("error = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,104 @@
+package pluginhost
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ProfileConfig selects which plugins run, so one plugin directory can
+// serve different runtime footprints such as "minimal", "full" or "ci"
+type ProfileConfig struct {
+	Description string `json:"description"`
+
+	// Groups and Plugins name the plugins to load. When both are empty every
+	// discovered plugin is loaded.
+	Groups  []string `json:"groups"`
+	Plugins []string `json:"plugins"`
+
+	// Exclude removes plugins from the selection, even ones listed above
+	Exclude []string `json:"exclude"`
+}
+
+// validateProfiles checks that the active profile and every group a
+// profile refers to exist
+func (c *HostConfig) validateProfiles() error {
+	if c.Profile != "" {
+		if _, ok := c.Profiles[c.Profile]; !ok {
+			return fmt.Errorf("profile %q is not defined in profiles", c.Profile)
+		}
+	}
+	for name, p := range c.Profiles {
+		for _, g := range p.Groups {
+			if _, ok := c.Groups[g]; !ok {
+				return fmt.Errorf("profiles.%s refers to unknown group %q", name, g)
+			}
+		}
+	}
+	return nil
+}
+
+// inProfile reports whether the active profile selects a plugin. Without an
+// active profile every plugin is selected.
+func (c *HostConfig) inProfile(plugin string) bool {
+	if c.Profile == "" {
+		return true
+	}
+	p := c.Profiles[c.Profile]
+	for _, name := range p.Exclude {
+		if name == plugin {
+			return false
+		}
+	}
+	if len(p.Groups) == 0 && len(p.Plugins) == 0 {
+		return true
+	}
+	for _, name := range p.Plugins {
+		if name == plugin {
+			return true
+		}
+	}
+	for _, g := range p.Groups {
+		for _, name := range c.Groups[g] {
+			if name == plugin {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// profileChanged reports whether switching from prev to next can change
+// which plugins are selected
+func profileChanged(prev, next *HostConfig) bool {
+	return prev.Profile != next.Profile ||
+		!reflect.DeepEqual(prev.Profiles, next.Profiles) ||
+		!reflect.DeepEqual(prev.Groups, next.Groups)
+}
+
+// applyProfile unloads plugins the active profile no longer selects and
+// discovers the plugin directories again to load newly selected ones
+func (pm *PluginManager) applyProfile(cfg *HostConfig) {
+	pm.mu.RLock()
+	var deselected, dirs []string
+	for name, info := range pm.plugins {
+		if !cfg.inProfile(name) && !info.builtin {
+			deselected = append(deselected, name)
+		}
+	}
+	for dir := range pm.dirs {
+		dirs = append(dirs, dir)
+	}
+	pm.mu.RUnlock()
+
+	for _, name := range deselected {
+		pm.hostLog.Printf("Plugin %s is not in profile %q", name, cfg.Profile)
+		if err := pm.UnloadPlugin(name); err != nil {
+			pm.hostLog.Printf("Failed to unload plugin %s: %v", name, err)
+		}
+	}
+	for _, dir := range dirs {
+		if err := pm.DiscoverPlugins(dir); err != nil {
+			pm.hostLog.Printf("Failed to discover plugins in %s: %v", dir, err)
+		}
+	}
+}
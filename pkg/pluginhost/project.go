@@ -0,0 +1,339 @@
+package pluginhost
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// InjectProject is the manifest inject entry asking for the project
+// context in every call
+const InjectProject = "project"
+
+const (
+	// DefaultProjectTTL is how long a project context is reused when the
+	// config sets no TTL
+	DefaultProjectTTL = 30 * time.Second
+
+	// DefaultProjectMaxFiles bounds the files sized for the language
+	// breakdown when the config sets no limit
+	DefaultProjectMaxFiles = 20000
+
+	// maxDirtyFiles bounds the dirty files listed in a project context
+	maxDirtyFiles = 200
+)
+
+// ProjectConfig sets how project contexts are computed and which plugins
+// receive one besides those whose manifest asks for it
+type ProjectConfig struct {
+	// Plugins receive the project context in every call, by name; a
+	// trailing "*" matches by prefix and "*" alone every plugin
+	Plugins []string `json:"plugins"`
+
+	// TTL is how long a computed context is reused; defaults to
+	// DefaultProjectTTL
+	TTL Duration `json:"ttl"`
+
+	// MaxFiles bounds the files sized for the language breakdown; defaults
+	// to DefaultProjectMaxFiles
+	MaxFiles int `json:"max_files"`
+}
+
+func (c *ProjectConfig) validate() error {
+	if c.TTL < 0 || c.MaxFiles < 0 {
+		return fmt.Errorf("project.ttl and project.max_files must not be negative")
+	}
+	return nil
+}
+
+func (c ProjectConfig) ttl() time.Duration {
+	if c.TTL == 0 {
+		return DefaultProjectTTL
+	}
+	return time.Duration(c.TTL)
+}
+
+func (c ProjectConfig) maxFiles() int {
+	if c.MaxFiles == 0 {
+		return DefaultProjectMaxFiles
+	}
+	return c.MaxFiles
+}
+
+// wants reports whether plugin receives the project context regardless
+// of its manifest
+func (c ProjectConfig) wants(plugin string) bool {
+	for _, p := range c.Plugins {
+		if p == plugin || (strings.HasSuffix(p, "*") && strings.HasPrefix(plugin, strings.TrimSuffix(p, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectCache holds computed project contexts by working directory
+type projectCache struct {
+	entries map[string]pluginsdk.ProjectContext
+	mu      sync.Mutex
+}
+
+// languages names the languages counted in the breakdown by file
+// extension; data and documentation formats are left out
+var languages = map[string]string{
+	".go":     "Go",
+	".py":     "Python",
+	".js":     "JavaScript",
+	".jsx":    "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".rs":     "Rust",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".rb":     "Ruby",
+	".php":    "PHP",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".swift":  "Swift",
+	".m":      "Objective-C",
+	".dart":   "Dart",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".hs":     "Haskell",
+	".lua":    "Lua",
+	".pl":     "Perl",
+	".r":      "R",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".ps1":    "PowerShell",
+	".sql":    "SQL",
+	".proto":  "Protocol Buffers",
+	".html":   "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+	".tf":     "HCL",
+}
+
+// ProjectContext returns the project context of dir: the git work tree it
+// is in with branch, commit and dirty files, and the language breakdown.
+// Contexts are reused for project.ttl; RefreshProject computes one anew.
+func (pm *PluginManager) ProjectContext(dir string) (pluginsdk.ProjectContext, error) {
+	dir, err := projectDir(dir)
+	if err != nil {
+		return pluginsdk.ProjectContext{}, err
+	}
+	cfg := pm.Config().Project
+
+	pm.projects.mu.Lock()
+	pc, ok := pm.projects.entries[dir]
+	pm.projects.mu.Unlock()
+	if ok && time.Since(pc.ComputedAt) < cfg.ttl() {
+		return pc, nil
+	}
+	return pm.computeProject(dir, cfg)
+}
+
+// RefreshProject computes the project context of dir anew, e.g. after a
+// checkout or a commit the host did not see
+func (pm *PluginManager) RefreshProject(dir string) (pluginsdk.ProjectContext, error) {
+	dir, err := projectDir(dir)
+	if err != nil {
+		return pluginsdk.ProjectContext{}, err
+	}
+	return pm.computeProject(dir, pm.Config().Project)
+}
+
+// projectDir makes dir absolute and checks that it is a directory
+func projectDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "invalid project directory: %v", err)
+	}
+	if fi, err := os.Stat(abs); err != nil || !fi.IsDir() {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "project directory does not exist: %s", abs)
+	}
+	return abs, nil
+}
+
+func (pm *PluginManager) computeProject(dir string, cfg ProjectConfig) (pluginsdk.ProjectContext, error) {
+	pc := pluginsdk.ProjectContext{Root: dir, ComputedAt: time.Now()}
+	var files []string
+	if git := gitInfo(dir); git.Root != "" {
+		pc.Root, pc.Git, pc.Commit = git.Root, true, git.Commit
+		if git.Branch != "HEAD" {
+			pc.Branch = git.Branch
+		}
+		pc.Dirty, pc.DirtyCount = dirtyFiles(pc.Root)
+		files = gitFiles(pc.Root, cfg.maxFiles())
+	} else {
+		files = walkFiles(dir, cfg.maxFiles())
+	}
+	pc.Languages = languageShares(pc.Root, files)
+
+	pm.projects.mu.Lock()
+	if pm.projects.entries == nil {
+		pm.projects.entries = make(map[string]pluginsdk.ProjectContext)
+	}
+	pm.projects.entries[dir] = pc
+	pm.projects.mu.Unlock()
+	return pc, nil
+}
+
+// dirtyFiles lists the changed and untracked files of a work tree, at most
+// maxDirtyFiles of them, and counts them all
+func dirtyFiles(root string) ([]string, int) {
+	out, err := gitRaw(root, "status", "--porcelain", "-z")
+	if err != nil {
+		return nil, 0
+	}
+	var dirty []string
+	count := 0
+	entries := bytes.Split(out, []byte{0})
+	for i := 0; i < len(entries); i++ {
+		entry := string(entries[i])
+		if len(entry) < 4 {
+			continue
+		}
+		count++
+		if len(dirty) < maxDirtyFiles {
+			dirty = append(dirty, entry[3:])
+		}
+		// Renames and copies are followed by their original path
+		if strings.ContainsAny(entry[:2], "RC") {
+			i++
+		}
+	}
+	return dirty, count
+}
+
+// gitFiles lists the tracked and untracked files of a work tree that are
+// not ignored, relative to root
+func gitFiles(root string, limit int) []string {
+	out, err := gitRaw(root, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, f := range bytes.Split(out, []byte{0}) {
+		if len(f) == 0 {
+			continue
+		}
+		if len(files) == limit {
+			break
+		}
+		files = append(files, string(f))
+	}
+	return files
+}
+
+// walkFiles lists the files under a directory that is no work tree,
+// relative to it, skipping hidden and dependency directories
+func walkFiles(root string, limit int) []string {
+	var files []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(files) == limit {
+			return filepath.SkipAll
+		}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files
+}
+
+// languageShares sizes files by language and returns each language's
+// share of the total, rounded to three places
+func languageShares(root string, files []string) map[string]float64 {
+	sizes := make(map[string]int64)
+	var total int64
+	for _, f := range files {
+		lang, ok := languages[strings.ToLower(filepath.Ext(f))]
+		if !ok {
+			continue
+		}
+		fi, err := os.Stat(filepath.Join(root, f))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		sizes[lang] += fi.Size()
+		total += fi.Size()
+	}
+	if total == 0 {
+		return nil
+	}
+	shares := make(map[string]float64, len(sizes))
+	for lang, size := range sizes {
+		shares[lang] = math.Round(float64(size)/float64(total)*1000) / 1000
+	}
+	return shares
+}
+
+// withProject adds the project context of the call's working directory,
+// or else of its request's project root, for plugins that asked for it.
+// The context is always computed here: one the call carries already is
+// dropped, and calls without a directory, or whose context cannot be
+// computed, go without one.
+func (pm *PluginManager) withProject(name string, args map[string]interface{}) map[string]interface{} {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	wants := exists && (containsString(info.Inject, InjectProject) || pm.config.Project.wants(name))
+	pm.mu.RUnlock()
+	if _, given := args[pluginsdk.ArgProject]; !wants && !given {
+		return args
+	}
+
+	out := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		out[k] = v
+	}
+	delete(out, pluginsdk.ArgProject)
+	if !wants {
+		return out
+	}
+	dir := pluginsdk.WorkDir(args)
+	if dir == "" {
+		dir = pluginsdk.ContextOf(args).ProjectRoot
+	}
+	if dir == "" {
+		return out
+	}
+
+	pc, err := pm.ProjectContext(dir)
+	if err != nil {
+		pm.hostLog.Printf("Failed to compute project context for %s: %v", name, err)
+		return out
+	}
+	out[pluginsdk.ArgProject] = pc.Map()
+	return out
+}
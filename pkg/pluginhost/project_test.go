@@ -0,0 +1,69 @@
+package pluginhost
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// projectPlugin is a built-in plugin answering with the root of the project
+// context its calls carry
+type projectPlugin struct{}
+
+func (p *projectPlugin) Name() string                            { return "projector" }
+func (p *projectPlugin) Version() string                         { return "1.0.0" }
+func (p *projectPlugin) GetCapabilities() []pluginsdk.Capability { return nil }
+func (p *projectPlugin) Execute(args map[string]interface{}) (string, error) {
+	pc, ok := pluginsdk.Project(args)
+	if !ok {
+		return "none", nil
+	}
+	return pc.Root, nil
+}
+
+func TestProjectContextIsComputedByTheHost(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Project.Plugins = []string{"projector"}
+	pm, err := New(WithConfig(cfg), WithPluginDirs(t.TempDir()), WithBuiltin(&projectPlugin{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := map[string]interface{}{pluginsdk.ArgProject: map[string]interface{}{"root": "/forged"}}
+
+	tests := []struct {
+		name string
+		env  CallEnv
+		want string
+	}{
+		{"without a working directory", CallEnv{}, "none"},
+		{"with a working directory", CallEnv{WorkDir: dir}, dir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pm.ExecuteIn("projector", forged, tt.env)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("plugin saw project %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Calls reaching the host past its public entry points lose the value
+	// too, also for plugins that did not ask for a project context
+	args := map[string]interface{}{pluginsdk.ArgProject: forged[pluginsdk.ArgProject], pluginsdk.ArgWorkDir: dir}
+	if pc, _ := pluginsdk.Project(pm.withProject("projector", args)); pc.Root != dir {
+		t.Errorf("project root = %q, want %q", pc.Root, dir)
+	}
+	if _, ok := pm.withProject("other", args)[pluginsdk.ArgProject]; ok {
+		t.Error("plugin that did not ask kept the caller's project context")
+	}
+}
@@ -0,0 +1,158 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Protocols plugin processes are spoken to over, as PluginStatus.Protocol
+// reports them
+const (
+	ProtocolGRPC   = string(plugin.ProtocolGRPC)
+	ProtocolNetRPC = string(plugin.ProtocolNetRPC)
+)
+
+// probeTimeout bounds each attempt to find out which protocol a plugin
+// serves
+const probeTimeout = 2 * time.Second
+
+// ProtocolError explains why the host and a plugin could not talk: the
+// plugin announced a protocol it does not serve, one the host does not
+// speak, or a version of the plugin protocol the host does not know
+type ProtocolError struct {
+	// Announced is the protocol the plugin's handshake line announced;
+	// "netrpc" for lines naming none
+	Announced string
+
+	// Detected is the protocol the plugin was found to serve, if known
+	Detected string
+
+	// Problem says what does not fit and Fix what to change in the plugin
+	Problem string
+	Fix     string
+
+	Err error
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("%s; %s (%v)", e.Problem, e.Fix, e.Err)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	unsupportedProtocol = regexp.MustCompile(`Unsupported plugin protocol "([^"]*)"`)
+	incompatibleVersion = regexp.MustCompile(`Incompatible API version with plugin. Plugin version: (\d+)`)
+)
+
+// diagnoseHandshake explains a failed handshake caused by the protocol
+// the plugin announced; other errors are returned as they are. go-plugin
+// reports these only as text.
+func diagnoseHandshake(err error) error {
+	msg := err.Error()
+	if m := unsupportedProtocol.FindStringSubmatch(msg); m != nil && m[1] == "" {
+		return &ProtocolError{
+			Problem: "plugin's handshake line has an empty protocol field",
+			Fix:     "name the protocol it serves there, e.g. 1|1|tcp|127.0.0.1:5000|grpc",
+			Err:     err,
+		}
+	} else if m != nil {
+		return &ProtocolError{
+			Announced: m[1],
+			Problem:   fmt.Sprintf("plugin announced protocol %q, but the host speaks only %s and %s", m[1], ProtocolGRPC, ProtocolNetRPC),
+			Fix:       "end its handshake line with |grpc and serve gRPC, as pluginsdk.Serve and the Python and Node SDKs do",
+			Err:       err,
+		}
+	}
+	if m := incompatibleVersion.FindStringSubmatch(msg); m != nil {
+		return &ProtocolError{
+			Problem: fmt.Sprintf("plugin speaks version %s of the plugin protocol, the host version %d", m[1], pluginsdk.Handshake.ProtocolVersion),
+			Fix:     "rebuild it against the SDK of this host",
+			Err:     err,
+		}
+	}
+	return err
+}
+
+// diagnoseProtocol explains a failure to reach a plugin after its
+// handshake by checking which protocol the plugin actually serves. Errors it
+// cannot explain are returned as they are.
+func diagnoseProtocol(client *plugin.Client, err error) error {
+	announced := string(client.Protocol())
+	rc := client.ReattachConfig()
+	if rc == nil || rc.Addr == nil {
+		return err
+	}
+	detected := probeProtocol(rc.Addr)
+
+	pe := &ProtocolError{Announced: announced, Detected: detected, Err: err}
+	switch {
+	case detected == ProtocolGRPC && announced == ProtocolNetRPC:
+		pe.Problem = "plugin serves gRPC, but its handshake line announced net/rpc or no protocol, which means net/rpc"
+		pe.Fix = "end its handshake line with |grpc, e.g. 1|1|tcp|127.0.0.1:5000|grpc"
+	case detected == ProtocolNetRPC && announced == ProtocolGRPC:
+		pe.Problem = "plugin serves net/rpc, but its handshake line announced grpc"
+		pe.Fix = "announce netrpc instead, or serve it with pluginsdk.Serve, which speaks gRPC"
+	case detected == ProtocolGRPC && status.Code(err) == codes.Unimplemented:
+		pe.Problem = "plugin serves gRPC, but not the services the host calls"
+		pe.Fix = "register the grpc.health.v1 health service reporting \"plugin\" as SERVING and the CommandPlugin service of pkg/pluginsdk/proto/command.proto"
+	default:
+		return err
+	}
+	return pe
+}
+
+// probeProtocol finds out which protocol the plugin at addr serves. gRPC
+// servers answer the HTTP/2 connection preface with their settings; net/rpc
+// servers of go-plugin are multiplexed by yamux, which answers a ping. It
+// returns "" when the plugin answers neither.
+func probeProtocol(addr net.Addr) string {
+	http2Preface := []byte("PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n")
+	http2Settings := []byte{0, 0, 0, 0x4, 0, 0, 0, 0, 0}
+	if reply, ok := probeReply(addr, append(http2Preface, http2Settings...), 9); ok && reply[3] == 0x4 {
+		return ProtocolGRPC
+	}
+
+	// A yamux ping: version 0, type ping, flag SYN, stream 0, opaque 1
+	ping := make([]byte, 12)
+	ping[1] = 0x2
+	binary.BigEndian.PutUint16(ping[2:4], 0x1)
+	binary.BigEndian.PutUint32(ping[8:12], 1)
+	if reply, ok := probeReply(addr, ping, 12); ok && reply[0] == 0 && reply[1] == 0x2 && bytes.Equal(reply[8:12], ping[8:12]) {
+		return ProtocolNetRPC
+	}
+	return ""
+}
+
+// probeReply sends msg to addr on a new connection and reads the first n bytes
+// of the answer
+func probeReply(addr net.Addr, msg []byte, n int) ([]byte, bool) {
+	conn, err := net.DialTimeout(addr.Network(), addr.String(), probeTimeout)
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(probeTimeout))
+	if _, err := conn.Write(msg); err != nil {
+		return nil, false
+	}
+	reply := make([]byte, n)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, false
+	}
+	return reply, true
+}
@@ -0,0 +1,355 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// EventPluginInstalled is published when a plugin was installed from the
+// registry index for a capability no plugin offered, or from a bundle
+const EventPluginInstalled = "plugin.installed"
+
+// Install policies of ProvisionConfig
+const (
+	InstallPrompt = "prompt"
+	InstallAuto   = "auto"
+)
+
+// DefaultDownloadTimeout bounds reading the index and downloading a plugin
+// when provision.timeout is not set
+const DefaultDownloadTimeout = 2 * time.Minute
+
+// ProvisionConfig lets ExecuteCapability install a plugin from a registry
+// index when no loaded plugin offers the capability
+type ProvisionConfig struct {
+	// Index is the path or http(s) URL of the registry index; empty turns
+	// provisioning off
+	Index string `json:"index"`
+
+	// Policy is "prompt", the default, to install only what
+	// Hooks.ApproveInstall approves, or "auto" to install without asking
+	Policy string `json:"policy"`
+
+	// Dir receives installed plugins; defaults to the first plugin
+	// directory
+	Dir string `json:"dir"`
+
+	// Timeout bounds reading the index and each download; defaults to
+	// DefaultDownloadTimeout
+	Timeout Duration `json:"timeout"`
+}
+
+func (c *ProvisionConfig) validate() error {
+	switch c.Policy {
+	case "", InstallPrompt, InstallAuto:
+	default:
+		return fmt.Errorf("provision.policy must be %q or %q, got %q", InstallPrompt, InstallAuto, c.Policy)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("provision.timeout must not be negative")
+	}
+	return nil
+}
+
+func (c *ProvisionConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout)
+	}
+	return DefaultDownloadTimeout
+}
+
+// RegistryIndex lists the plugins that can be installed
+type RegistryIndex struct {
+	Plugins []IndexEntry `json:"plugins"`
+}
+
+// IndexEntry is one installable plugin binary
+type IndexEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Capabilities are the capabilities the plugin advertises, e.g.
+	// "lint" or "greet@v2"
+	Capabilities []string `json:"capabilities"`
+
+	// URL is the binary, relative to the index or absolute
+	URL string `json:"url"`
+
+	// SHA256 is the hex checksum the binary must have
+	SHA256 string `json:"sha256"`
+
+	// Manifest is the plugin's manifest, relative to the index or
+	// absolute; optional
+	Manifest string `json:"manifest,omitempty"`
+
+	// SBOM and Provenance are the plugin's SBOM and SLSA provenance,
+	// relative to the index or absolute; optional
+	SBOM       string `json:"sbom,omitempty"`
+	Provenance string `json:"provenance,omitempty"`
+
+	// OS and Arch are the platform the binary was built for, as GOOS and
+	// GOARCH name it; entries without them run anywhere
+	OS   string `json:"os,omitempty"`
+	Arch string `json:"arch,omitempty"`
+
+	// Protocol is the plugin handshake version the binary speaks; entries
+	// without it are taken to speak the host's
+	Protocol uint `json:"protocol,omitempty"`
+}
+
+// runsHere reports whether the entry's binary is built for this platform
+// and speaks the host's plugin protocol
+func (e IndexEntry) runsHere() bool {
+	if e.Protocol != 0 && e.Protocol != pluginsdk.Handshake.ProtocolVersion {
+		return false
+	}
+	return (e.OS == "" || e.OS == runtime.GOOS) && (e.Arch == "" || e.Arch == runtime.GOARCH)
+}
+
+// offers reports whether the entry advertises the capability ref
+func (e IndexEntry) offers(ref string) bool {
+	name, version := pluginsdk.ParseCapabilityRef(ref)
+	for _, c := range e.Capabilities {
+		n, v := pluginsdk.ParseCapabilityRef(c)
+		if n == name && (version == "" || v == version) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteCapability runs a capability on the plugin Provider picks.
+// When none does and provision.index is set, a plugin advertising the
+// capability is installed from the index, with approval unless
+// provision.policy is "auto", loaded and called.
+func (pm *PluginManager) ExecuteCapability(capability string, args map[string]interface{}) (string, error) {
+	name, err := pm.Provider(capability)
+	if err != nil {
+		return "", err
+	}
+
+	callArgs := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	callArgs[pluginsdk.ArgCapability] = capability
+	return pm.ExecutePlugin(name, callArgs)
+}
+
+// Provider returns the plugin ExecuteCapability runs a capability on: of
+// those offering it the cheapest, then the fastest by their hints. One is
+// installed if needed and allowed.
+func (pm *PluginManager) Provider(capability string) (string, error) {
+	pm.mu.RLock()
+	names := pm.capabilityProviders(capability)
+	pm.mu.RUnlock()
+	if len(names) > 0 {
+		return names[0], nil
+	}
+	if pm.Config().Provision.Index == "" {
+		return "", pm.noProvider(capability)
+	}
+	return pm.install(capability)
+}
+
+// install installs and loads a plugin from the index for capability.
+// Installs are serialized, so concurrent calls install it once.
+func (pm *PluginManager) install(capability string) (string, error) {
+	pm.installMu.Lock()
+	defer pm.installMu.Unlock()
+
+	pm.mu.RLock()
+	names := pm.capabilityProviders(capability)
+	pm.mu.RUnlock()
+	if len(names) > 0 {
+		return names[0], nil
+	}
+
+	cfg := pm.Config()
+	prov := cfg.Provision
+	client := &http.Client{Timeout: prov.timeout()}
+	index, err := readIndex(client, prov.Index)
+	if err != nil {
+		return "", fmt.Errorf("failed to read registry index: %w", err)
+	}
+	var entry *IndexEntry
+	for i, e := range index.Plugins {
+		if e.offers(capability) && e.runsHere() && !cfg.isDenied(e.Name) {
+			entry = &index.Plugins[i]
+			break
+		}
+	}
+	if entry == nil {
+		return "", pm.noProvider(capability)
+	}
+
+	if prov.Policy != InstallAuto && (pm.hooks.ApproveInstall == nil || !pm.hooks.ApproveInstall(*entry, capability)) {
+		return "", pluginsdk.NewError(pluginsdk.CodePermissionDenied, "installing plugin %s v%s for capability %s was not approved", entry.Name, entry.Version, capability)
+	}
+
+	path, err := pm.download(client, prov, *entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to install plugin %s v%s: %w", entry.Name, entry.Version, err)
+	}
+	pm.hostLog.Printf("Installed plugin %s v%s for capability %s: %s", entry.Name, entry.Version, capability, path)
+
+	name, err := pm.loadPlugin(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to load installed plugin %s: %w", entry.Name, err)
+	}
+	pm.events.Publish(Event{Type: EventPluginInstalled, Plugin: name, Data: map[string]interface{}{"version": entry.Version, "capability": capability, "path": path}})
+
+	pm.mu.RLock()
+	names = pm.capabilityProviders(capability)
+	pm.mu.RUnlock()
+	if !containsString(names, name) {
+		return "", fmt.Errorf("installed plugin %s does not offer capability %s", name, capability)
+	}
+	return name, nil
+}
+
+// download fetches the binary of entry, and its manifest and attachments
+// if it has them, into the install directory and returns the binary's
+// path. The binary is only put in place once its checksum matches.
+func (pm *PluginManager) download(client *http.Client, prov ProvisionConfig, entry IndexEntry) (string, error) {
+	if err := checkPluginName(entry.Name); err != nil {
+		return "", err
+	}
+	path, err := pm.installPath(prov, entry.Name)
+	if err != nil {
+		return "", err
+	}
+	return path, fetchEntry(client, pm.artifacts(), prov.Index, entry, path)
+}
+
+// fetchEntry downloads the binary of entry to path, or takes it from the
+// artifact cache, and its manifest and attachments next to it, once the
+// binary's checksum matches
+func fetchEntry(client *http.Client, artifacts *ArtifactCache, index string, entry IndexEntry, path string) error {
+	if entry.SHA256 == "" {
+		return fmt.Errorf("index lists no sha256")
+	}
+	binary, err := artifacts.Fetch(client, resolveRef(index, entry.URL), entry.SHA256)
+	if err != nil {
+		return err
+	}
+	for ext, ref := range map[string]string{manifestExt: entry.Manifest, sbomExt: entry.SBOM, provenanceExt: entry.Provenance} {
+		if ref == "" {
+			continue
+		}
+		data, err := fetch(client, resolveRef(index, ref))
+		if err != nil {
+			return err
+		}
+		if err := installFile(path+ext, data, 0644); err != nil {
+			return err
+		}
+	}
+	return installFile(path, binary, 0755)
+}
+
+// installPath returns where the binary of a plugin being installed goes:
+// provision.dir or the first plugin directory. The binary must not exist.
+func (pm *PluginManager) installPath(prov ProvisionConfig, name string) (string, error) {
+	dir := prov.Dir
+	if dir == "" {
+		dirs := pm.Config().PluginDirs
+		if len(dirs) == 0 {
+			return "", fmt.Errorf("provision.dir is not set and there is no plugin directory")
+		}
+		dir = dirs[0]
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "plugin-"+name)
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+	return path, nil
+}
+
+// installFile puts data at path with the given permissions, through a
+// temporary file so the plugin directory never holds a partial binary
+func installFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readIndex reads the registry index at a path or URL
+func readIndex(client *http.Client, location string) (*RegistryIndex, error) {
+	data, err := fetch(client, location)
+	if err != nil {
+		return nil, err
+	}
+	var index RegistryIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("invalid index %s: %w", location, err)
+	}
+	return &index, nil
+}
+
+// fetch reads a file or http(s) URL
+func fetch(client *http.Client, location string) ([]byte, error) {
+	if !isURL(location) {
+		return os.ReadFile(location)
+	}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", location, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// resolveRef resolves a reference in the index relative to the index
+func resolveRef(index, ref string) string {
+	if isURL(ref) || filepath.IsAbs(ref) {
+		return ref
+	}
+	if isURL(index) {
+		base, err := url.Parse(index)
+		if err != nil {
+			return ref
+		}
+		rel, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return base.ResolveReference(rel).String()
+	}
+	return filepath.Join(filepath.Dir(index), ref)
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
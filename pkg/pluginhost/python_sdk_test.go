@@ -0,0 +1,113 @@
+package pluginhost_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginhost"
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// skipSDK skips a test of an SDK that cannot run here, or fails it when
+// SDK_TESTS is set, so a CI job that installed the SDKs notices them gone
+func skipSDK(t *testing.T, reason string) {
+	t.Helper()
+	if os.Getenv("SDK_TESTS") != "" {
+		t.Fatalf("%s, but SDK_TESTS is set", reason)
+	}
+	t.Skip(reason)
+}
+
+// TestPythonStubsMatchProto checks that the Python SDK's gRPC stubs have
+// every method of the proto, as they are generated separately
+func TestPythonStubsMatchProto(t *testing.T) {
+	proto, err := os.ReadFile(filepath.Join("..", "pluginsdk", "proto", "command.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stubs, err := os.ReadFile(filepath.Join("..", "..", "sdk", "python", "opencode_plugin", "command_pb2_grpc.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var service string
+	for _, line := range strings.Split(string(proto), "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 2 && fields[0] == "service":
+			service = fields[1]
+		case len(fields) >= 2 && fields[0] == "rpc":
+			method, _, _ := strings.Cut(fields[1], "(")
+			path := fmt.Sprintf("'/opencode.plugin.v1.%s/%s'", service, method)
+			if !strings.Contains(string(stubs), path) {
+				t.Errorf("the Python stubs lack %s; run make proto", path)
+			}
+		}
+	}
+}
+
+// TestPythonPluginOverGRPC starts the Python SDK's example plugin from the
+// Go host and calls it over gRPC
+func TestPythonPluginOverGRPC(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		skipSDK(t, "python3 is not installed")
+	}
+	if err := exec.Command(python, "-c", "import grpc, grpc_health, grpc_reflection").Run(); err != nil {
+		skipSDK(t, "the Python SDK's dependencies are not installed: pip install ./sdk/python")
+	}
+	sdk, err := filepath.Abs(filepath.Join("..", "..", "sdk", "python"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host starts binaries, so the script is run through a wrapper
+	dir := t.TempDir()
+	wrapper := fmt.Sprintf("#!/bin/sh\nPYTHONPATH=%q exec %q %q\n", sdk, python, filepath.Join(sdk, "examples", "hello_plugin.py"))
+	if err := os.WriteFile(filepath.Join(dir, "hello-py"), []byte(wrapper), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	pm, err := pluginhost.New(pluginhost.WithPluginDirs(dir), pluginhost.WithLogOutput(&strings.Builder{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	st, err := pm.GetPlugin("hello-py")
+	if err != nil {
+		t.Fatalf("plugin not loaded: %v", err)
+	}
+	if st.Protocol != "grpc" {
+		t.Errorf("protocol = %q, want grpc", st.Protocol)
+	}
+	if len(st.Capabilities) == 0 || st.Capabilities[0].Name != "greet" {
+		t.Errorf("capabilities = %v, want greet first", st.Capabilities)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{"default", map[string]interface{}{}, "Hello World from SuperClaude integration!"},
+		{"casual", map[string]interface{}{"name": "Ada", "type": "casual"}, "Hey Ada! Ready to enhance OpenCode with AI?"},
+		{"technical", map[string]interface{}{"name": "Ada", "type": "technical"}, "Plugin 'hello-py' v1.0.0 initialized. Target: Ada. Integration: operational."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args[pluginsdk.ArgCapability] = "greet"
+			got, err := pm.ExecutePlugin("hello-py", tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
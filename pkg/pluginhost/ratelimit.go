@@ -0,0 +1,109 @@
+package pluginhost
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket allows bursts of up to burst calls, refilled at rate per second
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rl RateLimit) *tokenBucket {
+	return &tokenBucket{
+		rate:   rl.PerSecond,
+		burst:  float64(rl.Burst),
+		tokens: float64(rl.Burst),
+		last:   time.Now(),
+	}
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reconfigure changes the limit in place. Clamping the remaining tokens to
+// the new burst makes a tightened limit take effect on the very next call.
+func (b *tokenBucket) reconfigure(rl RateLimit) {
+	b.rate = rl.PerSecond
+	b.burst = float64(rl.Burst)
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// rateLimiter applies the global limit plus optional per-plugin limits
+type rateLimiter struct {
+	global    *tokenBucket
+	perPlugin map[string]*tokenBucket
+	mu        sync.Mutex
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{perPlugin: make(map[string]*tokenBucket)}
+}
+
+// Update applies new limits, keeping the state of buckets that still exist
+func (l *rateLimiter) Update(limits LimitsConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.global = updateBucket(l.global, limits.RateLimit)
+	for name, bucket := range l.perPlugin {
+		if _, ok := limits.PluginRateLimits[name]; !ok {
+			delete(l.perPlugin, name)
+			continue
+		}
+		if b := updateBucket(bucket, limits.PluginRateLimits[name]); b != nil {
+			l.perPlugin[name] = b
+		} else {
+			delete(l.perPlugin, name)
+		}
+	}
+	for name, rl := range limits.PluginRateLimits {
+		if _, ok := l.perPlugin[name]; !ok {
+			if b := updateBucket(nil, rl); b != nil {
+				l.perPlugin[name] = b
+			}
+		}
+	}
+}
+
+func updateBucket(b *tokenBucket, rl RateLimit) *tokenBucket {
+	if rl.PerSecond == 0 {
+		return nil
+	}
+	if b == nil {
+		return newTokenBucket(rl)
+	}
+	b.reconfigure(rl)
+	return b
+}
+
+// Allow reports whether a call to the plugin may proceed now
+func (l *rateLimiter) Allow(plugin string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if b := l.perPlugin[plugin]; b != nil && !b.allow(now) {
+		return false
+	}
+	if l.global != nil && !l.global.allow(now) {
+		return false
+	}
+	return true
+}
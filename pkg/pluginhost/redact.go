@@ -0,0 +1,136 @@
+package pluginhost
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultRedactFields are the argument names redacted when
+// redaction.fields is not set
+var DefaultRedactFields = []string{"*password*", "*passwd*", "*secret*", "*token*", "*api_key*", "*apikey*", "*credential*", "*private_key*"}
+
+// RedactionConfig keeps sensitive arguments out of the call log, history
+// and traces. An argument is redacted when its name matches one of Fields,
+// or when the capability's args_schema marks its property "sensitive" or
+// "writeOnly". Plugins still receive the real values.
+type RedactionConfig struct {
+	// Fields are patterns such as "*token*", matched case-insensitively
+	// against argument names at any depth; defaults to
+	// DefaultRedactFields, and an empty list leaves only what schemas mark
+	Fields []string `json:"fields"`
+}
+
+func (c *RedactionConfig) validate() error {
+	for _, p := range c.Fields {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			return fmt.Errorf("redaction.fields: invalid pattern %q", p)
+		}
+	}
+	return nil
+}
+
+func (c *RedactionConfig) fields() []string {
+	if c.Fields == nil {
+		return DefaultRedactFields
+	}
+	return c.Fields
+}
+
+// recordArgs returns the arguments of a call as they are recorded: with
+// the names of pluginsdk.ArgEnv variables only and sensitive values
+// replaced. The caller holds pm.mu.
+func (pm *PluginManager) recordArgs(info *pluginInfo, args map[string]interface{}) map[string]interface{} {
+	var schema map[string]interface{}
+	if ref, ok := args[pluginsdk.ArgCapability].(string); ok {
+		name, version := pluginsdk.ParseCapabilityRef(ref)
+		if v, ok := args[pluginsdk.ArgCapabilityVersion].(string); ok && version == "" {
+			version = v
+		}
+		if c := findCapability(info.Capabilities, name, version); c != nil {
+			schema = c.ArgsSchema
+		}
+	}
+	r := redactor{fields: pm.config.Redaction.fields()}
+	return r.object(recordedArgs(args), schema)
+}
+
+// redactor replaces sensitive values in arguments, following the schema
+// into nested objects and arrays
+type redactor struct {
+	fields []string
+}
+
+func (r redactor) object(m map[string]interface{}, schema map[string]interface{}) map[string]interface{} {
+	props, _ := schema["properties"].(map[string]interface{})
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		prop, _ := props[k].(map[string]interface{})
+		if sensitive(prop) || r.matches(k) {
+			out[k] = redacted
+			continue
+		}
+		out[k] = r.value(v, prop)
+	}
+	return out
+}
+
+func (r redactor) value(v interface{}, schema map[string]interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return r.object(v, schema)
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			if sensitive(items) {
+				out[i] = redacted
+			} else {
+				out[i] = r.value(e, items)
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// matches reports whether an argument name matches a redaction pattern
+func (r redactor) matches(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range r.fields {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitive reports whether a property schema marks its value sensitive
+func sensitive(schema map[string]interface{}) bool {
+	s, _ := schema["sensitive"].(bool)
+	w, _ := schema["writeOnly"].(bool)
+	return s || w
+}
+
+// hasRedacted reports whether recorded arguments had values redacted
+func hasRedacted(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v == redacted
+	case map[string]interface{}:
+		for _, e := range v {
+			if hasRedacted(e) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range v {
+			if hasRedacted(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package pluginhost
+
+import (
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// EventCapabilitiesChanged is published when a plugin registers or
+// unregisters capabilities while it runs
+const EventCapabilitiesChanged = "plugin.capabilities_changed"
+
+func (h *pluginHost) RegisterCapabilities(capabilities []pluginsdk.Capability) error {
+	return h.pm.registerCapabilities(h.path, capabilities)
+}
+
+func (h *pluginHost) UnregisterCapabilities(refs []string) error {
+	return h.pm.unregisterCapabilities(h.path, refs)
+}
+
+// registerCapabilities adds capabilities to the plugin running the binary
+// at path, replacing those with the same name and version
+func (pm *PluginManager) registerCapabilities(path string, capabilities []pluginsdk.Capability) error {
+	refs := make([]string, 0, len(capabilities))
+	for _, c := range capabilities {
+		if c.Name == "" || strings.Contains(c.Name, "@") {
+			return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "invalid capability name %q", c.Name)
+		}
+		if err := c.ValidateHints(); err != nil {
+			return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "%v", err)
+		}
+		refs = append(refs, c.Ref())
+	}
+
+	pm.mu.Lock()
+	info := pm.pluginAt(path)
+	if info == nil {
+		pm.mu.Unlock()
+		return errNotRegistered()
+	}
+	for _, c := range capabilities {
+		info.registered = append(withoutCapability(info.registered, c), c)
+	}
+	name := pm.capabilitiesChanged(info)
+	pm.mu.Unlock()
+
+	pm.hostLog.Printf("Plugin %s registered capabilities: %s", name, strings.Join(refs, ", "))
+	pm.publishFrom(name, Event{Type: EventCapabilitiesChanged, Plugin: name, Data: map[string]interface{}{"registered": refs}})
+	return nil
+}
+
+// unregisterCapabilities withdraws capabilities of the plugin running the
+// binary at path, whether it declared or registered them
+func (pm *PluginManager) unregisterCapabilities(path string, refs []string) error {
+	for _, ref := range refs {
+		if name, _ := pluginsdk.ParseCapabilityRef(ref); name == "" {
+			return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "invalid capability reference %q", ref)
+		}
+	}
+
+	pm.mu.Lock()
+	info := pm.pluginAt(path)
+	if info == nil {
+		pm.mu.Unlock()
+		return errNotRegistered()
+	}
+	info.registered = withoutRefs(info.registered, refs)
+	for _, ref := range refs {
+		if !containsString(info.unregistered, ref) {
+			info.unregistered = append(info.unregistered, ref)
+		}
+	}
+	name := pm.capabilitiesChanged(info)
+	pm.mu.Unlock()
+
+	pm.hostLog.Printf("Plugin %s unregistered capabilities: %s", name, strings.Join(refs, ", "))
+	pm.publishFrom(name, Event{Type: EventCapabilitiesChanged, Plugin: name, Data: map[string]interface{}{"unregistered": refs}})
+	return nil
+}
+
+// pluginAt returns the plugin running the binary at path; nil while it is
+// still starting. The caller holds pm.mu.
+func (pm *PluginManager) pluginAt(path string) *pluginInfo {
+	for _, info := range pm.plugins {
+		if info.Path == path {
+			return info
+		}
+	}
+	return nil
+}
+
+func errNotRegistered() error {
+	return pluginsdk.NewError(pluginsdk.CodeUnavailable, "plugin is not registered yet; report the capabilities known at startup from GetCapabilities")
+}
+
+// capabilitiesChanged recomputes the capabilities of info, which the
+// router, Provider and FindCapabilities read, and drops results cached
+// from the ones replaced. The caller holds pm.mu.
+func (pm *PluginManager) capabilitiesChanged(info *pluginInfo) string {
+	info.Capabilities = info.capabilities()
+	if pm.cache != nil {
+		pm.cache.InvalidatePlugin(info.Name)
+	}
+	return info.Name
+}
+
+// capabilities returns what the plugin offers: the declared capabilities
+// neither unregistered nor registered again, then those registered since
+func (info *pluginInfo) capabilities() []pluginsdk.Capability {
+	if len(info.registered) == 0 && len(info.unregistered) == 0 {
+		return info.declared
+	}
+	caps := withoutRefs(info.declared, info.unregistered)
+	for _, c := range info.registered {
+		caps = append(withoutCapability(caps, c), c)
+	}
+	return caps
+}
+
+// withoutRefs returns the capabilities matching none of refs; a bare name
+// matches every version. caps is not modified.
+func withoutRefs(caps []pluginsdk.Capability, refs []string) []pluginsdk.Capability {
+	kept := make([]pluginsdk.Capability, 0, len(caps))
+	for _, c := range caps {
+		if !matchesRef(c, refs) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// withoutCapability returns the capabilities other than the one with the
+// name and version of c
+func withoutCapability(caps []pluginsdk.Capability, c pluginsdk.Capability) []pluginsdk.Capability {
+	kept := make([]pluginsdk.Capability, 0, len(caps))
+	for _, k := range caps {
+		if k.Name != c.Name || k.Version != c.Version {
+			kept = append(kept, k)
+		}
+	}
+	return kept
+}
+
+func matchesRef(c pluginsdk.Capability, refs []string) bool {
+	for _, ref := range refs {
+		name, version := pluginsdk.ParseCapabilityRef(ref)
+		if c.Name == name && (version == "" || c.Version == version) {
+			return true
+		}
+	}
+	return false
+}
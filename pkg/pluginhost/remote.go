@@ -0,0 +1,403 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultRemoteTimeout bounds a call to a remote host without its own timeout
+const DefaultRemoteTimeout = 60 * time.Second
+
+// DefaultRemoteRefresh is how often a remote host's plugins are listed again
+// when refresh is not set
+const DefaultRemoteRefresh = 30 * time.Second
+
+// RemoteConfig is another host whose plugins this host calls through its
+// management API, as served by hostapi. A central orchestrator attaches its
+// worker hosts this way.
+type RemoteConfig struct {
+	// URL is the base URL of the remote host's management API,
+	// e.g. "http://worker-1:8080"
+	URL string `json:"url"`
+
+	// APIKey is a key of the remote host's api section, usually a
+	// "secret://name" reference
+	APIKey string `json:"api_key"`
+
+	// Timeout bounds each call; defaults to DefaultRemoteTimeout
+	Timeout Duration `json:"timeout"`
+
+	// Refresh is how often the remote host's plugins are listed;
+	// defaults to DefaultRemoteRefresh
+	Refresh Duration `json:"refresh"`
+}
+
+func (c *RemoteConfig) validate(name string) error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("remotes.%s.url must be an http or https URL, got %q", name, c.URL)
+	}
+	if c.Timeout < 0 {
+		return fmt.Errorf("remotes.%s.timeout must not be negative", name)
+	}
+	if c.Refresh < 0 {
+		return fmt.Errorf("remotes.%s.refresh must not be negative", name)
+	}
+	return nil
+}
+
+// RemoteStatus reports an attached remote host
+type RemoteStatus struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+
+	// Plugins are the plugins the remote host serves, as last listed
+	Plugins []string `json:"plugins"`
+
+	// LastSync is when the plugins were last listed successfully
+	LastSync time.Time `json:"last_sync"`
+
+	// Error is why the latest listing failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// remoteHost is an attached remote host and the plugins it last listed
+type remoteHost struct {
+	name   string
+	cfg    RemoteConfig
+	key    string
+	client *http.Client
+	stop   chan struct{}
+
+	mu       sync.RWMutex
+	plugins  map[string]PluginStatus
+	lastSync time.Time
+	lastErr  string
+}
+
+func newRemoteHost(name string, cfg RemoteConfig, key string) *remoteHost {
+	timeout := time.Duration(cfg.Timeout)
+	if timeout == 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+	return &remoteHost{
+		name:   name,
+		cfg:    cfg,
+		key:    key,
+		client: &http.Client{Timeout: timeout},
+		stop:   make(chan struct{}),
+	}
+}
+
+// sync lists the remote host's own plugins. Plugins it serves from hosts
+// of its own are left out, so hosts attached to each other do not route
+// calls in circles.
+func (r *remoteHost) sync() error {
+	var statuses []PluginStatus
+	err := r.do(http.MethodGet, "/plugins", nil, &statuses)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.lastErr = err.Error()
+		return err
+	}
+	r.plugins = make(map[string]PluginStatus, len(statuses))
+	for _, st := range statuses {
+		if st.Host != "" {
+			continue
+		}
+		st.Host = r.name
+		st.Workspace = nil
+		r.plugins[st.Name] = st
+	}
+	r.lastSync = time.Now()
+	r.lastErr = ""
+	return nil
+}
+
+// refresh keeps the plugin list current until the remote is detached
+func (r *remoteHost) refresh(logf func(string, ...interface{})) {
+	interval := time.Duration(r.cfg.Refresh)
+	if interval == 0 {
+		interval = DefaultRemoteRefresh
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.sync(); err != nil {
+				logf("Failed to list plugins of remote host %s: %v", r.name, err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+func (r *remoteHost) plugin(name string) (PluginStatus, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	st, ok := r.plugins[name]
+	return st, ok
+}
+
+func (r *remoteHost) status() RemoteStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	st := RemoteStatus{Name: r.name, URL: r.cfg.URL, LastSync: r.lastSync, Error: r.lastErr, Plugins: []string{}}
+	for name := range r.plugins {
+		st.Plugins = append(st.Plugins, name)
+	}
+	sort.Strings(st.Plugins)
+	return st
+}
+
+// remoteError is the error body of the management API
+type remoteError struct {
+	Error     string            `json:"error"`
+	Code      string            `json:"code"`
+	Retryable bool              `json:"retryable"`
+	Details   map[string]string `json:"details"`
+}
+
+// execute runs a call on the remote host. Failures to reach it are
+// CodeUnavailable and retryable; a plugin's failure keeps its code.
+func (r *remoteHost) execute(plugin string, args map[string]interface{}) (ExecuteResult, error) {
+	var resp ExecuteResult
+	body := map[string]interface{}{"args": args}
+	if err := r.do(http.MethodPost, "/plugins/"+url.PathEscape(plugin)+"/execute", body, &resp); err != nil {
+		return ExecuteResult{}, err
+	}
+	return resp, nil
+}
+
+// do sends a request to the management API and decodes the JSON answer
+// into out
+func (r *remoteHost) do(method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "cannot send arguments to remote host %s: %v", r.name, err)
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, r.cfg.URL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.key != "" {
+		req.Header.Set("Authorization", "Bearer "+r.key)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		pe := pluginsdk.NewError(pluginsdk.CodeUnavailable, "remote host %s: %v", r.name, err)
+		pe.Retryable = true
+		return pe
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e remoteError
+		data, _ := io.ReadAll(resp.Body)
+		if json.Unmarshal(data, &e) != nil || e.Error == "" {
+			pe := pluginsdk.NewError(pluginsdk.CodeUnavailable, "remote host %s answered %s", r.name, resp.Status)
+			pe.Retryable = resp.StatusCode >= 500
+			return pe
+		}
+		code := pluginsdk.ErrorCode(e.Code)
+		if code == "" {
+			code = pluginsdk.CodeUnknown
+		}
+		return &pluginsdk.PluginError{Code: code, Message: e.Error, Retryable: e.Retryable, Details: e.Details}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return pluginsdk.NewError(pluginsdk.CodeUnavailable, "invalid answer from remote host %s: %v", r.name, err)
+	}
+	return nil
+}
+
+// AttachRemote makes the plugins of another host callable through this
+// manager. Calls to a plugin this host does not run go to the remote host
+// that lists it; plugins of this host take precedence. The remote host is
+// attached even when it cannot be reached yet; the error says why its
+// plugins are not listed.
+func (pm *PluginManager) AttachRemote(name string, cfg RemoteConfig) error {
+	if name == "" {
+		return fmt.Errorf("remote host needs a name")
+	}
+	if err := cfg.validate(name); err != nil {
+		return err
+	}
+	key, err := pm.ResolveSecret(cfg.APIKey)
+	if err != nil {
+		return fmt.Errorf("remotes.%s.api_key: %w", name, err)
+	}
+	r := newRemoteHost(name, cfg, key)
+
+	pm.mu.Lock()
+	old := pm.remotes[name]
+	if pm.remotes == nil {
+		pm.remotes = make(map[string]*remoteHost)
+	}
+	pm.remotes[name] = r
+	pm.mu.Unlock()
+	if old != nil {
+		close(old.stop)
+	}
+
+	go r.refresh(pm.hostLog.Printf)
+	if err := r.sync(); err != nil {
+		return fmt.Errorf("failed to list plugins of remote host %s: %w", name, err)
+	}
+	pm.hostLog.Printf("Attached remote host %s at %s with %d plugin(s)", name, r.cfg.URL, len(r.status().Plugins))
+	return nil
+}
+
+// DetachRemote stops routing calls to a remote host
+func (pm *PluginManager) DetachRemote(name string) {
+	pm.mu.Lock()
+	r := pm.remotes[name]
+	delete(pm.remotes, name)
+	pm.mu.Unlock()
+
+	if r != nil {
+		close(r.stop)
+		pm.hostLog.Printf("Detached remote host %s", name)
+	}
+}
+
+// Remotes reports the attached remote hosts, sorted by name
+func (pm *PluginManager) Remotes() []RemoteStatus {
+	pm.mu.RLock()
+	remotes := make([]*remoteHost, 0, len(pm.remotes))
+	for _, r := range pm.remotes {
+		remotes = append(remotes, r)
+	}
+	pm.mu.RUnlock()
+
+	statuses := make([]RemoteStatus, 0, len(remotes))
+	for _, r := range remotes {
+		statuses = append(statuses, r.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// applyRemotes attaches, re-attaches and detaches remote hosts to match
+// the config
+func (pm *PluginManager) applyRemotes(remotes map[string]RemoteConfig) {
+	pm.mu.RLock()
+	current := make(map[string]RemoteConfig, len(pm.remotes))
+	for name, r := range pm.remotes {
+		current[name] = r.cfg
+	}
+	pm.mu.RUnlock()
+
+	for name := range current {
+		if _, ok := remotes[name]; !ok {
+			pm.DetachRemote(name)
+		}
+	}
+	for name, cfg := range remotes {
+		cfg.URL = strings.TrimSuffix(cfg.URL, "/")
+		if cur, ok := current[name]; ok && cur == cfg {
+			continue
+		}
+		if err := pm.AttachRemote(name, cfg); err != nil {
+			pm.hostLog.Printf("Remote host %s: %v", name, err)
+		}
+	}
+}
+
+// remoteFor returns the remote host serving a plugin this host does not
+// run, or nil. With several, the first by name is used.
+func (pm *PluginManager) remoteFor(name string) *remoteHost {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if _, local := pm.plugins[name]; local {
+		return nil
+	}
+	var found *remoteHost
+	for _, r := range pm.remotes {
+		if _, ok := r.plugin(name); ok && (found == nil || r.name < found.name) {
+			found = r
+		}
+	}
+	return found
+}
+
+// remotePlugins returns the status of the remote plugins this host does
+// not shadow with its own. The caller holds pm.mu.
+func (pm *PluginManager) remotePlugins() []PluginStatus {
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(pm.remotes))
+	for name := range pm.remotes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var plugins []PluginStatus
+	for _, host := range names {
+		r := pm.remotes[host]
+		r.mu.RLock()
+		for name, st := range r.plugins {
+			if _, local := pm.plugins[name]; local || seen[name] {
+				continue
+			}
+			seen[name] = true
+			st.Capabilities = copyCapabilities(st.Capabilities)
+			plugins = append(plugins, st)
+		}
+		r.mu.RUnlock()
+	}
+	return plugins
+}
+
+// executeRemote runs a call on the remote host serving the plugin
+func (pm *PluginManager) executeRemote(r *remoteHost, name string, args map[string]interface{}) (ExecuteResult, error) {
+	start := time.Now()
+	res, err := r.execute(name, args)
+	elapsed := time.Since(start)
+
+	pm.observeAttempt(name, args, elapsed, err)
+	pm.publishExecuted(name, args, err)
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	pm.executed(Execution{Plugin: name, Capability: capability, Duration: elapsed, Attempts: 1, Err: err})
+	if err != nil {
+		return ExecuteResult{}, fmt.Errorf("plugin execution failed on remote host %s: %w", r.name, err)
+	}
+	return res, nil
+}
+
+// closeRemotes stops refreshing every remote host. The caller holds pm.mu.
+func (pm *PluginManager) closeRemotes() {
+	for _, r := range pm.remotes {
+		close(r.stop)
+	}
+	pm.remotes = nil
+}
@@ -0,0 +1,142 @@
+package pluginhost
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventPluginOverBudget is published when a plugin exceeds a soft resource
+// threshold
+const EventPluginOverBudget = "plugin.over_budget"
+
+// ResourceConfig controls sampling of the CPU and memory plugin processes
+// use. Sampling reads /proc and is only available on Linux.
+type ResourceConfig struct {
+	// Interval between samples; zero disables sampling
+	Interval Duration `json:"interval"`
+
+	// CPUPercent is a soft limit on CPU use, where 100 is one full core;
+	// zero means none
+	CPUPercent float64 `json:"cpu_percent"`
+
+	// RSSMB is a soft limit on resident memory in MiB; zero means none
+	RSSMB int64 `json:"rss_mb"`
+}
+
+func (c *ResourceConfig) validate() error {
+	if c.Interval < 0 || c.CPUPercent < 0 || c.RSSMB < 0 {
+		return fmt.Errorf("resources.interval, resources.cpu_percent and resources.rss_mb must not be negative")
+	}
+	return nil
+}
+
+// ResourceUsage is the most recent sample of a plugin process
+type ResourceUsage struct {
+	// CPUPercent is the CPU used since the previous sample, where 100 is
+	// one full core
+	CPUPercent float64   `json:"cpu_percent"`
+	RSSBytes   int64     `json:"rss_bytes"`
+	SampledAt  time.Time `json:"sampled_at"`
+
+	// OverBudget is set while a soft threshold is exceeded
+	OverBudget bool `json:"over_budget"`
+}
+
+// processSample is a raw reading of a process
+type processSample struct {
+	cpu time.Duration
+	rss int64
+	at  time.Time
+}
+
+// applyResources starts or stops resource sampling to match cfg. The caller
+// holds pm.mu.
+func (pm *PluginManager) applyResources(cfg *HostConfig) {
+	switch {
+	case cfg.Resources.Interval > 0 && pm.resourceStop == nil:
+		pm.resourceStop = make(chan struct{})
+		go pm.resourceLoop(pm.resourceStop)
+	case cfg.Resources.Interval == 0 && pm.resourceStop != nil:
+		close(pm.resourceStop)
+		pm.resourceStop = nil
+	}
+}
+
+// resourceLoop samples every running plugin until stop is closed
+func (pm *PluginManager) resourceLoop(stop chan struct{}) {
+	for {
+		interval := time.Duration(pm.Config().Resources.Interval)
+		if interval <= 0 {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		pm.sampleResources()
+	}
+}
+
+// sampleResources reads the usage of every running plugin process and
+// reports plugins that cross a soft threshold
+func (pm *PluginManager) sampleResources() {
+	cfg := pm.Config().Resources
+
+	type alert struct {
+		name string
+		over bool
+		msg  string
+	}
+	var alerts []alert
+
+	pm.mu.Lock()
+	for name, info := range pm.plugins {
+		if info.Client == nil || info.crashed {
+			continue
+		}
+		rc := info.Client.ReattachConfig()
+		if rc == nil || rc.Pid <= 0 {
+			continue
+		}
+		s, err := sampleProcess(rc.Pid)
+		if err != nil {
+			continue
+		}
+
+		usage := &ResourceUsage{RSSBytes: s.rss, SampledAt: s.at}
+		if prev := info.lastSample; prev != nil && info.lastSamplePid == rc.Pid {
+			if wall := s.at.Sub(prev.at); wall > 0 {
+				usage.CPUPercent = 100 * float64(s.cpu-prev.cpu) / float64(wall)
+			}
+		}
+		info.lastSample = s
+		info.lastSamplePid = rc.Pid
+
+		var reasons []string
+		if cfg.CPUPercent > 0 && usage.CPUPercent > cfg.CPUPercent {
+			reasons = append(reasons, fmt.Sprintf("CPU %.0f%% over %.0f%%", usage.CPUPercent, cfg.CPUPercent))
+		}
+		if cfg.RSSMB > 0 && usage.RSSBytes > cfg.RSSMB<<20 {
+			reasons = append(reasons, fmt.Sprintf("RSS %d MiB over %d MiB", usage.RSSBytes>>20, cfg.RSSMB))
+		}
+		usage.OverBudget = len(reasons) > 0
+
+		wasOver := info.Resources != nil && info.Resources.OverBudget
+		if usage.OverBudget != wasOver {
+			alerts = append(alerts, alert{name, usage.OverBudget, strings.Join(reasons, ", ")})
+		}
+		info.Resources = usage
+	}
+	pm.mu.Unlock()
+
+	for _, a := range alerts {
+		if !a.over {
+			pm.hostLog.Printf("Plugin %s is back within its resource budget", a.name)
+			continue
+		}
+		pm.hostLog.Printf("Plugin %s exceeds its resource budget: %s", a.name, a.msg)
+		pm.events.Publish(Event{Type: EventPluginOverBudget, Plugin: a.name, Data: map[string]interface{}{"reasons": a.msg}})
+	}
+}
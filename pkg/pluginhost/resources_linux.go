@@ -0,0 +1,46 @@
+package pluginhost
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc/<pid>/stat. It
+// is 100 on every Linux architecture Go supports.
+const clockTicks = 100
+
+// sampleProcess reads the CPU time and resident memory of a process from
+// /proc
+func sampleProcess(pid int) (*processSample, error) {
+	at := time.Now()
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+
+	// The command name may contain spaces, so fields are counted from the
+	// closing parenthesis; utime and stime are fields 14 and 15, rss is 24
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return nil, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+	utime, err1 := strconv.ParseInt(fields[11], 10, 64)
+	stime, err2 := strconv.ParseInt(fields[12], 10, 64)
+	rss, err3 := strconv.ParseInt(fields[21], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, fmt.Errorf("malformed /proc/%d/stat", pid)
+	}
+
+	return &processSample{
+		cpu: time.Duration(utime+stime) * time.Second / clockTicks,
+		rss: rss * int64(os.Getpagesize()),
+		at:  at,
+	}, nil
+}
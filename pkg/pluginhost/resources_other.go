@@ -0,0 +1,10 @@
+//go:build !linux
+
+package pluginhost
+
+import "errors"
+
+// sampleProcess is only implemented on Linux
+func sampleProcess(pid int) (*processSample, error) {
+	return nil, errors.New("resource sampling is only supported on Linux")
+}
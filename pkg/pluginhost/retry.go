@@ -0,0 +1,52 @@
+package pluginhost
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// delay returns how long to wait before retry number n, counting from 1
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := time.Duration(p.Backoff)
+	for i := 1; i < n && (p.MaxBackoff == 0 || d < time.Duration(p.MaxBackoff)); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > time.Duration(p.MaxBackoff) {
+		d = time.Duration(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		// Spread retries from many callers so they do not arrive in lockstep
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+// retryPolicy returns the policy for a call. Calls that name no capability,
+// or one its plugin does not mark idempotent, are never retried because
+// repeating them could apply a side effect twice. The caller holds pm.mu.
+func (pm *PluginManager) retryPolicy(info *pluginInfo, args map[string]interface{}) RetryPolicy {
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	if capability == "" {
+		return RetryPolicy{}
+	}
+
+	version, _ := args[pluginsdk.ArgCapabilityVersion].(string)
+	if c := findCapability(info.Capabilities, capability, version); c == nil || !c.Idempotent {
+		return RetryPolicy{}
+	}
+
+	if p, ok := pm.config.Retry.Capabilities[capability]; ok {
+		return p
+	}
+	return pm.config.Retry.Default
+}
+
+// isRetryable reports whether the plugin or transport marked err as worth
+// repeating
+func isRetryable(err error) bool {
+	var pe *pluginsdk.PluginError
+	return errors.As(err, &pe) && pe.Retryable
+}
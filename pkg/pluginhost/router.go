@@ -0,0 +1,333 @@
+package pluginhost
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// defaultRules activate the SuperClaude personas
+//
+//go:embed personas.yaml
+var defaultRules []byte
+
+// RoutingConfig chooses the rules that activate personas automatically
+type RoutingConfig struct {
+	// Rules is a YAML file of persona rules replacing the built-in ones
+	Rules string `json:"rules"`
+}
+
+// PersonaRule activates a persona for requests matching all of its lists.
+// An empty list matches any request.
+type PersonaRule struct {
+	Persona string `yaml:"persona" json:"persona"`
+
+	// Commands are command names, e.g. "build"
+	Commands []string `yaml:"commands" json:"commands,omitempty"`
+
+	// Files are patterns such as "*.tsx"; one file of the request must
+	// match one of them. Patterns without a slash match the base name.
+	Files []string `yaml:"files" json:"files,omitempty"`
+
+	// Flags are SuperClaude flags such as "--magic"; aliases count
+	Flags []string `yaml:"flags" json:"flags,omitempty"`
+
+	// Priority decides between rules that apply; the highest wins
+	Priority int `yaml:"priority" json:"priority,omitempty"`
+
+	// Plugin is preferred over the persona's plugins when it offers the
+	// command's capability
+	Plugin string `yaml:"plugin" json:"plugin,omitempty"`
+
+	// Args are added to the call's arguments unless the request sets them
+	Args map[string]interface{} `yaml:"args" json:"args,omitempty"`
+}
+
+// RouteRequest is a command invocation to find a persona and plugin for
+type RouteRequest struct {
+	Command string                 `json:"command"`
+	Files   []string               `json:"files,omitempty"`
+	Flags   []string               `json:"flags,omitempty"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+
+	// Persona activates a persona explicitly, as --persona-<name> does;
+	// rules are not consulted then
+	Persona string `json:"persona,omitempty"`
+
+	// Input is the positional input of the command, for its argument
+	// templates
+	Input []string `json:"input,omitempty"`
+
+	// WorkDir is where the command runs; argument templates read git
+	// metadata from there. Empty means the host's working directory.
+	WorkDir string `json:"workdir,omitempty"`
+}
+
+// LoadPersonaRules reads persona rules from a YAML file with a top-level
+// "rules" list
+func LoadPersonaRules(path string) ([]PersonaRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persona rules: %w", err)
+	}
+	rules, err := parsePersonaRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid persona rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// DefaultPersonaRules returns the built-in persona rules
+func DefaultPersonaRules() []PersonaRule {
+	rules, err := parsePersonaRules(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("built-in persona rules: %v", err))
+	}
+	return rules
+}
+
+func parsePersonaRules(data []byte) ([]PersonaRule, error) {
+	var doc struct {
+		Rules []PersonaRule `yaml:"rules"`
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(data)))
+	dec.KnownFields(true)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	for i, r := range doc.Rules {
+		if r.Persona == "" {
+			return nil, fmt.Errorf("rule %d names no persona", i+1)
+		}
+		for _, p := range r.Files {
+			if _, err := filepath.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid file pattern %q", i+1, p)
+			}
+		}
+	}
+	return doc.Rules, nil
+}
+
+// loadRules returns the rules the config selects
+func (c *RoutingConfig) loadRules() ([]PersonaRule, error) {
+	if c.Rules == "" {
+		return DefaultPersonaRules(), nil
+	}
+	return LoadPersonaRules(c.Rules)
+}
+
+// PersonaRouter picks the persona and plugin for a command invocation from
+// the command, the files involved and the flags, e.g. a /build touching
+// .tsx files goes to the frontend persona
+type PersonaRouter struct {
+	pm *PluginManager
+}
+
+// Router returns the persona router, which uses the rules of the current
+// config
+func (pm *PluginManager) Router() *PersonaRouter {
+	return &PersonaRouter{pm: pm}
+}
+
+// Rules returns the persona rules in use
+func (r *PersonaRouter) Rules() []PersonaRule {
+	r.pm.mu.RLock()
+	defer r.pm.mu.RUnlock()
+
+	return append([]PersonaRule(nil), r.pm.rules...)
+}
+
+// Resolve picks the persona for req, then the plugin to run it: the
+// rule's plugin, else the first of the persona's configured plugins, else
+// the plugin offering the command's first required capability that its
+// cost and latency hints make the best choice. The returned args are ready
+// for ExecutePlugin: they select that capability
+// and carry the persona in the options, with the MCP servers it prefers
+// added to their mcp list unless the request has --no-mcp. They are built from the command's
+// argument templates, then the rule's args, then req.Args, each overriding
+// the one before. The persona is empty when no rule applies.
+func (r *PersonaRouter) Resolve(req RouteRequest) (persona, plugin string, args map[string]interface{}, err error) {
+	pm := r.pm
+	persona, plugin, capability, cmd, rule, err := pm.route(req)
+	if err != nil {
+		return persona, "", nil, err
+	}
+
+	// Templates may run git, so they are rendered without the lock
+	args, err = pm.renderArgs(cmd, pm.argsData(cmd, req, persona))
+	if err != nil {
+		return persona, "", nil, err
+	}
+	if rule != nil {
+		for k, v := range rule.Args {
+			args[k] = v
+		}
+	}
+	for k, v := range req.Args {
+		args[k] = v
+	}
+	if _, ok := args[pluginsdk.ArgCapability]; !ok {
+		args[pluginsdk.ArgCapability] = capability
+	}
+	if persona != "" {
+		options := make(map[string]interface{})
+		if given, ok := args[pluginsdk.ArgOptions].(map[string]interface{}); ok {
+			for k, v := range given {
+				options[k] = v
+			}
+		}
+		options["persona"] = persona
+		if !pm.noMCP(req.Flags) {
+			addMCPServers(options, r.MCPServers(persona))
+		}
+		args[pluginsdk.ArgOptions] = options
+	}
+	return persona, plugin, args, nil
+}
+
+// route picks the persona, plugin and capability for req, with the command
+// definition and the rule that applied, if any
+func (pm *PluginManager) route(req RouteRequest) (persona, plugin, capability string, cmd CommandDef, rule *PersonaRule, err error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	persona = req.Persona
+	if persona == "" {
+		persona = pm.explicitPersona(req.Flags)
+	}
+	if persona == "" {
+		if rule = pm.matchRule(req); rule != nil {
+			persona = rule.Persona
+		}
+	}
+
+	capability = req.Command
+	for _, c := range pm.commands {
+		if c.Name == req.Command {
+			cmd = c
+			capability = c.Requires[0]
+			break
+		}
+	}
+	providers := pm.capabilityProviders(capability)
+	if len(providers) == 0 {
+		return persona, "", "", cmd, rule, &LookupError{Kind: ErrCapabilityNotSupported, Capability: capability}
+	}
+
+	var preferred []string
+	if rule != nil && rule.Plugin != "" {
+		preferred = append(preferred, rule.Plugin)
+	}
+	preferred = append(preferred, pm.config.Personas[persona].Plugins...)
+	plugin = providers[0]
+	for _, name := range preferred {
+		if containsString(providers, name) {
+			plugin = name
+			break
+		}
+	}
+	return persona, plugin, capability, cmd, rule, nil
+}
+
+// explicitPersona returns the persona a --persona-<name> flag activates
+func (pm *PluginManager) explicitPersona(flags []string) string {
+	for _, flag := range flags {
+		spec, value, err := pm.flags.resolve(flag)
+		if err == nil && spec.Option == "persona" {
+			if name, ok := value.(string); ok {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// matchRule returns the rule that applies to req: the one with the highest
+// priority, then the one matching on more lists, then the earliest. The
+// caller holds pm.mu.
+func (pm *PluginManager) matchRule(req RouteRequest) *PersonaRule {
+	flags := make(map[string]bool, len(req.Flags))
+	for _, f := range req.Flags {
+		flags[pm.flags.canonical(f)] = true
+	}
+
+	var best *PersonaRule
+	bestMatched := 0
+	for i := range pm.rules {
+		rule := &pm.rules[i]
+		matched, ok := rule.match(req, flags, pm.flags.canonical)
+		if !ok {
+			continue
+		}
+		if best == nil || rule.Priority > best.Priority || (rule.Priority == best.Priority && matched > bestMatched) {
+			best, bestMatched = rule, matched
+		}
+	}
+	return best
+}
+
+// match reports whether the rule applies to req and on how many of its
+// lists it matched
+func (rule *PersonaRule) match(req RouteRequest, flags map[string]bool, canonical func(string) string) (int, bool) {
+	matched := 0
+	if len(rule.Commands) > 0 {
+		if !containsString(rule.Commands, req.Command) {
+			return 0, false
+		}
+		matched++
+	}
+	if len(rule.Files) > 0 {
+		if !matchesAnyFile(rule.Files, req.Files) {
+			return 0, false
+		}
+		matched++
+	}
+	if len(rule.Flags) > 0 {
+		found := false
+		for _, f := range rule.Flags {
+			if flags[canonical(f)] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, false
+		}
+		matched++
+	}
+	return matched, true
+}
+
+// matchesAnyFile reports whether one of files matches one of patterns
+func matchesAnyFile(patterns, files []string) bool {
+	for _, file := range files {
+		slashed := filepath.ToSlash(file)
+		base := filepath.Base(file)
+		for _, p := range patterns {
+			target := base
+			if strings.Contains(p, "/") {
+				target = slashed
+			}
+			if ok, _ := filepath.Match(p, target); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
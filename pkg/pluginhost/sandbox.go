@@ -0,0 +1,139 @@
+package pluginhost
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// SandboxConfig confines plugin processes according to the permissions
+// declared in their manifests. Sandboxing is only available on Linux.
+type SandboxConfig struct {
+	Enabled bool `json:"enabled"`
+
+	// Required refuses to start plugins the host cannot confine: plugins
+	// that declare no permissions, and every plugin on other platforms
+	Required bool `json:"required"`
+
+	// Filesystem maps the permission set names manifests use to the
+	// AppArmor profile or SELinux context enforcing them
+	Filesystem map[string]MACProfile `json:"filesystem"`
+}
+
+// MACProfile names the mandatory access control label a plugin runs under.
+// The profile must already be loaded on the machine.
+type MACProfile struct {
+	AppArmor string `json:"apparmor"`
+	SELinux  string `json:"selinux"`
+}
+
+// Permissions are what a plugin declares it needs. Under a sandbox
+// everything not declared is denied.
+type Permissions struct {
+	// Network allows sockets other than Unix domain sockets; without it a
+	// seccomp filter makes creating them fail with EACCES
+	Network bool `json:"network"`
+
+	// Filesystem names a permission set from the host's
+	// sandbox.filesystem, e.g. "readonly"; empty leaves file access to the
+	// host's own confinement
+	Filesystem string `json:"filesystem"`
+
+	// Calls lists the plugins this plugin may call through the host, by
+	// name or by a prefix ending in "*"; without it the plugin may call none
+	Calls []string `json:"calls"`
+
+	// Files lists the roots of the host's files.roots this plugin may use
+	// through the host services, as "<root>", "<root>:read" or
+	// "<root>:write"
+	Files []string `json:"files"`
+
+	// LLM lists the providers of the host's llm.providers this plugin may
+	// use through HostServices.Complete, or "*" for all of them
+	LLM []string `json:"llm"`
+}
+
+// calls returns the plugins p allows calling; p may be nil
+func (p *Permissions) calls() []string {
+	if p == nil {
+		return nil
+	}
+	return p.Calls
+}
+
+// files returns the file roots p grants; p may be nil
+func (p *Permissions) files() []string {
+	if p == nil {
+		return nil
+	}
+	return p.Files
+}
+
+// llm returns the LLM providers p grants; p may be nil
+func (p *Permissions) llm() []string {
+	if p == nil {
+		return nil
+	}
+	return p.LLM
+}
+
+// sandboxSpec is what the sandbox launcher applies before running a plugin
+type sandboxSpec struct {
+	Binary   string `json:"binary"`
+	Network  bool   `json:"network"`
+	AppArmor string `json:"apparmor,omitempty"`
+	SELinux  string `json:"selinux,omitempty"`
+}
+
+func (c *SandboxConfig) validate() error {
+	for name, p := range c.Filesystem {
+		if (p.AppArmor == "") == (p.SELinux == "") {
+			return fmt.Errorf("sandbox.filesystem.%s must set exactly one of apparmor and selinux", name)
+		}
+	}
+	return nil
+}
+
+// pluginCommand returns the command starting the plugin binary at path,
+// confined by the sandbox when it is enabled. Plugins of sandboxed sources
+// must be confined.
+func (pm *PluginManager) pluginCommand(path string) (*exec.Cmd, error) {
+	cfg := pm.Config().Sandbox
+	if pm.trustOf(path) == TrustSandboxed {
+		if !cfg.Enabled {
+			return nil, fmt.Errorf("plugin %s comes from a source trusted only sandboxed, but the sandbox is disabled", path)
+		}
+		cfg.Required = true
+	}
+	if !cfg.Enabled {
+		return exec.Command(path), nil
+	}
+
+	m := pm.pluginManifest(path)
+	if m == nil || m.Permissions == nil {
+		if cfg.Required {
+			return nil, fmt.Errorf("plugin %s declares no permissions and sandboxing is required", path)
+		}
+		pm.hostLog.Printf("Running plugin %s unsandboxed: no permissions declared", path)
+		return exec.Command(path), nil
+	}
+
+	spec := sandboxSpec{Binary: path, Network: m.Permissions.Network}
+	if set := m.Permissions.Filesystem; set != "" {
+		p, ok := cfg.Filesystem[set]
+		if !ok {
+			return nil, fmt.Errorf("plugin %s asks for unknown filesystem permission set %q", m.Name, set)
+		}
+		spec.AppArmor, spec.SELinux = p.AppArmor, p.SELinux
+	}
+
+	cmd, err := sandboxCommand(spec)
+	if err != nil {
+		if cfg.Required {
+			return nil, fmt.Errorf("failed to sandbox plugin %s: %w", m.Name, err)
+		}
+		pm.hostLog.Printf("Running plugin %s unsandboxed: %v", m.Name, err)
+		return exec.Command(path), nil
+	}
+	pm.hostLog.Printf("Sandboxing plugin %s (network: %t, filesystem: %q)", m.Name, spec.Network, m.Permissions.Filesystem)
+	return cmd, nil
+}
@@ -0,0 +1,150 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// sandboxEnv carries the sandbox spec to the launcher. The launcher is the
+// host binary itself: it cannot confine a child between fork and exec, so it
+// starts itself with this variable set, confines that process and then
+// replaces it with the plugin. The plugin keeps the launcher's pid, so the
+// host sees it as a direct child.
+const sandboxEnv = "PLUGINHOST_SANDBOX"
+
+func init() {
+	if spec, ok := os.LookupEnv(sandboxEnv); ok {
+		runSandbox(spec)
+	}
+}
+
+// sandboxCommand returns a command that runs spec.Binary through the launcher
+func sandboxCommand(spec sandboxSpec) (*exec.Cmd, error) {
+	if !spec.Network {
+		if _, err := seccompArch(); err != nil {
+			return nil, err
+		}
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the host binary: %w", err)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(self)
+	// go-plugin appends the host environment and its handshake variables
+	cmd.Env = []string{sandboxEnv + "=" + string(data)}
+	return cmd, nil
+}
+
+// runSandbox confines the launcher process and executes the plugin. It
+// never returns; failures are written to stderr, which the host keeps in
+// the plugin's log tail.
+func runSandbox(raw string) {
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		sandboxFail(fmt.Errorf("invalid sandbox spec: %w", err))
+	}
+	os.Unsetenv(sandboxEnv)
+
+	// LSM exec labels and the seccomp filter are per thread, so the thread
+	// that sets them must be the one calling exec
+	runtime.LockOSThread()
+
+	if spec.AppArmor != "" {
+		if err := setExecLabel("apparmor", "exec "+spec.AppArmor); err != nil {
+			sandboxFail(fmt.Errorf("failed to apply AppArmor profile %s: %w", spec.AppArmor, err))
+		}
+	}
+	if spec.SELinux != "" {
+		if err := setExecLabel("selinux", spec.SELinux); err != nil {
+			sandboxFail(fmt.Errorf("failed to apply SELinux context %s: %w", spec.SELinux, err))
+		}
+	}
+	if !spec.Network {
+		if err := denyNetwork(); err != nil {
+			sandboxFail(fmt.Errorf("failed to install seccomp filter: %w", err))
+		}
+	}
+
+	err := unix.Exec(spec.Binary, []string{spec.Binary}, os.Environ())
+	sandboxFail(fmt.Errorf("failed to execute %s: %w", spec.Binary, err))
+}
+
+func sandboxFail(err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(1)
+}
+
+// setExecLabel sets the label the next exec of this thread transitions to.
+// Newer kernels keep one attr directory per LSM; older ones share attr/exec.
+func setExecLabel(lsm, label string) error {
+	err := os.WriteFile("/proc/thread-self/attr/"+lsm+"/exec", []byte(label), 0)
+	if os.IsNotExist(err) {
+		err = os.WriteFile("/proc/thread-self/attr/exec", []byte(label), 0)
+	}
+	return err
+}
+
+// seccompArch returns the audit architecture the filter checks against
+func seccompArch() (uint32, error) {
+	switch runtime.GOARCH {
+	case "amd64":
+		return unix.AUDIT_ARCH_X86_64, nil
+	case "arm64":
+		return unix.AUDIT_ARCH_AARCH64, nil
+	}
+	return 0, fmt.Errorf("seccomp filter not supported on %s", runtime.GOARCH)
+}
+
+// denyNetwork installs a seccomp filter that fails socket(2) with EACCES
+// for every address family except AF_UNIX, which go-plugin needs to talk
+// to the host. It also sets no_new_privs, which the kernel requires for
+// unprivileged filters.
+func denyNetwork() error {
+	arch, err := seccompArch()
+	if err != nil {
+		return err
+	}
+
+	// Offsets into struct seccomp_data
+	const (
+		offNr   = 0
+		offArch = 4
+		offArg0 = 16 // low 32 bits on little-endian machines
+
+		x32SyscallBit = 0x40000000
+	)
+	filter := []unix.SockFilter{
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offArch},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jf: 7, K: arch},
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offNr},
+		// x32 syscalls share the x86-64 architecture but not its numbers
+		{Code: unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K, Jt: 5, K: x32SyscallBit},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jf: 3, K: unix.SYS_SOCKET},
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: offArg0},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 1, K: unix.AF_UNIX},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ERRNO | uint32(unix.EACCES)},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_ALLOW},
+		{Code: unix.BPF_RET | unix.BPF_K, K: unix.SECCOMP_RET_KILL_PROCESS},
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return err
+	}
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
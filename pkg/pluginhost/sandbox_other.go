@@ -0,0 +1,15 @@
+//go:build !linux
+
+package pluginhost
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// sandboxCommand fails because plugin sandboxing relies on seccomp and Linux
+// security modules
+func sandboxCommand(spec sandboxSpec) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("sandboxing is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,164 @@
+package pluginhost
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// checkSchema checks value, decoded from JSON, against the common subset of
+// JSON Schema capabilities use: type, enum, const, required, properties,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum and maximum. Other keywords are ignored. It returns a
+// problem per violation, each naming the offending location as a JSON
+// pointer.
+func checkSchema(schema map[string]interface{}, value interface{}) []string {
+	var problems []string
+	checkSchemaAt(schema, value, "", &problems)
+	return problems
+}
+
+func checkSchemaAt(schema map[string]interface{}, value interface{}, at string, problems *[]string) {
+	report := func(format string, args ...interface{}) {
+		where := at
+		if where == "" {
+			where = "/"
+		}
+		*problems = append(*problems, where+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		report("got %s, want %v", jsonType(value), t)
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("%v is not one of %v", value, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		report("got %v, want %v", value, c)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					if _, present := v[name]; !present {
+						report("missing required property %q", name)
+					}
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := props[name].(map[string]interface{}); ok {
+				checkSchemaAt(sub, v[name], at+"/"+name, problems)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					report("unexpected property %q", name)
+				}
+			case map[string]interface{}:
+				checkSchemaAt(extra, v[name], at+"/"+name, problems)
+			}
+		}
+	case []interface{}:
+		if n, ok := schemaNumber(schema, "minItems"); ok && float64(len(v)) < n {
+			report("%d item(s), want at least %v", len(v), n)
+		}
+		if n, ok := schemaNumber(schema, "maxItems"); ok && float64(len(v)) > n {
+			report("%d item(s), want at most %v", len(v), n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				checkSchemaAt(items, item, fmt.Sprintf("%s/%d", at, i), problems)
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := schemaNumber(schema, "minLength"); ok && length < n {
+			report("%d character(s), want at least %v", int(length), n)
+		}
+		if n, ok := schemaNumber(schema, "maxLength"); ok && length > n {
+			report("%d character(s), want at most %v", int(length), n)
+		}
+		if p, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(p); err != nil {
+				report("invalid pattern %q in schema", p)
+			} else if !re.MatchString(v) {
+				report("%q does not match %q", v, p)
+			}
+		}
+	case float64:
+		if n, ok := schemaNumber(schema, "minimum"); ok && v < n {
+			report("%v is less than %v", v, n)
+		}
+		if n, ok := schemaNumber(schema, "maximum"); ok && v > n {
+			report("%v is more than %v", v, n)
+		}
+	}
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	n, ok := schema[key].(float64)
+	return n, ok
+}
+
+// matchesType reports whether value has the schema type t, a type name or
+// a list of them
+func matchesType(t interface{}, value interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		got := jsonType(value)
+		return got == t || (t == "number" && got == "integer")
+	case []interface{}:
+		for _, one := range t {
+			if matchesType(one, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// jsonType names the JSON Schema type of a value decoded from JSON
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", value), "*")
+}
@@ -0,0 +1,250 @@
+package pluginhost
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Scores of the ways a query word can match a capability; a word counts
+// with the best way it matches
+const (
+	scoreExactName  = 100
+	scorePrefixName = 60
+	scoreInName     = 40
+	scoreTag        = 30
+	scoreInDesc     = 15
+	scoreTypo       = 10
+	scoreScattered  = 5
+)
+
+// What a CapabilityMatch matched on
+const (
+	MatchName        = "name"
+	MatchTag         = "tag"
+	MatchDescription = "description"
+	MatchFuzzy       = "fuzzy"
+)
+
+// SearchConfig sets how FindCapabilities sees the capabilities
+type SearchConfig struct {
+	// Tags adds tags to capabilities, keyed by capability name, reference
+	// such as "greet@v2", or "plugin/capability", e.g.
+	// {"hello/greet": ["onboarding"]}. They count as the plugin's own.
+	Tags map[string][]string `json:"tags"`
+}
+
+func (c *SearchConfig) validate() error {
+	for key, tags := range c.Tags {
+		if key == "" {
+			return fmt.Errorf("search.tags: capability must not be empty")
+		}
+		for _, tag := range tags {
+			if strings.TrimSpace(tag) == "" || strings.ContainsAny(tag, " \t") {
+				return fmt.Errorf("search.tags.%s: %q is not a tag", key, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// CapabilityMatch is a capability FindCapabilities found
+type CapabilityMatch struct {
+	Plugin        string               `json:"plugin"`
+	PluginVersion string               `json:"plugin_version"`
+	Capability    pluginsdk.Capability `json:"capability"`
+
+	// Score ranks the match; higher is better
+	Score int `json:"score"`
+
+	// Matched is what the query matched on, e.g. "name" and "tag"
+	Matched []string `json:"matched,omitempty"`
+}
+
+// searchQuery is a parsed query
+type searchQuery struct {
+	words   []string
+	tags    []string
+	plugins []string
+}
+
+// parseQuery splits a query into free words and "tag:" and "plugin:"
+// filters, all lower case
+func parseQuery(query string) searchQuery {
+	var q searchQuery
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		switch {
+		case strings.HasPrefix(field, "tag:") && len(field) > len("tag:"):
+			q.tags = append(q.tags, strings.TrimPrefix(field, "tag:"))
+		case strings.HasPrefix(field, "plugin:") && len(field) > len("plugin:"):
+			q.plugins = append(q.plugins, strings.TrimPrefix(field, "plugin:"))
+		default:
+			q.words = append(q.words, field)
+		}
+	}
+	return q
+}
+
+// FindCapabilities returns the capabilities of the enabled plugins matching
+// query, best first. Every word of the query must be found in a
+// capability's name, tags or description, or be close to its name or a tag
+// as a typo or with letters left out, e.g. "grt.frml" for "greet.formal".
+// "tag:x" keeps capabilities tagged x and "plugin:x" those of plugin x. An
+// empty query returns every capability. Deprecated capabilities rank after
+// their replacements; removed ones are left out.
+func (pm *PluginManager) FindCapabilities(query string) []CapabilityMatch {
+	q := parseQuery(query)
+	extra := pm.Config().Search.Tags
+
+	var matches []CapabilityMatch
+	for _, p := range pm.ListPlugins() {
+		if p.Disabled || (len(q.plugins) > 0 && !containsString(q.plugins, strings.ToLower(p.Name))) {
+			continue
+		}
+		for _, c := range p.Capabilities {
+			if c.Removed {
+				continue
+			}
+			c.Tags = capabilityTags(c, p.Name, extra)
+			m, ok := matchCapability(c, q)
+			if !ok {
+				continue
+			}
+			m.Plugin, m.PluginVersion = p.Name, p.Version
+			if c.Deprecated != "" {
+				m.Score /= 2
+			}
+			matches = append(matches, m)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if deprecated := a.Capability.Deprecated != ""; deprecated != (b.Capability.Deprecated != "") {
+			return !deprecated
+		}
+		if a.Plugin != b.Plugin {
+			return a.Plugin < b.Plugin
+		}
+		return a.Capability.Ref() < b.Capability.Ref()
+	})
+	return matches
+}
+
+// capabilityTags returns the capability's tags with those search.tags adds
+func capabilityTags(c pluginsdk.Capability, plugin string, extra map[string][]string) []string {
+	tags := append([]string(nil), c.Tags...)
+	for _, key := range []string{c.Name, c.Ref(), plugin + "/" + c.Name, plugin + "/" + c.Ref()} {
+		for _, tag := range extra[key] {
+			if !containsString(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// matchCapability scores c against every word of q
+func matchCapability(c pluginsdk.Capability, q searchQuery) (CapabilityMatch, bool) {
+	m := CapabilityMatch{Capability: c}
+	tags := make([]string, len(c.Tags))
+	for i, tag := range c.Tags {
+		tags[i] = strings.ToLower(tag)
+	}
+	for _, tag := range q.tags {
+		if !containsString(tags, tag) {
+			return m, false
+		}
+	}
+
+	name := strings.ToLower(c.Name)
+	ref := strings.ToLower(c.Ref())
+	desc := strings.ToLower(c.Description)
+	for _, word := range q.words {
+		score, matched := 0, ""
+		better := func(s int, how string) {
+			if s > score {
+				score, matched = s, how
+			}
+		}
+		switch {
+		case word == name || word == ref:
+			better(scoreExactName, MatchName)
+		case strings.HasPrefix(name, word):
+			better(scorePrefixName, MatchName)
+		case strings.Contains(ref, word):
+			better(scoreInName, MatchName)
+		}
+		if containsString(tags, word) {
+			better(scoreTag, MatchTag)
+		}
+		if strings.Contains(desc, word) {
+			better(scoreInDesc, MatchDescription)
+		}
+		if score == 0 {
+			if isTypo(word, nameParts(name)) || isTypo(word, tags) {
+				better(scoreTypo, MatchFuzzy)
+			} else if len(word) >= 3 && isSubsequence(word, ref) {
+				better(scoreScattered, MatchFuzzy)
+			}
+		}
+		if score == 0 {
+			return m, false
+		}
+		m.Score += score
+		if !containsString(m.Matched, matched) {
+			m.Matched = append(m.Matched, matched)
+		}
+	}
+	return m, true
+}
+
+// nameParts returns the name with the words it is made of, e.g.
+// "greet.formal", "greet" and "formal"
+func nameParts(name string) []string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == '/'
+	})
+	return append(parts, name)
+}
+
+// isTypo reports whether word is within a few edits of one of candidates;
+// short words must match exactly, as too much would
+func isTypo(word string, candidates []string) bool {
+	allowed := 0
+	switch {
+	case len(word) >= 8:
+		allowed = 2
+	case len(word) >= 4:
+		allowed = 1
+	}
+	if allowed == 0 {
+		return false
+	}
+	for _, c := range candidates {
+		if editDistance(word, c) <= allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// isSubsequence reports whether the letters of word appear in s in order
+func isSubsequence(word, s string) bool {
+	rs := []rune(s)
+	i := 0
+	for _, r := range word {
+		for i < len(rs) && rs[i] != r {
+			i++
+		}
+		if i == len(rs) {
+			return false
+		}
+		i++
+	}
+	return true
+}
@@ -0,0 +1,320 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// secretScheme prefixes plugin config values the host replaces with a
+// secret, e.g. "secret://openai_api_key"
+const secretScheme = "secret://"
+
+// ErrSecretNotFound is returned by providers that do not hold a secret, so
+// the next provider is asked
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretProvider looks up secrets by name
+type SecretProvider interface {
+	Secret(name string) (string, error)
+}
+
+// Secret provider types
+const (
+	SecretsEnv      = "env"
+	SecretsFile     = "file"
+	SecretsKeychain = "keychain"
+	SecretsVault    = "vault"
+)
+
+// SecretsConfig lists where secrets referenced from plugin_config come
+// from. Providers are asked in order until one has the secret.
+type SecretsConfig struct {
+	Providers []SecretProviderConfig `json:"providers"`
+}
+
+// SecretProviderConfig configures one provider. Which fields apply depends
+// on Type.
+type SecretProviderConfig struct {
+	// Type is SecretsEnv, SecretsFile, SecretsKeychain or SecretsVault
+	Type string `json:"type"`
+
+	// Prefix is prepended to the upper-cased name for env, e.g.
+	// "OPENCODE_SECRET_" looks up openai_api_key as
+	// OPENCODE_SECRET_OPENAI_API_KEY
+	Prefix string `json:"prefix"`
+
+	// Dir holds one file per secret for file, as Docker and Kubernetes
+	// mount them
+	Dir string `json:"dir"`
+
+	// Service is the keychain service the secrets are stored under;
+	// defaults to "opencode"
+	Service string `json:"service"`
+
+	// Address of the Vault server; defaults to $VAULT_ADDR. The token is
+	// read from $VAULT_TOKEN so it never appears in the config.
+	Address string `json:"address"`
+
+	// Mount is the KV version 2 engine, defaults to "secret"; Path is the
+	// secret within it whose keys are the secret names
+	Mount string `json:"mount"`
+	Path  string `json:"path"`
+}
+
+func (c *SecretsConfig) validate() error {
+	for i, p := range c.Providers {
+		switch p.Type {
+		case SecretsEnv, SecretsKeychain:
+		case SecretsFile:
+			if p.Dir == "" {
+				return fmt.Errorf("secrets.providers[%d]: file provider needs a dir", i)
+			}
+		case SecretsVault:
+			if p.Path == "" {
+				return fmt.Errorf("secrets.providers[%d]: vault provider needs a path", i)
+			}
+		default:
+			return fmt.Errorf("secrets.providers[%d]: unknown type %q", i, p.Type)
+		}
+	}
+	return nil
+}
+
+// provider builds the provider the config describes
+func (c SecretProviderConfig) provider() SecretProvider {
+	switch c.Type {
+	case SecretsEnv:
+		return envSecrets{prefix: c.Prefix}
+	case SecretsFile:
+		return fileSecrets{dir: c.Dir}
+	case SecretsKeychain:
+		service := c.Service
+		if service == "" {
+			service = "opencode"
+		}
+		return keychainSecrets{service: service}
+	case SecretsVault:
+		addr := c.Address
+		if addr == "" {
+			addr = os.Getenv("VAULT_ADDR")
+		}
+		mount := c.Mount
+		if mount == "" {
+			mount = "secret"
+		}
+		return vaultSecrets{address: addr, mount: mount, path: c.Path}
+	}
+	return nil
+}
+
+// envSecrets reads secrets from environment variables
+type envSecrets struct {
+	prefix string
+}
+
+func (p envSecrets) Secret(name string) (string, error) {
+	if v, ok := os.LookupEnv(p.prefix + strings.ToUpper(name)); ok {
+		return v, nil
+	}
+	return "", ErrSecretNotFound
+}
+
+// fileSecrets reads each secret from a file named after it
+type fileSecrets struct {
+	dir string
+}
+
+func (p fileSecrets) Secret(name string) (string, error) {
+	if name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if os.IsNotExist(err) {
+		return "", ErrSecretNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// keychainSecrets reads secrets from the OS keychain through its command
+// line tool: security on macOS, secret-tool (libsecret) on Linux
+type keychainSecrets struct {
+	service string
+}
+
+func (p keychainSecrets) Secret(name string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", p.service, "-a", name, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", p.service, "account", name)
+	default:
+		return "", fmt.Errorf("keychain secrets are not supported on %s", runtime.GOOS)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", ErrSecretNotFound
+		}
+		return "", fmt.Errorf("failed to query keychain: %w", err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+// vaultSecrets reads secrets from the keys of one HashiCorp Vault KV
+// version 2 secret
+type vaultSecrets struct {
+	address string
+	mount   string
+	path    string
+}
+
+var vaultClient = &http.Client{Timeout: 10 * time.Second}
+
+func (p vaultSecrets) Secret(name string) (string, error) {
+	if p.address == "" {
+		return "", fmt.Errorf("vault address not configured")
+	}
+	token := os.Getenv("VAULT_TOKEN")
+	if token == "" {
+		return "", fmt.Errorf("VAULT_TOKEN is not set")
+	}
+
+	u, err := url.JoinPath(p.address, "v1", p.mount, "data", p.path)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := vaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrSecretNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to parse vault response: %w", err)
+	}
+	v, ok := body.Data.Data[name].(string)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return v, nil
+}
+
+// secret looks a secret up in the configured providers, then in the ones
+// added with WithSecretProvider
+func (pm *PluginManager) secret(name string) (string, error) {
+	var providers []SecretProvider
+	for _, c := range pm.Config().Secrets.Providers {
+		providers = append(providers, c.provider())
+	}
+	providers = append(providers, pm.secretProviders...)
+
+	for _, p := range providers {
+		v, err := p.Secret(name)
+		if err == nil {
+			pm.revealed.Store(v, true)
+			return v, nil
+		}
+		if !errors.Is(err, ErrSecretNotFound) {
+			return "", fmt.Errorf("secret %s: %w", name, err)
+		}
+	}
+	return "", fmt.Errorf("secret %s: %w", name, ErrSecretNotFound)
+}
+
+// ResolveSecret returns the secret a "secret://name" value refers to, or
+// the value itself when it is no reference. Applications use it for their
+// own settings that may hold secrets, such as API keys.
+func (pm *PluginManager) ResolveSecret(value string) (string, error) {
+	if name, ok := strings.CutPrefix(value, secretScheme); ok {
+		return pm.secret(name)
+	}
+	return value, nil
+}
+
+// resolveSecrets returns a copy of v with every "secret://name" string
+// replaced by the secret. Errors name the secret but never include values.
+func (pm *PluginManager) resolveSecrets(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		if name, ok := strings.CutPrefix(v, secretScheme); ok {
+			return pm.secret(name)
+		}
+		return v, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			resolved, err := pm.resolveSecrets(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := pm.resolveSecrets(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// initialize passes a just-started plugin its plugin_config entry. Plugins
+// without an entry, or that take no configuration, are left alone.
+func (pm *PluginManager) initialize(name string, instance pluginsdk.CommandPlugin) error {
+	config, ok := pm.Config().PluginConfig[name]
+	if !ok {
+		return nil
+	}
+	p, ok := instance.(pluginsdk.InitializablePlugin)
+	if !ok {
+		return nil
+	}
+
+	resolved, err := pm.resolveSecrets(config)
+	if err != nil {
+		return err
+	}
+	return p.Initialize(resolved.(map[string]interface{}))
+}
@@ -0,0 +1,279 @@
+package pluginhost
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultSessionIdleTimeout is how long a session may sit unused before the
+// host closes it
+const DefaultSessionIdleTimeout = 10 * time.Minute
+
+// session tracks one open plugin session
+type session struct {
+	plugin   string
+	impl     pluginsdk.PluginSession
+	lastUsed time.Time
+	mu       sync.Mutex
+}
+
+// sessionRegistry holds the open sessions of a PluginManager
+type sessionRegistry struct {
+	sessions    map[string]*session
+	idleTimeout time.Duration
+	stop        chan struct{}
+	logger      *log.Logger
+	mu          sync.Mutex
+}
+
+func newSessionRegistry(logger *log.Logger) *sessionRegistry {
+	return &sessionRegistry{
+		sessions:    make(map[string]*session),
+		idleTimeout: DefaultSessionIdleTimeout,
+		logger:      logger,
+	}
+}
+
+// newSessionID returns a random, unguessable session identifier
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// OpenSession starts a stateful session with a plugin and returns its ID.
+// The session stays bound to the plugin process it was opened on. Opening
+// it passes the checks a call to the plugin passes.
+func (pm *PluginManager) OpenSession(name string) (string, error) {
+	if err := pm.admit(name, nil); err != nil {
+		return "", err
+	}
+	if _, _, err := pm.checkSession(name, nil); err != nil {
+		return "", err
+	}
+	if err := pm.ensureRunning(name); err != nil {
+		return "", err
+	}
+
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	var instance pluginsdk.CommandPlugin
+	if exists {
+		instance = info.Instance
+	}
+	pm.mu.RUnlock()
+
+	if !exists {
+		return "", pm.pluginNotFound(name)
+	}
+
+	opener, ok := instance.(pluginsdk.SessionPlugin)
+	if !ok {
+		return "", pluginsdk.ErrSessionsUnsupported
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	impl, err := opener.OpenSession(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to open session: %w", err)
+	}
+
+	pm.sessions.mu.Lock()
+	pm.sessions.sessions[id] = &session{plugin: name, impl: impl, lastUsed: time.Now()}
+	if pm.sessions.stop == nil {
+		pm.sessions.stop = make(chan struct{})
+		go pm.sessions.reap(pm.sessions.stop)
+	}
+	pm.sessions.mu.Unlock()
+
+	pm.hostLog.Printf("Opened session %s on plugin: %s", id, name)
+	return id, nil
+}
+
+// ExecuteInSession runs a call within an open session. Each call passes the
+// checks of a call to the plugin and is bounded by its timeout.
+func (pm *PluginManager) ExecuteInSession(id string, args map[string]interface{}) (string, error) {
+	pm.sessions.mu.Lock()
+	s, exists := pm.sessions.sessions[id]
+	pm.sessions.mu.Unlock()
+
+	if !exists {
+		return "", fmt.Errorf("session not found: %s", id)
+	}
+	args = withoutReserved(args)
+	if err := pm.admit(s.plugin, args); err != nil {
+		return "", err
+	}
+	timeout, args, err := pm.checkSession(s.plugin, args)
+	if err != nil {
+		return "", err
+	}
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	// The session's lock is held until the plugin answers, even when the
+	// caller stopped waiting, so calls within it never overlap
+	done := make(chan outcome, 1)
+	s.mu.Lock()
+	s.lastUsed = time.Now()
+	go func() {
+		defer s.mu.Unlock()
+		result, err := s.impl.Execute(args)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil {
+			return "", fmt.Errorf("plugin execution failed: %w", o.err)
+		}
+		return o.result, nil
+	case <-time.After(timeout):
+		pm.hostLog.Printf("Call in session %s on %s timed out after %v", id, s.plugin, timeout)
+		return "", fmt.Errorf("plugin execution failed: %w", errTimeout(s.plugin, timeout))
+	}
+}
+
+// checkSession runs the checks executeResult runs before a call to plugin
+// name, for a session being opened or a call within one, and returns the
+// call's timeout with args minus the timeout argument and scoped by
+// call_env
+func (pm *PluginManager) checkSession(name string, args map[string]interface{}) (time.Duration, map[string]interface{}, error) {
+	if err := pm.checkHalt(); err != nil {
+		return 0, nil, err
+	}
+	if err := pm.authorize(name, args); err != nil {
+		return 0, nil, err
+	}
+	if err := pm.checkFlags(name, args); err != nil {
+		return 0, nil, err
+	}
+
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	info, exists := pm.plugins[name]
+	if !exists {
+		return 0, nil, pm.errPluginNotFound(name)
+	}
+	if pm.disabled[name] {
+		return 0, nil, &LookupError{Kind: ErrPluginDisabled, Plugin: name}
+	}
+	if pm.config.isDenied(name) {
+		return 0, nil, fmt.Errorf("plugin denied by policy: %s", name)
+	}
+	args, err := pm.config.CallEnv.scopeCall(args)
+	if err != nil {
+		return 0, nil, err
+	}
+	return pm.callTimeout(info, args)
+}
+
+// CloseSession ends a session and releases its plugin-side state
+func (pm *PluginManager) CloseSession(id string) error {
+	pm.sessions.mu.Lock()
+	s, exists := pm.sessions.sessions[id]
+	delete(pm.sessions.sessions, id)
+	pm.sessions.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("session not found: %s", id)
+	}
+
+	pm.hostLog.Printf("Closed session %s on plugin: %s", id, s.plugin)
+	return s.impl.Close()
+}
+
+// closePluginSessions ends every session bound to a plugin, used when its
+// process goes away
+func (r *sessionRegistry) closePluginSessions(plugin string) {
+	r.mu.Lock()
+	var closing []*session
+	for id, s := range r.sessions {
+		if s.plugin == plugin {
+			closing = append(closing, s)
+			delete(r.sessions, id)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, s := range closing {
+		s.impl.Close()
+	}
+}
+
+// hasSessions reports whether a plugin has open sessions
+func (r *sessionRegistry) hasSessions(plugin string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.sessions {
+		if s.plugin == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// closeAll ends every session and stops the idle reaper
+func (r *sessionRegistry) closeAll() {
+	r.mu.Lock()
+	closing := r.sessions
+	r.sessions = make(map[string]*session)
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.mu.Unlock()
+
+	for _, s := range closing {
+		s.impl.Close()
+	}
+}
+
+// reap periodically closes sessions that have been idle too long
+func (r *sessionRegistry) reap(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			r.mu.Lock()
+			var idle []*session
+			for id, s := range r.sessions {
+				// TryLock skips sessions with a call in flight; they are not idle
+				if !s.mu.TryLock() {
+					continue
+				}
+				if now.Sub(s.lastUsed) > r.idleTimeout {
+					idle = append(idle, s)
+					delete(r.sessions, id)
+					r.logger.Printf("Closing idle session %s on plugin: %s", id, s.plugin)
+				}
+				s.mu.Unlock()
+			}
+			r.mu.Unlock()
+
+			for _, s := range idle {
+				s.impl.Close()
+			}
+		}
+	}
+}
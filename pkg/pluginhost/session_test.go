@@ -0,0 +1,87 @@
+package pluginhost
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// sessionPlugin is a built-in plugin whose sessions answer after delay
+type sessionPlugin struct {
+	delay time.Duration
+}
+
+func (p *sessionPlugin) Name() string                                        { return "sessions" }
+func (p *sessionPlugin) Version() string                                     { return "1.0.0" }
+func (p *sessionPlugin) Execute(args map[string]interface{}) (string, error) { return "call", nil }
+func (p *sessionPlugin) GetCapabilities() []pluginsdk.Capability {
+	return []pluginsdk.Capability{{Name: "echo"}}
+}
+func (p *sessionPlugin) OpenSession(id string) (pluginsdk.PluginSession, error) {
+	return &echoSession{p.delay}, nil
+}
+
+type echoSession struct {
+	delay time.Duration
+}
+
+func (s *echoSession) Execute(args map[string]interface{}) (string, error) {
+	time.Sleep(s.delay)
+	return "session", nil
+}
+
+func (s *echoSession) Close() error { return nil }
+
+func TestSessionsPassCallChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(pm *PluginManager)
+		wantErr bool
+	}{
+		{"allowed", func(pm *PluginManager) {}, false},
+		{"disabled", func(pm *PluginManager) { pm.Disable("sessions") }, true},
+		{"denied", func(pm *PluginManager) { pm.config.Policies.DeniedPlugins = []string{"sessions"} }, true},
+		{"halted", func(pm *PluginManager) { pm.Halt("test", false) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, err := New(WithBuiltin(&sessionPlugin{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pm.Shutdown()
+			id, err := pm.OpenSession("sessions")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.setup(pm)
+			if _, err := pm.ExecuteInSession(id, map[string]interface{}{}); (err != nil) != tt.wantErr {
+				t.Errorf("ExecuteInSession error = %v, want error %v", err, tt.wantErr)
+			}
+			if _, err := pm.OpenSession("sessions"); (err != nil) != tt.wantErr {
+				t.Errorf("OpenSession error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSessionCallTimesOut(t *testing.T) {
+	pm, err := New(WithBuiltin(&sessionPlugin{delay: time.Second}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+	id, err := pm.OpenSession("sessions")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = pm.ExecuteInSession(id, map[string]interface{}{pluginsdk.ArgTimeout: "50ms"})
+	var pe *pluginsdk.PluginError
+	if !errors.As(err, &pe) || pe.Code != pluginsdk.CodeTimeout {
+		t.Errorf("error = %v, want a timeout", err)
+	}
+}
@@ -0,0 +1,375 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of an Objective that leaves them unset
+const (
+	DefaultSLOWindow   = 5 * time.Minute
+	DefaultSLOMinCalls = 20
+)
+
+// maxSLOSamples bounds the calls kept per capability, so a busy capability
+// with a long window does not grow without limit; the oldest go first
+const maxSLOSamples = 10000
+
+// sloWebhookTimeout bounds one delivery of an alert to a webhook
+const sloWebhookTimeout = 10 * time.Second
+
+// Indicators an Objective sets thresholds for
+const (
+	IndicatorP95       = "p95"
+	IndicatorErrorRate = "error_rate"
+)
+
+// Event types published when an objective is violated or met again
+const (
+	EventSLOViolated = "slo.violated"
+	EventSLOResolved = "slo.resolved"
+)
+
+// SLOConfig sets service level objectives for capabilities. They are
+// evaluated over a sliding window of each plugin's calls as the calls
+// finish; a change between met and violated alerts Hooks.OnSLOAlert, the
+// event bus and the webhooks.
+type SLOConfig struct {
+	// Window is how far back calls count; defaults to DefaultSLOWindow
+	Window Duration `json:"window"`
+
+	// Objectives by capability name, e.g. "greet"
+	Objectives map[string]Objective `json:"objectives"`
+
+	// Webhooks receive each SLOAlert as a JSON POST
+	Webhooks []string `json:"webhooks"`
+}
+
+// Objective is what a capability's calls must meet over the window. A
+// zero threshold sets none.
+type Objective struct {
+	// P95 is the latency 95% of calls must finish within, retries
+	// included
+	P95 Duration `json:"p95"`
+
+	// ErrorRate is the highest share of calls that may fail, from 0 to 1
+	ErrorRate float64 `json:"error_rate"`
+
+	// Window overrides slo.window for the capability
+	Window Duration `json:"window"`
+
+	// MinCalls is how many calls the window needs before the objective is
+	// judged; defaults to DefaultSLOMinCalls
+	MinCalls int `json:"min_calls"`
+}
+
+func (c *SLOConfig) validate() error {
+	if c.Window < 0 {
+		return fmt.Errorf("slo.window must not be negative")
+	}
+	for name, o := range c.Objectives {
+		if o.P95 < 0 || o.Window < 0 || o.MinCalls < 0 {
+			return fmt.Errorf("slo.objectives.%s: p95, window and min_calls must not be negative", name)
+		}
+		if o.ErrorRate < 0 || o.ErrorRate > 1 {
+			return fmt.Errorf("slo.objectives.%s: error_rate must be between 0 and 1", name)
+		}
+		if o.P95 == 0 && o.ErrorRate == 0 {
+			return fmt.Errorf("slo.objectives.%s sets neither p95 nor error_rate", name)
+		}
+	}
+	for _, u := range c.Webhooks {
+		if u == "" {
+			return fmt.Errorf("slo.webhooks: empty URL")
+		}
+	}
+	return nil
+}
+
+func (c *SLOConfig) window(o Objective) time.Duration {
+	switch {
+	case o.Window > 0:
+		return time.Duration(o.Window)
+	case c.Window > 0:
+		return time.Duration(c.Window)
+	}
+	return DefaultSLOWindow
+}
+
+func (o Objective) minCalls() int {
+	if o.MinCalls > 0 {
+		return o.MinCalls
+	}
+	return DefaultSLOMinCalls
+}
+
+// SLOStatus is how a plugin's capability is doing against its objective
+type SLOStatus struct {
+	Plugin     string    `json:"plugin"`
+	Capability string    `json:"capability"`
+	Objective  Objective `json:"objective"`
+
+	// Calls is how many calls the window holds
+	Calls int `json:"calls"`
+
+	// P95 and ErrorRate are measured over the window
+	P95       Duration `json:"p95"`
+	ErrorRate float64  `json:"error_rate"`
+
+	// Violated lists the indicators out of objective, IndicatorP95 or
+	// IndicatorErrorRate
+	Violated []string `json:"violated,omitempty"`
+}
+
+// SLOAlert reports an indicator that started or stopped violating its
+// objective
+type SLOAlert struct {
+	Plugin     string `json:"plugin"`
+	Capability string `json:"capability"`
+	Indicator  string `json:"indicator"`
+
+	// Threshold and Observed are in seconds for IndicatorP95 and a share
+	// of calls for IndicatorErrorRate
+	Threshold float64 `json:"threshold"`
+	Observed  float64 `json:"observed"`
+
+	// Calls is how many calls the window held
+	Calls  int      `json:"calls"`
+	Window Duration `json:"window"`
+
+	// Resolved is set when the indicator meets its objective again
+	Resolved bool      `json:"resolved"`
+	Time     time.Time `json:"time"`
+}
+
+// sloKey identifies the calls of one capability of one plugin
+type sloKey struct {
+	plugin, capability string
+}
+
+// sloSample is one finished call
+type sloSample struct {
+	at       time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// sloSeries is the window of calls of a capability and which indicators
+// are violated
+type sloSeries struct {
+	samples  []sloSample
+	violated map[string]bool
+}
+
+// sloTracker keeps the windows of all capabilities that have objectives
+type sloTracker struct {
+	mu     sync.Mutex
+	series map[sloKey]*sloSeries
+}
+
+func newSLOTracker() *sloTracker {
+	return &sloTracker{series: make(map[sloKey]*sloSeries)}
+}
+
+// prune drops the calls older than the window
+func (s *sloSeries) prune(window time.Duration, now time.Time) {
+	i := 0
+	for i < len(s.samples) && now.Sub(s.samples[i].at) > window {
+		i++
+	}
+	s.samples = s.samples[i:]
+}
+
+// measure returns the p95 latency and the error rate of the window
+func (s *sloSeries) measure() (time.Duration, float64) {
+	if len(s.samples) == 0 {
+		return 0, 0
+	}
+	durations := make([]time.Duration, len(s.samples))
+	failed := 0
+	for i, sm := range s.samples {
+		durations[i] = sm.duration
+		if sm.failed {
+			failed++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	rank := int(math.Ceil(0.95*float64(len(durations)))) - 1
+	return durations[rank], float64(failed) / float64(len(durations))
+}
+
+// observe adds a finished call and returns the alerts for indicators that
+// changed between met and violated. Windows with fewer than min_calls
+// calls keep their state.
+func (t *sloTracker) observe(cfg *SLOConfig, e Execution, now time.Time) []SLOAlert {
+	o, ok := cfg.Objectives[e.Capability]
+	if !ok {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := sloKey{e.Plugin, e.Capability}
+	s := t.series[key]
+	if s == nil {
+		s = &sloSeries{violated: make(map[string]bool)}
+		t.series[key] = s
+	}
+	window := cfg.window(o)
+	s.samples = append(s.samples, sloSample{at: now, duration: e.Duration, failed: e.Err != nil})
+	if len(s.samples) > maxSLOSamples {
+		s.samples = s.samples[len(s.samples)-maxSLOSamples:]
+	}
+	s.prune(window, now)
+	if len(s.samples) < o.minCalls() {
+		return nil
+	}
+
+	p95, rate := s.measure()
+	var alerts []SLOAlert
+	check := func(indicator string, threshold, observed float64) {
+		violated := observed > threshold
+		if violated == s.violated[indicator] {
+			return
+		}
+		s.violated[indicator] = violated
+		alerts = append(alerts, SLOAlert{
+			Plugin:     e.Plugin,
+			Capability: e.Capability,
+			Indicator:  indicator,
+			Threshold:  threshold,
+			Observed:   observed,
+			Calls:      len(s.samples),
+			Window:     Duration(window),
+			Resolved:   !violated,
+			Time:       now,
+		})
+	}
+	if o.P95 > 0 {
+		check(IndicatorP95, time.Duration(o.P95).Seconds(), p95.Seconds())
+	}
+	if o.ErrorRate > 0 {
+		check(IndicatorErrorRate, o.ErrorRate, rate)
+	}
+	return alerts
+}
+
+// statuses reports the capabilities with objectives of plugin, or of all
+// plugins when plugin is empty
+func (t *sloTracker) statuses(cfg *SLOConfig, plugin string, now time.Time) []SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []SLOStatus
+	for key, s := range t.series {
+		o, ok := cfg.Objectives[key.capability]
+		if !ok || (plugin != "" && key.plugin != plugin) {
+			continue
+		}
+		s.prune(cfg.window(o), now)
+		p95, rate := s.measure()
+		st := SLOStatus{
+			Plugin:     key.plugin,
+			Capability: key.capability,
+			Objective:  o,
+			Calls:      len(s.samples),
+			P95:        Duration(p95),
+			ErrorRate:  rate,
+		}
+		for _, indicator := range []string{IndicatorP95, IndicatorErrorRate} {
+			if s.violated[indicator] {
+				st.Violated = append(st.Violated, indicator)
+			}
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Plugin != out[j].Plugin {
+			return out[i].Plugin < out[j].Plugin
+		}
+		return out[i].Capability < out[j].Capability
+	})
+	return out
+}
+
+// forget drops the windows of an unloaded plugin
+func (t *sloTracker) forget(plugin string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key := range t.series {
+		if key.plugin == plugin {
+			delete(t.series, key)
+		}
+	}
+}
+
+// SLOs reports every capability with an objective that has been called,
+// against its objective
+func (pm *PluginManager) SLOs() []SLOStatus {
+	return pm.slos.statuses(&pm.Config().SLO, "", time.Now())
+}
+
+// observeSLO adds a finished call to its capability's window and sends the
+// alerts it causes
+func (pm *PluginManager) observeSLO(e Execution) {
+	cfg := &pm.Config().SLO
+	if len(cfg.Objectives) == 0 {
+		return
+	}
+	for _, a := range pm.slos.observe(cfg, e, time.Now()) {
+		pm.alertSLO(cfg, a)
+	}
+}
+
+// alertSLO logs an alert, publishes it, runs Hooks.OnSLOAlert and posts it
+// to the webhooks in the background
+func (pm *PluginManager) alertSLO(cfg *SLOConfig, a SLOAlert) {
+	event := EventSLOViolated
+	if a.Resolved {
+		event = EventSLOResolved
+		pm.hostLog.Printf("SLO of %s/%s met again: %s %.3g within %.3g over %d calls", a.Plugin, a.Capability, a.Indicator, a.Observed, a.Threshold, a.Calls)
+	} else {
+		pm.hostLog.Printf("SLO of %s/%s violated: %s %.3g exceeds %.3g over %d calls", a.Plugin, a.Capability, a.Indicator, a.Observed, a.Threshold, a.Calls)
+	}
+	pm.events.Publish(Event{Type: event, Plugin: a.Plugin, Data: map[string]interface{}{
+		"capability": a.Capability,
+		"indicator":  a.Indicator,
+		"threshold":  a.Threshold,
+		"observed":   a.Observed,
+		"calls":      a.Calls,
+	}})
+	if pm.hooks.OnSLOAlert != nil {
+		pm.hooks.OnSLOAlert(a)
+	}
+	if len(cfg.Webhooks) > 0 {
+		go pm.postSLOAlert(cfg.Webhooks, a)
+	}
+}
+
+// postSLOAlert delivers an alert to each webhook, logging failures
+func (pm *PluginManager) postSLOAlert(webhooks []string, a SLOAlert) {
+	body, err := json.Marshal(a)
+	if err != nil {
+		pm.hostLog.Printf("Failed to encode SLO alert: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: sloWebhookTimeout}
+	for _, u := range webhooks {
+		resp, err := client.Post(u, "application/json", bytes.NewReader(body))
+		if err != nil {
+			pm.hostLog.Printf("Failed to post SLO alert to %s: %v", u, err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			pm.hostLog.Printf("Failed to post SLO alert to %s: %s", u, resp.Status)
+		}
+	}
+}
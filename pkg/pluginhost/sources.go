@@ -0,0 +1,613 @@
+package pluginhost
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Types of built-in plugin sources
+const (
+	// SourceDir is a local directory of plugin binaries, like plugin_dirs
+	SourceDir = "dir"
+
+	// SourceGit is a git repository holding a registry index
+	SourceGit = "git"
+
+	// SourceHTTP is a registry index served over http(s)
+	SourceHTTP = "http"
+
+	// SourceConfigMap is a Kubernetes ConfigMap holding a registry index
+	SourceConfigMap = "configmap"
+)
+
+// Trust levels of a source
+const (
+	// TrustFull starts the source's plugins like those of plugin_dirs
+	TrustFull = "full"
+
+	// TrustVerified only starts plugins whose provenance attests the
+	// binary, from a builder supply_chain.trusted_builders lists, whatever
+	// supply_chain.mode says
+	TrustVerified = "verified"
+
+	// TrustSandboxed is TrustVerified, and the plugins must run confined by
+	// the sandbox
+	TrustSandboxed = "sandboxed"
+)
+
+// DefaultSourceCache receives the plugins of sources that are not local
+// directories when discovery.cache_dir is not set
+const DefaultSourceCache = "./sources"
+
+// serviceAccountDir is where Kubernetes mounts the pod's API credentials
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Source is an origin of plugins. Sync brings its plugins into dir, a
+// directory of its own under discovery.cache_dir, as binaries with their
+// manifests next to them, and returns the directory to scan for them;
+// sources that are local directories return their own.
+type Source interface {
+	Sync(ctx context.Context, dir string) (string, error)
+}
+
+// SourceFactory creates the Source an entry of discovery.sources
+// describes; see WithSourceType
+type SourceFactory func(SourceConfig) (Source, error)
+
+// DiscoveryConfig adds sources of plugins to the plugin directories
+type DiscoveryConfig struct {
+	// Sources are synced when they are added or changed, in order
+	Sources []SourceConfig `json:"sources"`
+
+	// CacheDir holds a directory per source that is not a local directory;
+	// defaults to DefaultSourceCache. Plugins synced before stay usable
+	// while their source cannot be reached.
+	CacheDir string `json:"cache_dir"`
+
+	// Timeout bounds syncing each source; defaults to
+	// DefaultDownloadTimeout
+	Timeout Duration `json:"timeout"`
+}
+
+// SourceConfig describes one source of plugins
+type SourceConfig struct {
+	// Name identifies the source in logs and plugin status
+	Name string `json:"name"`
+
+	// Type is SourceDir, SourceGit, SourceHTTP, SourceConfigMap or one
+	// added with WithSourceType
+	Type string `json:"type"`
+
+	// Trust is TrustFull, TrustVerified or TrustSandboxed; defaults to
+	// TrustFull for local directories and TrustVerified otherwise
+	Trust string `json:"trust"`
+
+	// Path is the directory of a dir source, or the index file in a git
+	// repository, "index.json" by default
+	Path string `json:"path"`
+
+	// URL is the repository of a git source, the index of an http source,
+	// or the API server of a configmap source, which defaults to the
+	// cluster the host runs in
+	URL string `json:"url"`
+
+	// Ref is the branch or tag of a git source; defaults to the remote's
+	// default branch
+	Ref string `json:"ref"`
+
+	// Namespace, ConfigMap and Key locate the index of a configmap
+	// source: the key, "index.json" by default, of the ConfigMap in the
+	// namespace, by default the host's own
+	Namespace string `json:"namespace"`
+	ConfigMap string `json:"configmap"`
+	Key       string `json:"key"`
+}
+
+func (c *DiscoveryConfig) validate() error {
+	if c.Timeout < 0 {
+		return fmt.Errorf("discovery.timeout must not be negative")
+	}
+	names := make(map[string]bool)
+	for i, s := range c.Sources {
+		field := fmt.Sprintf("discovery.sources[%d]", i)
+		if err := checkPluginName(s.Name); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("%s: source %q is listed twice", field, s.Name)
+		}
+		names[s.Name] = true
+		switch s.Trust {
+		case "", TrustFull, TrustVerified, TrustSandboxed:
+		default:
+			return fmt.Errorf("%s.trust must be %q, %q or %q, got %q", field, TrustFull, TrustVerified, TrustSandboxed, s.Trust)
+		}
+		switch s.Type {
+		case SourceDir:
+			if s.Path == "" {
+				return fmt.Errorf("%s: dir sources need a path", field)
+			}
+		case SourceGit, SourceHTTP:
+			if s.URL == "" {
+				return fmt.Errorf("%s: %s sources need a url", field, s.Type)
+			}
+		case SourceConfigMap:
+			if s.ConfigMap == "" {
+				return fmt.Errorf("%s: configmap sources need a configmap", field)
+			}
+		case "":
+			return fmt.Errorf("%s has no type", field)
+		}
+	}
+	return nil
+}
+
+func (c *DiscoveryConfig) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout)
+	}
+	return DefaultDownloadTimeout
+}
+
+func (c *DiscoveryConfig) cacheDir() string {
+	if c.CacheDir != "" {
+		return c.CacheDir
+	}
+	return DefaultSourceCache
+}
+
+// trust returns the trust level of the source with its default applied
+func (s SourceConfig) trust() string {
+	switch {
+	case s.Trust != "":
+		return s.Trust
+	case s.Type == SourceDir:
+		return TrustFull
+	}
+	return TrustVerified
+}
+
+// sourceState is a configured source and the directory its plugins are in
+type sourceState struct {
+	config SourceConfig
+	dir    string
+}
+
+// checkSources reports sources of types that are neither built in nor
+// added with WithSourceType
+func (pm *PluginManager) checkSources(cfg DiscoveryConfig) error {
+	for _, s := range cfg.Sources {
+		if _, err := pm.openSource(s); err != nil {
+			return fmt.Errorf("discovery.sources: %s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// openSource creates the Source a config entry describes
+func (pm *PluginManager) openSource(s SourceConfig) (Source, error) {
+	if factory, ok := pm.sourceTypes[s.Type]; ok {
+		return factory(s)
+	}
+	switch s.Type {
+	case SourceDir:
+		return dirSource(s.Path), nil
+	case SourceGit:
+		return &gitSource{url: s.URL, ref: s.Ref, index: s.Path, artifacts: pm.artifacts()}, nil
+	case SourceHTTP:
+		return &httpSource{index: s.URL, artifacts: pm.artifacts()}, nil
+	case SourceConfigMap:
+		return &configMapSource{api: s.URL, namespace: s.Namespace, name: s.ConfigMap, key: s.Key, artifacts: pm.artifacts()}, nil
+	}
+	return nil, fmt.Errorf("unknown source type %q", s.Type)
+}
+
+// applySources syncs the sources that were added or changed and forgets
+// those that were removed, whose directories ApplyConfig then unloads.
+// Sources whose directory was discovered before are discovered again, and
+// plugins their sync removed are unloaded.
+func (pm *PluginManager) applySources(cfg *HostConfig) {
+	pm.mu.RLock()
+	prev := pm.sources
+	pm.mu.RUnlock()
+
+	next := make(map[string]*sourceState, len(cfg.Discovery.Sources))
+	var synced []*sourceState
+	for _, s := range cfg.Discovery.Sources {
+		if old, ok := prev[s.Name]; ok && reflect.DeepEqual(old.config, s) {
+			next[s.Name] = old
+			continue
+		}
+		state := &sourceState{config: s, dir: filepath.Clean(filepath.Join(cfg.Discovery.cacheDir(), s.Name))}
+		dir, err := pm.syncSource(cfg.Discovery, s, state.dir)
+		if err != nil {
+			pm.hostLog.Printf("Failed to sync plugin source %s, using the plugins synced before: %v", s.Name, err)
+		} else {
+			state.dir = filepath.Clean(dir)
+		}
+		next[s.Name] = state
+		synced = append(synced, state)
+	}
+
+	pm.mu.Lock()
+	pm.sources = next
+	var rediscover []string
+	for _, state := range synced {
+		if pm.dirs[state.dir] {
+			rediscover = append(rediscover, state.dir)
+		}
+	}
+	pm.mu.Unlock()
+
+	for _, dir := range rediscover {
+		pm.unloadMissing(dir)
+		if err := pm.DiscoverPlugins(dir); err != nil {
+			pm.hostLog.Printf("Failed to discover plugins in %s: %v", dir, err)
+		}
+	}
+}
+
+// syncSource brings the plugins of a source into dir
+func (pm *PluginManager) syncSource(cfg DiscoveryConfig, s SourceConfig, dir string) (string, error) {
+	src, err := pm.openSource(s)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+	defer cancel()
+
+	pm.hostLog.Printf("Syncing plugin source %s (%s, trust: %s)", s.Name, s.Type, s.trust())
+	return src.Sync(ctx, dir)
+}
+
+// sourceDirs returns the directories of the sources in use
+func (pm *PluginManager) sourceDirs() []string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var dirs []string
+	for _, state := range pm.sources {
+		dirs = append(dirs, state.dir)
+	}
+	return dirs
+}
+
+// sourceOf returns the source the binary at path came from; nil for
+// binaries in plugin_dirs and elsewhere. The caller holds pm.mu.
+func (pm *PluginManager) sourceOf(path string) *sourceState {
+	dir := filepath.Dir(filepath.Clean(path))
+	for _, state := range pm.sources {
+		if state.dir == dir {
+			return state
+		}
+	}
+	return nil
+}
+
+// trustOf returns the trust level of the binary at path
+func (pm *PluginManager) trustOf(path string) string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	if state := pm.sourceOf(path); state != nil {
+		return state.config.trust()
+	}
+	return TrustFull
+}
+
+// sourceStatus fills in the source of a plugin; the caller holds pm.mu
+func (pm *PluginManager) sourceStatus(info *pluginInfo, st *PluginStatus) {
+	if info.builtin {
+		return
+	}
+	if state := pm.sourceOf(info.Path); state != nil {
+		st.Source, st.Trust = state.config.Name, state.config.trust()
+	}
+}
+
+// unloadMissing unloads the plugins in dir whose binary is gone
+func (pm *PluginManager) unloadMissing(dir string) {
+	pm.mu.RLock()
+	var names []string
+	for name, info := range pm.plugins {
+		if info.builtin || filepath.Dir(filepath.Clean(info.Path)) != dir {
+			continue
+		}
+		if _, err := os.Stat(info.Path); errors.Is(err, os.ErrNotExist) {
+			names = append(names, name)
+		}
+	}
+	pm.mu.RUnlock()
+
+	for _, name := range names {
+		pm.hostLog.Printf("Plugin %s was removed from its source", name)
+		if err := pm.UnloadPlugin(name); err != nil {
+			pm.hostLog.Printf("Failed to unload plugin %s: %v", name, err)
+		}
+	}
+}
+
+// dirSource is a local directory of plugins
+type dirSource string
+
+func (d dirSource) Sync(ctx context.Context, dir string) (string, error) {
+	return string(d), nil
+}
+
+// httpSource is a registry index served over http(s)
+type httpSource struct {
+	index     string
+	artifacts *ArtifactCache
+}
+
+func (s *httpSource) Sync(ctx context.Context, dir string) (string, error) {
+	client := contextClient(ctx)
+	index, err := readIndex(client, s.index)
+	if err != nil {
+		return "", fmt.Errorf("failed to read index: %w", err)
+	}
+	return dir, syncIndex(client, s.artifacts, index, s.index, dir)
+}
+
+// gitSource is a git repository holding a registry index. It is checked
+// out into a hidden directory next to the plugins, so binaries the index
+// refers to may live in the repository itself.
+type gitSource struct {
+	url       string
+	ref       string
+	index     string
+	artifacts *ArtifactCache
+}
+
+func (s *gitSource) Sync(ctx context.Context, dir string) (string, error) {
+	repo := filepath.Join(dir, ".repo")
+	if err := os.MkdirAll(repo, 0755); err != nil {
+		return "", err
+	}
+	git := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+
+	// Local repositories are named relative to the host, not the checkout
+	remote := s.url
+	if _, err := os.Stat(remote); err == nil {
+		if remote, err = filepath.Abs(remote); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git")); err != nil {
+		if err := git("init", "-q"); err != nil {
+			return "", err
+		}
+		if err := git("remote", "add", "origin", remote); err != nil {
+			return "", err
+		}
+	} else if err := git("remote", "set-url", "origin", remote); err != nil {
+		return "", err
+	}
+	ref := s.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := git("fetch", "-q", "--depth", "1", "origin", ref); err != nil {
+		return "", err
+	}
+	if err := git("checkout", "-q", "--force", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+
+	path := s.index
+	if path == "" {
+		path = "index.json"
+	}
+	location := filepath.Join(repo, filepath.FromSlash(path))
+	if !strings.HasPrefix(location, repo+string(filepath.Separator)) {
+		return "", fmt.Errorf("index %s is outside the repository", path)
+	}
+	index, err := readIndex(contextClient(ctx), location)
+	if err != nil {
+		return "", fmt.Errorf("failed to read index: %w", err)
+	}
+	return dir, syncIndex(contextClient(ctx), s.artifacts, index, location, dir)
+}
+
+// configMapSource is a registry index in a Kubernetes ConfigMap, read
+// through the API server with the pod's service account. The references
+// of the index must be absolute.
+type configMapSource struct {
+	api       string
+	namespace string
+	name      string
+	key       string
+	artifacts *ArtifactCache
+}
+
+func (s *configMapSource) Sync(ctx context.Context, dir string) (string, error) {
+	data, err := s.read(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read configmap %s: %w", s.name, err)
+	}
+	key := s.key
+	if key == "" {
+		key = "index.json"
+	}
+	doc, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("configmap %s has no key %q", s.name, key)
+	}
+	var index RegistryIndex
+	if err := json.Unmarshal([]byte(doc), &index); err != nil {
+		return "", fmt.Errorf("invalid index in configmap %s: %w", s.name, err)
+	}
+	return dir, syncIndex(contextClient(ctx), s.artifacts, &index, "", dir)
+}
+
+// read returns the data of the ConfigMap
+func (s *configMapSource) read(ctx context.Context) (map[string]string, error) {
+	client := contextClient(ctx)
+	api := s.api
+	if api == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("the host does not run in a Kubernetes cluster and the source sets no url")
+		}
+		api = "https://" + host + ":" + port
+
+		ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(ca)
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+	namespace := s.namespace
+	if namespace == "" {
+		ns, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("no namespace is set and the host's own is unknown: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(api, "/")+"/api/v1/namespaces/"+url.PathEscape(namespace)+"/configmaps/"+url.PathEscape(s.name), nil)
+	if err != nil {
+		return nil, err
+	}
+	if token, err := os.ReadFile(filepath.Join(serviceAccountDir, "token")); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", req.URL, resp.Status)
+	}
+	var cm struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&cm); err != nil {
+		return nil, err
+	}
+	return cm.Data, nil
+}
+
+// contextClient returns an http client giving up at the deadline of ctx
+func contextClient(ctx context.Context) *http.Client {
+	client := &http.Client{}
+	if deadline, ok := ctx.Deadline(); ok {
+		client.Timeout = time.Until(deadline)
+	}
+	return client
+}
+
+// syncIndex makes dir hold the plugins of the index that run here, with
+// their manifests and attachments: binaries that changed are downloaded
+// and those no longer listed removed. References resolve against base;
+// with an empty base they must be absolute.
+func syncIndex(client *http.Client, artifacts *ArtifactCache, index *RegistryIndex, base, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	var errs []error
+	for _, entry := range index.Plugins {
+		if !entry.runsHere() || keep["plugin-"+entry.Name] {
+			continue
+		}
+		if err := checkPluginName(entry.Name); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		path := filepath.Join(dir, "plugin-"+entry.Name)
+		for _, ext := range []string{"", manifestExt, sbomExt, provenanceExt} {
+			keep[filepath.Base(path)+ext] = true
+		}
+		if err := syncEntry(client, artifacts, entry, base, path); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s v%s: %w", entry.Name, entry.Version, err))
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || keep[e.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncEntry puts the binary of entry at path unless it is there already,
+// from the artifact cache when it holds it, then its manifest and
+// attachments next to it
+func syncEntry(client *http.Client, artifacts *ArtifactCache, entry IndexEntry, base, path string) error {
+	if entry.SHA256 == "" {
+		return fmt.Errorf("index lists no sha256")
+	}
+	resolve := func(ref string) (string, error) {
+		if base == "" && !isURL(ref) && !filepath.IsAbs(ref) {
+			return "", fmt.Errorf("%s is not an absolute URL or path", ref)
+		}
+		return resolveRef(base, ref), nil
+	}
+
+	if sum, err := fileSHA256(path); err != nil || !strings.EqualFold(sum, entry.SHA256) {
+		location, err := resolve(entry.URL)
+		if err != nil {
+			return err
+		}
+		binary, err := artifacts.Fetch(client, location, entry.SHA256)
+		if err != nil {
+			return err
+		}
+		if err := installFile(path, binary, 0755); err != nil {
+			return err
+		}
+	}
+
+	for ext, ref := range map[string]string{manifestExt: entry.Manifest, sbomExt: entry.SBOM, provenanceExt: entry.Provenance} {
+		if ref == "" {
+			if err := os.Remove(path + ext); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			continue
+		}
+		location, err := resolve(ref)
+		if err != nil {
+			return err
+		}
+		data, err := fetch(client, location)
+		if err != nil {
+			return err
+		}
+		if err := installFile(path+ext, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
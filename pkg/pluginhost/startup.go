@@ -0,0 +1,134 @@
+package pluginhost
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"time"
+)
+
+// DefaultStartTimeout bounds plugin startup when loading.start_timeout is
+// not set
+const DefaultStartTimeout = time.Minute
+
+// StartupPhase is a step of starting a plugin process
+type StartupPhase string
+
+const (
+	// PhaseDependencies waits for the external services the manifest
+	// lists; it is not bounded by the start timeout
+	PhaseDependencies StartupPhase = "dependencies"
+
+	// PhaseSpawn starts the binary, or the sandbox launcher running it
+	PhaseSpawn StartupPhase = "spawn"
+
+	// PhaseHandshake waits for the go-plugin handshake line and connects
+	PhaseHandshake StartupPhase = "handshake"
+
+	// PhaseDispense obtains the plugin interface, asks for its name and
+	// agrees on an argument codec
+	PhaseDispense StartupPhase = "dispense"
+
+	// PhaseInitialize passes the plugin its plugin_config entry
+	PhaseInitialize StartupPhase = "initialize"
+)
+
+// PhaseTiming is how long one startup phase took. Spawning is not timed
+// separately from the handshake, as go-plugin does both in one step.
+type PhaseTiming struct {
+	Phase    StartupPhase  `json:"phase"`
+	Duration time.Duration `json:"duration"`
+}
+
+// StartupError reports the phase in which a plugin failed to start
+type StartupError struct {
+	Path    string
+	Phase   StartupPhase
+	Elapsed time.Duration
+	Err     error
+}
+
+func (e *StartupError) Error() string {
+	return fmt.Sprintf("plugin %s failed during %s after %v: %v", e.Path, e.Phase, e.Elapsed.Round(time.Millisecond), e.Err)
+}
+
+func (e *StartupError) Unwrap() error {
+	return e.Err
+}
+
+// errStartTimeout is the cause of a StartupError when a phase ran past the
+// start timeout
+var errStartTimeout = errors.New("start timeout exceeded")
+
+// startup tracks the phases of one plugin start against its deadline
+type startup struct {
+	path     string
+	begin    time.Time
+	deadline time.Time
+	mark     time.Time
+	timings  []PhaseTiming
+}
+
+func newStartup(path string, timeout time.Duration) *startup {
+	now := time.Now()
+	return &startup{path: path, begin: now, deadline: now.Add(timeout), mark: now}
+}
+
+// done records that phase completed
+func (s *startup) done(phase StartupPhase) {
+	now := time.Now()
+	s.timings = append(s.timings, PhaseTiming{Phase: phase, Duration: now.Sub(s.mark)})
+	s.mark = now
+}
+
+// fail wraps err as the failure of phase
+func (s *startup) fail(phase StartupPhase, err error) error {
+	return &StartupError{Path: s.path, Phase: phase, Elapsed: time.Since(s.begin), Err: err}
+}
+
+// run calls fn for phase, giving up when the start deadline passes. fn
+// keeps running after a timeout until the caller kills the process.
+func (s *startup) run(phase StartupPhase, fn func() error) error {
+	remaining := time.Until(s.deadline)
+	if remaining <= 0 {
+		return s.fail(phase, errStartTimeout)
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- fn()
+	}()
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return s.fail(phase, err)
+		}
+		s.done(phase)
+		return nil
+	case <-timer.C:
+		return s.fail(phase, errStartTimeout)
+	}
+}
+
+// startPhase classifies an error from go-plugin's Start: failing to run the
+// command is a spawn failure, anything after it a handshake failure
+func startPhase(err error) StartupPhase {
+	var execErr *exec.Error
+	var pathErr *fs.PathError
+	if errors.As(err, &execErr) || errors.As(err, &pathErr) {
+		return PhaseSpawn
+	}
+	return PhaseHandshake
+}
+
+// startTimeout returns the configured start timeout or the default
+func (c *LoadingConfig) startTimeout() time.Duration {
+	if c.StartTimeout > 0 {
+		return time.Duration(c.StartTimeout)
+	}
+	return DefaultStartTimeout
+}
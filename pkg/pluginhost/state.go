@@ -0,0 +1,233 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StateConfig keeps the plugin registry on disk, so a restarted host
+// restores the plugins it was running instead of scanning the plugin
+// directories again
+type StateConfig struct {
+	// Path is the state file; empty disables persistence
+	Path string `json:"path"`
+}
+
+// RegistryState is the plugin set a host was running
+type RegistryState struct {
+	SavedAt time.Time     `json:"saved_at"`
+	Plugins []StatePlugin `json:"plugins"`
+
+	// Pins maps pinned plugins to their version
+	Pins map[string]string `json:"pins,omitempty"`
+
+	// Disabled are the plugins an operator disabled
+	Disabled []string `json:"disabled,omitempty"`
+
+	// Halt is the kill switch, when it is engaged
+	Halt *HaltStatus `json:"halt,omitempty"`
+}
+
+// StatePlugin is one registered plugin and where its binary is
+type StatePlugin struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+}
+
+// registryState guards the state file. Saving is suspended while the state
+// is being restored, so a restore cut short does not overwrite the file
+// with part of the plugin set.
+type registryState struct {
+	mu        sync.Mutex
+	suspended bool
+
+	// consulted is set once the first config had its chance to restore
+	consulted bool
+}
+
+// LoadRegistryState reads a state file
+func LoadRegistryState(path string) (*RegistryState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry state: %w", err)
+	}
+	state := &RegistryState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid registry state %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// State returns the registry as it would be saved
+func (pm *PluginManager) State() *RegistryState {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	state := &RegistryState{SavedAt: time.Now(), Plugins: make([]StatePlugin, 0, len(pm.plugins))}
+	for _, info := range pm.plugins {
+		if info.builtin {
+			continue
+		}
+		state.Plugins = append(state.Plugins, StatePlugin{Name: info.Name, Path: info.Path, Version: info.Version})
+	}
+	sort.Slice(state.Plugins, func(i, j int) bool {
+		return state.Plugins[i].Name < state.Plugins[j].Name
+	})
+	if len(pm.pins) > 0 {
+		state.Pins = make(map[string]string, len(pm.pins))
+		for name, version := range pm.pins {
+			state.Pins[name] = version
+		}
+	}
+	for name := range pm.disabled {
+		state.Disabled = append(state.Disabled, name)
+	}
+	sort.Strings(state.Disabled)
+	if st := pm.Halted(); st.Halted {
+		state.Halt = &st
+	}
+	return state
+}
+
+// saveState writes the registry to the state file, if one is configured.
+// The file is replaced atomically, so a crash leaves the previous state.
+func (pm *PluginManager) saveState() {
+	path := pm.Config().State.Path
+	if path == "" {
+		return
+	}
+	pm.state.mu.Lock()
+	defer pm.state.mu.Unlock()
+
+	if pm.state.suspended {
+		return
+	}
+	data, err := json.MarshalIndent(pm.State(), "", "  ")
+	if err != nil {
+		pm.hostLog.Printf("Failed to save registry state: %v", err)
+		return
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		pm.hostLog.Printf("Failed to save registry state: %v", err)
+	}
+}
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// restoreState loads the plugins of the state file at path and restores
+// the pins, disabled plugins and kill switch, once per manager. It reports
+// false when it already ran or there is no state file, so the caller
+// discovers plugins instead. Plugins whose binary is gone are left out; a
+// binary whose version changed since the state was saved is loaded with a
+// warning, unless a pin refuses it.
+func (pm *PluginManager) restoreState(path string) bool {
+	pm.state.mu.Lock()
+	first := !pm.state.consulted
+	pm.state.consulted = true
+	pm.state.mu.Unlock()
+	if !first || path == "" {
+		return false
+	}
+
+	state, err := LoadRegistryState(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if err != nil {
+		pm.hostLog.Printf("Ignoring registry state: %v", err)
+		return false
+	}
+
+	pm.state.mu.Lock()
+	pm.state.suspended = true
+	pm.state.mu.Unlock()
+	defer func() {
+		pm.state.mu.Lock()
+		pm.state.suspended = false
+		pm.state.mu.Unlock()
+		pm.saveState()
+	}()
+
+	pm.mu.Lock()
+	for name, version := range state.Pins {
+		if pm.pins == nil {
+			pm.pins = make(map[string]string)
+		}
+		pm.pins[name] = version
+	}
+	for _, name := range state.Disabled {
+		if pm.disabled == nil {
+			pm.disabled = make(map[string]bool)
+		}
+		pm.disabled[name] = true
+	}
+	pm.mu.Unlock()
+	if state.Halt != nil && state.Halt.Halted {
+		pm.halt.mu.Lock()
+		pm.halt.status = *state.Halt
+		pm.halt.mu.Unlock()
+		pm.hostLog.Printf("Plugin execution stays halted since %s: %s", state.Halt.Since.Format(time.RFC3339), state.Halt.Reason)
+	}
+
+	cfg := pm.Config()
+	restored := 0
+	for _, p := range state.Plugins {
+		if _, err := os.Stat(p.Path); err != nil {
+			pm.hostLog.Printf("Not restoring plugin %s: %v", p.Name, err)
+			continue
+		}
+		m := pm.pluginManifest(p.Path)
+		if m != nil && p.Version != "" && m.Version != p.Version {
+			if err := pm.checkPin(p.Name, m.Version); err != nil {
+				pm.hostLog.Printf("Not restoring plugin %s: %v", p.Name, err)
+				continue
+			}
+			pm.hostLog.Printf("Plugin %s at %s is now v%s, was v%s when the state was saved", p.Name, p.Path, m.Version, p.Version)
+		}
+
+		if pm.startsLazily(cfg, m) {
+			pm.registerLazy(p.Path, m)
+			restored++
+			continue
+		}
+		name, err := pm.loadPlugin(p.Path)
+		if err != nil {
+			pm.hostLog.Printf("Failed to restore plugin %s: %v", p.Name, err)
+			continue
+		}
+		if name != p.Name {
+			pm.hostLog.Printf("Plugin at %s now reports name %q, was %q; keeping it", p.Path, name, p.Name)
+		}
+		restored++
+	}
+	pm.hostLog.Printf("Restored %d of %d plugin(s) from %s, saved %s", restored, len(state.Plugins), path, state.SavedAt.Format(time.RFC3339))
+	return true
+}
@@ -0,0 +1,305 @@
+package pluginhost
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// PluginState is the lifecycle state of a plugin
+type PluginState string
+
+const (
+	// StateLoading means the process is up but metadata is still being read
+	StateLoading PluginState = "loading"
+	// StateReady means the plugin is accepting calls
+	StateReady PluginState = "ready"
+	// StateUnhealthy means recent calls keep failing although the process runs
+	StateUnhealthy PluginState = "unhealthy"
+	// StateCrashed means the process exited without being unloaded
+	StateCrashed PluginState = "crashed"
+	// StateStopped means the plugin is registered but its process is not
+	// running; it is started by the next call
+	StateStopped PluginState = "stopped"
+	// StateWaiting means the process is not started until the external
+	// services the manifest lists are ready
+	StateWaiting PluginState = "waiting"
+)
+
+// unhealthyAfter is the number of consecutive failed calls after which a
+// running plugin is reported unhealthy
+const unhealthyAfter = 3
+
+// PluginStatus is a read-only snapshot of a plugin, safe to hand to callers
+// because it shares no state with the manager
+type PluginStatus struct {
+	Name         string
+	Version      string
+	Path         string
+	Capabilities []pluginsdk.Capability
+	Flags        []string
+	State        PluginState
+	Uptime       time.Duration
+	Calls        int64
+	Failures     int64
+	Timeouts     int64
+	LastError    string
+	LastCrash    *CrashReport
+
+	// Startup is how long each phase of the latest start took
+	Startup []PhaseTiming
+
+	// Codec is how call arguments are encoded for the running process,
+	// e.g. "msgpack", or "gob" for net/rpc plugins that cannot negotiate
+	Codec string
+
+	// Handoff is set when the running process accepted large arguments
+	// through files; see TransportConfig.HandoffBytes
+	Handoff bool
+
+	// Protocol is the protocol the running process is spoken to over,
+	// "grpc" or "netrpc"; empty for plugins running in the host process
+	Protocol string
+
+	// SupplyChain is what the SBOM and provenance attached to the binary
+	// say, as checked when it was started; nil when none are attached
+	SupplyChain *SupplyChain
+
+	// Build is what the running binary recorded of its build; nil for
+	// binaries recording none and for built-in plugins
+	Build *BuildInfo
+
+	// Resources is the latest sample while the process runs and resource
+	// sampling is enabled
+	Resources *ResourceUsage
+
+	// WaitingOn names the dependency the plugin is waiting on and why it
+	// is not ready, while the state is StateWaiting
+	WaitingOn string
+
+	// SLOs are how the plugin's capabilities with objectives are doing
+	SLOs []SLOStatus
+
+	// Locales are the locales the plugin answers in, from its manifest
+	Locales []string
+
+	// Processes is how many processes serve the plugin, more than one
+	// when it is pooled; none for plugins running in the host process
+	Processes int
+
+	// InProcess is set for built-in plugins, which run inside the host
+	// process rather than as subprocesses
+	InProcess bool
+
+	// Pinned is the version the plugin is pinned to, if any
+	Pinned string
+
+	// Updatable is set when the manifest lets UpdatePlugin update the
+	// plugin from the registry index
+	Updatable bool
+
+	// Disabled is set while an operator has the plugin disabled
+	Disabled bool
+
+	// LogLevel is the level an operator set the plugin to log at; empty
+	// while it logs at its own level
+	LogLevel string
+
+	// Events is what became of the events the plugin emitted, and its
+	// mute; nil when it emitted none and is not muted
+	Events *EventEmission
+
+	// LLM is what the plugin spent on LLM requests through the host; nil
+	// when it made none
+	LLM *LLMUsageStats
+
+	// Workspace is the plugin's own directories and how much they hold;
+	// nil when workspaces are disabled
+	Workspace *WorkspaceUsage
+
+	// Daemon is the state of the server of daemon plugins; nil for the
+	// others
+	Daemon *DaemonInfo
+
+	// Host is the remote host serving the plugin; empty for plugins this
+	// host runs
+	Host string
+
+	// Source is the discovery source the plugin came from and Trust its
+	// trust level; empty for plugins from plugin_dirs
+	Source string
+	Trust  string
+}
+
+// pluginStats counts calls to a plugin. It has its own lock because calls
+// are recorded while the manager only holds its read lock.
+type pluginStats struct {
+	calls               int64
+	failures            int64
+	timeouts            int64
+	consecutiveFailures int
+	lastError           string
+	lastUsed            time.Time
+	mu                  sync.Mutex
+}
+
+func (s *pluginStats) record(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	s.lastUsed = time.Now()
+	if err != nil {
+		s.failures++
+		if isTimeout(err) {
+			s.timeouts++
+		}
+		s.consecutiveFailures++
+		s.lastError = err.Error()
+		return
+	}
+	s.consecutiveFailures = 0
+}
+
+func (s *pluginStats) lastUsedAt() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lastUsed
+}
+
+// status builds the snapshot for a plugin; the caller holds pm.mu
+func (info *pluginInfo) status() PluginStatus {
+	info.stats.mu.Lock()
+	defer info.stats.mu.Unlock()
+
+	st := PluginStatus{
+		Name:         info.Name,
+		Version:      info.Version,
+		Path:         info.Path,
+		Capabilities: copyCapabilities(info.Capabilities),
+		Flags:        append([]string(nil), info.Flags...),
+		State:        StateReady,
+		Uptime:       time.Since(info.StartedAt),
+		Calls:        info.stats.calls,
+		Failures:     info.stats.failures,
+		Timeouts:     info.stats.timeouts,
+		LastError:    info.stats.lastError,
+		Startup:      append([]PhaseTiming(nil), info.Startup...),
+		Codec:        info.Codec,
+		Handoff:      info.Handoff,
+		Protocol:     info.Protocol,
+		SupplyChain:  info.SupplyChain,
+		Build:        info.Build,
+		Locales:      append([]string(nil), info.Locales...),
+		Updatable:    info.Updatable,
+	}
+	if ws := info.workspace; ws != nil {
+		st.Workspace = &WorkspaceUsage{DataDir: ws.DataDir, CacheDir: ws.CacheDir, TempDir: ws.TempDir}
+	}
+	if info.LastCrash != nil {
+		crash := *info.LastCrash
+		st.LastCrash = &crash
+	}
+	if info.daemon != nil {
+		daemon := info.daemon.snapshot()
+		st.Daemon = &daemon
+	}
+	if info.Resources != nil && info.Instance != nil && !info.crashed {
+		usage := *info.Resources
+		st.Resources = &usage
+	}
+	st.InProcess = info.builtin
+	if info.Instance != nil && !info.crashed && !info.builtin {
+		st.Processes = 1
+		if info.pool != nil {
+			st.Processes = info.pool.size()
+		}
+	}
+
+	switch {
+	case info.crashed:
+		st.State = StateCrashed
+		st.Uptime = 0
+	case info.Instance == nil:
+		st.State = StateStopped
+		st.Uptime = 0
+	case info.loading:
+		st.State = StateLoading
+	case info.unresponsive, info.stats.consecutiveFailures >= unhealthyAfter:
+		st.State = StateUnhealthy
+	}
+	return st
+}
+
+// copyCapabilities copies the slice so callers cannot modify the registry;
+// the schemas and examples inside are treated as immutable
+func copyCapabilities(caps []pluginsdk.Capability) []pluginsdk.Capability {
+	return append([]pluginsdk.Capability(nil), caps...)
+}
+
+// ListPlugins returns the status of all loaded plugins, sorted by name
+func (pm *PluginManager) ListPlugins() []PluginStatus {
+	pm.mu.RLock()
+	plugins := make([]PluginStatus, 0, len(pm.plugins))
+	for _, info := range pm.plugins {
+		st := info.status()
+		pm.waitState(info, &st)
+		pm.sourceStatus(info, &st)
+		st.Pinned = pm.pins[info.Name]
+		st.Disabled = pm.disabled[info.Name]
+		st.LogLevel = pm.logLevelOf(info.Path)
+		st.Events = pm.emissionOf(info.Name)
+		st.LLM = pm.llmUsageOf(info.Name)
+		st.SLOs = pm.slos.statuses(&pm.config.SLO, info.Name, time.Now())
+		plugins = append(plugins, st)
+	}
+	plugins = append(plugins, pm.remotePlugins()...)
+	pm.mu.RUnlock()
+
+	// Workspaces are measured unlocked, as walking them may take a while
+	for _, st := range plugins {
+		if st.Workspace != nil {
+			st.Workspace.measure()
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+
+	return plugins
+}
+
+// GetPlugin returns the status of a single plugin
+func (pm *PluginManager) GetPlugin(name string) (PluginStatus, error) {
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	if !exists {
+		for _, st := range pm.remotePlugins() {
+			if st.Name == name {
+				pm.mu.RUnlock()
+				return st, nil
+			}
+		}
+		err := pm.errPluginNotFound(name)
+		pm.mu.RUnlock()
+		return PluginStatus{}, err
+	}
+	st := info.status()
+	pm.waitState(info, &st)
+	pm.sourceStatus(info, &st)
+	st.Pinned = pm.pins[name]
+	st.Disabled = pm.disabled[name]
+	st.LogLevel = pm.logLevelOf(info.Path)
+	st.Events = pm.emissionOf(name)
+	st.LLM = pm.llmUsageOf(name)
+	st.SLOs = pm.slos.statuses(&pm.config.SLO, name, time.Now())
+	pm.mu.RUnlock()
+
+	if st.Workspace != nil {
+		st.Workspace.measure()
+	}
+	return st, nil
+}
@@ -0,0 +1,293 @@
+package pluginhost
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Attachments stored next to a plugin binary: plugins/plugin-hello is
+// described by plugins/plugin-hello.sbom.json and attested by
+// plugins/plugin-hello.provenance.json
+const (
+	sbomExt       = ".sbom.json"
+	provenanceExt = ".provenance.json"
+)
+
+// SBOM formats
+const (
+	SBOMSPDX      = "spdx"
+	SBOMCycloneDX = "cyclonedx"
+)
+
+// SupplyChainConfig decides which plugins may be installed and started by
+// what is known of how they were built. Provenance that is attached is
+// always checked against the binary; the settings add requirements.
+type SupplyChainConfig struct {
+	// RequireSBOM refuses plugins without a software bill of materials
+	RequireSBOM bool `json:"require_sbom"`
+
+	// RequireProvenance refuses plugins without SLSA provenance for their
+	// binary
+	RequireProvenance bool `json:"require_provenance"`
+
+	// TrustedBuilders are the builder IDs provenance must name, e.g.
+	// "https://github.com/slsa-framework/slsa-github-generator/.github/workflows/builder_go_slsa3.yml@refs/tags/v2.0.0";
+	// empty trusts every builder
+	TrustedBuilders []string `json:"trusted_builders"`
+
+	// Mode is LockEnforce (the default), refusing plugins that fail the
+	// checks, or LockWarn, which only logs them
+	Mode string `json:"mode"`
+}
+
+func (c *SupplyChainConfig) validate() error {
+	switch c.Mode {
+	case "", LockEnforce, LockWarn:
+	default:
+		return fmt.Errorf("supply_chain.mode must be %q or %q, got %q", LockEnforce, LockWarn, c.Mode)
+	}
+	for i, b := range c.TrustedBuilders {
+		if b == "" {
+			return fmt.Errorf("supply_chain.trusted_builders[%d] is empty", i)
+		}
+	}
+	return nil
+}
+
+// SupplyChain is what the SBOM and provenance attached to a plugin say
+type SupplyChain struct {
+	SBOM       *SBOMInfo       `json:"sbom,omitempty"`
+	Provenance *ProvenanceInfo `json:"provenance,omitempty"`
+}
+
+// orNil returns nil when nothing is known
+func (sc *SupplyChain) orNil() *SupplyChain {
+	if sc == nil || (sc.SBOM == nil && sc.Provenance == nil) {
+		return nil
+	}
+	return sc
+}
+
+// SBOMInfo summarizes a software bill of materials
+type SBOMInfo struct {
+	// Format is SBOMSPDX or SBOMCycloneDX, and SpecVersion its version,
+	// e.g. "SPDX-2.3" or "1.5"
+	Format      string `json:"format"`
+	SpecVersion string `json:"spec_version"`
+
+	// Components is how many packages or components it lists
+	Components int `json:"components"`
+}
+
+// ProvenanceInfo summarizes the SLSA provenance of a plugin binary
+type ProvenanceInfo struct {
+	// PredicateType is the SLSA provenance version, e.g.
+	// "https://slsa.dev/provenance/v1"
+	PredicateType string `json:"predicate_type"`
+
+	// Builder is the ID of the builder that produced the binary
+	Builder string `json:"builder"`
+
+	// Subject is the artifact it attests and Digest its sha256
+	Subject string `json:"subject"`
+	Digest  string `json:"digest"`
+}
+
+// parseSBOM reads an SPDX or CycloneDX document in JSON
+func parseSBOM(data []byte) (*SBOMInfo, error) {
+	var doc struct {
+		SPDXVersion string            `json:"spdxVersion"`
+		Packages    []json.RawMessage `json:"packages"`
+
+		BOMFormat   string            `json:"bomFormat"`
+		SpecVersion string            `json:"specVersion"`
+		Components  []json.RawMessage `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid SBOM: %w", err)
+	}
+	switch {
+	case doc.SPDXVersion != "":
+		return &SBOMInfo{Format: SBOMSPDX, SpecVersion: doc.SPDXVersion, Components: len(doc.Packages)}, nil
+	case doc.BOMFormat == "CycloneDX":
+		return &SBOMInfo{Format: SBOMCycloneDX, SpecVersion: doc.SpecVersion, Components: len(doc.Components)}, nil
+	}
+	return nil, errors.New("SBOM is neither SPDX nor CycloneDX JSON")
+}
+
+// parseProvenance reads an in-toto statement with a SLSA provenance
+// predicate, bare or in a DSSE envelope. Envelope signatures are not
+// checked; signed bundles cover the provenance with their own signature.
+func parseProvenance(data []byte) (*ProvenanceInfo, error) {
+	var envelope struct {
+		PayloadType string `json:"payloadType"`
+		Payload     string `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid provenance: %w", err)
+	}
+	if envelope.Payload != "" {
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("invalid provenance envelope payload: %w", err)
+		}
+		data = payload
+	}
+
+	var statement struct {
+		Subject []struct {
+			Name   string            `json:"name"`
+			Digest map[string]string `json:"digest"`
+		} `json:"subject"`
+		PredicateType string `json:"predicateType"`
+		Predicate     struct {
+			// SLSA v0.2
+			Builder struct {
+				ID string `json:"id"`
+			} `json:"builder"`
+			// SLSA v1
+			RunDetails struct {
+				Builder struct {
+					ID string `json:"id"`
+				} `json:"builder"`
+			} `json:"runDetails"`
+		} `json:"predicate"`
+	}
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return nil, fmt.Errorf("invalid provenance statement: %w", err)
+	}
+	if !strings.HasPrefix(statement.PredicateType, "https://slsa.dev/provenance/") {
+		return nil, fmt.Errorf("provenance predicate %q is not SLSA provenance", statement.PredicateType)
+	}
+	p := &ProvenanceInfo{PredicateType: statement.PredicateType, Builder: statement.Predicate.RunDetails.Builder.ID}
+	if p.Builder == "" {
+		p.Builder = statement.Predicate.Builder.ID
+	}
+	for _, s := range statement.Subject {
+		if d := s.Digest["sha256"]; d != "" {
+			p.Subject, p.Digest = s.Name, strings.ToLower(d)
+			break
+		}
+	}
+	if p.Digest == "" {
+		return nil, errors.New("provenance names no subject with a sha256 digest")
+	}
+	return p, nil
+}
+
+// readSupplyChain parses the SBOM and provenance of a binary, either of
+// which may be nil when absent
+func readSupplyChain(sbom, provenance []byte) (*SupplyChain, error) {
+	sc := &SupplyChain{}
+	var err error
+	if sbom != nil {
+		if sc.SBOM, err = parseSBOM(sbom); err != nil {
+			return nil, err
+		}
+	}
+	if provenance != nil {
+		if sc.Provenance, err = parseProvenance(provenance); err != nil {
+			return nil, err
+		}
+	}
+	return sc, nil
+}
+
+// check applies the policy to what is known about a binary with digest
+func (c SupplyChainConfig) check(sc *SupplyChain, digest string) error {
+	if c.RequireSBOM && sc.SBOM == nil {
+		return errors.New("no SBOM is attached, but supply_chain.require_sbom is set")
+	}
+	p := sc.Provenance
+	if p == nil {
+		if c.RequireProvenance {
+			return errors.New("no provenance is attached, but supply_chain.require_provenance is set")
+		}
+		return nil
+	}
+	if p.Digest != digest {
+		return fmt.Errorf("provenance attests sha256 %s, but the binary is %s", p.Digest, digest)
+	}
+	if len(c.TrustedBuilders) > 0 && !containsString(c.TrustedBuilders, p.Builder) {
+		return fmt.Errorf("provenance names builder %q, which supply_chain.trusted_builders does not list", p.Builder)
+	}
+	return nil
+}
+
+// verifySupplyChain reads the attachments of the binary at path and
+// applies the policy before the binary is started. In warn mode failures
+// are only logged, except for binaries of sources that are not fully
+// trusted, which always need provenance.
+func (pm *PluginManager) verifySupplyChain(path string) (*SupplyChain, error) {
+	cfg := pm.Config().SupplyChain
+	trust := pm.trustOf(path)
+	if trust != TrustFull {
+		cfg.RequireProvenance, cfg.Mode = true, LockEnforce
+	}
+	sc, err := pm.checkSupplyChain(cfg, path)
+	if err == nil {
+		return sc.orNil(), nil
+	}
+	err = fmt.Errorf("supply chain check of %s failed: %w", path, err)
+	if trust != TrustFull {
+		err = fmt.Errorf("%w; its source is only trusted %s", err, trust)
+	}
+	if cfg.Mode == LockWarn {
+		pm.hostLog.Printf("Starting plugin despite failed check: %v", err)
+		return sc.orNil(), nil
+	}
+	return nil, err
+}
+
+func (pm *PluginManager) checkSupplyChain(cfg SupplyChainConfig, path string) (*SupplyChain, error) {
+	sbom, err := readAttachment(path + sbomExt)
+	if err != nil {
+		return nil, err
+	}
+	provenance, err := readAttachment(path + provenanceExt)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := readSupplyChain(sbom, provenance)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := fileSHA256(path)
+	if err != nil {
+		return sc, err
+	}
+	return sc, cfg.check(sc, digest)
+}
+
+// readAttachment reads a file next to a binary; nil when it does not exist
+func readAttachment(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// bundleSupplyChain checks the SBOM and provenance of a bundle against its
+// binary before it is installed
+func (pm *PluginManager) bundleSupplyChain(b *bundle) (*SupplyChain, error) {
+	var sbom, provenance []byte
+	if b.index.SBOM != "" {
+		sbom = b.files[b.index.SBOM]
+	}
+	if b.index.Provenance != "" {
+		provenance = b.files[b.index.Provenance]
+	}
+	sc, err := readSupplyChain(sbom, provenance)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b.files[b.index.Binary])
+	return sc, pm.Config().SupplyChain.check(sc, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,491 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Task states
+const (
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+	TaskCanceled  = "canceled"
+
+	// TaskInterrupted is a task the host stopped running, by shutting down
+	// while it ran
+	TaskInterrupted = "interrupted"
+)
+
+// Task events
+const (
+	EventTaskStarted  = "task.started"
+	EventTaskProgress = "task.progress"
+	EventTaskFinished = "task.finished"
+)
+
+const (
+	// DefaultTaskTimeout bounds task calls that set no timeout of their own
+	DefaultTaskTimeout = time.Hour
+
+	// DefaultTaskRetention is how long finished tasks are kept when the
+	// config sets no retention
+	DefaultTaskRetention = 24 * time.Hour
+
+	// maxTaskRestarts is how often a task is started again when the
+	// process of its plugin goes away under it, e.g. on a reload
+	maxTaskRestarts = 3
+)
+
+// TaskConfig sets how background tasks run and how long they are kept
+type TaskConfig struct {
+	// Path is a file tasks are kept in, so they outlive the host; empty
+	// keeps them in memory
+	Path string `json:"path"`
+
+	// Timeout bounds task calls that set no timeout of their own; defaults
+	// to DefaultTaskTimeout. timeouts.max still caps it.
+	Timeout Duration `json:"timeout"`
+
+	// Retention is how long finished tasks are kept; defaults to
+	// DefaultTaskRetention
+	Retention Duration `json:"retention"`
+}
+
+func (c *TaskConfig) validate() error {
+	if c.Timeout < 0 || c.Retention < 0 {
+		return fmt.Errorf("tasks.timeout and tasks.retention must not be negative")
+	}
+	return nil
+}
+
+func (c TaskConfig) timeout() time.Duration {
+	if c.Timeout == 0 {
+		return DefaultTaskTimeout
+	}
+	return time.Duration(c.Timeout)
+}
+
+func (c TaskConfig) retention() time.Duration {
+	if c.Retention == 0 {
+		return DefaultTaskRetention
+	}
+	return time.Duration(c.Retention)
+}
+
+// Task is a call running in the background, for operations that take
+// minutes such as analyzing a whole repository
+type Task struct {
+	ID         string                 `json:"id"`
+	Plugin     string                 `json:"plugin"`
+	Capability string                 `json:"capability,omitempty"`
+	Args       map[string]interface{} `json:"args,omitempty"`
+	State      string                 `json:"state"`
+
+	// Principal is who the task runs for, from the request context it was
+	// started with
+	Principal Principal `json:"principal"`
+
+	// Percent and Message are the progress the plugin last reported
+	Percent float64 `json:"percent"`
+	Message string  `json:"message,omitempty"`
+
+	// Result is set once the task succeeded, Error once it did not
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// Restarts counts the times the task was started again after its
+	// plugin process went away
+	Restarts int `json:"restarts,omitempty"`
+
+	Created  time.Time  `json:"created"`
+	Updated  time.Time  `json:"updated"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// TaskFilter selects tasks. Zero fields match everything.
+type TaskFilter struct {
+	Plugin string
+	State  string
+
+	// Principal, when set, selects the tasks running for it
+	Principal *Principal
+}
+
+// taskRegistry holds the tasks by ID
+type taskRegistry struct {
+	tasks map[string]*Task
+
+	// canceled holds the reasons of the running tasks CancelTask was
+	// called for
+	canceled map[string]string
+
+	// loaded is the task file read last
+	loaded string
+
+	mu sync.Mutex
+
+	// saveMu keeps saves in order, so an older snapshot never replaces a
+	// newer one
+	saveMu sync.Mutex
+}
+
+// taskFile is the content of the task file
+type taskFile struct {
+	SavedAt time.Time `json:"saved_at"`
+	Tasks   []*Task   `json:"tasks"`
+}
+
+// StartTask runs capability of a plugin in the background and returns the
+// task's ID at once. The task ID is also the ID of the request the call
+// runs as, so the plugin reports progress with pluginsdk.ReportProgress and
+// CancelRequest cancels it like CancelTask. Calls that set no timeout get the
+// task timeout instead of the call timeout. Tasks of idempotent
+// capabilities are started again when their plugin is reloaded or crashes
+// while they run.
+func (pm *PluginManager) StartTask(plugin, capability string, args map[string]interface{}) (string, error) {
+	return pm.StartTaskWithContext(plugin, capability, args, pluginsdk.RequestContext{})
+}
+
+// StartTaskWithContext starts a task like StartTask whose request shares rc,
+// as ExecuteWithContext does; rc's RequestID is replaced by the task ID.
+// The user or service the task runs for comes from rc only, never from
+// args.
+func (pm *PluginManager) StartTaskWithContext(plugin, capability string, args map[string]interface{}, rc pluginsdk.RequestContext) (string, error) {
+	if err := pm.checkHalt(); err != nil {
+		return "", err
+	}
+	args = withoutReserved(args)
+	callArgs := make(map[string]interface{}, len(args)+2)
+	for k, v := range args {
+		callArgs[k] = v
+	}
+	if capability != "" {
+		callArgs[pluginsdk.ArgCapability] = capability
+	}
+
+	// Arguments of remote plugins are not recorded, as the host does not
+	// know which of them are sensitive
+	pm.mu.RLock()
+	info, exists := pm.plugins[plugin]
+	var recorded map[string]interface{}
+	restartable := false
+	if exists {
+		restartable = idempotent(info, callArgs)
+		recorded = pm.recordArgs(info, callArgs)
+		delete(recorded, pluginsdk.ArgCapability)
+	}
+	timeout := pm.config.Tasks.timeout()
+	pm.mu.RUnlock()
+	if !exists && pm.remoteFor(plugin) == nil {
+		return "", pm.pluginNotFound(plugin)
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return "", fmt.Errorf("failed to create task ID: %w", err)
+	}
+	if _, ok := callArgs[pluginsdk.ArgTimeout]; !ok {
+		callArgs[pluginsdk.ArgTimeout] = timeout.String()
+	}
+	rc.RequestID = id
+
+	now := time.Now()
+	task := &Task{
+		ID:         id,
+		Plugin:     plugin,
+		Capability: capability,
+		Args:       recorded,
+		State:      TaskRunning,
+		Principal:  Principal{Name: rc.User, Service: rc.Service},
+		Created:    now,
+		Updated:    now,
+	}
+	pm.tasks.mu.Lock()
+	if pm.tasks.tasks == nil {
+		pm.tasks.tasks = make(map[string]*Task)
+	}
+	pm.tasks.tasks[id] = task
+	pm.tasks.mu.Unlock()
+	pm.saveTasks()
+
+	pm.hostLog.Printf("Started task %s: %s %s", id, plugin, capability)
+	pm.events.Publish(Event{Type: EventTaskStarted, Plugin: plugin, Data: map[string]interface{}{
+		"task_id":    id,
+		"capability": capability,
+	}})
+	go pm.runTask(task.ID, plugin, callArgs, rc, restartable)
+	return id, nil
+}
+
+// runTask runs a task's call until it ends. Calls of a restartable
+// capability are started again when the plugin's process went away under
+// them, which is all a reload or crash looks like from here.
+func (pm *PluginManager) runTask(id, plugin string, args map[string]interface{}, rc pluginsdk.RequestContext, restartable bool) {
+	for restarts := 0; ; restarts++ {
+		result, err := pm.ExecuteWithContext(plugin, args, rc)
+		if err == nil || !restartable || errors.Is(err, ErrHalted) || restarts >= maxTaskRestarts || pluginsdk.AsPluginError(err).Code != pluginsdk.CodeUnavailable {
+			pm.finishTask(id, result, err)
+			return
+		}
+
+		pm.tasks.mu.Lock()
+		task := pm.tasks.tasks[id]
+		_, canceled := pm.tasks.canceled[id]
+		stop := canceled || task == nil || task.State != TaskRunning
+		if !stop {
+			task.Restarts, task.Percent, task.Message = restarts+1, 0, ""
+			task.Updated = time.Now()
+		}
+		pm.tasks.mu.Unlock()
+		if stop {
+			pm.finishTask(id, result, err)
+			return
+		}
+		delay := time.Duration(1<<restarts) * time.Second
+		pm.hostLog.Printf("Restarting task %s in %v after its plugin went away: %v", id, delay, err)
+		time.Sleep(delay)
+
+		pm.tasks.mu.Lock()
+		reason, canceled := pm.tasks.canceled[id]
+		pm.tasks.mu.Unlock()
+		if canceled {
+			pm.finishTask(id, "", pluginsdk.NewError(pluginsdk.CodeCanceled, "task canceled: %s", reason))
+			return
+		}
+	}
+}
+
+// idempotent reports whether the capability a call is for is marked
+// idempotent. The caller holds pm.mu.
+func idempotent(info *pluginInfo, args map[string]interface{}) bool {
+	ref, _ := args[pluginsdk.ArgCapability].(string)
+	capability, version := pluginsdk.ParseCapabilityRef(ref)
+	if v, ok := args[pluginsdk.ArgCapabilityVersion].(string); ok && version == "" {
+		version = v
+	}
+	c := findCapability(info.Capabilities, capability, version)
+	return c != nil && c.Idempotent
+}
+
+// finishTask records how a task ended, unless the host already interrupted
+// it
+func (pm *PluginManager) finishTask(id, result string, err error) {
+	pm.tasks.mu.Lock()
+	task := pm.tasks.tasks[id]
+	if task == nil || task.State != TaskRunning {
+		pm.tasks.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	task.Updated, task.Finished = now, &now
+	switch {
+	case err == nil:
+		task.State, task.Result, task.Percent = TaskSucceeded, result, 100
+	case pm.tasks.canceled[id] != "":
+		task.State, task.Error = TaskCanceled, err.Error()
+	default:
+		task.State, task.Error = TaskFailed, err.Error()
+	}
+	delete(pm.tasks.canceled, id)
+	plugin, state := task.Plugin, task.State
+	pm.tasks.mu.Unlock()
+	pm.saveTasks()
+
+	pm.hostLog.Printf("Task %s %s", id, state)
+	data := map[string]interface{}{"task_id": id, "state": state}
+	if err != nil {
+		data["error"] = err.Error()
+	}
+	pm.events.Publish(Event{Type: EventTaskFinished, Plugin: plugin, Data: data})
+}
+
+// GetTask returns a task by ID
+func (pm *PluginManager) GetTask(id string) (Task, error) {
+	pm.tasks.mu.Lock()
+	defer pm.tasks.mu.Unlock()
+
+	task, ok := pm.tasks.tasks[id]
+	if !ok {
+		return Task{}, pluginsdk.NewError(pluginsdk.CodeNotFound, "no task %q", id)
+	}
+	return *task, nil
+}
+
+// ListTasks returns the tasks matching filter, newest first
+func (pm *PluginManager) ListTasks(filter TaskFilter) []Task {
+	pm.tasks.mu.Lock()
+	defer pm.tasks.mu.Unlock()
+
+	tasks := make([]Task, 0, len(pm.tasks.tasks))
+	for _, task := range pm.tasks.tasks {
+		if (filter.Plugin == "" || task.Plugin == filter.Plugin) && (filter.State == "" || task.State == filter.State) && (filter.Principal == nil || task.Principal == *filter.Principal) {
+			tasks = append(tasks, *task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].Created.After(tasks[j].Created)
+	})
+	return tasks
+}
+
+// CancelTask cancels a running task; its call fails with
+// pluginsdk.CodeCanceled and the task ends as TaskCanceled
+func (pm *PluginManager) CancelTask(id, reason string) error {
+	pm.tasks.mu.Lock()
+	task, ok := pm.tasks.tasks[id]
+	if !ok {
+		pm.tasks.mu.Unlock()
+		return pluginsdk.NewError(pluginsdk.CodeNotFound, "no task %q", id)
+	}
+	if task.State != TaskRunning {
+		pm.tasks.mu.Unlock()
+		return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "task %s is %s", id, task.State)
+	}
+	if reason == "" {
+		reason = "canceled"
+	}
+	if pm.tasks.canceled == nil {
+		pm.tasks.canceled = make(map[string]string)
+	}
+	pm.tasks.canceled[id] = reason
+	pm.tasks.mu.Unlock()
+
+	pm.CancelRequest(id, reason)
+	return nil
+}
+
+func (h *pluginHost) ReportProgress(requestID string, percent float64, message string) error {
+	caller, _ := h.pm.callerOf(h.path)
+	if _, err := h.pm.requests.get(requestID, caller); err != nil {
+		return err
+	}
+	return h.pm.reportProgress(caller, requestID, percent, message)
+}
+
+// reportProgress records the progress a plugin serving the request of a
+// task reported; progress of requests that are no task is dropped
+func (pm *PluginManager) reportProgress(plugin, id string, percent float64, message string) error {
+	if percent < 0 || percent > 100 {
+		return pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "progress must be between 0 and 100 percent, got %v", percent)
+	}
+	pm.tasks.mu.Lock()
+	task, ok := pm.tasks.tasks[id]
+	if !ok || task.State != TaskRunning {
+		pm.tasks.mu.Unlock()
+		return nil
+	}
+	task.Percent, task.Message, task.Updated = percent, message, time.Now()
+	name := task.Plugin
+	pm.tasks.mu.Unlock()
+
+	pm.publishFrom(plugin, Event{Type: EventTaskProgress, Plugin: name, Data: map[string]interface{}{
+		"task_id":  id,
+		"percent":  percent,
+		"message":  message,
+		"reporter": plugin,
+	}})
+	return nil
+}
+
+// interruptTasks marks the running tasks interrupted as the host shuts
+// down
+func (pm *PluginManager) interruptTasks() {
+	pm.tasks.mu.Lock()
+	now := time.Now()
+	n := 0
+	for _, task := range pm.tasks.tasks {
+		if task.State == TaskRunning {
+			task.State, task.Error = TaskInterrupted, "the host shut down while the task ran"
+			task.Updated, task.Finished = now, &now
+			n++
+		}
+	}
+	pm.tasks.mu.Unlock()
+	if n > 0 {
+		pm.hostLog.Printf("Interrupted %d running task(s)", n)
+		pm.saveTasks()
+	}
+}
+
+// applyTasks reads the task file of cfg when it changed, keeping the tasks
+// already known. Tasks it lists as running were interrupted by the host
+// stopping.
+func (pm *PluginManager) applyTasks(cfg TaskConfig) error {
+	pm.tasks.mu.Lock()
+	defer pm.tasks.mu.Unlock()
+
+	if cfg.Path == "" || cfg.Path == pm.tasks.loaded {
+		return nil
+	}
+	pm.tasks.loaded = cfg.Path
+	data, err := os.ReadFile(cfg.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read tasks: %w", err)
+	}
+	var file taskFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid tasks %s: %w", cfg.Path, err)
+	}
+	if pm.tasks.tasks == nil {
+		pm.tasks.tasks = make(map[string]*Task)
+	}
+	for _, task := range file.Tasks {
+		if _, known := pm.tasks.tasks[task.ID]; known || task.ID == "" {
+			continue
+		}
+		if task.State == TaskRunning {
+			finished := file.SavedAt
+			task.State, task.Error = TaskInterrupted, "the host stopped while the task ran"
+			task.Finished = &finished
+		}
+		pm.tasks.tasks[task.ID] = task
+	}
+	return nil
+}
+
+// saveTasks drops finished tasks past their retention and writes the rest
+// to the task file, if one is configured. Progress is saved with the next
+// change of a task's state.
+func (pm *PluginManager) saveTasks() {
+	cfg := pm.Config().Tasks
+	pm.tasks.saveMu.Lock()
+	defer pm.tasks.saveMu.Unlock()
+
+	pm.tasks.mu.Lock()
+	cutoff := time.Now().Add(-cfg.retention())
+	file := taskFile{SavedAt: time.Now()}
+	for id, task := range pm.tasks.tasks {
+		if task.Finished != nil && task.Finished.Before(cutoff) {
+			delete(pm.tasks.tasks, id)
+			continue
+		}
+		t := *task
+		file.Tasks = append(file.Tasks, &t)
+	}
+	pm.tasks.mu.Unlock()
+	if cfg.Path == "" {
+		return
+	}
+
+	sort.Slice(file.Tasks, func(i, j int) bool {
+		return file.Tasks[i].Created.Before(file.Tasks[j].Created)
+	})
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(cfg.Path, data)
+	}
+	if err != nil {
+		pm.hostLog.Printf("Failed to save tasks: %v", err)
+	}
+}
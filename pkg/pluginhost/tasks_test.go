@@ -0,0 +1,59 @@
+package pluginhost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestTaskPrincipalComesFromContext(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Authorization = AuthorizationConfig{
+		Enabled:  true,
+		Roles:    map[string]Role{"all": {Allow: []string{"*"}}},
+		Bindings: map[string][]string{"service:billing": {"all"}},
+	}
+	pm, err := New(WithConfig(cfg), WithBuiltin(&sessionPlugin{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+
+	claimed := map[string]interface{}{pluginsdk.ArgContext: map[string]interface{}{"user": "billing", "service": true}}
+	tests := []struct {
+		name  string
+		start func() (string, error)
+		want  string
+	}{
+		{"claimed in args", func() (string, error) { return pm.StartTask("sessions", "echo", claimed) }, TaskFailed},
+		{"given as context", func() (string, error) {
+			return pm.StartTaskWithContext("sessions", "echo", nil, pluginsdk.RequestContext{User: "billing", Service: true})
+		}, TaskSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := tt.start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				task, err := pm.GetTask(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if task.State != TaskRunning {
+					if task.State != tt.want {
+						t.Errorf("state = %s (%s), want %s", task.State, task.Error, tt.want)
+					}
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("task did not finish")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package pluginhost
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// templateExt is the extension of prompt templates. A template is named by
+// its path below the template directory without the extension, so
+// templates/commands/analyze.tmpl is "commands/analyze".
+const templateExt = ".tmpl"
+
+// templateStore holds the parsed prompt templates. It parses the directory
+// again when a file is added, removed or modified, so prompts can be edited
+// while the host runs.
+type templateStore struct {
+	dir   string
+	stamp string
+	set   *template.Template
+	mu    sync.Mutex
+}
+
+// templates returns the parsed templates in dir, reparsing them if they
+// changed since the last call
+func (s *templateStore) templates(dir string, funcs template.FuncMap) (*template.Template, error) {
+	stamp, err := templateStamp(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.set != nil && s.dir == dir && s.stamp == stamp {
+		return s.set, nil
+	}
+	set, err := parseTemplates(dir, funcs)
+	if err != nil {
+		return nil, err
+	}
+	s.dir, s.stamp, s.set = dir, stamp, set
+	return set, nil
+}
+
+// templateStamp summarizes the template files in dir so changes can be
+// detected without parsing them. A missing directory has no templates.
+func templateStamp(dir string) (string, error) {
+	var b strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != templateExt {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return b.String(), err
+}
+
+// parseTemplates parses every template below dir into one set, so templates
+// can include each other
+func parseTemplates(dir string, funcs template.FuncMap) (*template.Template, error) {
+	set := template.New("").Funcs(funcs)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != templateExt {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(strings.TrimSuffix(rel, templateExt))
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := set.New(name).Parse(string(data)); err != nil {
+			return fmt.Errorf("failed to parse template %s: %w", path, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// templateFuncs are the functions available in prompt templates in addition
+// to the text/template builtins
+func (pm *PluginManager) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"trim":  strings.TrimSpace,
+		"join": func(sep string, items interface{}) string {
+			switch items := items.(type) {
+			case []string:
+				return strings.Join(items, sep)
+			case []interface{}:
+				parts := make([]string, len(items))
+				for i, item := range items {
+					parts[i] = fmt.Sprint(item)
+				}
+				return strings.Join(parts, sep)
+			}
+			return fmt.Sprint(items)
+		},
+		"indent": func(n int, s string) string {
+			pad := strings.Repeat(" ", n)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"default": func(def, v interface{}) interface{} {
+			if v == nil || v == "" {
+				return def
+			}
+			return v
+		},
+		"json": func(v interface{}) (string, error) {
+			b, err := json.MarshalIndent(v, "", "  ")
+			return string(b), err
+		},
+		"now": func() string {
+			return time.Now().Format(time.RFC3339)
+		},
+		// include renders another template by a name computed at run time
+		"include": func(name string, data interface{}) (string, error) {
+			return pm.render(name, data)
+		},
+		// persona renders the prompt template of a configured persona
+		"persona": func(name string) (string, error) {
+			return pm.RenderPersona(name, nil)
+		},
+	}
+}
+
+// RenderTemplate renders the prompt template name, e.g. "commands/analyze",
+// with data. It is the host side of pluginsdk.HostServices.
+func (pm *PluginManager) RenderTemplate(name string, data map[string]interface{}) (string, error) {
+	return pm.render(name, data)
+}
+
+// RenderPersona renders the prompt template of a configured persona. The
+// template sees the persona as .Persona next to data.
+func (pm *PluginManager) RenderPersona(persona string, data map[string]interface{}) (string, error) {
+	p, ok := pm.Config().Personas[persona]
+	if !ok {
+		return "", pluginsdk.NewError(pluginsdk.CodeNotFound, "persona %q is not configured", persona)
+	}
+	name := p.Template
+	if name == "" {
+		name = "personas/" + persona
+	}
+
+	merged := map[string]interface{}{
+		"Persona": map[string]interface{}{
+			"Name":        persona,
+			"Description": p.Description,
+			"Plugins":     p.Plugins,
+		},
+	}
+	for k, v := range data {
+		merged[k] = v
+	}
+	return pm.render(name, merged)
+}
+
+func (pm *PluginManager) render(name string, data interface{}) (string, error) {
+	set, err := pm.prompts.templates(pm.Config().TemplateDir, pm.templateFuncs())
+	if err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeInternal, "failed to load templates: %v", err)
+	}
+	t := set.Lookup(name)
+	if t == nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeNotFound, "template %q not found", name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", pluginsdk.NewError(pluginsdk.CodeInvalidArgument, "failed to render template %s: %v", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,126 @@
+package pluginhost
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DefaultCallTimeout bounds a call when neither the host config, the
+// plugin manifest nor the call sets a timeout
+const DefaultCallTimeout = 5 * time.Minute
+
+// TimeoutConfig sets the host's call timeouts. A call is bounded by the
+// first of: its pluginsdk.ArgTimeout argument, the capability's timeout in
+// the plugin manifest, the manifest's timeout, the timeout the capability's
+// latency or cost hint implies, and Default. Retries count against the same
+// timeout.
+type TimeoutConfig struct {
+	// Default bounds calls the plugin and the caller set no timeout for;
+	// defaults to DefaultCallTimeout
+	Default Duration `json:"default"`
+
+	// Max caps every timeout, including those of manifests and calls;
+	// zero means no cap
+	Max Duration `json:"max"`
+
+	// LatencyMultiple bounds calls of capabilities declaring a typical
+	// latency by that many times it; defaults to DefaultLatencyMultiple
+	LatencyMultiple float64 `json:"latency_multiple"`
+
+	// ByCost bounds calls of capabilities declaring a cost class but no
+	// latency, e.g. {"cheap": "30s", "expensive": "30m"}
+	ByCost map[string]Duration `json:"by_cost"`
+}
+
+func (c *TimeoutConfig) validate() error {
+	if c.Default < 0 || c.Max < 0 {
+		return fmt.Errorf("timeouts.default and timeouts.max must not be negative")
+	}
+	if c.Max > 0 && c.Default > c.Max {
+		return fmt.Errorf("timeouts.default must not exceed timeouts.max")
+	}
+	return c.validateHints()
+}
+
+// callTimeout picks the timeout of a call and returns the arguments without
+// pluginsdk.ArgTimeout. The caller holds pm.mu.
+func (pm *PluginManager) callTimeout(info *pluginInfo, args map[string]interface{}) (time.Duration, map[string]interface{}, error) {
+	cfg := pm.config.Timeouts
+	timeout := time.Duration(cfg.Default)
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	if info.Timeout > 0 {
+		timeout = time.Duration(info.Timeout)
+	} else if d := cfg.hinted(info, capability); d > 0 {
+		timeout = d
+	}
+	if d := info.CapabilityTimeouts[capability]; d > 0 {
+		timeout = time.Duration(d)
+	}
+
+	if v, ok := args[pluginsdk.ArgTimeout]; ok {
+		d, err := pluginsdk.ParseTimeout(v)
+		if err != nil {
+			return 0, nil, err
+		}
+		timeout = d
+		stripped := make(map[string]interface{}, len(args))
+		for k, v := range args {
+			if k != pluginsdk.ArgTimeout {
+				stripped[k] = v
+			}
+		}
+		args = stripped
+	}
+
+	if cfg.Max > 0 && timeout > time.Duration(cfg.Max) {
+		timeout = time.Duration(cfg.Max)
+	}
+	return timeout, args, nil
+}
+
+// invokeContext runs a call until ctx ends. Plugins served over gRPC get
+// the deadline; for the others the host stops waiting and the late result
+// is dropped.
+func invokeContext(ctx context.Context, instance pluginsdk.CommandPlugin, args map[string]interface{}) (result string, err error) {
+	defer recoverPanic(&err)
+	if c, ok := instance.(pluginsdk.ContextPlugin); ok && !pluginsdk.DryRun(args) {
+		return c.ExecuteContext(ctx, args)
+	}
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		var o outcome
+		defer func() { done <- o }()
+		defer recoverPanic(&o.err)
+		o.result, o.err = invoke(instance, args)
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// errTimeout reports a call that ran out of time
+func errTimeout(name string, timeout time.Duration) error {
+	return &pluginsdk.PluginError{
+		Code:    pluginsdk.CodeTimeout,
+		Message: fmt.Sprintf("plugin %s did not answer within %v", name, timeout),
+	}
+}
+
+// isTimeout reports whether err is a call running out of time
+func isTimeout(err error) bool {
+	return err != nil && pluginsdk.AsPluginError(err).Code == pluginsdk.CodeTimeout
+}
@@ -0,0 +1,276 @@
+package pluginhost
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// TraceConfig records every call with its full arguments and result, so
+// the calls can be replayed later with Replay. Recording is off unless Path
+// is set, as traces hold everything plugins were sent and returned.
+type TraceConfig struct {
+	// Path is the trace file; entries are appended as JSON lines
+	Path string `json:"path"`
+}
+
+// TraceEntry is one recorded call. Environment variables are recorded by
+// name only.
+type TraceEntry struct {
+	Time       time.Time              `json:"time"`
+	Plugin     string                 `json:"plugin"`
+	Version    string                 `json:"version,omitempty"`
+	Capability string                 `json:"capability,omitempty"`
+	Args       map[string]interface{} `json:"args"`
+	Result     string                 `json:"result,omitempty"`
+	Error      *pluginsdk.PluginError `json:"error,omitempty"`
+	Duration   time.Duration          `json:"duration_ns"`
+}
+
+// traceWriter appends entries to a trace file
+type traceWriter struct {
+	path string
+	f    *os.File
+	mu   sync.Mutex
+}
+
+func openTrace(path string) (*traceWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace %s: %w", path, err)
+	}
+	return &traceWriter{path: path, f: f}, nil
+}
+
+func (t *traceWriter) write(e TraceEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err = t.f.Write(append(data, '\n'))
+	return err
+}
+
+func (t *traceWriter) close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.f.Close()
+}
+
+// applyTrace starts, switches or stops recording to match cfg
+func (pm *PluginManager) applyTrace(cfg TraceConfig) error {
+	pm.mu.RLock()
+	current := pm.trace
+	pm.mu.RUnlock()
+
+	if current != nil && current.path == cfg.Path {
+		return nil
+	}
+
+	var next *traceWriter
+	if cfg.Path != "" {
+		var err error
+		if next, err = openTrace(cfg.Path); err != nil {
+			return err
+		}
+		pm.hostLog.Printf("Recording calls to trace %s", cfg.Path)
+	}
+
+	pm.mu.Lock()
+	pm.trace = next
+	pm.mu.Unlock()
+
+	if current != nil {
+		if err := current.close(); err != nil {
+			pm.hostLog.Printf("Failed to close trace %s: %v", current.path, err)
+		}
+	}
+	return nil
+}
+
+// recordTrace appends a finished call to the trace, if one is recorded,
+// with args as recordArgs returned them. The caller holds pm.mu.
+func (pm *PluginManager) recordTrace(info *pluginInfo, args map[string]interface{}, start time.Time, elapsed time.Duration, result string, err error) {
+	if pm.trace == nil {
+		return
+	}
+
+	capability, _ := args[pluginsdk.ArgCapability].(string)
+	e := TraceEntry{
+		Time:       start,
+		Plugin:     info.Name,
+		Version:    info.Version,
+		Capability: capability,
+		Args:       args,
+		Result:     result,
+		Duration:   elapsed,
+	}
+	if err != nil {
+		e.Error = pluginsdk.AsPluginError(err)
+	}
+	if err := pm.trace.write(e); err != nil {
+		pm.hostLog.Printf("Failed to record trace for %s: %v", info.Name, err)
+	}
+}
+
+// ReadTrace reads the entries of a trace file
+func ReadTrace(path string) ([]TraceEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer f.Close()
+
+	var entries []TraceEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, pluginsdk.DefaultMaxMessageBytes*4)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e TraceEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid trace %s, line %d: %w", path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// ReplayResult compares a recorded call with the same call made again
+type ReplayResult struct {
+	Entry TraceEntry `json:"entry"`
+
+	// Version is the plugin version that answered the replayed call
+	Version string `json:"version,omitempty"`
+
+	Result string                 `json:"result,omitempty"`
+	Error  *pluginsdk.PluginError `json:"error,omitempty"`
+
+	// Match is true when result and error code are what was recorded
+	Match bool `json:"match"`
+
+	// Diff shows how the results differ, line by line
+	Diff string `json:"diff,omitempty"`
+
+	// Skipped says why the call was not compared, e.g. a spilled result
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Replay makes every recorded call again against the plugins loaded now
+// and compares the results with the recorded ones, e.g. to check that an
+// upgraded plugin still answers as before. Calls are made in order through
+// ExecutePlugin, so rate limits and the cache apply.
+func (pm *PluginManager) Replay(entries []TraceEntry) []ReplayResult {
+	results := make([]ReplayResult, 0, len(entries))
+	for _, e := range entries {
+		r := ReplayResult{Entry: e}
+		if _, spilled := pluginsdk.ResultFile(e.Result); spilled {
+			r.Skipped = "recorded result was spilled to a file"
+			results = append(results, r)
+			continue
+		}
+		if hasRedacted(e.Args) {
+			r.Skipped = "recorded arguments were redacted"
+			results = append(results, r)
+			continue
+		}
+
+		result, err := pm.executeRaw(e.Plugin, e.Args)
+		if st, serr := pm.GetPlugin(e.Plugin); serr == nil {
+			r.Version = st.Version
+		}
+		if path, spilled := pm.ResultFile(result); spilled {
+			data, rerr := os.ReadFile(path)
+			os.Remove(path)
+			if rerr != nil {
+				r.Skipped = fmt.Sprintf("replayed result was spilled and cannot be read: %v", rerr)
+				results = append(results, r)
+				continue
+			}
+			result = string(data)
+		}
+		r.Result = result
+		if err != nil {
+			r.Error = pluginsdk.AsPluginError(err)
+		}
+		r.Match, r.Diff = compareReplay(e, r)
+		results = append(results, r)
+	}
+	return results
+}
+
+// compareReplay reports whether a replayed call answered as recorded, and
+// how it differs if not
+func compareReplay(e TraceEntry, r ReplayResult) (bool, string) {
+	var want, got string
+	switch {
+	case e.Error != nil && r.Error != nil:
+		if e.Error.Code == r.Error.Code {
+			return true, ""
+		}
+		want, got = "error "+string(e.Error.Code)+": "+e.Error.Message, "error "+string(r.Error.Code)+": "+r.Error.Message
+	case e.Error != nil:
+		want, got = "error "+string(e.Error.Code)+": "+e.Error.Message, r.Result
+	case r.Error != nil:
+		want, got = e.Result, "error "+string(r.Error.Code)+": "+r.Error.Message
+	default:
+		if e.Result == r.Result {
+			return true, ""
+		}
+		want, got = e.Result, r.Result
+	}
+	return false, lineDiff(want, got)
+}
+
+// lineDiff shows the lines only in want with "-" and those only in got with
+// "+", in order, with the common lines between them unmarked
+func lineDiff(want, got string) string {
+	a, b := strings.Split(want, "\n"), strings.Split(got, "\n")
+
+	// lcs[i][j] is the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		default:
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		}
+	}
+	return sb.String()
+}
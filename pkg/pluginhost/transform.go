@@ -0,0 +1,260 @@
+package pluginhost
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Transformer post-processes the result of a call before it reaches the
+// caller, e.g. to render markdown or redact secrets
+type Transformer interface {
+	Transform(result string, call TransformCall) (string, error)
+}
+
+// TransformerFunc adapts a function to Transformer
+type TransformerFunc func(result string, call TransformCall) (string, error)
+
+// Transform calls f
+func (f TransformerFunc) Transform(result string, call TransformCall) (string, error) {
+	return f(result, call)
+}
+
+// TransformCall describes the call whose result is transformed
+type TransformCall struct {
+	Plugin     string
+	Capability string
+	Args       map[string]interface{}
+}
+
+// Names of the built-in transformers
+const (
+	// TransformMarkdown renders markdown as plain terminal text
+	TransformMarkdown = "markdown"
+
+	// TransformStripANSI removes terminal escape sequences
+	TransformStripANSI = "strip_ansi"
+
+	// TransformTruncate cuts results to transforms.truncate_at bytes
+	TransformTruncate = "truncate"
+
+	// TransformRedactSecrets replaces the secrets the host resolved for
+	// plugins, and strings shaped like API keys and private keys
+	TransformRedactSecrets = "redact_secrets"
+)
+
+// DefaultTruncateAt is the length truncate cuts results to when
+// transforms.truncate_at is not set
+const DefaultTruncateAt = 16000
+
+// redacted replaces each secret redact_secrets finds
+const redacted = "[REDACTED]"
+
+// TransformConfig chooses the transformers results pass through, by name:
+// the built-in ones or those added with WithTransformer
+type TransformConfig struct {
+	// Global transform every result, after the capability's transformers
+	Global []string `json:"global"`
+
+	// Capabilities maps capability names to their transformers
+	Capabilities map[string][]string `json:"capabilities"`
+
+	// TruncateAt is the length in bytes truncate cuts results to;
+	// defaults to DefaultTruncateAt
+	TruncateAt int `json:"truncate_at"`
+}
+
+func (c *TransformConfig) validate() error {
+	if c.TruncateAt < 0 {
+		return fmt.Errorf("transforms.truncate_at must not be negative")
+	}
+	return nil
+}
+
+// checkTransforms reports transformers cfg names that do not exist
+func (pm *PluginManager) checkTransforms(cfg TransformConfig) error {
+	names := append([]string(nil), cfg.Global...)
+	for _, list := range cfg.Capabilities {
+		names = append(names, list...)
+	}
+	for _, name := range names {
+		if pm.transformer(name) == nil {
+			return fmt.Errorf("unknown transformer %q", name)
+		}
+	}
+	return nil
+}
+
+// transformer returns the transformer registered under name, or the
+// built-in one; nil if there is none
+func (pm *PluginManager) transformer(name string) Transformer {
+	if t, ok := pm.transformers[name]; ok {
+		return t
+	}
+	switch name {
+	case TransformMarkdown:
+		return TransformerFunc(renderMarkdown)
+	case TransformStripANSI:
+		return TransformerFunc(stripANSI)
+	case TransformTruncate:
+		return TransformerFunc(pm.truncate)
+	case TransformRedactSecrets:
+		return TransformerFunc(pm.redactSecrets)
+	}
+	return nil
+}
+
+// transform passes the result of a call through the transformers of its
+// capability, then the global ones. Results spilled to a file are left
+// as they are.
+func (pm *PluginManager) transform(name string, args map[string]interface{}, result string) (string, error) {
+	if _, spilled := pluginsdk.ResultFile(result); spilled {
+		return result, nil
+	}
+	ref, _ := args[pluginsdk.ArgCapability].(string)
+	capability, _ := pluginsdk.ParseCapabilityRef(ref)
+
+	cfg := pm.Config().Transforms
+	names := append(append([]string(nil), cfg.Capabilities[capability]...), cfg.Global...)
+	if len(names) == 0 {
+		return result, nil
+	}
+	call := TransformCall{Plugin: name, Capability: capability, Args: args}
+	for _, tn := range names {
+		t := pm.transformer(tn)
+		if t == nil {
+			continue
+		}
+		var err error
+		if result, err = t.Transform(result, call); err != nil {
+			return "", fmt.Errorf("transformer %s failed on result of %s: %w", tn, name, err)
+		}
+	}
+	return result, nil
+}
+
+// truncate cuts a result to transforms.truncate_at bytes, on a character
+// boundary, and says how much was left out
+func (pm *PluginManager) truncate(result string, _ TransformCall) (string, error) {
+	limit := pm.Config().Transforms.TruncateAt
+	if limit == 0 {
+		limit = DefaultTruncateAt
+	}
+	if len(result) <= limit {
+		return result, nil
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(result[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n… [truncated %d of %d bytes]", result[:cut], len(result)-cut, len(result)), nil
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+func stripANSI(result string, _ TransformCall) (string, error) {
+	return ansiEscape.ReplaceAllString(result, ""), nil
+}
+
+// secretPatterns match common credentials by their shape
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+}
+
+// minRedactedSecret keeps short secret values, which would match ordinary
+// text, from being redacted
+const minRedactedSecret = 6
+
+func (pm *PluginManager) redactSecrets(result string, _ TransformCall) (string, error) {
+	// Longer values first, so a secret containing another is replaced whole
+	var values []string
+	pm.revealed.Range(func(k, _ interface{}) bool {
+		if v := k.(string); len(v) >= minRedactedSecret {
+			values = append(values, v)
+		}
+		return true
+	})
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, v := range values {
+		result = strings.ReplaceAll(result, v, redacted)
+	}
+	for _, re := range secretPatterns {
+		result = re.ReplaceAllString(result, redacted)
+	}
+	return result, nil
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdQuote   = regexp.MustCompile(`^\s*>\s?`)
+	mdRule    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	mdImage   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)[^)]*\)`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	mdBold    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalic  = regexp.MustCompile(`\*([^*\s][^*]*)\*`)
+	mdCode    = regexp.MustCompile("`([^`]+)`")
+)
+
+// renderMarkdown turns markdown into plain text for terminals: headings
+// are underlined, list bullets and quotes drawn, code blocks indented and
+// inline markup removed
+func renderMarkdown(result string, _ TransformCall) (string, error) {
+	var out []string
+	inCode := false
+	for _, line := range strings.Split(result, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			out = append(out, "    "+line)
+			continue
+		}
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			text := renderInline(m[2])
+			out = append(out, text)
+			if len(m[1]) <= 2 {
+				underline := "="
+				if len(m[1]) == 2 {
+					underline = "-"
+				}
+				out = append(out, strings.Repeat(underline, utf8.RuneCountInString(text)))
+			}
+			continue
+		}
+		if mdRule.MatchString(line) {
+			out = append(out, strings.Repeat("─", 40))
+			continue
+		}
+		line = mdQuote.ReplaceAllString(line, "│ ")
+		line = mdBullet.ReplaceAllString(line, "$1• ")
+		out = append(out, renderInline(line))
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+func renderInline(s string) string {
+	// Code spans are kept verbatim, so markup inside them is not touched
+	var spans []string
+	s = mdCode.ReplaceAllStringFunc(s, func(m string) string {
+		spans = append(spans, m[1:len(m)-1])
+		return fmt.Sprintf("\x00%d\x00", len(spans)-1)
+	})
+	s = mdImage.ReplaceAllString(s, "$1 ($2)")
+	s = mdLink.ReplaceAllString(s, "$1 ($2)")
+	s = mdBold.ReplaceAllString(s, "$1$2")
+	s = mdItalic.ReplaceAllString(s, "$1")
+	for i, span := range spans {
+		s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), span, 1)
+	}
+	return s
+}
@@ -0,0 +1,207 @@
+package pluginhost
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// TransportConfig bounds the messages exchanged with plugin processes, so a
+// plugin returning a huge result cannot exhaust the host's memory. Changes
+// apply to plugins started afterwards.
+type TransportConfig struct {
+	// MaxRequestBytes limits the encoded arguments of a call; the default
+	// is pluginsdk.DefaultMaxMessageBytes
+	MaxRequestBytes int `json:"max_request_bytes"`
+
+	// MaxResponseBytes limits a plugin's encoded response; the default is
+	// pluginsdk.DefaultMaxMessageBytes
+	MaxResponseBytes int `json:"max_response_bytes"`
+
+	// SpillBytes is the result size above which plugins write the result
+	// to a file in the host's spill directory and return its path instead;
+	// the default is MaxResponseBytes, spilling only what could not be sent
+	SpillBytes int `json:"spill_bytes"`
+
+	// SpillDir is where the host creates its spill directory; the default
+	// is the system's temporary directory. Plugins may only spill to the
+	// directory the host created.
+	SpillDir string `json:"spill_dir"`
+
+	// Codecs are the argument encodings offered to plugins, most preferred
+	// first; the default is pluginsdk.DefaultCodecs. A plugin supporting
+	// none of them keeps its transport's encoding.
+	Codecs []string `json:"codecs"`
+
+	// HandoffBytes is the encoded argument size from which arguments are
+	// handed to plugins through a file in HandoffDir rather than in the
+	// request; 0 sends all arguments in the request. Handed-off arguments
+	// need a negotiated codec, and MaxRequestBytes does not bound them.
+	HandoffBytes int `json:"handoff_bytes"`
+
+	// HandoffDir receives handed-off arguments; the default is
+	// pluginsdk.DefaultHandoffDir, in shared memory on Linux
+	HandoffDir string `json:"handoff_dir"`
+}
+
+func (c *TransportConfig) validate() error {
+	if c.MaxRequestBytes < 0 || c.MaxResponseBytes < 0 || c.SpillBytes < 0 || c.HandoffBytes < 0 {
+		return fmt.Errorf("transport sizes must not be negative")
+	}
+	if c.SpillBytes > c.maxResponse() {
+		return fmt.Errorf("transport.spill_bytes must not exceed the response limit of %d bytes", c.maxResponse())
+	}
+	for _, name := range c.Codecs {
+		if _, ok := pluginsdk.LookupCodec(name); !ok {
+			return fmt.Errorf("transport.codecs: unknown codec %q, want one of %v", name, pluginsdk.Codecs())
+		}
+	}
+	return nil
+}
+
+// handoffDir returns the directory arguments are handed off in
+func (c *TransportConfig) handoffDir() string {
+	if c.HandoffDir != "" {
+		return c.HandoffDir
+	}
+	return pluginsdk.DefaultHandoffDir()
+}
+
+// codecs returns the codecs offered to plugins
+func (c *TransportConfig) codecs() []string {
+	if len(c.Codecs) > 0 {
+		return c.Codecs
+	}
+	return pluginsdk.DefaultCodecs
+}
+
+func (c *TransportConfig) maxRequest() int {
+	if c.MaxRequestBytes > 0 {
+		return c.MaxRequestBytes
+	}
+	return pluginsdk.DefaultMaxMessageBytes
+}
+
+func (c *TransportConfig) maxResponse() int {
+	if c.MaxResponseBytes > 0 {
+		return c.MaxResponseBytes
+	}
+	return pluginsdk.DefaultMaxMessageBytes
+}
+
+// env passes the limits to a plugin process, which enforces them on its
+// side and spills large results to spills
+func (c *TransportConfig) env(spills *pluginsdk.SpillStore) []string {
+	return []string{
+		pluginsdk.EnvMaxRequestBytes + "=" + strconv.Itoa(c.maxRequest()),
+		pluginsdk.EnvMaxResponseBytes + "=" + strconv.Itoa(c.maxResponse()),
+		pluginsdk.EnvSpillBytes + "=" + strconv.Itoa(c.spill()),
+		pluginsdk.EnvSpillDir + "=" + spills.Dir(),
+	}
+}
+
+func (c *TransportConfig) spill() int {
+	if c.SpillBytes > 0 {
+		return c.SpillBytes
+	}
+	return c.maxResponse()
+}
+
+// dialOptions enforces the limits on the host's end of gRPC connections,
+// for plugins that do not. Oversized messages fail with CodeTooLarge.
+func (c *TransportConfig) dialOptions() []grpc.DialOption {
+	return []grpc.DialOption{grpc.WithDefaultCallOptions(
+		grpc.MaxCallSendMsgSize(c.maxRequest()),
+		grpc.MaxCallRecvMsgSize(c.maxResponse()),
+	)}
+}
+
+// handoffStores holds the handoff store of each directory and threshold
+// configured, so plugins started before a config change keep theirs
+type handoffStores struct {
+	mu     sync.Mutex
+	stores map[string]*pluginsdk.HandoffStore
+}
+
+// store returns the store for the handoff settings of c
+func (h *handoffStores) store(c *TransportConfig) *pluginsdk.HandoffStore {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := c.handoffDir() + "\x00" + strconv.Itoa(c.HandoffBytes)
+	if s, ok := h.stores[key]; ok {
+		return s
+	}
+	if h.stores == nil {
+		h.stores = make(map[string]*pluginsdk.HandoffStore)
+	}
+	s := pluginsdk.NewHandoffStore(c.handoffDir(), c.HandoffBytes)
+	h.stores[key] = s
+	return s
+}
+
+// close removes the files of all stores
+func (h *handoffStores) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, s := range h.stores {
+		s.Close()
+		delete(h.stores, key)
+	}
+}
+
+// spillStores holds the spill store of each directory configured, so
+// results of plugins started before a config change can still be taken
+type spillStores struct {
+	mu     sync.Mutex
+	stores map[string]*pluginsdk.SpillStore
+}
+
+// store returns the store for the spill directory of c
+func (h *spillStores) store(c *TransportConfig) (*pluginsdk.SpillStore, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s, ok := h.stores[c.SpillDir]; ok {
+		return s, nil
+	}
+	s, err := pluginsdk.NewSpillStore(c.SpillDir)
+	if err != nil {
+		return nil, err
+	}
+	if h.stores == nil {
+		h.stores = make(map[string]*pluginsdk.SpillStore)
+	}
+	h.stores[c.SpillDir] = s
+	return s, nil
+}
+
+// take returns the file of a result a plugin spilled, which the caller
+// then owns
+func (h *spillStores) take(result string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, s := range h.stores {
+		if path, ok := s.Take(result); ok {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// close removes the directories of all stores
+func (h *spillStores) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, s := range h.stores {
+		s.Close()
+		delete(h.stores, key)
+	}
+}
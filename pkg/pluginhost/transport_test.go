@@ -0,0 +1,78 @@
+package pluginhost
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+func TestSpilledResults(t *testing.T) {
+	pm, err := New(WithPluginDirs(t.TempDir()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pm.Shutdown()
+	store, err := pm.spills.store(&TransportConfig{SpillDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spilled := filepath.Join(store.Dir(), "opencode-result-1")
+	if err := os.WriteFile(spilled, []byte("result"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(store.Dir(), "opencode-result-link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(store.Dir(), "sub")
+	if err := os.Symlink(filepath.Dir(outside), sub); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{"outside the spill directory", outside, true},
+		{"climbing out", filepath.Join(store.Dir(), "..", filepath.Base(filepath.Dir(outside)), "secret"), true},
+		{"relative", "opencode-result-1", true},
+		{"the spill directory", store.Dir(), true},
+		{"symbolic link", link, true},
+		{"through a linked directory", filepath.Join(sub, "secret"), true},
+		{"missing", filepath.Join(store.Dir(), "missing"), true},
+		{"spilled", spilled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := store.Claim(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Claim error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if path, ok := pm.ResultFile(result); !ok || path != spilled {
+				t.Errorf("ResultFile = %q, %v; want %q", path, ok, spilled)
+			}
+			if _, ok := pm.ResultFile(result); ok {
+				t.Error("ResultFile returned the file twice")
+			}
+		})
+	}
+
+	// Results that only look spilled are text
+	if _, ok := pm.ResultFile(pluginsdk.ResultFilePrefix + outside); ok {
+		t.Error("ResultFile accepted a path the plugin did not spill")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the spill directory is gone: %v", err)
+	}
+}
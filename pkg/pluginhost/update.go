@@ -0,0 +1,201 @@
+package pluginhost
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// UpdateResult is the outcome of updating one plugin from the registry
+// index
+type UpdateResult struct {
+	Plugin string `json:"plugin"`
+	From   string `json:"from"`
+
+	// To is the newer version the index offers; empty when the plugin is
+	// up to date
+	To string `json:"to,omitempty"`
+
+	// Updated is set once To serves the plugin's calls
+	Updated bool `json:"updated"`
+
+	// Error is why the update failed; set by UpdateAll only
+	Error string `json:"error,omitempty"`
+}
+
+// registry is a registry index as read for an update
+type registry struct {
+	client   *http.Client
+	location string
+	index    *RegistryIndex
+}
+
+// UpdatePlugin updates a plugin whose manifest sets updatable to the newest
+// compatible version in provision.index: one built for this host, of the
+// same major version unless upgrade.allow_major is set, and the pinned
+// version of pinned plugins. The binary is downloaded into a directory of
+// its own below provision.dir or the first plugin directory, its checksum
+// checked, and swapped in by Upgrade, which checks the lockfile and supply
+// chain, starts it and runs the smoke test before it takes calls. A failed
+// update leaves the plugin as it was. The previous binary is kept, so
+// Upgrade to its version rolls back.
+func (pm *PluginManager) UpdatePlugin(name string) (UpdateResult, error) {
+	pm.installMu.Lock()
+	defer pm.installMu.Unlock()
+
+	reg, err := pm.readRegistry()
+	if err != nil {
+		return UpdateResult{Plugin: name}, err
+	}
+	return pm.update(reg, name)
+}
+
+// UpdateAll updates every updatable plugin as UpdatePlugin does, one after
+// another, and returns the outcome of each by name. A plugin failing to
+// update does not stop the others; the error is for reading the index.
+func (pm *PluginManager) UpdateAll() ([]UpdateResult, error) {
+	pm.installMu.Lock()
+	defer pm.installMu.Unlock()
+
+	reg, err := pm.readRegistry()
+	if err != nil {
+		return nil, err
+	}
+	pm.mu.RLock()
+	var names []string
+	for name, info := range pm.plugins {
+		if info.Updatable && !info.builtin {
+			names = append(names, name)
+		}
+	}
+	pm.mu.RUnlock()
+	sort.Strings(names)
+
+	results := make([]UpdateResult, 0, len(names))
+	for _, name := range names {
+		res, err := pm.update(reg, name)
+		if err != nil {
+			pm.hostLog.Printf("Failed to update plugin %s: %v", name, err)
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// readRegistry reads the index updates come from
+func (pm *PluginManager) readRegistry() (*registry, error) {
+	prov := pm.Config().Provision
+	if prov.Index == "" {
+		return nil, fmt.Errorf("provision.index is not set, so there is no registry to update plugins from")
+	}
+	client := &http.Client{Timeout: prov.timeout()}
+	index, err := readIndex(client, prov.Index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry index: %w", err)
+	}
+	return &registry{client: client, location: prov.Index, index: index}, nil
+}
+
+// update moves a plugin to the newest compatible version of reg. The
+// caller holds pm.installMu.
+func (pm *PluginManager) update(reg *registry, name string) (UpdateResult, error) {
+	res := UpdateResult{Plugin: name}
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	var updatable, builtin bool
+	if exists {
+		res.From, updatable, builtin = info.Version, info.Updatable, info.builtin
+	}
+	pm.mu.RUnlock()
+	switch {
+	case !exists:
+		return res, pm.pluginNotFound(name)
+	case builtin:
+		return res, errBuiltin(name, "updated")
+	case !updatable:
+		return res, fmt.Errorf("plugin %s is not updatable; its manifest does not set updatable", name)
+	}
+
+	entry := pm.newestUpdate(reg.index, name, res.From)
+	if entry == nil {
+		return res, nil
+	}
+	res.To = entry.Version
+
+	dir, err := pm.updateDir(name, entry.Version)
+	if err != nil {
+		return res, err
+	}
+	_, statErr := os.Stat(dir)
+	created := os.IsNotExist(statErr)
+	discard := func() {
+		if created {
+			os.RemoveAll(dir)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return res, err
+	}
+	path := filepath.Join(dir, "plugin-"+name)
+	if err := fetchEntry(reg.client, pm.artifacts(), reg.location, *entry, path); err != nil {
+		discard()
+		return res, fmt.Errorf("failed to download plugin %s v%s: %w", name, entry.Version, err)
+	}
+	if err := pm.Upgrade(name, path); err != nil {
+		discard()
+		return res, err
+	}
+
+	res.Updated = true
+	pm.hostLog.Printf("Updated plugin %s from v%s to v%s from the registry index", name, res.From, res.To)
+	return res, nil
+}
+
+// newestUpdate returns the newest entry of index a plugin at version can
+// be updated to, or nil when there is none
+func (pm *PluginManager) newestUpdate(index *RegistryIndex, name, version string) *IndexEntry {
+	allowMajor := pm.Config().Upgrade.AllowMajor
+	var best *IndexEntry
+	for i, e := range index.Plugins {
+		if e.Name != name || !e.runsHere() || compareVersions(e.Version, version) <= 0 {
+			continue
+		}
+		if !allowMajor && majorVersion(e.Version) != majorVersion(version) {
+			continue
+		}
+		if pm.checkPin(name, e.Version) != nil {
+			continue
+		}
+		if best == nil || compareVersions(e.Version, best.Version) > 0 {
+			best = &index.Plugins[i]
+		}
+	}
+	return best
+}
+
+// updateDir returns the directory the binary of a plugin's version is
+// downloaded into, e.g. plugins/hello-1.2.0
+func (pm *PluginManager) updateDir(name, version string) (string, error) {
+	if err := checkPluginName(name + "-" + version); err != nil {
+		return "", fmt.Errorf("invalid version %q of plugin %s", version, name)
+	}
+	dir := pm.Config().Provision.Dir
+	if dir == "" {
+		dirs := pm.Config().PluginDirs
+		if len(dirs) == 0 {
+			return "", fmt.Errorf("provision.dir is not set and there is no plugin directory")
+		}
+		dir = dirs[0]
+	}
+	return filepath.Join(dir, name+"-"+version), nil
+}
+
+// majorVersion returns the first part of a version, e.g. "1" of "v1.4.2"
+func majorVersion(version string) string {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	return major
+}
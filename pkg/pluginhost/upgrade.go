@@ -0,0 +1,342 @@
+package pluginhost
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// EventPluginUpgraded is published when Upgrade has swapped a plugin to a
+// new binary
+const EventPluginUpgraded = "plugin.upgraded"
+
+// DefaultDrainTimeout is how long the old process of an upgraded plugin may
+// finish the calls it is serving when upgrade.drain_timeout is not set
+const DefaultDrainTimeout = 30 * time.Second
+
+// DefaultSmokeTestTimeout bounds a smoke test without its own timeout
+const DefaultSmokeTestTimeout = 10 * time.Second
+
+// UpgradeConfig controls Upgrade
+type UpgradeConfig struct {
+	// SmokeTests maps plugin names to the call a new binary must pass
+	// before it takes traffic; without one, a binary that starts and
+	// reports its metadata is accepted
+	SmokeTests map[string]SmokeTest `json:"smoke_tests"`
+
+	// DrainTimeout bounds how long the old process keeps serving calls in
+	// flight after the swap; defaults to DefaultDrainTimeout
+	DrainTimeout Duration `json:"drain_timeout"`
+
+	// AllowMajor lets UpdatePlugin move plugins to a new major version,
+	// which may change their capabilities incompatibly
+	AllowMajor bool `json:"allow_major"`
+}
+
+// SmokeTest is a capability invocation and the result it must produce
+type SmokeTest struct {
+	Capability string                 `json:"capability"`
+	Args       map[string]interface{} `json:"args"`
+
+	// Expect is the exact result the call must return
+	Expect string `json:"expect"`
+
+	// Contains is a substring the result must contain
+	Contains string `json:"contains"`
+
+	// Timeout bounds the call; defaults to DefaultSmokeTestTimeout
+	Timeout Duration `json:"timeout"`
+}
+
+func (c *UpgradeConfig) validate() error {
+	if c.DrainTimeout < 0 {
+		return fmt.Errorf("upgrade.drain_timeout must not be negative")
+	}
+	for name, t := range c.SmokeTests {
+		if t.Timeout < 0 {
+			return fmt.Errorf("upgrade.smoke_tests.%s.timeout must not be negative", name)
+		}
+		if t.Expect != "" && t.Contains != "" {
+			return fmt.Errorf("upgrade.smoke_tests.%s: set expect or contains, not both", name)
+		}
+	}
+	return nil
+}
+
+func (c *UpgradeConfig) drainTimeout() time.Duration {
+	if c.DrainTimeout > 0 {
+		return time.Duration(c.DrainTimeout)
+	}
+	return DefaultDrainTimeout
+}
+
+// Upgrade moves a plugin to a new binary without refusing calls. The
+// binary is pathOrVersion itself if that is a file, otherwise the binary in
+// a plugin directory, or a directory directly below one, whose manifest
+// names the plugin at version pathOrVersion. It is started next to the
+// running process and must pass the plugin's smoke test before calls are
+// routed to it; the old process then finishes the calls it is serving and
+// is stopped. A failed upgrade leaves the plugin as it was. Sessions end
+// with the old process.
+func (pm *PluginManager) Upgrade(name, pathOrVersion string) error {
+	pm.applyMu.Lock()
+	defer pm.applyMu.Unlock()
+
+	pm.mu.RLock()
+	info, exists := pm.plugins[name]
+	var from string
+	if exists {
+		from = info.Version
+	}
+	pm.mu.RUnlock()
+	if !exists {
+		return pm.pluginNotFound(name)
+	}
+	if info.builtin {
+		return errBuiltin(name, "upgraded")
+	}
+
+	path, err := pm.upgradeTarget(name, pathOrVersion)
+	if err != nil {
+		return err
+	}
+	if m := pm.pluginManifest(path); m != nil {
+		if err := pm.checkPin(name, m.Version); err != nil {
+			return err
+		}
+	}
+
+	// Start the new binary side by side with the running one
+	proc, err := pm.startProcess(path)
+	if err != nil {
+		return fmt.Errorf("failed to start %s for upgrade of plugin %s: %w", path, name, err)
+	}
+	if proc.name != info.reports() {
+		proc.client.Kill()
+		return fmt.Errorf("plugin at %s reports name %q, not %q", path, proc.name, info.reports())
+	}
+	md := fetchMetadata(proc.instance)
+	if err := pm.checkPin(name, md.version); err != nil {
+		proc.client.Kill()
+		return err
+	}
+	if err := pm.smokeTest(name, proc.instance); err != nil {
+		proc.client.Kill()
+		return fmt.Errorf("plugin %s v%s failed its smoke test: %w", name, md.version, err)
+	}
+
+	// Swap: calls from now on go to the new process
+	m := pm.pluginManifest(path)
+	pm.mu.Lock()
+	if pm.plugins[name] != info {
+		pm.mu.Unlock()
+		proc.client.Kill()
+		return fmt.Errorf("plugin %s was unloaded during the upgrade", name)
+	}
+	old := info.Client
+	extras := retirePool(info)
+	daemon := info.daemon
+	info.daemon = nil
+	pm.sessions.closePluginSessions(name)
+	info.Path = path
+	if m != nil {
+		info.Flags = m.Flags
+		info.Locales = m.Locales
+		info.Inject = m.Inject
+		info.Updatable = m.Updatable
+		info.Timeout, info.CapabilityTimeouts = m.Timeouts.Default, m.Timeouts.Capabilities
+		info.Calls = m.Permissions.calls()
+		info.Files = m.Permissions.files()
+		info.LLM = m.Permissions.llm()
+	}
+	pm.attach(info, proc)
+	pm.setMetadata(info, md)
+	drain := pm.config.Upgrade.drainTimeout()
+	pm.mu.Unlock()
+	pm.logFor(path).setName(name)
+
+	pm.hostLog.Printf("Upgraded plugin %s from v%s to v%s", name, from, md.version)
+	pm.events.Publish(Event{Type: EventPluginUpgraded, Plugin: name, Data: map[string]interface{}{"from": from, "to": md.version, "path": path}})
+	pm.loaded(info)
+
+	// The old server stops before the new one takes over its endpoint
+	if daemon != nil {
+		if err := stopDaemon(daemon, pm.Config().Daemons.startTimeout()); err != nil {
+			pm.hostLog.Printf("Daemon of plugin %s failed to stop: %v", name, err)
+		}
+	}
+	pm.superviseDaemon(info)
+	pm.saveState()
+
+	// Pooled processes of the old binary drain along with the main one
+	if old != nil {
+		extras = append(extras, old)
+	}
+	pm.mu.Lock()
+	if len(extras) > 0 && pm.draining == nil {
+		pm.draining = make(map[*plugin.Client]bool)
+	}
+	for _, c := range extras {
+		pm.draining[c] = true
+	}
+	pm.mu.Unlock()
+	for _, c := range extras {
+		go pm.drainOld(info, c, drain)
+	}
+	return nil
+}
+
+// upgradeTarget resolves the pathOrVersion given to Upgrade to a binary
+func (pm *PluginManager) upgradeTarget(name, pathOrVersion string) (string, error) {
+	if st, err := os.Stat(pathOrVersion); err == nil && !st.IsDir() {
+		return filepath.Clean(pathOrVersion), nil
+	}
+	if strings.ContainsRune(pathOrVersion, filepath.Separator) {
+		return "", fmt.Errorf("plugin binary not found: %s", pathOrVersion)
+	}
+
+	for _, dir := range pm.Config().PluginDirs {
+		for _, pattern := range []string{"*" + manifestExt, filepath.Join("*", "*"+manifestExt)} {
+			manifests, _ := filepath.Glob(filepath.Join(dir, pattern))
+			sort.Strings(manifests)
+			for _, mp := range manifests {
+				binary := strings.TrimSuffix(mp, manifestExt)
+				m, err := LoadManifest(mp)
+				if err != nil || m.Name != name || m.Version != pathOrVersion {
+					continue
+				}
+				if _, err := os.Stat(binary); err == nil {
+					return binary, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("no binary of plugin %s v%s in the plugin directories", name, pathOrVersion)
+}
+
+// smokeTest runs the plugin's configured smoke test against a new instance
+func (pm *PluginManager) smokeTest(name string, instance pluginsdk.CommandPlugin) error {
+	test, ok := pm.Config().Upgrade.SmokeTests[name]
+	if !ok {
+		return nil
+	}
+	timeout := time.Duration(test.Timeout)
+	if timeout == 0 {
+		timeout = DefaultSmokeTestTimeout
+	}
+
+	args := make(map[string]interface{}, len(test.Args)+1)
+	for k, v := range test.Args {
+		args[k] = v
+	}
+	if test.Capability != "" {
+		args[pluginsdk.ArgCapability] = test.Capability
+	}
+
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := instance.Execute(args)
+		done <- outcome{result, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-time.After(timeout):
+		return fmt.Errorf("no result within %v", timeout)
+	}
+	if o.err != nil {
+		return o.err
+	}
+	if path, spilled := pm.ResultFile(o.result); spilled {
+		data, err := os.ReadFile(path)
+		os.Remove(path)
+		if err != nil {
+			return err
+		}
+		o.result = string(data)
+	}
+	switch {
+	case test.Expect != "" && o.result != test.Expect:
+		return fmt.Errorf("got %q, want %q", o.result, test.Expect)
+	case test.Contains != "" && !strings.Contains(o.result, test.Contains):
+		return fmt.Errorf("got %q, want it to contain %q", o.result, test.Contains)
+	}
+	return nil
+}
+
+// drainOld stops the process an upgrade replaced once the calls in flight
+// have finished, or when the drain timeout runs out
+func (pm *PluginManager) drainOld(info *pluginInfo, old *plugin.Client, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for info.active.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	old.Kill()
+
+	pm.mu.Lock()
+	delete(pm.draining, old)
+	pm.mu.Unlock()
+}
+
+// Pin holds a plugin at version: Upgrade and Apply refuse to run any other
+// version of it until Unpin. The plugin need not be loaded.
+func (pm *PluginManager) Pin(name, version string) error {
+	if name == "" || version == "" {
+		return fmt.Errorf("pin needs a plugin name and a version")
+	}
+	pm.mu.Lock()
+	if pm.pins == nil {
+		pm.pins = make(map[string]string)
+	}
+	pm.pins[name] = version
+	pm.mu.Unlock()
+
+	pm.hostLog.Printf("Pinned plugin %s to v%s", name, version)
+	pm.saveState()
+	return nil
+}
+
+// Unpin lets a pinned plugin be upgraded again
+func (pm *PluginManager) Unpin(name string) {
+	pm.mu.Lock()
+	delete(pm.pins, name)
+	pm.mu.Unlock()
+
+	pm.saveState()
+}
+
+// Pins returns the pinned version of each pinned plugin
+func (pm *PluginManager) Pins() map[string]string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	pins := make(map[string]string, len(pm.pins))
+	for name, version := range pm.pins {
+		pins[name] = version
+	}
+	return pins
+}
+
+// checkPin refuses a version other than the one a plugin is pinned to
+func (pm *PluginManager) checkPin(name, version string) error {
+	pm.mu.RLock()
+	pinned, ok := pm.pins[name]
+	pm.mu.RUnlock()
+
+	if ok && version != pinned {
+		return fmt.Errorf("plugin %s is pinned to v%s, refusing v%s", name, pinned, version)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package pluginhost
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Outcomes of a conformance check
+const (
+	CheckPassed  = "passed"
+	CheckFailed  = "failed"
+	CheckSkipped = "skipped"
+)
+
+// CapabilityCheck is the outcome of exercising one capability
+type CapabilityCheck struct {
+	// Capability is the capability reference, e.g. "greet@v2"
+	Capability string `json:"capability"`
+
+	// Status is CheckPassed, CheckFailed or CheckSkipped
+	Status string `json:"status"`
+
+	Duration time.Duration `json:"duration_ns,omitempty"`
+
+	// Problems say why the check failed or was skipped
+	Problems []string `json:"problems,omitempty"`
+}
+
+// ConformanceReport tells whether a plugin does what its capability
+// metadata promises
+type ConformanceReport struct {
+	Plugin  string `json:"plugin"`
+	Version string `json:"version"`
+
+	// Passed is true when no check failed; skipped checks do not count
+	Passed bool `json:"passed"`
+
+	Checks []CapabilityCheck `json:"checks"`
+}
+
+// Verify exercises each capability the plugin declares with the example
+// invocation from its metadata: the example must satisfy the capability's
+// argument schema, the call must succeed, and the result must satisfy the
+// result schema when there is one. Capabilities without an example and
+// removed ones are skipped. Examples run for real, so plugins should only
+// declare examples that are safe to run. The error is for a plugin that
+// cannot be found or started; failed checks are in the report.
+func (pm *PluginManager) Verify(name string) (*ConformanceReport, error) {
+	if err := pm.ensureRunning(name); err != nil {
+		return nil, err
+	}
+	st, err := pm.GetPlugin(name)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConformanceReport{Plugin: st.Name, Version: st.Version, Passed: true}
+	for _, c := range st.Capabilities {
+		check := pm.verifyCapability(name, c)
+		if check.Status == CheckFailed {
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, check)
+	}
+	return report, nil
+}
+
+func (pm *PluginManager) verifyCapability(plugin string, c pluginsdk.Capability) CapabilityCheck {
+	check := CapabilityCheck{Capability: c.Ref(), Status: CheckSkipped}
+	switch {
+	case c.Removed:
+		check.Problems = []string{"capability is removed"}
+		return check
+	case c.Example == nil:
+		check.Problems = []string{"no example invocation in the capability metadata"}
+		return check
+	}
+
+	// Plugins over net/rpc may hand over Go values, so compare as JSON
+	example, err := asJSON(c.Example)
+	if err != nil {
+		return failCheck(check, "example cannot be encoded as JSON: %v", err)
+	}
+	argsSchema, err := schemaAsJSON(c.ArgsSchema)
+	if err != nil {
+		return failCheck(check, "argument schema cannot be encoded as JSON: %v", err)
+	}
+	resultSchema, err := schemaAsJSON(c.ResultSchema)
+	if err != nil {
+		return failCheck(check, "result schema cannot be encoded as JSON: %v", err)
+	}
+	if argsSchema != nil {
+		if problems := checkSchema(argsSchema, example); len(problems) > 0 {
+			check.Status = CheckFailed
+			for _, p := range problems {
+				check.Problems = append(check.Problems, "example does not match the argument schema at "+p)
+			}
+			return check
+		}
+	}
+
+	args := make(map[string]interface{}, len(c.Example)+1)
+	for k, v := range c.Example {
+		args[k] = v
+	}
+	args[pluginsdk.ArgCapability] = c.Ref()
+
+	start := time.Now()
+	result, err := pm.executeRaw(plugin, args)
+	check.Duration = time.Since(start)
+	if err != nil {
+		return failCheck(check, "example call failed: %v", err)
+	}
+	if path, spilled := pm.ResultFile(result); spilled {
+		data, err := os.ReadFile(path)
+		os.Remove(path)
+		if err != nil {
+			return failCheck(check, "spilled result cannot be read: %v", err)
+		}
+		result = string(data)
+	}
+
+	if resultSchema != nil {
+		var value interface{} = result
+		if resultSchema["type"] != "string" {
+			if err := json.Unmarshal([]byte(result), &value); err != nil {
+				return failCheck(check, "result is not JSON: %v", err)
+			}
+		}
+		if problems := checkSchema(resultSchema, value); len(problems) > 0 {
+			check.Status = CheckFailed
+			for _, p := range problems {
+				check.Problems = append(check.Problems, "result does not match the result schema at "+p)
+			}
+			return check
+		}
+	}
+	check.Status = CheckPassed
+	return check
+}
+
+func failCheck(check CapabilityCheck, format string, args ...interface{}) CapabilityCheck {
+	check.Status = CheckFailed
+	check.Problems = append(check.Problems, fmt.Sprintf(format, args...))
+	return check
+}
+
+// asJSON returns v as encoding/json decodes it, with numbers as float64
+func asJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+// schemaAsJSON is asJSON for schemas, keeping nil for no schema
+func schemaAsJSON(schema map[string]interface{}) (map[string]interface{}, error) {
+	if schema == nil {
+		return nil, nil
+	}
+	v, err := asJSON(schema)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := v.(map[string]interface{})
+	return m, nil
+}
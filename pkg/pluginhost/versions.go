@@ -0,0 +1,130 @@
+package pluginhost
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// DeprecationConfig controls calls to deprecated capabilities
+type DeprecationConfig struct {
+	// RejectRemoved fails calls to capabilities their plugin marks removed,
+	// returning the plugin's migration hint; otherwise they are only logged
+	RejectRemoved bool `json:"reject_removed"`
+}
+
+// resolveCapability turns the capability a call names, such as "greet@v2"
+// or "greet", into the plugin's capability and the arguments the plugin
+// receives: the bare name in ArgCapability and the version in
+// ArgCapabilityVersion. Calls without a version go to the newest version
+// that is not removed. Capabilities the plugin does not declare are
+// refused with ErrCapabilityNotSupported, unless the plugin declares none
+// at all. The caller holds pm.mu.
+func (pm *PluginManager) resolveCapability(info *pluginInfo, args map[string]interface{}) (map[string]interface{}, error) {
+	ref, _ := args[pluginsdk.ArgCapability].(string)
+	if ref == "" {
+		return args, nil
+	}
+	name, version := pluginsdk.ParseCapabilityRef(ref)
+	if v, ok := args[pluginsdk.ArgCapabilityVersion].(string); ok && version == "" {
+		version = v
+	}
+
+	c := findCapability(info.Capabilities, name, version)
+	if c == nil {
+		if len(info.Capabilities) == 0 {
+			return args, nil
+		}
+		return nil, &LookupError{
+			Kind:        ErrCapabilityNotSupported,
+			Plugin:      info.Name,
+			Capability:  ref,
+			Suggestions: suggest(ref, pluginsdk.CapabilityNames(info.Capabilities)),
+		}
+	}
+
+	if c.Removed {
+		if pm.config.Deprecations.RejectRemoved {
+			return nil, pluginsdk.NewError(pluginsdk.CodeUnsupported, "capability %s of plugin %s was removed: %s", c.Ref(), info.Name, c.Deprecated)
+		}
+		pm.warnDeprecated(info.Name, c, "removed")
+	} else if c.Deprecated != "" {
+		pm.warnDeprecated(info.Name, c, "deprecated")
+	}
+
+	if c.Version == "" && ref == name {
+		return args, nil
+	}
+	resolved := make(map[string]interface{}, len(args)+1)
+	for k, v := range args {
+		resolved[k] = v
+	}
+	resolved[pluginsdk.ArgCapability] = c.Name
+	if c.Version != "" {
+		resolved[pluginsdk.ArgCapabilityVersion] = c.Version
+	}
+	return resolved, nil
+}
+
+// warnDeprecated logs the first call to a deprecated capability of each
+// plugin, so callers are told without flooding the log
+func (pm *PluginManager) warnDeprecated(plugin string, c *pluginsdk.Capability, state string) {
+	if _, warned := pm.deprecationWarned.LoadOrStore(plugin+"/"+c.Ref(), true); warned {
+		return
+	}
+	pm.hostLog.Printf("Capability %s of plugin %s is %s: %s", c.Ref(), plugin, state, c.Deprecated)
+}
+
+// findCapability returns the capability with the given name and version.
+// An empty version picks the newest version that is not removed, or the
+// newest removed one when all are.
+func findCapability(caps []pluginsdk.Capability, name, version string) *pluginsdk.Capability {
+	var best *pluginsdk.Capability
+	for i := range caps {
+		c := &caps[i]
+		if c.Name != name {
+			continue
+		}
+		if version != "" {
+			if c.Version == version {
+				return c
+			}
+			continue
+		}
+		if best == nil || (best.Removed && !c.Removed) ||
+			(best.Removed == c.Removed && compareVersions(c.Version, best.Version) > 0) {
+			best = c
+		}
+	}
+	return best
+}
+
+// compareVersions orders versions such as "v2" and "v10" or "1.2" and
+// "1.10" numerically part by part, falling back to string order for parts
+// that are not numbers
+func compareVersions(a, b string) int {
+	pa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	pb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y string
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		nx, errx := strconv.Atoi(x)
+		ny, erry := strconv.Atoi(y)
+		switch {
+		case errx == nil && erry == nil && nx != ny:
+			if nx < ny {
+				return -1
+			}
+			return 1
+		case (errx != nil || erry != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package pluginhost
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// ConfigWatcher polls the host config file and hands every valid new
+// version to OnChange. Polling keeps the host free of platform-specific
+// file notification code and is cheap at this interval.
+type ConfigWatcher struct {
+	Path     string
+	Interval time.Duration
+	OnChange func(*HostConfig)
+}
+
+// NewConfigWatcher creates a watcher for the given config file
+func NewConfigWatcher(path string, onChange func(*HostConfig)) *ConfigWatcher {
+	return &ConfigWatcher{
+		Path:     path,
+		Interval: 2 * time.Second,
+		OnChange: onChange,
+	}
+}
+
+// Watch polls until stop is closed. An invalid config is logged and
+// ignored, leaving the previous one in effect.
+func (w *ConfigWatcher) Watch(stop <-chan struct{}) {
+	var lastMod time.Time
+	var lastSize int64
+	if st, err := os.Stat(w.Path); err == nil {
+		lastMod, lastSize = st.ModTime(), st.Size()
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		st, err := os.Stat(w.Path)
+		if err != nil {
+			continue
+		}
+		if st.ModTime().Equal(lastMod) && st.Size() == lastSize {
+			continue
+		}
+		lastMod, lastSize = st.ModTime(), st.Size()
+
+		cfg, err := LoadConfig(w.Path)
+		if err != nil {
+			log.Printf("Ignoring config change: %v", err)
+			continue
+		}
+		log.Printf("Config file changed: %s", w.Path)
+		w.OnChange(cfg)
+	}
+}
@@ -0,0 +1,204 @@
+package pluginhost
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk"
+)
+
+// Workspace cleanup policies, applied when a plugin is unloaded
+const (
+	// CleanupTemp empties the temporary directory and keeps data and cache
+	CleanupTemp = "temp"
+	// CleanupAll removes the data, cache and temporary directories
+	CleanupAll = "all"
+	// CleanupNone keeps every directory
+	CleanupNone = "none"
+)
+
+// WorkspaceConfig gives each plugin process its own data, cache and
+// temporary directory in an XDG-style layout, so plugins do not write into
+// the host's working directory. A plugin's directories are named after its
+// manifest, or its binary when it has none.
+type WorkspaceConfig struct {
+	// Disabled starts plugins in the host's working directory, as before
+	// workspaces existed
+	Disabled bool `json:"disabled"`
+
+	// DataRoot holds the data directories; the default is
+	// $XDG_DATA_HOME/opencode/plugins, or ~/.local/share/opencode/plugins
+	DataRoot string `json:"data_root"`
+
+	// CacheRoot holds the cache directories; the default is
+	// $XDG_CACHE_HOME/opencode/plugins, or ~/.cache/opencode/plugins
+	CacheRoot string `json:"cache_root"`
+
+	// TempRoot holds the temporary directories; the default is
+	// opencode-plugins in the system's temporary directory
+	TempRoot string `json:"temp_root"`
+
+	// Cleanup is CleanupTemp (the default), CleanupAll or CleanupNone
+	Cleanup string `json:"cleanup"`
+}
+
+func (c *WorkspaceConfig) validate() error {
+	switch c.Cleanup {
+	case "", CleanupTemp, CleanupAll, CleanupNone:
+		return nil
+	}
+	return fmt.Errorf("workspace.cleanup must be %q, %q or %q, got %q", CleanupTemp, CleanupAll, CleanupNone, c.Cleanup)
+}
+
+// xdgRoot returns $env/opencode/plugins, or the fallback below the home
+// directory when env is not set
+func xdgRoot(env, fallback string) (string, error) {
+	base := os.Getenv(env)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("no %s and no home directory: %w", env, err)
+		}
+		base = filepath.Join(home, fallback)
+	}
+	return filepath.Join(base, "opencode", "plugins"), nil
+}
+
+// workspace returns the directories of the plugin whose binary is at path
+func (c *WorkspaceConfig) workspace(path string, m *Manifest) (pluginsdk.Workspace, error) {
+	key := filepath.Base(path)
+	if m != nil {
+		key = m.Name
+	}
+
+	dataRoot, cacheRoot, tempRoot := c.DataRoot, c.CacheRoot, c.TempRoot
+	var err error
+	if dataRoot == "" {
+		if dataRoot, err = xdgRoot("XDG_DATA_HOME", filepath.Join(".local", "share")); err != nil {
+			return pluginsdk.Workspace{}, err
+		}
+	}
+	if cacheRoot == "" {
+		if cacheRoot, err = xdgRoot("XDG_CACHE_HOME", ".cache"); err != nil {
+			return pluginsdk.Workspace{}, err
+		}
+	}
+	if tempRoot == "" {
+		tempRoot = filepath.Join(os.TempDir(), "opencode-plugins")
+	}
+	return pluginsdk.Workspace{
+		DataDir:  filepath.Join(dataRoot, key),
+		CacheDir: filepath.Join(cacheRoot, key),
+		TempDir:  filepath.Join(tempRoot, key),
+	}, nil
+}
+
+// prepareWorkspace creates the directories of the plugin at path and
+// starts cmd in them. The temporary directory starts out empty. It returns
+// nil when workspaces are disabled.
+func (pm *PluginManager) prepareWorkspace(path string, cmd *exec.Cmd) (*pluginsdk.Workspace, error) {
+	cfg := pm.Config().Workspace
+	if cfg.Disabled {
+		return nil, nil
+	}
+	ws, err := cfg.workspace(path, pm.pluginManifest(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up plugin workspace: %w", err)
+	}
+	if !pm.workspaceInUse(ws) {
+		if err := os.RemoveAll(ws.TempDir); err != nil {
+			return nil, fmt.Errorf("failed to empty plugin temp directory: %w", err)
+		}
+	}
+	for _, dir := range []string{ws.DataDir, ws.CacheDir, ws.TempDir} {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to set up plugin workspace: %w", err)
+		}
+	}
+	cmd.Dir = ws.DataDir
+	cmd.Env = append(cmd.Env, workspaceEnv(ws)...)
+	return &ws, nil
+}
+
+// workspaceInUse reports whether a running process has the workspace, as
+// the one an upgrade replaces does
+func (pm *PluginManager) workspaceInUse(ws pluginsdk.Workspace) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	for _, info := range pm.plugins {
+		if info.workspace != nil && info.Instance != nil && info.workspace.TempDir == ws.TempDir {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkspaceUsage reports a plugin's directories and how much they hold
+type WorkspaceUsage struct {
+	DataDir    string `json:"data_dir"`
+	CacheDir   string `json:"cache_dir"`
+	TempDir    string `json:"temp_dir"`
+	DataBytes  int64  `json:"data_bytes"`
+	CacheBytes int64  `json:"cache_bytes"`
+	TempBytes  int64  `json:"temp_bytes"`
+}
+
+// measure fills in how much the directories hold
+func (u *WorkspaceUsage) measure() {
+	u.DataBytes = dirSize(u.DataDir)
+	u.CacheBytes = dirSize(u.CacheDir)
+	u.TempBytes = dirSize(u.TempDir)
+}
+
+// dirSize is the total size of the regular files below dir
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// workspaceEnv passes the directories to the plugin process
+func workspaceEnv(ws pluginsdk.Workspace) []string {
+	return []string{
+		pluginsdk.EnvDataDir + "=" + ws.DataDir,
+		pluginsdk.EnvCacheDir + "=" + ws.CacheDir,
+		pluginsdk.EnvTempDir + "=" + ws.TempDir,
+		"TMPDIR=" + ws.TempDir,
+	}
+}
+
+// cleanWorkspace applies the cleanup policy to an unloaded plugin's
+// directories
+func (pm *PluginManager) cleanWorkspace(name string, ws *pluginsdk.Workspace) {
+	if ws == nil {
+		return
+	}
+	var dirs []string
+	switch pm.Config().Workspace.Cleanup {
+	case CleanupNone:
+		return
+	case CleanupAll:
+		dirs = []string{ws.TempDir, ws.CacheDir, ws.DataDir}
+	default:
+		dirs = []string{ws.TempDir}
+	}
+	for _, dir := range dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			pm.hostLog.Printf("Failed to clean workspace of plugin %s: %v", name, err)
+		}
+	}
+}
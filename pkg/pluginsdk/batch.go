@@ -0,0 +1,220 @@
+package pluginsdk
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// BatchResult is the outcome of one item of a batch
+type BatchResult struct {
+	Result string
+	Err    error
+}
+
+// BatchPlugin is optionally implemented by plugins that handle many calls
+// more cheaply together than one by one, e.g. by loading a model or
+// indexing a repository once for all of them. ExecuteBatch returns one
+// result per item, in order; items it has no result for fail. Plugins
+// without it have the items of a batch executed one after another in
+// their process, which still saves a round trip per item.
+type BatchPlugin interface {
+	ExecuteBatch(items []map[string]interface{}) []BatchResult
+}
+
+// BatchExecutor is implemented by the host side of plugin clients that
+// can send many calls to the plugin process in one RPC. Plugins predating
+// batches fail it with ErrBatchUnsupported.
+type BatchExecutor interface {
+	ExecuteBatch(ctx context.Context, items []map[string]interface{}) ([]BatchResult, error)
+}
+
+// ErrBatchUnsupported is returned by BatchExecutors talking to plugins
+// built before batches; the host then sends the items one by one
+var ErrBatchUnsupported = NewError(CodeUnsupported, "plugin predates batches")
+
+// executeBatch runs the items of a batch in this process, each registered
+// as a running call so it can be canceled on its own
+func executeBatch(ctx context.Context, impl CommandPlugin, items []map[string]interface{}) []BatchResult {
+	contexts := make([]context.Context, len(items))
+	for i, args := range items {
+		var done func()
+		contexts[i], done = runningCalls.start(ctx, args)
+		defer done()
+	}
+
+	results := make([]BatchResult, len(items))
+	if b, ok := impl.(BatchPlugin); ok {
+		out := b.ExecuteBatch(items)
+		for i := range results {
+			if i < len(out) {
+				results[i] = out[i]
+			} else {
+				results[i].Err = NewError(CodeInternal, "plugin returned no result for batch item %d", i)
+			}
+		}
+	} else {
+		for i, args := range items {
+			if c, ok := impl.(ContextPlugin); ok {
+				results[i].Result, results[i].Err = c.ExecuteContext(contexts[i], args)
+			} else {
+				results[i].Result, results[i].Err = impl.Execute(args)
+			}
+		}
+	}
+	return results
+}
+
+// ExecuteBatchRequest is the net/rpc argument to ExecuteBatch. Items are
+// encoded with the negotiated codec when there is one, else sent as Args;
+// Files has the arguments of each item that were handed off through files.
+type ExecuteBatchRequest struct {
+	Args    []map[string]interface{}
+	Codec   string
+	Encoded [][]byte
+	Files   []map[string]string
+}
+
+// ExecuteBatchResponse is the net/rpc reply to ExecuteBatch, one response
+// per item
+type ExecuteBatchResponse struct {
+	Results []ExecuteResponse
+}
+
+// ExecuteBatch implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) ExecuteBatch(req ExecuteBatchRequest, resp *ExecuteBatchResponse) error {
+	items := req.Args
+	resp.Results = make([]ExecuteResponse, max(len(req.Args), len(req.Encoded)))
+	if req.Codec != "" {
+		items = make([]map[string]interface{}, len(req.Encoded))
+		for i, data := range req.Encoded {
+			item := EncodedArgs{Codec: req.Codec, Data: data}
+			if i < len(req.Files) {
+				item.Files = req.Files[i]
+			}
+			args, err := item.decode()
+			if err != nil {
+				resp.Results[i].Error = AsPluginError(err)
+				continue
+			}
+			items[i] = args
+		}
+	}
+
+	run, index := decoded(items, func(i int) bool { return resp.Results[i].Error == nil })
+	for j, r := range executeBatch(context.Background(), s.Impl, run) {
+		result, spillPath, err := spillResult(r.Result, r.Err)
+		resp.Results[index[j]] = ExecuteResponse{Result: result, SpillPath: spillPath, Error: AsPluginError(err)}
+	}
+	return nil
+}
+
+// ExecuteBatch runs a batch of calls in the plugin via RPC
+func (c *CommandPluginRPCClient) ExecuteBatch(ctx context.Context, items []map[string]interface{}) ([]BatchResult, error) {
+	req := ExecuteBatchRequest{Args: items}
+	if c.codec != nil {
+		req = ExecuteBatchRequest{Codec: c.codec.Name(), Encoded: make([][]byte, len(items)), Files: make([]map[string]string, len(items))}
+		for i, args := range items {
+			item, release, err := c.encode(args)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+			req.Encoded[i], req.Files[i] = item.Data, item.Files
+		}
+	}
+
+	var resp ExecuteBatchResponse
+	if err := c.client.Call("Plugin.ExecuteBatch", req, &resp); err != nil {
+		if missingMethod(err) {
+			return nil, ErrBatchUnsupported
+		}
+		return nil, transportError(err)
+	}
+	results := make([]BatchResult, len(items))
+	for i := range results {
+		if i >= len(resp.Results) {
+			results[i].Err = NewError(CodeInternal, "plugin returned no result for batch item %d", i)
+			continue
+		}
+		if resp.Results[i].Error != nil {
+			results[i].Result, results[i].Err = resp.Results[i].Result, resp.Results[i].Error
+			continue
+		}
+		results[i].Result, results[i].Err = c.spills.result(resp.Results[i].Result, resp.Results[i].SpillPath)
+	}
+	return results, nil
+}
+
+// ExecuteBatch implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) ExecuteBatch(ctx context.Context, req *proto.ExecuteBatchRequest) (*proto.ExecuteBatchResponse, error) {
+	resp := &proto.ExecuteBatchResponse{Results: make([]*proto.ExecuteResponse, len(req.GetItems()))}
+	items := make([]map[string]interface{}, len(req.GetItems()))
+	for i, item := range req.GetItems() {
+		args, err := requestArgs(item)
+		if err != nil {
+			resp.Results[i] = &proto.ExecuteResponse{Error: errorToProto(err)}
+			continue
+		}
+		items[i] = args
+	}
+
+	run, index := decoded(items, func(i int) bool { return resp.Results[i] == nil })
+	for j, r := range executeBatch(ctx, s.Impl, run) {
+		result, spillPath, err := spillResult(r.Result, r.Err)
+		resp.Results[index[j]] = &proto.ExecuteResponse{Result: result, SpillPath: spillPath, Error: errorToProto(err)}
+	}
+	return resp, nil
+}
+
+// ExecuteBatch runs a batch of calls in the plugin via gRPC
+func (c *CommandPluginGRPCClient) ExecuteBatch(ctx context.Context, items []map[string]interface{}) ([]BatchResult, error) {
+	req := &proto.ExecuteBatchRequest{Items: make([]*proto.ExecuteRequest, len(items))}
+	for i, args := range items {
+		item, release, err := c.executeRequest(args)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		req.Items[i] = item
+	}
+
+	resp, err := c.client.ExecuteBatch(ctx, req)
+	if status.Code(err) == codes.Unimplemented {
+		return nil, ErrBatchUnsupported
+	}
+	if err != nil {
+		return nil, transportError(err)
+	}
+	results := make([]BatchResult, len(items))
+	for i := range results {
+		if i >= len(resp.GetResults()) {
+			results[i].Err = NewError(CodeInternal, "plugin returned no result for batch item %d", i)
+			continue
+		}
+		r := resp.GetResults()[i]
+		if results[i].Err = errorFromProto(r.GetError()); results[i].Err != nil {
+			results[i].Result = r.GetResult()
+			continue
+		}
+		results[i].Result, results[i].Err = c.spills.result(r.GetResult(), r.GetSpillPath())
+	}
+	return results, nil
+}
+
+// decoded returns the items that were decoded, as ok says by index, with
+// the index of each in items
+func decoded(items []map[string]interface{}, ok func(i int) bool) ([]map[string]interface{}, []int) {
+	var run []map[string]interface{}
+	var index []int
+	for i, args := range items {
+		if ok(i) {
+			run = append(run, args)
+			index = append(index, i)
+		}
+	}
+	return run, index
+}
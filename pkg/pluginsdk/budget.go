@@ -0,0 +1,102 @@
+package pluginsdk
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// ErrPartial is returned along with the result so far by capabilities
+// declaring Partial whose latency budget ran out. The SDK sends the result
+// flagged as partial, and the host's call succeeds with it.
+var ErrPartial = errors.New("partial result: latency budget ran out")
+
+// BudgetExpirer is implemented by the host side of plugin clients that can
+// tell the plugin process a call's latency budget ran out. found is false
+// when the call was no longer running.
+type BudgetExpirer interface {
+	ExpireBudget(callID string) (found bool, err error)
+}
+
+// WrapUp returns a channel that is closed when the latency budget of the
+// call args were passed to runs out. Capabilities declaring Partial select
+// on it while they work and then return what they have with ErrPartial.
+// Calls without a budget and code outside a call get a channel that is
+// never closed.
+func WrapUp(args map[string]interface{}) <-chan struct{} {
+	id, _ := args[ArgCallID].(string)
+	return runningCalls.wrapUp(id)
+}
+
+func (r *callRegistry) wrapUp(id string) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.calls[id]; ok {
+		return c.wrapUp
+	}
+	return nil
+}
+
+// expire closes the wrap-up channel of a running call, reporting whether
+// it ran
+func (r *callRegistry) expire(id string) bool {
+	r.mu.Lock()
+	c, ok := r.calls[id]
+	r.mu.Unlock()
+
+	if ok {
+		c.expired.Do(func() { close(c.wrapUp) })
+	}
+	return ok
+}
+
+// splitPartial turns the ErrPartial of a call into the partial flag of its
+// response, spilling the result as usual
+func splitPartial(result string, err error) (string, string, bool, error) {
+	partial := errors.Is(err, ErrPartial)
+	if partial {
+		err = nil
+	}
+	result, spillPath, err := spillResult(result, err)
+	return result, spillPath, partial && err == nil, err
+}
+
+// ExpireBudget implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) ExpireBudget(callID string, found *bool) error {
+	*found = runningCalls.expire(callID)
+	return nil
+}
+
+// ExpireBudget tells the plugin a call's latency budget ran out via RPC
+func (c *CommandPluginRPCClient) ExpireBudget(callID string) (bool, error) {
+	var found bool
+	if err := c.client.Call("Plugin.ExpireBudget", callID, &found); err != nil {
+		if missingMethod(err) {
+			return false, NewError(CodeUnsupported, "plugin predates latency budgets")
+		}
+		return false, transportError(err)
+	}
+	return found, nil
+}
+
+// ExpireBudget implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) ExpireBudget(ctx context.Context, req *proto.ExpireBudgetRequest) (*proto.ExpireBudgetResponse, error) {
+	return &proto.ExpireBudgetResponse{Found: runningCalls.expire(req.GetCallId())}, nil
+}
+
+// ExpireBudget tells the plugin a call's latency budget ran out via gRPC
+func (c *CommandPluginGRPCClient) ExpireBudget(callID string) (bool, error) {
+	resp, err := c.client.ExpireBudget(context.Background(), &proto.ExpireBudgetRequest{CallId: callID})
+	if status.Code(err) == codes.Unimplemented {
+		return false, NewError(CodeUnsupported, "plugin predates latency budgets")
+	}
+	if err != nil {
+		return false, transportError(err)
+	}
+	return resp.GetFound(), nil
+}
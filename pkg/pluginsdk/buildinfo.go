@@ -0,0 +1,23 @@
+package pluginsdk
+
+// Build metadata of a plugin binary, set when it is linked, e.g. by
+// "superplugin release":
+//
+//	go build -ldflags "-X github.com/Kirchlive/super/pkg/pluginsdk.BuildVersion=1.2.0"
+//
+// They are empty in binaries built without them.
+var (
+	BuildVersion string
+	BuildCommit  string
+	BuildDate    string
+)
+
+// VersionOr returns BuildVersion, or fallback when the binary was built
+// without one. Plugins return it from Version so released binaries report
+// the version they were released as.
+func VersionOr(fallback string) string {
+	if BuildVersion != "" {
+		return BuildVersion
+	}
+	return fallback
+}
@@ -0,0 +1,62 @@
+package pluginsdk
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ArgWorkDir is the reserved argument key holding the absolute directory a
+// call operates on, e.g. the root of the project the developer has open.
+// The host checks it is an existing directory before the call reaches the
+// plugin.
+const ArgWorkDir = "workdir"
+
+// ArgEnv is the reserved argument key holding environment variables for a
+// call, as a map of names to string values. The host drops the variables
+// its call_env.allow list does not allow, and LD_* and DYLD_*, from every
+// call before it reaches the plugin.
+const ArgEnv = "env"
+
+// WorkDir returns the directory a call operates on, or "" when the host
+// named none
+func WorkDir(args map[string]interface{}) string {
+	dir, _ := args[ArgWorkDir].(string)
+	return dir
+}
+
+// Env returns the environment variables passed for a call
+func Env(args map[string]interface{}) map[string]string {
+	raw, _ := args[ArgEnv].(map[string]interface{})
+	env := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			env[k] = s
+		}
+	}
+	return env
+}
+
+// ResolvePath makes a relative path relative to the call's working
+// directory instead of the plugin process's
+func ResolvePath(args map[string]interface{}, path string) string {
+	dir := WorkDir(args)
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// Command prepares a subprocess that runs in the call's working directory
+// with the call's environment variables added to the plugin's own
+func Command(args map[string]interface{}, name string, arg ...string) *exec.Cmd {
+	cmd := exec.Command(name, arg...)
+	cmd.Dir = WorkDir(args)
+	if env := Env(args); len(env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+	}
+	return cmd
+}
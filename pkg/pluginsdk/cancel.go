@@ -0,0 +1,137 @@
+package pluginsdk
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// ArgCallID is the reserved argument key carrying the host's ID of a call,
+// which the host names when it cancels the call
+const ArgCallID = "call_id"
+
+// Canceler is implemented by the host side of plugin clients that can ask
+// the plugin process to abort one call. found is false when the call was
+// no longer running.
+type Canceler interface {
+	Cancel(callID, reason string) (found bool, err error)
+}
+
+// CallContext returns the context of the call args were passed to, which
+// ends when the host cancels the call; context.Cause then returns a
+// PluginError with CodeCanceled saying why. Plugins implementing only
+// Execute can watch it to stop early, ContextPlugins receive it as their
+// ctx. Outside a call it never ends.
+func CallContext(args map[string]interface{}) context.Context {
+	id, _ := args[ArgCallID].(string)
+	return runningCalls.context(id)
+}
+
+// runningCalls are the calls this plugin process is serving
+var runningCalls = &callRegistry{}
+
+// callRegistry holds the contexts of the running calls by call ID
+type callRegistry struct {
+	mu    sync.Mutex
+	calls map[string]*runningCall
+}
+
+type runningCall struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	// wrapUp is closed when the call's latency budget runs out
+	wrapUp  chan struct{}
+	expired sync.Once
+}
+
+// start registers the call args belong to and returns its context, derived
+// from parent, and the function to call when it returns. Calls without a
+// call ID are not registered.
+func (r *callRegistry) start(parent context.Context, args map[string]interface{}) (context.Context, func()) {
+	id, _ := args[ArgCallID].(string)
+	if id == "" {
+		return parent, func() {}
+	}
+	ctx, cancel := context.WithCancelCause(parent)
+	r.mu.Lock()
+	if r.calls == nil {
+		r.calls = make(map[string]*runningCall)
+	}
+	r.calls[id] = &runningCall{ctx: ctx, cancel: cancel, wrapUp: make(chan struct{})}
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.calls, id)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+// cancel ends the context of a running call, reporting whether it ran
+func (r *callRegistry) cancel(id, reason string) bool {
+	r.mu.Lock()
+	c, ok := r.calls[id]
+	r.mu.Unlock()
+
+	if ok {
+		c.cancel(NewError(CodeCanceled, "call canceled: %s", reason))
+	}
+	return ok
+}
+
+func (r *callRegistry) context(id string) context.Context {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.calls[id]; ok {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+// CancelArgs is the request of the Cancel RPC over net/rpc
+type CancelArgs struct {
+	CallID string
+	Reason string
+}
+
+// Cancel implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Cancel(req CancelArgs, found *bool) error {
+	*found = runningCalls.cancel(req.CallID, req.Reason)
+	return nil
+}
+
+// Cancel asks the plugin to abort a call via RPC
+func (c *CommandPluginRPCClient) Cancel(callID, reason string) (bool, error) {
+	var found bool
+	if err := c.client.Call("Plugin.Cancel", CancelArgs{CallID: callID, Reason: reason}, &found); err != nil {
+		if missingMethod(err) {
+			return false, NewError(CodeUnsupported, "plugin predates call cancellation")
+		}
+		return false, transportError(err)
+	}
+	return found, nil
+}
+
+// Cancel implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) Cancel(ctx context.Context, req *proto.CancelRequest) (*proto.CancelResponse, error) {
+	return &proto.CancelResponse{Found: runningCalls.cancel(req.GetCallId(), req.GetReason())}, nil
+}
+
+// Cancel asks the plugin to abort a call via gRPC
+func (c *CommandPluginGRPCClient) Cancel(callID, reason string) (bool, error) {
+	resp, err := c.client.Cancel(context.Background(), &proto.CancelRequest{CallId: callID, Reason: reason})
+	if status.Code(err) == codes.Unimplemented {
+		return false, NewError(CodeUnsupported, "plugin predates call cancellation")
+	}
+	if err != nil {
+		return false, transportError(err)
+	}
+	return resp.GetFound(), nil
+}
@@ -0,0 +1,283 @@
+package pluginsdk
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// Capability describes one operation a plugin offers, with enough detail
+// for hosts to render help and for the MCP bridge to emit tool descriptions
+type Capability struct {
+	// Name identifies the capability, e.g. "greet"
+	Name string `json:"name"`
+
+	// Description is a one-line, human readable summary
+	Description string `json:"description,omitempty"`
+
+	// ArgsSchema is a JSON Schema object describing the accepted arguments.
+	// Properties marked "sensitive": true or "writeOnly": true are
+	// redacted where the host records calls.
+	ArgsSchema map[string]interface{} `json:"args_schema,omitempty"`
+
+	// Example is a sample argument map for invoking the capability
+	Example map[string]interface{} `json:"example,omitempty"`
+
+	// ResultSchema is a JSON Schema object the result satisfies. The
+	// result is parsed as JSON unless the schema's type is "string".
+	ResultSchema map[string]interface{} `json:"result_schema,omitempty"`
+
+	// Tags group related capabilities, e.g. "text" or "experimental"
+	Tags []string `json:"tags,omitempty"`
+
+	// Idempotent means repeating a call has no further effect, which allows
+	// the host to retry it automatically after a retryable failure
+	Idempotent bool `json:"idempotent,omitempty"`
+
+	// Compensate names the capability of the same plugin that undoes a
+	// successful call, e.g. "scaffold.remove". Pipelines run it when a
+	// later step fails; it receives the call in ArgCompensates.
+	Compensate string `json:"compensate,omitempty"`
+
+	// Version distinguishes incompatible revisions of a capability that
+	// share a name, e.g. "v2". Callers pick one as "greet@v2"; calls
+	// without a version go to the newest one that is not removed.
+	Version string `json:"version,omitempty"`
+
+	// Deprecated marks the capability as on its way out and tells callers
+	// what to use instead, e.g. "use greet@v2"
+	Deprecated string `json:"deprecated,omitempty"`
+
+	// Removed means the capability no longer works; the host can be
+	// configured to reject calls to it with the Deprecated hint
+	Removed bool `json:"removed,omitempty"`
+
+	// Cost is the expected cost class of a call, CostCheap, CostMedium or
+	// CostExpensive. Among plugins offering the same capability the host
+	// prefers the cheapest.
+	Cost string `json:"cost,omitempty"`
+
+	// Latency is how long a call typically takes, e.g. "200ms". The host
+	// prefers the fastest of equally cheap plugins and derives a timeout
+	// from it when the manifest sets none.
+	Latency string `json:"latency,omitempty"`
+
+	// Formats are the result formats the capability can answer in, such
+	// as FormatJSON or FormatMarkdown, the default first; see ResultFormat.
+	// Capabilities without them answer in FormatText.
+	Formats []string `json:"formats,omitempty"`
+
+	// ConcurrencyKey names what calls of the capability modify, e.g.
+	// "repo-write". The host runs calls sharing a key one at a time, across
+	// plugins, while calls with other keys or none run in parallel.
+	ConcurrencyKey string `json:"concurrency_key,omitempty"`
+
+	// Partial means calls may be given a latency budget with
+	// ArgLatencyBudget; when it runs out the capability returns what it
+	// has so far with ErrPartial instead of failing
+	Partial bool `json:"partial,omitempty"`
+}
+
+// Cost classes of capabilities, from cheapest to most expensive
+const (
+	CostCheap     = "cheap"
+	CostMedium    = "medium"
+	CostExpensive = "expensive"
+)
+
+// CostRank orders cost classes for comparison: cheap before medium before
+// expensive. Capabilities without a cost rank as medium.
+func CostRank(cost string) int {
+	switch cost {
+	case CostCheap:
+		return 0
+	case CostExpensive:
+		return 2
+	}
+	return 1
+}
+
+// TypicalLatency returns the Latency hint, or 0 when there is none
+func (c Capability) TypicalLatency() time.Duration {
+	d, err := time.ParseDuration(c.Latency)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// ValidateHints checks that Cost names a cost class and Latency is a
+// duration
+func (c Capability) ValidateHints() error {
+	switch c.Cost {
+	case "", CostCheap, CostMedium, CostExpensive:
+	default:
+		return fmt.Errorf("capability %s: cost %q is not one of %s, %s or %s", c.Ref(), c.Cost, CostCheap, CostMedium, CostExpensive)
+	}
+	if c.Latency == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(c.Latency); err != nil || d <= 0 {
+		return fmt.Errorf("capability %s: latency %q is not a positive duration like \"200ms\"", c.Ref(), c.Latency)
+	}
+	return nil
+}
+
+// Ref returns how callers name the capability: "greet@v2", or just the
+// name when it is unversioned
+func (c Capability) Ref() string {
+	if c.Version == "" {
+		return c.Name
+	}
+	return c.Name + "@" + c.Version
+}
+
+// ParseCapabilityRef splits "greet@v2" into its name and version. The
+// version is empty when ref names none.
+func ParseCapabilityRef(ref string) (name, version string) {
+	if i := strings.LastIndex(ref, "@"); i > 0 {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, ""
+}
+
+// UnmarshalJSON accepts either a full capability object or a bare name, so
+// manifests that list capabilities as strings keep working
+func (c *Capability) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*c = Capability{Name: name}
+		return nil
+	}
+
+	// A distinct type avoids recursing into this method
+	type plain Capability
+	var p plain
+	if err := json.Unmarshal(b, &p); err != nil {
+		return err
+	}
+	*c = Capability(p)
+	return nil
+}
+
+// CapabilityNames returns the names of caps in order, with their version
+// when they have one
+func CapabilityNames(caps []Capability) []string {
+	names := make([]string, len(caps))
+	for i, c := range caps {
+		names[i] = c.Ref()
+	}
+	return names
+}
+
+func init() {
+	// Schemas and examples hold nested JSON values, which gob can only
+	// carry over net/rpc inside interface{} fields once registered
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// capabilitiesToProto fills both the plain name list, which older hosts
+// read, and the structured details
+func capabilitiesToProto(caps []Capability) (*proto.GetCapabilitiesResponse, error) {
+	resp := &proto.GetCapabilitiesResponse{Capabilities: CapabilityNames(caps)}
+	for _, c := range caps {
+		pc, err := CapabilityToProto(c)
+		if err != nil {
+			return nil, err
+		}
+		resp.Details = append(resp.Details, pc)
+	}
+	return resp, nil
+}
+
+// CapabilityToProto converts a capability into its wire form
+func CapabilityToProto(c Capability) (*proto.Capability, error) {
+	pc := &proto.Capability{
+		Name:           c.Name,
+		Description:    c.Description,
+		Tags:           c.Tags,
+		Idempotent:     c.Idempotent,
+		Version:        c.Version,
+		Deprecated:     c.Deprecated,
+		Removed:        c.Removed,
+		Compensate:     c.Compensate,
+		Cost:           c.Cost,
+		Latency:        c.Latency,
+		Formats:        c.Formats,
+		ConcurrencyKey: c.ConcurrencyKey,
+		Partial:        c.Partial,
+	}
+	var err error
+	if c.ArgsSchema != nil {
+		if pc.ArgsSchema, err = structpb.NewStruct(c.ArgsSchema); err != nil {
+			return nil, err
+		}
+	}
+	if c.Example != nil {
+		if pc.Example, err = structpb.NewStruct(c.Example); err != nil {
+			return nil, err
+		}
+	}
+	if c.ResultSchema != nil {
+		if pc.ResultSchema, err = structpb.NewStruct(c.ResultSchema); err != nil {
+			return nil, err
+		}
+	}
+	return pc, nil
+}
+
+// capabilitiesFromProto prefers the structured details and falls back to
+// bare names for plugins built before capabilities had metadata
+func capabilitiesFromProto(resp *proto.GetCapabilitiesResponse) []Capability {
+	details := resp.GetDetails()
+	if len(details) == 0 {
+		caps := make([]Capability, 0, len(resp.GetCapabilities()))
+		for _, ref := range resp.GetCapabilities() {
+			name, version := ParseCapabilityRef(ref)
+			caps = append(caps, Capability{Name: name, Version: version})
+		}
+		return caps
+	}
+
+	caps := make([]Capability, 0, len(details))
+	for _, pc := range details {
+		caps = append(caps, CapabilityFromProto(pc))
+	}
+	return caps
+}
+
+// CapabilityFromProto converts a capability from its wire form
+func CapabilityFromProto(pc *proto.Capability) Capability {
+	c := Capability{
+		Name:           pc.GetName(),
+		Description:    pc.GetDescription(),
+		Tags:           pc.GetTags(),
+		Idempotent:     pc.GetIdempotent(),
+		Version:        pc.GetVersion(),
+		Deprecated:     pc.GetDeprecated(),
+		Removed:        pc.GetRemoved(),
+		Compensate:     pc.GetCompensate(),
+		Cost:           pc.GetCost(),
+		Latency:        pc.GetLatency(),
+		Formats:        pc.GetFormats(),
+		ConcurrencyKey: pc.GetConcurrencyKey(),
+		Partial:        pc.GetPartial(),
+	}
+	if pc.GetArgsSchema() != nil {
+		c.ArgsSchema = pc.GetArgsSchema().AsMap()
+	}
+	if pc.GetExample() != nil {
+		c.Example = pc.GetExample().AsMap()
+	}
+	if pc.GetResultSchema() != nil {
+		c.ResultSchema = pc.GetResultSchema().AsMap()
+	}
+	return c
+}
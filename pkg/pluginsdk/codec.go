@@ -0,0 +1,353 @@
+package pluginsdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"sort"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// Codec encodes the arguments of Execute and Plan calls. Host and plugin
+// agree on one per plugin process when the host connects, so how values
+// cross the boundary does not depend on the transport.
+type Codec interface {
+	Name() string
+	Marshal(args map[string]interface{}) ([]byte, error)
+	Unmarshal(data []byte) (map[string]interface{}, error)
+}
+
+const (
+	// CodecMsgpack keeps integers as int64, byte slices as bytes and
+	// times as time.Time
+	CodecMsgpack = "msgpack"
+
+	// CodecJSON turns numbers into float64, byte slices into base64
+	// strings and times into RFC 3339 strings
+	CodecJSON = "json"
+
+	// CodecStructpb carries arguments as a google.protobuf.Struct, with the
+	// same value mapping as JSON. It is what gRPC plugins that cannot
+	// negotiate receive.
+	CodecStructpb = "structpb"
+
+	// CodecGob is how net/rpc carries arguments for plugins that cannot
+	// negotiate. Gob only handles the types registered with it, so it is
+	// never chosen otherwise.
+	CodecGob = "gob"
+)
+
+// DefaultCodecs are the codecs a host offers when configured with none,
+// most preferred first
+var DefaultCodecs = []string{CodecMsgpack, CodecJSON, CodecStructpb}
+
+var codecs = map[string]Codec{
+	CodecMsgpack:  msgpackCodec{},
+	CodecJSON:     jsonCodec{},
+	CodecStructpb: structpbCodec{},
+}
+
+// LookupCodec returns the codec with the given name
+func LookupCodec(name string) (Codec, bool) {
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// Codecs returns the names of the codecs this SDK supports
+func Codecs() []string {
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CodecNegotiator is implemented by the host side of a plugin connection.
+// NegotiateCodec offers the codecs in order of preference and returns the
+// one the plugin chose. Plugins that cannot negotiate keep their
+// transport's own encoding, reported as CodecStructpb or CodecGob.
+type CodecNegotiator interface {
+	NegotiateCodec(preferred []string) (string, error)
+}
+
+// chooseCodec returns the first preferred codec this SDK supports, or ""
+func chooseCodec(preferred []string) string {
+	for _, name := range preferred {
+		if _, ok := codecs[name]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return CodecMsgpack }
+
+func (msgpackCodec) Marshal(args map[string]interface{}) ([]byte, error) {
+	return msgpack.Marshal(args)
+}
+
+func (msgpackCodec) Unmarshal(data []byte) (map[string]interface{}, error) {
+	var args map[string]interface{}
+	if err := msgpack.Unmarshal(data, &args); err != nil {
+		return nil, err
+	}
+	for k, v := range args {
+		args[k] = widenInts(v)
+	}
+	return args, nil
+}
+
+// widenInts turns the integers msgpack decodes at their encoded width into
+// int64 and uint64, so plugins need not care how small a number was
+func widenInts(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint8:
+		return uint64(n)
+	case uint16:
+		return uint64(n)
+	case uint32:
+		return uint64(n)
+	case map[string]interface{}:
+		for k, e := range n {
+			n[k] = widenInts(e)
+		}
+	case []interface{}:
+		for i, e := range n {
+			n[i] = widenInts(e)
+		}
+	}
+	return v
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return CodecJSON }
+
+func (jsonCodec) Marshal(args map[string]interface{}) ([]byte, error) {
+	return json.Marshal(args)
+}
+
+func (jsonCodec) Unmarshal(data []byte) (map[string]interface{}, error) {
+	var args map[string]interface{}
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+type structpbCodec struct{}
+
+func (structpbCodec) Name() string { return CodecStructpb }
+
+func (structpbCodec) Marshal(args map[string]interface{}) ([]byte, error) {
+	s, err := newStruct(args)
+	if err != nil {
+		return nil, err
+	}
+	return protobuf.Marshal(s)
+}
+
+func (structpbCodec) Unmarshal(data []byte) (map[string]interface{}, error) {
+	s := &structpb.Struct{}
+	if err := protobuf.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	return s.AsMap(), nil
+}
+
+// newStruct converts args to a Struct. Values Struct cannot hold directly,
+// such as []string or structs, are converted through JSON first.
+func newStruct(args map[string]interface{}) (*structpb.Struct, error) {
+	s, err := structpb.NewStruct(args)
+	if err == nil {
+		return s, nil
+	}
+	data, jerr := json.Marshal(args)
+	if jerr != nil {
+		return nil, fmt.Errorf("arguments cannot be encoded: %w", jerr)
+	}
+	var normalized map[string]interface{}
+	if jerr := json.Unmarshal(data, &normalized); jerr != nil {
+		return nil, fmt.Errorf("arguments cannot be encoded: %w", jerr)
+	}
+	return structpb.NewStruct(normalized)
+}
+
+// requestArgs decodes the arguments of an Execute or Plan request
+func requestArgs(req *proto.ExecuteRequest) (map[string]interface{}, error) {
+	if req.GetCodec() == "" {
+		return req.GetArgs().AsMap(), nil
+	}
+	return decodeHandoff(req.GetCodec(), req.GetEncodedArgs(), req.GetArgsFiles())
+}
+
+func decodeArgs(name string, data []byte) (map[string]interface{}, error) {
+	codec, ok := codecs[name]
+	if !ok {
+		return nil, NewError(CodeInvalidArgument, "unknown argument codec %q", name)
+	}
+	args, err := codec.Unmarshal(data)
+	if err != nil {
+		return nil, NewError(CodeInvalidArgument, "failed to decode %s arguments: %v", name, err)
+	}
+	return args, nil
+}
+
+// NegotiateCodec implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) NegotiateCodec(ctx context.Context, req *proto.NegotiateCodecRequest) (*proto.NegotiateCodecResponse, error) {
+	return &proto.NegotiateCodecResponse{Codec: chooseCodec(req.GetCodecs())}, nil
+}
+
+// NegotiateCodec agrees on the argument codec via gRPC, keeping Struct
+// arguments when the plugin supports none of the preferred codecs
+func (c *CommandPluginGRPCClient) NegotiateCodec(preferred []string) (string, error) {
+	resp, err := c.client.NegotiateCodec(context.Background(), &proto.NegotiateCodecRequest{Codecs: preferred})
+	if status.Code(err) == codes.Unimplemented {
+		return CodecStructpb, nil
+	}
+	if err != nil {
+		return "", transportError(err)
+	}
+	name := resp.GetCodec()
+	if name == "" || name == CodecStructpb {
+		return CodecStructpb, nil
+	}
+	codec, ok := codecs[name]
+	if !ok {
+		return "", fmt.Errorf("plugin chose unknown argument codec %q", name)
+	}
+	c.codec = codec
+	return name, nil
+}
+
+// executeRequest encodes args with the negotiated codec, or as a Struct.
+// Large encoded arguments are handed off through a file when the plugin
+// accepted handoffs; release frees it once the call is done.
+func (c *CommandPluginGRPCClient) executeRequest(args map[string]interface{}) (req *proto.ExecuteRequest, release func(), err error) {
+	if c.codec != nil {
+		data, files, release, err := c.handoff.encode(c.codec, args)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &proto.ExecuteRequest{EncodedArgs: data, ArgsFiles: files, Codec: c.codec.Name()}, release, nil
+	}
+	pbArgs, err := newStruct(args)
+	if err != nil {
+		return nil, nil, &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	return &proto.ExecuteRequest{Args: pbArgs}, func() {}, nil
+}
+
+// EncodedArgs carries arguments encoded with the negotiated codec over
+// net/rpc, bypassing gob for the values themselves
+type EncodedArgs struct {
+	Codec string
+	Data  []byte
+
+	// Files are the arguments handed off through files, by name
+	Files map[string]string
+}
+
+// decode returns the arguments, with those handed off read from their files
+func (a EncodedArgs) decode() (map[string]interface{}, error) {
+	return decodeHandoff(a.Codec, a.Data, a.Files)
+}
+
+// NegotiateCodec implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) NegotiateCodec(preferred []string, resp *string) error {
+	*resp = chooseCodec(preferred)
+	return nil
+}
+
+// ExecuteEncoded implements the server side of the RPC interface for
+// arguments encoded with the negotiated codec
+func (s *CommandPluginRPCServer) ExecuteEncoded(req EncodedArgs, resp *ExecuteResponse) error {
+	args, err := req.decode()
+	if err != nil {
+		resp.Error = AsPluginError(err)
+		return nil
+	}
+	return s.Execute(args, resp)
+}
+
+// PlanEncoded implements the server side of the RPC interface for
+// arguments encoded with the negotiated codec
+func (s *CommandPluginRPCServer) PlanEncoded(req EncodedArgs, resp *ExecuteResponse) error {
+	args, err := req.decode()
+	if err != nil {
+		resp.Error = AsPluginError(err)
+		return nil
+	}
+	return s.Plan(args, resp)
+}
+
+// NegotiateCodec agrees on the argument codec via RPC, keeping gob when the
+// plugin predates codecs or supports none of the preferred ones
+func (c *CommandPluginRPCClient) NegotiateCodec(preferred []string) (string, error) {
+	var name string
+	if err := c.client.Call("Plugin.NegotiateCodec", preferred, &name); err != nil {
+		if missingMethod(err) {
+			return CodecGob, nil
+		}
+		return "", transportError(err)
+	}
+	if name == "" {
+		return CodecGob, nil
+	}
+	codec, ok := codecs[name]
+	if !ok {
+		return "", fmt.Errorf("plugin chose unknown argument codec %q", name)
+	}
+	c.codec = codec
+	return name, nil
+}
+
+// call invokes an Execute-style method, with the arguments encoded by the
+// negotiated codec when there is one and handed off when large
+func (c *CommandPluginRPCClient) call(method string, args map[string]interface{}, resp *ExecuteResponse) error {
+	if c.codec == nil {
+		return c.client.Call("Plugin."+method, args, resp)
+	}
+	req, release, err := c.encode(args)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return c.client.Call("Plugin."+method+"Encoded", req, resp)
+}
+
+// encode encodes args with the negotiated codec, handing the largest off
+// through files when the plugin accepted handoffs
+func (c *CommandPluginRPCClient) encode(args map[string]interface{}) (EncodedArgs, func(), error) {
+	data, files, release, err := c.handoff.encode(c.codec, args)
+	if err != nil {
+		return EncodedArgs{}, nil, err
+	}
+	return EncodedArgs{Codec: c.codec.Name(), Data: data, Files: files}, release, nil
+}
+
+// missingMethod reports whether an RPC failed because the plugin, built
+// against an older SDK, does not have the method
+func missingMethod(err error) bool {
+	var serverErr rpc.ServerError
+	return errors.As(err, &serverErr) && strings.HasPrefix(string(serverErr), "rpc: can't find method")
+}
@@ -0,0 +1,43 @@
+package pluginsdk
+
+// ArgCompensates is the reserved argument key carrying the call a
+// compensating capability is asked to undo, as a map with "capability",
+// "args" and "result". The host sets it when rolling back a pipeline.
+const ArgCompensates = "compensates"
+
+// ArgSteps is the reserved argument key carrying the results of the
+// pipeline steps that ran before a call, by step name
+const ArgSteps = "steps"
+
+// Compensation is a successful call to undo
+type Compensation struct {
+	// Capability is the capability that ran, e.g. "scaffold"
+	Capability string
+
+	// Args are the arguments it ran with, without reserved ones
+	Args map[string]interface{}
+
+	// Result is what it returned, e.g. the path it created
+	Result string
+}
+
+// Compensates returns the call a compensating capability is asked to
+// undo; ok is false for ordinary calls
+func Compensates(args map[string]interface{}) (c Compensation, ok bool) {
+	m, ok := args[ArgCompensates].(map[string]interface{})
+	if !ok {
+		return Compensation{}, false
+	}
+	c.Capability, _ = m["capability"].(string)
+	c.Args, _ = m["args"].(map[string]interface{})
+	c.Result, _ = m["result"].(string)
+	return c, true
+}
+
+// StepResult returns the result of an earlier step of the pipeline a call
+// belongs to
+func StepResult(args map[string]interface{}, step string) (string, bool) {
+	steps, _ := args[ArgSteps].(map[string]interface{})
+	result, ok := steps[step].(string)
+	return result, ok
+}
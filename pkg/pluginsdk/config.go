@@ -0,0 +1,57 @@
+package pluginsdk
+
+// EventConfigChanged is sent to plugins listing it under "events" when the
+// host applies a changed config at runtime, so they can refresh what they
+// took from it without being restarted; see ConfigChangeOf
+const EventConfigChanged = "config.changed"
+
+// ConfigChange is what an EventConfigChanged event reports
+type ConfigChange struct {
+	// Sections are the top-level config fields that changed, e.g.
+	// "personas" or "limits"
+	Sections []string
+
+	// PersonasAdded, PersonasRemoved and PersonasChanged name the personas
+	// the change touched
+	PersonasAdded   []string
+	PersonasRemoved []string
+	PersonasChanged []string
+
+	// Plugins are the plugins whose plugin_config entry changed
+	Plugins []string
+
+	// Config is the receiving plugin's current plugin_config entry, with
+	// secrets resolved, when it is among Plugins; nil otherwise. It is
+	// empty when the entry was removed.
+	Config map[string]interface{}
+}
+
+// ConfigChangeOf returns what an EventConfigChanged event of the host
+// reports; false for other events, including those of that type a plugin
+// sent
+func ConfigChangeOf(e Event) (ConfigChange, bool) {
+	if e.Type != EventConfigChanged || e.Plugin != "" {
+		return ConfigChange{}, false
+	}
+	c := ConfigChange{
+		Sections:        stringList(e.Data["sections"]),
+		PersonasAdded:   stringList(e.Data["personas_added"]),
+		PersonasRemoved: stringList(e.Data["personas_removed"]),
+		PersonasChanged: stringList(e.Data["personas_changed"]),
+		Plugins:         stringList(e.Data["plugins"]),
+	}
+	c.Config, _ = e.Data["config"].(map[string]interface{})
+	return c, true
+}
+
+// stringList returns the strings of an event data list
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
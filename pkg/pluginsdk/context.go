@@ -0,0 +1,90 @@
+package pluginsdk
+
+// ArgContext is the reserved argument key holding the shared context of
+// the request a call belongs to, as a RequestContext encoded as a map. Every
+// plugin in a chain of calls receives the same request, with the values the
+// plugins before it have set.
+const ArgContext = "context"
+
+// RequestContext is state shared by every plugin serving one request, so
+// multi-plugin flows need not pass it along in their own arguments
+type RequestContext struct {
+	// RequestID correlates the calls, logs and history of the request
+	RequestID string `json:"request_id"`
+
+	// User is who the request is made for, if the host knows
+	User string `json:"user,omitempty"`
+
+	// Service marks User as a service, another system calling the host,
+	// rather than a person
+	Service bool `json:"service,omitempty"`
+
+	// Namespace is the tenant, team or environment the request is served
+	// for, e.g. "staging"; feature flags can differ per namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// ProjectRoot is the absolute root of the project the request is about
+	ProjectRoot string `json:"project_root,omitempty"`
+
+	// Persona is the SuperClaude persona the request runs as
+	Persona string `json:"persona,omitempty"`
+
+	// Values are set by plugins with HostServices.SetContext
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+// ContextOf returns the request context of a call; RequestID is empty when
+// the call carries none
+func ContextOf(args map[string]interface{}) RequestContext {
+	raw, _ := args[ArgContext].(map[string]interface{})
+	rc := RequestContext{}
+	rc.RequestID, _ = raw["request_id"].(string)
+	rc.User, _ = raw["user"].(string)
+	rc.Service, _ = raw["service"].(bool)
+	rc.Namespace, _ = raw["namespace"].(string)
+	rc.ProjectRoot, _ = raw["project_root"].(string)
+	rc.Persona, _ = raw["persona"].(string)
+	rc.Values, _ = raw["values"].(map[string]interface{})
+	return rc
+}
+
+// Map encodes the context the way the ArgContext argument carries it
+func (rc RequestContext) Map() map[string]interface{} {
+	m := map[string]interface{}{"request_id": rc.RequestID}
+	if rc.User != "" {
+		m["user"] = rc.User
+	}
+	if rc.Service {
+		m["service"] = true
+	}
+	if rc.Namespace != "" {
+		m["namespace"] = rc.Namespace
+	}
+	if rc.ProjectRoot != "" {
+		m["project_root"] = rc.ProjectRoot
+	}
+	if rc.Persona != "" {
+		m["persona"] = rc.Persona
+	}
+	if len(rc.Values) > 0 {
+		values := make(map[string]interface{}, len(rc.Values))
+		for k, v := range rc.Values {
+			values[k] = v
+		}
+		m["values"] = values
+	}
+	return m
+}
+
+// WithContext adds the request context of the call being served to the
+// arguments of a call the plugin makes through HostServices.CallPlugin, so
+// the callee joins the same request
+func WithContext(args, callArgs map[string]interface{}) map[string]interface{} {
+	if rc, ok := args[ArgContext]; ok {
+		if callArgs == nil {
+			callArgs = make(map[string]interface{}, 1)
+		}
+		callArgs[ArgContext] = rc
+	}
+	return callArgs
+}
@@ -0,0 +1,229 @@
+package pluginsdk
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// States of a daemon plugin's server
+const (
+	DaemonStarting = "starting"
+	DaemonRunning  = "running"
+	DaemonStopped  = "stopped"
+	DaemonFailed   = "failed"
+)
+
+// DaemonStatus is the state of a daemon plugin's server
+type DaemonStatus struct {
+	// State is DaemonStarting, DaemonRunning, DaemonStopped or
+	// DaemonFailed
+	State string `json:"state"`
+
+	// Message says why the server is not running, e.g. the error it
+	// failed with
+	Message string `json:"message,omitempty"`
+
+	// Endpoint is where the server serves; the SDK fills it in from
+	// Endpoint when Status leaves it empty
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// DaemonPlugin is implemented by plugins that are long-lived servers, such
+// as an LSP bridge or a file indexer, rather than answering calls. The
+// host starts the server once the process is up, asks for its status
+// periodically, restarts it by its restart policy when it fails and stops
+// it before stopping the process. Serve daemons with ServeDaemon; plugins
+// that also take calls implement CommandPlugin as well and are served with
+// Serve.
+type DaemonPlugin interface {
+	Name() string
+	Version() string
+
+	// Start starts the server and returns once it runs; ctx ends when the
+	// host stops waiting. It is called again to restart a failed server.
+	Start(ctx context.Context) error
+
+	// Stop stops the server, e.g. closing its listener and connections
+	Stop(ctx context.Context) error
+
+	// Status reports the state of the server
+	Status() DaemonStatus
+
+	// Endpoint returns where the server serves, e.g. tcp://127.0.0.1:7000
+	// or a socket path, for clients to find it
+	Endpoint() string
+}
+
+// DaemonController is implemented by the host side of plugin clients that
+// can manage the server of a daemon plugin. Each call returns the status
+// afterwards; plugins that are no daemon, or predate daemon plugins,
+// answer with a CodeUnsupported error.
+type DaemonController interface {
+	StartDaemon(timeout time.Duration) (DaemonStatus, error)
+	StopDaemon(timeout time.Duration) (DaemonStatus, error)
+	DaemonStatus(timeout time.Duration) (DaemonStatus, error)
+}
+
+// ServeDaemon runs impl as a plugin process that serves no capabilities;
+// calls to it are refused. It blocks like Serve.
+func ServeDaemon(impl DaemonPlugin) {
+	Serve(daemonCommand{impl})
+}
+
+// daemonCommand serves a DaemonPlugin as a CommandPlugin without
+// capabilities
+type daemonCommand struct {
+	DaemonPlugin
+}
+
+func (d daemonCommand) Execute(args map[string]interface{}) (string, error) {
+	return "", NewError(CodeUnsupported, "plugin %s is a daemon and takes no calls", d.Name())
+}
+
+func (d daemonCommand) GetCapabilities() []Capability {
+	return nil
+}
+
+// Daemon operations the RPC servers run on the plugin
+const (
+	daemonStart  = "start"
+	daemonStop   = "stop"
+	daemonStatus = "status"
+)
+
+// runDaemon runs op on impl's daemon, bounded by timeout, and returns the
+// status afterwards
+func runDaemon(ctx context.Context, impl CommandPlugin, op string, timeout time.Duration) (DaemonStatus, error) {
+	d, ok := impl.(DaemonPlugin)
+	if !ok {
+		return DaemonStatus{}, NewError(CodeUnsupported, "plugin %s is not a daemon", impl.Name())
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var err error
+	switch op {
+	case daemonStart:
+		err = d.Start(ctx)
+	case daemonStop:
+		err = d.Stop(ctx)
+	}
+	st := d.Status()
+	if st.Endpoint == "" {
+		st.Endpoint = d.Endpoint()
+	}
+	return st, err
+}
+
+// DaemonResponse is the net/rpc reply to the daemon calls
+type DaemonResponse struct {
+	Status DaemonStatus
+	Error  *PluginError
+}
+
+// DaemonStart implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) DaemonStart(timeout time.Duration, resp *DaemonResponse) error {
+	st, err := runDaemon(context.Background(), s.Impl, daemonStart, timeout)
+	resp.Status, resp.Error = st, AsPluginError(err)
+	return nil
+}
+
+// DaemonStop implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) DaemonStop(timeout time.Duration, resp *DaemonResponse) error {
+	st, err := runDaemon(context.Background(), s.Impl, daemonStop, timeout)
+	resp.Status, resp.Error = st, AsPluginError(err)
+	return nil
+}
+
+// DaemonStatus implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) DaemonStatus(timeout time.Duration, resp *DaemonResponse) error {
+	st, err := runDaemon(context.Background(), s.Impl, daemonStatus, timeout)
+	resp.Status, resp.Error = st, AsPluginError(err)
+	return nil
+}
+
+// StartDaemon starts the plugin's server via RPC
+func (c *CommandPluginRPCClient) StartDaemon(timeout time.Duration) (DaemonStatus, error) {
+	return c.callDaemon("Plugin.DaemonStart", timeout)
+}
+
+// StopDaemon stops the plugin's server via RPC
+func (c *CommandPluginRPCClient) StopDaemon(timeout time.Duration) (DaemonStatus, error) {
+	return c.callDaemon("Plugin.DaemonStop", timeout)
+}
+
+// DaemonStatus asks for the state of the plugin's server via RPC
+func (c *CommandPluginRPCClient) DaemonStatus(timeout time.Duration) (DaemonStatus, error) {
+	return c.callDaemon("Plugin.DaemonStatus", timeout)
+}
+
+func (c *CommandPluginRPCClient) callDaemon(method string, timeout time.Duration) (DaemonStatus, error) {
+	var resp DaemonResponse
+	if err := c.client.Call(method, timeout, &resp); err != nil {
+		if missingMethod(err) {
+			return DaemonStatus{}, NewError(CodeUnsupported, "plugin predates daemon plugins")
+		}
+		return DaemonStatus{}, transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Status, resp.Error
+	}
+	return resp.Status, nil
+}
+
+// DaemonStart implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) DaemonStart(ctx context.Context, req *proto.DaemonRequest) (*proto.DaemonResponse, error) {
+	return daemonToProto(runDaemon(ctx, s.Impl, daemonStart, time.Duration(req.GetTimeoutMs())*time.Millisecond)), nil
+}
+
+// DaemonStop implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) DaemonStop(ctx context.Context, req *proto.DaemonRequest) (*proto.DaemonResponse, error) {
+	return daemonToProto(runDaemon(ctx, s.Impl, daemonStop, time.Duration(req.GetTimeoutMs())*time.Millisecond)), nil
+}
+
+// DaemonStatus implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) DaemonStatus(ctx context.Context, req *proto.DaemonRequest) (*proto.DaemonResponse, error) {
+	return daemonToProto(runDaemon(ctx, s.Impl, daemonStatus, time.Duration(req.GetTimeoutMs())*time.Millisecond)), nil
+}
+
+func daemonToProto(st DaemonStatus, err error) *proto.DaemonResponse {
+	return &proto.DaemonResponse{State: st.State, Message: st.Message, Endpoint: st.Endpoint, Error: errorToProto(err)}
+}
+
+// StartDaemon starts the plugin's server via gRPC
+func (c *CommandPluginGRPCClient) StartDaemon(timeout time.Duration) (DaemonStatus, error) {
+	return c.callDaemon(c.client.DaemonStart, timeout)
+}
+
+// StopDaemon stops the plugin's server via gRPC
+func (c *CommandPluginGRPCClient) StopDaemon(timeout time.Duration) (DaemonStatus, error) {
+	return c.callDaemon(c.client.DaemonStop, timeout)
+}
+
+// DaemonStatus asks for the state of the plugin's server via gRPC
+func (c *CommandPluginGRPCClient) DaemonStatus(timeout time.Duration) (DaemonStatus, error) {
+	return c.callDaemon(c.client.DaemonStatus, timeout)
+}
+
+func (c *CommandPluginGRPCClient) callDaemon(call func(context.Context, *proto.DaemonRequest, ...grpc.CallOption) (*proto.DaemonResponse, error), timeout time.Duration) (DaemonStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := call(ctx, &proto.DaemonRequest{TimeoutMs: timeout.Milliseconds()})
+	if status.Code(err) == codes.Unimplemented {
+		return DaemonStatus{}, NewError(CodeUnsupported, "plugin predates daemon plugins")
+	}
+	if err != nil {
+		return DaemonStatus{}, transportError(err)
+	}
+	st := DaemonStatus{State: resp.GetState(), Message: resp.GetMessage(), Endpoint: resp.GetEndpoint()}
+	return st, errorFromProto(resp.GetError())
+}
@@ -0,0 +1,86 @@
+package pluginsdk
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// DrainablePlugin is optionally implemented by plugins with work of their
+// own beyond the calls they serve, such as background indexing or buffered
+// writes. The host calls Drain before it stops the process: the plugin
+// stops taking on new internal work, checkpoints its state, e.g. to its
+// data directory in PluginWorkspace, and returns once it is safe to
+// terminate. ctx ends when the host stops waiting; returning an error
+// tells the host the plugin could not get to a safe state.
+type DrainablePlugin interface {
+	Drain(ctx context.Context) error
+}
+
+// Drainer is implemented by the host side of plugin clients that can ask
+// the plugin process to get ready to be stopped. It returns when the
+// plugin reports it is safe to terminate, or with an error when the plugin
+// failed to or did not answer within timeout.
+type Drainer interface {
+	Drain(timeout time.Duration) error
+}
+
+// drain runs impl's Drain, if it has one, bounded by timeout
+func drain(ctx context.Context, impl CommandPlugin, timeout time.Duration) error {
+	d, ok := impl.(DrainablePlugin)
+	if !ok {
+		return nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return d.Drain(ctx)
+}
+
+// Drain implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Drain(timeout time.Duration, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(drain(context.Background(), s.Impl, timeout))
+	return nil
+}
+
+// Drain asks the plugin to get ready to be stopped via RPC
+func (c *CommandPluginRPCClient) Drain(timeout time.Duration) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.Drain", timeout, &resp); err != nil {
+		if missingMethod(err) {
+			return NewError(CodeUnsupported, "plugin predates cooperative shutdown")
+		}
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+// Drain implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) Drain(ctx context.Context, req *proto.DrainRequest) (*proto.DrainResponse, error) {
+	timeout := time.Duration(req.GetTimeoutMs()) * time.Millisecond
+	return &proto.DrainResponse{Error: errorToProto(drain(ctx, s.Impl, timeout))}, nil
+}
+
+// Drain asks the plugin to get ready to be stopped via gRPC
+func (c *CommandPluginGRPCClient) Drain(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := c.client.Drain(ctx, &proto.DrainRequest{TimeoutMs: timeout.Milliseconds()})
+	if status.Code(err) == codes.Unimplemented {
+		return NewError(CodeUnsupported, "plugin predates cooperative shutdown")
+	}
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
@@ -0,0 +1,136 @@
+package pluginsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/rpc"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// ErrorCode classifies a plugin failure so callers can branch on it
+type ErrorCode string
+
+const (
+	// CodeUnknown is used for errors that carry no classification
+	CodeUnknown ErrorCode = "unknown"
+	// CodeInvalidArgument means the arguments were rejected
+	CodeInvalidArgument ErrorCode = "invalid_argument"
+	// CodeNotFound means something the call referred to does not exist
+	CodeNotFound ErrorCode = "not_found"
+	// CodePermissionDenied means the plugin refused the operation
+	CodePermissionDenied ErrorCode = "permission_denied"
+	// CodeUnsupported means the plugin does not offer the operation
+	CodeUnsupported ErrorCode = "unsupported"
+	// CodeUnavailable means a dependency or the plugin itself is unreachable
+	CodeUnavailable ErrorCode = "unavailable"
+	// CodeTimeout means the operation did not finish in time
+	CodeTimeout ErrorCode = "timeout"
+	// CodeInternal means the plugin hit a bug
+	CodeInternal ErrorCode = "internal"
+	// CodeTooLarge means a request or response exceeded the transport limits
+	CodeTooLarge ErrorCode = "too_large"
+	// CodeCanceled means the host canceled the call before it finished
+	CodeCanceled ErrorCode = "canceled"
+)
+
+// PluginError is the error envelope sent across the plugin boundary. Plugins
+// return it to classify failures; the host reconstructs it on the other side,
+// so callers can use errors.As regardless of the transport.
+type PluginError struct {
+	Code      ErrorCode
+	Message   string
+	Retryable bool
+	Details   map[string]string
+}
+
+// NewError creates a PluginError
+func NewError(code ErrorCode, format string, args ...interface{}) *PluginError {
+	return &PluginError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// AsPluginError returns err as a PluginError, wrapping unclassified errors
+// with CodeUnknown, or CodeTimeout for an expired context. It returns nil
+// for a nil error.
+func AsPluginError(err error) *PluginError {
+	if err == nil {
+		return nil
+	}
+	var pe *PluginError
+	if errors.As(err, &pe) {
+		return pe
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &PluginError{Code: CodeTimeout, Message: err.Error()}
+	}
+	return &PluginError{Code: CodeUnknown, Message: err.Error()}
+}
+
+// errorToProto converts an error into its wire form
+func errorToProto(err error) *proto.PluginError {
+	pe := AsPluginError(err)
+	if pe == nil {
+		return nil
+	}
+	return &proto.PluginError{
+		Code:      string(pe.Code),
+		Message:   pe.Message,
+		Retryable: pe.Retryable,
+		Details:   pe.Details,
+	}
+}
+
+// errorFromProto rebuilds a PluginError from its wire form, or returns nil
+func errorFromProto(e *proto.PluginError) error {
+	if e == nil {
+		return nil
+	}
+	code := ErrorCode(e.GetCode())
+	if code == "" {
+		code = CodeUnknown
+	}
+	return &PluginError{
+		Code:      code,
+		Message:   e.GetMessage(),
+		Retryable: e.GetRetryable(),
+		Details:   e.GetDetails(),
+	}
+}
+
+// transportError classifies failures of the connection itself rather than
+// of the plugin, which are usually worth retrying
+func transportError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, rpc.ErrShutdown) {
+		return &PluginError{Code: CodeUnavailable, Message: err.Error(), Retryable: true}
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.Unavailable:
+		return &PluginError{Code: CodeUnavailable, Message: st.Message(), Retryable: true}
+	case codes.DeadlineExceeded:
+		return &PluginError{Code: CodeTimeout, Message: st.Message(), Retryable: true}
+	case codes.Unimplemented:
+		return &PluginError{Code: CodeUnsupported, Message: st.Message()}
+	case codes.InvalidArgument:
+		return &PluginError{Code: CodeInvalidArgument, Message: st.Message()}
+	case codes.PermissionDenied:
+		return &PluginError{Code: CodePermissionDenied, Message: st.Message()}
+	case codes.ResourceExhausted:
+		return &PluginError{Code: CodeTooLarge, Message: st.Message()}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package pluginsdk
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// Event is something that happened in the host or the editor, such as
+// "plugin.loaded" or "file.saved"
+type Event struct {
+	Type   string                 `json:"type"`
+	Plugin string                 `json:"plugin,omitempty"`
+	Time   time.Time              `json:"time"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
+
+// EventHandler is optionally implemented by plugins that react to events.
+// The plugin's manifest lists the event types it wants under "events";
+// only those are delivered, one at a time and in order.
+type EventHandler interface {
+	HandleEvent(e Event) error
+}
+
+func eventToProto(e Event) (*proto.Event, error) {
+	pe := &proto.Event{Type: e.Type, Plugin: e.Plugin, TimeUnixNano: e.Time.UnixNano()}
+	if e.Data != nil {
+		data, err := structpb.NewStruct(e.Data)
+		if err != nil {
+			return nil, err
+		}
+		pe.Data = data
+	}
+	return pe, nil
+}
+
+func eventFromProto(pe *proto.Event) Event {
+	e := Event{Type: pe.GetType(), Plugin: pe.GetPlugin(), Time: time.Unix(0, pe.GetTimeUnixNano())}
+	if pe.GetData() != nil {
+		e.Data = pe.GetData().AsMap()
+	}
+	return e
+}
+
+// HandleEvent implements the server side of the gRPC interface. Plugins
+// that do not handle events ignore them.
+func (s *CommandPluginGRPCServer) HandleEvent(ctx context.Context, req *proto.Event) (*proto.HandleEventResponse, error) {
+	resp := &proto.HandleEventResponse{}
+	if h, ok := s.Impl.(EventHandler); ok {
+		resp.Error = errorToProto(h.HandleEvent(eventFromProto(req)))
+	}
+	return resp, nil
+}
+
+// HandleEvent delivers an event to the plugin via gRPC
+func (c *CommandPluginGRPCClient) HandleEvent(e Event) error {
+	pe, err := eventToProto(e)
+	if err != nil {
+		return &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	resp, err := c.client.HandleEvent(context.Background(), pe)
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+// HandleEvent implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) HandleEvent(e Event, resp *ExecuteResponse) error {
+	if h, ok := s.Impl.(EventHandler); ok {
+		resp.Error = AsPluginError(h.HandleEvent(e))
+	}
+	return nil
+}
+
+// HandleEvent delivers an event to the plugin via RPC
+func (c *CommandPluginRPCClient) HandleEvent(e Event) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.HandleEvent", e, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package pluginsdk
+
+import "encoding/json"
+
+// EventFilesChanged is sent to a plugin when files it watches with
+// HostServices.WatchFiles change. Its data holds the "watch" ID, the
+// "pattern" and the "changes"; see FileChanges.
+const EventFilesChanged = "files.changed"
+
+// Kinds of file changes
+const (
+	FileCreated  = "created"
+	FileModified = "modified"
+	FileRemoved  = "removed"
+)
+
+// FileChange is one file a watch saw change
+type FileChange struct {
+	// Path is the file's absolute path
+	Path string `json:"path"`
+
+	// Change is FileCreated, FileModified or FileRemoved
+	Change string `json:"change"`
+}
+
+// FileChanges returns the watch ID and the changes an EventFilesChanged
+// event reports; false for other events
+func FileChanges(e Event) (string, []FileChange, bool) {
+	if e.Type != EventFilesChanged {
+		return "", nil, false
+	}
+	id, _ := e.Data["watch"].(string)
+	data, err := json.Marshal(e.Data["changes"])
+	if err != nil {
+		return id, nil, false
+	}
+	var changes []FileChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return id, nil, false
+	}
+	return id, changes, true
+}
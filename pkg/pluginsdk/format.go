@@ -0,0 +1,62 @@
+package pluginsdk
+
+import "strings"
+
+// Result formats
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatDiff     = "diff"
+)
+
+// OptionAccept is the key of the execution options listing the result
+// formats the caller accepts, the one it prefers first, e.g.
+// {"accept": ["markdown", "text"]}
+const OptionAccept = "accept"
+
+// Accepted returns the result formats a call accepts, the preferred one
+// first; nil accepts any
+func Accepted(args map[string]interface{}) []string {
+	options, _ := args[ArgOptions].(map[string]interface{})
+	var formats []string
+	switch v := options[OptionAccept].(type) {
+	case []string:
+		formats = v
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				formats = append(formats, s)
+			}
+		}
+	case string:
+		formats = strings.Split(v, ",")
+	}
+
+	var accepted []string
+	for _, f := range formats {
+		if f = strings.ToLower(strings.TrimSpace(f)); f != "" {
+			accepted = append(accepted, f)
+		}
+	}
+	return accepted
+}
+
+// ResultFormat returns the format a capability answering in formats, its
+// default first, must answer a call in: the first format the call accepts
+// that it offers, else its default. The host converts the result when the
+// caller does not accept that. Capabilities without formats answer in
+// FormatText.
+func ResultFormat(args map[string]interface{}, formats []string) string {
+	if len(formats) == 0 {
+		return FormatText
+	}
+	for _, accepted := range Accepted(args) {
+		for _, f := range formats {
+			if f == accepted {
+				return f
+			}
+		}
+	}
+	return formats[0]
+}
@@ -0,0 +1,122 @@
+package pluginsdk
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// CommandPluginGRPCServer adapts a CommandPlugin to the generated gRPC service
+type CommandPluginGRPCServer struct {
+	proto.UnimplementedCommandPluginServer
+	Impl   CommandPlugin
+	broker *plugin.GRPCBroker
+}
+
+// Name implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) Name(ctx context.Context, req *proto.Empty) (*proto.NameResponse, error) {
+	return &proto.NameResponse{Name: s.Impl.Name()}, nil
+}
+
+// Version implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) Version(ctx context.Context, req *proto.Empty) (*proto.VersionResponse, error) {
+	return &proto.VersionResponse{Version: s.Impl.Version()}, nil
+}
+
+// Execute implements the server side of the gRPC interface. Plugin errors are
+// returned in the response body; only transport problems become gRPC errors.
+// Results too large for the host's limits are spilled to a file. Plugins
+// implementing ContextPlugin get the call's context, which also ends when
+// the host cancels the call.
+func (s *CommandPluginGRPCServer) Execute(ctx context.Context, req *proto.ExecuteRequest) (*proto.ExecuteResponse, error) {
+	args, err := requestArgs(req)
+	if err != nil {
+		return &proto.ExecuteResponse{Error: errorToProto(err)}, nil
+	}
+	ctx, done := runningCalls.start(ctx, args)
+	defer done()
+	if c, ok := s.Impl.(ContextPlugin); ok {
+		result, spillPath, partial, err := splitPartial(c.ExecuteContext(ctx, args))
+		return &proto.ExecuteResponse{Result: result, SpillPath: spillPath, Partial: partial, Error: errorToProto(err)}, nil
+	}
+	result, spillPath, partial, err := splitPartial(s.Impl.Execute(args))
+	return &proto.ExecuteResponse{Result: result, SpillPath: spillPath, Partial: partial, Error: errorToProto(err)}, nil
+}
+
+// GetCapabilities implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) GetCapabilities(ctx context.Context, req *proto.Empty) (*proto.GetCapabilitiesResponse, error) {
+	return capabilitiesToProto(s.Impl.GetCapabilities())
+}
+
+// CacheTTLs implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) CacheTTLs(ctx context.Context, req *proto.Empty) (*proto.CacheTTLsResponse, error) {
+	resp := &proto.CacheTTLsResponse{TtlSeconds: map[string]int64{}}
+	if c, ok := s.Impl.(CacheablePlugin); ok {
+		for capability, ttl := range c.CacheTTLs() {
+			resp.TtlSeconds[capability] = int64(ttl / time.Second)
+		}
+	}
+	return resp, nil
+}
+
+// CommandPluginGRPCClient is the gRPC client implementation of CommandPlugin
+type CommandPluginGRPCClient struct {
+	client proto.CommandPluginClient
+
+	// codec encodes arguments once negotiated; nil sends them as a Struct
+	codec Codec
+
+	// handoff receives large arguments once the plugin accepted handoffs
+	handoff *HandoffStore
+
+	// spills takes the results the plugin spills
+	spills *SpillStore
+}
+
+// Name calls the plugin's Name method via gRPC
+func (c *CommandPluginGRPCClient) Name() string {
+	resp, err := c.client.Name(context.Background(), &proto.Empty{})
+	if err != nil {
+		return ""
+	}
+	return resp.GetName()
+}
+
+// Version calls the plugin's Version method via gRPC
+func (c *CommandPluginGRPCClient) Version() string {
+	resp, err := c.client.Version(context.Background(), &proto.Empty{})
+	if err != nil {
+		return ""
+	}
+	return resp.GetVersion()
+}
+
+// Execute calls the plugin's Execute method via gRPC
+func (c *CommandPluginGRPCClient) Execute(args map[string]interface{}) (string, error) {
+	return c.ExecuteContext(context.Background(), args)
+}
+
+// GetCapabilities calls the plugin's GetCapabilities method via gRPC
+func (c *CommandPluginGRPCClient) GetCapabilities() []Capability {
+	resp, err := c.client.GetCapabilities(context.Background(), &proto.Empty{})
+	if err != nil {
+		return []Capability{}
+	}
+	return capabilitiesFromProto(resp)
+}
+
+// CacheTTLs calls the plugin's CacheTTLs method via gRPC
+func (c *CommandPluginGRPCClient) CacheTTLs() map[string]time.Duration {
+	resp, err := c.client.CacheTTLs(context.Background(), &proto.Empty{})
+	if err != nil {
+		return nil
+	}
+	ttls := make(map[string]time.Duration, len(resp.GetTtlSeconds()))
+	for capability, secs := range resp.GetTtlSeconds() {
+		ttls[capability] = time.Duration(secs) * time.Second
+	}
+	return ttls
+}
@@ -0,0 +1,324 @@
+package pluginsdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// handoffLinger is how long a handed-off file outlives its last call, so
+// a payload sent again shortly after, e.g. by a retry or the next stage of
+// a pipeline, is not written again
+const handoffLinger = 30 * time.Second
+
+// DefaultHandoffDir returns where arguments are handed off when the host
+// names no directory: /dev/shm on Linux, so the files live in shared
+// memory, and the system's temporary directory elsewhere
+func DefaultHandoffDir() string {
+	if runtime.GOOS == "linux" {
+		if fi, err := os.Stat("/dev/shm"); err == nil && fi.IsDir() {
+			return filepath.Join("/dev/shm", "opencode-handoff")
+		}
+	}
+	return filepath.Join(os.TempDir(), "opencode-handoff")
+}
+
+// HandoffStore holds the arguments a host hands off to plugin processes
+// through files instead of sending them in the request. Each argument gets
+// a file named by the hash of its content, so a blob going to several
+// calls, such as the plugins of a fan-out or the stages of a pipeline, is
+// written once and each plugin reads the same file.
+type HandoffStore struct {
+	dir      string
+	minBytes int
+
+	mu    sync.Mutex
+	files map[string]*handoffFile
+}
+
+// handoffFile is a handed-off payload and the calls still using it
+type handoffFile struct {
+	path  string
+	refs  int
+	timer *time.Timer
+}
+
+// NewHandoffStore returns a store handing off arguments through files in
+// dir once they encode to minBytes or more
+func NewHandoffStore(dir string, minBytes int) *HandoffStore {
+	return &HandoffStore{dir: dir, minBytes: minBytes, files: make(map[string]*handoffFile)}
+}
+
+// Dir returns the directory handed-off files are written to
+func (s *HandoffStore) Dir() string {
+	return s.dir
+}
+
+// prepare creates the store's directory, so a plugin offered handoffs can
+// tell whether it reads it
+func (s *HandoffStore) prepare() error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create handoff directory: %w", err)
+	}
+	return nil
+}
+
+// encode encodes args with codec. When they come to the store's threshold
+// or more, the largest arguments are handed off, each in a file of its
+// own, until the rest is below it. release frees the files once the call
+// is done. A nil store hands off nothing.
+func (s *HandoffStore) encode(codec Codec, args map[string]interface{}) (data []byte, files map[string]string, release func(), err error) {
+	data, err = codec.Marshal(args)
+	if err != nil {
+		return nil, nil, nil, &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	if s == nil || len(data) < s.minBytes {
+		return data, nil, func() {}, nil
+	}
+
+	type entry struct {
+		name string
+		data []byte
+	}
+	entries := make([]entry, 0, len(args))
+	for name, v := range args {
+		d, err := codec.Marshal(map[string]interface{}{name: v})
+		if err != nil {
+			return nil, nil, nil, &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+		}
+		entries = append(entries, entry{name: name, data: d})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if len(entries[i].data) != len(entries[j].data) {
+			return len(entries[i].data) > len(entries[j].data)
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	var releases []func()
+	release = func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	rest := make(map[string]interface{}, len(args))
+	for name, v := range args {
+		rest[name] = v
+	}
+	files = make(map[string]string)
+	size := len(data)
+	for _, e := range entries {
+		if size < s.minBytes {
+			break
+		}
+		path, r, err := s.put(e.data)
+		if err != nil {
+			release()
+			return nil, nil, nil, &PluginError{Code: CodeInternal, Message: fmt.Sprintf("failed to hand off argument %s: %v", e.name, err)}
+		}
+		releases = append(releases, r)
+		files[e.name] = path
+		delete(rest, e.name)
+		size -= len(e.data)
+	}
+	if data, err = codec.Marshal(rest); err != nil {
+		release()
+		return nil, nil, nil, &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	return data, files, release, nil
+}
+
+// put returns the path of a file holding data, writing it unless an
+// earlier call still has it, and the func releasing it once the call is
+// done
+func (s *HandoffStore) put(data []byte) (string, func(), error) {
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.files[key]
+	if f == nil {
+		path, err := s.write(key, data)
+		if err != nil {
+			return "", nil, err
+		}
+		f = &handoffFile{path: path}
+		s.files[key] = f
+	}
+	if f.timer != nil {
+		f.timer.Stop()
+		f.timer = nil
+	}
+	f.refs++
+
+	var once sync.Once
+	return f.path, func() { once.Do(func() { s.release(key, f) }) }, nil
+}
+
+// write stores data under its hash. It is written to a temporary name
+// first, so a plugin never reads half a payload.
+func (s *HandoffStore) write(key string, data []byte) (string, error) {
+	if err := s.prepare(); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".handoff-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	path := filepath.Join(s.dir, "args-"+key)
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+// release drops a call's use of a file, which is removed once it has
+// lingered unused
+func (s *HandoffStore) release(key string, f *handoffFile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f.refs--
+	if f.refs > 0 {
+		return
+	}
+	f.timer = time.AfterFunc(handoffLinger, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if f.refs == 0 && s.files[key] == f {
+			delete(s.files, key)
+			os.Remove(f.path)
+		}
+	})
+}
+
+// Close removes the files of the store, including those calls still use
+func (s *HandoffStore) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, f := range s.files {
+		if f.timer != nil {
+			f.timer.Stop()
+		}
+		os.Remove(f.path)
+		delete(s.files, key)
+	}
+}
+
+// HandoffNegotiator is implemented by the host side of plugin clients.
+// NegotiateHandoff offers the plugin the store's directory and reports
+// whether it accepted; from then on arguments the negotiated codec encodes
+// to the store's threshold or more are handed off through files. Plugins
+// predating handoffs, those without a negotiated codec and those that
+// cannot read the directory decline.
+type HandoffNegotiator interface {
+	NegotiateHandoff(store *HandoffStore) (bool, error)
+}
+
+// acceptHandoff is the plugin's answer to a handoff offer
+func acceptHandoff(dir string) bool {
+	fi, err := os.Stat(dir)
+	return err == nil && fi.IsDir()
+}
+
+// decodeHandoff decodes the arguments sent in a request and adds those the
+// host handed off in files, by name
+func decodeHandoff(codec string, data []byte, files map[string]string) (map[string]interface{}, error) {
+	args, err := decodeArgs(codec, data)
+	if err != nil || len(files) == 0 {
+		return args, err
+	}
+	if args == nil {
+		args = make(map[string]interface{}, len(files))
+	}
+	for name, path := range files {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, NewError(CodeInvalidArgument, "failed to read handed-off argument %s: %v", name, err)
+		}
+		v, err := decodeArgs(codec, raw)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v[name]
+	}
+	return args, nil
+}
+
+// NegotiateHandoff implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) NegotiateHandoff(ctx context.Context, req *proto.NegotiateHandoffRequest) (*proto.NegotiateHandoffResponse, error) {
+	return &proto.NegotiateHandoffResponse{Accepted: acceptHandoff(req.GetDir())}, nil
+}
+
+// NegotiateHandoff offers the plugin handoffs via gRPC
+func (c *CommandPluginGRPCClient) NegotiateHandoff(store *HandoffStore) (bool, error) {
+	if c.codec == nil {
+		return false, nil
+	}
+	if err := store.prepare(); err != nil {
+		return false, err
+	}
+	resp, err := c.client.NegotiateHandoff(context.Background(), &proto.NegotiateHandoffRequest{Dir: store.Dir()})
+	if status.Code(err) == codes.Unimplemented {
+		return false, nil
+	}
+	if err != nil {
+		return false, transportError(err)
+	}
+	if resp.GetAccepted() {
+		c.handoff = store
+	}
+	return resp.GetAccepted(), nil
+}
+
+// NegotiateHandoff implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) NegotiateHandoff(dir string, accepted *bool) error {
+	*accepted = acceptHandoff(dir)
+	return nil
+}
+
+// NegotiateHandoff offers the plugin handoffs via RPC
+func (c *CommandPluginRPCClient) NegotiateHandoff(store *HandoffStore) (bool, error) {
+	if c.codec == nil {
+		return false, nil
+	}
+	if err := store.prepare(); err != nil {
+		return false, err
+	}
+	var accepted bool
+	if err := c.client.Call("Plugin.NegotiateHandoff", store.Dir(), &accepted); err != nil {
+		if missingMethod(err) {
+			return false, nil
+		}
+		return false, transportError(err)
+	}
+	if accepted {
+		c.handoff = store
+	}
+	return accepted, nil
+}
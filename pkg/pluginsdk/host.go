@@ -0,0 +1,770 @@
+package pluginsdk
+
+import (
+	"context"
+	"net/rpc"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// HostServices are the calls a plugin can make back into its host
+type HostServices interface {
+	// RenderTemplate renders a prompt template from the host's templates
+	// directory, e.g. "commands/analyze", so prompt logic stays in one place
+	// and changes without rebuilding plugins
+	RenderTemplate(name string, data map[string]interface{}) (string, error)
+
+	// CallPlugin runs capability of another plugin with args and returns its
+	// result, so a plugin can delegate work, e.g. an orchestrator to its
+	// analyzers. The caller's manifest must list the plugin under
+	// permissions.calls; calls that would form a cycle are refused.
+	CallPlugin(plugin, capability string, args map[string]interface{}) (string, error)
+
+	// GetContext returns the shared context of a request the plugin is
+	// serving, by the ID in the call's reserved context argument. It
+	// includes values set by plugins the plugin has called since.
+	GetContext(requestID string) (RequestContext, error)
+
+	// SetContext sets a value in the shared context of a request, which
+	// plugins called from then on receive
+	SetContext(requestID, key string, value interface{}) error
+
+	// RegisterCapabilities adds capabilities to those the plugin offers,
+	// or replaces those with the same name and version, e.g. the tools a
+	// proxy discovered on an MCP server. They last while the plugin process
+	// runs; capabilities known at startup belong in GetCapabilities.
+	RegisterCapabilities(capabilities []Capability) error
+
+	// UnregisterCapabilities withdraws capabilities of the plugin by
+	// reference, e.g. "mcp.search@v2"; a bare name withdraws every version
+	UnregisterCapabilities(refs []string) error
+
+	// ReportProgress tells the host how far a request the plugin is serving
+	// has come, percent from 0 to 100 and what it is doing, e.g. 40 and
+	// "indexing src/". Tasks the request runs for show it; for other
+	// requests it is ignored. See the ReportProgress function.
+	ReportProgress(requestID string, percent float64, message string) error
+
+	// ReadFile returns a file under the roots the host granted the plugin
+	// in its manifest's permissions.files, by absolute path or relative
+	// to the first root, e.g. "src/main.go". Sandboxed plugins work with
+	// project files this way; the host records every operation.
+	ReadFile(path string) ([]byte, error)
+
+	// WriteFile replaces a file under a root granted for writing, creating
+	// it and its directories as needed
+	WriteFile(path string, data []byte) error
+
+	// GlobFiles returns the absolute paths of the files under the granted
+	// roots matching pattern, e.g. "src/**/*.go", where "**" matches any
+	// number of directories
+	GlobFiles(pattern string) ([]string, error)
+
+	// WatchFiles has the host send the plugin EventFilesChanged events
+	// whenever files matching pattern are created, modified or removed,
+	// and returns the watch's ID
+	WatchFiles(pattern string) (string, error)
+
+	// UnwatchFiles ends a watch WatchFiles started
+	UnwatchFiles(id string) error
+
+	// PublishEvent puts an event of the plugin on the host's event bus,
+	// where subscribers and other plugins receive it. Its type must start
+	// with the plugin's name, e.g. "linter.finished"; plugins cannot be
+	// named after the namespaces of host events, such as config or
+	// plugin. Events over the plugin's quota, or sent while an operator
+	// muted the plugin, are dropped without an error.
+	PublishEvent(eventType string, data map[string]interface{}) error
+
+	// Complete asks a model of one of the host's LLM providers, e.g.
+	// Anthropic or OpenAI, for a completion. The host holds the API keys,
+	// applies rate limits and token budgets and accounts the tokens to the
+	// plugin, whose manifest must list the provider under permissions.llm.
+	Complete(req LLMRequest) (LLMResponse, error)
+}
+
+// ArgCaller is the reserved argument key naming the plugin that made a call
+// through HostServices.CallPlugin; it is absent for calls from the host,
+// which drops it from the arguments of calls made from outside
+const ArgCaller = "caller"
+
+// HostAware is optionally implemented by plugins that call back into the
+// host. SetHostServices is called once, right after the host connects.
+type HostAware interface {
+	SetHostServices(host HostServices)
+}
+
+// SetHostRequest is the net/rpc argument to SetHost; BrokerID is where
+// the host serves its HostServices
+type SetHostRequest struct {
+	BrokerID uint32
+}
+
+// RenderTemplateRequest is the net/rpc argument to HostServices.RenderTemplate
+type RenderTemplateRequest struct {
+	Name string
+	Data map[string]interface{}
+}
+
+// CallPluginRequest is the net/rpc argument to HostServices.CallPlugin
+type CallPluginRequest struct {
+	Plugin     string
+	Capability string
+	Args       map[string]interface{}
+}
+
+// GetContextRequest is the net/rpc argument to HostServices.GetContext
+type GetContextRequest struct {
+	RequestID string
+}
+
+// GetContextResponse is the net/rpc result of HostServices.GetContext
+type GetContextResponse struct {
+	Context RequestContext
+	Error   *PluginError
+}
+
+// SetContextRequest is the net/rpc argument to HostServices.SetContext
+type SetContextRequest struct {
+	RequestID string
+	Key       string
+	Value     interface{}
+}
+
+// RegisterCapabilitiesRequest is the net/rpc argument to
+// HostServices.RegisterCapabilities
+type RegisterCapabilitiesRequest struct {
+	Capabilities []Capability
+}
+
+// UnregisterCapabilitiesRequest is the net/rpc argument to
+// HostServices.UnregisterCapabilities
+type UnregisterCapabilitiesRequest struct {
+	Refs []string
+}
+
+// ReportProgressRequest is the net/rpc argument to HostServices.ReportProgress
+type ReportProgressRequest struct {
+	RequestID string
+	Percent   float64
+	Message   string
+}
+
+// ReadFileRequest is the net/rpc argument to HostServices.ReadFile
+type ReadFileRequest struct {
+	Path string
+}
+
+// ReadFileResponse is the net/rpc result of HostServices.ReadFile
+type ReadFileResponse struct {
+	Data  []byte
+	Error *PluginError
+}
+
+// WriteFileRequest is the net/rpc argument to HostServices.WriteFile
+type WriteFileRequest struct {
+	Path string
+	Data []byte
+}
+
+// GlobFilesRequest is the net/rpc argument to HostServices.GlobFiles
+type GlobFilesRequest struct {
+	Pattern string
+}
+
+// GlobFilesResponse is the net/rpc result of HostServices.GlobFiles
+type GlobFilesResponse struct {
+	Paths []string
+	Error *PluginError
+}
+
+// WatchFilesRequest is the net/rpc argument to HostServices.WatchFiles
+type WatchFilesRequest struct {
+	Pattern string
+}
+
+// PublishEventRequest is the net/rpc argument to HostServices.PublishEvent
+type PublishEventRequest struct {
+	Type string
+	Data map[string]interface{}
+}
+
+// CompleteResponse is the net/rpc result of HostServices.Complete
+type CompleteResponse struct {
+	Response LLMResponse
+	Error    *PluginError
+}
+
+// UnwatchFilesRequest is the net/rpc argument to HostServices.UnwatchFiles
+type UnwatchFilesRequest struct {
+	ID string
+}
+
+// serveHostRPC offers host on the broker and tells the plugin where to find
+// it. Plugins built before host services reject the call and simply never
+// receive them.
+func serveHostRPC(host HostServices, broker *plugin.MuxBroker, client *rpc.Client) {
+	id := broker.NextId()
+	go broker.AcceptAndServe(id, &hostServicesRPCServer{impl: host})
+	_ = client.Call("Plugin.SetHost", &SetHostRequest{BrokerID: id}, new(interface{}))
+}
+
+// SetHost implements the server side of the RPC interface by dialing the
+// host's services and handing them to the plugin
+func (s *CommandPluginRPCServer) SetHost(req *SetHostRequest, resp *interface{}) error {
+	aware, ok := s.Impl.(HostAware)
+	if !ok {
+		return nil
+	}
+	conn, err := s.broker.Dial(req.BrokerID)
+	if err != nil {
+		return err
+	}
+	aware.SetHostServices(&hostServicesRPCClient{client: rpc.NewClient(conn)})
+	return nil
+}
+
+// hostServicesRPCServer runs in the host and answers the plugin's calls
+type hostServicesRPCServer struct {
+	impl HostServices
+}
+
+func (s *hostServicesRPCServer) RenderTemplate(req *RenderTemplateRequest, resp *ExecuteResponse) error {
+	result, err := s.impl.RenderTemplate(req.Name, req.Data)
+	resp.Result = result
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+func (s *hostServicesRPCServer) CallPlugin(req *CallPluginRequest, resp *ExecuteResponse) error {
+	result, err := s.impl.CallPlugin(req.Plugin, req.Capability, req.Args)
+	resp.Result = result
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+func (s *hostServicesRPCServer) GetContext(req *GetContextRequest, resp *GetContextResponse) error {
+	rc, err := s.impl.GetContext(req.RequestID)
+	resp.Context = rc
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+func (s *hostServicesRPCServer) SetContext(req *SetContextRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.SetContext(req.RequestID, req.Key, req.Value))
+	return nil
+}
+
+func (s *hostServicesRPCServer) RegisterCapabilities(req *RegisterCapabilitiesRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.RegisterCapabilities(req.Capabilities))
+	return nil
+}
+
+func (s *hostServicesRPCServer) UnregisterCapabilities(req *UnregisterCapabilitiesRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.UnregisterCapabilities(req.Refs))
+	return nil
+}
+
+func (s *hostServicesRPCServer) ReportProgress(req *ReportProgressRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.ReportProgress(req.RequestID, req.Percent, req.Message))
+	return nil
+}
+
+func (s *hostServicesRPCServer) ReadFile(req *ReadFileRequest, resp *ReadFileResponse) error {
+	data, err := s.impl.ReadFile(req.Path)
+	resp.Data = data
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+func (s *hostServicesRPCServer) WriteFile(req *WriteFileRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.WriteFile(req.Path, req.Data))
+	return nil
+}
+
+func (s *hostServicesRPCServer) GlobFiles(req *GlobFilesRequest, resp *GlobFilesResponse) error {
+	paths, err := s.impl.GlobFiles(req.Pattern)
+	resp.Paths = paths
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+func (s *hostServicesRPCServer) WatchFiles(req *WatchFilesRequest, resp *ExecuteResponse) error {
+	id, err := s.impl.WatchFiles(req.Pattern)
+	resp.Result = id
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+func (s *hostServicesRPCServer) UnwatchFiles(req *UnwatchFilesRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.UnwatchFiles(req.ID))
+	return nil
+}
+
+func (s *hostServicesRPCServer) PublishEvent(req *PublishEventRequest, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(s.impl.PublishEvent(req.Type, req.Data))
+	return nil
+}
+
+func (s *hostServicesRPCServer) Complete(req *LLMRequest, resp *CompleteResponse) error {
+	r, err := s.impl.Complete(*req)
+	resp.Response = r
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+// hostServicesRPCClient is the plugin's handle on the host over net/rpc
+type hostServicesRPCClient struct {
+	client *rpc.Client
+}
+
+func (c *hostServicesRPCClient) RenderTemplate(name string, data map[string]interface{}) (string, error) {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.RenderTemplate", &RenderTemplateRequest{Name: name, Data: data}, &resp); err != nil {
+		return "", transportError(err)
+	}
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	return resp.Result, nil
+}
+
+func (c *hostServicesRPCClient) CallPlugin(plugin, capability string, args map[string]interface{}) (string, error) {
+	var resp ExecuteResponse
+	req := &CallPluginRequest{Plugin: plugin, Capability: capability, Args: args}
+	if err := c.client.Call("Plugin.CallPlugin", req, &resp); err != nil {
+		return "", transportError(err)
+	}
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	return resp.Result, nil
+}
+
+func (c *hostServicesRPCClient) GetContext(requestID string) (RequestContext, error) {
+	var resp GetContextResponse
+	if err := c.client.Call("Plugin.GetContext", &GetContextRequest{RequestID: requestID}, &resp); err != nil {
+		return RequestContext{}, transportError(err)
+	}
+	if resp.Error != nil {
+		return RequestContext{}, resp.Error
+	}
+	return resp.Context, nil
+}
+
+func (c *hostServicesRPCClient) SetContext(requestID, key string, value interface{}) error {
+	var resp ExecuteResponse
+	req := &SetContextRequest{RequestID: requestID, Key: key, Value: value}
+	if err := c.client.Call("Plugin.SetContext", req, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) RegisterCapabilities(capabilities []Capability) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.RegisterCapabilities", &RegisterCapabilitiesRequest{Capabilities: capabilities}, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) UnregisterCapabilities(refs []string) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.UnregisterCapabilities", &UnregisterCapabilitiesRequest{Refs: refs}, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) ReportProgress(requestID string, percent float64, message string) error {
+	var resp ExecuteResponse
+	req := &ReportProgressRequest{RequestID: requestID, Percent: percent, Message: message}
+	if err := c.client.Call("Plugin.ReportProgress", req, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) ReadFile(path string) ([]byte, error) {
+	var resp ReadFileResponse
+	if err := c.client.Call("Plugin.ReadFile", &ReadFileRequest{Path: path}, &resp); err != nil {
+		return nil, transportError(err)
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Data, nil
+}
+
+func (c *hostServicesRPCClient) WriteFile(path string, data []byte) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.WriteFile", &WriteFileRequest{Path: path, Data: data}, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) GlobFiles(pattern string) ([]string, error) {
+	var resp GlobFilesResponse
+	if err := c.client.Call("Plugin.GlobFiles", &GlobFilesRequest{Pattern: pattern}, &resp); err != nil {
+		return nil, transportError(err)
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Paths, nil
+}
+
+func (c *hostServicesRPCClient) WatchFiles(pattern string) (string, error) {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.WatchFiles", &WatchFilesRequest{Pattern: pattern}, &resp); err != nil {
+		return "", transportError(err)
+	}
+	if resp.Error != nil {
+		return "", resp.Error
+	}
+	return resp.Result, nil
+}
+
+func (c *hostServicesRPCClient) UnwatchFiles(id string) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.UnwatchFiles", &UnwatchFilesRequest{ID: id}, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) PublishEvent(eventType string, data map[string]interface{}) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.PublishEvent", &PublishEventRequest{Type: eventType, Data: data}, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+func (c *hostServicesRPCClient) Complete(req LLMRequest) (LLMResponse, error) {
+	var resp CompleteResponse
+	if err := c.client.Call("Plugin.Complete", &req, &resp); err != nil {
+		return LLMResponse{}, transportError(err)
+	}
+	if resp.Error != nil {
+		return LLMResponse{}, resp.Error
+	}
+	return resp.Response, nil
+}
+
+// serveHostGRPC offers host on the broker and tells the plugin where to
+// find it. Plugins that do not implement SetHost, including those built with
+// an SDK that has no broker, answer Unimplemented and never receive them.
+func serveHostGRPC(host HostServices, broker *plugin.GRPCBroker, client proto.CommandPluginClient) {
+	id := broker.NextId()
+	go broker.AcceptAndServe(id, func(opts []grpc.ServerOption) *grpc.Server {
+		s := grpc.NewServer(opts...)
+		proto.RegisterHostServicesServer(s, &hostServicesGRPCServer{impl: host})
+		return s
+	})
+	_, _ = client.SetHost(context.Background(), &proto.SetHostRequest{BrokerId: id})
+}
+
+// SetHost implements the server side of the gRPC interface by dialing the
+// host's services and handing them to the plugin
+func (s *CommandPluginGRPCServer) SetHost(ctx context.Context, req *proto.SetHostRequest) (*proto.Empty, error) {
+	aware, ok := s.Impl.(HostAware)
+	if !ok {
+		return &proto.Empty{}, nil
+	}
+	conn, err := s.broker.Dial(req.GetBrokerId())
+	if err != nil {
+		return nil, err
+	}
+	aware.SetHostServices(&hostServicesGRPCClient{client: proto.NewHostServicesClient(conn)})
+	return &proto.Empty{}, nil
+}
+
+// hostServicesGRPCServer runs in the host and answers the plugin's calls
+type hostServicesGRPCServer struct {
+	proto.UnimplementedHostServicesServer
+	impl HostServices
+}
+
+func (s *hostServicesGRPCServer) RenderTemplate(ctx context.Context, req *proto.RenderTemplateRequest) (*proto.RenderTemplateResponse, error) {
+	result, err := s.impl.RenderTemplate(req.GetName(), req.GetData().AsMap())
+	return &proto.RenderTemplateResponse{Result: result, Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) CallPlugin(ctx context.Context, req *proto.CallPluginRequest) (*proto.ExecuteResponse, error) {
+	result, err := s.impl.CallPlugin(req.GetPlugin(), req.GetCapability(), req.GetArgs().AsMap())
+	return &proto.ExecuteResponse{Result: result, Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) GetContext(ctx context.Context, req *proto.GetContextRequest) (*proto.GetContextResponse, error) {
+	rc, err := s.impl.GetContext(req.GetRequestId())
+	if err != nil {
+		return &proto.GetContextResponse{Error: errorToProto(err)}, nil
+	}
+	pbContext, err := newStruct(rc.Map())
+	if err != nil {
+		return &proto.GetContextResponse{Error: errorToProto(&PluginError{Code: CodeInternal, Message: err.Error()})}, nil
+	}
+	return &proto.GetContextResponse{Context: pbContext}, nil
+}
+
+func (s *hostServicesGRPCServer) SetContext(ctx context.Context, req *proto.SetContextRequest) (*proto.SetContextResponse, error) {
+	err := s.impl.SetContext(req.GetRequestId(), req.GetKey(), req.GetValue().AsInterface())
+	return &proto.SetContextResponse{Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) RegisterCapabilities(ctx context.Context, req *proto.RegisterCapabilitiesRequest) (*proto.RegisterCapabilitiesResponse, error) {
+	caps := make([]Capability, 0, len(req.GetCapabilities()))
+	for _, pc := range req.GetCapabilities() {
+		caps = append(caps, CapabilityFromProto(pc))
+	}
+	return &proto.RegisterCapabilitiesResponse{Error: errorToProto(s.impl.RegisterCapabilities(caps))}, nil
+}
+
+func (s *hostServicesGRPCServer) UnregisterCapabilities(ctx context.Context, req *proto.UnregisterCapabilitiesRequest) (*proto.UnregisterCapabilitiesResponse, error) {
+	return &proto.UnregisterCapabilitiesResponse{Error: errorToProto(s.impl.UnregisterCapabilities(req.GetRefs()))}, nil
+}
+
+func (s *hostServicesGRPCServer) ReportProgress(ctx context.Context, req *proto.ReportProgressRequest) (*proto.ReportProgressResponse, error) {
+	err := s.impl.ReportProgress(req.GetRequestId(), req.GetPercent(), req.GetMessage())
+	return &proto.ReportProgressResponse{Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) ReadFile(ctx context.Context, req *proto.ReadFileRequest) (*proto.ReadFileResponse, error) {
+	data, err := s.impl.ReadFile(req.GetPath())
+	return &proto.ReadFileResponse{Data: data, Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) WriteFile(ctx context.Context, req *proto.WriteFileRequest) (*proto.WriteFileResponse, error) {
+	return &proto.WriteFileResponse{Error: errorToProto(s.impl.WriteFile(req.GetPath(), req.GetData()))}, nil
+}
+
+func (s *hostServicesGRPCServer) GlobFiles(ctx context.Context, req *proto.GlobFilesRequest) (*proto.GlobFilesResponse, error) {
+	paths, err := s.impl.GlobFiles(req.GetPattern())
+	return &proto.GlobFilesResponse{Paths: paths, Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) WatchFiles(ctx context.Context, req *proto.WatchFilesRequest) (*proto.WatchFilesResponse, error) {
+	id, err := s.impl.WatchFiles(req.GetPattern())
+	return &proto.WatchFilesResponse{Id: id, Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) UnwatchFiles(ctx context.Context, req *proto.UnwatchFilesRequest) (*proto.UnwatchFilesResponse, error) {
+	return &proto.UnwatchFilesResponse{Error: errorToProto(s.impl.UnwatchFiles(req.GetId()))}, nil
+}
+
+func (s *hostServicesGRPCServer) PublishEvent(ctx context.Context, req *proto.PublishEventRequest) (*proto.PublishEventResponse, error) {
+	err := s.impl.PublishEvent(req.GetType(), req.GetData().AsMap())
+	return &proto.PublishEventResponse{Error: errorToProto(err)}, nil
+}
+
+func (s *hostServicesGRPCServer) Complete(ctx context.Context, req *proto.CompleteRequest) (*proto.CompleteResponse, error) {
+	r, err := s.impl.Complete(llmRequestFromProto(req))
+	if err != nil {
+		return &proto.CompleteResponse{Error: errorToProto(err)}, nil
+	}
+	return &proto.CompleteResponse{
+		Provider:     r.Provider,
+		Model:        r.Model,
+		Content:      r.Content,
+		StopReason:   r.StopReason,
+		InputTokens:  int64(r.Usage.InputTokens),
+		OutputTokens: int64(r.Usage.OutputTokens),
+	}, nil
+}
+
+// hostServicesGRPCClient is the plugin's handle on the host over gRPC
+type hostServicesGRPCClient struct {
+	client proto.HostServicesClient
+}
+
+func (c *hostServicesGRPCClient) RenderTemplate(name string, data map[string]interface{}) (string, error) {
+	pbData, err := structpb.NewStruct(data)
+	if err != nil {
+		return "", &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	resp, err := c.client.RenderTemplate(context.Background(), &proto.RenderTemplateRequest{Name: name, Data: pbData})
+	if err != nil {
+		return "", transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return "", err
+	}
+	return resp.GetResult(), nil
+}
+
+func (c *hostServicesGRPCClient) CallPlugin(plugin, capability string, args map[string]interface{}) (string, error) {
+	pbArgs, err := newStruct(args)
+	if err != nil {
+		return "", &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	resp, err := c.client.CallPlugin(context.Background(), &proto.CallPluginRequest{Plugin: plugin, Capability: capability, Args: pbArgs})
+	if err != nil {
+		return "", transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return "", err
+	}
+	return resp.GetResult(), nil
+}
+
+func (c *hostServicesGRPCClient) GetContext(requestID string) (RequestContext, error) {
+	resp, err := c.client.GetContext(context.Background(), &proto.GetContextRequest{RequestId: requestID})
+	if err != nil {
+		return RequestContext{}, transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return RequestContext{}, err
+	}
+	return ContextOf(map[string]interface{}{ArgContext: resp.GetContext().AsMap()}), nil
+}
+
+func (c *hostServicesGRPCClient) SetContext(requestID, key string, value interface{}) error {
+	pbValue, err := structpb.NewValue(value)
+	if err != nil {
+		return &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	resp, err := c.client.SetContext(context.Background(), &proto.SetContextRequest{RequestId: requestID, Key: key, Value: pbValue})
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) RegisterCapabilities(capabilities []Capability) error {
+	req := &proto.RegisterCapabilitiesRequest{}
+	for _, capability := range capabilities {
+		pc, err := CapabilityToProto(capability)
+		if err != nil {
+			return &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+		}
+		req.Capabilities = append(req.Capabilities, pc)
+	}
+	resp, err := c.client.RegisterCapabilities(context.Background(), req)
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) UnregisterCapabilities(refs []string) error {
+	resp, err := c.client.UnregisterCapabilities(context.Background(), &proto.UnregisterCapabilitiesRequest{Refs: refs})
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) ReportProgress(requestID string, percent float64, message string) error {
+	req := &proto.ReportProgressRequest{RequestId: requestID, Percent: percent, Message: message}
+	resp, err := c.client.ReportProgress(context.Background(), req)
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) ReadFile(path string) ([]byte, error) {
+	resp, err := c.client.ReadFile(context.Background(), &proto.ReadFileRequest{Path: path})
+	if err != nil {
+		return nil, transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return nil, err
+	}
+	return resp.GetData(), nil
+}
+
+func (c *hostServicesGRPCClient) WriteFile(path string, data []byte) error {
+	resp, err := c.client.WriteFile(context.Background(), &proto.WriteFileRequest{Path: path, Data: data})
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) GlobFiles(pattern string) ([]string, error) {
+	resp, err := c.client.GlobFiles(context.Background(), &proto.GlobFilesRequest{Pattern: pattern})
+	if err != nil {
+		return nil, transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return nil, err
+	}
+	return resp.GetPaths(), nil
+}
+
+func (c *hostServicesGRPCClient) WatchFiles(pattern string) (string, error) {
+	resp, err := c.client.WatchFiles(context.Background(), &proto.WatchFilesRequest{Pattern: pattern})
+	if err != nil {
+		return "", transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return "", err
+	}
+	return resp.GetId(), nil
+}
+
+func (c *hostServicesGRPCClient) UnwatchFiles(id string) error {
+	resp, err := c.client.UnwatchFiles(context.Background(), &proto.UnwatchFilesRequest{Id: id})
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) PublishEvent(eventType string, data map[string]interface{}) error {
+	pbData, err := structpb.NewStruct(data)
+	if err != nil {
+		return &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	resp, err := c.client.PublishEvent(context.Background(), &proto.PublishEventRequest{Type: eventType, Data: pbData})
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+func (c *hostServicesGRPCClient) Complete(req LLMRequest) (LLMResponse, error) {
+	resp, err := c.client.Complete(context.Background(), llmRequestToProto(req))
+	if err != nil {
+		return LLMResponse{}, transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return LLMResponse{}, err
+	}
+	return LLMResponse{
+		Provider:   resp.GetProvider(),
+		Model:      resp.GetModel(),
+		Content:    resp.GetContent(),
+		StopReason: resp.GetStopReason(),
+		Usage:      LLMUsage{InputTokens: int(resp.GetInputTokens()), OutputTokens: int(resp.GetOutputTokens())},
+	}, nil
+}
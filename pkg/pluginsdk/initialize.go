@@ -0,0 +1,60 @@
+package pluginsdk
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// InitializablePlugin is optionally implemented by plugins that take
+// configuration. The host calls Initialize once per process, before the
+// first call, with the plugin's entry from the host's plugin_config.
+// References such as "secret://openai_api_key" are already replaced by the
+// secret, so plugins must not log the values they receive.
+type InitializablePlugin interface {
+	Initialize(config map[string]interface{}) error
+}
+
+// Initialize implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) Initialize(ctx context.Context, req *proto.InitializeRequest) (*proto.InitializeResponse, error) {
+	resp := &proto.InitializeResponse{}
+	if p, ok := s.Impl.(InitializablePlugin); ok {
+		resp.Error = errorToProto(p.Initialize(req.GetConfig().AsMap()))
+	}
+	return resp, nil
+}
+
+// Initialize passes the plugin its configuration via gRPC
+func (c *CommandPluginGRPCClient) Initialize(config map[string]interface{}) error {
+	pbConfig, err := structpb.NewStruct(config)
+	if err != nil {
+		return &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+	resp, err := c.client.Initialize(context.Background(), &proto.InitializeRequest{Config: pbConfig})
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
+
+// Initialize implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Initialize(config map[string]interface{}, resp *ExecuteResponse) error {
+	if p, ok := s.Impl.(InitializablePlugin); ok {
+		resp.Error = AsPluginError(p.Initialize(config))
+	}
+	return nil
+}
+
+// Initialize passes the plugin its configuration via RPC
+func (c *CommandPluginRPCClient) Initialize(config map[string]interface{}) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.Initialize", config, &resp); err != nil {
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
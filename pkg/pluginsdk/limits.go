@@ -0,0 +1,128 @@
+package pluginsdk
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// Environment variables through which the host passes its transport limits
+// to plugin processes
+const (
+	EnvMaxRequestBytes  = "OPENCODE_MAX_REQUEST_BYTES"
+	EnvMaxResponseBytes = "OPENCODE_MAX_RESPONSE_BYTES"
+	EnvSpillBytes       = "OPENCODE_SPILL_BYTES"
+	EnvSpillDir         = "OPENCODE_SPILL_DIR"
+)
+
+// DefaultMaxMessageBytes limits requests and responses when the host sets
+// no limit. It is gRPC's own default.
+const DefaultMaxMessageBytes = 4 << 20
+
+// ResultFilePrefix marks a result the host passes on that was too large to
+// send and was written to a file instead: the result is the prefix
+// followed by the file's path. The caller owns the file and removes it
+// when done. Plugins do not write it themselves; they report the file in
+// their response, and only the host marks results with it.
+const ResultFilePrefix = "opencode-result-file:"
+
+// ResultFile returns the path of a spilled result. Hosts ask their
+// SpillStore instead, as anyone can return text starting with the prefix.
+func ResultFile(result string) (string, bool) {
+	if !strings.HasPrefix(result, ResultFilePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(result, ResultFilePrefix), true
+}
+
+// ReadResult returns a result, reading it back from its file when it was
+// spilled. Only use it for results known to fit in memory.
+func ReadResult(result string) (string, error) {
+	path, ok := ResultFile(result)
+	if !ok {
+		return result, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// transportLimits are the limits the host passed to this plugin process
+type transportLimits struct {
+	maxRequest  int
+	maxResponse int
+	spill       int
+	spillDir    string
+}
+
+// limitsFromEnv reads the limits from the environment. Results are spilled
+// from the response limit on unless the host asks for less.
+func limitsFromEnv() transportLimits {
+	l := transportLimits{
+		maxRequest:  envBytes(EnvMaxRequestBytes, DefaultMaxMessageBytes),
+		maxResponse: envBytes(EnvMaxResponseBytes, DefaultMaxMessageBytes),
+		spillDir:    os.Getenv(EnvSpillDir),
+	}
+	l.spill = envBytes(EnvSpillBytes, l.maxResponse)
+	return l
+}
+
+func envBytes(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return def
+}
+
+// limitedGRPCServer creates the plugin's gRPC server with the host's limits
+func limitedGRPCServer(opts []grpc.ServerOption) *grpc.Server {
+	l := limitsFromEnv()
+	opts = append(opts, grpc.MaxRecvMsgSize(l.maxRequest), grpc.MaxSendMsgSize(l.maxResponse))
+	return grpc.NewServer(opts...)
+}
+
+// spillResult writes a result larger than the spill threshold to a file
+// in the host's spill directory and returns the file's path instead of
+// the result. A result that can neither be written nor sent is refused
+// with CodeTooLarge.
+func spillResult(result string, err error) (string, string, error) {
+	l := limitsFromEnv()
+	if err != nil || len(result) <= l.spill {
+		return result, "", err
+	}
+
+	path, werr := writeSpill(l.spillDir, result)
+	if werr == nil {
+		return "", path, nil
+	}
+	if len(result) > l.maxResponse {
+		return "", "", NewError(CodeTooLarge, "result of %d bytes exceeds the %d byte response limit and could not be spilled: %v", len(result), l.maxResponse, werr)
+	}
+	return result, "", nil
+}
+
+func writeSpill(dir, result string) (string, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return "", err
+		}
+	}
+	f, err := os.CreateTemp(dir, "opencode-result-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(result); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
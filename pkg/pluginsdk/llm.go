@@ -0,0 +1,112 @@
+package pluginsdk
+
+import "github.com/Kirchlive/super/pkg/pluginsdk/proto"
+
+// Roles of the messages of an LLM conversation
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// LLMMessage is one turn of a conversation with a model
+type LLMMessage struct {
+	// Role is RoleUser or RoleAssistant
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// LLMRequest asks the host's LLM service for a completion. The host holds
+// the API keys, so plugins name providers and models but never see them.
+type LLMRequest struct {
+	// Provider names one of the host's llm.providers, e.g. "anthropic";
+	// empty picks the host's default
+	Provider string `json:"provider,omitempty"`
+
+	// Model is the provider's model, e.g. "claude-sonnet-4-5"; empty picks
+	// the one the host configured for the provider
+	Model string `json:"model,omitempty"`
+
+	// System is the system prompt
+	System string `json:"system,omitempty"`
+
+	Messages []LLMMessage `json:"messages"`
+
+	// MaxTokens caps the tokens generated; 0 leaves it to the host
+	MaxTokens int `json:"max_tokens,omitempty"`
+
+	// Temperature is sent when set; nil keeps the provider's default
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// CallID is the call the request is made for, which the host charges
+	// its cost to; set it with For
+	CallID string `json:"call_id,omitempty"`
+}
+
+// LLMUsage is the tokens a completion consumed
+type LLMUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// LLMResponse is a completion of the host's LLM service
+type LLMResponse struct {
+	// Provider and Model are what answered, after the host's defaults
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	Content string `json:"content"`
+
+	// StopReason is why generation stopped as the provider reports it,
+	// e.g. "end_turn" or "max_tokens"
+	StopReason string `json:"stop_reason"`
+
+	Usage LLMUsage `json:"usage"`
+}
+
+// Prompt returns a request for a single user message, e.g.
+// host.Complete(pluginsdk.Prompt("Summarize: " + text))
+func Prompt(text string) LLMRequest {
+	return LLMRequest{Messages: []LLMMessage{{Role: RoleUser, Content: text}}}
+}
+
+// For returns the request charged to the call args were passed to, so the
+// host accounts its cost to the capability and caller of that call, e.g.
+// host.Complete(pluginsdk.Prompt(text).For(args)). Requests without it are
+// charged to the plugin's only running call, if it has just one, else to
+// the anonymous caller.
+func (r LLMRequest) For(args map[string]interface{}) LLMRequest {
+	r.CallID, _ = args[ArgCallID].(string)
+	return r
+}
+
+// llmRequestToProto converts req for the gRPC interface
+func llmRequestToProto(req LLMRequest) *proto.CompleteRequest {
+	pb := &proto.CompleteRequest{
+		Provider:    req.Provider,
+		Model:       req.Model,
+		System:      req.System,
+		MaxTokens:   int32(req.MaxTokens),
+		Temperature: req.Temperature,
+		CallId:      req.CallID,
+	}
+	for _, m := range req.Messages {
+		pb.Messages = append(pb.Messages, &proto.LLMMessage{Role: m.Role, Content: m.Content})
+	}
+	return pb
+}
+
+// llmRequestFromProto converts a request of the gRPC interface
+func llmRequestFromProto(pb *proto.CompleteRequest) LLMRequest {
+	req := LLMRequest{
+		Provider:    pb.GetProvider(),
+		Model:       pb.GetModel(),
+		System:      pb.GetSystem(),
+		MaxTokens:   int(pb.GetMaxTokens()),
+		Temperature: pb.Temperature,
+		CallID:      pb.GetCallId(),
+	}
+	for _, m := range pb.GetMessages() {
+		req.Messages = append(req.Messages, LLMMessage{Role: m.GetRole(), Content: m.GetContent()})
+	}
+	return req
+}
@@ -0,0 +1,176 @@
+package pluginsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ArgLocale is the reserved argument key holding the locale the caller
+// wants the result in, as a BCP 47 tag such as "de-DE". When the plugin's
+// manifest lists the locales it supports, the host passes the closest of
+// them.
+const ArgLocale = "locale"
+
+// Locale returns the locale of a call, or "" when the caller named none
+func Locale(args map[string]interface{}) string {
+	locale, _ := args[ArgLocale].(string)
+	return locale
+}
+
+// NormalizeLocale brings a tag into the form "de-DE": underscores become
+// dashes, the language is lower case and the region upper case
+func NormalizeLocale(tag string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"), "-")
+	for i, p := range parts {
+		switch {
+		case i == 0:
+			parts[i] = strings.ToLower(p)
+		case len(p) == 2:
+			parts[i] = strings.ToUpper(p)
+		case len(p) == 4:
+			parts[i] = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		default:
+			parts[i] = strings.ToLower(p)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// language returns the language of a normalized tag, "de" for "de-DE"
+func language(tag string) string {
+	if i := strings.Index(tag, "-"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// MatchLocale returns the entry of supported closest to requested: the same
+// tag, else the most general one of the same language, so "de-AT" matches
+// "de" or, failing that, "de-DE". It returns "" when none shares the
+// language.
+func MatchLocale(requested string, supported []string) string {
+	want := NormalizeLocale(requested)
+	if want == "" {
+		return ""
+	}
+	best := ""
+	for _, s := range supported {
+		have := NormalizeLocale(s)
+		switch {
+		case have == want:
+			return s
+		case language(have) != language(want):
+		case best == "" || len(have) < len(NormalizeLocale(best)):
+			best = s
+		}
+	}
+	return best
+}
+
+// Catalog holds translated messages by locale and key, for plugins
+// localizing their results and for the host localizing its errors.
+// Messages are fmt format strings. A Catalog is safe for concurrent use.
+type Catalog struct {
+	fallback string
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// NewCatalog creates an empty catalog falling back to messages of the
+// fallback locale, e.g. "en", for keys a locale lacks
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog{fallback: NormalizeLocale(fallback), messages: make(map[string]map[string]string)}
+}
+
+// Add adds messages for a locale, replacing those with the same keys
+func (c *Catalog) Add(locale string, messages map[string]string) {
+	locale = NormalizeLocale(locale)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m := c.messages[locale]
+	if m == nil {
+		m = make(map[string]string, len(messages))
+		c.messages[locale] = m
+	}
+	for k, v := range messages {
+		m[k] = v
+	}
+}
+
+// LoadFS adds the messages of every "<locale>.json" file in dir of fsys,
+// each a JSON object of keys to messages. Plugins typically embed their
+// translations with go:embed.
+func (c *Catalog) LoadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("invalid catalog %s: %w", f, err)
+		}
+		c.Add(strings.TrimSuffix(path.Base(f), ".json"), messages)
+	}
+	return nil
+}
+
+// Locales lists the locales the catalog has messages for, sorted
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.locales()
+}
+
+// locales lists the locales of the catalog; the caller holds c.mu
+func (c *Catalog) locales() []string {
+	locales := make([]string, 0, len(c.messages))
+	for l := range c.messages {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup returns the message for key in the locale closest to locale, or
+// in the fallback locale
+func (c *Catalog) Lookup(locale, key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, l := range []string{MatchLocale(locale, c.locales()), c.fallback} {
+		if msg, ok := c.messages[l][key]; ok && l != "" {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Message formats the message for key in the given locale with args. A key
+// no locale has is formatted itself, so missing translations stay visible
+// rather than silent.
+func (c *Catalog) Message(locale, key string, args ...interface{}) string {
+	msg, ok := c.Lookup(locale, key)
+	if !ok {
+		msg = key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Localize is Message in the locale of a call
+func (c *Catalog) Localize(callArgs map[string]interface{}, key string, args ...interface{}) string {
+	return c.Message(Locale(callArgs), key, args...)
+}
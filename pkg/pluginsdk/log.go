@@ -0,0 +1,114 @@
+package pluginsdk
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// EnvLogLevel is the environment variable through which the host passes a
+// plugin process the level to log at, when an operator set one for the
+// plugin. Without it plugins log at info.
+const EnvLogLevel = "OPENCODE_LOG_LEVEL"
+
+// LogLevelSetter is implemented by the host side of plugin clients that can
+// switch the level the plugin process logs at. An empty level restores the
+// level the process started with.
+type LogLevelSetter interface {
+	SetLogLevel(level string) error
+}
+
+// LogLevelAware is implemented by plugins that log through a logger of
+// their own rather than Logger, to follow the level the host sets
+type LogLevelAware interface {
+	SetLogLevel(level hclog.Level)
+}
+
+var (
+	loggerOnce   sync.Once
+	logger       hclog.Logger
+	initialLevel hclog.Level
+
+	// logOutput is stderr as the process started with it. go-plugin's
+	// Serve redirects os.Stderr to a stream the host does not keep.
+	logOutput = os.Stderr
+)
+
+// Logger returns the logger of the plugin process. It writes JSON lines to
+// stderr, which the host keeps as the plugin's log with their levels and
+// fields, and logs at the level the host asks for, which operators can
+// switch while the plugin runs.
+func Logger() hclog.Logger {
+	loggerOnce.Do(func() {
+		initialLevel = hclog.Info
+		if level := hclog.LevelFromString(os.Getenv(EnvLogLevel)); level != hclog.NoLevel {
+			initialLevel = level
+		}
+		logger = hclog.New(&hclog.LoggerOptions{
+			Level:      initialLevel,
+			Output:     logOutput,
+			JSONFormat: true,
+		})
+	})
+	return logger
+}
+
+// setLogLevel applies a level the host sent to Logger and to impl
+func setLogLevel(impl CommandPlugin, name string) error {
+	l := Logger()
+	level := initialLevel
+	if name != "" {
+		if level = hclog.LevelFromString(name); level == hclog.NoLevel {
+			return NewError(CodeInvalidArgument, "unknown log level %q", name)
+		}
+	}
+	l.SetLevel(level)
+	if a, ok := impl.(LogLevelAware); ok {
+		a.SetLogLevel(level)
+	}
+	return nil
+}
+
+// SetLogLevel implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) SetLogLevel(level string, resp *ExecuteResponse) error {
+	resp.Error = AsPluginError(setLogLevel(s.Impl, level))
+	return nil
+}
+
+// SetLogLevel switches the level the plugin logs at via RPC
+func (c *CommandPluginRPCClient) SetLogLevel(level string) error {
+	var resp ExecuteResponse
+	if err := c.client.Call("Plugin.SetLogLevel", level, &resp); err != nil {
+		if missingMethod(err) {
+			return NewError(CodeUnsupported, "plugin predates runtime log levels")
+		}
+		return transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+// SetLogLevel implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) SetLogLevel(ctx context.Context, req *proto.SetLogLevelRequest) (*proto.SetLogLevelResponse, error) {
+	return &proto.SetLogLevelResponse{Error: errorToProto(setLogLevel(s.Impl, req.GetLevel()))}, nil
+}
+
+// SetLogLevel switches the level the plugin logs at via gRPC
+func (c *CommandPluginGRPCClient) SetLogLevel(level string) error {
+	resp, err := c.client.SetLogLevel(context.Background(), &proto.SetLogLevelRequest{Level: level})
+	if status.Code(err) == codes.Unimplemented {
+		return NewError(CodeUnsupported, "plugin predates runtime log levels")
+	}
+	if err != nil {
+		return transportError(err)
+	}
+	return errorFromProto(resp.GetError())
+}
@@ -0,0 +1,92 @@
+package pluginsdk
+
+import (
+	"context"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// ArgDryRun is the reserved argument key marking a dry run: the plugin
+// describes what it would do instead of doing it. The host sets it on the
+// arguments passed to Plan.
+const ArgDryRun = "dry_run"
+
+// DryRun reports whether a call is a dry run
+func DryRun(args map[string]interface{}) bool {
+	dryRun, _ := args[ArgDryRun].(bool)
+	return dryRun
+}
+
+// PlanningPlugin is optionally implemented by plugins that support dry
+// runs. Plan returns a description of what Execute would do with the same
+// arguments, such as the files it would change or the commands it would
+// run, and must have no side effects. Plugins without it refuse dry runs
+// with CodeUnsupported, so a plan is never answered by executing.
+type PlanningPlugin interface {
+	Plan(args map[string]interface{}) (string, error)
+}
+
+func errNoPlan() *PluginError {
+	return &PluginError{Code: CodeUnsupported, Message: "plugin does not support dry runs"}
+}
+
+// Plan implements the server side of the gRPC interface
+func (s *CommandPluginGRPCServer) Plan(ctx context.Context, req *proto.ExecuteRequest) (*proto.ExecuteResponse, error) {
+	p, ok := s.Impl.(PlanningPlugin)
+	if !ok {
+		return &proto.ExecuteResponse{Error: errorToProto(errNoPlan())}, nil
+	}
+	args, err := requestArgs(req)
+	if err != nil {
+		return &proto.ExecuteResponse{Error: errorToProto(err)}, nil
+	}
+	result, spillPath, err := spillResult(p.Plan(args))
+	return &proto.ExecuteResponse{Result: result, SpillPath: spillPath, Error: errorToProto(err)}, nil
+}
+
+// Plan asks the plugin for a dry run via gRPC
+func (c *CommandPluginGRPCClient) Plan(args map[string]interface{}) (string, error) {
+	req, release, err := c.executeRequest(args)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	resp, err := c.client.Plan(context.Background(), req)
+	if err != nil {
+		return "", transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return resp.GetResult(), err
+	}
+	return c.spills.result(resp.GetResult(), resp.GetSpillPath())
+}
+
+// Plan implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Plan(args map[string]interface{}, resp *ExecuteResponse) error {
+	p, ok := s.Impl.(PlanningPlugin)
+	if !ok {
+		resp.Error = errNoPlan()
+		return nil
+	}
+	result, spillPath, err := spillResult(p.Plan(args))
+	resp.Result = result
+	resp.SpillPath = spillPath
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+// Plan asks the plugin for a dry run via RPC
+func (c *CommandPluginRPCClient) Plan(args map[string]interface{}) (string, error) {
+	var resp ExecuteResponse
+	if err := c.call("Plan", args, &resp); err != nil {
+		// Plugins built before dry runs existed do not have the method
+		if missingMethod(err) {
+			return "", errNoPlan()
+		}
+		return "", transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Result, resp.Error
+	}
+	return c.spills.result(resp.Result, resp.SpillPath)
+}
@@ -0,0 +1,14 @@
+package pluginsdk
+
+// ReportProgress tells the host how far the request of the call with args
+// has come, e.g. ReportProgress(host, args, 40, "indexing src/") from a
+// capability scanning a repository. Long operations started as host tasks
+// report as they go, so callers can watch them; calls without a request
+// context and plugins without host services report nothing.
+func ReportProgress(host HostServices, args map[string]interface{}, percent float64, message string) error {
+	id := ContextOf(args).RequestID
+	if host == nil || id == "" {
+		return nil
+	}
+	return host.ReportProgress(id, percent, message)
+}
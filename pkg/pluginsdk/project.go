@@ -0,0 +1,69 @@
+package pluginsdk
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ArgProject is the reserved argument key holding the project context the
+// host computed for a call's working directory, as a ProjectContext encoded
+// as a map. Only plugins that ask for it receive it, and callers cannot
+// set it.
+const ArgProject = "project_context"
+
+// ProjectContext describes the project a call works on
+type ProjectContext struct {
+	// Root is the top of the git work tree, or the working directory when
+	// it is not in one
+	Root string `json:"root"`
+
+	// Git is set when Root is a git work tree. Branch is empty for a
+	// detached HEAD, Commit for a repository without commits.
+	Git    bool   `json:"git"`
+	Branch string `json:"branch,omitempty"`
+	Commit string `json:"commit,omitempty"`
+
+	// Dirty are the changed and untracked files relative to Root, at most
+	// the first 200 of DirtyCount
+	Dirty      []string `json:"dirty,omitempty"`
+	DirtyCount int      `json:"dirty_count"`
+
+	// Languages maps languages, e.g. "Go", to their share of the source
+	// bytes in the project, from 0 to 1
+	Languages map[string]float64 `json:"languages,omitempty"`
+
+	// ComputedAt is when the host looked; it caches the context for a
+	// while
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// Project returns the project context of a call; false when the host
+// passed none
+func Project(args map[string]interface{}) (ProjectContext, bool) {
+	raw, ok := args[ArgProject]
+	if !ok {
+		return ProjectContext{}, false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return ProjectContext{}, false
+	}
+	var pc ProjectContext
+	if err := json.Unmarshal(data, &pc); err != nil {
+		return ProjectContext{}, false
+	}
+	return pc, true
+}
+
+// Map encodes the context the way the ArgProject argument carries it
+func (pc ProjectContext) Map() map[string]interface{} {
+	data, err := json.Marshal(pc)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return m
+}
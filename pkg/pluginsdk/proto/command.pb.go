@@ -0,0 +1,3720 @@
+// Protocol definition for CommandPlugin over gRPC.
+//
+// This is the language-neutral contract between the Go host and plugins
+// written in any language with gRPC support (see ../sdk/ for the Python and
+// TypeScript SDKs). The Go side is generated with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//          proto/command.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: proto/command.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_proto_command_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{0}
+}
+
+type NameResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NameResponse) Reset() {
+	*x = NameResponse{}
+	mi := &file_proto_command_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NameResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NameResponse) ProtoMessage() {}
+
+func (x *NameResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NameResponse.ProtoReflect.Descriptor instead.
+func (*NameResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *NameResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type VersionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionResponse) Reset() {
+	*x = VersionResponse{}
+	mi := &file_proto_command_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionResponse) ProtoMessage() {}
+
+func (x *VersionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionResponse.ProtoReflect.Descriptor instead.
+func (*VersionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *VersionResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type ExecuteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Arguments are free-form JSON-compatible values.
+	Args *structpb.Struct `protobuf:"bytes,1,opt,name=args,proto3" json:"args,omitempty"`
+	// Arguments encoded with the negotiated codec, used instead of args when
+	// codec is set.
+	EncodedArgs []byte `protobuf:"bytes,2,opt,name=encoded_args,json=encodedArgs,proto3" json:"encoded_args,omitempty"`
+	Codec       string `protobuf:"bytes,3,opt,name=codec,proto3" json:"codec,omitempty"`
+	// Arguments handed off through files instead of encoded_args, by name.
+	// Each file holds a map of just that argument encoded with codec. The
+	// host owns the files; plugins only read them.
+	ArgsFiles     map[string]string `protobuf:"bytes,4,rep,name=args_files,json=argsFiles,proto3" json:"args_files,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteRequest) Reset() {
+	*x = ExecuteRequest{}
+	mi := &file_proto_command_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteRequest) ProtoMessage() {}
+
+func (x *ExecuteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ExecuteRequest) GetArgs() *structpb.Struct {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *ExecuteRequest) GetEncodedArgs() []byte {
+	if x != nil {
+		return x.EncodedArgs
+	}
+	return nil
+}
+
+func (x *ExecuteRequest) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *ExecuteRequest) GetArgsFiles() map[string]string {
+	if x != nil {
+		return x.ArgsFiles
+	}
+	return nil
+}
+
+type ExecuteResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Result string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	// Plugin-level failure. Transport failures are reported as gRPC status
+	// errors instead, so the host can tell the two apart.
+	Error *PluginError `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// The result is what the plugin had when its latency budget ran out.
+	Partial bool `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	// The result was too large to send and was written to this file, in the
+	// directory the host passed in OPENCODE_SPILL_DIR, instead; result is
+	// empty then. The host refuses files anywhere else.
+	SpillPath     string `protobuf:"bytes,5,opt,name=spill_path,json=spillPath,proto3" json:"spill_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteResponse) Reset() {
+	*x = ExecuteResponse{}
+	mi := &file_proto_command_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteResponse) ProtoMessage() {}
+
+func (x *ExecuteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ExecuteResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *ExecuteResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *ExecuteResponse) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+func (x *ExecuteResponse) GetSpillPath() string {
+	if x != nil {
+		return x.SpillPath
+	}
+	return ""
+}
+
+// PluginError is the error envelope shared by all languages; the Go host
+// turns it into a pluginsdk.PluginError.
+type PluginError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of the pluginsdk.ErrorCode values, e.g. "invalid_argument".
+	Code    string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Whether the same call may succeed if repeated.
+	Retryable     bool              `protobuf:"varint,3,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	Details       map[string]string `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginError) Reset() {
+	*x = PluginError{}
+	mi := &file_proto_command_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginError) ProtoMessage() {}
+
+func (x *PluginError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginError.ProtoReflect.Descriptor instead.
+func (*PluginError) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PluginError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *PluginError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PluginError) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+func (x *PluginError) GetDetails() map[string]string {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+type GetCapabilitiesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Capability names, kept for hosts that predate details.
+	Capabilities []string `protobuf:"bytes,1,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	// Full descriptions in the same order as capabilities.
+	Details       []*Capability `protobuf:"bytes,2,rep,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCapabilitiesResponse) Reset() {
+	*x = GetCapabilitiesResponse{}
+	mi := &file_proto_command_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCapabilitiesResponse) ProtoMessage() {}
+
+func (x *GetCapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*GetCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetCapabilitiesResponse) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *GetCapabilitiesResponse) GetDetails() []*Capability {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+type Capability struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// One-line, human readable summary.
+	Description string `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// JSON Schema object describing the accepted arguments.
+	ArgsSchema *structpb.Struct `protobuf:"bytes,3,opt,name=args_schema,json=argsSchema,proto3" json:"args_schema,omitempty"`
+	// Sample arguments for invoking the capability.
+	Example *structpb.Struct `protobuf:"bytes,4,opt,name=example,proto3" json:"example,omitempty"`
+	Tags    []string         `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	// Repeating a call has no further effect, so the host may retry it.
+	Idempotent bool `protobuf:"varint,6,opt,name=idempotent,proto3" json:"idempotent,omitempty"`
+	// Revision of the capability, e.g. "v2"; callers pick it as "greet@v2".
+	Version string `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`
+	// Non-empty marks the capability deprecated and says what to use instead.
+	Deprecated string `protobuf:"bytes,8,opt,name=deprecated,proto3" json:"deprecated,omitempty"`
+	// The capability no longer works; the host may reject calls to it.
+	Removed bool `protobuf:"varint,9,opt,name=removed,proto3" json:"removed,omitempty"`
+	// JSON Schema the result satisfies; results are parsed as JSON unless the
+	// schema's type is "string".
+	ResultSchema *structpb.Struct `protobuf:"bytes,10,opt,name=result_schema,json=resultSchema,proto3" json:"result_schema,omitempty"`
+	// Capability of the same plugin that undoes a successful call; pipelines
+	// run it when a later step fails.
+	Compensate string `protobuf:"bytes,11,opt,name=compensate,proto3" json:"compensate,omitempty"`
+	// Expected cost class of a call: "cheap", "medium" or "expensive". Hosts
+	// prefer the cheapest of the plugins offering a capability.
+	Cost string `protobuf:"bytes,12,opt,name=cost,proto3" json:"cost,omitempty"`
+	// How long a call typically takes, as a duration such as "200ms".
+	Latency string `protobuf:"bytes,13,opt,name=latency,proto3" json:"latency,omitempty"`
+	// Result formats the capability can answer in, e.g. "json" or "markdown";
+	// the first is its default.
+	Formats []string `protobuf:"bytes,14,rep,name=formats,proto3" json:"formats,omitempty"`
+	// Resource the capability's calls modify, e.g. "repo-write"; the host runs
+	// calls sharing a key one at a time.
+	ConcurrencyKey string `protobuf:"bytes,15,opt,name=concurrency_key,json=concurrencyKey,proto3" json:"concurrency_key,omitempty"`
+	// Calls may be given a latency budget, after which the plugin returns
+	// what it has so far with partial set instead of failing.
+	Partial       bool `protobuf:"varint,16,opt,name=partial,proto3" json:"partial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Capability) Reset() {
+	*x = Capability{}
+	mi := &file_proto_command_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Capability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Capability) ProtoMessage() {}
+
+func (x *Capability) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Capability.ProtoReflect.Descriptor instead.
+func (*Capability) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Capability) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Capability) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Capability) GetArgsSchema() *structpb.Struct {
+	if x != nil {
+		return x.ArgsSchema
+	}
+	return nil
+}
+
+func (x *Capability) GetExample() *structpb.Struct {
+	if x != nil {
+		return x.Example
+	}
+	return nil
+}
+
+func (x *Capability) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Capability) GetIdempotent() bool {
+	if x != nil {
+		return x.Idempotent
+	}
+	return false
+}
+
+func (x *Capability) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Capability) GetDeprecated() string {
+	if x != nil {
+		return x.Deprecated
+	}
+	return ""
+}
+
+func (x *Capability) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+func (x *Capability) GetResultSchema() *structpb.Struct {
+	if x != nil {
+		return x.ResultSchema
+	}
+	return nil
+}
+
+func (x *Capability) GetCompensate() string {
+	if x != nil {
+		return x.Compensate
+	}
+	return ""
+}
+
+func (x *Capability) GetCost() string {
+	if x != nil {
+		return x.Cost
+	}
+	return ""
+}
+
+func (x *Capability) GetLatency() string {
+	if x != nil {
+		return x.Latency
+	}
+	return ""
+}
+
+func (x *Capability) GetFormats() []string {
+	if x != nil {
+		return x.Formats
+	}
+	return nil
+}
+
+func (x *Capability) GetConcurrencyKey() string {
+	if x != nil {
+		return x.ConcurrencyKey
+	}
+	return ""
+}
+
+func (x *Capability) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+type CacheTTLsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Capability name to cache lifetime. Capabilities not listed are never
+	// cached by the host.
+	TtlSeconds    map[string]int64 `protobuf:"bytes,1,rep,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CacheTTLsResponse) Reset() {
+	*x = CacheTTLsResponse{}
+	mi := &file_proto_command_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CacheTTLsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheTTLsResponse) ProtoMessage() {}
+
+func (x *CacheTTLsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheTTLsResponse.ProtoReflect.Descriptor instead.
+func (*CacheTTLsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *CacheTTLsResponse) GetTtlSeconds() map[string]int64 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return nil
+}
+
+type SessionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only read from the first request on a stream.
+	SessionId     string           `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	Args          *structpb.Struct `protobuf:"bytes,2,opt,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionRequest) Reset() {
+	*x = SessionRequest{}
+	mi := &file_proto_command_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionRequest) ProtoMessage() {}
+
+func (x *SessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionRequest.ProtoReflect.Descriptor instead.
+func (*SessionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *SessionRequest) GetArgs() *structpb.Struct {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type SessionResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Result string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Error  *PluginError           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// As in ExecuteResponse.
+	SpillPath     string `protobuf:"bytes,4,opt,name=spill_path,json=spillPath,proto3" json:"spill_path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionResponse) Reset() {
+	*x = SessionResponse{}
+	mi := &file_proto_command_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionResponse) ProtoMessage() {}
+
+func (x *SessionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionResponse.ProtoReflect.Descriptor instead.
+func (*SessionResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SessionResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *SessionResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+func (x *SessionResponse) GetSpillPath() string {
+	if x != nil {
+		return x.SpillPath
+	}
+	return ""
+}
+
+type SetHostRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BrokerId      uint32                 `protobuf:"varint,1,opt,name=broker_id,json=brokerId,proto3" json:"broker_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetHostRequest) Reset() {
+	*x = SetHostRequest{}
+	mi := &file_proto_command_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetHostRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetHostRequest) ProtoMessage() {}
+
+func (x *SetHostRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetHostRequest.ProtoReflect.Descriptor instead.
+func (*SetHostRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetHostRequest) GetBrokerId() uint32 {
+	if x != nil {
+		return x.BrokerId
+	}
+	return 0
+}
+
+type RenderTemplateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Template name relative to the host's templates directory, without the
+	// extension, e.g. "commands/analyze".
+	Name          string           `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Data          *structpb.Struct `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderTemplateRequest) Reset() {
+	*x = RenderTemplateRequest{}
+	mi := &file_proto_command_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderTemplateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderTemplateRequest) ProtoMessage() {}
+
+func (x *RenderTemplateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderTemplateRequest.ProtoReflect.Descriptor instead.
+func (*RenderTemplateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RenderTemplateRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RenderTemplateRequest) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type RenderTemplateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Result        string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	Error         *PluginError           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenderTemplateResponse) Reset() {
+	*x = RenderTemplateResponse{}
+	mi := &file_proto_command_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenderTemplateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenderTemplateResponse) ProtoMessage() {}
+
+func (x *RenderTemplateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenderTemplateResponse.ProtoReflect.Descriptor instead.
+func (*RenderTemplateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RenderTemplateResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *RenderTemplateResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type CallPluginRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Plugin to call, by name.
+	Plugin string `protobuf:"bytes,1,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	// Capability of that plugin, e.g. "analyze" or "analyze@v2".
+	Capability    string           `protobuf:"bytes,2,opt,name=capability,proto3" json:"capability,omitempty"`
+	Args          *structpb.Struct `protobuf:"bytes,3,opt,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallPluginRequest) Reset() {
+	*x = CallPluginRequest{}
+	mi := &file_proto_command_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallPluginRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallPluginRequest) ProtoMessage() {}
+
+func (x *CallPluginRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallPluginRequest.ProtoReflect.Descriptor instead.
+func (*CallPluginRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *CallPluginRequest) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *CallPluginRequest) GetCapability() string {
+	if x != nil {
+		return x.Capability
+	}
+	return ""
+}
+
+func (x *CallPluginRequest) GetArgs() *structpb.Struct {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type GetContextRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Request ID from the reserved "context" argument of the call.
+	RequestId     string `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetContextRequest) Reset() {
+	*x = GetContextRequest{}
+	mi := &file_proto_command_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetContextRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetContextRequest) ProtoMessage() {}
+
+func (x *GetContextRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetContextRequest.ProtoReflect.Descriptor instead.
+func (*GetContextRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *GetContextRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+type GetContextResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The context as the "context" argument carries it.
+	Context       *structpb.Struct `protobuf:"bytes,1,opt,name=context,proto3" json:"context,omitempty"`
+	Error         *PluginError     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetContextResponse) Reset() {
+	*x = GetContextResponse{}
+	mi := &file_proto_command_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetContextResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetContextResponse) ProtoMessage() {}
+
+func (x *GetContextResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetContextResponse.ProtoReflect.Descriptor instead.
+func (*GetContextResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GetContextResponse) GetContext() *structpb.Struct {
+	if x != nil {
+		return x.Context
+	}
+	return nil
+}
+
+func (x *GetContextResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type SetContextRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value         *structpb.Value        `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetContextRequest) Reset() {
+	*x = SetContextRequest{}
+	mi := &file_proto_command_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetContextRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetContextRequest) ProtoMessage() {}
+
+func (x *SetContextRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetContextRequest.ProtoReflect.Descriptor instead.
+func (*SetContextRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SetContextRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *SetContextRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetContextRequest) GetValue() *structpb.Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type SetContextResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetContextResponse) Reset() {
+	*x = SetContextResponse{}
+	mi := &file_proto_command_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetContextResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetContextResponse) ProtoMessage() {}
+
+func (x *SetContextResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetContextResponse.ProtoReflect.Descriptor instead.
+func (*SetContextResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SetContextResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type RegisterCapabilitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capabilities  []*Capability          `protobuf:"bytes,1,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterCapabilitiesRequest) Reset() {
+	*x = RegisterCapabilitiesRequest{}
+	mi := &file_proto_command_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterCapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterCapabilitiesRequest) ProtoMessage() {}
+
+func (x *RegisterCapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterCapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*RegisterCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *RegisterCapabilitiesRequest) GetCapabilities() []*Capability {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+type RegisterCapabilitiesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterCapabilitiesResponse) Reset() {
+	*x = RegisterCapabilitiesResponse{}
+	mi := &file_proto_command_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterCapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterCapabilitiesResponse) ProtoMessage() {}
+
+func (x *RegisterCapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterCapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*RegisterCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RegisterCapabilitiesResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type UnregisterCapabilitiesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// References such as "mcp.search" or "mcp.search@v2".
+	Refs          []string `protobuf:"bytes,1,rep,name=refs,proto3" json:"refs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterCapabilitiesRequest) Reset() {
+	*x = UnregisterCapabilitiesRequest{}
+	mi := &file_proto_command_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterCapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterCapabilitiesRequest) ProtoMessage() {}
+
+func (x *UnregisterCapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterCapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*UnregisterCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *UnregisterCapabilitiesRequest) GetRefs() []string {
+	if x != nil {
+		return x.Refs
+	}
+	return nil
+}
+
+type UnregisterCapabilitiesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnregisterCapabilitiesResponse) Reset() {
+	*x = UnregisterCapabilitiesResponse{}
+	mi := &file_proto_command_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnregisterCapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnregisterCapabilitiesResponse) ProtoMessage() {}
+
+func (x *UnregisterCapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnregisterCapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*UnregisterCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *UnregisterCapabilitiesResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type ReportProgressRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Percent done, from 0 to 100.
+	Percent       float64 `protobuf:"fixed64,2,opt,name=percent,proto3" json:"percent,omitempty"`
+	Message       string  `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportProgressRequest) Reset() {
+	*x = ReportProgressRequest{}
+	mi := &file_proto_command_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportProgressRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportProgressRequest) ProtoMessage() {}
+
+func (x *ReportProgressRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportProgressRequest.ProtoReflect.Descriptor instead.
+func (*ReportProgressRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ReportProgressRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ReportProgressRequest) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *ReportProgressRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ReportProgressResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportProgressResponse) Reset() {
+	*x = ReportProgressResponse{}
+	mi := &file_proto_command_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportProgressResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportProgressResponse) ProtoMessage() {}
+
+func (x *ReportProgressResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportProgressResponse.ProtoReflect.Descriptor instead.
+func (*ReportProgressResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ReportProgressResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type ReadFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadFileRequest) Reset() {
+	*x = ReadFileRequest{}
+	mi := &file_proto_command_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadFileRequest) ProtoMessage() {}
+
+func (x *ReadFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadFileRequest.ProtoReflect.Descriptor instead.
+func (*ReadFileRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ReadFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type ReadFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	Error         *PluginError           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadFileResponse) Reset() {
+	*x = ReadFileResponse{}
+	mi := &file_proto_command_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadFileResponse) ProtoMessage() {}
+
+func (x *ReadFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadFileResponse.ProtoReflect.Descriptor instead.
+func (*ReadFileResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *ReadFileResponse) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *ReadFileResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type WriteFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteFileRequest) Reset() {
+	*x = WriteFileRequest{}
+	mi := &file_proto_command_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteFileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteFileRequest) ProtoMessage() {}
+
+func (x *WriteFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteFileRequest.ProtoReflect.Descriptor instead.
+func (*WriteFileRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *WriteFileRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *WriteFileRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type WriteFileResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteFileResponse) Reset() {
+	*x = WriteFileResponse{}
+	mi := &file_proto_command_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteFileResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteFileResponse) ProtoMessage() {}
+
+func (x *WriteFileResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteFileResponse.ProtoReflect.Descriptor instead.
+func (*WriteFileResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *WriteFileResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type GlobFilesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Pattern such as "src/**/*.go"; "**" matches any number of directories.
+	Pattern       string `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GlobFilesRequest) Reset() {
+	*x = GlobFilesRequest{}
+	mi := &file_proto_command_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GlobFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GlobFilesRequest) ProtoMessage() {}
+
+func (x *GlobFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GlobFilesRequest.ProtoReflect.Descriptor instead.
+func (*GlobFilesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *GlobFilesRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+type GlobFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Paths         []string               `protobuf:"bytes,1,rep,name=paths,proto3" json:"paths,omitempty"`
+	Error         *PluginError           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GlobFilesResponse) Reset() {
+	*x = GlobFilesResponse{}
+	mi := &file_proto_command_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GlobFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GlobFilesResponse) ProtoMessage() {}
+
+func (x *GlobFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GlobFilesResponse.ProtoReflect.Descriptor instead.
+func (*GlobFilesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *GlobFilesResponse) GetPaths() []string {
+	if x != nil {
+		return x.Paths
+	}
+	return nil
+}
+
+func (x *GlobFilesResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type WatchFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pattern       string                 `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchFilesRequest) Reset() {
+	*x = WatchFilesRequest{}
+	mi := &file_proto_command_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchFilesRequest) ProtoMessage() {}
+
+func (x *WatchFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchFilesRequest.ProtoReflect.Descriptor instead.
+func (*WatchFilesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *WatchFilesRequest) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+type WatchFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Error         *PluginError           `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchFilesResponse) Reset() {
+	*x = WatchFilesResponse{}
+	mi := &file_proto_command_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchFilesResponse) ProtoMessage() {}
+
+func (x *WatchFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchFilesResponse.ProtoReflect.Descriptor instead.
+func (*WatchFilesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *WatchFilesResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *WatchFilesResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type UnwatchFilesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnwatchFilesRequest) Reset() {
+	*x = UnwatchFilesRequest{}
+	mi := &file_proto_command_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnwatchFilesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnwatchFilesRequest) ProtoMessage() {}
+
+func (x *UnwatchFilesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnwatchFilesRequest.ProtoReflect.Descriptor instead.
+func (*UnwatchFilesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *UnwatchFilesRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type UnwatchFilesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnwatchFilesResponse) Reset() {
+	*x = UnwatchFilesResponse{}
+	mi := &file_proto_command_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnwatchFilesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnwatchFilesResponse) ProtoMessage() {}
+
+func (x *UnwatchFilesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnwatchFilesResponse.ProtoReflect.Descriptor instead.
+func (*UnwatchFilesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *UnwatchFilesResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type PublishEventRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Data          *structpb.Struct       `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishEventRequest) Reset() {
+	*x = PublishEventRequest{}
+	mi := &file_proto_command_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishEventRequest) ProtoMessage() {}
+
+func (x *PublishEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishEventRequest.ProtoReflect.Descriptor instead.
+func (*PublishEventRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *PublishEventRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PublishEventRequest) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type PublishEventResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Error         *PluginError           `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishEventResponse) Reset() {
+	*x = PublishEventResponse{}
+	mi := &file_proto_command_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishEventResponse) ProtoMessage() {}
+
+func (x *PublishEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishEventResponse.ProtoReflect.Descriptor instead.
+func (*PublishEventResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *PublishEventResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type LLMMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Role          string                 `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Content       string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LLMMessage) Reset() {
+	*x = LLMMessage{}
+	mi := &file_proto_command_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LLMMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LLMMessage) ProtoMessage() {}
+
+func (x *LLMMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LLMMessage.ProtoReflect.Descriptor instead.
+func (*LLMMessage) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *LLMMessage) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *LLMMessage) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+type CompleteRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Provider and model default to the host's when empty.
+	Provider    string        `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model       string        `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	System      string        `protobuf:"bytes,3,opt,name=system,proto3" json:"system,omitempty"`
+	Messages    []*LLMMessage `protobuf:"bytes,4,rep,name=messages,proto3" json:"messages,omitempty"`
+	MaxTokens   int32         `protobuf:"varint,5,opt,name=max_tokens,json=maxTokens,proto3" json:"max_tokens,omitempty"`
+	Temperature *float64      `protobuf:"fixed64,6,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	// The call the request is made for, which the host charges its cost to.
+	CallId        string `protobuf:"bytes,7,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteRequest) Reset() {
+	*x = CompleteRequest{}
+	mi := &file_proto_command_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteRequest) ProtoMessage() {}
+
+func (x *CompleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteRequest.ProtoReflect.Descriptor instead.
+func (*CompleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *CompleteRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *CompleteRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *CompleteRequest) GetSystem() string {
+	if x != nil {
+		return x.System
+	}
+	return ""
+}
+
+func (x *CompleteRequest) GetMessages() []*LLMMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *CompleteRequest) GetMaxTokens() int32 {
+	if x != nil {
+		return x.MaxTokens
+	}
+	return 0
+}
+
+func (x *CompleteRequest) GetTemperature() float64 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *CompleteRequest) GetCallId() string {
+	if x != nil {
+		return x.CallId
+	}
+	return ""
+}
+
+type CompleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Provider      string                 `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	StopReason    string                 `protobuf:"bytes,4,opt,name=stop_reason,json=stopReason,proto3" json:"stop_reason,omitempty"`
+	InputTokens   int64                  `protobuf:"varint,5,opt,name=input_tokens,json=inputTokens,proto3" json:"input_tokens,omitempty"`
+	OutputTokens  int64                  `protobuf:"varint,6,opt,name=output_tokens,json=outputTokens,proto3" json:"output_tokens,omitempty"`
+	Error         *PluginError           `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompleteResponse) Reset() {
+	*x = CompleteResponse{}
+	mi := &file_proto_command_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompleteResponse) ProtoMessage() {}
+
+func (x *CompleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompleteResponse.ProtoReflect.Descriptor instead.
+func (*CompleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *CompleteResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *CompleteResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *CompleteResponse) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *CompleteResponse) GetStopReason() string {
+	if x != nil {
+		return x.StopReason
+	}
+	return ""
+}
+
+func (x *CompleteResponse) GetInputTokens() int64 {
+	if x != nil {
+		return x.InputTokens
+	}
+	return 0
+}
+
+func (x *CompleteResponse) GetOutputTokens() int64 {
+	if x != nil {
+		return x.OutputTokens
+	}
+	return 0
+}
+
+func (x *CompleteResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// Event is something that happened in the host or the editor, e.g.
+// "file.saved".
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Type  string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Plugin the event is about, if any.
+	Plugin        string           `protobuf:"bytes,2,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	TimeUnixNano  int64            `protobuf:"varint,3,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Data          *structpb.Struct `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_proto_command_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *Event) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *Event) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type HandleEventResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when the plugin failed to handle the event.
+	Error         *PluginError `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandleEventResponse) Reset() {
+	*x = HandleEventResponse{}
+	mi := &file_proto_command_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandleEventResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandleEventResponse) ProtoMessage() {}
+
+func (x *HandleEventResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandleEventResponse.ProtoReflect.Descriptor instead.
+func (*HandleEventResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *HandleEventResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type NegotiateCodecRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Codec names such as "msgpack" or "json", most preferred first.
+	Codecs        []string `protobuf:"bytes,1,rep,name=codecs,proto3" json:"codecs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateCodecRequest) Reset() {
+	*x = NegotiateCodecRequest{}
+	mi := &file_proto_command_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateCodecRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateCodecRequest) ProtoMessage() {}
+
+func (x *NegotiateCodecRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateCodecRequest.ProtoReflect.Descriptor instead.
+func (*NegotiateCodecRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *NegotiateCodecRequest) GetCodecs() []string {
+	if x != nil {
+		return x.Codecs
+	}
+	return nil
+}
+
+type NegotiateCodecResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The chosen codec; empty when the plugin supports none of them.
+	Codec         string `protobuf:"bytes,1,opt,name=codec,proto3" json:"codec,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateCodecResponse) Reset() {
+	*x = NegotiateCodecResponse{}
+	mi := &file_proto_command_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateCodecResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateCodecResponse) ProtoMessage() {}
+
+func (x *NegotiateCodecResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateCodecResponse.ProtoReflect.Descriptor instead.
+func (*NegotiateCodecResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *NegotiateCodecResponse) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+type CancelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The call_id argument of the call to abort.
+	CallId string `protobuf:"bytes,1,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	// Why the host gave up on the call, e.g. "deadline exceeded".
+	Reason        string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelRequest) Reset() {
+	*x = CancelRequest{}
+	mi := &file_proto_command_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelRequest) ProtoMessage() {}
+
+func (x *CancelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelRequest.ProtoReflect.Descriptor instead.
+func (*CancelRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *CancelRequest) GetCallId() string {
+	if x != nil {
+		return x.CallId
+	}
+	return ""
+}
+
+func (x *CancelRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type CancelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when the call was still running and has been told to stop.
+	Found         bool `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelResponse) Reset() {
+	*x = CancelResponse{}
+	mi := &file_proto_command_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelResponse) ProtoMessage() {}
+
+func (x *CancelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelResponse.ProtoReflect.Descriptor instead.
+func (*CancelResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *CancelResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+type ExpireBudgetRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The call_id argument of the call whose budget ran out.
+	CallId        string `protobuf:"bytes,1,opt,name=call_id,json=callId,proto3" json:"call_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpireBudgetRequest) Reset() {
+	*x = ExpireBudgetRequest{}
+	mi := &file_proto_command_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpireBudgetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpireBudgetRequest) ProtoMessage() {}
+
+func (x *ExpireBudgetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpireBudgetRequest.ProtoReflect.Descriptor instead.
+func (*ExpireBudgetRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ExpireBudgetRequest) GetCallId() string {
+	if x != nil {
+		return x.CallId
+	}
+	return ""
+}
+
+type ExpireBudgetResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when the call was still running and has been told to wrap up.
+	Found         bool `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExpireBudgetResponse) Reset() {
+	*x = ExpireBudgetResponse{}
+	mi := &file_proto_command_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExpireBudgetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExpireBudgetResponse) ProtoMessage() {}
+
+func (x *ExpireBudgetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExpireBudgetResponse.ProtoReflect.Descriptor instead.
+func (*ExpireBudgetResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *ExpireBudgetResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+type NegotiateHandoffRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dir           string                 `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateHandoffRequest) Reset() {
+	*x = NegotiateHandoffRequest{}
+	mi := &file_proto_command_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateHandoffRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateHandoffRequest) ProtoMessage() {}
+
+func (x *NegotiateHandoffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateHandoffRequest.ProtoReflect.Descriptor instead.
+func (*NegotiateHandoffRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *NegotiateHandoffRequest) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+type NegotiateHandoffResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NegotiateHandoffResponse) Reset() {
+	*x = NegotiateHandoffResponse{}
+	mi := &file_proto_command_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NegotiateHandoffResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NegotiateHandoffResponse) ProtoMessage() {}
+
+func (x *NegotiateHandoffResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NegotiateHandoffResponse.ProtoReflect.Descriptor instead.
+func (*NegotiateHandoffResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *NegotiateHandoffResponse) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+type SetLogLevelRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Level         string                 `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLogLevelRequest) Reset() {
+	*x = SetLogLevelRequest{}
+	mi := &file_proto_command_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelRequest) ProtoMessage() {}
+
+func (x *SetLogLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelRequest.ProtoReflect.Descriptor instead.
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *SetLogLevelRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+type SetLogLevelResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when the plugin does not know the level.
+	Error         *PluginError `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLogLevelResponse) Reset() {
+	*x = SetLogLevelResponse{}
+	mi := &file_proto_command_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelResponse) ProtoMessage() {}
+
+func (x *SetLogLevelResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelResponse.ProtoReflect.Descriptor instead.
+func (*SetLogLevelResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *SetLogLevelResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type DrainRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// How long the host waits for the answer before it stops the process.
+	TimeoutMs     int64 `protobuf:"varint,1,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainRequest) Reset() {
+	*x = DrainRequest{}
+	mi := &file_proto_command_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainRequest) ProtoMessage() {}
+
+func (x *DrainRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainRequest.ProtoReflect.Descriptor instead.
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *DrainRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type DrainResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when the plugin could not get to a state safe to terminate in.
+	Error         *PluginError `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DrainResponse) Reset() {
+	*x = DrainResponse{}
+	mi := &file_proto_command_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DrainResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DrainResponse) ProtoMessage() {}
+
+func (x *DrainResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DrainResponse.ProtoReflect.Descriptor instead.
+func (*DrainResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *DrainResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type DaemonRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// How long the host waits for the answer.
+	TimeoutMs     int64 `protobuf:"varint,1,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DaemonRequest) Reset() {
+	*x = DaemonRequest{}
+	mi := &file_proto_command_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DaemonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DaemonRequest) ProtoMessage() {}
+
+func (x *DaemonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DaemonRequest.ProtoReflect.Descriptor instead.
+func (*DaemonRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *DaemonRequest) GetTimeoutMs() int64 {
+	if x != nil {
+		return x.TimeoutMs
+	}
+	return 0
+}
+
+type DaemonResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// starting, running, stopped or failed
+	State string `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	// Why the server is not running, e.g. the error it failed with.
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Where the server serves, e.g. tcp://127.0.0.1:7000 or a socket path.
+	Endpoint string `protobuf:"bytes,3,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	// Set when the call failed or the plugin is no daemon.
+	Error         *PluginError `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DaemonResponse) Reset() {
+	*x = DaemonResponse{}
+	mi := &file_proto_command_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DaemonResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DaemonResponse) ProtoMessage() {}
+
+func (x *DaemonResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DaemonResponse.ProtoReflect.Descriptor instead.
+func (*DaemonResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *DaemonResponse) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *DaemonResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *DaemonResponse) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *DaemonResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+type ExecuteBatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*ExecuteRequest      `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteBatchRequest) Reset() {
+	*x = ExecuteBatchRequest{}
+	mi := &file_proto_command_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteBatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteBatchRequest) ProtoMessage() {}
+
+func (x *ExecuteBatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteBatchRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteBatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *ExecuteBatchRequest) GetItems() []*ExecuteRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type ExecuteBatchResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One response per item, in the order of the items.
+	Results       []*ExecuteResponse `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteBatchResponse) Reset() {
+	*x = ExecuteBatchResponse{}
+	mi := &file_proto_command_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteBatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteBatchResponse) ProtoMessage() {}
+
+func (x *ExecuteBatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteBatchResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteBatchResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *ExecuteBatchResponse) GetResults() []*ExecuteResponse {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type InitializeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Config        *structpb.Struct       `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InitializeRequest) Reset() {
+	*x = InitializeRequest{}
+	mi := &file_proto_command_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InitializeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitializeRequest) ProtoMessage() {}
+
+func (x *InitializeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitializeRequest.ProtoReflect.Descriptor instead.
+func (*InitializeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *InitializeRequest) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type InitializeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Set when the plugin rejected its configuration.
+	Error         *PluginError `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InitializeResponse) Reset() {
+	*x = InitializeResponse{}
+	mi := &file_proto_command_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InitializeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitializeResponse) ProtoMessage() {}
+
+func (x *InitializeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_command_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitializeResponse.ProtoReflect.Descriptor instead.
+func (*InitializeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_command_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *InitializeResponse) GetError() *PluginError {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+var File_proto_command_proto protoreflect.FileDescriptor
+
+const file_proto_command_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/command.proto\x12\x12opencode.plugin.v1\x1a\x1cgoogle/protobuf/struct.proto\"\a\n" +
+	"\x05Empty\"\"\n" +
+	"\fNameResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"+\n" +
+	"\x0fVersionResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\"\x86\x02\n" +
+	"\x0eExecuteRequest\x12+\n" +
+	"\x04args\x18\x01 \x01(\v2\x17.google.protobuf.StructR\x04args\x12!\n" +
+	"\fencoded_args\x18\x02 \x01(\fR\vencodedArgs\x12\x14\n" +
+	"\x05codec\x18\x03 \x01(\tR\x05codec\x12P\n" +
+	"\n" +
+	"args_files\x18\x04 \x03(\v21.opencode.plugin.v1.ExecuteRequest.ArgsFilesEntryR\targsFiles\x1a<\n" +
+	"\x0eArgsFilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x9f\x01\n" +
+	"\x0fExecuteResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\x125\n" +
+	"\x05error\x18\x03 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\x12\x1d\n" +
+	"\n" +
+	"spill_path\x18\x05 \x01(\tR\tspillPathJ\x04\b\x02\x10\x03\"\xdd\x01\n" +
+	"\vPluginError\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1c\n" +
+	"\tretryable\x18\x03 \x01(\bR\tretryable\x12F\n" +
+	"\adetails\x18\x04 \x03(\v2,.opencode.plugin.v1.PluginError.DetailsEntryR\adetails\x1a:\n" +
+	"\fDetailsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"w\n" +
+	"\x17GetCapabilitiesResponse\x12\"\n" +
+	"\fcapabilities\x18\x01 \x03(\tR\fcapabilities\x128\n" +
+	"\adetails\x18\x02 \x03(\v2\x1e.opencode.plugin.v1.CapabilityR\adetails\"\xa0\x04\n" +
+	"\n" +
+	"Capability\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x128\n" +
+	"\vargs_schema\x18\x03 \x01(\v2\x17.google.protobuf.StructR\n" +
+	"argsSchema\x121\n" +
+	"\aexample\x18\x04 \x01(\v2\x17.google.protobuf.StructR\aexample\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x12\x1e\n" +
+	"\n" +
+	"idempotent\x18\x06 \x01(\bR\n" +
+	"idempotent\x12\x18\n" +
+	"\aversion\x18\a \x01(\tR\aversion\x12\x1e\n" +
+	"\n" +
+	"deprecated\x18\b \x01(\tR\n" +
+	"deprecated\x12\x18\n" +
+	"\aremoved\x18\t \x01(\bR\aremoved\x12<\n" +
+	"\rresult_schema\x18\n" +
+	" \x01(\v2\x17.google.protobuf.StructR\fresultSchema\x12\x1e\n" +
+	"\n" +
+	"compensate\x18\v \x01(\tR\n" +
+	"compensate\x12\x12\n" +
+	"\x04cost\x18\f \x01(\tR\x04cost\x12\x18\n" +
+	"\alatency\x18\r \x01(\tR\alatency\x12\x18\n" +
+	"\aformats\x18\x0e \x03(\tR\aformats\x12'\n" +
+	"\x0fconcurrency_key\x18\x0f \x01(\tR\x0econcurrencyKey\x12\x18\n" +
+	"\apartial\x18\x10 \x01(\bR\apartial\"\xaa\x01\n" +
+	"\x11CacheTTLsResponse\x12V\n" +
+	"\vttl_seconds\x18\x01 \x03(\v25.opencode.plugin.v1.CacheTTLsResponse.TtlSecondsEntryR\n" +
+	"ttlSeconds\x1a=\n" +
+	"\x0fTtlSecondsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\\\n" +
+	"\x0eSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12+\n" +
+	"\x04args\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x04args\"\x85\x01\n" +
+	"\x0fSessionResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\x125\n" +
+	"\x05error\x18\x03 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\x12\x1d\n" +
+	"\n" +
+	"spill_path\x18\x04 \x01(\tR\tspillPathJ\x04\b\x02\x10\x03\"-\n" +
+	"\x0eSetHostRequest\x12\x1b\n" +
+	"\tbroker_id\x18\x01 \x01(\rR\bbrokerId\"X\n" +
+	"\x15RenderTemplateRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12+\n" +
+	"\x04data\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x04data\"g\n" +
+	"\x16RenderTemplateResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\x125\n" +
+	"\x05error\x18\x02 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"x\n" +
+	"\x11CallPluginRequest\x12\x16\n" +
+	"\x06plugin\x18\x01 \x01(\tR\x06plugin\x12\x1e\n" +
+	"\n" +
+	"capability\x18\x02 \x01(\tR\n" +
+	"capability\x12+\n" +
+	"\x04args\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x04args\"2\n" +
+	"\x11GetContextRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\"~\n" +
+	"\x12GetContextResponse\x121\n" +
+	"\acontext\x18\x01 \x01(\v2\x17.google.protobuf.StructR\acontext\x125\n" +
+	"\x05error\x18\x02 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"r\n" +
+	"\x11SetContextRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12,\n" +
+	"\x05value\x18\x03 \x01(\v2\x16.google.protobuf.ValueR\x05value\"K\n" +
+	"\x12SetContextResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"a\n" +
+	"\x1bRegisterCapabilitiesRequest\x12B\n" +
+	"\fcapabilities\x18\x01 \x03(\v2\x1e.opencode.plugin.v1.CapabilityR\fcapabilities\"U\n" +
+	"\x1cRegisterCapabilitiesResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"3\n" +
+	"\x1dUnregisterCapabilitiesRequest\x12\x12\n" +
+	"\x04refs\x18\x01 \x03(\tR\x04refs\"W\n" +
+	"\x1eUnregisterCapabilitiesResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"j\n" +
+	"\x15ReportProgressRequest\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x01R\apercent\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"O\n" +
+	"\x16ReportProgressResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"%\n" +
+	"\x0fReadFileRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"]\n" +
+	"\x10ReadFileResponse\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x125\n" +
+	"\x05error\x18\x02 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\":\n" +
+	"\x10WriteFileRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"J\n" +
+	"\x11WriteFileResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\",\n" +
+	"\x10GlobFilesRequest\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\"`\n" +
+	"\x11GlobFilesResponse\x12\x14\n" +
+	"\x05paths\x18\x01 \x03(\tR\x05paths\x125\n" +
+	"\x05error\x18\x02 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"-\n" +
+	"\x11WatchFilesRequest\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\"[\n" +
+	"\x12WatchFilesResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x125\n" +
+	"\x05error\x18\x02 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"%\n" +
+	"\x13UnwatchFilesRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"M\n" +
+	"\x14UnwatchFilesResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"V\n" +
+	"\x13PublishEventRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12+\n" +
+	"\x04data\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x04data\"M\n" +
+	"\x14PublishEventResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\":\n" +
+	"\n" +
+	"LLMMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\x86\x02\n" +
+	"\x0fCompleteRequest\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x16\n" +
+	"\x06system\x18\x03 \x01(\tR\x06system\x12:\n" +
+	"\bmessages\x18\x04 \x03(\v2\x1e.opencode.plugin.v1.LLMMessageR\bmessages\x12\x1d\n" +
+	"\n" +
+	"max_tokens\x18\x05 \x01(\x05R\tmaxTokens\x12%\n" +
+	"\vtemperature\x18\x06 \x01(\x01H\x00R\vtemperature\x88\x01\x01\x12\x17\n" +
+	"\acall_id\x18\a \x01(\tR\x06callIdB\x0e\n" +
+	"\f_temperature\"\xfe\x01\n" +
+	"\x10CompleteResponse\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x18\n" +
+	"\acontent\x18\x03 \x01(\tR\acontent\x12\x1f\n" +
+	"\vstop_reason\x18\x04 \x01(\tR\n" +
+	"stopReason\x12!\n" +
+	"\finput_tokens\x18\x05 \x01(\x03R\vinputTokens\x12#\n" +
+	"\routput_tokens\x18\x06 \x01(\x03R\foutputTokens\x125\n" +
+	"\x05error\x18\a \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"\x86\x01\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n" +
+	"\x06plugin\x18\x02 \x01(\tR\x06plugin\x12$\n" +
+	"\x0etime_unix_nano\x18\x03 \x01(\x03R\ftimeUnixNano\x12+\n" +
+	"\x04data\x18\x04 \x01(\v2\x17.google.protobuf.StructR\x04data\"L\n" +
+	"\x13HandleEventResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"/\n" +
+	"\x15NegotiateCodecRequest\x12\x16\n" +
+	"\x06codecs\x18\x01 \x03(\tR\x06codecs\".\n" +
+	"\x16NegotiateCodecResponse\x12\x14\n" +
+	"\x05codec\x18\x01 \x01(\tR\x05codec\"@\n" +
+	"\rCancelRequest\x12\x17\n" +
+	"\acall_id\x18\x01 \x01(\tR\x06callId\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"&\n" +
+	"\x0eCancelResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\".\n" +
+	"\x13ExpireBudgetRequest\x12\x17\n" +
+	"\acall_id\x18\x01 \x01(\tR\x06callId\",\n" +
+	"\x14ExpireBudgetResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\"+\n" +
+	"\x17NegotiateHandoffRequest\x12\x10\n" +
+	"\x03dir\x18\x01 \x01(\tR\x03dir\"6\n" +
+	"\x18NegotiateHandoffResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\bR\baccepted\"*\n" +
+	"\x12SetLogLevelRequest\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\tR\x05level\"L\n" +
+	"\x13SetLogLevelResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"-\n" +
+	"\fDrainRequest\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x01 \x01(\x03R\ttimeoutMs\"F\n" +
+	"\rDrainResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\".\n" +
+	"\rDaemonRequest\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x01 \x01(\x03R\ttimeoutMs\"\x93\x01\n" +
+	"\x0eDaemonResponse\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\bendpoint\x18\x03 \x01(\tR\bendpoint\x125\n" +
+	"\x05error\x18\x04 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error\"O\n" +
+	"\x13ExecuteBatchRequest\x128\n" +
+	"\x05items\x18\x01 \x03(\v2\".opencode.plugin.v1.ExecuteRequestR\x05items\"U\n" +
+	"\x14ExecuteBatchResponse\x12=\n" +
+	"\aresults\x18\x01 \x03(\v2#.opencode.plugin.v1.ExecuteResponseR\aresults\"D\n" +
+	"\x11InitializeRequest\x12/\n" +
+	"\x06config\x18\x01 \x01(\v2\x17.google.protobuf.StructR\x06config\"K\n" +
+	"\x12InitializeResponse\x125\n" +
+	"\x05error\x18\x01 \x01(\v2\x1f.opencode.plugin.v1.PluginErrorR\x05error2\xdf\r\n" +
+	"\rCommandPlugin\x12C\n" +
+	"\x04Name\x12\x19.opencode.plugin.v1.Empty\x1a .opencode.plugin.v1.NameResponse\x12I\n" +
+	"\aVersion\x12\x19.opencode.plugin.v1.Empty\x1a#.opencode.plugin.v1.VersionResponse\x12R\n" +
+	"\aExecute\x12\".opencode.plugin.v1.ExecuteRequest\x1a#.opencode.plugin.v1.ExecuteResponse\x12Y\n" +
+	"\x0fGetCapabilities\x12\x19.opencode.plugin.v1.Empty\x1a+.opencode.plugin.v1.GetCapabilitiesResponse\x12M\n" +
+	"\tCacheTTLs\x12\x19.opencode.plugin.v1.Empty\x1a%.opencode.plugin.v1.CacheTTLsResponse\x12V\n" +
+	"\aSession\x12\".opencode.plugin.v1.SessionRequest\x1a#.opencode.plugin.v1.SessionResponse(\x010\x01\x12H\n" +
+	"\aSetHost\x12\".opencode.plugin.v1.SetHostRequest\x1a\x19.opencode.plugin.v1.Empty\x12Q\n" +
+	"\vHandleEvent\x12\x19.opencode.plugin.v1.Event\x1a'.opencode.plugin.v1.HandleEventResponse\x12[\n" +
+	"\n" +
+	"Initialize\x12%.opencode.plugin.v1.InitializeRequest\x1a&.opencode.plugin.v1.InitializeResponse\x12O\n" +
+	"\x04Plan\x12\".opencode.plugin.v1.ExecuteRequest\x1a#.opencode.plugin.v1.ExecuteResponse\x12g\n" +
+	"\x0eNegotiateCodec\x12).opencode.plugin.v1.NegotiateCodecRequest\x1a*.opencode.plugin.v1.NegotiateCodecResponse\x12O\n" +
+	"\x06Cancel\x12!.opencode.plugin.v1.CancelRequest\x1a\".opencode.plugin.v1.CancelResponse\x12a\n" +
+	"\fExpireBudget\x12'.opencode.plugin.v1.ExpireBudgetRequest\x1a(.opencode.plugin.v1.ExpireBudgetResponse\x12a\n" +
+	"\fExecuteBatch\x12'.opencode.plugin.v1.ExecuteBatchRequest\x1a(.opencode.plugin.v1.ExecuteBatchResponse\x12m\n" +
+	"\x10NegotiateHandoff\x12+.opencode.plugin.v1.NegotiateHandoffRequest\x1a,.opencode.plugin.v1.NegotiateHandoffResponse\x12^\n" +
+	"\vSetLogLevel\x12&.opencode.plugin.v1.SetLogLevelRequest\x1a'.opencode.plugin.v1.SetLogLevelResponse\x12L\n" +
+	"\x05Drain\x12 .opencode.plugin.v1.DrainRequest\x1a!.opencode.plugin.v1.DrainResponse\x12T\n" +
+	"\vDaemonStart\x12!.opencode.plugin.v1.DaemonRequest\x1a\".opencode.plugin.v1.DaemonResponse\x12S\n" +
+	"\n" +
+	"DaemonStop\x12!.opencode.plugin.v1.DaemonRequest\x1a\".opencode.plugin.v1.DaemonResponse\x12U\n" +
+	"\fDaemonStatus\x12!.opencode.plugin.v1.DaemonRequest\x1a\".opencode.plugin.v1.DaemonResponse2\xf5\n" +
+	"\n" +
+	"\fHostServices\x12g\n" +
+	"\x0eRenderTemplate\x12).opencode.plugin.v1.RenderTemplateRequest\x1a*.opencode.plugin.v1.RenderTemplateResponse\x12X\n" +
+	"\n" +
+	"CallPlugin\x12%.opencode.plugin.v1.CallPluginRequest\x1a#.opencode.plugin.v1.ExecuteResponse\x12[\n" +
+	"\n" +
+	"GetContext\x12%.opencode.plugin.v1.GetContextRequest\x1a&.opencode.plugin.v1.GetContextResponse\x12[\n" +
+	"\n" +
+	"SetContext\x12%.opencode.plugin.v1.SetContextRequest\x1a&.opencode.plugin.v1.SetContextResponse\x12y\n" +
+	"\x14RegisterCapabilities\x12/.opencode.plugin.v1.RegisterCapabilitiesRequest\x1a0.opencode.plugin.v1.RegisterCapabilitiesResponse\x12\x7f\n" +
+	"\x16UnregisterCapabilities\x121.opencode.plugin.v1.UnregisterCapabilitiesRequest\x1a2.opencode.plugin.v1.UnregisterCapabilitiesResponse\x12g\n" +
+	"\x0eReportProgress\x12).opencode.plugin.v1.ReportProgressRequest\x1a*.opencode.plugin.v1.ReportProgressResponse\x12U\n" +
+	"\bReadFile\x12#.opencode.plugin.v1.ReadFileRequest\x1a$.opencode.plugin.v1.ReadFileResponse\x12X\n" +
+	"\tWriteFile\x12$.opencode.plugin.v1.WriteFileRequest\x1a%.opencode.plugin.v1.WriteFileResponse\x12X\n" +
+	"\tGlobFiles\x12$.opencode.plugin.v1.GlobFilesRequest\x1a%.opencode.plugin.v1.GlobFilesResponse\x12[\n" +
+	"\n" +
+	"WatchFiles\x12%.opencode.plugin.v1.WatchFilesRequest\x1a&.opencode.plugin.v1.WatchFilesResponse\x12a\n" +
+	"\fUnwatchFiles\x12'.opencode.plugin.v1.UnwatchFilesRequest\x1a(.opencode.plugin.v1.UnwatchFilesResponse\x12a\n" +
+	"\fPublishEvent\x12'.opencode.plugin.v1.PublishEventRequest\x1a(.opencode.plugin.v1.PublishEventResponse\x12U\n" +
+	"\bComplete\x12#.opencode.plugin.v1.CompleteRequest\x1a$.opencode.plugin.v1.CompleteResponseB0Z.github.com/Kirchlive/super/pkg/pluginsdk/protob\x06proto3"
+
+var (
+	file_proto_command_proto_rawDescOnce sync.Once
+	file_proto_command_proto_rawDescData []byte
+)
+
+func file_proto_command_proto_rawDescGZIP() []byte {
+	file_proto_command_proto_rawDescOnce.Do(func() {
+		file_proto_command_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_command_proto_rawDesc), len(file_proto_command_proto_rawDesc)))
+	})
+	return file_proto_command_proto_rawDescData
+}
+
+var file_proto_command_proto_msgTypes = make([]protoimpl.MessageInfo, 63)
+var file_proto_command_proto_goTypes = []any{
+	(*Empty)(nil),                          // 0: opencode.plugin.v1.Empty
+	(*NameResponse)(nil),                   // 1: opencode.plugin.v1.NameResponse
+	(*VersionResponse)(nil),                // 2: opencode.plugin.v1.VersionResponse
+	(*ExecuteRequest)(nil),                 // 3: opencode.plugin.v1.ExecuteRequest
+	(*ExecuteResponse)(nil),                // 4: opencode.plugin.v1.ExecuteResponse
+	(*PluginError)(nil),                    // 5: opencode.plugin.v1.PluginError
+	(*GetCapabilitiesResponse)(nil),        // 6: opencode.plugin.v1.GetCapabilitiesResponse
+	(*Capability)(nil),                     // 7: opencode.plugin.v1.Capability
+	(*CacheTTLsResponse)(nil),              // 8: opencode.plugin.v1.CacheTTLsResponse
+	(*SessionRequest)(nil),                 // 9: opencode.plugin.v1.SessionRequest
+	(*SessionResponse)(nil),                // 10: opencode.plugin.v1.SessionResponse
+	(*SetHostRequest)(nil),                 // 11: opencode.plugin.v1.SetHostRequest
+	(*RenderTemplateRequest)(nil),          // 12: opencode.plugin.v1.RenderTemplateRequest
+	(*RenderTemplateResponse)(nil),         // 13: opencode.plugin.v1.RenderTemplateResponse
+	(*CallPluginRequest)(nil),              // 14: opencode.plugin.v1.CallPluginRequest
+	(*GetContextRequest)(nil),              // 15: opencode.plugin.v1.GetContextRequest
+	(*GetContextResponse)(nil),             // 16: opencode.plugin.v1.GetContextResponse
+	(*SetContextRequest)(nil),              // 17: opencode.plugin.v1.SetContextRequest
+	(*SetContextResponse)(nil),             // 18: opencode.plugin.v1.SetContextResponse
+	(*RegisterCapabilitiesRequest)(nil),    // 19: opencode.plugin.v1.RegisterCapabilitiesRequest
+	(*RegisterCapabilitiesResponse)(nil),   // 20: opencode.plugin.v1.RegisterCapabilitiesResponse
+	(*UnregisterCapabilitiesRequest)(nil),  // 21: opencode.plugin.v1.UnregisterCapabilitiesRequest
+	(*UnregisterCapabilitiesResponse)(nil), // 22: opencode.plugin.v1.UnregisterCapabilitiesResponse
+	(*ReportProgressRequest)(nil),          // 23: opencode.plugin.v1.ReportProgressRequest
+	(*ReportProgressResponse)(nil),         // 24: opencode.plugin.v1.ReportProgressResponse
+	(*ReadFileRequest)(nil),                // 25: opencode.plugin.v1.ReadFileRequest
+	(*ReadFileResponse)(nil),               // 26: opencode.plugin.v1.ReadFileResponse
+	(*WriteFileRequest)(nil),               // 27: opencode.plugin.v1.WriteFileRequest
+	(*WriteFileResponse)(nil),              // 28: opencode.plugin.v1.WriteFileResponse
+	(*GlobFilesRequest)(nil),               // 29: opencode.plugin.v1.GlobFilesRequest
+	(*GlobFilesResponse)(nil),              // 30: opencode.plugin.v1.GlobFilesResponse
+	(*WatchFilesRequest)(nil),              // 31: opencode.plugin.v1.WatchFilesRequest
+	(*WatchFilesResponse)(nil),             // 32: opencode.plugin.v1.WatchFilesResponse
+	(*UnwatchFilesRequest)(nil),            // 33: opencode.plugin.v1.UnwatchFilesRequest
+	(*UnwatchFilesResponse)(nil),           // 34: opencode.plugin.v1.UnwatchFilesResponse
+	(*PublishEventRequest)(nil),            // 35: opencode.plugin.v1.PublishEventRequest
+	(*PublishEventResponse)(nil),           // 36: opencode.plugin.v1.PublishEventResponse
+	(*LLMMessage)(nil),                     // 37: opencode.plugin.v1.LLMMessage
+	(*CompleteRequest)(nil),                // 38: opencode.plugin.v1.CompleteRequest
+	(*CompleteResponse)(nil),               // 39: opencode.plugin.v1.CompleteResponse
+	(*Event)(nil),                          // 40: opencode.plugin.v1.Event
+	(*HandleEventResponse)(nil),            // 41: opencode.plugin.v1.HandleEventResponse
+	(*NegotiateCodecRequest)(nil),          // 42: opencode.plugin.v1.NegotiateCodecRequest
+	(*NegotiateCodecResponse)(nil),         // 43: opencode.plugin.v1.NegotiateCodecResponse
+	(*CancelRequest)(nil),                  // 44: opencode.plugin.v1.CancelRequest
+	(*CancelResponse)(nil),                 // 45: opencode.plugin.v1.CancelResponse
+	(*ExpireBudgetRequest)(nil),            // 46: opencode.plugin.v1.ExpireBudgetRequest
+	(*ExpireBudgetResponse)(nil),           // 47: opencode.plugin.v1.ExpireBudgetResponse
+	(*NegotiateHandoffRequest)(nil),        // 48: opencode.plugin.v1.NegotiateHandoffRequest
+	(*NegotiateHandoffResponse)(nil),       // 49: opencode.plugin.v1.NegotiateHandoffResponse
+	(*SetLogLevelRequest)(nil),             // 50: opencode.plugin.v1.SetLogLevelRequest
+	(*SetLogLevelResponse)(nil),            // 51: opencode.plugin.v1.SetLogLevelResponse
+	(*DrainRequest)(nil),                   // 52: opencode.plugin.v1.DrainRequest
+	(*DrainResponse)(nil),                  // 53: opencode.plugin.v1.DrainResponse
+	(*DaemonRequest)(nil),                  // 54: opencode.plugin.v1.DaemonRequest
+	(*DaemonResponse)(nil),                 // 55: opencode.plugin.v1.DaemonResponse
+	(*ExecuteBatchRequest)(nil),            // 56: opencode.plugin.v1.ExecuteBatchRequest
+	(*ExecuteBatchResponse)(nil),           // 57: opencode.plugin.v1.ExecuteBatchResponse
+	(*InitializeRequest)(nil),              // 58: opencode.plugin.v1.InitializeRequest
+	(*InitializeResponse)(nil),             // 59: opencode.plugin.v1.InitializeResponse
+	nil,                                    // 60: opencode.plugin.v1.ExecuteRequest.ArgsFilesEntry
+	nil,                                    // 61: opencode.plugin.v1.PluginError.DetailsEntry
+	nil,                                    // 62: opencode.plugin.v1.CacheTTLsResponse.TtlSecondsEntry
+	(*structpb.Struct)(nil),                // 63: google.protobuf.Struct
+	(*structpb.Value)(nil),                 // 64: google.protobuf.Value
+}
+var file_proto_command_proto_depIdxs = []int32{
+	63, // 0: opencode.plugin.v1.ExecuteRequest.args:type_name -> google.protobuf.Struct
+	60, // 1: opencode.plugin.v1.ExecuteRequest.args_files:type_name -> opencode.plugin.v1.ExecuteRequest.ArgsFilesEntry
+	5,  // 2: opencode.plugin.v1.ExecuteResponse.error:type_name -> opencode.plugin.v1.PluginError
+	61, // 3: opencode.plugin.v1.PluginError.details:type_name -> opencode.plugin.v1.PluginError.DetailsEntry
+	7,  // 4: opencode.plugin.v1.GetCapabilitiesResponse.details:type_name -> opencode.plugin.v1.Capability
+	63, // 5: opencode.plugin.v1.Capability.args_schema:type_name -> google.protobuf.Struct
+	63, // 6: opencode.plugin.v1.Capability.example:type_name -> google.protobuf.Struct
+	63, // 7: opencode.plugin.v1.Capability.result_schema:type_name -> google.protobuf.Struct
+	62, // 8: opencode.plugin.v1.CacheTTLsResponse.ttl_seconds:type_name -> opencode.plugin.v1.CacheTTLsResponse.TtlSecondsEntry
+	63, // 9: opencode.plugin.v1.SessionRequest.args:type_name -> google.protobuf.Struct
+	5,  // 10: opencode.plugin.v1.SessionResponse.error:type_name -> opencode.plugin.v1.PluginError
+	63, // 11: opencode.plugin.v1.RenderTemplateRequest.data:type_name -> google.protobuf.Struct
+	5,  // 12: opencode.plugin.v1.RenderTemplateResponse.error:type_name -> opencode.plugin.v1.PluginError
+	63, // 13: opencode.plugin.v1.CallPluginRequest.args:type_name -> google.protobuf.Struct
+	63, // 14: opencode.plugin.v1.GetContextResponse.context:type_name -> google.protobuf.Struct
+	5,  // 15: opencode.plugin.v1.GetContextResponse.error:type_name -> opencode.plugin.v1.PluginError
+	64, // 16: opencode.plugin.v1.SetContextRequest.value:type_name -> google.protobuf.Value
+	5,  // 17: opencode.plugin.v1.SetContextResponse.error:type_name -> opencode.plugin.v1.PluginError
+	7,  // 18: opencode.plugin.v1.RegisterCapabilitiesRequest.capabilities:type_name -> opencode.plugin.v1.Capability
+	5,  // 19: opencode.plugin.v1.RegisterCapabilitiesResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 20: opencode.plugin.v1.UnregisterCapabilitiesResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 21: opencode.plugin.v1.ReportProgressResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 22: opencode.plugin.v1.ReadFileResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 23: opencode.plugin.v1.WriteFileResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 24: opencode.plugin.v1.GlobFilesResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 25: opencode.plugin.v1.WatchFilesResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 26: opencode.plugin.v1.UnwatchFilesResponse.error:type_name -> opencode.plugin.v1.PluginError
+	63, // 27: opencode.plugin.v1.PublishEventRequest.data:type_name -> google.protobuf.Struct
+	5,  // 28: opencode.plugin.v1.PublishEventResponse.error:type_name -> opencode.plugin.v1.PluginError
+	37, // 29: opencode.plugin.v1.CompleteRequest.messages:type_name -> opencode.plugin.v1.LLMMessage
+	5,  // 30: opencode.plugin.v1.CompleteResponse.error:type_name -> opencode.plugin.v1.PluginError
+	63, // 31: opencode.plugin.v1.Event.data:type_name -> google.protobuf.Struct
+	5,  // 32: opencode.plugin.v1.HandleEventResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 33: opencode.plugin.v1.SetLogLevelResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 34: opencode.plugin.v1.DrainResponse.error:type_name -> opencode.plugin.v1.PluginError
+	5,  // 35: opencode.plugin.v1.DaemonResponse.error:type_name -> opencode.plugin.v1.PluginError
+	3,  // 36: opencode.plugin.v1.ExecuteBatchRequest.items:type_name -> opencode.plugin.v1.ExecuteRequest
+	4,  // 37: opencode.plugin.v1.ExecuteBatchResponse.results:type_name -> opencode.plugin.v1.ExecuteResponse
+	63, // 38: opencode.plugin.v1.InitializeRequest.config:type_name -> google.protobuf.Struct
+	5,  // 39: opencode.plugin.v1.InitializeResponse.error:type_name -> opencode.plugin.v1.PluginError
+	0,  // 40: opencode.plugin.v1.CommandPlugin.Name:input_type -> opencode.plugin.v1.Empty
+	0,  // 41: opencode.plugin.v1.CommandPlugin.Version:input_type -> opencode.plugin.v1.Empty
+	3,  // 42: opencode.plugin.v1.CommandPlugin.Execute:input_type -> opencode.plugin.v1.ExecuteRequest
+	0,  // 43: opencode.plugin.v1.CommandPlugin.GetCapabilities:input_type -> opencode.plugin.v1.Empty
+	0,  // 44: opencode.plugin.v1.CommandPlugin.CacheTTLs:input_type -> opencode.plugin.v1.Empty
+	9,  // 45: opencode.plugin.v1.CommandPlugin.Session:input_type -> opencode.plugin.v1.SessionRequest
+	11, // 46: opencode.plugin.v1.CommandPlugin.SetHost:input_type -> opencode.plugin.v1.SetHostRequest
+	40, // 47: opencode.plugin.v1.CommandPlugin.HandleEvent:input_type -> opencode.plugin.v1.Event
+	58, // 48: opencode.plugin.v1.CommandPlugin.Initialize:input_type -> opencode.plugin.v1.InitializeRequest
+	3,  // 49: opencode.plugin.v1.CommandPlugin.Plan:input_type -> opencode.plugin.v1.ExecuteRequest
+	42, // 50: opencode.plugin.v1.CommandPlugin.NegotiateCodec:input_type -> opencode.plugin.v1.NegotiateCodecRequest
+	44, // 51: opencode.plugin.v1.CommandPlugin.Cancel:input_type -> opencode.plugin.v1.CancelRequest
+	46, // 52: opencode.plugin.v1.CommandPlugin.ExpireBudget:input_type -> opencode.plugin.v1.ExpireBudgetRequest
+	56, // 53: opencode.plugin.v1.CommandPlugin.ExecuteBatch:input_type -> opencode.plugin.v1.ExecuteBatchRequest
+	48, // 54: opencode.plugin.v1.CommandPlugin.NegotiateHandoff:input_type -> opencode.plugin.v1.NegotiateHandoffRequest
+	50, // 55: opencode.plugin.v1.CommandPlugin.SetLogLevel:input_type -> opencode.plugin.v1.SetLogLevelRequest
+	52, // 56: opencode.plugin.v1.CommandPlugin.Drain:input_type -> opencode.plugin.v1.DrainRequest
+	54, // 57: opencode.plugin.v1.CommandPlugin.DaemonStart:input_type -> opencode.plugin.v1.DaemonRequest
+	54, // 58: opencode.plugin.v1.CommandPlugin.DaemonStop:input_type -> opencode.plugin.v1.DaemonRequest
+	54, // 59: opencode.plugin.v1.CommandPlugin.DaemonStatus:input_type -> opencode.plugin.v1.DaemonRequest
+	12, // 60: opencode.plugin.v1.HostServices.RenderTemplate:input_type -> opencode.plugin.v1.RenderTemplateRequest
+	14, // 61: opencode.plugin.v1.HostServices.CallPlugin:input_type -> opencode.plugin.v1.CallPluginRequest
+	15, // 62: opencode.plugin.v1.HostServices.GetContext:input_type -> opencode.plugin.v1.GetContextRequest
+	17, // 63: opencode.plugin.v1.HostServices.SetContext:input_type -> opencode.plugin.v1.SetContextRequest
+	19, // 64: opencode.plugin.v1.HostServices.RegisterCapabilities:input_type -> opencode.plugin.v1.RegisterCapabilitiesRequest
+	21, // 65: opencode.plugin.v1.HostServices.UnregisterCapabilities:input_type -> opencode.plugin.v1.UnregisterCapabilitiesRequest
+	23, // 66: opencode.plugin.v1.HostServices.ReportProgress:input_type -> opencode.plugin.v1.ReportProgressRequest
+	25, // 67: opencode.plugin.v1.HostServices.ReadFile:input_type -> opencode.plugin.v1.ReadFileRequest
+	27, // 68: opencode.plugin.v1.HostServices.WriteFile:input_type -> opencode.plugin.v1.WriteFileRequest
+	29, // 69: opencode.plugin.v1.HostServices.GlobFiles:input_type -> opencode.plugin.v1.GlobFilesRequest
+	31, // 70: opencode.plugin.v1.HostServices.WatchFiles:input_type -> opencode.plugin.v1.WatchFilesRequest
+	33, // 71: opencode.plugin.v1.HostServices.UnwatchFiles:input_type -> opencode.plugin.v1.UnwatchFilesRequest
+	35, // 72: opencode.plugin.v1.HostServices.PublishEvent:input_type -> opencode.plugin.v1.PublishEventRequest
+	38, // 73: opencode.plugin.v1.HostServices.Complete:input_type -> opencode.plugin.v1.CompleteRequest
+	1,  // 74: opencode.plugin.v1.CommandPlugin.Name:output_type -> opencode.plugin.v1.NameResponse
+	2,  // 75: opencode.plugin.v1.CommandPlugin.Version:output_type -> opencode.plugin.v1.VersionResponse
+	4,  // 76: opencode.plugin.v1.CommandPlugin.Execute:output_type -> opencode.plugin.v1.ExecuteResponse
+	6,  // 77: opencode.plugin.v1.CommandPlugin.GetCapabilities:output_type -> opencode.plugin.v1.GetCapabilitiesResponse
+	8,  // 78: opencode.plugin.v1.CommandPlugin.CacheTTLs:output_type -> opencode.plugin.v1.CacheTTLsResponse
+	10, // 79: opencode.plugin.v1.CommandPlugin.Session:output_type -> opencode.plugin.v1.SessionResponse
+	0,  // 80: opencode.plugin.v1.CommandPlugin.SetHost:output_type -> opencode.plugin.v1.Empty
+	41, // 81: opencode.plugin.v1.CommandPlugin.HandleEvent:output_type -> opencode.plugin.v1.HandleEventResponse
+	59, // 82: opencode.plugin.v1.CommandPlugin.Initialize:output_type -> opencode.plugin.v1.InitializeResponse
+	4,  // 83: opencode.plugin.v1.CommandPlugin.Plan:output_type -> opencode.plugin.v1.ExecuteResponse
+	43, // 84: opencode.plugin.v1.CommandPlugin.NegotiateCodec:output_type -> opencode.plugin.v1.NegotiateCodecResponse
+	45, // 85: opencode.plugin.v1.CommandPlugin.Cancel:output_type -> opencode.plugin.v1.CancelResponse
+	47, // 86: opencode.plugin.v1.CommandPlugin.ExpireBudget:output_type -> opencode.plugin.v1.ExpireBudgetResponse
+	57, // 87: opencode.plugin.v1.CommandPlugin.ExecuteBatch:output_type -> opencode.plugin.v1.ExecuteBatchResponse
+	49, // 88: opencode.plugin.v1.CommandPlugin.NegotiateHandoff:output_type -> opencode.plugin.v1.NegotiateHandoffResponse
+	51, // 89: opencode.plugin.v1.CommandPlugin.SetLogLevel:output_type -> opencode.plugin.v1.SetLogLevelResponse
+	53, // 90: opencode.plugin.v1.CommandPlugin.Drain:output_type -> opencode.plugin.v1.DrainResponse
+	55, // 91: opencode.plugin.v1.CommandPlugin.DaemonStart:output_type -> opencode.plugin.v1.DaemonResponse
+	55, // 92: opencode.plugin.v1.CommandPlugin.DaemonStop:output_type -> opencode.plugin.v1.DaemonResponse
+	55, // 93: opencode.plugin.v1.CommandPlugin.DaemonStatus:output_type -> opencode.plugin.v1.DaemonResponse
+	13, // 94: opencode.plugin.v1.HostServices.RenderTemplate:output_type -> opencode.plugin.v1.RenderTemplateResponse
+	4,  // 95: opencode.plugin.v1.HostServices.CallPlugin:output_type -> opencode.plugin.v1.ExecuteResponse
+	16, // 96: opencode.plugin.v1.HostServices.GetContext:output_type -> opencode.plugin.v1.GetContextResponse
+	18, // 97: opencode.plugin.v1.HostServices.SetContext:output_type -> opencode.plugin.v1.SetContextResponse
+	20, // 98: opencode.plugin.v1.HostServices.RegisterCapabilities:output_type -> opencode.plugin.v1.RegisterCapabilitiesResponse
+	22, // 99: opencode.plugin.v1.HostServices.UnregisterCapabilities:output_type -> opencode.plugin.v1.UnregisterCapabilitiesResponse
+	24, // 100: opencode.plugin.v1.HostServices.ReportProgress:output_type -> opencode.plugin.v1.ReportProgressResponse
+	26, // 101: opencode.plugin.v1.HostServices.ReadFile:output_type -> opencode.plugin.v1.ReadFileResponse
+	28, // 102: opencode.plugin.v1.HostServices.WriteFile:output_type -> opencode.plugin.v1.WriteFileResponse
+	30, // 103: opencode.plugin.v1.HostServices.GlobFiles:output_type -> opencode.plugin.v1.GlobFilesResponse
+	32, // 104: opencode.plugin.v1.HostServices.WatchFiles:output_type -> opencode.plugin.v1.WatchFilesResponse
+	34, // 105: opencode.plugin.v1.HostServices.UnwatchFiles:output_type -> opencode.plugin.v1.UnwatchFilesResponse
+	36, // 106: opencode.plugin.v1.HostServices.PublishEvent:output_type -> opencode.plugin.v1.PublishEventResponse
+	39, // 107: opencode.plugin.v1.HostServices.Complete:output_type -> opencode.plugin.v1.CompleteResponse
+	74, // [74:108] is the sub-list for method output_type
+	40, // [40:74] is the sub-list for method input_type
+	40, // [40:40] is the sub-list for extension type_name
+	40, // [40:40] is the sub-list for extension extendee
+	0,  // [0:40] is the sub-list for field type_name
+}
+
+func init() { file_proto_command_proto_init() }
+func file_proto_command_proto_init() {
+	if File_proto_command_proto != nil {
+		return
+	}
+	file_proto_command_proto_msgTypes[38].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_command_proto_rawDesc), len(file_proto_command_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   63,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_proto_command_proto_goTypes,
+		DependencyIndexes: file_proto_command_proto_depIdxs,
+		MessageInfos:      file_proto_command_proto_msgTypes,
+	}.Build()
+	File_proto_command_proto = out.File
+	file_proto_command_proto_goTypes = nil
+	file_proto_command_proto_depIdxs = nil
+}
@@ -0,0 +1,1570 @@
+// Protocol definition for CommandPlugin over gRPC.
+//
+// This is the language-neutral contract between the Go host and plugins
+// written in any language with gRPC support (see ../sdk/ for the Python and
+// TypeScript SDKs). The Go side is generated with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//          proto/command.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/command.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CommandPlugin_Name_FullMethodName             = "/opencode.plugin.v1.CommandPlugin/Name"
+	CommandPlugin_Version_FullMethodName          = "/opencode.plugin.v1.CommandPlugin/Version"
+	CommandPlugin_Execute_FullMethodName          = "/opencode.plugin.v1.CommandPlugin/Execute"
+	CommandPlugin_GetCapabilities_FullMethodName  = "/opencode.plugin.v1.CommandPlugin/GetCapabilities"
+	CommandPlugin_CacheTTLs_FullMethodName        = "/opencode.plugin.v1.CommandPlugin/CacheTTLs"
+	CommandPlugin_Session_FullMethodName          = "/opencode.plugin.v1.CommandPlugin/Session"
+	CommandPlugin_SetHost_FullMethodName          = "/opencode.plugin.v1.CommandPlugin/SetHost"
+	CommandPlugin_HandleEvent_FullMethodName      = "/opencode.plugin.v1.CommandPlugin/HandleEvent"
+	CommandPlugin_Initialize_FullMethodName       = "/opencode.plugin.v1.CommandPlugin/Initialize"
+	CommandPlugin_Plan_FullMethodName             = "/opencode.plugin.v1.CommandPlugin/Plan"
+	CommandPlugin_NegotiateCodec_FullMethodName   = "/opencode.plugin.v1.CommandPlugin/NegotiateCodec"
+	CommandPlugin_Cancel_FullMethodName           = "/opencode.plugin.v1.CommandPlugin/Cancel"
+	CommandPlugin_ExpireBudget_FullMethodName     = "/opencode.plugin.v1.CommandPlugin/ExpireBudget"
+	CommandPlugin_ExecuteBatch_FullMethodName     = "/opencode.plugin.v1.CommandPlugin/ExecuteBatch"
+	CommandPlugin_NegotiateHandoff_FullMethodName = "/opencode.plugin.v1.CommandPlugin/NegotiateHandoff"
+	CommandPlugin_SetLogLevel_FullMethodName      = "/opencode.plugin.v1.CommandPlugin/SetLogLevel"
+	CommandPlugin_Drain_FullMethodName            = "/opencode.plugin.v1.CommandPlugin/Drain"
+	CommandPlugin_DaemonStart_FullMethodName      = "/opencode.plugin.v1.CommandPlugin/DaemonStart"
+	CommandPlugin_DaemonStop_FullMethodName       = "/opencode.plugin.v1.CommandPlugin/DaemonStop"
+	CommandPlugin_DaemonStatus_FullMethodName     = "/opencode.plugin.v1.CommandPlugin/DaemonStatus"
+)
+
+// CommandPluginClient is the client API for CommandPlugin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CommandPlugin mirrors shared.CommandPlugin method for method.
+type CommandPluginClient interface {
+	Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error)
+	Version(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionResponse, error)
+	Execute(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (*ExecuteResponse, error)
+	GetCapabilities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetCapabilitiesResponse, error)
+	CacheTTLs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CacheTTLsResponse, error)
+	// Session carries one stateful conversation. The first request opens the
+	// session, every following request is executed in it, and closing the
+	// send side ends it.
+	Session(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionResponse], error)
+	// SetHost tells the plugin where to reach the host's HostServices. The
+	// host serves them on the go-plugin broker under broker_id.
+	SetHost(ctx context.Context, in *SetHostRequest, opts ...grpc.CallOption) (*Empty, error)
+	// HandleEvent delivers a host event the plugin subscribed to in its
+	// manifest. Plugins that do not handle events ignore it.
+	HandleEvent(ctx context.Context, in *Event, opts ...grpc.CallOption) (*HandleEventResponse, error)
+	// Initialize passes the plugin its configuration from the host config,
+	// with secret references already resolved. It is called once per process
+	// before the first call.
+	Initialize(ctx context.Context, in *InitializeRequest, opts ...grpc.CallOption) (*InitializeResponse, error)
+	// Plan describes what Execute would do with the same arguments without
+	// doing it. Arguments carry dry_run set to true.
+	Plan(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (*ExecuteResponse, error)
+	// NegotiateCodec picks the argument encoding for Execute and Plan. The
+	// host calls it once after connecting, listing the codecs it accepts in
+	// order of preference; the plugin answers with the first it supports.
+	NegotiateCodec(ctx context.Context, in *NegotiateCodecRequest, opts ...grpc.CallOption) (*NegotiateCodecResponse, error)
+	// Cancel asks the plugin to abort the call whose call_id argument names
+	// call_id. The host waits a grace period for the call to return before
+	// killing the process.
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
+	// ExpireBudget tells the plugin that the latency budget of the call whose
+	// call_id argument names call_id ran out, so it should return what it has
+	// so far with partial set.
+	ExpireBudget(ctx context.Context, in *ExpireBudgetRequest, opts ...grpc.CallOption) (*ExpireBudgetResponse, error)
+	// ExecuteBatch runs many calls in one round trip, so the plugin can share
+	// setup such as loading a model across them. Each item has its own
+	// result or error, in the order of the items.
+	ExecuteBatch(ctx context.Context, in *ExecuteBatchRequest, opts ...grpc.CallOption) (*ExecuteBatchResponse, error)
+	// NegotiateHandoff offers to pass large arguments through files in dir
+	// instead of the request. The host calls it once after NegotiateCodec;
+	// the plugin accepts when it can read the directory.
+	NegotiateHandoff(ctx context.Context, in *NegotiateHandoffRequest, opts ...grpc.CallOption) (*NegotiateHandoffResponse, error)
+	// SetLogLevel switches the level the plugin logs at while it runs, one of
+	// trace, debug, info, warn or error; empty restores the level it started
+	// with.
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error)
+	// Drain is called before the host stops the plugin process. The plugin
+	// stops taking on new internal work, checkpoints its state and answers
+	// once it is safe to terminate.
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error)
+	// DaemonStart starts the server of a daemon plugin and answers once it
+	// runs. Plugins that are no daemon answer with an unsupported error.
+	DaemonStart(ctx context.Context, in *DaemonRequest, opts ...grpc.CallOption) (*DaemonResponse, error)
+	// DaemonStop stops the server of a daemon plugin; the process keeps
+	// running until the host stops it.
+	DaemonStop(ctx context.Context, in *DaemonRequest, opts ...grpc.CallOption) (*DaemonResponse, error)
+	// DaemonStatus reports the state of the server of a daemon plugin.
+	DaemonStatus(ctx context.Context, in *DaemonRequest, opts ...grpc.CallOption) (*DaemonResponse, error)
+}
+
+type commandPluginClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCommandPluginClient(cc grpc.ClientConnInterface) CommandPluginClient {
+	return &commandPluginClient{cc}
+}
+
+func (c *commandPluginClient) Name(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*NameResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NameResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Name_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Version(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VersionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VersionResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Version_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Execute(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (*ExecuteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Execute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) GetCapabilities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetCapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_GetCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) CacheTTLs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*CacheTTLsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CacheTTLsResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_CacheTTLs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Session(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CommandPlugin_ServiceDesc.Streams[0], CommandPlugin_Session_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SessionRequest, SessionResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CommandPlugin_SessionClient = grpc.BidiStreamingClient[SessionRequest, SessionResponse]
+
+func (c *commandPluginClient) SetHost(ctx context.Context, in *SetHostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, CommandPlugin_SetHost_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) HandleEvent(ctx context.Context, in *Event, opts ...grpc.CallOption) (*HandleEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HandleEventResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_HandleEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Initialize(ctx context.Context, in *InitializeRequest, opts ...grpc.CallOption) (*InitializeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InitializeResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Initialize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Plan(ctx context.Context, in *ExecuteRequest, opts ...grpc.CallOption) (*ExecuteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Plan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) NegotiateCodec(ctx context.Context, in *NegotiateCodecRequest, opts ...grpc.CallOption) (*NegotiateCodecResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NegotiateCodecResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_NegotiateCodec_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) ExpireBudget(ctx context.Context, in *ExpireBudgetRequest, opts ...grpc.CallOption) (*ExpireBudgetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExpireBudgetResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_ExpireBudget_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) ExecuteBatch(ctx context.Context, in *ExecuteBatchRequest, opts ...grpc.CallOption) (*ExecuteBatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteBatchResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_ExecuteBatch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) NegotiateHandoff(ctx context.Context, in *NegotiateHandoffRequest, opts ...grpc.CallOption) (*NegotiateHandoffResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NegotiateHandoffResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_NegotiateHandoff_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*SetLogLevelResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetLogLevelResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_SetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DrainResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_Drain_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) DaemonStart(ctx context.Context, in *DaemonRequest, opts ...grpc.CallOption) (*DaemonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DaemonResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_DaemonStart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) DaemonStop(ctx context.Context, in *DaemonRequest, opts ...grpc.CallOption) (*DaemonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DaemonResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_DaemonStop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *commandPluginClient) DaemonStatus(ctx context.Context, in *DaemonRequest, opts ...grpc.CallOption) (*DaemonResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DaemonResponse)
+	err := c.cc.Invoke(ctx, CommandPlugin_DaemonStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CommandPluginServer is the server API for CommandPlugin service.
+// All implementations must embed UnimplementedCommandPluginServer
+// for forward compatibility.
+//
+// CommandPlugin mirrors shared.CommandPlugin method for method.
+type CommandPluginServer interface {
+	Name(context.Context, *Empty) (*NameResponse, error)
+	Version(context.Context, *Empty) (*VersionResponse, error)
+	Execute(context.Context, *ExecuteRequest) (*ExecuteResponse, error)
+	GetCapabilities(context.Context, *Empty) (*GetCapabilitiesResponse, error)
+	CacheTTLs(context.Context, *Empty) (*CacheTTLsResponse, error)
+	// Session carries one stateful conversation. The first request opens the
+	// session, every following request is executed in it, and closing the
+	// send side ends it.
+	Session(grpc.BidiStreamingServer[SessionRequest, SessionResponse]) error
+	// SetHost tells the plugin where to reach the host's HostServices. The
+	// host serves them on the go-plugin broker under broker_id.
+	SetHost(context.Context, *SetHostRequest) (*Empty, error)
+	// HandleEvent delivers a host event the plugin subscribed to in its
+	// manifest. Plugins that do not handle events ignore it.
+	HandleEvent(context.Context, *Event) (*HandleEventResponse, error)
+	// Initialize passes the plugin its configuration from the host config,
+	// with secret references already resolved. It is called once per process
+	// before the first call.
+	Initialize(context.Context, *InitializeRequest) (*InitializeResponse, error)
+	// Plan describes what Execute would do with the same arguments without
+	// doing it. Arguments carry dry_run set to true.
+	Plan(context.Context, *ExecuteRequest) (*ExecuteResponse, error)
+	// NegotiateCodec picks the argument encoding for Execute and Plan. The
+	// host calls it once after connecting, listing the codecs it accepts in
+	// order of preference; the plugin answers with the first it supports.
+	NegotiateCodec(context.Context, *NegotiateCodecRequest) (*NegotiateCodecResponse, error)
+	// Cancel asks the plugin to abort the call whose call_id argument names
+	// call_id. The host waits a grace period for the call to return before
+	// killing the process.
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+	// ExpireBudget tells the plugin that the latency budget of the call whose
+	// call_id argument names call_id ran out, so it should return what it has
+	// so far with partial set.
+	ExpireBudget(context.Context, *ExpireBudgetRequest) (*ExpireBudgetResponse, error)
+	// ExecuteBatch runs many calls in one round trip, so the plugin can share
+	// setup such as loading a model across them. Each item has its own
+	// result or error, in the order of the items.
+	ExecuteBatch(context.Context, *ExecuteBatchRequest) (*ExecuteBatchResponse, error)
+	// NegotiateHandoff offers to pass large arguments through files in dir
+	// instead of the request. The host calls it once after NegotiateCodec;
+	// the plugin accepts when it can read the directory.
+	NegotiateHandoff(context.Context, *NegotiateHandoffRequest) (*NegotiateHandoffResponse, error)
+	// SetLogLevel switches the level the plugin logs at while it runs, one of
+	// trace, debug, info, warn or error; empty restores the level it started
+	// with.
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error)
+	// Drain is called before the host stops the plugin process. The plugin
+	// stops taking on new internal work, checkpoints its state and answers
+	// once it is safe to terminate.
+	Drain(context.Context, *DrainRequest) (*DrainResponse, error)
+	// DaemonStart starts the server of a daemon plugin and answers once it
+	// runs. Plugins that are no daemon answer with an unsupported error.
+	DaemonStart(context.Context, *DaemonRequest) (*DaemonResponse, error)
+	// DaemonStop stops the server of a daemon plugin; the process keeps
+	// running until the host stops it.
+	DaemonStop(context.Context, *DaemonRequest) (*DaemonResponse, error)
+	// DaemonStatus reports the state of the server of a daemon plugin.
+	DaemonStatus(context.Context, *DaemonRequest) (*DaemonResponse, error)
+	mustEmbedUnimplementedCommandPluginServer()
+}
+
+// UnimplementedCommandPluginServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCommandPluginServer struct{}
+
+func (UnimplementedCommandPluginServer) Name(context.Context, *Empty) (*NameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Name not implemented")
+}
+func (UnimplementedCommandPluginServer) Version(context.Context, *Empty) (*VersionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Version not implemented")
+}
+func (UnimplementedCommandPluginServer) Execute(context.Context, *ExecuteRequest) (*ExecuteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Execute not implemented")
+}
+func (UnimplementedCommandPluginServer) GetCapabilities(context.Context, *Empty) (*GetCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapabilities not implemented")
+}
+func (UnimplementedCommandPluginServer) CacheTTLs(context.Context, *Empty) (*CacheTTLsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CacheTTLs not implemented")
+}
+func (UnimplementedCommandPluginServer) Session(grpc.BidiStreamingServer[SessionRequest, SessionResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Session not implemented")
+}
+func (UnimplementedCommandPluginServer) SetHost(context.Context, *SetHostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetHost not implemented")
+}
+func (UnimplementedCommandPluginServer) HandleEvent(context.Context, *Event) (*HandleEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HandleEvent not implemented")
+}
+func (UnimplementedCommandPluginServer) Initialize(context.Context, *InitializeRequest) (*InitializeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Initialize not implemented")
+}
+func (UnimplementedCommandPluginServer) Plan(context.Context, *ExecuteRequest) (*ExecuteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Plan not implemented")
+}
+func (UnimplementedCommandPluginServer) NegotiateCodec(context.Context, *NegotiateCodecRequest) (*NegotiateCodecResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NegotiateCodec not implemented")
+}
+func (UnimplementedCommandPluginServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedCommandPluginServer) ExpireBudget(context.Context, *ExpireBudgetRequest) (*ExpireBudgetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExpireBudget not implemented")
+}
+func (UnimplementedCommandPluginServer) ExecuteBatch(context.Context, *ExecuteBatchRequest) (*ExecuteBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteBatch not implemented")
+}
+func (UnimplementedCommandPluginServer) NegotiateHandoff(context.Context, *NegotiateHandoffRequest) (*NegotiateHandoffResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NegotiateHandoff not implemented")
+}
+func (UnimplementedCommandPluginServer) SetLogLevel(context.Context, *SetLogLevelRequest) (*SetLogLevelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedCommandPluginServer) Drain(context.Context, *DrainRequest) (*DrainResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Drain not implemented")
+}
+func (UnimplementedCommandPluginServer) DaemonStart(context.Context, *DaemonRequest) (*DaemonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DaemonStart not implemented")
+}
+func (UnimplementedCommandPluginServer) DaemonStop(context.Context, *DaemonRequest) (*DaemonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DaemonStop not implemented")
+}
+func (UnimplementedCommandPluginServer) DaemonStatus(context.Context, *DaemonRequest) (*DaemonResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DaemonStatus not implemented")
+}
+func (UnimplementedCommandPluginServer) mustEmbedUnimplementedCommandPluginServer() {}
+func (UnimplementedCommandPluginServer) testEmbeddedByValue()                       {}
+
+// UnsafeCommandPluginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CommandPluginServer will
+// result in compilation errors.
+type UnsafeCommandPluginServer interface {
+	mustEmbedUnimplementedCommandPluginServer()
+}
+
+func RegisterCommandPluginServer(s grpc.ServiceRegistrar, srv CommandPluginServer) {
+	// If the following call pancis, it indicates UnimplementedCommandPluginServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CommandPlugin_ServiceDesc, srv)
+}
+
+func _CommandPlugin_Name_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Name(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Name_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Name(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Version_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Version(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Version_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Version(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Execute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Execute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Execute_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Execute(ctx, req.(*ExecuteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_GetCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).GetCapabilities(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_CacheTTLs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).CacheTTLs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_CacheTTLs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).CacheTTLs(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Session_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CommandPluginServer).Session(&grpc.GenericServerStream[SessionRequest, SessionResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CommandPlugin_SessionServer = grpc.BidiStreamingServer[SessionRequest, SessionResponse]
+
+func _CommandPlugin_SetHost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).SetHost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_SetHost_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).SetHost(ctx, req.(*SetHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_HandleEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Event)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).HandleEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_HandleEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).HandleEvent(ctx, req.(*Event))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Initialize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitializeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Initialize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Initialize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Initialize(ctx, req.(*InitializeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Plan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Plan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Plan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Plan(ctx, req.(*ExecuteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_NegotiateCodec_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NegotiateCodecRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).NegotiateCodec(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_NegotiateCodec_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).NegotiateCodec(ctx, req.(*NegotiateCodecRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_ExpireBudget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExpireBudgetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).ExpireBudget(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_ExpireBudget_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).ExpireBudget(ctx, req.(*ExpireBudgetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_ExecuteBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).ExecuteBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_ExecuteBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).ExecuteBatch(ctx, req.(*ExecuteBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_NegotiateHandoff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NegotiateHandoffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).NegotiateHandoff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_NegotiateHandoff_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).NegotiateHandoff(ctx, req.(*NegotiateHandoffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_Drain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_DaemonStart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DaemonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).DaemonStart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_DaemonStart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).DaemonStart(ctx, req.(*DaemonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_DaemonStop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DaemonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).DaemonStop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_DaemonStop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).DaemonStop(ctx, req.(*DaemonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CommandPlugin_DaemonStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DaemonRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CommandPluginServer).DaemonStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CommandPlugin_DaemonStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CommandPluginServer).DaemonStatus(ctx, req.(*DaemonRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CommandPlugin_ServiceDesc is the grpc.ServiceDesc for CommandPlugin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CommandPlugin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opencode.plugin.v1.CommandPlugin",
+	HandlerType: (*CommandPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Name",
+			Handler:    _CommandPlugin_Name_Handler,
+		},
+		{
+			MethodName: "Version",
+			Handler:    _CommandPlugin_Version_Handler,
+		},
+		{
+			MethodName: "Execute",
+			Handler:    _CommandPlugin_Execute_Handler,
+		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _CommandPlugin_GetCapabilities_Handler,
+		},
+		{
+			MethodName: "CacheTTLs",
+			Handler:    _CommandPlugin_CacheTTLs_Handler,
+		},
+		{
+			MethodName: "SetHost",
+			Handler:    _CommandPlugin_SetHost_Handler,
+		},
+		{
+			MethodName: "HandleEvent",
+			Handler:    _CommandPlugin_HandleEvent_Handler,
+		},
+		{
+			MethodName: "Initialize",
+			Handler:    _CommandPlugin_Initialize_Handler,
+		},
+		{
+			MethodName: "Plan",
+			Handler:    _CommandPlugin_Plan_Handler,
+		},
+		{
+			MethodName: "NegotiateCodec",
+			Handler:    _CommandPlugin_NegotiateCodec_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _CommandPlugin_Cancel_Handler,
+		},
+		{
+			MethodName: "ExpireBudget",
+			Handler:    _CommandPlugin_ExpireBudget_Handler,
+		},
+		{
+			MethodName: "ExecuteBatch",
+			Handler:    _CommandPlugin_ExecuteBatch_Handler,
+		},
+		{
+			MethodName: "NegotiateHandoff",
+			Handler:    _CommandPlugin_NegotiateHandoff_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _CommandPlugin_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _CommandPlugin_Drain_Handler,
+		},
+		{
+			MethodName: "DaemonStart",
+			Handler:    _CommandPlugin_DaemonStart_Handler,
+		},
+		{
+			MethodName: "DaemonStop",
+			Handler:    _CommandPlugin_DaemonStop_Handler,
+		},
+		{
+			MethodName: "DaemonStatus",
+			Handler:    _CommandPlugin_DaemonStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Session",
+			Handler:       _CommandPlugin_Session_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "proto/command.proto",
+}
+
+const (
+	HostServices_RenderTemplate_FullMethodName         = "/opencode.plugin.v1.HostServices/RenderTemplate"
+	HostServices_CallPlugin_FullMethodName             = "/opencode.plugin.v1.HostServices/CallPlugin"
+	HostServices_GetContext_FullMethodName             = "/opencode.plugin.v1.HostServices/GetContext"
+	HostServices_SetContext_FullMethodName             = "/opencode.plugin.v1.HostServices/SetContext"
+	HostServices_RegisterCapabilities_FullMethodName   = "/opencode.plugin.v1.HostServices/RegisterCapabilities"
+	HostServices_UnregisterCapabilities_FullMethodName = "/opencode.plugin.v1.HostServices/UnregisterCapabilities"
+	HostServices_ReportProgress_FullMethodName         = "/opencode.plugin.v1.HostServices/ReportProgress"
+	HostServices_ReadFile_FullMethodName               = "/opencode.plugin.v1.HostServices/ReadFile"
+	HostServices_WriteFile_FullMethodName              = "/opencode.plugin.v1.HostServices/WriteFile"
+	HostServices_GlobFiles_FullMethodName              = "/opencode.plugin.v1.HostServices/GlobFiles"
+	HostServices_WatchFiles_FullMethodName             = "/opencode.plugin.v1.HostServices/WatchFiles"
+	HostServices_UnwatchFiles_FullMethodName           = "/opencode.plugin.v1.HostServices/UnwatchFiles"
+	HostServices_PublishEvent_FullMethodName           = "/opencode.plugin.v1.HostServices/PublishEvent"
+	HostServices_Complete_FullMethodName               = "/opencode.plugin.v1.HostServices/Complete"
+)
+
+// HostServicesClient is the client API for HostServices service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// HostServices are the calls a plugin can make back into its host.
+type HostServicesClient interface {
+	RenderTemplate(ctx context.Context, in *RenderTemplateRequest, opts ...grpc.CallOption) (*RenderTemplateResponse, error)
+	// CallPlugin runs a capability of another plugin through the host, which
+	// checks that the caller may call it and that the call forms no cycle.
+	CallPlugin(ctx context.Context, in *CallPluginRequest, opts ...grpc.CallOption) (*ExecuteResponse, error)
+	// GetContext returns the shared context of a request the plugin is
+	// serving, including values other plugins in the chain have set.
+	GetContext(ctx context.Context, in *GetContextRequest, opts ...grpc.CallOption) (*GetContextResponse, error)
+	// SetContext sets a value in the shared context of a request, for the
+	// plugins called after it in the chain.
+	SetContext(ctx context.Context, in *SetContextRequest, opts ...grpc.CallOption) (*SetContextResponse, error)
+	// RegisterCapabilities adds capabilities to those the plugin offers, or
+	// replaces those with the same name and version, while its process runs.
+	RegisterCapabilities(ctx context.Context, in *RegisterCapabilitiesRequest, opts ...grpc.CallOption) (*RegisterCapabilitiesResponse, error)
+	// UnregisterCapabilities withdraws capabilities of the plugin by
+	// reference; a bare name withdraws every version.
+	UnregisterCapabilities(ctx context.Context, in *UnregisterCapabilitiesRequest, opts ...grpc.CallOption) (*UnregisterCapabilitiesResponse, error)
+	// ReportProgress tells the host how far a request the plugin is serving
+	// has come, for tasks started through the host.
+	ReportProgress(ctx context.Context, in *ReportProgressRequest, opts ...grpc.CallOption) (*ReportProgressResponse, error)
+	// ReadFile, WriteFile and GlobFiles work with files under the roots the
+	// host granted the plugin, which it checks and records.
+	ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error)
+	WriteFile(ctx context.Context, in *WriteFileRequest, opts ...grpc.CallOption) (*WriteFileResponse, error)
+	GlobFiles(ctx context.Context, in *GlobFilesRequest, opts ...grpc.CallOption) (*GlobFilesResponse, error)
+	// WatchFiles has the host send "files.changed" events for the files
+	// matching a pattern until UnwatchFiles.
+	WatchFiles(ctx context.Context, in *WatchFilesRequest, opts ...grpc.CallOption) (*WatchFilesResponse, error)
+	UnwatchFiles(ctx context.Context, in *UnwatchFilesRequest, opts ...grpc.CallOption) (*UnwatchFilesResponse, error)
+	// PublishEvent puts an event of the plugin on the host's event bus. Its
+	// type must start with the plugin's name; events over the plugin's quota
+	// or sent while it is muted are dropped.
+	PublishEvent(ctx context.Context, in *PublishEventRequest, opts ...grpc.CallOption) (*PublishEventResponse, error)
+	// Complete asks a model of one of the host's LLM providers for a
+	// completion. The host holds the API keys and accounts the tokens.
+	Complete(ctx context.Context, in *CompleteRequest, opts ...grpc.CallOption) (*CompleteResponse, error)
+}
+
+type hostServicesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewHostServicesClient(cc grpc.ClientConnInterface) HostServicesClient {
+	return &hostServicesClient{cc}
+}
+
+func (c *hostServicesClient) RenderTemplate(ctx context.Context, in *RenderTemplateRequest, opts ...grpc.CallOption) (*RenderTemplateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RenderTemplateResponse)
+	err := c.cc.Invoke(ctx, HostServices_RenderTemplate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) CallPlugin(ctx context.Context, in *CallPluginRequest, opts ...grpc.CallOption) (*ExecuteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteResponse)
+	err := c.cc.Invoke(ctx, HostServices_CallPlugin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) GetContext(ctx context.Context, in *GetContextRequest, opts ...grpc.CallOption) (*GetContextResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetContextResponse)
+	err := c.cc.Invoke(ctx, HostServices_GetContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) SetContext(ctx context.Context, in *SetContextRequest, opts ...grpc.CallOption) (*SetContextResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetContextResponse)
+	err := c.cc.Invoke(ctx, HostServices_SetContext_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) RegisterCapabilities(ctx context.Context, in *RegisterCapabilitiesRequest, opts ...grpc.CallOption) (*RegisterCapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, HostServices_RegisterCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) UnregisterCapabilities(ctx context.Context, in *UnregisterCapabilitiesRequest, opts ...grpc.CallOption) (*UnregisterCapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnregisterCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, HostServices_UnregisterCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) ReportProgress(ctx context.Context, in *ReportProgressRequest, opts ...grpc.CallOption) (*ReportProgressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReportProgressResponse)
+	err := c.cc.Invoke(ctx, HostServices_ReportProgress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) ReadFile(ctx context.Context, in *ReadFileRequest, opts ...grpc.CallOption) (*ReadFileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReadFileResponse)
+	err := c.cc.Invoke(ctx, HostServices_ReadFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) WriteFile(ctx context.Context, in *WriteFileRequest, opts ...grpc.CallOption) (*WriteFileResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WriteFileResponse)
+	err := c.cc.Invoke(ctx, HostServices_WriteFile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) GlobFiles(ctx context.Context, in *GlobFilesRequest, opts ...grpc.CallOption) (*GlobFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GlobFilesResponse)
+	err := c.cc.Invoke(ctx, HostServices_GlobFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) WatchFiles(ctx context.Context, in *WatchFilesRequest, opts ...grpc.CallOption) (*WatchFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WatchFilesResponse)
+	err := c.cc.Invoke(ctx, HostServices_WatchFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) UnwatchFiles(ctx context.Context, in *UnwatchFilesRequest, opts ...grpc.CallOption) (*UnwatchFilesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnwatchFilesResponse)
+	err := c.cc.Invoke(ctx, HostServices_UnwatchFiles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) PublishEvent(ctx context.Context, in *PublishEventRequest, opts ...grpc.CallOption) (*PublishEventResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishEventResponse)
+	err := c.cc.Invoke(ctx, HostServices_PublishEvent_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *hostServicesClient) Complete(ctx context.Context, in *CompleteRequest, opts ...grpc.CallOption) (*CompleteResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompleteResponse)
+	err := c.cc.Invoke(ctx, HostServices_Complete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HostServicesServer is the server API for HostServices service.
+// All implementations must embed UnimplementedHostServicesServer
+// for forward compatibility.
+//
+// HostServices are the calls a plugin can make back into its host.
+type HostServicesServer interface {
+	RenderTemplate(context.Context, *RenderTemplateRequest) (*RenderTemplateResponse, error)
+	// CallPlugin runs a capability of another plugin through the host, which
+	// checks that the caller may call it and that the call forms no cycle.
+	CallPlugin(context.Context, *CallPluginRequest) (*ExecuteResponse, error)
+	// GetContext returns the shared context of a request the plugin is
+	// serving, including values other plugins in the chain have set.
+	GetContext(context.Context, *GetContextRequest) (*GetContextResponse, error)
+	// SetContext sets a value in the shared context of a request, for the
+	// plugins called after it in the chain.
+	SetContext(context.Context, *SetContextRequest) (*SetContextResponse, error)
+	// RegisterCapabilities adds capabilities to those the plugin offers, or
+	// replaces those with the same name and version, while its process runs.
+	RegisterCapabilities(context.Context, *RegisterCapabilitiesRequest) (*RegisterCapabilitiesResponse, error)
+	// UnregisterCapabilities withdraws capabilities of the plugin by
+	// reference; a bare name withdraws every version.
+	UnregisterCapabilities(context.Context, *UnregisterCapabilitiesRequest) (*UnregisterCapabilitiesResponse, error)
+	// ReportProgress tells the host how far a request the plugin is serving
+	// has come, for tasks started through the host.
+	ReportProgress(context.Context, *ReportProgressRequest) (*ReportProgressResponse, error)
+	// ReadFile, WriteFile and GlobFiles work with files under the roots the
+	// host granted the plugin, which it checks and records.
+	ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error)
+	WriteFile(context.Context, *WriteFileRequest) (*WriteFileResponse, error)
+	GlobFiles(context.Context, *GlobFilesRequest) (*GlobFilesResponse, error)
+	// WatchFiles has the host send "files.changed" events for the files
+	// matching a pattern until UnwatchFiles.
+	WatchFiles(context.Context, *WatchFilesRequest) (*WatchFilesResponse, error)
+	UnwatchFiles(context.Context, *UnwatchFilesRequest) (*UnwatchFilesResponse, error)
+	// PublishEvent puts an event of the plugin on the host's event bus. Its
+	// type must start with the plugin's name; events over the plugin's quota
+	// or sent while it is muted are dropped.
+	PublishEvent(context.Context, *PublishEventRequest) (*PublishEventResponse, error)
+	// Complete asks a model of one of the host's LLM providers for a
+	// completion. The host holds the API keys and accounts the tokens.
+	Complete(context.Context, *CompleteRequest) (*CompleteResponse, error)
+	mustEmbedUnimplementedHostServicesServer()
+}
+
+// UnimplementedHostServicesServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedHostServicesServer struct{}
+
+func (UnimplementedHostServicesServer) RenderTemplate(context.Context, *RenderTemplateRequest) (*RenderTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderTemplate not implemented")
+}
+func (UnimplementedHostServicesServer) CallPlugin(context.Context, *CallPluginRequest) (*ExecuteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CallPlugin not implemented")
+}
+func (UnimplementedHostServicesServer) GetContext(context.Context, *GetContextRequest) (*GetContextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetContext not implemented")
+}
+func (UnimplementedHostServicesServer) SetContext(context.Context, *SetContextRequest) (*SetContextResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetContext not implemented")
+}
+func (UnimplementedHostServicesServer) RegisterCapabilities(context.Context, *RegisterCapabilitiesRequest) (*RegisterCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterCapabilities not implemented")
+}
+func (UnimplementedHostServicesServer) UnregisterCapabilities(context.Context, *UnregisterCapabilitiesRequest) (*UnregisterCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnregisterCapabilities not implemented")
+}
+func (UnimplementedHostServicesServer) ReportProgress(context.Context, *ReportProgressRequest) (*ReportProgressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportProgress not implemented")
+}
+func (UnimplementedHostServicesServer) ReadFile(context.Context, *ReadFileRequest) (*ReadFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadFile not implemented")
+}
+func (UnimplementedHostServicesServer) WriteFile(context.Context, *WriteFileRequest) (*WriteFileResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteFile not implemented")
+}
+func (UnimplementedHostServicesServer) GlobFiles(context.Context, *GlobFilesRequest) (*GlobFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GlobFiles not implemented")
+}
+func (UnimplementedHostServicesServer) WatchFiles(context.Context, *WatchFilesRequest) (*WatchFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WatchFiles not implemented")
+}
+func (UnimplementedHostServicesServer) UnwatchFiles(context.Context, *UnwatchFilesRequest) (*UnwatchFilesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnwatchFiles not implemented")
+}
+func (UnimplementedHostServicesServer) PublishEvent(context.Context, *PublishEventRequest) (*PublishEventResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishEvent not implemented")
+}
+func (UnimplementedHostServicesServer) Complete(context.Context, *CompleteRequest) (*CompleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Complete not implemented")
+}
+func (UnimplementedHostServicesServer) mustEmbedUnimplementedHostServicesServer() {}
+func (UnimplementedHostServicesServer) testEmbeddedByValue()                      {}
+
+// UnsafeHostServicesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to HostServicesServer will
+// result in compilation errors.
+type UnsafeHostServicesServer interface {
+	mustEmbedUnimplementedHostServicesServer()
+}
+
+func RegisterHostServicesServer(s grpc.ServiceRegistrar, srv HostServicesServer) {
+	// If the following call pancis, it indicates UnimplementedHostServicesServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&HostServices_ServiceDesc, srv)
+}
+
+func _HostServices_RenderTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).RenderTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_RenderTemplate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).RenderTemplate(ctx, req.(*RenderTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_CallPlugin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CallPluginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).CallPlugin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_CallPlugin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).CallPlugin(ctx, req.(*CallPluginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_GetContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetContextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).GetContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_GetContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).GetContext(ctx, req.(*GetContextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_SetContext_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetContextRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).SetContext(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_SetContext_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).SetContext(ctx, req.(*SetContextRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_RegisterCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).RegisterCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_RegisterCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).RegisterCapabilities(ctx, req.(*RegisterCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_UnregisterCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnregisterCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).UnregisterCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_UnregisterCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).UnregisterCapabilities(ctx, req.(*UnregisterCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_ReportProgress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportProgressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).ReportProgress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_ReportProgress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).ReportProgress(ctx, req.(*ReportProgressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_ReadFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).ReadFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_ReadFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).ReadFile(ctx, req.(*ReadFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_WriteFile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteFileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).WriteFile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_WriteFile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).WriteFile(ctx, req.(*WriteFileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_GlobFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GlobFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).GlobFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_GlobFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).GlobFiles(ctx, req.(*GlobFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_WatchFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WatchFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).WatchFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_WatchFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).WatchFiles(ctx, req.(*WatchFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_UnwatchFiles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnwatchFilesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).UnwatchFiles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_UnwatchFiles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).UnwatchFiles(ctx, req.(*UnwatchFilesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_PublishEvent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishEventRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).PublishEvent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_PublishEvent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).PublishEvent(ctx, req.(*PublishEventRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _HostServices_Complete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HostServicesServer).Complete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HostServices_Complete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HostServicesServer).Complete(ctx, req.(*CompleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// HostServices_ServiceDesc is the grpc.ServiceDesc for HostServices service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var HostServices_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opencode.plugin.v1.HostServices",
+	HandlerType: (*HostServicesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RenderTemplate",
+			Handler:    _HostServices_RenderTemplate_Handler,
+		},
+		{
+			MethodName: "CallPlugin",
+			Handler:    _HostServices_CallPlugin_Handler,
+		},
+		{
+			MethodName: "GetContext",
+			Handler:    _HostServices_GetContext_Handler,
+		},
+		{
+			MethodName: "SetContext",
+			Handler:    _HostServices_SetContext_Handler,
+		},
+		{
+			MethodName: "RegisterCapabilities",
+			Handler:    _HostServices_RegisterCapabilities_Handler,
+		},
+		{
+			MethodName: "UnregisterCapabilities",
+			Handler:    _HostServices_UnregisterCapabilities_Handler,
+		},
+		{
+			MethodName: "ReportProgress",
+			Handler:    _HostServices_ReportProgress_Handler,
+		},
+		{
+			MethodName: "ReadFile",
+			Handler:    _HostServices_ReadFile_Handler,
+		},
+		{
+			MethodName: "WriteFile",
+			Handler:    _HostServices_WriteFile_Handler,
+		},
+		{
+			MethodName: "GlobFiles",
+			Handler:    _HostServices_GlobFiles_Handler,
+		},
+		{
+			MethodName: "WatchFiles",
+			Handler:    _HostServices_WatchFiles_Handler,
+		},
+		{
+			MethodName: "UnwatchFiles",
+			Handler:    _HostServices_UnwatchFiles_Handler,
+		},
+		{
+			MethodName: "PublishEvent",
+			Handler:    _HostServices_PublishEvent_Handler,
+		},
+		{
+			MethodName: "Complete",
+			Handler:    _HostServices_Complete_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/command.proto",
+}
@@ -0,0 +1,546 @@
+// Public gRPC service of the plugin host.
+//
+// Other services use it to run the capabilities of the host's plugins, in
+// any language with gRPC support. Calls authenticate with an API key or an
+// OpenID Connect token in the "authorization: Bearer <token>" metadata.
+// Failures are gRPC status errors, with codes following the plugin's error
+// code, e.g. NOT_FOUND or INVALID_ARGUMENT. The Go side is generated with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//          proto/gateway.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: proto/gateway.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ExecuteCapabilityRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Capability to run, e.g. "analyze" or "analyze@v2".
+	Capability string `protobuf:"bytes,1,opt,name=capability,proto3" json:"capability,omitempty"`
+	// Plugin to run it on; empty picks the first plugin offering it.
+	Plugin string `protobuf:"bytes,2,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	// Arguments are free-form JSON-compatible values.
+	Args *structpb.Struct `protobuf:"bytes,3,opt,name=args,proto3" json:"args,omitempty"`
+	// Locale of the result, e.g. "de-DE".
+	Locale string `protobuf:"bytes,4,opt,name=locale,proto3" json:"locale,omitempty"`
+	// Correlates the call with the client's own request in the host's
+	// history; empty gets a random one. A running request of another
+	// client is refused.
+	RequestId string `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// How long the capability may work before returning what it has so far,
+	// e.g. "2s"; only for capabilities declaring partial results.
+	LatencyBudget string `protobuf:"bytes,6,opt,name=latency_budget,json=latencyBudget,proto3" json:"latency_budget,omitempty"`
+	// Namespace the call is made in, e.g. "staging", for feature flags that
+	// differ per namespace.
+	Namespace     string `protobuf:"bytes,7,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteCapabilityRequest) Reset() {
+	*x = ExecuteCapabilityRequest{}
+	mi := &file_proto_gateway_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteCapabilityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteCapabilityRequest) ProtoMessage() {}
+
+func (x *ExecuteCapabilityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gateway_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteCapabilityRequest.ProtoReflect.Descriptor instead.
+func (*ExecuteCapabilityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gateway_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ExecuteCapabilityRequest) GetCapability() string {
+	if x != nil {
+		return x.Capability
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityRequest) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityRequest) GetArgs() *structpb.Struct {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+func (x *ExecuteCapabilityRequest) GetLocale() string {
+	if x != nil {
+		return x.Locale
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityRequest) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityRequest) GetLatencyBudget() string {
+	if x != nil {
+		return x.LatencyBudget
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+type ExecuteCapabilityResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Result string                 `protobuf:"bytes,1,opt,name=result,proto3" json:"result,omitempty"`
+	// Plugin that ran the capability.
+	Plugin    string `protobuf:"bytes,2,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	RequestId string `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// The latency budget ran out and the result is incomplete.
+	Partial       bool `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExecuteCapabilityResponse) Reset() {
+	*x = ExecuteCapabilityResponse{}
+	mi := &file_proto_gateway_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExecuteCapabilityResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExecuteCapabilityResponse) ProtoMessage() {}
+
+func (x *ExecuteCapabilityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gateway_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExecuteCapabilityResponse.ProtoReflect.Descriptor instead.
+func (*ExecuteCapabilityResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gateway_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExecuteCapabilityResponse) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityResponse) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ExecuteCapabilityResponse) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+type CapabilityChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The next part of the result; the chunks in order make up all of it.
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	// Set on the first chunk only.
+	Plugin        string `protobuf:"bytes,2,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	RequestId     string `protobuf:"bytes,3,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	Partial       bool   `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapabilityChunk) Reset() {
+	*x = CapabilityChunk{}
+	mi := &file_proto_gateway_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilityChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilityChunk) ProtoMessage() {}
+
+func (x *CapabilityChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gateway_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilityChunk.ProtoReflect.Descriptor instead.
+func (*CapabilityChunk) Descriptor() ([]byte, []int) {
+	return file_proto_gateway_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CapabilityChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *CapabilityChunk) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *CapabilityChunk) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *CapabilityChunk) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+type ListCapabilitiesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only list the capabilities of this plugin.
+	Plugin string `protobuf:"bytes,1,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	// Only list capabilities with this tag.
+	Tag           string `protobuf:"bytes,2,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCapabilitiesRequest) Reset() {
+	*x = ListCapabilitiesRequest{}
+	mi := &file_proto_gateway_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCapabilitiesRequest) ProtoMessage() {}
+
+func (x *ListCapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gateway_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*ListCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gateway_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListCapabilitiesRequest) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *ListCapabilitiesRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type ListCapabilitiesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Capabilities  []*ProvidedCapability  `protobuf:"bytes,1,rep,name=capabilities,proto3" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCapabilitiesResponse) Reset() {
+	*x = ListCapabilitiesResponse{}
+	mi := &file_proto_gateway_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCapabilitiesResponse) ProtoMessage() {}
+
+func (x *ListCapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gateway_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*ListCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gateway_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListCapabilitiesResponse) GetCapabilities() []*ProvidedCapability {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+// ProvidedCapability is a capability and the plugin offering it.
+type ProvidedCapability struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Plugin        string                 `protobuf:"bytes,1,opt,name=plugin,proto3" json:"plugin,omitempty"`
+	PluginVersion string                 `protobuf:"bytes,2,opt,name=plugin_version,json=pluginVersion,proto3" json:"plugin_version,omitempty"`
+	Capability    *Capability            `protobuf:"bytes,3,opt,name=capability,proto3" json:"capability,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProvidedCapability) Reset() {
+	*x = ProvidedCapability{}
+	mi := &file_proto_gateway_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProvidedCapability) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProvidedCapability) ProtoMessage() {}
+
+func (x *ProvidedCapability) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gateway_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProvidedCapability.ProtoReflect.Descriptor instead.
+func (*ProvidedCapability) Descriptor() ([]byte, []int) {
+	return file_proto_gateway_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ProvidedCapability) GetPlugin() string {
+	if x != nil {
+		return x.Plugin
+	}
+	return ""
+}
+
+func (x *ProvidedCapability) GetPluginVersion() string {
+	if x != nil {
+		return x.PluginVersion
+	}
+	return ""
+}
+
+func (x *ProvidedCapability) GetCapability() *Capability {
+	if x != nil {
+		return x.Capability
+	}
+	return nil
+}
+
+var File_proto_gateway_proto protoreflect.FileDescriptor
+
+const file_proto_gateway_proto_rawDesc = "" +
+	"\n" +
+	"\x13proto/gateway.proto\x12\x13opencode.gateway.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x13proto/command.proto\"\xfb\x01\n" +
+	"\x18ExecuteCapabilityRequest\x12\x1e\n" +
+	"\n" +
+	"capability\x18\x01 \x01(\tR\n" +
+	"capability\x12\x16\n" +
+	"\x06plugin\x18\x02 \x01(\tR\x06plugin\x12+\n" +
+	"\x04args\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x04args\x12\x16\n" +
+	"\x06locale\x18\x04 \x01(\tR\x06locale\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x12%\n" +
+	"\x0elatency_budget\x18\x06 \x01(\tR\rlatencyBudget\x12\x1c\n" +
+	"\tnamespace\x18\a \x01(\tR\tnamespace\"\x84\x01\n" +
+	"\x19ExecuteCapabilityResponse\x12\x16\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\x12\x16\n" +
+	"\x06plugin\x18\x02 \x01(\tR\x06plugin\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\"v\n" +
+	"\x0fCapabilityChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\x12\x16\n" +
+	"\x06plugin\x18\x02 \x01(\tR\x06plugin\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x03 \x01(\tR\trequestId\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\"C\n" +
+	"\x17ListCapabilitiesRequest\x12\x16\n" +
+	"\x06plugin\x18\x01 \x01(\tR\x06plugin\x12\x10\n" +
+	"\x03tag\x18\x02 \x01(\tR\x03tag\"g\n" +
+	"\x18ListCapabilitiesResponse\x12K\n" +
+	"\fcapabilities\x18\x01 \x03(\v2'.opencode.gateway.v1.ProvidedCapabilityR\fcapabilities\"\x93\x01\n" +
+	"\x12ProvidedCapability\x12\x16\n" +
+	"\x06plugin\x18\x01 \x01(\tR\x06plugin\x12%\n" +
+	"\x0eplugin_version\x18\x02 \x01(\tR\rpluginVersion\x12>\n" +
+	"\n" +
+	"capability\x18\x03 \x01(\v2\x1e.opencode.plugin.v1.CapabilityR\n" +
+	"capability2\xe3\x02\n" +
+	"\x11CapabilityGateway\x12r\n" +
+	"\x11ExecuteCapability\x12-.opencode.gateway.v1.ExecuteCapabilityRequest\x1a..opencode.gateway.v1.ExecuteCapabilityResponse\x12i\n" +
+	"\x10StreamCapability\x12-.opencode.gateway.v1.ExecuteCapabilityRequest\x1a$.opencode.gateway.v1.CapabilityChunk0\x01\x12o\n" +
+	"\x10ListCapabilities\x12,.opencode.gateway.v1.ListCapabilitiesRequest\x1a-.opencode.gateway.v1.ListCapabilitiesResponseB0Z.github.com/Kirchlive/super/pkg/pluginsdk/protob\x06proto3"
+
+var (
+	file_proto_gateway_proto_rawDescOnce sync.Once
+	file_proto_gateway_proto_rawDescData []byte
+)
+
+func file_proto_gateway_proto_rawDescGZIP() []byte {
+	file_proto_gateway_proto_rawDescOnce.Do(func() {
+		file_proto_gateway_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_gateway_proto_rawDesc), len(file_proto_gateway_proto_rawDesc)))
+	})
+	return file_proto_gateway_proto_rawDescData
+}
+
+var file_proto_gateway_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_gateway_proto_goTypes = []any{
+	(*ExecuteCapabilityRequest)(nil),  // 0: opencode.gateway.v1.ExecuteCapabilityRequest
+	(*ExecuteCapabilityResponse)(nil), // 1: opencode.gateway.v1.ExecuteCapabilityResponse
+	(*CapabilityChunk)(nil),           // 2: opencode.gateway.v1.CapabilityChunk
+	(*ListCapabilitiesRequest)(nil),   // 3: opencode.gateway.v1.ListCapabilitiesRequest
+	(*ListCapabilitiesResponse)(nil),  // 4: opencode.gateway.v1.ListCapabilitiesResponse
+	(*ProvidedCapability)(nil),        // 5: opencode.gateway.v1.ProvidedCapability
+	(*structpb.Struct)(nil),           // 6: google.protobuf.Struct
+	(*Capability)(nil),                // 7: opencode.plugin.v1.Capability
+}
+var file_proto_gateway_proto_depIdxs = []int32{
+	6, // 0: opencode.gateway.v1.ExecuteCapabilityRequest.args:type_name -> google.protobuf.Struct
+	5, // 1: opencode.gateway.v1.ListCapabilitiesResponse.capabilities:type_name -> opencode.gateway.v1.ProvidedCapability
+	7, // 2: opencode.gateway.v1.ProvidedCapability.capability:type_name -> opencode.plugin.v1.Capability
+	0, // 3: opencode.gateway.v1.CapabilityGateway.ExecuteCapability:input_type -> opencode.gateway.v1.ExecuteCapabilityRequest
+	0, // 4: opencode.gateway.v1.CapabilityGateway.StreamCapability:input_type -> opencode.gateway.v1.ExecuteCapabilityRequest
+	3, // 5: opencode.gateway.v1.CapabilityGateway.ListCapabilities:input_type -> opencode.gateway.v1.ListCapabilitiesRequest
+	1, // 6: opencode.gateway.v1.CapabilityGateway.ExecuteCapability:output_type -> opencode.gateway.v1.ExecuteCapabilityResponse
+	2, // 7: opencode.gateway.v1.CapabilityGateway.StreamCapability:output_type -> opencode.gateway.v1.CapabilityChunk
+	4, // 8: opencode.gateway.v1.CapabilityGateway.ListCapabilities:output_type -> opencode.gateway.v1.ListCapabilitiesResponse
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_proto_gateway_proto_init() }
+func file_proto_gateway_proto_init() {
+	if File_proto_gateway_proto != nil {
+		return
+	}
+	file_proto_command_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_gateway_proto_rawDesc), len(file_proto_gateway_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_gateway_proto_goTypes,
+		DependencyIndexes: file_proto_gateway_proto_depIdxs,
+		MessageInfos:      file_proto_gateway_proto_msgTypes,
+	}.Build()
+	File_proto_gateway_proto = out.File
+	file_proto_gateway_proto_goTypes = nil
+	file_proto_gateway_proto_depIdxs = nil
+}
@@ -0,0 +1,225 @@
+// Public gRPC service of the plugin host.
+//
+// Other services use it to run the capabilities of the host's plugins, in
+// any language with gRPC support. Calls authenticate with an API key or an
+// OpenID Connect token in the "authorization: Bearer <token>" metadata.
+// Failures are gRPC status errors, with codes following the plugin's error
+// code, e.g. NOT_FOUND or INVALID_ARGUMENT. The Go side is generated with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//          --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//          proto/gateway.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: proto/gateway.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CapabilityGateway_ExecuteCapability_FullMethodName = "/opencode.gateway.v1.CapabilityGateway/ExecuteCapability"
+	CapabilityGateway_StreamCapability_FullMethodName  = "/opencode.gateway.v1.CapabilityGateway/StreamCapability"
+	CapabilityGateway_ListCapabilities_FullMethodName  = "/opencode.gateway.v1.CapabilityGateway/ListCapabilities"
+)
+
+// CapabilityGatewayClient is the client API for CapabilityGateway service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CapabilityGateway runs plugin capabilities for external clients.
+type CapabilityGatewayClient interface {
+	// ExecuteCapability runs a capability and returns its whole result.
+	ExecuteCapability(ctx context.Context, in *ExecuteCapabilityRequest, opts ...grpc.CallOption) (*ExecuteCapabilityResponse, error)
+	// StreamCapability runs a capability and streams its result in chunks,
+	// for results too large for one message.
+	StreamCapability(ctx context.Context, in *ExecuteCapabilityRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CapabilityChunk], error)
+	// ListCapabilities lists what the host's plugins offer.
+	ListCapabilities(ctx context.Context, in *ListCapabilitiesRequest, opts ...grpc.CallOption) (*ListCapabilitiesResponse, error)
+}
+
+type capabilityGatewayClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCapabilityGatewayClient(cc grpc.ClientConnInterface) CapabilityGatewayClient {
+	return &capabilityGatewayClient{cc}
+}
+
+func (c *capabilityGatewayClient) ExecuteCapability(ctx context.Context, in *ExecuteCapabilityRequest, opts ...grpc.CallOption) (*ExecuteCapabilityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteCapabilityResponse)
+	err := c.cc.Invoke(ctx, CapabilityGateway_ExecuteCapability_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *capabilityGatewayClient) StreamCapability(ctx context.Context, in *ExecuteCapabilityRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CapabilityChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CapabilityGateway_ServiceDesc.Streams[0], CapabilityGateway_StreamCapability_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecuteCapabilityRequest, CapabilityChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CapabilityGateway_StreamCapabilityClient = grpc.ServerStreamingClient[CapabilityChunk]
+
+func (c *capabilityGatewayClient) ListCapabilities(ctx context.Context, in *ListCapabilitiesRequest, opts ...grpc.CallOption) (*ListCapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, CapabilityGateway_ListCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CapabilityGatewayServer is the server API for CapabilityGateway service.
+// All implementations must embed UnimplementedCapabilityGatewayServer
+// for forward compatibility.
+//
+// CapabilityGateway runs plugin capabilities for external clients.
+type CapabilityGatewayServer interface {
+	// ExecuteCapability runs a capability and returns its whole result.
+	ExecuteCapability(context.Context, *ExecuteCapabilityRequest) (*ExecuteCapabilityResponse, error)
+	// StreamCapability runs a capability and streams its result in chunks,
+	// for results too large for one message.
+	StreamCapability(*ExecuteCapabilityRequest, grpc.ServerStreamingServer[CapabilityChunk]) error
+	// ListCapabilities lists what the host's plugins offer.
+	ListCapabilities(context.Context, *ListCapabilitiesRequest) (*ListCapabilitiesResponse, error)
+	mustEmbedUnimplementedCapabilityGatewayServer()
+}
+
+// UnimplementedCapabilityGatewayServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCapabilityGatewayServer struct{}
+
+func (UnimplementedCapabilityGatewayServer) ExecuteCapability(context.Context, *ExecuteCapabilityRequest) (*ExecuteCapabilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteCapability not implemented")
+}
+func (UnimplementedCapabilityGatewayServer) StreamCapability(*ExecuteCapabilityRequest, grpc.ServerStreamingServer[CapabilityChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamCapability not implemented")
+}
+func (UnimplementedCapabilityGatewayServer) ListCapabilities(context.Context, *ListCapabilitiesRequest) (*ListCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCapabilities not implemented")
+}
+func (UnimplementedCapabilityGatewayServer) mustEmbedUnimplementedCapabilityGatewayServer() {}
+func (UnimplementedCapabilityGatewayServer) testEmbeddedByValue()                           {}
+
+// UnsafeCapabilityGatewayServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CapabilityGatewayServer will
+// result in compilation errors.
+type UnsafeCapabilityGatewayServer interface {
+	mustEmbedUnimplementedCapabilityGatewayServer()
+}
+
+func RegisterCapabilityGatewayServer(s grpc.ServiceRegistrar, srv CapabilityGatewayServer) {
+	// If the following call pancis, it indicates UnimplementedCapabilityGatewayServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CapabilityGateway_ServiceDesc, srv)
+}
+
+func _CapabilityGateway_ExecuteCapability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteCapabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CapabilityGatewayServer).ExecuteCapability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CapabilityGateway_ExecuteCapability_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CapabilityGatewayServer).ExecuteCapability(ctx, req.(*ExecuteCapabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CapabilityGateway_StreamCapability_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExecuteCapabilityRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CapabilityGatewayServer).StreamCapability(m, &grpc.GenericServerStream[ExecuteCapabilityRequest, CapabilityChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CapabilityGateway_StreamCapabilityServer = grpc.ServerStreamingServer[CapabilityChunk]
+
+func _CapabilityGateway_ListCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CapabilityGatewayServer).ListCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CapabilityGateway_ListCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CapabilityGatewayServer).ListCapabilities(ctx, req.(*ListCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CapabilityGateway_ServiceDesc is the grpc.ServiceDesc for CapabilityGateway service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CapabilityGateway_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "opencode.gateway.v1.CapabilityGateway",
+	HandlerType: (*CapabilityGatewayServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ExecuteCapability",
+			Handler:    _CapabilityGateway_ExecuteCapability_Handler,
+		},
+		{
+			MethodName: "ListCapabilities",
+			Handler:    _CapabilityGateway_ListCapabilities_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamCapability",
+			Handler:       _CapabilityGateway_StreamCapability_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/gateway.proto",
+}
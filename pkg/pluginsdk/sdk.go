@@ -0,0 +1,247 @@
+// Package pluginsdk contains the interfaces and types shared between hosts and plugins
+package pluginsdk
+
+import (
+	"context"
+	"net/rpc"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// CommandPlugin is the interface that all OpenCode plugins must implement
+type CommandPlugin interface {
+	// Name returns the plugin's unique identifier
+	Name() string
+
+	// Version returns the plugin's version
+	Version() string
+
+	// Execute runs the plugin's main functionality
+	Execute(args map[string]interface{}) (string, error)
+
+	// GetCapabilities describes the capabilities this plugin provides
+	GetCapabilities() []Capability
+}
+
+// ArgCapability is the reserved argument key naming the capability a call
+// is for. The host uses it to apply per-capability policies such as caching.
+const ArgCapability = "capability"
+
+// ArgCapabilityVersion is the reserved argument key holding the version of
+// the capability a call is for, when the plugin versions it. The host
+// resolves "greet@v2" to capability "greet" and version "v2".
+const ArgCapabilityVersion = "capability_version"
+
+// ArgOptions is the reserved argument key holding the execution options
+// the host normalized from SuperClaude flags, e.g. {"think": "hard",
+// "mcp": ["sequential"]}. Only flags the plugin declares are included.
+const ArgOptions = "options"
+
+// CacheablePlugin is optionally implemented by plugins whose capabilities are
+// deterministic enough for the host to cache results
+type CacheablePlugin interface {
+	// CacheTTLs maps capability names to how long their results stay valid
+	CacheTTLs() map[string]time.Duration
+}
+
+// Handshake is a common handshake that is shared by plugin and host
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "OPENCODE_PLUGIN",
+	MagicCookieValue: "superclaude",
+}
+
+// PluginMap is the map of plugins we can dispense
+var PluginMap = map[string]plugin.Plugin{
+	"command": &CommandPluginImpl{},
+}
+
+// Serve runs impl as a plugin process. It is called from a plugin's main
+// function and blocks until the host disconnects. Over gRPC, go-plugin also
+// registers the standard health service, which the host polls for
+// liveness, and server reflection, so tools such as grpcurl work against a
+// running plugin.
+func Serve(impl CommandPlugin) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins: map[string]plugin.Plugin{
+			"command": &CommandPluginImpl{Impl: impl},
+		},
+
+		// A non-nil value here enables gRPC serving for this plugin
+		GRPCServer: limitedGRPCServer,
+	})
+}
+
+// CommandPluginImpl is the implementation of plugin.Plugin and
+// plugin.GRPCPlugin for CommandPlugin. Go plugins may be served over either
+// protocol; plugins written in other languages always use gRPC.
+type CommandPluginImpl struct {
+	Impl CommandPlugin
+
+	// Host is offered to plugins implementing HostAware; only the host
+	// side sets it
+	Host HostServices
+}
+
+func (p *CommandPluginImpl) Server(broker *plugin.MuxBroker) (interface{}, error) {
+	return &CommandPluginRPCServer{Impl: p.Impl, broker: broker}, nil
+}
+
+func (p *CommandPluginImpl) Client(broker *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	if p.Host != nil {
+		serveHostRPC(p.Host, broker, c)
+	}
+	return &CommandPluginRPCClient{client: c, broker: broker}, nil
+}
+
+func (p *CommandPluginImpl) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterCommandPluginServer(s, &CommandPluginGRPCServer{Impl: p.Impl, broker: broker})
+	return nil
+}
+
+func (p *CommandPluginImpl) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	client := proto.NewCommandPluginClient(c)
+	if p.Host != nil {
+		serveHostGRPC(p.Host, broker, client)
+	}
+	return &CommandPluginGRPCClient{client: client}, nil
+}
+
+// CommandPluginRPCServer is the RPC server that CommandPluginRPCClient talks to
+type CommandPluginRPCServer struct {
+	Impl   CommandPlugin
+	broker *plugin.MuxBroker
+}
+
+// Name implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Name(args interface{}, resp *string) error {
+	*resp = s.Impl.Name()
+	return nil
+}
+
+// Version implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Version(args interface{}, resp *string) error {
+	*resp = s.Impl.Version()
+	return nil
+}
+
+// ExecuteResponse is the net/rpc reply to Execute. Plugin errors travel in
+// the envelope rather than as the RPC error, which net/rpc would flatten
+// into a string.
+type ExecuteResponse struct {
+	Result string
+	Error  *PluginError
+
+	// Partial is set when the result is what the plugin had when the
+	// call's latency budget ran out
+	Partial bool
+
+	// SpillPath is the file the result was spilled to instead of being
+	// sent, in the host's spill directory
+	SpillPath string
+}
+
+// Execute implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) Execute(args map[string]interface{}, resp *ExecuteResponse) error {
+	_, done := runningCalls.start(context.Background(), args)
+	defer done()
+	result, spillPath, partial, err := splitPartial(s.Impl.Execute(args))
+	resp.Result = result
+	resp.SpillPath = spillPath
+	resp.Partial = partial
+	resp.Error = AsPluginError(err)
+	return nil
+}
+
+// GetCapabilities implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) GetCapabilities(args interface{}, resp *[]Capability) error {
+	*resp = s.Impl.GetCapabilities()
+	return nil
+}
+
+// CacheTTLs implements the server side of the RPC interface
+func (s *CommandPluginRPCServer) CacheTTLs(args interface{}, resp *map[string]time.Duration) error {
+	if c, ok := s.Impl.(CacheablePlugin); ok {
+		*resp = c.CacheTTLs()
+	}
+	return nil
+}
+
+// CommandPluginRPCClient is the client implementation
+type CommandPluginRPCClient struct {
+	client *rpc.Client
+	broker *plugin.MuxBroker
+
+	// codec encodes arguments once negotiated; nil leaves them to gob
+	codec Codec
+
+	// handoff receives large arguments once the plugin accepted handoffs
+	handoff *HandoffStore
+
+	// spills takes the results the plugin spills
+	spills *SpillStore
+}
+
+// Name calls the plugin's Name method via RPC
+func (c *CommandPluginRPCClient) Name() string {
+	var resp string
+	err := c.client.Call("Plugin.Name", new(interface{}), &resp)
+	if err != nil {
+		return ""
+	}
+	return resp
+}
+
+// Version calls the plugin's Version method via RPC
+func (c *CommandPluginRPCClient) Version() string {
+	var resp string
+	err := c.client.Call("Plugin.Version", new(interface{}), &resp)
+	if err != nil {
+		return ""
+	}
+	return resp
+}
+
+// Execute calls the plugin's Execute method via RPC
+func (c *CommandPluginRPCClient) Execute(args map[string]interface{}) (string, error) {
+	var resp ExecuteResponse
+	if err := c.call("Execute", args, &resp); err != nil {
+		return "", transportError(err)
+	}
+	if resp.Error != nil {
+		return resp.Result, resp.Error
+	}
+	result, err := c.spills.result(resp.Result, resp.SpillPath)
+	if err != nil {
+		return "", err
+	}
+	if resp.Partial {
+		return result, ErrPartial
+	}
+	return result, nil
+}
+
+// GetCapabilities calls the plugin's GetCapabilities method via RPC
+func (c *CommandPluginRPCClient) GetCapabilities() []Capability {
+	var resp []Capability
+	err := c.client.Call("Plugin.GetCapabilities", new(interface{}), &resp)
+	if err != nil {
+		return []Capability{}
+	}
+	return resp
+}
+
+// CacheTTLs calls the plugin's CacheTTLs method via RPC
+func (c *CommandPluginRPCClient) CacheTTLs() map[string]time.Duration {
+	var resp map[string]time.Duration
+	err := c.client.Call("Plugin.CacheTTLs", new(interface{}), &resp)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
@@ -0,0 +1,146 @@
+package pluginsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Kirchlive/super/pkg/pluginsdk/proto"
+)
+
+// ErrSessionsUnsupported is returned when a plugin cannot hold sessions,
+// either because it does not implement SessionPlugin or because it is served
+// over net/rpc, which has no streaming support.
+var ErrSessionsUnsupported = errors.New("plugin does not support sessions")
+
+// PluginSession is one conversation with a plugin that keeps state across calls
+type PluginSession interface {
+	// Execute runs a call within the session
+	Execute(args map[string]interface{}) (string, error)
+
+	// Close ends the session and releases its state
+	Close() error
+}
+
+// SessionPlugin is optionally implemented by plugins that need
+// conversation-style state across calls
+type SessionPlugin interface {
+	// OpenSession starts a new session identified by the host-assigned id
+	OpenSession(id string) (PluginSession, error)
+}
+
+// Session implements the server side of the gRPC session stream. The stream
+// lives as long as the session, which pins it to this plugin process.
+func (s *CommandPluginGRPCServer) Session(stream proto.CommandPlugin_SessionServer) error {
+	opener, ok := s.Impl.(SessionPlugin)
+	if !ok {
+		return status.Error(codes.Unimplemented, ErrSessionsUnsupported.Error())
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	session, err := opener.OpenSession(first.GetSessionId())
+	if err != nil {
+		return stream.Send(&proto.SessionResponse{Error: errorToProto(err)})
+	}
+	defer session.Close()
+	if err := stream.Send(&proto.SessionResponse{}); err != nil {
+		return err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result, spillPath, err := spillResult(session.Execute(req.GetArgs().AsMap()))
+		resp := &proto.SessionResponse{Result: result, SpillPath: spillPath, Error: errorToProto(err)}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// grpcSession is the host side of an open session stream
+type grpcSession struct {
+	stream proto.CommandPlugin_SessionClient
+	cancel context.CancelFunc
+	spills *SpillStore
+	mu     sync.Mutex
+}
+
+// OpenSession opens a session stream to the plugin
+func (c *CommandPluginGRPCClient) OpenSession(id string) (PluginSession, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.client.Session(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := stream.Send(&proto.SessionRequest{SessionId: id}); err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		cancel()
+		if status.Code(err) == codes.Unimplemented {
+			return nil, ErrSessionsUnsupported
+		}
+		return nil, err
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	return &grpcSession{stream: stream, cancel: cancel, spills: c.spills}, nil
+}
+
+// Execute sends one call over the session stream and waits for its reply.
+// Calls are serialized so replies cannot be matched to the wrong request.
+func (s *grpcSession) Execute(args map[string]interface{}) (string, error) {
+	pbArgs, err := newStruct(args)
+	if err != nil {
+		return "", &PluginError{Code: CodeInvalidArgument, Message: err.Error()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.stream.Send(&proto.SessionRequest{Args: pbArgs}); err != nil {
+		return "", transportError(err)
+	}
+	resp, err := s.stream.Recv()
+	if err != nil {
+		return "", transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return resp.GetResult(), err
+	}
+	return s.spills.result(resp.GetResult(), resp.GetSpillPath())
+}
+
+// Close ends the session stream
+func (s *grpcSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.stream.CloseSend()
+	s.cancel()
+	return err
+}
+
+// OpenSession always fails over net/rpc, which cannot carry a stream
+func (c *CommandPluginRPCClient) OpenSession(id string) (PluginSession, error) {
+	return nil, ErrSessionsUnsupported
+}
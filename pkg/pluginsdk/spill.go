@@ -0,0 +1,135 @@
+package pluginsdk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SpillStore is the host's end of spilled results: a directory of its own
+// that plugins are told to spill to, and the files they reported spilling
+// there. Results only count as spilled when the plugin reported the file
+// in its response and the file lies in the store's directory, so a plugin
+// cannot have the host read or remove any other file.
+type SpillStore struct {
+	dir string
+
+	mu    sync.Mutex
+	files map[string]bool
+}
+
+// NewSpillStore creates a directory under root, the system's temporary
+// directory when empty, for plugins to spill results to
+func NewSpillStore(root string) (*SpillStore, error) {
+	if root == "" {
+		root = os.TempDir()
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(root, "opencode-spill-")
+	if err == nil {
+		dir, err = filepath.Abs(dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	return &SpillStore{dir: dir, files: make(map[string]bool)}, nil
+}
+
+// Dir returns the directory plugins spill results to
+func (s *SpillStore) Dir() string {
+	return s.dir
+}
+
+// Claim takes the file a plugin reported spilling a result to and returns
+// the result the host passes on, marked with ResultFilePrefix. Files
+// outside the store's directory and anything but regular files, such as
+// symbolic links, are refused.
+func (s *SpillStore) Claim(path string) (string, error) {
+	if s == nil {
+		return "", NewError(CodeInternal, "plugin spilled its result to %s, but the host gave it no spill directory", path)
+	}
+	path, err := s.check(path)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.files[path] = true
+	s.mu.Unlock()
+	return ResultFilePrefix + path, nil
+}
+
+// Take returns the file of a result Claim marked and forgets it, so the
+// caller owns the file. Results that merely look marked are not spilled.
+func (s *SpillStore) Take(result string) (string, bool) {
+	path, ok := ResultFile(result)
+	if !ok {
+		return "", false
+	}
+	s.mu.Lock()
+	claimed := s.files[path]
+	delete(s.files, path)
+	s.mu.Unlock()
+	if !claimed {
+		return "", false
+	}
+	// The plugin may have replaced the file since
+	if _, err := s.check(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// check returns the clean path of a spilled file directly in the store's
+// directory. Files in subdirectories are refused, as Lstat would follow a
+// symbolic link on the way.
+func (s *SpillStore) check(path string) (string, error) {
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(s.dir, path)
+	if err != nil || !filepath.IsAbs(path) || rel == "." || rel == ".." || strings.ContainsRune(rel, filepath.Separator) {
+		return "", NewError(CodeInternal, "plugin spilled its result to %s, outside the spill directory %s", path, s.dir)
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return "", NewError(CodeInternal, "plugin spilled its result to %s: %v", path, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return "", NewError(CodeInternal, "plugin spilled its result to %s, which is not a regular file", path)
+	}
+	return path, nil
+}
+
+// Close removes the store's directory with the files still in it
+func (s *SpillStore) Close() error {
+	return os.RemoveAll(s.dir)
+}
+
+// SpillReceiver is implemented by the host side of plugin clients, which
+// take the results plugins spill through a SpillStore
+type SpillReceiver interface {
+	ReceiveSpills(store *SpillStore)
+}
+
+// ReceiveSpills has the client take the results the plugin spills from
+// store
+func (c *CommandPluginGRPCClient) ReceiveSpills(store *SpillStore) {
+	c.spills = store
+}
+
+// ReceiveSpills has the client take the results the plugin spills from
+// store
+func (c *CommandPluginRPCClient) ReceiveSpills(store *SpillStore) {
+	c.spills = store
+}
+
+// result returns the result of a response, claimed from store when the
+// plugin spilled it
+func (s *SpillStore) result(result, spillPath string) (string, error) {
+	if spillPath == "" {
+		return result, nil
+	}
+	return s.Claim(spillPath)
+}
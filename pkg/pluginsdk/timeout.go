@@ -0,0 +1,86 @@
+package pluginsdk
+
+import (
+	"context"
+	"time"
+)
+
+// ArgTimeout is the reserved argument key bounding a single call, as a
+// duration such as "30s" or a number of seconds. It overrides the timeouts
+// of the host and the plugin manifest; the host consumes it, so plugins
+// never see it.
+const ArgTimeout = "call_timeout"
+
+// ArgLatencyBudget is the reserved argument key giving a call of a
+// capability declaring Partial a latency budget, as a duration like
+// ArgTimeout. When it runs out the host asks the plugin to return what it
+// has so far, see WrapUp, and the call succeeds with a partial result. The
+// host consumes it, so plugins never see it.
+const ArgLatencyBudget = "latency_budget"
+
+// ContextPlugin is optionally implemented by plugins that stop working on
+// a call once the host has given up on it. Over gRPC the host's deadline
+// reaches the plugin process, and ExecuteContext is called instead of
+// Execute with a ctx that ends with the call.
+type ContextPlugin interface {
+	ExecuteContext(ctx context.Context, args map[string]interface{}) (string, error)
+}
+
+// ParseTimeout reads the value of ArgTimeout
+func ParseTimeout(v interface{}) (time.Duration, error) {
+	return parseDuration(ArgTimeout, v)
+}
+
+// ParseLatencyBudget reads the value of ArgLatencyBudget
+func ParseLatencyBudget(v interface{}) (time.Duration, error) {
+	return parseDuration(ArgLatencyBudget, v)
+}
+
+func parseDuration(key string, v interface{}) (time.Duration, error) {
+	var d time.Duration
+	switch v := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return 0, NewError(CodeInvalidArgument, "invalid %s: %v", key, err)
+		}
+		d = parsed
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case int:
+		d = time.Duration(v) * time.Second
+	case int64:
+		d = time.Duration(v) * time.Second
+	default:
+		return 0, NewError(CodeInvalidArgument, "invalid %s: %v", key, v)
+	}
+	if d <= 0 {
+		return 0, NewError(CodeInvalidArgument, "%s must be positive", key)
+	}
+	return d, nil
+}
+
+// ExecuteContext calls the plugin's Execute method via gRPC, bounded by
+// ctx; the plugin process sees the same deadline
+func (c *CommandPluginGRPCClient) ExecuteContext(ctx context.Context, args map[string]interface{}) (string, error) {
+	req, release, err := c.executeRequest(args)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	resp, err := c.client.Execute(ctx, req)
+	if err != nil {
+		return "", transportError(err)
+	}
+	if err := errorFromProto(resp.GetError()); err != nil {
+		return resp.GetResult(), err
+	}
+	result, err := c.spills.result(resp.GetResult(), resp.GetSpillPath())
+	if err != nil {
+		return "", err
+	}
+	if resp.GetPartial() {
+		return result, ErrPartial
+	}
+	return result, nil
+}
@@ -0,0 +1,32 @@
+package pluginsdk
+
+import "os"
+
+// Environment variables through which the host passes a plugin process its
+// own directories. The process also starts in its data directory, with
+// TMPDIR set to its temporary directory.
+const (
+	EnvDataDir  = "OPENCODE_DATA_DIR"
+	EnvCacheDir = "OPENCODE_CACHE_DIR"
+	EnvTempDir  = "OPENCODE_TEMP_DIR"
+)
+
+// Workspace holds the directories the host set aside for a plugin. Data
+// persists across restarts and upgrades; cache may be deleted at any time;
+// temp is emptied whenever the plugin starts or is unloaded.
+type Workspace struct {
+	DataDir  string
+	CacheDir string
+	TempDir  string
+}
+
+// PluginWorkspace returns the directories of this plugin process. They are
+// set before Initialize is called; fields are empty when the host keeps
+// plugins in its own working directory.
+func PluginWorkspace() Workspace {
+	return Workspace{
+		DataDir:  os.Getenv(EnvDataDir),
+		CacheDir: os.Getenv(EnvCacheDir),
+		TempDir:  os.Getenv(EnvTempDir),
+	}
+}
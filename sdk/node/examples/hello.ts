@@ -0,0 +1,54 @@
+#!/usr/bin/env node
+/** TypeScript port of the Go hello plugin, used to exercise the SDK end-to-end. */
+import { Args, Capability, CommandPlugin, serve } from '../src';
+
+class HelloPlugin extends CommandPlugin {
+  name(): string {
+    return 'hello-node';
+  }
+
+  version(): string {
+    return '1.0.0';
+  }
+
+  execute(args: Args): string {
+    // stdout carries the handshake, so diagnostics go to stderr
+    console.error('[PLUGIN] Executing hello command');
+    const name = typeof args.name === 'string' && args.name !== '' ? args.name : 'World';
+    switch (args.type) {
+      case 'formal':
+        return `Greetings, ${name}. Welcome to the SuperClaude integration platform.`;
+      case 'casual':
+        return `Hey ${name}! Ready to enhance OpenCode with AI?`;
+      case 'technical':
+        return `Plugin 'hello-node' v${this.version()} initialized. Target: ${name}. Integration: operational.`;
+      default:
+        return `Hello ${name} from SuperClaude integration!`;
+    }
+  }
+
+  getCapabilities(): Array<Capability | string> {
+    return [
+      {
+        name: 'greet',
+        description: 'Greet someone in the requested style',
+        argsSchema: {
+          type: 'object',
+          properties: {
+            name: { type: 'string', description: 'Who to greet' },
+            type: { type: 'string', enum: ['standard', 'formal', 'casual', 'technical'] },
+          },
+        },
+        example: { name: 'Developer', type: 'casual' },
+        tags: ['greeting'],
+        idempotent: true,
+      },
+      'greet.formal',
+      'greet.casual',
+      'greet.technical',
+      'plugin.info',
+    ];
+  }
+}
+
+serve(new HelloPlugin());
@@ -0,0 +1,772 @@
+/**
+ * TypeScript SDK for writing OpenCode SuperClaude plugins.
+ *
+ * Extend {@link CommandPlugin} and pass an instance to {@link serve}. The Go
+ * host launches the compiled script as a subprocess and talks to it over gRPC
+ * using the contract in pkg/pluginsdk/proto/command.proto.
+ */
+import { randomBytes } from 'crypto';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+
+import * as grpc from '@grpc/grpc-js';
+import * as protoLoader from '@grpc/proto-loader';
+import { ReflectionService } from '@grpc/reflection';
+import { HealthImplementation } from 'grpc-health-check';
+
+// Must match pluginsdk.Handshake in the Go host.
+export const MAGIC_COOKIE_KEY = 'OPENCODE_PLUGIN';
+export const MAGIC_COOKIE_VALUE = 'superclaude';
+const CORE_PROTOCOL_VERSION = 1;
+const APP_PROTOCOL_VERSION = 1;
+
+// Must match pluginsdk.DefaultMaxMessageBytes.
+const DEFAULT_MAX_MESSAGE_BYTES = 4 << 20;
+
+export type Args = Record<string, unknown>;
+
+// Argument codecs this SDK decodes, most preferred first; see pluginsdk.Codec.
+const CODECS = ['json'];
+
+// Decodes the arguments of an Execute or Plan request.
+function requestArgs(req: any): Args {
+  if (!req.codec) return fromStruct(req.args);
+  if (req.codec === 'json') return JSON.parse(Buffer.from(req.encodedArgs).toString('utf8'));
+  throw new PluginError(`unknown argument codec ${JSON.stringify(req.codec)}`, 'invalid_argument');
+}
+
+/**
+ * Thrown by execute() to report a classified failure to the host, which
+ * rebuilds it as a pluginsdk.PluginError. `code` should be one of the
+ * pluginsdk.ErrorCode values; any other thrown value arrives as "unknown".
+ */
+export class PluginError extends Error {
+  constructor(
+    message: string,
+    public readonly code: string = 'unknown',
+    public readonly retryable: boolean = false,
+    public readonly details: Record<string, string> = {},
+  ) {
+    super(message);
+    this.name = 'PluginError';
+  }
+}
+
+function errorToProto(err: unknown): object {
+  if (err instanceof PluginError) {
+    return {
+      code: err.code,
+      message: err.message,
+      retryable: err.retryable,
+      details: err.details,
+    };
+  }
+  return { code: 'unknown', message: err instanceof Error ? err.message : String(err) };
+}
+
+// Must match pluginsdk.ArgCallID.
+export const ARG_CALL_ID = 'call_id';
+
+// Abort controllers of the calls this process is serving, by call ID
+const runningCalls = new Map<string, AbortController>();
+
+/**
+ * Returns the signal of the call args were passed to, mirroring
+ * pluginsdk.CallContext. It aborts when the host cancels the call; the host
+ * kills the process when a canceled call does not return within its grace
+ * period. Outside a call the signal never aborts.
+ */
+export function cancelSignal(args: Args): AbortSignal {
+  return runningCalls.get(String(args[ARG_CALL_ID] ?? ''))?.signal ?? new AbortController().signal;
+}
+
+// Abort controllers of the same calls, aborted when their latency budget
+// runs out
+const wrapUps = new Map<string, AbortController>();
+
+/**
+ * Returns a signal that aborts when the latency budget of the call args were
+ * passed to runs out, mirroring pluginsdk.WrapUp. Capabilities declaring
+ * partial watch it as they work and then return what they have so far as a
+ * Partial. Outside a call the signal never aborts.
+ */
+export function wrapUpSignal(args: Args): AbortSignal {
+  return wrapUps.get(String(args[ARG_CALL_ID] ?? ''))?.signal ?? new AbortController().signal;
+}
+
+/**
+ * A result returned when the call's latency budget ran out, mirroring
+ * returning pluginsdk.ErrPartial; the host flags it as partial.
+ */
+export class Partial {
+  constructor(readonly result: string) {}
+}
+
+/**
+ * Returns the format a capability answering in formats, its default first,
+ * must answer the call args were passed to in, mirroring
+ * pluginsdk.ResultFormat: the first format the call's "accept" option lists
+ * that it offers, else its default, else "text".
+ */
+export function resultFormat(args: Args, formats: string[]): string {
+  if (formats.length === 0) {
+    return 'text';
+  }
+  const options = (args.options ?? {}) as Args;
+  let accept = options.accept ?? [];
+  if (typeof accept === 'string') {
+    accept = accept.split(',');
+  }
+  for (const wanted of accept as unknown[]) {
+    const f = String(wanted).trim().toLowerCase();
+    if (formats.includes(f)) {
+      return f;
+    }
+  }
+  return formats[0];
+}
+
+/** Must match pluginsdk.ArgProject. */
+export const ARG_PROJECT = 'project_context';
+
+/** The project a call works on, mirroring pluginsdk.ProjectContext. */
+export interface ProjectContext {
+  root: string;
+  git: boolean;
+  branch?: string;
+  commit?: string;
+  /** Changed and untracked files relative to root, at most 200 of dirty_count. */
+  dirty?: string[];
+  dirty_count: number;
+  /** Each language's share of the source bytes, from 0 to 1. */
+  languages?: Record<string, number>;
+  computed_at: string;
+}
+
+/**
+ * Returns the project context the host computed for the call args were
+ * passed to, mirroring pluginsdk.Project. It is undefined unless the manifest
+ * lists "project" under "inject" and the call names a working directory or
+ * project root.
+ */
+export function projectContext(args: Args): ProjectContext | undefined {
+  return args[ARG_PROJECT] as ProjectContext | undefined;
+}
+
+/** Describes one operation a plugin offers, mirroring pluginsdk.Capability. */
+export interface Capability {
+  name: string;
+  /** One-line, human readable summary. */
+  description?: string;
+  /** JSON Schema object describing the accepted arguments. */
+  argsSchema?: Args;
+  /** Sample arguments for invoking the capability. */
+  example?: Args;
+  tags?: string[];
+  /** Repeating a call has no further effect, so the host may retry it. */
+  idempotent?: boolean;
+  /** Revision of the capability, e.g. "v2"; callers pick it as "greet@v2". */
+  version?: string;
+  /** Non-empty marks the capability deprecated and says what to use instead. */
+  deprecated?: string;
+  /** The capability no longer works; the host may reject calls to it. */
+  removed?: boolean;
+  /**
+   * JSON Schema the result satisfies; results are parsed as JSON unless the
+   * schema's type is "string".
+   */
+  resultSchema?: Args;
+  /**
+   * Capability of the same plugin that undoes a successful call; pipelines
+   * run it when a later step fails.
+   */
+  compensate?: string;
+  /**
+   * Expected cost class of a call: "cheap", "medium" or "expensive"; the
+   * host prefers the cheapest plugin offering the capability.
+   */
+  cost?: 'cheap' | 'medium' | 'expensive';
+  /** How long a call typically takes, e.g. "200ms". */
+  latency?: string;
+  /**
+   * Result formats the capability can answer in, e.g. "json" or "markdown",
+   * the default first; see resultFormat.
+   */
+  formats?: string[];
+  /**
+   * What calls of the capability modify, e.g. "repo-write"; the host runs
+   * calls sharing a key one at a time.
+   */
+  concurrencyKey?: string;
+  /**
+   * Calls may be given a latency budget, after which execute returns what it
+   * has as a Partial; see wrapUpSignal.
+   */
+  partial?: boolean;
+}
+
+function capabilityToProto(cap: Capability | string): object {
+  const c = typeof cap === 'string' ? { name: cap } : cap;
+  return {
+    name: c.name,
+    description: c.description ?? '',
+    argsSchema: c.argsSchema ? toStruct(c.argsSchema) : undefined,
+    example: c.example ? toStruct(c.example) : undefined,
+    tags: c.tags ?? [],
+    idempotent: c.idempotent ?? false,
+    version: c.version ?? '',
+    deprecated: c.deprecated ?? '',
+    removed: c.removed ?? false,
+    resultSchema: c.resultSchema ? toStruct(c.resultSchema) : undefined,
+    compensate: c.compensate ?? '',
+    cost: c.cost ?? '',
+    latency: c.latency ?? '',
+    formats: c.formats ?? [],
+    concurrencyKey: c.concurrencyKey ?? '',
+    partial: c.partial ?? false,
+  };
+}
+
+/** Something that happened in the host or the editor, e.g. "file.saved". */
+export interface PluginEvent {
+  type: string;
+  /** Plugin the event is about, if any. */
+  plugin: string;
+  timeUnixNano: string;
+  data: Args;
+}
+
+/** One conversation with a plugin that keeps state across calls. */
+export interface PluginSession {
+  execute(args: Args): Promise<string> | string;
+  close(): void;
+}
+
+/** The state of a daemon plugin's server, mirroring pluginsdk.DaemonStatus. */
+export interface DaemonStatus {
+  state: 'starting' | 'running' | 'stopped' | 'failed';
+  /** Why the server is not running, e.g. the error it failed with. */
+  message?: string;
+  /** Where the server serves; endpoint() fills it in when left out. */
+  endpoint?: string;
+}
+
+/**
+ * The directories the host set aside for this plugin process, mirroring
+ * pluginsdk.Workspace. The process starts in dataDir with TMPDIR set to
+ * tempDir; fields are empty when the host has workspaces disabled.
+ */
+export interface Workspace {
+  dataDir: string;
+  cacheDir: string;
+  tempDir: string;
+}
+
+/** Returns this plugin's directories, mirroring pluginsdk.PluginWorkspace. */
+export function workspace(): Workspace {
+  return {
+    dataDir: process.env.OPENCODE_DATA_DIR ?? '',
+    cacheDir: process.env.OPENCODE_CACHE_DIR ?? '',
+    tempDir: process.env.OPENCODE_TEMP_DIR ?? '',
+  };
+}
+
+/** Log levels, mirroring hclog's; see pluginsdk.Logger. */
+export type LogLevel = 'trace' | 'debug' | 'info' | 'warn' | 'error';
+
+const LOG_LEVELS: LogLevel[] = ['trace', 'debug', 'info', 'warn', 'error'];
+
+function envLogLevel(): LogLevel {
+  const level = (process.env.OPENCODE_LOG_LEVEL ?? '').toLowerCase() as LogLevel;
+  return LOG_LEVELS.includes(level) ? level : 'info';
+}
+
+let logLevel = envLogLevel();
+
+function write(level: LogLevel, message: string, fields?: Record<string, unknown>): void {
+  if (LOG_LEVELS.indexOf(level) < LOG_LEVELS.indexOf(logLevel)) {
+    return;
+  }
+  const entry = { ...fields, '@level': level, '@message': message, '@timestamp': new Date().toISOString() };
+  process.stderr.write(JSON.stringify(entry) + '\n');
+}
+
+/**
+ * The plugin's logger, mirroring pluginsdk.Logger. It writes JSON lines to
+ * stderr, which the host keeps as the plugin's log with their levels and
+ * fields. It starts at OPENCODE_LOG_LEVEL, or info, and the host switches
+ * its level while the plugin runs.
+ */
+export const log = {
+  trace: (message: string, fields?: Record<string, unknown>) => write('trace', message, fields),
+  debug: (message: string, fields?: Record<string, unknown>) => write('debug', message, fields),
+  info: (message: string, fields?: Record<string, unknown>) => write('info', message, fields),
+  warn: (message: string, fields?: Record<string, unknown>) => write('warn', message, fields),
+  error: (message: string, fields?: Record<string, unknown>) => write('error', message, fields),
+  level: (): LogLevel => logLevel,
+};
+
+/** Base class all TypeScript plugins derive from. */
+export abstract class CommandPlugin {
+  /** Returns the plugin's unique identifier. */
+  abstract name(): string;
+
+  /** Returns the plugin's version. */
+  version(): string {
+    return '0.0.0';
+  }
+
+  /**
+   * Runs the plugin's main functionality. Throw to report a plugin error;
+   * return a Partial when the call's latency budget ran out.
+   */
+  abstract execute(args: Args): Promise<string | Partial> | string | Partial;
+
+  /**
+   * Runs many calls at once, mirroring pluginsdk.BatchPlugin. Returns one
+   * result per item, in order; an Error in place of a result fails that
+   * item. Override it to share setup such as loading a model across the
+   * items; the default runs execute for each.
+   */
+  async executeBatch(items: Args[]): Promise<Array<string | Error>> {
+    const results: Array<string | Error> = [];
+    for (const args of items) {
+      try {
+        const result = await this.execute(args);
+        results.push(result instanceof Partial ? result.result : result);
+      } catch (err) {
+        results.push(err instanceof Error ? err : new Error(String(err)));
+      }
+    }
+    return results;
+  }
+
+  /**
+   * Describes the capabilities this plugin provides. Bare names are accepted
+   * for plugins that have no metadata to offer.
+   */
+  getCapabilities(): Array<Capability | string> {
+    return [];
+  }
+
+  /**
+   * Maps capability names to how many seconds the host may cache results.
+   * Capabilities not listed are never cached.
+   */
+  cacheTTLs(): Record<string, number> {
+    return {};
+  }
+
+  /**
+   * Describes what execute would do with args, without doing it. Called for
+   * dry runs (--plan); args carry dry_run: true. The default refuses, so
+   * plugins that cannot plan are never executed instead.
+   */
+  plan(_args: Args): Promise<string> | string {
+    throw new PluginError('plugin does not support dry runs', 'unsupported');
+  }
+
+  /**
+   * Receives the plugin's entry from the host's plugin_config, once per
+   * process before the first call. Secret references are already resolved,
+   * so never log the values. Throw to report a failure to the host.
+   */
+  initialize(_config: Args): Promise<void> | void {}
+
+  /** Starts a session; return undefined if the plugin does not support them. */
+  openSession(_sessionId: string): PluginSession | undefined {
+    return undefined;
+  }
+
+  /**
+   * Reacts to an event listed under "events" in the plugin's manifest.
+   * Throw to report a failure to the host.
+   */
+  handleEvent(_event: PluginEvent): Promise<void> | void {}
+
+  /**
+   * Follows the level the host switched the plugin to. log already uses
+   * it; override this when the plugin logs through a logger of its own.
+   */
+  setLogLevel(_level: LogLevel): void {}
+
+  /**
+   * Gets the plugin ready to be stopped, mirroring
+   * pluginsdk.DrainablePlugin. Called before the host stops the process:
+   * stop taking on new internal work, checkpoint state, e.g. to
+   * workspace().dataDir, and resolve once it is safe to terminate. signal
+   * aborts when the host stops waiting. Throw if the plugin could not get to
+   * a safe state.
+   */
+  drain(_signal: AbortSignal): Promise<void> | void {}
+
+  /**
+   * Starts the server of a daemon plugin, mirroring pluginsdk.DaemonPlugin.
+   * Daemons are long-lived servers, such as an LSP bridge, rather than
+   * answering calls. The host calls start once the process is up and again
+   * to restart a failed server; resolve once it runs. signal aborts when the
+   * host stops waiting.
+   */
+  start(_signal: AbortSignal): Promise<void> | void {
+    throw new PluginError('plugin is not a daemon', 'unsupported');
+  }
+
+  /** Stops the server of a daemon plugin. */
+  stop(_signal: AbortSignal): Promise<void> | void {
+    throw new PluginError('plugin is not a daemon', 'unsupported');
+  }
+
+  /**
+   * Reports the state of the server of a daemon plugin. The host asks
+   * periodically and restarts servers that failed by its restart policy.
+   * Plugins that are no daemon keep this default.
+   */
+  status(): DaemonStatus {
+    throw new PluginError('plugin is not a daemon', 'unsupported');
+  }
+
+  /**
+   * Returns where the server of a daemon plugin serves, e.g.
+   * tcp://127.0.0.1:7000 or a socket path, for clients to find it.
+   */
+  endpoint(): string {
+    return '';
+  }
+}
+
+/**
+ * Runs op on the daemon's server, if any, and answers with its status
+ * afterwards, mirroring runDaemon in pluginsdk.
+ */
+async function runDaemon(
+  impl: CommandPlugin,
+  call: any,
+  cb: grpc.sendUnaryData<any>,
+  op?: (signal: AbortSignal) => Promise<void> | void,
+): Promise<void> {
+  let error: object | undefined;
+  if (op) {
+    const timeoutMs = Number(call.request.timeoutMs);
+    const signal = timeoutMs > 0 ? AbortSignal.timeout(timeoutMs) : new AbortController().signal;
+    try {
+      await op(signal);
+    } catch (err) {
+      error = errorToProto(err);
+    }
+  }
+  try {
+    const st = impl.status();
+    cb(null, { state: st.state, message: st.message ?? '', endpoint: st.endpoint || impl.endpoint(), error });
+  } catch (err) {
+    cb(null, { error: error ?? errorToProto(err) });
+  }
+}
+
+function envBytes(key: string, def: number): number {
+  const n = Number.parseInt(process.env[key] ?? '', 10);
+  return n > 0 ? n : def;
+}
+
+// Writes a result above the host's spill threshold to a file in the host's
+// spill directory, so it does not have to cross the transport. Returns the
+// response fields carrying the result or the file's path.
+function spill(result: string): { result?: string; spillPath?: string } {
+  const maxResponse = envBytes('OPENCODE_MAX_RESPONSE_BYTES', DEFAULT_MAX_MESSAGE_BYTES);
+  if (Buffer.byteLength(result) <= envBytes('OPENCODE_SPILL_BYTES', maxResponse)) {
+    return { result };
+  }
+  const dir = process.env.OPENCODE_SPILL_DIR || os.tmpdir();
+  fs.mkdirSync(dir, { recursive: true, mode: 0o700 });
+  const file = path.join(dir, `opencode-result-${randomBytes(8).toString('hex')}`);
+  fs.writeFileSync(file, result, { flag: 'wx', mode: 0o600 });
+  return { spillPath: file };
+}
+
+// Serves one session stream: the first message opens it, every following
+// message is executed in order, and the end of the stream closes it.
+function handleSession(impl: CommandPlugin, call: grpc.ServerDuplexStream<any, any>): void {
+  let session: PluginSession | undefined;
+  let chain = Promise.resolve();
+  call.on('data', (req: any) => {
+    chain = chain.then(async () => {
+      if (!session) {
+        session = impl.openSession(req.sessionId);
+        if (!session) {
+          call.emit('error', {
+            code: grpc.status.UNIMPLEMENTED,
+            details: 'plugin does not support sessions',
+          });
+          return;
+        }
+        call.write({});
+        return;
+      }
+      try {
+        call.write(spill(await session.execute(fromStruct(req.args))));
+      } catch (err) {
+        call.write({ error: errorToProto(err) });
+      }
+    });
+  });
+  call.on('end', () => {
+    chain.then(() => {
+      session?.close();
+      call.end();
+    });
+  });
+}
+
+const COMMAND_SERVICE = 'opencode.plugin.v1.CommandPlugin';
+
+function loadDefinition(): protoLoader.PackageDefinition {
+  return protoLoader.loadSync(
+    path.join(__dirname, '..', '..', 'proto', 'command.proto'),
+    { keepCase: false, defaults: true, oneofs: true },
+  );
+}
+
+function loadService(definition: protoLoader.PackageDefinition): grpc.ServiceDefinition {
+  const pkg = grpc.loadPackageDefinition(definition) as any;
+  return pkg.opencode.plugin.v1.CommandPlugin.service;
+}
+
+// Struct arrives from proto-loader in its wire shape; unwrap it into plain JSON.
+function fromValue(v: any): unknown {
+  if (v == null) return null;
+  switch (v.kind) {
+    case 'nullValue':
+      return null;
+    case 'numberValue':
+      return v.numberValue;
+    case 'stringValue':
+      return v.stringValue;
+    case 'boolValue':
+      return v.boolValue;
+    case 'structValue':
+      return fromStruct(v.structValue);
+    case 'listValue':
+      return (v.listValue.values || []).map(fromValue);
+    default:
+      return null;
+  }
+}
+
+function fromStruct(s: any): Args {
+  const out: Args = {};
+  for (const [k, v] of Object.entries(s?.fields || {})) {
+    out[k] = fromValue(v);
+  }
+  return out;
+}
+
+// The reverse of fromValue: wrap plain JSON into the Struct wire shape.
+function toValue(v: unknown): object {
+  if (v == null) return { nullValue: 'NULL_VALUE' };
+  if (typeof v === 'number') return { numberValue: v };
+  if (typeof v === 'string') return { stringValue: v };
+  if (typeof v === 'boolean') return { boolValue: v };
+  if (Array.isArray(v)) return { listValue: { values: v.map(toValue) } };
+  return { structValue: toStruct(v as Args) };
+}
+
+function toStruct(o: Args): object {
+  const fields: Record<string, object> = {};
+  for (const [k, v] of Object.entries(o)) {
+    fields[k] = toValue(v);
+  }
+  return { fields };
+}
+
+/** Serves impl to the host and keeps the process alive until killed. */
+export function serve(impl: CommandPlugin): void {
+  if (process.env[MAGIC_COOKIE_KEY] !== MAGIC_COOKIE_VALUE) {
+    process.stderr.write(
+      'This binary is a plugin. These are not meant to be executed directly. ' +
+        'Please execute the program that consumes these plugins, which will ' +
+        'load any plugins automatically\n',
+    );
+    process.exit(1);
+  }
+
+  const versions = process.env.PLUGIN_PROTOCOL_VERSIONS;
+  if (versions && !versions.split(',').includes(String(APP_PROTOCOL_VERSION))) {
+    process.stderr.write(
+      `plugin protocol version ${APP_PROTOCOL_VERSION} not accepted by host (${versions})\n`,
+    );
+    process.exit(1);
+  }
+
+  const server = new grpc.Server({
+    'grpc.max_receive_message_length': envBytes('OPENCODE_MAX_REQUEST_BYTES', DEFAULT_MAX_MESSAGE_BYTES),
+    'grpc.max_send_message_length': envBytes('OPENCODE_MAX_RESPONSE_BYTES', DEFAULT_MAX_MESSAGE_BYTES),
+  });
+
+  const definition = loadDefinition();
+
+  // "plugin" is what go-plugin checks; the others serve standard probes
+  const health = new HealthImplementation({
+    '': 'SERVING',
+    plugin: 'SERVING',
+    [COMMAND_SERVICE]: 'SERVING',
+  });
+  health.addToServer(server);
+
+  // Lets tools such as grpcurl inspect a running plugin
+  new ReflectionService(definition).addToServer(server);
+
+  server.addService(loadService(definition), {
+    name: (_call: any, cb: grpc.sendUnaryData<any>) => cb(null, { name: impl.name() }),
+    version: (_call: any, cb: grpc.sendUnaryData<any>) => cb(null, { version: impl.version() }),
+    execute: async (call: any, cb: grpc.sendUnaryData<any>) => {
+      let callID = '';
+      try {
+        const args = requestArgs(call.request);
+        callID = String(args[ARG_CALL_ID] ?? '');
+        if (callID) {
+          runningCalls.set(callID, new AbortController());
+          wrapUps.set(callID, new AbortController());
+        }
+        const result = await impl.execute(args);
+        if (result instanceof Partial) {
+          cb(null, { ...spill(result.result), partial: true });
+        } else {
+          cb(null, spill(result));
+        }
+      } catch (err) {
+        cb(null, { error: errorToProto(err) });
+      } finally {
+        runningCalls.delete(callID);
+        wrapUps.delete(callID);
+      }
+    },
+    executeBatch: async (call: any, cb: grpc.sendUnaryData<any>) => {
+      const responses: any[] = new Array(call.request.items.length);
+      const items: Args[] = [];
+      const index: number[] = [];
+      call.request.items.forEach((item: any, i: number) => {
+        try {
+          items.push(requestArgs(item));
+          index.push(i);
+        } catch (err) {
+          responses[i] = { error: errorToProto(err) };
+        }
+      });
+
+      const callIDs = items.map((args) => String(args[ARG_CALL_ID] ?? '')).filter((id) => id);
+      for (const id of callIDs) {
+        runningCalls.set(id, new AbortController());
+        wrapUps.set(id, new AbortController());
+      }
+      try {
+        let results: Array<string | Error>;
+        try {
+          results = await impl.executeBatch(items);
+        } catch (err) {
+          results = items.map(() => (err instanceof Error ? err : new Error(String(err))));
+        }
+        index.forEach((i, j) => {
+          const result =
+            j < results.length ? results[j] : new PluginError(`plugin returned no result for batch item ${i}`, 'internal');
+          try {
+            responses[i] = result instanceof Error ? { error: errorToProto(result) } : spill(result);
+          } catch (err) {
+            responses[i] = { error: errorToProto(err) };
+          }
+        });
+      } finally {
+        for (const id of callIDs) {
+          runningCalls.delete(id);
+          wrapUps.delete(id);
+        }
+      }
+      cb(null, { results: responses });
+    },
+    cancel: (call: any, cb: grpc.sendUnaryData<any>) => {
+      const controller = runningCalls.get(call.request.callId);
+      controller?.abort(new PluginError(`call canceled: ${call.request.reason}`, 'canceled'));
+      cb(null, { found: controller !== undefined });
+    },
+    setLogLevel: (call: any, cb: grpc.sendUnaryData<any>) => {
+      const level = ((call.request.level as string) || envLogLevel()).toLowerCase() as LogLevel;
+      if (!LOG_LEVELS.includes(level)) {
+        cb(null, { error: errorToProto(new PluginError(`unknown log level "${call.request.level}"`, 'invalid_argument')) });
+        return;
+      }
+      logLevel = level;
+      try {
+        impl.setLogLevel(level);
+        cb(null, {});
+      } catch (err) {
+        cb(null, { error: errorToProto(err) });
+      }
+    },
+    drain: async (call: any, cb: grpc.sendUnaryData<any>) => {
+      const timeoutMs = Number(call.request.timeoutMs);
+      const signal = timeoutMs > 0 ? AbortSignal.timeout(timeoutMs) : new AbortController().signal;
+      try {
+        await impl.drain(signal);
+        cb(null, {});
+      } catch (err) {
+        cb(null, { error: errorToProto(err) });
+      }
+    },
+    daemonStart: (call: any, cb: grpc.sendUnaryData<any>) => runDaemon(impl, call, cb, (signal) => impl.start(signal)),
+    daemonStop: (call: any, cb: grpc.sendUnaryData<any>) => runDaemon(impl, call, cb, (signal) => impl.stop(signal)),
+    daemonStatus: (call: any, cb: grpc.sendUnaryData<any>) => runDaemon(impl, call, cb),
+    expireBudget: (call: any, cb: grpc.sendUnaryData<any>) => {
+      const controller = wrapUps.get(call.request.callId);
+      controller?.abort();
+      cb(null, { found: controller !== undefined });
+    },
+    plan: async (call: any, cb: grpc.sendUnaryData<any>) => {
+      try {
+        cb(null, spill(await impl.plan(requestArgs(call.request))));
+      } catch (err) {
+        cb(null, { error: errorToProto(err) });
+      }
+    },
+    negotiateCodec: (call: any, cb: grpc.sendUnaryData<any>) =>
+      cb(null, { codec: (call.request.codecs as string[]).find((c) => CODECS.includes(c)) ?? '' }),
+    getCapabilities: (_call: any, cb: grpc.sendUnaryData<any>) => {
+      const details = impl.getCapabilities().map(capabilityToProto) as Array<{ name: string }>;
+      cb(null, { capabilities: details.map((d) => d.name), details });
+    },
+    cacheTTLs: (_call: any, cb: grpc.sendUnaryData<any>) =>
+      cb(null, { ttlSeconds: impl.cacheTTLs() }),
+    initialize: async (call: any, cb: grpc.sendUnaryData<any>) => {
+      try {
+        await impl.initialize(call.request.config ? fromStruct(call.request.config) : {});
+        cb(null, {});
+      } catch (err) {
+        cb(null, { error: errorToProto(err) });
+      }
+    },
+    handleEvent: async (call: any, cb: grpc.sendUnaryData<any>) => {
+      const req = call.request;
+      try {
+        await impl.handleEvent({
+          type: req.type,
+          plugin: req.plugin,
+          timeUnixNano: String(req.timeUnixNano),
+          data: req.data ? fromStruct(req.data) : {},
+        });
+        cb(null, {});
+      } catch (err) {
+        cb(null, { error: errorToProto(err) });
+      }
+    },
+    session: (call: grpc.ServerDuplexStream<any, any>) => handleSession(impl, call),
+  });
+
+  server.bindAsync('127.0.0.1:0', grpc.ServerCredentials.createInsecure(), (err, port) => {
+    if (err) {
+      process.stderr.write(`failed to bind gRPC server: ${err.message}\n`);
+      process.exit(1);
+    }
+    // Anything else written to stdout before this line breaks the handshake.
+    process.stdout.write(
+      `${CORE_PROTOCOL_VERSION}|${APP_PROTOCOL_VERSION}|tcp|127.0.0.1:${port}|grpc\n`,
+    );
+  });
+}
@@ -0,0 +1,55 @@
+#!/usr/bin/env python3
+"""Python port of the Go hello plugin, used to exercise the SDK end-to-end."""
+
+import sys
+
+from opencode_plugin import Capability, CommandPlugin, serve
+
+GREETINGS = {
+    "formal": "Greetings, {name}. Welcome to the SuperClaude integration platform.",
+    "casual": "Hey {name}! Ready to enhance OpenCode with AI?",
+    "technical": "Plugin 'hello-py' v{version} initialized. Target: {name}. Integration: operational.",
+}
+
+
+class HelloPlugin(CommandPlugin):
+    def name(self):
+        return "hello-py"
+
+    def version(self):
+        return "1.0.0"
+
+    def execute(self, args):
+        # stdout carries the handshake, so diagnostics go to stderr
+        print("[PLUGIN] Executing hello command", file=sys.stderr)
+        name = args.get("name") or "World"
+        template = GREETINGS.get(
+            args.get("type", "standard"), "Hello {name} from SuperClaude integration!"
+        )
+        return template.format(name=name, version=self.version())
+
+    def get_capabilities(self):
+        return [
+            Capability(
+                name="greet",
+                description="Greet someone in the requested style",
+                args_schema={
+                    "type": "object",
+                    "properties": {
+                        "name": {"type": "string", "description": "Who to greet"},
+                        "type": {"type": "string", "enum": ["standard", *GREETINGS]},
+                    },
+                },
+                example={"name": "Developer", "type": "casual"},
+                tags=["greeting"],
+                idempotent=True,
+            ),
+            "greet.formal",
+            "greet.casual",
+            "greet.technical",
+            "plugin.info",
+        ]
+
+
+if __name__ == "__main__":
+    serve(HelloPlugin())
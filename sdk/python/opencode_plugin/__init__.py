@@ -0,0 +1,38 @@
+"""Python SDK for writing OpenCode SuperClaude plugins.
+
+Subclass :class:`CommandPlugin` and hand an instance to :func:`serve`; the
+Go host launches the script as a subprocess and talks to it over gRPC using
+the contract in ``pkg/pluginsdk/proto/command.proto``.
+"""
+
+from .plugin import (
+    Capability,
+    CommandPlugin,
+    DaemonStatus,
+    Partial,
+    PluginError,
+    PluginSession,
+    Workspace,
+    is_cancelled,
+    project_context,
+    result_format,
+    should_wrap_up,
+    workspace,
+)
+from .server import serve
+
+__all__ = [
+    "Capability",
+    "CommandPlugin",
+    "DaemonStatus",
+    "Partial",
+    "PluginError",
+    "PluginSession",
+    "Workspace",
+    "is_cancelled",
+    "project_context",
+    "result_format",
+    "serve",
+    "should_wrap_up",
+    "workspace",
+]
@@ -0,0 +1,23 @@
+# -*- coding: utf-8 -*-
+# Generated by the protocol buffer compiler.  DO NOT EDIT!
+# NO CHECKED-IN PROTOBUF GENCODE
+# source: proto/command.proto
+"""Generated protocol buffer code."""
+from google.protobuf import descriptor as _descriptor
+from google.protobuf import descriptor_pool as _descriptor_pool
+from google.protobuf import symbol_database as _symbol_database
+from google.protobuf.internal import builder as _builder
+# @@protoc_insertion_point(imports)
+
+_sym_db = _symbol_database.Default()
+
+
+from google.protobuf import struct_pb2 as google_dot_protobuf_dot_struct__pb2
+
+
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x13proto/command.proto\x12\x12opencode.plugin.v1\x1a\x1cgoogle/protobuf/struct.proto"\x07\n\x05Empty""\n\x0cNameResponse\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name"+\n\x0fVersionResponse\x12\x18\n\x07version\x18\x01 \x01(\tR\x07version"\x86\x02\n\x0eExecuteRequest\x12+\n\x04args\x18\x01 \x01(\x0b2\x17.google.protobuf.StructR\x04args\x12!\n\x0cencoded_args\x18\x02 \x01(\x0cR\x0bencodedArgs\x12\x14\n\x05codec\x18\x03 \x01(\tR\x05codec\x12P\n\nargs_files\x18\x04 \x03(\x0b21.opencode.plugin.v1.ExecuteRequest.ArgsFilesEntryR\targsFiles\x1a<\n\x0eArgsFilesEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x028\x01"\x9f\x01\n\x0fExecuteResponse\x12\x16\n\x06result\x18\x01 \x01(\tR\x06result\x125\n\x05error\x18\x03 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error\x12\x18\n\x07partial\x18\x04 \x01(\x08R\x07partial\x12\x1d\n\nspill_path\x18\x05 \x01(\tR\tspillPathJ\x04\x08\x02\x10\x03"\xdd\x01\n\x0bPluginError\x12\x12\n\x04code\x18\x01 \x01(\tR\x04code\x12\x18\n\x07message\x18\x02 \x01(\tR\x07message\x12\x1c\n\tretryable\x18\x03 \x01(\x08R\tretryable\x12F\n\x07details\x18\x04 \x03(\x0b2,.opencode.plugin.v1.PluginError.DetailsEntryR\x07details\x1a:\n\x0cDetailsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\tR\x05value:\x028\x01"w\n\x17GetCapabilitiesResponse\x12"\n\x0ccapabilities\x18\x01 \x03(\tR\x0ccapabilities\x128\n\x07details\x18\x02 \x03(\x0b2\x1e.opencode.plugin.v1.CapabilityR\x07details"\xa0\x04\n\nCapability\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name\x12 \n\x0bdescription\x18\x02 \x01(\tR\x0bdescription\x128\n\x0bargs_schema\x18\x03 \x01(\x0b2\x17.google.protobuf.StructR\nargsSchema\x121\n\x07example\x18\x04 \x01(\x0b2\x17.google.protobuf.StructR\x07example\x12\x12\n\x04tags\x18\x05 \x03(\tR\x04tags\x12\x1e\n\nidempotent\x18\x06 \x01(\x08R\nidempotent\x12\x18\n\x07version\x18\x07 \x01(\tR\x07version\x12\x1e\n\ndeprecated\x18\x08 \x01(\tR\ndeprecated\x12\x18\n\x07removed\x18\t \x01(\x08R\x07removed\x12<\n\rresult_schema\x18\n \x01(\x0b2\x17.google.protobuf.StructR\x0cresultSchema\x12\x1e\n\ncompensate\x18\x0b \x01(\tR\ncompensate\x12\x12\n\x04cost\x18\x0c \x01(\tR\x04cost\x12\x18\n\x07latency\x18\r \x01(\tR\x07latency\x12\x18\n\x07formats\x18\x0e \x03(\tR\x07formats\x12\'\n\x0fconcurrency_key\x18\x0f \x01(\tR\x0econcurrencyKey\x12\x18\n\x07partial\x18\x10 \x01(\x08R\x07partial"\xaa\x01\n\x11CacheTTLsResponse\x12V\n\x0bttl_seconds\x18\x01 \x03(\x0b25.opencode.plugin.v1.CacheTTLsResponse.TtlSecondsEntryR\nttlSeconds\x1a=\n\x0fTtlSecondsEntry\x12\x10\n\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01"\\\n\x0eSessionRequest\x12\x1d\n\nsession_id\x18\x01 \x01(\tR\tsessionId\x12+\n\x04args\x18\x02 \x01(\x0b2\x17.google.protobuf.StructR\x04args"\x85\x01\n\x0fSessionResponse\x12\x16\n\x06result\x18\x01 \x01(\tR\x06result\x125\n\x05error\x18\x03 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error\x12\x1d\n\nspill_path\x18\x04 \x01(\tR\tspillPathJ\x04\x08\x02\x10\x03"-\n\x0eSetHostRequest\x12\x1b\n\tbroker_id\x18\x01 \x01(\rR\x08brokerId"X\n\x15RenderTemplateRequest\x12\x12\n\x04name\x18\x01 \x01(\tR\x04name\x12+\n\x04data\x18\x02 \x01(\x0b2\x17.google.protobuf.StructR\x04data"g\n\x16RenderTemplateResponse\x12\x16\n\x06result\x18\x01 \x01(\tR\x06result\x125\n\x05error\x18\x02 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"x\n\x11CallPluginRequest\x12\x16\n\x06plugin\x18\x01 \x01(\tR\x06plugin\x12\x1e\n\ncapability\x18\x02 \x01(\tR\ncapability\x12+\n\x04args\x18\x03 \x01(\x0b2\x17.google.protobuf.StructR\x04args"2\n\x11GetContextRequest\x12\x1d\n\nrequest_id\x18\x01 \x01(\tR\trequestId"~\n\x12GetContextResponse\x121\n\x07context\x18\x01 \x01(\x0b2\x17.google.protobuf.StructR\x07context\x125\n\x05error\x18\x02 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"r\n\x11SetContextRequest\x12\x1d\n\nrequest_id\x18\x01 \x01(\tR\trequestId\x12\x10\n\x03key\x18\x02 \x01(\tR\x03key\x12,\n\x05value\x18\x03 \x01(\x0b2\x16.google.protobuf.ValueR\x05value"K\n\x12SetContextResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"a\n\x1bRegisterCapabilitiesRequest\x12B\n\x0ccapabilities\x18\x01 \x03(\x0b2\x1e.opencode.plugin.v1.CapabilityR\x0ccapabilities"U\n\x1cRegisterCapabilitiesResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"3\n\x1dUnregisterCapabilitiesRequest\x12\x12\n\x04refs\x18\x01 \x03(\tR\x04refs"W\n\x1eUnregisterCapabilitiesResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"j\n\x15ReportProgressRequest\x12\x1d\n\nrequest_id\x18\x01 \x01(\tR\trequestId\x12\x18\n\x07percent\x18\x02 \x01(\x01R\x07percent\x12\x18\n\x07message\x18\x03 \x01(\tR\x07message"O\n\x16ReportProgressResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"%\n\x0fReadFileRequest\x12\x12\n\x04path\x18\x01 \x01(\tR\x04path"]\n\x10ReadFileResponse\x12\x12\n\x04data\x18\x01 \x01(\x0cR\x04data\x125\n\x05error\x18\x02 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error":\n\x10WriteFileRequest\x12\x12\n\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n\x04data\x18\x02 \x01(\x0cR\x04data"J\n\x11WriteFileResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error",\n\x10GlobFilesRequest\x12\x18\n\x07pattern\x18\x01 \x01(\tR\x07pattern"`\n\x11GlobFilesResponse\x12\x14\n\x05paths\x18\x01 \x03(\tR\x05paths\x125\n\x05error\x18\x02 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"-\n\x11WatchFilesRequest\x12\x18\n\x07pattern\x18\x01 \x01(\tR\x07pattern"[\n\x12WatchFilesResponse\x12\x0e\n\x02id\x18\x01 \x01(\tR\x02id\x125\n\x05error\x18\x02 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"%\n\x13UnwatchFilesRequest\x12\x0e\n\x02id\x18\x01 \x01(\tR\x02id"M\n\x14UnwatchFilesResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"V\n\x13PublishEventRequest\x12\x12\n\x04type\x18\x01 \x01(\tR\x04type\x12+\n\x04data\x18\x02 \x01(\x0b2\x17.google.protobuf.StructR\x04data"M\n\x14PublishEventResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error":\n\nLLMMessage\x12\x12\n\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n\x07content\x18\x02 \x01(\tR\x07content"\x86\x02\n\x0fCompleteRequest\x12\x1a\n\x08provider\x18\x01 \x01(\tR\x08provider\x12\x14\n\x05model\x18\x02 \x01(\tR\x05model\x12\x16\n\x06system\x18\x03 \x01(\tR\x06system\x12:\n\x08messages\x18\x04 \x03(\x0b2\x1e.opencode.plugin.v1.LLMMessageR\x08messages\x12\x1d\n\nmax_tokens\x18\x05 \x01(\x05R\tmaxTokens\x12%\n\x0btemperature\x18\x06 \x01(\x01H\x00R\x0btemperature\x88\x01\x01\x12\x17\n\x07call_id\x18\x07 \x01(\tR\x06callIdB\x0e\n\x0c_temperature"\xfe\x01\n\x10CompleteResponse\x12\x1a\n\x08provider\x18\x01 \x01(\tR\x08provider\x12\x14\n\x05model\x18\x02 \x01(\tR\x05model\x12\x18\n\x07content\x18\x03 \x01(\tR\x07content\x12\x1f\n\x0bstop_reason\x18\x04 \x01(\tR\nstopReason\x12!\n\x0cinput_tokens\x18\x05 \x01(\x03R\x0binputTokens\x12#\n\routput_tokens\x18\x06 \x01(\x03R\x0coutputTokens\x125\n\x05error\x18\x07 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"\x86\x01\n\x05Event\x12\x12\n\x04type\x18\x01 \x01(\tR\x04type\x12\x16\n\x06plugin\x18\x02 \x01(\tR\x06plugin\x12$\n\x0etime_unix_nano\x18\x03 \x01(\x03R\x0ctimeUnixNano\x12+\n\x04data\x18\x04 \x01(\x0b2\x17.google.protobuf.StructR\x04data"L\n\x13HandleEventResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"/\n\x15NegotiateCodecRequest\x12\x16\n\x06codecs\x18\x01 \x03(\tR\x06codecs".\n\x16NegotiateCodecResponse\x12\x14\n\x05codec\x18\x01 \x01(\tR\x05codec"@\n\rCancelRequest\x12\x17\n\x07call_id\x18\x01 \x01(\tR\x06callId\x12\x16\n\x06reason\x18\x02 \x01(\tR\x06reason"&\n\x0eCancelResponse\x12\x14\n\x05found\x18\x01 \x01(\x08R\x05found".\n\x13ExpireBudgetRequest\x12\x17\n\x07call_id\x18\x01 \x01(\tR\x06callId",\n\x14ExpireBudgetResponse\x12\x14\n\x05found\x18\x01 \x01(\x08R\x05found"+\n\x17NegotiateHandoffRequest\x12\x10\n\x03dir\x18\x01 \x01(\tR\x03dir"6\n\x18NegotiateHandoffResponse\x12\x1a\n\x08accepted\x18\x01 \x01(\x08R\x08accepted"*\n\x12SetLogLevelRequest\x12\x14\n\x05level\x18\x01 \x01(\tR\x05level"L\n\x13SetLogLevelResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"-\n\x0cDrainRequest\x12\x1d\n\ntimeout_ms\x18\x01 \x01(\x03R\ttimeoutMs"F\n\rDrainResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error".\n\rDaemonRequest\x12\x1d\n\ntimeout_ms\x18\x01 \x01(\x03R\ttimeoutMs"\x93\x01\n\x0eDaemonResponse\x12\x14\n\x05state\x18\x01 \x01(\tR\x05state\x12\x18\n\x07message\x18\x02 \x01(\tR\x07message\x12\x1a\n\x08endpoint\x18\x03 \x01(\tR\x08endpoint\x125\n\x05error\x18\x04 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error"O\n\x13ExecuteBatchRequest\x128\n\x05items\x18\x01 \x03(\x0b2".opencode.plugin.v1.ExecuteRequestR\x05items"U\n\x14ExecuteBatchResponse\x12=\n\x07results\x18\x01 \x03(\x0b2#.opencode.plugin.v1.ExecuteResponseR\x07results"D\n\x11InitializeRequest\x12/\n\x06config\x18\x01 \x01(\x0b2\x17.google.protobuf.StructR\x06config"K\n\x12InitializeResponse\x125\n\x05error\x18\x01 \x01(\x0b2\x1f.opencode.plugin.v1.PluginErrorR\x05error2\xdf\r\n\rCommandPlugin\x12C\n\x04Name\x12\x19.opencode.plugin.v1.Empty\x1a .opencode.plugin.v1.NameResponse\x12I\n\x07Version\x12\x19.opencode.plugin.v1.Empty\x1a#.opencode.plugin.v1.VersionResponse\x12R\n\x07Execute\x12".opencode.plugin.v1.ExecuteRequest\x1a#.opencode.plugin.v1.ExecuteResponse\x12Y\n\x0fGetCapabilities\x12\x19.opencode.plugin.v1.Empty\x1a+.opencode.plugin.v1.GetCapabilitiesResponse\x12M\n\tCacheTTLs\x12\x19.opencode.plugin.v1.Empty\x1a%.opencode.plugin.v1.CacheTTLsResponse\x12V\n\x07Session\x12".opencode.plugin.v1.SessionRequest\x1a#.opencode.plugin.v1.SessionResponse(\x010\x01\x12H\n\x07SetHost\x12".opencode.plugin.v1.SetHostRequest\x1a\x19.opencode.plugin.v1.Empty\x12Q\n\x0bHandleEvent\x12\x19.opencode.plugin.v1.Event\x1a\'.opencode.plugin.v1.HandleEventResponse\x12[\n\nInitialize\x12%.opencode.plugin.v1.InitializeRequest\x1a&.opencode.plugin.v1.InitializeResponse\x12O\n\x04Plan\x12".opencode.plugin.v1.ExecuteRequest\x1a#.opencode.plugin.v1.ExecuteResponse\x12g\n\x0eNegotiateCodec\x12).opencode.plugin.v1.NegotiateCodecRequest\x1a*.opencode.plugin.v1.NegotiateCodecResponse\x12O\n\x06Cancel\x12!.opencode.plugin.v1.CancelRequest\x1a".opencode.plugin.v1.CancelResponse\x12a\n\x0cExpireBudget\x12\'.opencode.plugin.v1.ExpireBudgetRequest\x1a(.opencode.plugin.v1.ExpireBudgetResponse\x12a\n\x0cExecuteBatch\x12\'.opencode.plugin.v1.ExecuteBatchRequest\x1a(.opencode.plugin.v1.ExecuteBatchResponse\x12m\n\x10NegotiateHandoff\x12+.opencode.plugin.v1.NegotiateHandoffRequest\x1a,.opencode.plugin.v1.NegotiateHandoffResponse\x12^\n\x0bSetLogLevel\x12&.opencode.plugin.v1.SetLogLevelRequest\x1a\'.opencode.plugin.v1.SetLogLevelResponse\x12L\n\x05Drain\x12 .opencode.plugin.v1.DrainRequest\x1a!.opencode.plugin.v1.DrainResponse\x12T\n\x0bDaemonStart\x12!.opencode.plugin.v1.DaemonRequest\x1a".opencode.plugin.v1.DaemonResponse\x12S\n\nDaemonStop\x12!.opencode.plugin.v1.DaemonRequest\x1a".opencode.plugin.v1.DaemonResponse\x12U\n\x0cDaemonStatus\x12!.opencode.plugin.v1.DaemonRequest\x1a".opencode.plugin.v1.DaemonResponse2\xf5\n\n\x0cHostServices\x12g\n\x0eRenderTemplate\x12).opencode.plugin.v1.RenderTemplateRequest\x1a*.opencode.plugin.v1.RenderTemplateResponse\x12X\n\nCallPlugin\x12%.opencode.plugin.v1.CallPluginRequest\x1a#.opencode.plugin.v1.ExecuteResponse\x12[\n\nGetContext\x12%.opencode.plugin.v1.GetContextRequest\x1a&.opencode.plugin.v1.GetContextResponse\x12[\n\nSetContext\x12%.opencode.plugin.v1.SetContextRequest\x1a&.opencode.plugin.v1.SetContextResponse\x12y\n\x14RegisterCapabilities\x12/.opencode.plugin.v1.RegisterCapabilitiesRequest\x1a0.opencode.plugin.v1.RegisterCapabilitiesResponse\x12\x7f\n\x16UnregisterCapabilities\x121.opencode.plugin.v1.UnregisterCapabilitiesRequest\x1a2.opencode.plugin.v1.UnregisterCapabilitiesResponse\x12g\n\x0eReportProgress\x12).opencode.plugin.v1.ReportProgressRequest\x1a*.opencode.plugin.v1.ReportProgressResponse\x12U\n\x08ReadFile\x12#.opencode.plugin.v1.ReadFileRequest\x1a$.opencode.plugin.v1.ReadFileResponse\x12X\n\tWriteFile\x12$.opencode.plugin.v1.WriteFileRequest\x1a%.opencode.plugin.v1.WriteFileResponse\x12X\n\tGlobFiles\x12$.opencode.plugin.v1.GlobFilesRequest\x1a%.opencode.plugin.v1.GlobFilesResponse\x12[\n\nWatchFiles\x12%.opencode.plugin.v1.WatchFilesRequest\x1a&.opencode.plugin.v1.WatchFilesResponse\x12a\n\x0cUnwatchFiles\x12\'.opencode.plugin.v1.UnwatchFilesRequest\x1a(.opencode.plugin.v1.UnwatchFilesResponse\x12a\n\x0cPublishEvent\x12\'.opencode.plugin.v1.PublishEventRequest\x1a(.opencode.plugin.v1.PublishEventResponse\x12U\n\x08Complete\x12#.opencode.plugin.v1.CompleteRequest\x1a$.opencode.plugin.v1.CompleteResponseB0Z.github.com/Kirchlive/super/pkg/pluginsdk/protob\x06proto3')
+
+_globals = globals()
+_builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
+_builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'opencode_plugin.command_pb2', _globals)
+# @@protoc_insertion_point(module_scope)
@@ -0,0 +1,79 @@
+"""Logging in the JSON format the host keeps as the plugin's log.
+
+serve routes the standard logging module to stderr as hclog JSON lines, so
+the host records each entry with its level and module. The level starts at
+OPENCODE_LOG_LEVEL, or info, and the host switches it with SetLogLevel while
+the plugin runs.
+"""
+
+import json
+import logging
+import os
+import sys
+from datetime import datetime, timezone
+
+from .plugin import PluginError
+
+# Must match pluginsdk.EnvLogLevel in the Go host.
+ENV_LOG_LEVEL = "OPENCODE_LOG_LEVEL"
+
+# logging has no trace level; hclog's sits below debug.
+TRACE = 5
+logging.addLevelName(TRACE, "TRACE")
+
+_LEVELS = {
+    "trace": TRACE,
+    "debug": logging.DEBUG,
+    "info": logging.INFO,
+    "warn": logging.WARNING,
+    "error": logging.ERROR,
+}
+
+
+def _level_name(levelno: int) -> str:
+    """Returns the hclog name of the highest level at or below levelno."""
+    name = "trace"
+    for candidate, level in _LEVELS.items():
+        if levelno >= level:
+            name = candidate
+    return name
+
+
+class _HclogFormatter(logging.Formatter):
+    def format(self, record: logging.LogRecord) -> str:
+        entry = {
+            "@level": _level_name(record.levelno),
+            "@message": record.getMessage(),
+            "@module": record.name,
+            "@timestamp": datetime.fromtimestamp(record.created, timezone.utc).isoformat(),
+        }
+        if record.exc_info:
+            entry["error"] = self.formatException(record.exc_info)
+        return json.dumps(entry, default=str)
+
+
+def _initial_level() -> int:
+    return _LEVELS.get(os.environ.get(ENV_LOG_LEVEL, "").lower(), logging.INFO)
+
+
+def setup_logging() -> None:
+    """Routes logging to stderr unless the plugin configured it already."""
+    root = logging.getLogger()
+    if not root.handlers:
+        handler = logging.StreamHandler(sys.stderr)
+        handler.setFormatter(_HclogFormatter())
+        root.addHandler(handler)
+    root.setLevel(_initial_level())
+
+
+def set_level(name: str) -> int:
+    """Sets the root logger to the hclog level name, or back to the level it
+    started with when name is empty, and returns the logging level."""
+    if not name:
+        level = _initial_level()
+    elif name.lower() in _LEVELS:
+        level = _LEVELS[name.lower()]
+    else:
+        raise PluginError("unknown log level %r" % name, code="invalid_argument")
+    logging.getLogger().setLevel(level)
+    return level
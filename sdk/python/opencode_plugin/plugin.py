@@ -0,0 +1,296 @@
+"""Base class for plugins, mirroring pluginsdk.CommandPlugin on the Go side."""
+
+import os
+import threading
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional, Union
+
+
+class PluginError(Exception):
+    """Raised by execute() to report a classified failure to the host.
+
+    The host rebuilds it as a pluginsdk.PluginError, so ``code`` should be one
+    of the pluginsdk.ErrorCode values ("invalid_argument", "not_found",
+    "permission_denied", "unsupported", "unavailable", "timeout", "canceled",
+    "internal").
+    Any other exception reaches the host with code "unknown".
+    """
+
+    def __init__(
+        self,
+        message: str,
+        code: str = "unknown",
+        retryable: bool = False,
+        details: Optional[Dict[str, str]] = None,
+    ):
+        super().__init__(message)
+        self.message = message
+        self.code = code
+        self.retryable = retryable
+        self.details = details or {}
+
+
+# Must match pluginsdk.ArgCallID.
+ARG_CALL_ID = "call_id"
+
+# Events of the calls this process is serving by call ID, set when the host
+# cancels the call.
+_running_calls: Dict[str, threading.Event] = {}
+_running_calls_lock = threading.Lock()
+
+# Events of the same calls, set when their latency budget runs out.
+_wrap_ups: Dict[str, threading.Event] = {}
+
+
+def is_cancelled(args: Dict[str, Any]) -> bool:
+    """Reports whether the host canceled the call args were passed to,
+    mirroring pluginsdk.CallContext. Long-running execute() implementations
+    should check it and stop, e.g. by raising
+    PluginError("...", code="canceled"); the host kills the process when a
+    canceled call does not return within its grace period."""
+    with _running_calls_lock:
+        event = _running_calls.get(args.get(ARG_CALL_ID, ""))
+    return event is not None and event.is_set()
+
+
+def should_wrap_up(args: Dict[str, Any]) -> bool:
+    """Reports whether the latency budget of the call args were passed to
+    ran out, mirroring pluginsdk.WrapUp. execute() implementations of
+    capabilities declaring partial=True should check it as they work and
+    then return what they have so far as Partial(result)."""
+    with _running_calls_lock:
+        event = _wrap_ups.get(args.get(ARG_CALL_ID, ""))
+    return event is not None and event.is_set()
+
+
+class Partial(str):
+    """A result returned when the call's latency budget ran out, mirroring
+    returning pluginsdk.ErrPartial; the host flags it as partial."""
+
+
+def result_format(args: Dict[str, Any], formats: List[str]) -> str:
+    """Returns the format a capability answering in formats, its default
+    first, must answer the call args were passed to in, mirroring
+    pluginsdk.ResultFormat: the first format the call's "accept" option
+    lists that it offers, else its default, else "text"."""
+    if not formats:
+        return "text"
+    accept = (args.get("options") or {}).get("accept") or []
+    if isinstance(accept, str):
+        accept = accept.split(",")
+    for wanted in accept:
+        wanted = str(wanted).strip().lower()
+        if wanted in formats:
+            return wanted
+    return formats[0]
+
+
+# Must match pluginsdk.ArgProject.
+ARG_PROJECT = "project_context"
+
+
+def project_context(args: Dict[str, Any]) -> Optional[Dict[str, Any]]:
+    """Returns the project context the host computed for the call args were
+    passed to, mirroring pluginsdk.Project: a dict with "root", "git",
+    "branch", "commit", "dirty", "dirty_count" and "languages". It is None
+    unless the manifest lists "project" under "inject" and the call names a
+    working directory or project root."""
+    return args.get(ARG_PROJECT)
+
+
+@dataclass
+class Workspace:
+    """The directories the host set aside for this plugin process, mirroring
+    pluginsdk.Workspace. The process starts in ``data_dir`` with TMPDIR set to
+    ``temp_dir``; fields are empty when the host has workspaces disabled."""
+
+    data_dir: str = ""
+    cache_dir: str = ""
+    temp_dir: str = ""
+
+
+def workspace() -> Workspace:
+    """Returns this plugin's directories, mirroring pluginsdk.PluginWorkspace."""
+    return Workspace(
+        data_dir=os.environ.get("OPENCODE_DATA_DIR", ""),
+        cache_dir=os.environ.get("OPENCODE_CACHE_DIR", ""),
+        temp_dir=os.environ.get("OPENCODE_TEMP_DIR", ""),
+    )
+
+
+@dataclass
+class Capability:
+    """Describes one operation a plugin offers, mirroring pluginsdk.Capability."""
+
+    name: str
+    description: str = ""
+    # JSON Schema object describing the accepted arguments.
+    args_schema: Optional[Dict[str, Any]] = None
+    # Sample arguments for invoking the capability.
+    example: Optional[Dict[str, Any]] = None
+    tags: List[str] = field(default_factory=list)
+    # Repeating a call has no further effect, so the host may retry it.
+    idempotent: bool = False
+    # Revision of the capability, e.g. "v2"; callers pick it as "greet@v2".
+    version: str = ""
+    # Non-empty marks the capability deprecated and says what to use instead.
+    deprecated: str = ""
+    # The capability no longer works; the host may reject calls to it.
+    removed: bool = False
+    # JSON Schema the result satisfies; results are parsed as JSON unless the
+    # schema's type is "string".
+    result_schema: Optional[Dict[str, Any]] = None
+    # Capability of the same plugin that undoes a successful call; pipelines
+    # run it when a later step fails.
+    compensate: str = ""
+    # Expected cost class of a call: "cheap", "medium" or "expensive"; the
+    # host prefers the cheapest plugin offering the capability.
+    cost: str = ""
+    # How long a call typically takes, e.g. "200ms".
+    latency: str = ""
+    # Result formats the capability can answer in, e.g. "json" or
+    # "markdown", the default first; see result_format.
+    formats: List[str] = field(default_factory=list)
+    # What calls of the capability modify, e.g. "repo-write"; the host runs
+    # calls sharing a key one at a time.
+    concurrency_key: str = ""
+    # Calls may be given a latency budget, after which execute() returns
+    # what it has as Partial(result); see should_wrap_up.
+    partial: bool = False
+
+
+@dataclass
+class DaemonStatus:
+    """The state of a daemon plugin's server, mirroring pluginsdk.DaemonStatus."""
+
+    # "starting", "running", "stopped" or "failed".
+    state: str
+    # Why the server is not running, e.g. the error it failed with.
+    message: str = ""
+    # Where the server serves; endpoint() fills it in when left empty.
+    endpoint: str = ""
+
+
+class PluginSession:
+    """One conversation with a plugin that keeps state across calls."""
+
+    def execute(self, args: Dict[str, Any]) -> str:
+        """Runs a call within the session."""
+        raise NotImplementedError
+
+    def close(self) -> None:
+        """Ends the session and releases its state."""
+
+
+class CommandPlugin:
+    """Base class all Python plugins derive from."""
+
+    def name(self) -> str:
+        """Returns the plugin's unique identifier."""
+        raise NotImplementedError
+
+    def version(self) -> str:
+        """Returns the plugin's version."""
+        return "0.0.0"
+
+    def execute(self, args: Dict[str, Any]) -> str:
+        """Runs the plugin's main functionality."""
+        raise NotImplementedError
+
+    def execute_batch(self, items: List[Dict[str, Any]]) -> List[Union[str, Exception]]:
+        """Runs many calls at once, mirroring pluginsdk.BatchPlugin.
+
+        Returns one result per item, in order; an exception in place of a
+        result fails that item. Override it to share setup such as loading a
+        model across the items; the default runs execute for each.
+        """
+        results: List[Union[str, Exception]] = []
+        for args in items:
+            try:
+                results.append(self.execute(args))
+            except Exception as exc:  # fails only this item
+                results.append(exc)
+        return results
+
+    def get_capabilities(self) -> List[Union[Capability, str]]:
+        """Describes the capabilities this plugin provides.
+
+        Bare names are accepted for plugins that have no metadata to offer.
+        """
+        return []
+
+    def cache_ttls(self) -> Dict[str, int]:
+        """Maps capability names to how many seconds the host may cache results.
+
+        Capabilities not listed are never cached.
+        """
+        return {}
+
+    def plan(self, args: Dict[str, Any]) -> str:
+        """Describes what execute would do with args, without doing it.
+
+        Called for dry runs (--plan); args carry "dry_run": True. The default
+        refuses, so plugins that cannot plan are never executed instead.
+        """
+        raise PluginError("plugin does not support dry runs", code="unsupported")
+
+    def initialize(self, config: Dict[str, Any]) -> None:
+        """Receives the plugin's entry from the host's plugin_config.
+
+        Called once per process before the first call. Secret references are
+        already resolved, so never log the values. Raise to report a failure.
+        """
+
+    def set_log_level(self, level: int) -> None:
+        """Follows the logging level the host switched the plugin to.
+
+        The root logger is already set; override this when the plugin logs
+        through handlers or loggers with levels of their own.
+        """
+
+    def drain(self, timeout: float) -> None:
+        """Gets the plugin ready to be stopped, mirroring pluginsdk.DrainablePlugin.
+
+        Called before the host stops the process. Stop taking on new internal
+        work, checkpoint state, e.g. to workspace().data_dir, and return once
+        it is safe to terminate, within timeout seconds. Raise if the plugin
+        could not get to a safe state.
+        """
+
+    def start(self, timeout: float) -> None:
+        """Starts the server of a daemon plugin, mirroring pluginsdk.DaemonPlugin.
+
+        Daemons are long-lived servers, such as an LSP bridge, rather than
+        answering calls. The host calls start once the process is up and again
+        to restart a failed server; return once it runs, within timeout
+        seconds, or raise.
+        """
+        raise PluginError("plugin is not a daemon", code="unsupported")
+
+    def stop(self, timeout: float) -> None:
+        """Stops the server of a daemon plugin, within timeout seconds."""
+        raise PluginError("plugin is not a daemon", code="unsupported")
+
+    def status(self) -> DaemonStatus:
+        """Reports the state of the server of a daemon plugin.
+
+        The host asks periodically and restarts servers that failed by its
+        restart policy. Plugins that are no daemon keep this default.
+        """
+        raise PluginError("plugin is not a daemon", code="unsupported")
+
+    def endpoint(self) -> str:
+        """Returns where the server of a daemon plugin serves, e.g.
+        "tcp://127.0.0.1:7000" or a socket path, for clients to find it."""
+        return ""
+
+    def open_session(self, session_id: str) -> Optional[PluginSession]:
+        """Starts a session; return None if the plugin does not support them."""
+        return None
+
+    def handle_event(self, event: Dict[str, Any]) -> None:
+        """Reacts to an event listed under "events" in the plugin's manifest.
+
+        event has "type", "plugin", "time_unix_nano" and "data" keys. Raise
+        to report a failure to the host.
+        """
@@ -0,0 +1,372 @@
+"""go-plugin handshake and gRPC server for Python plugins.
+
+The host starts the plugin with a magic cookie in the environment and waits
+for a single handshake line on stdout:
+
+    CORE-PROTOCOL-VERSION | APP-PROTOCOL-VERSION | NETWORK | ADDR | PROTOCOL
+
+After that all communication happens over gRPC. go-plugin also requires a
+health service reporting "plugin" as SERVING, which the host polls for
+liveness. Server reflection is enabled too, so tools such as grpcurl can
+inspect a running plugin.
+"""
+
+import json
+import os
+import sys
+import tempfile
+import threading
+from concurrent import futures
+
+import grpc
+from google.protobuf import json_format, struct_pb2
+from grpc_health.v1 import health, health_pb2, health_pb2_grpc
+from grpc_reflection.v1alpha import reflection
+
+from . import command_pb2, command_pb2_grpc
+from .logs import set_level, setup_logging
+from .plugin import (
+    ARG_CALL_ID,
+    Capability,
+    CommandPlugin,
+    Partial,
+    PluginError,
+    _running_calls,
+    _running_calls_lock,
+    _wrap_ups,
+)
+
+# Must match pluginsdk.Handshake in the Go host.
+MAGIC_COOKIE_KEY = "OPENCODE_PLUGIN"
+MAGIC_COOKIE_VALUE = "superclaude"
+CORE_PROTOCOL_VERSION = 1
+APP_PROTOCOL_VERSION = 1
+
+# Must match pluginsdk.DefaultMaxMessageBytes.
+DEFAULT_MAX_MESSAGE_BYTES = 4 << 20
+
+
+# Argument codecs this SDK decodes, most preferred first; see pluginsdk.Codec.
+CODECS = ("json", "structpb")
+
+
+def _request_args(request) -> dict:
+    """Decodes the arguments of an Execute or Plan request."""
+    if not request.codec:
+        return json_format.MessageToDict(request.args)
+    if request.codec == "json":
+        return json.loads(request.encoded_args)
+    if request.codec == "structpb":
+        return json_format.MessageToDict(struct_pb2.Struct.FromString(request.encoded_args))
+    raise PluginError("unknown argument codec %r" % request.codec, code="invalid_argument")
+
+
+def _env_bytes(key: str, default: int) -> int:
+    try:
+        n = int(os.environ.get(key, ""))
+    except ValueError:
+        return default
+    return n if n > 0 else default
+
+
+def _spill(result: str) -> dict:
+    """Writes a result above the host's spill threshold to a file in the
+    host's spill directory, so it does not have to cross the transport.
+    Returns the response fields carrying the result or the file's path."""
+    max_response = _env_bytes("OPENCODE_MAX_RESPONSE_BYTES", DEFAULT_MAX_MESSAGE_BYTES)
+    data = result.encode()
+    if len(data) <= _env_bytes("OPENCODE_SPILL_BYTES", max_response):
+        return {"result": result}
+    spill_dir = os.environ.get("OPENCODE_SPILL_DIR") or None
+    if spill_dir:
+        os.makedirs(spill_dir, mode=0o700, exist_ok=True)
+    fd, path = tempfile.mkstemp(prefix="opencode-result-", dir=spill_dir)
+    with os.fdopen(fd, "wb") as f:
+        f.write(data)
+    return {"spill_path": path}
+
+
+def _error_to_proto(exc: Exception) -> command_pb2.PluginError:
+    if isinstance(exc, PluginError):
+        return command_pb2.PluginError(
+            code=exc.code,
+            message=exc.message,
+            retryable=exc.retryable,
+            details=exc.details,
+        )
+    return command_pb2.PluginError(code="unknown", message=str(exc))
+
+
+def _capability_to_proto(cap) -> command_pb2.Capability:
+    if isinstance(cap, str):
+        cap = Capability(name=cap)
+    msg = command_pb2.Capability(
+        name=cap.name,
+        description=cap.description,
+        tags=cap.tags,
+        idempotent=cap.idempotent,
+        version=cap.version,
+        deprecated=cap.deprecated,
+        removed=cap.removed,
+        compensate=cap.compensate,
+        cost=cap.cost,
+        latency=cap.latency,
+        formats=cap.formats,
+        concurrency_key=cap.concurrency_key,
+        partial=cap.partial,
+    )
+    if cap.args_schema is not None:
+        msg.args_schema.update(cap.args_schema)
+    if cap.example is not None:
+        msg.example.update(cap.example)
+    if cap.result_schema is not None:
+        msg.result_schema.update(cap.result_schema)
+    return msg
+
+
+class _CommandPluginServicer(command_pb2_grpc.CommandPluginServicer):
+    def __init__(self, impl: CommandPlugin):
+        self._impl = impl
+
+    def Name(self, request, context):
+        return command_pb2.NameResponse(name=self._impl.name())
+
+    def Version(self, request, context):
+        return command_pb2.VersionResponse(version=self._impl.version())
+
+    def Execute(self, request, context):
+        call_id = ""
+        try:
+            args = _request_args(request)
+            call_id = args.get(ARG_CALL_ID, "")
+            if call_id:
+                with _running_calls_lock:
+                    _running_calls[call_id] = threading.Event()
+                    _wrap_ups[call_id] = threading.Event()
+            result = self._impl.execute(args)
+            partial = isinstance(result, Partial)
+            fields = _spill(result)
+        except Exception as exc:  # reported to the host as a plugin error
+            return command_pb2.ExecuteResponse(error=_error_to_proto(exc))
+        finally:
+            if call_id:
+                with _running_calls_lock:
+                    _running_calls.pop(call_id, None)
+                    _wrap_ups.pop(call_id, None)
+        return command_pb2.ExecuteResponse(partial=partial, **fields)
+
+    def ExecuteBatch(self, request, context):
+        responses = [None] * len(request.items)
+        items, index = [], []
+        for i, item in enumerate(request.items):
+            try:
+                items.append(_request_args(item))
+                index.append(i)
+            except Exception as exc:
+                responses[i] = command_pb2.ExecuteResponse(error=_error_to_proto(exc))
+
+        call_ids = [args.get(ARG_CALL_ID, "") for args in items]
+        with _running_calls_lock:
+            for call_id in filter(None, call_ids):
+                _running_calls[call_id] = threading.Event()
+                _wrap_ups[call_id] = threading.Event()
+        try:
+            try:
+                results = list(self._impl.execute_batch(items))
+            except Exception as exc:  # fails every item
+                results = [exc] * len(items)
+            for j, i in enumerate(index):
+                result = results[j] if j < len(results) else PluginError(
+                    "plugin returned no result for batch item %d" % i, code="internal"
+                )
+                if isinstance(result, Exception):
+                    responses[i] = command_pb2.ExecuteResponse(error=_error_to_proto(result))
+                    continue
+                try:
+                    responses[i] = command_pb2.ExecuteResponse(**_spill(result))
+                except Exception as exc:
+                    responses[i] = command_pb2.ExecuteResponse(error=_error_to_proto(exc))
+        finally:
+            with _running_calls_lock:
+                for call_id in filter(None, call_ids):
+                    _running_calls.pop(call_id, None)
+                    _wrap_ups.pop(call_id, None)
+        return command_pb2.ExecuteBatchResponse(results=responses)
+
+    def Cancel(self, request, context):
+        with _running_calls_lock:
+            event = _running_calls.get(request.call_id)
+        if event is not None:
+            event.set()
+        return command_pb2.CancelResponse(found=event is not None)
+
+    def ExpireBudget(self, request, context):
+        with _running_calls_lock:
+            event = _wrap_ups.get(request.call_id)
+        if event is not None:
+            event.set()
+        return command_pb2.ExpireBudgetResponse(found=event is not None)
+
+    def SetLogLevel(self, request, context):
+        try:
+            self._impl.set_log_level(set_level(request.level))
+        except Exception as exc:
+            return command_pb2.SetLogLevelResponse(error=_error_to_proto(exc))
+        return command_pb2.SetLogLevelResponse()
+
+    def Drain(self, request, context):
+        try:
+            self._impl.drain(request.timeout_ms / 1000.0)
+        except Exception as exc:
+            return command_pb2.DrainResponse(error=_error_to_proto(exc))
+        return command_pb2.DrainResponse()
+
+    def DaemonStart(self, request, context):
+        return self._daemon(self._impl.start, request)
+
+    def DaemonStop(self, request, context):
+        return self._daemon(self._impl.stop, request)
+
+    def DaemonStatus(self, request, context):
+        return self._daemon(None, request)
+
+    def _daemon(self, op, request):
+        """Runs op on the daemon's server, if any, and answers with its
+        status afterwards, mirroring runDaemon in pluginsdk."""
+        error = None
+        if op is not None:
+            try:
+                op(request.timeout_ms / 1000.0)
+            except Exception as exc:
+                error = _error_to_proto(exc)
+        try:
+            status = self._impl.status()
+            endpoint = status.endpoint or self._impl.endpoint()
+        except Exception as exc:
+            return command_pb2.DaemonResponse(error=error or _error_to_proto(exc))
+        return command_pb2.DaemonResponse(
+            state=status.state, message=status.message, endpoint=endpoint, error=error
+        )
+
+    def Plan(self, request, context):
+        try:
+            fields = _spill(self._impl.plan(_request_args(request)))
+        except Exception as exc:
+            return command_pb2.ExecuteResponse(error=_error_to_proto(exc))
+        return command_pb2.ExecuteResponse(**fields)
+
+    def NegotiateCodec(self, request, context):
+        chosen = next((c for c in request.codecs if c in CODECS), "")
+        return command_pb2.NegotiateCodecResponse(codec=chosen)
+
+    def GetCapabilities(self, request, context):
+        details = [_capability_to_proto(c) for c in self._impl.get_capabilities()]
+        return command_pb2.GetCapabilitiesResponse(
+            capabilities=[d.name for d in details], details=details
+        )
+
+    def CacheTTLs(self, request, context):
+        return command_pb2.CacheTTLsResponse(ttl_seconds=self._impl.cache_ttls())
+
+    def Initialize(self, request, context):
+        try:
+            self._impl.initialize(json_format.MessageToDict(request.config))
+        except Exception as exc:
+            return command_pb2.InitializeResponse(error=_error_to_proto(exc))
+        return command_pb2.InitializeResponse()
+
+    def HandleEvent(self, request, context):
+        event = {
+            "type": request.type,
+            "plugin": request.plugin,
+            "time_unix_nano": request.time_unix_nano,
+            "data": json_format.MessageToDict(request.data),
+        }
+        try:
+            self._impl.handle_event(event)
+        except Exception as exc:
+            return command_pb2.HandleEventResponse(error=_error_to_proto(exc))
+        return command_pb2.HandleEventResponse()
+
+    def Session(self, request_iterator, context):
+        first = next(request_iterator, None)
+        if first is None:
+            return
+        session = self._impl.open_session(first.session_id)
+        if session is None:
+            context.abort(grpc.StatusCode.UNIMPLEMENTED, "plugin does not support sessions")
+        yield command_pb2.SessionResponse()
+        try:
+            for request in request_iterator:
+                try:
+                    fields = _spill(session.execute(json_format.MessageToDict(request.args)))
+                except Exception as exc:
+                    yield command_pb2.SessionResponse(error=_error_to_proto(exc))
+                    continue
+                yield command_pb2.SessionResponse(**fields)
+        finally:
+            session.close()
+
+
+def serve(impl: CommandPlugin) -> None:
+    """Serves impl to the host and blocks until the host kills the process."""
+    if os.environ.get(MAGIC_COOKIE_KEY) != MAGIC_COOKIE_VALUE:
+        sys.stderr.write(
+            "This binary is a plugin. These are not meant to be executed "
+            "directly. Please execute the program that consumes these "
+            "plugins, which will load any plugins automatically\n"
+        )
+        sys.exit(1)
+
+    versions = os.environ.get("PLUGIN_PROTOCOL_VERSIONS", "")
+    if versions and str(APP_PROTOCOL_VERSION) not in versions.split(","):
+        sys.stderr.write(
+            "plugin protocol version %d not accepted by host (%s)\n"
+            % (APP_PROTOCOL_VERSION, versions)
+        )
+        sys.exit(1)
+
+    setup_logging()
+
+    server = grpc.server(
+        futures.ThreadPoolExecutor(max_workers=10),
+        options=[
+            ("grpc.max_receive_message_length",
+             _env_bytes("OPENCODE_MAX_REQUEST_BYTES", DEFAULT_MAX_MESSAGE_BYTES)),
+            ("grpc.max_send_message_length",
+             _env_bytes("OPENCODE_MAX_RESPONSE_BYTES", DEFAULT_MAX_MESSAGE_BYTES)),
+        ],
+    )
+
+    command_service = command_pb2.DESCRIPTOR.services_by_name["CommandPlugin"].full_name
+
+    # "plugin" is what go-plugin checks; the others serve standard probes
+    health_servicer = health.HealthServicer()
+    for service in ("", "plugin", command_service):
+        health_servicer.set(service, health_pb2.HealthCheckResponse.SERVING)
+    health_pb2_grpc.add_HealthServicer_to_server(health_servicer, server)
+
+    command_pb2_grpc.add_CommandPluginServicer_to_server(
+        _CommandPluginServicer(impl), server
+    )
+
+    reflection.enable_server_reflection(
+        (
+            command_service,
+            health_pb2.DESCRIPTOR.services_by_name["Health"].full_name,
+            reflection.SERVICE_NAME,
+        ),
+        server,
+    )
+
+    port = server.add_insecure_port("127.0.0.1:0")
+    server.start()
+
+    # Anything else written to stdout before this line breaks the handshake.
+    sys.stdout.write(
+        "%d|%d|tcp|127.0.0.1:%d|grpc\n"
+        % (CORE_PROTOCOL_VERSION, APP_PROTOCOL_VERSION, port)
+    )
+    sys.stdout.flush()
+
+    server.wait_for_termination()